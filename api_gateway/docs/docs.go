@@ -24,24 +24,77 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/analysis/results-all": {
+        "/analysis/collections/{collection_id}/similarity": {
             "get": {
-                "description": "Перенаправляет запрос на получение списка всех результатов анализа в File Analysis Service.",
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Перенаправляет запрос на попарное сравнение проанализированных файлов коллекции в File Analysis Service.\nВозвращаются пары с оценкой сходства не ниже min_score в порядке убывания сходства.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "analysis"
                 ],
-                "summary": "Прокси для получения списка всех результатов анализа (дополнительно)",
+                "summary": "Прокси для сравнения файлов коллекции",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID коллекции",
+                        "name": "collection_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "number",
+                        "description": "Наименьшая оценка сходства от 0 до 1 (по умолчанию 0.3)",
+                        "name": "min_score",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Максимальное количество пар (по умолчанию 100, не более 1000)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
                 "responses": {
                     "200": {
-                        "description": "Список результатов анализа (каждый элемент с file_id, paragraph_count, etc.)",
+                        "description": "Подозрительные пары (collection_id, file_count, analyzed_count, not_analyzed, pairs_compared, suspicious_count, pairs)",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "type": "object",
-                                "additionalProperties": true
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректные параметры запроса",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Не передан или неверен API-ключ или токен",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Коллекция не найдена",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
                             }
                         }
                     },
@@ -57,35 +110,52 @@ const docTemplate = `{
                 }
             }
         },
-        "/analysis/results/{file_id}": {
+        "/analysis/jobs/{id}": {
             "get": {
-                "description": "Перенаправляет запрос на получение результатов анализа в File Analysis Service.",
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Перенаправляет запрос на получение состояния задачи анализа в File Analysis Service.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "analysis"
                 ],
-                "summary": "Прокси для получения результатов анализа файла (Сценарий 2)",
+                "summary": "Прокси для получения состояния задачи анализа",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "ID файла",
-                        "name": "file_id",
+                        "type": "integer",
+                        "description": "ID задачи анализа",
+                        "name": "id",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Результаты анализа (file_id, paragraph_count, word_count, character_count)",
+                        "description": "Состояние задачи (id, file_id, status, attempts, last_error, ...)",
                         "schema": {
                             "type": "object",
                             "additionalProperties": true
                         }
                     },
+                    "401": {
+                        "description": "Не передан или неверен API-ключ или токен",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
                     "404": {
-                        "description": "Результаты анализа не найдены",
+                        "description": "Задача не найдена",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -105,34 +175,105 @@ const docTemplate = `{
                 }
             }
         },
-        "/analysis/wordclouds": {
+        "/analysis/results-all": {
             "get": {
-                "description": "Перенаправляет запрос на получение облака слов в File Analysis Service.",
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Перенаправляет запрос на получение списка результатов анализа в File Analysis Service. Пользователь видит только результаты\nанализа своих файлов, администратор — всех файлов или файлов владельца owner_id.\nСписок возвращается постранично: ссылка на следующую страницу возвращается в поле next, на последней странице ее нет.",
                 "produces": [
-                    "image/png"
+                    "application/json"
                 ],
                 "tags": [
                     "analysis"
                 ],
-                "summary": "Прокси для получения облака слов (Сценарий 4)",
+                "summary": "Прокси для получения списка результатов анализа (дополнительно)",
                 "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Количество результатов на странице (по умолчанию 50, не более 500)",
+                        "name": "limit",
+                        "in": "query"
+                    },
                     {
                         "type": "string",
-                        "description": "Location (путь) к файлу облака слов",
-                        "name": "location",
-                        "in": "query",
-                        "required": true
+                        "description": "Курсор следующей страницы из поля next_cursor",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "created_at",
+                            "file_id",
+                            "word_count"
+                        ],
+                        "type": "string",
+                        "description": "Поле сортировки (по умолчанию created_at)",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "description": "Порядок сортировки (по умолчанию desc)",
+                        "name": "order",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID файла",
+                        "name": "file_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID владельца файла (другого пользователя — только для администратора)",
+                        "name": "owner_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Код языка текста (ISO 639-1 или und)",
+                        "name": "language",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Версия алгоритмов анализа",
+                        "name": "analyzer_version",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Результаты, полученные не раньше этого времени (RFC 3339)",
+                        "name": "created_after",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Результаты, полученные раньше этого времени (RFC 3339)",
+                        "name": "created_before",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Изображение облака слов",
+                        "description": "Страница списка результатов анализа: items, total, next_cursor, next",
                         "schema": {
-                            "type": "file"
+                            "type": "object",
+                            "additionalProperties": true
                         }
                     },
                     "400": {
-                        "description": "Параметр location не указан",
+                        "description": "Некорректные параметры запроса",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -140,8 +281,17 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "404": {
-                        "description": "Облако слов не найдено",
+                    "401": {
+                        "description": "Не передан или неверен API-ключ или токен",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Результаты анализа файлов другого пользователя доступны только администратору",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -161,37 +311,49 @@ const docTemplate = `{
                 }
             }
         },
-        "/analysis/{file_id}": {
-            "post": {
-                "description": "Перенаправляет запрос на анализ файла в File Analysis Service.",
+        "/analysis/results/{file_id}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Перенаправляет запрос на получение результатов анализа в File Analysis Service.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "analysis"
                 ],
-                "summary": "Прокси для анализа файла (Сценарий 2)",
+                "summary": "Прокси для получения результатов анализа файла (Сценарий 2)",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "ID файла для анализа",
+                        "description": "ID файла",
                         "name": "file_id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID результата анализа из истории (по умолчанию последний)",
+                        "name": "result_id",
+                        "in": "query"
                     }
                 ],
                 "responses": {
-                    "202": {
-                        "description": "Сообщение о принятии запроса на анализ",
+                    "200": {
+                        "description": "Статистика текста (id, created_at, analyzer_version, file_id, language, paragraph_count, line_count, sentence_count, word_count, unique_word_count, character_count, character_count_with_spaces, average_word_length, average_sentence_length, lexical_density)",
                         "schema": {
                             "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "additionalProperties": true
                         }
                     },
-                    "400": {
-                        "description": "Ошибка запроса",
+                    "401": {
+                        "description": "Не передан или неверен API-ключ или токен",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -199,41 +361,17 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "500": {
-                        "description": "Внутренняя ошибка сервера или ошибка File Analysis Service",
+                    "404": {
+                        "description": "Результаты анализа не найдены",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
                                 "type": "string"
                             }
                         }
-                    }
-                }
-            }
-        },
-        "/files": {
-            "get": {
-                "description": "Перенаправляет запрос на получение списка всех файлов в File Storing Service.",
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "files"
-                ],
-                "summary": "Прокси для получения списка всех файлов (дополнительно)",
-                "responses": {
-                    "200": {
-                        "description": "Список файлов (каждый элемент с id, name, location)",
-                        "schema": {
-                            "type": "array",
-                            "items": {
-                                "type": "object",
-                                "additionalProperties": true
-                            }
-                        }
                     },
                     "500": {
-                        "description": "Внутренняя ошибка сервера или ошибка File Storing Service",
+                        "description": "Внутренняя ошибка сервера или ошибка File Analysis Service",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -244,34 +382,102 @@ const docTemplate = `{
                 }
             }
         },
-        "/files/{id}": {
+        "/analysis/results/{file_id}/frequencies": {
             "get": {
-                "description": "Перенаправляет запрос на получение файла в File Storing Service.",
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Перенаправляет запрос на получение таблицы частот слов файла в File Analysis Service.",
                 "produces": [
-                    "text/plain"
+                    "application/json",
+                    "text/csv"
                 ],
                 "tags": [
-                    "files"
+                    "analysis"
                 ],
-                "summary": "Прокси для получения файла (Сценарий 3)",
+                "summary": "Прокси для получения частот слов файла",
                 "parameters": [
                     {
                         "type": "string",
                         "description": "ID файла",
-                        "name": "id",
+                        "name": "file_id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Максимальное количество слов (по умолчанию 100, не более 1000)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Сколько слов пропустить (по умолчанию 0)",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Включать ли служебные слова (по умолчанию false)",
+                        "name": "stopwords",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Объединять ли формы одного слова (по умолчанию false)",
+                        "name": "lemmatize",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "json",
+                            "csv"
+                        ],
+                        "type": "string",
+                        "description": "Формат ответа: json (по умолчанию) или csv",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID результата анализа из истории (по умолчанию последний)",
+                        "name": "result_id",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Содержимое файла",
+                        "description": "Частоты слов (file_id, result_id, language, word_count, total, limit, offset, include_stop_words, lemmatized, items: word, count, frequency)",
                         "schema": {
-                            "type": "string"
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректные параметры запроса",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Не передан или неверен API-ключ или токен",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     },
                     "404": {
-                        "description": "Файл не найден",
+                        "description": "Результаты анализа не найдены",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -280,7 +486,7 @@ const docTemplate = `{
                         }
                     },
                     "500": {
-                        "description": "Внутренняя ошибка сервера или ошибка File Storing Service",
+                        "description": "Внутренняя ошибка сервера или ошибка File Analysis Service",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -291,31 +497,43 @@ const docTemplate = `{
                 }
             }
         },
-        "/upload": {
-            "post": {
-                "description": "Перенаправляет запрос на загрузку файла в File Storing Service.",
-                "consumes": [
-                    "multipart/form-data"
+        "/analysis/results/{file_id}/history": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
                 ],
+                "description": "Перенаправляет запрос на получение всех результатов анализа файла (от нового к старому) в File Analysis Service.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "files"
+                    "analysis"
                 ],
-                "summary": "Прокси для загрузки файла (Сценарий 1)",
+                "summary": "Прокси для получения истории анализов файла",
                 "parameters": [
                     {
-                        "type": "file",
-                        "description": "Файл для загрузки (только .txt)",
-                        "name": "file",
-                        "in": "formData",
+                        "type": "string",
+                        "description": "ID файла",
+                        "name": "file_id",
+                        "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "ID загруженного файла",
+                    "200": {
+                        "description": "История анализов (file_id, analyzer_version, results)",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "Не передан или неверен API-ключ или токен",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -323,8 +541,8 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "400": {
-                        "description": "Ошибка запроса",
+                    "404": {
+                        "description": "Файл еще не анализировался",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -333,7 +551,7 @@ const docTemplate = `{
                         }
                     },
                     "500": {
-                        "description": "Внутренняя ошибка сервера или ошибка File Storing Service",
+                        "description": "Внутренняя ошибка сервера или ошибка File Analysis Service",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -343,6 +561,2349 @@ const docTemplate = `{
                     }
                 }
             }
+        },
+        "/analysis/results/{file_id}/passages": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Перенаправляет запрос отчета о заимствованиях в File Analysis Service. Отчет перечисляет фрагменты файла, совпадающие с фрагментами\nдругих проанализированных файлов: смещения в файле и в файле-источнике (в символах Unicode), ID источника и длину совпадения в словах.\nС format=html возвращается страница с текстом файла, в котором совпадающие фрагменты выделены цветом.",
+                "produces": [
+                    "application/json",
+                    "text/html"
+                ],
+                "tags": [
+                    "analysis"
+                ],
+                "summary": "Прокси для получения отчета о совпадающих фрагментах файла",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID файла",
+                        "name": "file_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "json",
+                            "html"
+                        ],
+                        "type": "string",
+                        "description": "Формат отчета (по умолчанию json)",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Наименьшая длина фрагмента в словах (по умолчанию 8)",
+                        "name": "min_words",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Сколько самых длинных фрагментов включить в отчет (по умолчанию 500, не более 5000)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Отчет (file_id, word_count, matched_words, matched_percent, sources, passages) или HTML-страница",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректные параметры запроса",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Не передан или неверен API-ключ или токен",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Файл еще не анализировался",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Файл проанализирован предыдущей версией алгоритмов; нужен повторный анализ",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера или ошибка File Analysis Service",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/analysis/similarity/{file_id}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Перенаправляет запрос на получение файлов, наиболее похожих на указанный, в File Analysis Service.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "analysis"
+                ],
+                "summary": "Прокси для получения похожих файлов",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID файла",
+                        "name": "file_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Похожие файлы (file_id, matches: file_id, score, percent, description)",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "Не передан или неверен API-ключ или токен",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Файл еще не анализировался",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера или ошибка File Analysis Service",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/analysis/webhook": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Перенаправляет запрос на получение URL уведомлений о завершении анализа и секрета подписи пользователя в File Analysis Service.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Прокси для получения настроек уведомлений",
+                "responses": {
+                    "200": {
+                        "description": "Настройки уведомлений (owner_id, url, secret, created_at, updated_at)",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "Не передан или неверен API-ключ или токен",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Уведомления не настроены",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера или ошибка File Analysis Service",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Перенаправляет запрос на изменение URL уведомлений о завершении анализа файлов пользователя в File Analysis Service.\nПустой url отключает уведомления обо всех файлах; rotate_secret=true заменяет секрет подписи.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Прокси для изменения настроек уведомлений",
+                "parameters": [
+                    {
+                        "description": "URL уведомлений (url, rotate_secret)",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Настройки уведомлений (owner_id, url, secret, created_at, updated_at)",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный запрос или URL",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Не передан или неверен API-ключ или токен",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера или ошибка File Analysis Service",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Перенаправляет запрос на удаление URL и секрета уведомлений пользователя в File Analysis Service. Недоставленные уведомления не отправляются.",
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Прокси для удаления настроек уведомлений",
+                "responses": {
+                    "204": {
+                        "description": "Настройки удалены",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Не передан или неверен API-ключ или токен",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Уведомления не настроены",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера или ошибка File Analysis Service",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/analysis/webhook/deliveries": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Перенаправляет запрос на получение последних уведомлений пользователя о завершении анализа в File Analysis Service.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Прокси для получения истории доставки уведомлений",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Количество уведомлений (по умолчанию 20, не более 100)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Уведомления (id, event_id, type, job_id, file_id, url, attempts, next_attempt_at, delivered_at, failed_at, last_error)",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "type": "object",
+                                "additionalProperties": true
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный параметр limit",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Не передан или неверен API-ключ или токен",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера или ошибка File Analysis Service",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/analysis/wordclouds": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Перенаправляет запрос на получение облака слов в File Analysis Service.",
+                "produces": [
+                    "image/png"
+                ],
+                "tags": [
+                    "analysis"
+                ],
+                "summary": "Прокси для получения облака слов (Сценарий 4)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Location (путь) к файлу облака слов",
+                        "name": "location",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Изображение облака слов",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Параметр location не указан",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Не передан или неверен API-ключ или токен",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Облако слов не найдено",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера или ошибка File Analysis Service",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/analysis/{file_id}": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Перенаправляет запрос на анализ файла в File Analysis Service. Если есть результат анализа текущей версии алгоритмов, он переиспользуется;\nforce=true заставляет проанализировать файл заново.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "analysis"
+                ],
+                "summary": "Прокси для анализа файла (Сценарий 2)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID файла для анализа",
+                        "name": "file_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Выполнить анализ заново, даже если есть результат текущей версии",
+                        "name": "force",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "URL, на который по завершении задачи будет отправлено подписанное уведомление с результатом анализа или причиной неудачи",
+                        "name": "callback_url",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Сообщение о принятии запроса на анализ, ID и состояние задачи (job_id, status, force, callback_url)",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Ошибка запроса",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Не передан или неверен API-ключ или токен",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера или ошибка File Analysis Service",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/analysis/{file_id}/events": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Перенаправляет запрос на поток Server-Sent Events о ходе анализа файла в File Analysis Service и передает события клиенту по мере получения.\nПервое событие — текущее состояние последней задачи анализа (queued, started, retrying, saved, failed или cancelled), затем этапы: started, fetched, counted, wordcloud, saved; retrying и failed при ошибках, cancelled, если файл удален во время анализа.\nПоток закрывается после saved, failed или cancelled.",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "analysis"
+                ],
+                "summary": "Прокси для потока событий анализа файла",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID файла",
+                        "name": "file_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Поток событий (event: этап, data: JSON с полями stage, file_id, job_id, time, attempt, word_count, result_id, word_cloud_location, word_cloud_svg_location, error, next_run_at)",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Не передан или неверен API-ключ или токен",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Файл не найден или не анализировался",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера или ошибка File Analysis Service",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/batches": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Перенаправляет пакетную загрузку в File Storing Service: несколько файлов в полях files, ZIP-архивы распаковываются.\nВсе сохраненные файлы относятся к одному именованному пакету, ответ содержит результат для каждого файла.\nЗапрос больше MAX_BATCH_SIZE отклоняется с кодом 413.",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "files"
+                ],
+                "summary": "Прокси для пакетной загрузки файлов",
+                "parameters": [
+                    {
+                        "type": "file",
+                        "description": "Файлы или ZIP-архивы (поле можно повторять)",
+                        "name": "files",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Имя пакета",
+                        "name": "name",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Поставить сохраненные файлы в очередь анализа",
+                        "name": "analyze",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID коллекции, в которую добавляются сохраненные файлы",
+                        "name": "collection_id",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Пакет и результат для каждого файла (id, name, analyze, uploaded, failed, files)",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Не сохранен ни один файл, или запрос некорректен",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "Не передан или неверен API-ключ или токен",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Коллекция не найдена",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "413": {
+                        "description": "Суммарный размер запроса больше допустимого",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера или ошибка File Storing Service",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/batches/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Перенаправляет запрос на получение пакета и метаданных его файлов в File Storing Service.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "files"
+                ],
+                "summary": "Прокси для получения пакета файлов",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID пакета",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Пакет и его файлы (id, name, owner_id, created_at, files)",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "Не передан или неверен API-ключ или токен",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Пакет не найден",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера или ошибка File Storing Service",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/collections": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Перенаправляет запрос на получение коллекций пользователя с количеством файлов в каждой в File Storing Service.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "collections"
+                ],
+                "summary": "Прокси для получения списка коллекций",
+                "responses": {
+                    "200": {
+                        "description": "Список коллекций",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "type": "object",
+                                "additionalProperties": true
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Не передан или неверен API-ключ или токен",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера или ошибка File Storing Service",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Перенаправляет запрос на создание коллекции файлов (например, задания) в File Storing Service. Файлы добавляются в коллекцию\nпри загрузке (поле collection_id) или запросом PUT /collections/{id}/files/{file_id}.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "collections"
+                ],
+                "summary": "Прокси для создания коллекции",
+                "parameters": [
+                    {
+                        "description": "Имя и описание коллекции (name, description)",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Созданная коллекция (id, name, description, owner_id, file_count, created_at, updated_at)",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный запрос",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Не передан или неверен API-ключ или токен",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера или ошибка File Storing Service",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/collections/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Перенаправляет запрос на получение коллекции и метаданных ее файлов в File Storing Service.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "collections"
+                ],
+                "summary": "Прокси для получения коллекции",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID коллекции",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Коллекция и ее файлы (id, name, description, owner_id, file_count, files)",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "Не передан или неверен API-ключ или токен",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Коллекция не найдена",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера или ошибка File Storing Service",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Перенаправляет запрос на удаление коллекции в File Storing Service. Файлы коллекции не удаляются.",
+                "tags": [
+                    "collections"
+                ],
+                "summary": "Прокси для удаления коллекции",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID коллекции",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Коллекция удалена",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Не передан или неверен API-ключ или токен",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Коллекция не найдена",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера или ошибка File Storing Service",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Перенаправляет запрос на изменение имени и (или) описания коллекции в File Storing Service.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "collections"
+                ],
+                "summary": "Прокси для изменения коллекции",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID коллекции",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Новое имя и (или) описание (name, description)",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Измененная коллекция",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный запрос",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Не передан или неверен API-ключ или токен",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Коллекция не найдена",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера или ошибка File Storing Service",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/collections/{id}/files/{file_id}": {
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Перенаправляет запрос на добавление ранее загруженного файла в коллекцию в File Storing Service.\nФайл, входивший в другую коллекцию, переносится в эту.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "collections"
+                ],
+                "summary": "Прокси для добавления файла в коллекцию",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID коллекции",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID файла",
+                        "name": "file_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Метаданные файла",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Файл и коллекция принадлежат разным пользователям",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Не передан или неверен API-ключ или токен",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Коллекция или файл не найдены",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера или ошибка File Storing Service",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Перенаправляет запрос на исключение файла из коллекции в File Storing Service. Сам файл не удаляется.",
+                "tags": [
+                    "collections"
+                ],
+                "summary": "Прокси для исключения файла из коллекции",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID коллекции",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID файла",
+                        "name": "file_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Файл исключен из коллекции",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Не передан или неверен API-ключ или токен",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Коллекция не найдена или файл не входит в нее",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера или ошибка File Storing Service",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/files": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Перенаправляет запрос на получение списка файлов в File Storing Service. Пользователь получает только свои файлы, администратор — все.\nСписок возвращается постранично: ссылка на следующую страницу возвращается в поле next, на последней странице ее нет.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "files"
+                ],
+                "summary": "Прокси для получения списка всех файлов (дополнительно)",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Количество файлов на странице (по умолчанию 50, не более 500)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Курсор следующей страницы из поля next_cursor",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "created_at",
+                            "name",
+                            "size"
+                        ],
+                        "type": "string",
+                        "description": "Поле сортировки (по умолчанию created_at)",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "description": "Порядок сортировки (по умолчанию desc)",
+                        "name": "order",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Подстрока имени файла (без учета регистра)",
+                        "name": "name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Файлы, загруженные не раньше этого времени (RFC 3339)",
+                        "name": "created_after",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Файлы, загруженные раньше этого времени (RFC 3339)",
+                        "name": "created_before",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID владельца (другого пользователя — только для администратора)",
+                        "name": "owner_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Только проанализированные (true) или непроанализированные (false) файлы",
+                        "name": "has_analysis",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID коллекции",
+                        "name": "collection_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID пакета",
+                        "name": "batch_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Страница списка файлов: items, total, next_cursor, next",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректные параметры запроса",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Не передан или неверен API-ключ или токен",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Файлы другого пользователя доступны только администратору",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера или ошибка File Storing Service",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/files/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Перенаправляет запрос на получение исходного файла в File Storing Service.",
+                "produces": [
+                    "text/plain",
+                    "application/octet-stream"
+                ],
+                "tags": [
+                    "files"
+                ],
+                "summary": "Прокси для получения файла (Сценарий 3)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID файла",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Содержимое файла",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "401": {
+                        "description": "Не передан или неверен API-ключ или токен",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Файл не найден",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера или ошибка File Storing Service",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Перенаправляет запрос на удаление файла в File Storing Service. Файл помечается удаленным, его результаты анализа удаляются,\nа содержимое удаляется из хранилища по истечении срока хранения.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "files"
+                ],
+                "summary": "Прокси для удаления файла",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID файла",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Файл помечен удаленным (id, deleted_at, purge_after)",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "Не передан или неверен API-ключ или токен",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Файл не найден",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера или ошибка File Storing Service",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/files/{id}/text": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Перенаправляет запрос на получение текста, извлеченного из файла, в File Storing Service.",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "files"
+                ],
+                "summary": "Прокси для получения текста файла",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID файла",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Текст файла",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Не передан или неверен API-ключ или токен",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Файл не найден",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера или ошибка File Storing Service",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/search": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Перенаправляет поисковый запрос в File Storing Service. Возвращает файлы, извлеченный текст которых соответствует запросу,\nв порядке убывания релевантности с фрагментами текста, где совпадения выделены тегом \u003cmark\u003e.\nСинтаксис запроса: слова через пробел должны встречаться все; \"фраза в кавычках\" — слова подряд; слово* — слова с таким началом;\n-слово — исключить; OR — любое из условий. Пользователь ищет только среди своих файлов, администратор — среди всех.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "search"
+                ],
+                "summary": "Прокси для полнотекстового поиска по файлам",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Поисковый запрос",
+                        "name": "q",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID владельца (другого пользователя — только для администратора)",
+                        "name": "owner_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Количество файлов на странице (по умолчанию 20, не более 50)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Количество пропускаемых файлов (по умолчанию 0)",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Страница найденных файлов: items (file_id, name, rank, snippet, ...), total, next",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный запрос",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Не передан или неверен API-ключ или токен",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Файлы другого пользователя доступны только администратору",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера или ошибка File Storing Service",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/upload": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Перенаправляет запрос на загрузку файла в File Storing Service. Поддерживаются .txt, .md, .html, .docx, .rtf и .pdf:\nиз документа извлекается текст, на котором затем выполняется анализ.\nОтвет содержит SHA-256 хеш файла, его MIME-тип, использованный экстрактор и, если содержимое уже загружалось, ID и время загрузки самого раннего совпадающего файла.\nФайл больше MAX_UPLOAD_SIZE, не помещающийся в квоту пользователя или распаковывающийся больше чем в MAX_EXTRACTED_SIZE байт отклоняется с кодом 413.",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "files"
+                ],
+                "summary": "Прокси для загрузки файла (Сценарий 1)",
+                "parameters": [
+                    {
+                        "type": "file",
+                        "description": "Файл для загрузки (.txt, .md, .html, .docx, .rtf, .pdf)",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID коллекции, в которую добавляется файл",
+                        "name": "collection_id",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "ID загруженного файла (id, hash, mime_type, extractor, is_duplicate, duplicate_of)",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Ошибка запроса",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Не передан или неверен API-ключ или токен",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Коллекция не найдена",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "413": {
+                        "description": "Файл или извлеченный из него текст больше допустимого размера или превышена квота хранилища",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера или ошибка File Storing Service",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/uploads": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Перенаправляет запрос на создание сессии возобновляемой загрузки в File Storing Service. Файл затем загружается частями\nзапросами PATCH /uploads/{id} и сохраняется запросом POST /uploads/{id}/complete.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "uploads"
+                ],
+                "summary": "Прокси для создания сессии возобновляемой загрузки",
+                "parameters": [
+                    {
+                        "description": "Имя, размер и SHA-256 хеш файла и необязательный ID коллекции (name, size, sha256, collection_id)",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Созданная сессия загрузки (id, name, size, offset, sha256, expires_at)",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный запрос или неподдерживаемый формат файла",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Не передан или неверен API-ключ или токен",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "413": {
+                        "description": "Файл больше допустимого размера или превышена квота хранилища",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "429": {
+                        "description": "Слишком много незавершенных сессий загрузки",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера или ошибка File Storing Service",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/uploads/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Перенаправляет запрос состояния сессии загрузки в File Storing Service. Количество полученных байт, с которого нужно\nпродолжить загрузку, возвращается в поле offset и в заголовке Upload-Offset (достаточно запроса HEAD).",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "uploads"
+                ],
+                "summary": "Прокси для получения состояния сессии загрузки",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID сессии загрузки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Сессия загрузки (id, name, size, offset, sha256, expires_at)",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "Не передан или неверен API-ключ или токен",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Сессия загрузки не найдена",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера или ошибка File Storing Service",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Перенаправляет запрос на удаление сессии загрузки и полученных частей файла в File Storing Service.",
+                "tags": [
+                    "uploads"
+                ],
+                "summary": "Прокси для отмены возобновляемой загрузки",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID сессии загрузки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Сессия загрузки удалена",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Не передан или неверен API-ключ или токен",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Сессия загрузки не найдена",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "В сессию загружается часть файла",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера или ошибка File Storing Service",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "head": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Перенаправляет запрос состояния сессии загрузки в File Storing Service. Количество полученных байт, с которого нужно\nпродолжить загрузку, возвращается в поле offset и в заголовке Upload-Offset (достаточно запроса HEAD).",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "uploads"
+                ],
+                "summary": "Прокси для получения состояния сессии загрузки",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID сессии загрузки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Сессия загрузки (id, name, size, offset, sha256, expires_at)",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "Не передан или неверен API-ключ или токен",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Сессия загрузки не найдена",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера или ошибка File Storing Service",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Передает часть файла в File Storing Service; часть больше MAX_UPLOAD_SIZE отклоняется с кодом 413. Заголовок Upload-Offset должен совпадать с количеством уже полученных байт;\nновое смещение возвращается в заголовке Upload-Offset.",
+                "consumes": [
+                    "application/offset+octet-stream"
+                ],
+                "tags": [
+                    "uploads"
+                ],
+                "summary": "Прокси для загрузки части файла",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID сессии загрузки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Смещение части в файле",
+                        "name": "Upload-Offset",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Часть файла сохранена",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный заголовок Upload-Offset",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Не передан или неверен API-ключ или токен",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Сессия загрузки не найдена",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Смещение не совпадает с количеством полученных байт или в сессию уже загружается другая часть",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "413": {
+                        "description": "Часть выходит за пределы размера файла или больше MAX_UPLOAD_SIZE",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "415": {
+                        "description": "Тип содержимого не application/offset+octet-stream",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера или ошибка File Storing Service",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/uploads/{id}/complete": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Перенаправляет запрос на завершение загрузки в File Storing Service: проверяется SHA-256 хеш полученного файла,\nпосле чего файл сохраняется так же, как при POST /upload.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "uploads"
+                ],
+                "summary": "Прокси для завершения возобновляемой загрузки",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID сессии загрузки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "ID сохраненного файла, хеш, MIME-тип, экстрактор и сведения о дубликате",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Хеш файла не совпадает, ошибка валидации или извлечения текста",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Не передан или неверен API-ключ или токен",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Сессия загрузки не найдена",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Файл получен не полностью или сессия занята",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "413": {
+                        "description": "Превышена квота хранилища",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера или ошибка File Storing Service",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/usage": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Перенаправляет запрос на получение суммарного размера файлов пользователя, его квоты и максимального размера файла в File Storing Service.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "files"
+                ],
+                "summary": "Прокси для получения объема файлов и квоты пользователя",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID пользователя (только для администратора)",
+                        "name": "owner_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Объем файлов и квота (owner_id, used_bytes, quota_bytes, max_file_size)",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "Не передан или неверен API-ключ или токен",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Сведения о другом пользователе доступны только администратору",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера или ошибка File Storing Service",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "securityDefinitions": {
+        "ApiKeyAuth": {
+            "description": "API-ключ из API_KEYS",
+            "type": "apiKey",
+            "name": "X-API-Key",
+            "in": "header"
+        },
+        "BearerAuth": {
+            "description": "JWT (HS256 или RS256) в формате \"Bearer \u003ctoken\u003e\"",
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header"
         }
     }
 }`
@@ -1,15 +1,16 @@
 package handlers
 
 import (
-	"bytes"
+	"errors"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"log"
-	"mime/multipart"
+	"mime"
 	"net/http"
 	"net/url"
 	"os"
+	"pkg/auth"
+	"pkg/limits"
 	"strings"
 
 	"github.com/gin-gonic/gin"
@@ -25,73 +26,658 @@ import (
 type ProxyHandler struct {
 	FileStoringServiceAddr  string
 	FileAnalysisServiceAddr string
+	MaxUploadSize           int64              // Максимальный размер загружаемого файла в байтах
+	MaxBatchSize            int64              // Максимальный суммарный размер файлов пакетной загрузки в байтах
+	Signer                  auth.ServiceSigner // Подпись пользователя в пересылаемых запросах
 }
 
+// bodyTooLargeKey — ключ контекста запроса с сообщением об ошибке для тела, превысившего лимит limitBody.
+const bodyTooLargeKey = "bodyTooLargeError"
+
+// maxRequestBodySize — лимит тела запроса для эндпоинтов без собственного лимита загрузки.
+// Тело читается целиком перед подписью (см. auth.ServiceSigner.SignIdentity), поэтому ограничивается всегда.
+const maxRequestBodySize = 1 << 20
+
 // NewProxyHandler создает новый экземпляр ProxyHandler.
 // @Summary Создает новый ProxyHandler
-// @Description Инициализирует ProxyHandler с адресами целевых сервисов.
+// @Description Инициализирует ProxyHandler с адресами целевых сервисов. Ограничения размера загрузки — значения по умолчанию из pkg/limits.
+// @Description Пользователь в каждом пересылаемом запросе подписывается signer, так как сервисы принимают только подписанных пользователей.
 // @Return *ProxyHandler
-func NewProxyHandler(fileStoringServiceAddr, fileAnalysisServiceAddr string) *ProxyHandler {
+func NewProxyHandler(fileStoringServiceAddr, fileAnalysisServiceAddr string, signer auth.ServiceSigner) *ProxyHandler {
 	return &ProxyHandler{
 		FileStoringServiceAddr:  fileStoringServiceAddr,
 		FileAnalysisServiceAddr: fileAnalysisServiceAddr,
+		MaxUploadSize:           limits.DefaultMaxUploadSize,
+		MaxBatchSize:            limits.DefaultMaxBatchSize,
+		Signer:                  signer,
 	}
 }
 
 // @Summary Прокси для загрузки файла (Сценарий 1)
-// @Description Перенаправляет запрос на загрузку файла в File Storing Service.
+// @Description Перенаправляет запрос на загрузку файла в File Storing Service. Поддерживаются .txt, .md, .html, .docx, .rtf и .pdf:
+// @Description из документа извлекается текст, на котором затем выполняется анализ.
+// @Description Ответ содержит SHA-256 хеш файла, его MIME-тип, использованный экстрактор и, если содержимое уже загружалось, ID и время загрузки самого раннего совпадающего файла.
+// @Description Файл больше MAX_UPLOAD_SIZE, не помещающийся в квоту пользователя или распаковывающийся больше чем в MAX_EXTRACTED_SIZE байт отклоняется с кодом 413.
 // @Tags files
 // @Accept multipart/form-data
-// @Param file formData file true "Файл для загрузки (только .txt)"
+// @Param file formData file true "Файл для загрузки (.txt, .md, .html, .docx, .rtf, .pdf)"
+// @Param collection_id formData string false "ID коллекции, в которую добавляется файл"
 // @Produce json
-// @Success 201 {object} map[string]string "ID загруженного файла"
+// @Success 201 {object} map[string]any "ID загруженного файла (id, hash, mime_type, extractor, is_duplicate, duplicate_of)"
 // @Failure 400 {object} map[string]string "Ошибка запроса"
+// @Failure 404 {object} map[string]string "Коллекция не найдена"
+// @Failure 413 {object} map[string]string "Файл или извлеченный из него текст больше допустимого размера или превышена квота хранилища"
 // @Failure 500 {object} map[string]string "Внутренняя ошибка сервера или ошибка File Storing Service"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Failure 401 {object} map[string]string "Не передан или неверен API-ключ или токен"
 // @Router /upload [post]
 func (h *ProxyHandler) UploadFile(c *gin.Context) {
+	// Тело запроса не может быть больше файла максимального размера с заголовками multipart.
+	// Точный размер файла и квоту пользователя проверяет File Storing Service
+	message := fmt.Sprintf("File is too large: the maximum size is %d bytes", h.MaxUploadSize)
+	if !limitBody(c, h.MaxUploadSize+limits.MultipartOverhead, message) {
+		return
+	}
 	h.proxyRequest(c, h.FileStoringServiceAddr, "/api/v1/files/upload")
 }
 
+// limitBody ограничивает тело запроса limit байтами. Запрос с большим Content-Length сразу получает 413 с сообщением message;
+// если тело без Content-Length окажется больше, proxyRequest ответит 413 с тем же сообщением.
+func limitBody(c *gin.Context, limit int64, message string) bool {
+	if c.Request.ContentLength > limit {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": message})
+		return false
+	}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+	c.Set(bodyTooLargeKey, message)
+	return true
+}
+
+// @Summary Прокси для пакетной загрузки файлов
+// @Description Перенаправляет пакетную загрузку в File Storing Service: несколько файлов в полях files, ZIP-архивы распаковываются.
+// @Description Все сохраненные файлы относятся к одному именованному пакету, ответ содержит результат для каждого файла.
+// @Description Запрос больше MAX_BATCH_SIZE отклоняется с кодом 413.
+// @Tags files
+// @Accept multipart/form-data
+// @Param files formData file true "Файлы или ZIP-архивы (поле можно повторять)"
+// @Param name formData string false "Имя пакета"
+// @Param analyze formData bool false "Поставить сохраненные файлы в очередь анализа"
+// @Param collection_id formData string false "ID коллекции, в которую добавляются сохраненные файлы"
+// @Produce json
+// @Success 201 {object} map[string]any "Пакет и результат для каждого файла (id, name, analyze, uploaded, failed, files)"
+// @Failure 400 {object} map[string]any "Не сохранен ни один файл, или запрос некорректен"
+// @Failure 404 {object} map[string]string "Коллекция не найдена"
+// @Failure 413 {object} map[string]string "Суммарный размер запроса больше допустимого"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера или ошибка File Storing Service"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Failure 401 {object} map[string]string "Не передан или неверен API-ключ или токен"
+// @Router /batches [post]
+func (h *ProxyHandler) UploadBatch(c *gin.Context) {
+	message := fmt.Sprintf("Batch is too large: the maximum total size is %d bytes", h.MaxBatchSize)
+	if !limitBody(c, h.MaxBatchSize+limits.MultipartOverhead, message) {
+		return
+	}
+	h.proxyRequest(c, h.FileStoringServiceAddr, "/api/v1/batches")
+}
+
+// @Summary Прокси для получения пакета файлов
+// @Description Перенаправляет запрос на получение пакета и метаданных его файлов в File Storing Service.
+// @Tags files
+// @Param id path string true "ID пакета"
+// @Produce json
+// @Success 200 {object} map[string]any "Пакет и его файлы (id, name, owner_id, created_at, files)"
+// @Failure 404 {object} map[string]string "Пакет не найден"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера или ошибка File Storing Service"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Failure 401 {object} map[string]string "Не передан или неверен API-ключ или токен"
+// @Router /batches/{id} [get]
+func (h *ProxyHandler) GetBatch(c *gin.Context) {
+	h.proxyRequest(c, h.FileStoringServiceAddr, "/api/v1/batches/"+c.Param("id"))
+}
+
+// @Summary Прокси для создания коллекции
+// @Description Перенаправляет запрос на создание коллекции файлов (например, задания) в File Storing Service. Файлы добавляются в коллекцию
+// @Description при загрузке (поле collection_id) или запросом PUT /collections/{id}/files/{file_id}.
+// @Tags collections
+// @Accept json
+// @Param request body object true "Имя и описание коллекции (name, description)"
+// @Produce json
+// @Success 201 {object} map[string]any "Созданная коллекция (id, name, description, owner_id, file_count, created_at, updated_at)"
+// @Failure 400 {object} map[string]string "Некорректный запрос"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера или ошибка File Storing Service"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Failure 401 {object} map[string]string "Не передан или неверен API-ключ или токен"
+// @Router /collections [post]
+func (h *ProxyHandler) CreateCollection(c *gin.Context) {
+	h.proxyRequest(c, h.FileStoringServiceAddr, "/api/v1/collections")
+}
+
+// @Summary Прокси для получения списка коллекций
+// @Description Перенаправляет запрос на получение коллекций пользователя с количеством файлов в каждой в File Storing Service.
+// @Tags collections
+// @Produce json
+// @Success 200 {array} map[string]any "Список коллекций"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера или ошибка File Storing Service"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Failure 401 {object} map[string]string "Не передан или неверен API-ключ или токен"
+// @Router /collections [get]
+func (h *ProxyHandler) ListCollections(c *gin.Context) {
+	h.proxyRequest(c, h.FileStoringServiceAddr, "/api/v1/collections")
+}
+
+// @Summary Прокси для получения коллекции
+// @Description Перенаправляет запрос на получение коллекции и метаданных ее файлов в File Storing Service.
+// @Tags collections
+// @Param id path string true "ID коллекции"
+// @Produce json
+// @Success 200 {object} map[string]any "Коллекция и ее файлы (id, name, description, owner_id, file_count, files)"
+// @Failure 404 {object} map[string]string "Коллекция не найдена"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера или ошибка File Storing Service"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Failure 401 {object} map[string]string "Не передан или неверен API-ключ или токен"
+// @Router /collections/{id} [get]
+func (h *ProxyHandler) GetCollection(c *gin.Context) {
+	h.proxyRequest(c, h.FileStoringServiceAddr, "/api/v1/collections/"+c.Param("id"))
+}
+
+// @Summary Прокси для изменения коллекции
+// @Description Перенаправляет запрос на изменение имени и (или) описания коллекции в File Storing Service.
+// @Tags collections
+// @Accept json
+// @Param id path string true "ID коллекции"
+// @Param request body object true "Новое имя и (или) описание (name, description)"
+// @Produce json
+// @Success 200 {object} map[string]any "Измененная коллекция"
+// @Failure 400 {object} map[string]string "Некорректный запрос"
+// @Failure 404 {object} map[string]string "Коллекция не найдена"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера или ошибка File Storing Service"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Failure 401 {object} map[string]string "Не передан или неверен API-ключ или токен"
+// @Router /collections/{id} [patch]
+func (h *ProxyHandler) UpdateCollection(c *gin.Context) {
+	h.proxyRequest(c, h.FileStoringServiceAddr, "/api/v1/collections/"+c.Param("id"))
+}
+
+// @Summary Прокси для удаления коллекции
+// @Description Перенаправляет запрос на удаление коллекции в File Storing Service. Файлы коллекции не удаляются.
+// @Tags collections
+// @Param id path string true "ID коллекции"
+// @Success 204 {string} string "Коллекция удалена"
+// @Failure 404 {object} map[string]string "Коллекция не найдена"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера или ошибка File Storing Service"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Failure 401 {object} map[string]string "Не передан или неверен API-ключ или токен"
+// @Router /collections/{id} [delete]
+func (h *ProxyHandler) DeleteCollection(c *gin.Context) {
+	h.proxyRequest(c, h.FileStoringServiceAddr, "/api/v1/collections/"+c.Param("id"))
+}
+
+// @Summary Прокси для добавления файла в коллекцию
+// @Description Перенаправляет запрос на добавление ранее загруженного файла в коллекцию в File Storing Service.
+// @Description Файл, входивший в другую коллекцию, переносится в эту.
+// @Tags collections
+// @Param id path string true "ID коллекции"
+// @Param file_id path string true "ID файла"
+// @Produce json
+// @Success 200 {object} map[string]any "Метаданные файла"
+// @Failure 400 {object} map[string]string "Файл и коллекция принадлежат разным пользователям"
+// @Failure 404 {object} map[string]string "Коллекция или файл не найдены"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера или ошибка File Storing Service"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Failure 401 {object} map[string]string "Не передан или неверен API-ключ или токен"
+// @Router /collections/{id}/files/{file_id} [put]
+func (h *ProxyHandler) AddCollectionFile(c *gin.Context) {
+	h.proxyRequest(c, h.FileStoringServiceAddr, "/api/v1/collections/"+c.Param("id")+"/files/"+c.Param("file_id"))
+}
+
+// @Summary Прокси для исключения файла из коллекции
+// @Description Перенаправляет запрос на исключение файла из коллекции в File Storing Service. Сам файл не удаляется.
+// @Tags collections
+// @Param id path string true "ID коллекции"
+// @Param file_id path string true "ID файла"
+// @Success 204 {string} string "Файл исключен из коллекции"
+// @Failure 404 {object} map[string]string "Коллекция не найдена или файл не входит в нее"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера или ошибка File Storing Service"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Failure 401 {object} map[string]string "Не передан или неверен API-ключ или токен"
+// @Router /collections/{id}/files/{file_id} [delete]
+func (h *ProxyHandler) RemoveCollectionFile(c *gin.Context) {
+	h.proxyRequest(c, h.FileStoringServiceAddr, "/api/v1/collections/"+c.Param("id")+"/files/"+c.Param("file_id"))
+}
+
+// @Summary Прокси для получения объема файлов и квоты пользователя
+// @Description Перенаправляет запрос на получение суммарного размера файлов пользователя, его квоты и максимального размера файла в File Storing Service.
+// @Tags files
+// @Param owner_id query string false "ID пользователя (только для администратора)"
+// @Produce json
+// @Success 200 {object} map[string]any "Объем файлов и квота (owner_id, used_bytes, quota_bytes, max_file_size)"
+// @Failure 403 {object} map[string]string "Сведения о другом пользователе доступны только администратору"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера или ошибка File Storing Service"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Failure 401 {object} map[string]string "Не передан или неверен API-ключ или токен"
+// @Router /usage [get]
+func (h *ProxyHandler) GetStorageUsage(c *gin.Context) {
+	h.proxyRequest(c, h.FileStoringServiceAddr, "/api/v1/usage")
+}
+
+// @Summary Прокси для полнотекстового поиска по файлам
+// @Description Перенаправляет поисковый запрос в File Storing Service. Возвращает файлы, извлеченный текст которых соответствует запросу,
+// @Description в порядке убывания релевантности с фрагментами текста, где совпадения выделены тегом <mark>.
+// @Description Синтаксис запроса: слова через пробел должны встречаться все; "фраза в кавычках" — слова подряд; слово* — слова с таким началом;
+// @Description -слово — исключить; OR — любое из условий. Пользователь ищет только среди своих файлов, администратор — среди всех.
+// @Tags search
+// @Param q query string true "Поисковый запрос"
+// @Param owner_id query string false "ID владельца (другого пользователя — только для администратора)"
+// @Param limit query int false "Количество файлов на странице (по умолчанию 20, не более 50)"
+// @Param offset query int false "Количество пропускаемых файлов (по умолчанию 0)"
+// @Produce json
+// @Success 200 {object} map[string]any "Страница найденных файлов: items (file_id, name, rank, snippet, ...), total, next"
+// @Failure 400 {object} map[string]string "Некорректный запрос"
+// @Failure 403 {object} map[string]string "Файлы другого пользователя доступны только администратору"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера или ошибка File Storing Service"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Failure 401 {object} map[string]string "Не передан или неверен API-ключ или токен"
+// @Router /search [get]
+func (h *ProxyHandler) Search(c *gin.Context) {
+	h.proxyRequest(c, h.FileStoringServiceAddr, "/api/v1/search")
+}
+
+// @Summary Прокси для создания сессии возобновляемой загрузки
+// @Description Перенаправляет запрос на создание сессии возобновляемой загрузки в File Storing Service. Файл затем загружается частями
+// @Description запросами PATCH /uploads/{id} и сохраняется запросом POST /uploads/{id}/complete.
+// @Tags uploads
+// @Accept json
+// @Param request body object true "Имя, размер и SHA-256 хеш файла и необязательный ID коллекции (name, size, sha256, collection_id)"
+// @Produce json
+// @Success 201 {object} map[string]any "Созданная сессия загрузки (id, name, size, offset, sha256, expires_at)"
+// @Failure 400 {object} map[string]string "Некорректный запрос или неподдерживаемый формат файла"
+// @Failure 413 {object} map[string]string "Файл больше допустимого размера или превышена квота хранилища"
+// @Failure 429 {object} map[string]string "Слишком много незавершенных сессий загрузки"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера или ошибка File Storing Service"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Failure 401 {object} map[string]string "Не передан или неверен API-ключ или токен"
+// @Router /uploads [post]
+func (h *ProxyHandler) CreateUploadSession(c *gin.Context) {
+	h.proxyRequest(c, h.FileStoringServiceAddr, "/api/v1/uploads")
+}
+
+// @Summary Прокси для получения состояния сессии загрузки
+// @Description Перенаправляет запрос состояния сессии загрузки в File Storing Service. Количество полученных байт, с которого нужно
+// @Description продолжить загрузку, возвращается в поле offset и в заголовке Upload-Offset (достаточно запроса HEAD).
+// @Tags uploads
+// @Param id path string true "ID сессии загрузки"
+// @Produce json
+// @Success 200 {object} map[string]any "Сессия загрузки (id, name, size, offset, sha256, expires_at)"
+// @Failure 404 {object} map[string]string "Сессия загрузки не найдена"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера или ошибка File Storing Service"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Failure 401 {object} map[string]string "Не передан или неверен API-ключ или токен"
+// @Router /uploads/{id} [get]
+// @Router /uploads/{id} [head]
+func (h *ProxyHandler) GetUploadSession(c *gin.Context) {
+	h.proxyRequest(c, h.FileStoringServiceAddr, "/api/v1/uploads/"+c.Param("id"))
+}
+
+// @Summary Прокси для загрузки части файла
+// @Description Передает часть файла в File Storing Service; часть больше MAX_UPLOAD_SIZE отклоняется с кодом 413. Заголовок Upload-Offset должен совпадать с количеством уже полученных байт;
+// @Description новое смещение возвращается в заголовке Upload-Offset.
+// @Tags uploads
+// @Accept application/offset+octet-stream
+// @Param id path string true "ID сессии загрузки"
+// @Param Upload-Offset header int true "Смещение части в файле"
+// @Success 204 {string} string "Часть файла сохранена"
+// @Failure 400 {object} map[string]string "Некорректный заголовок Upload-Offset"
+// @Failure 404 {object} map[string]string "Сессия загрузки не найдена"
+// @Failure 409 {object} map[string]string "Смещение не совпадает с количеством полученных байт или в сессию уже загружается другая часть"
+// @Failure 413 {object} map[string]string "Часть выходит за пределы размера файла или больше MAX_UPLOAD_SIZE"
+// @Failure 415 {object} map[string]string "Тип содержимого не application/offset+octet-stream"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера или ошибка File Storing Service"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Failure 401 {object} map[string]string "Не передан или неверен API-ключ или токен"
+// @Router /uploads/{id} [patch]
+func (h *ProxyHandler) UploadChunk(c *gin.Context) {
+	message := fmt.Sprintf("Chunk is too large: the maximum size is %d bytes", h.MaxUploadSize)
+	if !limitBody(c, h.MaxUploadSize, message) {
+		return
+	}
+	h.proxyRequest(c, h.FileStoringServiceAddr, "/api/v1/uploads/"+c.Param("id"))
+}
+
+// @Summary Прокси для завершения возобновляемой загрузки
+// @Description Перенаправляет запрос на завершение загрузки в File Storing Service: проверяется SHA-256 хеш полученного файла,
+// @Description после чего файл сохраняется так же, как при POST /upload.
+// @Tags uploads
+// @Param id path string true "ID сессии загрузки"
+// @Produce json
+// @Success 201 {object} map[string]any "ID сохраненного файла, хеш, MIME-тип, экстрактор и сведения о дубликате"
+// @Failure 400 {object} map[string]string "Хеш файла не совпадает, ошибка валидации или извлечения текста"
+// @Failure 404 {object} map[string]string "Сессия загрузки не найдена"
+// @Failure 409 {object} map[string]string "Файл получен не полностью или сессия занята"
+// @Failure 413 {object} map[string]string "Превышена квота хранилища"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера или ошибка File Storing Service"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Failure 401 {object} map[string]string "Не передан или неверен API-ключ или токен"
+// @Router /uploads/{id}/complete [post]
+func (h *ProxyHandler) CompleteUpload(c *gin.Context) {
+	h.proxyRequest(c, h.FileStoringServiceAddr, "/api/v1/uploads/"+c.Param("id")+"/complete")
+}
+
+// @Summary Прокси для отмены возобновляемой загрузки
+// @Description Перенаправляет запрос на удаление сессии загрузки и полученных частей файла в File Storing Service.
+// @Tags uploads
+// @Param id path string true "ID сессии загрузки"
+// @Success 204 {string} string "Сессия загрузки удалена"
+// @Failure 404 {object} map[string]string "Сессия загрузки не найдена"
+// @Failure 409 {object} map[string]string "В сессию загружается часть файла"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера или ошибка File Storing Service"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Failure 401 {object} map[string]string "Не передан или неверен API-ключ или токен"
+// @Router /uploads/{id} [delete]
+func (h *ProxyHandler) CancelUpload(c *gin.Context) {
+	h.proxyRequest(c, h.FileStoringServiceAddr, "/api/v1/uploads/"+c.Param("id"))
+}
+
 // @Summary Прокси для анализа файла (Сценарий 2)
-// @Description Перенаправляет запрос на анализ файла в File Analysis Service.
+// @Description Перенаправляет запрос на анализ файла в File Analysis Service. Если есть результат анализа текущей версии алгоритмов, он переиспользуется;
+// @Description force=true заставляет проанализировать файл заново.
 // @Tags analysis
 // @Param file_id path string true "ID файла для анализа"
+// @Param force query bool false "Выполнить анализ заново, даже если есть результат текущей версии"
+// @Param callback_url query string false "URL, на который по завершении задачи будет отправлено подписанное уведомление с результатом анализа или причиной неудачи"
 // @Produce json
-// @Success 202 {object} map[string]string "Сообщение о принятии запроса на анализ"
+// @Success 202 {object} map[string]any "Сообщение о принятии запроса на анализ, ID и состояние задачи (job_id, status, force, callback_url)"
 // @Failure 400 {object} map[string]string "Ошибка запроса"
 // @Failure 500 {object} map[string]string "Внутренняя ошибка сервера или ошибка File Analysis Service"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Failure 401 {object} map[string]string "Не передан или неверен API-ключ или токен"
 // @Router /analysis/{file_id} [post]
 func (h *ProxyHandler) RequestAnalysis(c *gin.Context) {
 	// file_id извлекается из пути в proxyRequest
 	h.proxyRequest(c, h.FileAnalysisServiceAddr, "/api/v1/analysis/"+c.Param("file_id"))
 }
 
+// @Summary Прокси для получения состояния задачи анализа
+// @Description Перенаправляет запрос на получение состояния задачи анализа в File Analysis Service.
+// @Tags analysis
+// @Param id path int true "ID задачи анализа"
+// @Produce json
+// @Success 200 {object} map[string]any "Состояние задачи (id, file_id, status, attempts, last_error, ...)"
+// @Failure 404 {object} map[string]string "Задача не найдена"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера или ошибка File Analysis Service"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Failure 401 {object} map[string]string "Не передан или неверен API-ключ или токен"
+// @Router /analysis/jobs/{id} [get]
+func (h *ProxyHandler) GetAnalysisJob(c *gin.Context) {
+	h.proxyRequest(c, h.FileAnalysisServiceAddr, "/api/v1/analysis/jobs/"+c.Param("id"))
+}
+
+// @Summary Прокси для потока событий анализа файла
+// @Description Перенаправляет запрос на поток Server-Sent Events о ходе анализа файла в File Analysis Service и передает события клиенту по мере получения.
+// @Description Первое событие — текущее состояние последней задачи анализа (queued, started, retrying, saved, failed или cancelled), затем этапы: started, fetched, counted, wordcloud, saved; retrying и failed при ошибках, cancelled, если файл удален во время анализа.
+// @Description Поток закрывается после saved, failed или cancelled.
+// @Tags analysis
+// @Param file_id path string true "ID файла"
+// @Produce text/event-stream
+// @Success 200 {string} string "Поток событий (event: этап, data: JSON с полями stage, file_id, job_id, time, attempt, word_count, result_id, word_cloud_location, word_cloud_svg_location, error, next_run_at)"
+// @Failure 404 {object} map[string]string "Файл не найден или не анализировался"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера или ошибка File Analysis Service"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Failure 401 {object} map[string]string "Не передан или неверен API-ключ или токен"
+// @Router /analysis/{file_id}/events [get]
+func (h *ProxyHandler) StreamAnalysisEvents(c *gin.Context) {
+	h.proxyRequest(c, h.FileAnalysisServiceAddr, "/api/v1/analysis/"+c.Param("file_id")+"/events")
+}
+
 // @Summary Прокси для получения результатов анализа файла (Сценарий 2)
 // @Description Перенаправляет запрос на получение результатов анализа в File Analysis Service.
 // @Tags analysis
 // @Param file_id path string true "ID файла"
+// @Param result_id query int false "ID результата анализа из истории (по умолчанию последний)"
 // @Produce json
-// @Success 200 {object} map[string]any "Результаты анализа (file_id, paragraph_count, word_count, character_count)"
+// @Success 200 {object} map[string]any "Статистика текста (id, created_at, analyzer_version, file_id, language, paragraph_count, line_count, sentence_count, word_count, unique_word_count, character_count, character_count_with_spaces, average_word_length, average_sentence_length, lexical_density)"
 // @Failure 404 {object} map[string]string "Результаты анализа не найдены"
 // @Failure 500 {object} map[string]string "Внутренняя ошибка сервера или ошибка File Analysis Service"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Failure 401 {object} map[string]string "Не передан или неверен API-ключ или токен"
 // @Router /analysis/results/{file_id} [get]
 func (h *ProxyHandler) GetAnalysisResults(c *gin.Context) {
 	h.proxyRequest(c, h.FileAnalysisServiceAddr, "/api/v1/analysis/results/"+c.Param("file_id"))
 }
 
+// @Summary Прокси для получения частот слов файла
+// @Description Перенаправляет запрос на получение таблицы частот слов файла в File Analysis Service.
+// @Tags analysis
+// @Param file_id path string true "ID файла"
+// @Param limit query int false "Максимальное количество слов (по умолчанию 100, не более 1000)"
+// @Param offset query int false "Сколько слов пропустить (по умолчанию 0)"
+// @Param stopwords query bool false "Включать ли служебные слова (по умолчанию false)"
+// @Param lemmatize query bool false "Объединять ли формы одного слова (по умолчанию false)"
+// @Param format query string false "Формат ответа: json (по умолчанию) или csv" Enums(json, csv)
+// @Param result_id query int false "ID результата анализа из истории (по умолчанию последний)"
+// @Produce json
+// @Produce text/csv
+// @Success 200 {object} map[string]any "Частоты слов (file_id, result_id, language, word_count, total, limit, offset, include_stop_words, lemmatized, items: word, count, frequency)"
+// @Failure 400 {object} map[string]string "Некорректные параметры запроса"
+// @Failure 404 {object} map[string]string "Результаты анализа не найдены"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера или ошибка File Analysis Service"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Failure 401 {object} map[string]string "Не передан или неверен API-ключ или токен"
+// @Router /analysis/results/{file_id}/frequencies [get]
+func (h *ProxyHandler) GetWordFrequencies(c *gin.Context) {
+	h.proxyRequest(c, h.FileAnalysisServiceAddr, "/api/v1/analysis/results/"+c.Param("file_id")+"/frequencies")
+}
+
+// @Summary Прокси для получения отчета о совпадающих фрагментах файла
+// @Description Перенаправляет запрос отчета о заимствованиях в File Analysis Service. Отчет перечисляет фрагменты файла, совпадающие с фрагментами
+// @Description других проанализированных файлов: смещения в файле и в файле-источнике (в символах Unicode), ID источника и длину совпадения в словах.
+// @Description С format=html возвращается страница с текстом файла, в котором совпадающие фрагменты выделены цветом.
+// @Tags analysis
+// @Param file_id path string true "ID файла"
+// @Param format query string false "Формат отчета (по умолчанию json)" Enums(json, html)
+// @Param min_words query int false "Наименьшая длина фрагмента в словах (по умолчанию 8)"
+// @Param limit query int false "Сколько самых длинных фрагментов включить в отчет (по умолчанию 500, не более 5000)"
+// @Produce json
+// @Produce html
+// @Success 200 {object} map[string]any "Отчет (file_id, word_count, matched_words, matched_percent, sources, passages) или HTML-страница"
+// @Failure 400 {object} map[string]string "Некорректные параметры запроса"
+// @Failure 404 {object} map[string]string "Файл еще не анализировался"
+// @Failure 409 {object} map[string]string "Файл проанализирован предыдущей версией алгоритмов; нужен повторный анализ"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера или ошибка File Analysis Service"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Failure 401 {object} map[string]string "Не передан или неверен API-ключ или токен"
+// @Router /analysis/results/{file_id}/passages [get]
+func (h *ProxyHandler) GetPassageReport(c *gin.Context) {
+	h.proxyRequest(c, h.FileAnalysisServiceAddr, "/api/v1/analysis/results/"+c.Param("file_id")+"/passages")
+}
+
+// @Summary Прокси для получения истории анализов файла
+// @Description Перенаправляет запрос на получение всех результатов анализа файла (от нового к старому) в File Analysis Service.
+// @Tags analysis
+// @Param file_id path string true "ID файла"
+// @Produce json
+// @Success 200 {object} map[string]any "История анализов (file_id, analyzer_version, results)"
+// @Failure 404 {object} map[string]string "Файл еще не анализировался"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера или ошибка File Analysis Service"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Failure 401 {object} map[string]string "Не передан или неверен API-ключ или токен"
+// @Router /analysis/results/{file_id}/history [get]
+func (h *ProxyHandler) GetAnalysisHistory(c *gin.Context) {
+	h.proxyRequest(c, h.FileAnalysisServiceAddr, "/api/v1/analysis/results/"+c.Param("file_id")+"/history")
+}
+
+// @Summary Прокси для получения похожих файлов
+// @Description Перенаправляет запрос на получение файлов, наиболее похожих на указанный, в File Analysis Service.
+// @Tags analysis
+// @Param file_id path string true "ID файла"
+// @Produce json
+// @Success 200 {object} map[string]any "Похожие файлы (file_id, matches: file_id, score, percent, description)"
+// @Failure 404 {object} map[string]string "Файл еще не анализировался"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера или ошибка File Analysis Service"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Failure 401 {object} map[string]string "Не передан или неверен API-ключ или токен"
+// @Router /analysis/similarity/{file_id} [get]
+func (h *ProxyHandler) GetSimilarFiles(c *gin.Context) {
+	h.proxyRequest(c, h.FileAnalysisServiceAddr, "/api/v1/analysis/similarity/"+c.Param("file_id"))
+}
+
+// @Summary Прокси для сравнения файлов коллекции
+// @Description Перенаправляет запрос на попарное сравнение проанализированных файлов коллекции в File Analysis Service.
+// @Description Возвращаются пары с оценкой сходства не ниже min_score в порядке убывания сходства.
+// @Tags analysis
+// @Param collection_id path string true "ID коллекции"
+// @Param min_score query number false "Наименьшая оценка сходства от 0 до 1 (по умолчанию 0.3)"
+// @Param limit query int false "Максимальное количество пар (по умолчанию 100, не более 1000)"
+// @Produce json
+// @Success 200 {object} map[string]any "Подозрительные пары (collection_id, file_count, analyzed_count, not_analyzed, pairs_compared, suspicious_count, pairs)"
+// @Failure 400 {object} map[string]string "Некорректные параметры запроса"
+// @Failure 404 {object} map[string]string "Коллекция не найдена"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера или ошибка File Analysis Service"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Failure 401 {object} map[string]string "Не передан или неверен API-ключ или токен"
+// @Router /analysis/collections/{collection_id}/similarity [get]
+func (h *ProxyHandler) GetCollectionSimilarity(c *gin.Context) {
+	h.proxyRequest(c, h.FileAnalysisServiceAddr, "/api/v1/analysis/collections/"+c.Param("collection_id")+"/similarity")
+}
+
+// @Summary Прокси для получения настроек уведомлений
+// @Description Перенаправляет запрос на получение URL уведомлений о завершении анализа и секрета подписи пользователя в File Analysis Service.
+// @Tags webhooks
+// @Produce json
+// @Success 200 {object} map[string]any "Настройки уведомлений (owner_id, url, secret, created_at, updated_at)"
+// @Failure 404 {object} map[string]string "Уведомления не настроены"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера или ошибка File Analysis Service"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Failure 401 {object} map[string]string "Не передан или неверен API-ключ или токен"
+// @Router /analysis/webhook [get]
+func (h *ProxyHandler) GetWebhook(c *gin.Context) {
+	h.proxyRequest(c, h.FileAnalysisServiceAddr, "/api/v1/analysis/webhook")
+}
+
+// @Summary Прокси для изменения настроек уведомлений
+// @Description Перенаправляет запрос на изменение URL уведомлений о завершении анализа файлов пользователя в File Analysis Service.
+// @Description Пустой url отключает уведомления обо всех файлах; rotate_secret=true заменяет секрет подписи.
+// @Tags webhooks
+// @Accept json
+// @Param request body object true "URL уведомлений (url, rotate_secret)"
+// @Produce json
+// @Success 200 {object} map[string]any "Настройки уведомлений (owner_id, url, secret, created_at, updated_at)"
+// @Failure 400 {object} map[string]string "Некорректный запрос или URL"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера или ошибка File Analysis Service"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Failure 401 {object} map[string]string "Не передан или неверен API-ключ или токен"
+// @Router /analysis/webhook [put]
+func (h *ProxyHandler) SetWebhook(c *gin.Context) {
+	h.proxyRequest(c, h.FileAnalysisServiceAddr, "/api/v1/analysis/webhook")
+}
+
+// @Summary Прокси для удаления настроек уведомлений
+// @Description Перенаправляет запрос на удаление URL и секрета уведомлений пользователя в File Analysis Service. Недоставленные уведомления не отправляются.
+// @Tags webhooks
+// @Success 204 {string} string "Настройки удалены"
+// @Failure 404 {object} map[string]string "Уведомления не настроены"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера или ошибка File Analysis Service"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Failure 401 {object} map[string]string "Не передан или неверен API-ключ или токен"
+// @Router /analysis/webhook [delete]
+func (h *ProxyHandler) DeleteWebhook(c *gin.Context) {
+	h.proxyRequest(c, h.FileAnalysisServiceAddr, "/api/v1/analysis/webhook")
+}
+
+// @Summary Прокси для получения истории доставки уведомлений
+// @Description Перенаправляет запрос на получение последних уведомлений пользователя о завершении анализа в File Analysis Service.
+// @Tags webhooks
+// @Param limit query int false "Количество уведомлений (по умолчанию 20, не более 100)"
+// @Produce json
+// @Success 200 {array} map[string]any "Уведомления (id, event_id, type, job_id, file_id, url, attempts, next_attempt_at, delivered_at, failed_at, last_error)"
+// @Failure 400 {object} map[string]string "Некорректный параметр limit"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера или ошибка File Analysis Service"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Failure 401 {object} map[string]string "Не передан или неверен API-ключ или токен"
+// @Router /analysis/webhook/deliveries [get]
+func (h *ProxyHandler) ListWebhookDeliveries(c *gin.Context) {
+	h.proxyRequest(c, h.FileAnalysisServiceAddr, "/api/v1/analysis/webhook/deliveries")
+}
+
 // @Summary Прокси для получения файла (Сценарий 3)
-// @Description Перенаправляет запрос на получение файла в File Storing Service.
+// @Description Перенаправляет запрос на получение исходного файла в File Storing Service.
 // @Tags files
 // @Param id path string true "ID файла"
 // @Produce plain
-// @Success 200 {string} string "Содержимое файла"
+// @Produce octet-stream
+// @Success 200 {file} file "Содержимое файла"
 // @Failure 404 {object} map[string]string "Файл не найден"
 // @Failure 500 {object} map[string]string "Внутренняя ошибка сервера или ошибка File Storing Service"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Failure 401 {object} map[string]string "Не передан или неверен API-ключ или токен"
 // @Router /files/{id} [get]
 func (h *ProxyHandler) GetFileByID(c *gin.Context) {
 	h.proxyRequest(c, h.FileStoringServiceAddr, "/api/v1/files/"+c.Param("id"))
 }
 
+// @Summary Прокси для удаления файла
+// @Description Перенаправляет запрос на удаление файла в File Storing Service. Файл помечается удаленным, его результаты анализа удаляются,
+// @Description а содержимое удаляется из хранилища по истечении срока хранения.
+// @Tags files
+// @Param id path string true "ID файла"
+// @Produce json
+// @Success 202 {object} map[string]any "Файл помечен удаленным (id, deleted_at, purge_after)"
+// @Failure 404 {object} map[string]string "Файл не найден"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера или ошибка File Storing Service"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Failure 401 {object} map[string]string "Не передан или неверен API-ключ или токен"
+// @Router /files/{id} [delete]
+func (h *ProxyHandler) DeleteFile(c *gin.Context) {
+	h.proxyRequest(c, h.FileStoringServiceAddr, "/api/v1/files/"+c.Param("id"))
+}
+
+// @Summary Прокси для получения текста файла
+// @Description Перенаправляет запрос на получение текста, извлеченного из файла, в File Storing Service.
+// @Tags files
+// @Param id path string true "ID файла"
+// @Produce plain
+// @Success 200 {string} string "Текст файла"
+// @Failure 404 {object} map[string]string "Файл не найден"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера или ошибка File Storing Service"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Failure 401 {object} map[string]string "Не передан или неверен API-ключ или токен"
+// @Router /files/{id}/text [get]
+func (h *ProxyHandler) GetFileText(c *gin.Context) {
+	h.proxyRequest(c, h.FileStoringServiceAddr, "/api/v1/files/"+c.Param("id")+"/text")
+}
+
 // @Summary Прокси для получения облака слов (Сценарий 4)
 // @Description Перенаправляет запрос на получение облака слов в File Analysis Service.
 // @Tags analysis
@@ -101,6 +687,9 @@ func (h *ProxyHandler) GetFileByID(c *gin.Context) {
 // @Failure 400 {object} map[string]string "Параметр location не указан"
 // @Failure 404 {object} map[string]string "Облако слов не найдено"
 // @Failure 500 {object} map[string]string "Внутренняя ошибка сервера или ошибка File Analysis Service"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Failure 401 {object} map[string]string "Не передан или неверен API-ключ или токен"
 // @Router /analysis/wordclouds [get]
 func (h *ProxyHandler) GetWordCloud(c *gin.Context) {
 	// location передается как query параметр, proxyRequest это учтет
@@ -108,22 +697,56 @@ func (h *ProxyHandler) GetWordCloud(c *gin.Context) {
 }
 
 // @Summary Прокси для получения списка всех файлов (дополнительно)
-// @Description Перенаправляет запрос на получение списка всех файлов в File Storing Service.
+// @Description Перенаправляет запрос на получение списка файлов в File Storing Service. Пользователь получает только свои файлы, администратор — все.
+// @Description Список возвращается постранично: ссылка на следующую страницу возвращается в поле next, на последней странице ее нет.
 // @Tags files
+// @Param limit query int false "Количество файлов на странице (по умолчанию 50, не более 500)"
+// @Param cursor query string false "Курсор следующей страницы из поля next_cursor"
+// @Param sort query string false "Поле сортировки (по умолчанию created_at)" Enums(created_at, name, size)
+// @Param order query string false "Порядок сортировки (по умолчанию desc)" Enums(asc, desc)
+// @Param name query string false "Подстрока имени файла (без учета регистра)"
+// @Param created_after query string false "Файлы, загруженные не раньше этого времени (RFC 3339)"
+// @Param created_before query string false "Файлы, загруженные раньше этого времени (RFC 3339)"
+// @Param owner_id query string false "ID владельца (другого пользователя — только для администратора)"
+// @Param has_analysis query bool false "Только проанализированные (true) или непроанализированные (false) файлы"
+// @Param collection_id query string false "ID коллекции"
+// @Param batch_id query string false "ID пакета"
 // @Produce json
-// @Success 200 {array} map[string]any "Список файлов (каждый элемент с id, name, location)"
+// @Success 200 {object} map[string]any "Страница списка файлов: items, total, next_cursor, next"
+// @Failure 400 {object} map[string]string "Некорректные параметры запроса"
+// @Failure 403 {object} map[string]string "Файлы другого пользователя доступны только администратору"
 // @Failure 500 {object} map[string]string "Внутренняя ошибка сервера или ошибка File Storing Service"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Failure 401 {object} map[string]string "Не передан или неверен API-ключ или токен"
 // @Router /files [get]
 func (h *ProxyHandler) ListFiles(c *gin.Context) {
 	h.proxyRequest(c, h.FileStoringServiceAddr, "/api/v1/files")
 }
 
-// @Summary Прокси для получения списка всех результатов анализа (дополнительно)
-// @Description Перенаправляет запрос на получение списка всех результатов анализа в File Analysis Service.
+// @Summary Прокси для получения списка результатов анализа (дополнительно)
+// @Description Перенаправляет запрос на получение списка результатов анализа в File Analysis Service. Пользователь видит только результаты
+// @Description анализа своих файлов, администратор — всех файлов или файлов владельца owner_id.
+// @Description Список возвращается постранично: ссылка на следующую страницу возвращается в поле next, на последней странице ее нет.
 // @Tags analysis
+// @Param limit query int false "Количество результатов на странице (по умолчанию 50, не более 500)"
+// @Param cursor query string false "Курсор следующей страницы из поля next_cursor"
+// @Param sort query string false "Поле сортировки (по умолчанию created_at)" Enums(created_at, file_id, word_count)
+// @Param order query string false "Порядок сортировки (по умолчанию desc)" Enums(asc, desc)
+// @Param file_id query string false "ID файла"
+// @Param owner_id query string false "ID владельца файла (другого пользователя — только для администратора)"
+// @Param language query string false "Код языка текста (ISO 639-1 или und)"
+// @Param analyzer_version query string false "Версия алгоритмов анализа"
+// @Param created_after query string false "Результаты, полученные не раньше этого времени (RFC 3339)"
+// @Param created_before query string false "Результаты, полученные раньше этого времени (RFC 3339)"
 // @Produce json
-// @Success 200 {array} map[string]any "Список результатов анализа (каждый элемент с file_id, paragraph_count, etc.)"
+// @Success 200 {object} map[string]any "Страница списка результатов анализа: items, total, next_cursor, next"
+// @Failure 400 {object} map[string]string "Некорректные параметры запроса"
+// @Failure 403 {object} map[string]string "Результаты анализа файлов другого пользователя доступны только администратору"
 // @Failure 500 {object} map[string]string "Внутренняя ошибка сервера или ошибка File Analysis Service"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Failure 401 {object} map[string]string "Не передан или неверен API-ключ или токен"
 // @Router /analysis/results-all [get]
 func (h *ProxyHandler) ListAnalysisResults(c *gin.Context) {
 	h.proxyRequest(c, h.FileAnalysisServiceAddr, "/api/v1/analysis/results-all")
@@ -138,69 +761,21 @@ func (h *ProxyHandler) proxyRequest(c *gin.Context, targetServiceBaseURL, target
 	targetURL.Path = targetPath
 	targetURL.RawQuery = c.Request.URL.RawQuery
 
-	var reqBody io.Reader
-	contentType := c.Request.Header.Get("Content-Type")
-
-	// Специальная обработка для multipart/form-data (загрузка файлов)
-	if strings.HasPrefix(contentType, "multipart/form-data") {
-		file, header, err := c.Request.FormFile("file")
-		if err != nil {
-			// Проверяем, может это другой multipart запрос без файла "file"
-			if err == http.ErrMissingFile {
-				// Если это не загрузка файла, а другой multipart, пытаемся проксировать как есть
-				// Это может быть полезно, если File Storing Service ожидает другие поля multipart
-				// В данном случае, мы знаем, что /upload ожидает "file", так что это больше для общности
-				if c.Request.Body != nil {
-					bodyBytes, _ := ioutil.ReadAll(c.Request.Body)
-					c.Request.Body = ioutil.NopCloser(bytes.NewBuffer(bodyBytes)) // Восстанавливаем тело для Gin
-					reqBody = bytes.NewBuffer(bodyBytes)                          // Используем для прокси-запроса
-				} else {
-					reqBody = nil
-				}
-			} else {
-				c.JSON(http.StatusBadRequest, gin.H{"error": "Error processing form file: " + err.Error()})
-				return
-			}
-		} else {
-			defer file.Close()
-
-			body := &bytes.Buffer{}
-			writer := multipart.NewWriter(body)
-			part, err := writer.CreateFormFile("file", header.Filename)
-			if err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating form file for proxy: " + err.Error()})
-				return
-			}
-			_, err = io.Copy(part, file)
-			if err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Error copying file data for proxy: " + err.Error()})
-				return
-			}
-			// Копирование других полей формы, если они есть
-			for key, values := range c.Request.MultipartForm.Value {
-				for _, value := range values {
-					_ = writer.WriteField(key, value)
-				}
-			}
-			err = writer.Close()
-			if err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Error closing multipart writer for proxy: " + err.Error()})
-				return
-			}
-			reqBody = body
-			contentType = writer.FormDataContentType()
+	// Эндпоинты загрузки ограничивают тело сами (см. limitBody), остальные — лимитом maxRequestBodySize
+	if _, limited := c.Get(bodyTooLargeKey); !limited {
+		message := fmt.Sprintf("Request body is too large: the maximum size is %d bytes", maxRequestBodySize)
+		if !limitBody(c, maxRequestBodySize, message) {
+			return
 		}
-	} else if c.Request.Body != nil {
-		bodyBytes, _ := ioutil.ReadAll(c.Request.Body)
-		c.Request.Body = ioutil.NopCloser(bytes.NewBuffer(bodyBytes)) // Восстанавливаем тело, чтобы Gin мог его прочитать, если нужно
-		reqBody = bytes.NewBuffer(bodyBytes)
 	}
 
-	req, err := http.NewRequest(c.Request.Method, targetURL.String(), reqBody)
+	// Запрос к сервису отменяется, если клиент закрыл соединение (например, перестал читать поток событий)
+	req, err := http.NewRequestWithContext(c.Request.Context(), c.Request.Method, targetURL.String(), c.Request.Body)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating proxy request: " + err.Error()})
 		return
 	}
+	req.ContentLength = c.Request.ContentLength // -1, если размер тела неизвестен: тело передается по частям
 
 	// Копируем заголовки, кроме Host, так как он устанавливается транспортным уровнем
 	for k, v := range c.Request.Header {
@@ -208,14 +783,29 @@ func (h *ProxyHandler) proxyRequest(c *gin.Context, targetServiceBaseURL, target
 			req.Header[k] = v
 		}
 	}
-	// Устанавливаем Content-Type, если он был изменен (например, для multipart)
-	if contentType != "" {
-		req.Header.Set("Content-Type", contentType)
+	// Сервисы доверяют пользователю из X-User-ID и X-User-Role, только если он подписан вместе с телом запроса.
+	// Для подписи тело (в том числе multipart/form-data при загрузке файла) читается целиком: большое — во временный файл
+	if identity, ok := auth.FromHeaders(c.Request.Header); ok {
+		if err := h.Signer.SignIdentity(req, identity); err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": c.GetString(bodyTooLargeKey)})
+				return
+			}
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Error reading request body: " + err.Error()})
+			return
+		}
 	}
 
 	client := &http.Client{}
 	resp, err := client.Do(req)
 	if err != nil {
+		// Тело запроса оказалось больше лимита (см. limitBody)
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": c.GetString(bodyTooLargeKey)})
+			return
+		}
 		// Проверка на ошибку подключения (например, сервис упал)
 		if os.IsTimeout(err) || strings.Contains(err.Error(), "connect: connection refused") || strings.Contains(err.Error(), "no such host") {
 			c.JSON(http.StatusBadGateway, gin.H{"error": fmt.Sprintf("Service %s is unavailable: %s", targetServiceBaseURL, err.Error())})
@@ -232,11 +822,42 @@ func (h *ProxyHandler) proxyRequest(c *gin.Context, targetServiceBaseURL, target
 	}
 
 	// Копируем тело ответа
-	// Используем io.Copy для эффективности, особенно для больших ответов (например, файлов)
+	// Используем io.Copy для эффективности, особенно для больших ответов (например, файлов).
+	// Поток событий (Server-Sent Events) передается клиенту по мере получения, без буферизации
 	c.Status(resp.StatusCode)
-	_, err = io.Copy(c.Writer, resp.Body)
-	if err != nil {
+	if isStreamingResponse(resp) {
+		err = copyFlushing(c.Writer, resp.Body)
+	} else {
+		_, err = io.Copy(c.Writer, resp.Body)
+	}
+	if err != nil && c.Request.Context().Err() == nil {
 		// Если уже начали писать ответ, сложно что-то сделать, кроме как логировать
 		log.Printf("Error copying response body to client: %v", err)
 	}
 }
+
+// isStreamingResponse сообщает, нужно ли передавать ответ клиенту по частям сразу по получении.
+func isStreamingResponse(resp *http.Response) bool {
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	return mediaType == "text/event-stream"
+}
+
+// copyFlushing копирует тело ответа клиенту, отправляя каждую полученную часть сразу, а не по заполнении буфера.
+func copyFlushing(w gin.ResponseWriter, body io.Reader) error {
+	buf := make([]byte, 4096)
+	for {
+		n, err := body.Read(buf)
+		if n > 0 {
+			if _, writeErr := w.Write(buf[:n]); writeErr != nil {
+				return writeErr
+			}
+			w.Flush()
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"pkg/auth"
+	"pkg/limits"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+var testServiceSecret = []byte("test-service-secret")
+
+// testBackend — сервис за API Gateway: проверяет подпись пользователя и запоминает размеры полученных тел.
+type testBackend struct {
+	*httptest.Server
+	received []int
+}
+
+func newTestBackend(t *testing.T) *testBackend {
+	t.Helper()
+	backend := &testBackend{}
+	backend.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(auth.HeaderUserID) != "" {
+			if _, err := auth.VerifyIdentity(r, testServiceSecret, time.Minute); err != nil {
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		backend.received = append(backend.received, len(body))
+		w.WriteHeader(http.StatusCreated)
+	}))
+	t.Cleanup(backend.Close)
+	return backend
+}
+
+// newTestRouter возвращает маршруты загрузки API Gateway, пересылающие запросы в backend.
+func newTestRouter(backend *testBackend, maxUploadSize int64) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	h := NewProxyHandler(backend.URL, backend.URL, auth.ServiceSigner{Service: "api_gateway", Secret: testServiceSecret})
+	h.MaxUploadSize = maxUploadSize
+	r := gin.New()
+	r.POST("/upload", h.UploadFile)
+	r.PATCH("/uploads/:id", h.UploadChunk)
+	r.POST("/collections", h.CreateCollection)
+	return r
+}
+
+// chunkedBody скрывает размер тела, поэтому запрос передается без Content-Length.
+type chunkedBody struct{ io.Reader }
+
+func TestProxyRequestBodyLimits(t *testing.T) {
+	const maxUploadSize int64 = 4 << 10
+	uploadLimit := maxUploadSize + limits.MultipartOverhead
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		size       int64
+		chunked    bool // Тело без Content-Length: лимит срабатывает при чтении тела
+		signed     bool // Запрос от пользователя: тело читается целиком для подписи
+		wantStatus int
+		wantError  string
+	}{
+		{"файл в пределах лимита", http.MethodPost, "/upload", uploadLimit, false, true, http.StatusCreated, ""},
+		{"Content-Length больше лимита файла", http.MethodPost, "/upload", uploadLimit + 1, false, true, http.StatusRequestEntityTooLarge, "File is too large"},
+		{"тело файла без Content-Length при подписи", http.MethodPost, "/upload", uploadLimit + 1, true, true, http.StatusRequestEntityTooLarge, "File is too large"},
+		{"тело файла без Content-Length без подписи", http.MethodPost, "/upload", uploadLimit + 1, true, false, http.StatusRequestEntityTooLarge, "File is too large"},
+		{"часть размером с лимит", http.MethodPatch, "/uploads/s1", maxUploadSize, true, true, http.StatusCreated, ""},
+		{"Content-Length части больше лимита", http.MethodPatch, "/uploads/s1", maxUploadSize + 1, false, true, http.StatusRequestEntityTooLarge, "Chunk is too large"},
+		{"часть без Content-Length больше лимита", http.MethodPatch, "/uploads/s1", maxUploadSize + 1, true, true, http.StatusRequestEntityTooLarge, "Chunk is too large"},
+		{"тело по умолчанию в пределах 1 МБ", http.MethodPost, "/collections", maxRequestBodySize, true, true, http.StatusCreated, ""},
+		{"Content-Length больше 1 МБ", http.MethodPost, "/collections", maxRequestBodySize + 1, false, true, http.StatusRequestEntityTooLarge, "Request body is too large"},
+		{"тело без Content-Length больше 1 МБ", http.MethodPost, "/collections", maxRequestBodySize + 1, true, true, http.StatusRequestEntityTooLarge, "Request body is too large"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := newTestBackend(t)
+			router := newTestRouter(backend, maxUploadSize)
+
+			var body io.Reader = bytes.NewReader(bytes.Repeat([]byte("a"), int(tt.size)))
+			if tt.chunked {
+				body = chunkedBody{body}
+			}
+			req := httptest.NewRequest(tt.method, tt.path, body)
+			if tt.chunked && req.ContentLength != -1 {
+				t.Fatalf("ContentLength %d, ожидался неизвестный размер", req.ContentLength)
+			}
+			if tt.signed {
+				auth.Identity{UserID: "user1", Role: auth.RoleUser}.SetHeaders(req.Header)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("статус %d, ожидался %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantStatus == http.StatusCreated {
+				if len(backend.received) != 1 || int64(backend.received[0]) != tt.size {
+					t.Fatalf("сервис получил тела %v, ожидалось одно тело %d байт", backend.received, tt.size)
+				}
+				return
+			}
+			var response map[string]string
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil || !strings.HasPrefix(response["error"], tt.wantError) {
+				t.Fatalf("ответ %s, ожидалась ошибка %q", w.Body.String(), tt.wantError)
+			}
+			// Подписанный запрос не доходит до сервиса: тело целиком читается до отправки
+			if tt.signed && len(backend.received) != 0 {
+				t.Fatalf("сервис получил тела %v", backend.received)
+			}
+		})
+	}
+}
+
+func TestStreamAnalysisEventsFlushesEachEvent(t *testing.T) {
+	// Сервис анализа отправляет следующее событие, только когда клиент получил предыдущее,
+	// поэтому тест завершится по таймауту, если API Gateway буферизует поток до его закрытия
+	received := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/analysis/f1/events" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, stage := range []string{"fetched", "counted", "wordcloud", "saved"} {
+			io.WriteString(w, "event:"+stage+"\ndata:{\"stage\":\""+stage+"\"}\n\n")
+			w.(http.Flusher).Flush()
+			if stage == "saved" {
+				return
+			}
+			select {
+			case <-received:
+			case <-time.After(10 * time.Second):
+				return
+			}
+		}
+	}))
+	defer upstream.Close()
+
+	gin.SetMode(gin.TestMode)
+	h := NewProxyHandler(upstream.URL, upstream.URL, auth.ServiceSigner{Service: "api_gateway", Secret: testServiceSecret})
+	router := gin.New()
+	router.GET("/analysis/:file_id/events", h.StreamAnalysisEvents)
+	gateway := httptest.NewServer(router)
+	defer gateway.Close()
+
+	client := gateway.Client()
+	client.Timeout = 5 * time.Second
+	resp, err := client.Get(gateway.URL + "/analysis/f1/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("ответ %d, Content-Type %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	reader := bufio.NewReader(resp.Body)
+	var stages []string
+	for {
+		line, err := reader.ReadString('\n')
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("событие после %v не получено до закрытия потока сервисом: %v", stages, err)
+		}
+		if stage := strings.TrimPrefix(strings.TrimSpace(line), "event:"); stage != strings.TrimSpace(line) {
+			stages = append(stages, stage)
+			if stage != "saved" {
+				received <- struct{}{}
+			}
+		}
+	}
+	if want := []string{"fetched", "counted", "wordcloud", "saved"}; strings.Join(stages, ",") != strings.Join(want, ",") {
+		t.Fatalf("события %v, ожидалось %v", stages, want)
+	}
+}
@@ -2,9 +2,14 @@ package main
 
 import (
 	"api_gateway/handlers"
+	"api_gateway/middleware"
 	"fmt"
 	"log"
 	"os"
+	"pkg/auth"
+	"pkg/limits"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"
@@ -28,12 +33,30 @@ import (
 // @host localhost:8080
 // @BasePath /
 // @schemes http
+
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name X-API-Key
+// @description API-ключ из API_KEYS
+
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+// @description JWT (HS256 или RS256) в формате "Bearer <token>"
 func main() {
 	fmt.Println("API Gateway starting...")
 
 	// Загрузка конфигурации из переменных окружения
 	fileStoringServiceAddr := os.Getenv("FILE_STORING_SERVICE_ADDR")
 	fileAnalysisServiceAddr := os.Getenv("FILE_ANALYSIS_SERVICE_ADDR")
+	apiKeys := os.Getenv("API_KEYS")
+	jwtHS256Secret := os.Getenv("JWT_HS256_SECRET")
+	jwtJWKSFile := os.Getenv("JWT_JWKS_FILE")
+	jwtLeeway := os.Getenv("JWT_LEEWAY")
+	authDisabled := os.Getenv("AUTH_DISABLED")
+	maxUploadSize := os.Getenv("MAX_UPLOAD_SIZE")
+	maxBatchSize := os.Getenv("MAX_BATCH_SIZE")
+	serviceAuthSecret := os.Getenv("SERVICE_AUTH_SECRET")
 
 	// Значения по умолчанию, если переменные не установлены
 	if fileStoringServiceAddr == "" {
@@ -48,29 +71,117 @@ func main() {
 		fileAnalysisServiceAddr = "http://" + fileAnalysisServiceAddr
 	}
 
+	if serviceAuthSecret == "" {
+		log.Fatalf("Не задан SERVICE_AUTH_SECRET: общий с сервисами секрет для подписи пользователя в пересылаемых запросах")
+	}
+
+	// Аутентификация: API-ключи и JWT
+	authenticator := &middleware.Authenticator{
+		HMACSecret: []byte(jwtHS256Secret),
+		Issuer:     os.Getenv("JWT_ISSUER"),
+		Audience:   os.Getenv("JWT_AUDIENCE"),
+		Leeway:     time.Minute,
+	}
+	if apiKeys != "" {
+		keys, err := middleware.ParseAPIKeys(apiKeys)
+		if err != nil {
+			log.Fatalf("Некорректное значение API_KEYS: %v", err)
+		}
+		authenticator.APIKeys = keys
+	}
+	if jwtJWKSFile != "" {
+		keys, err := middleware.LoadJWKS(jwtJWKSFile)
+		if err != nil {
+			log.Fatalf("Не удалось загрузить JWT_JWKS_FILE: %v", err)
+		}
+		authenticator.RSAKeys = keys
+	}
+	if jwtLeeway != "" {
+		leeway, err := time.ParseDuration(jwtLeeway)
+		if err != nil || leeway < 0 {
+			log.Fatalf("Некорректное значение JWT_LEEWAY: %s", jwtLeeway)
+		}
+		authenticator.Leeway = leeway
+	}
+
+	authMiddleware := authenticator.Middleware()
+	if disabled, _ := strconv.ParseBool(authDisabled); disabled {
+		log.Println("Внимание: аутентификация выключена (AUTH_DISABLED), все запросы выполняются от имени администратора")
+		authMiddleware = middleware.Anonymous()
+	} else if !authenticator.Enabled() {
+		log.Fatalf("Не настроена аутентификация: задайте API_KEYS, JWT_HS256_SECRET или JWT_JWKS_FILE (или AUTH_DISABLED=true для локальной разработки)")
+	}
+
 	// Инициализация обработчика прокси
-	proxyHandler := handlers.NewProxyHandler(fileStoringServiceAddr, fileAnalysisServiceAddr)
+	proxyHandler := handlers.NewProxyHandler(fileStoringServiceAddr, fileAnalysisServiceAddr,
+		auth.ServiceSigner{Service: "api_gateway", Secret: []byte(serviceAuthSecret)})
+	if maxUploadSize != "" {
+		size, err := limits.ParseSize(maxUploadSize)
+		if err != nil || size <= 0 {
+			log.Fatalf("Некорректное значение MAX_UPLOAD_SIZE: %s", maxUploadSize)
+		}
+		proxyHandler.MaxUploadSize = size
+	}
+	if maxBatchSize != "" {
+		size, err := limits.ParseSize(maxBatchSize)
+		if err != nil || size <= 0 {
+			log.Fatalf("Некорректное значение MAX_BATCH_SIZE: %s", maxBatchSize)
+		}
+		proxyHandler.MaxBatchSize = size
+	}
 
 	// Инициализация Gin
 	r := gin.Default()
 
-	// Маршруты API
+	// Маршруты API доступны только аутентифицированным пользователям
+	api := r.Group("", authMiddleware)
+
 	// 1. Загрузка файла
-	r.POST("/upload", proxyHandler.UploadFile)
+	api.POST("/upload", proxyHandler.UploadFile)
+	api.POST("/batches", proxyHandler.UploadBatch)
+	api.GET("/batches/:id", proxyHandler.GetBatch)
+	api.POST("/uploads", proxyHandler.CreateUploadSession)
+	api.GET("/uploads/:id", proxyHandler.GetUploadSession)
+	api.HEAD("/uploads/:id", proxyHandler.GetUploadSession)
+	api.PATCH("/uploads/:id", proxyHandler.UploadChunk)
+	api.POST("/uploads/:id/complete", proxyHandler.CompleteUpload)
+	api.DELETE("/uploads/:id", proxyHandler.CancelUpload)
+	api.POST("/collections", proxyHandler.CreateCollection)
+	api.GET("/collections", proxyHandler.ListCollections)
+	api.GET("/collections/:id", proxyHandler.GetCollection)
+	api.PATCH("/collections/:id", proxyHandler.UpdateCollection)
+	api.DELETE("/collections/:id", proxyHandler.DeleteCollection)
+	api.PUT("/collections/:id/files/:file_id", proxyHandler.AddCollectionFile)
+	api.DELETE("/collections/:id/files/:file_id", proxyHandler.RemoveCollectionFile)
 
 	// 2. Анализ файла
-	r.POST("/analysis/:file_id", proxyHandler.RequestAnalysis)
-	r.GET("/analysis/results/:file_id", proxyHandler.GetAnalysisResults)
+	api.POST("/analysis/:file_id", proxyHandler.RequestAnalysis)
+	api.GET("/analysis/results/:file_id", proxyHandler.GetAnalysisResults)
+	api.GET("/analysis/results/:file_id/frequencies", proxyHandler.GetWordFrequencies)
+	api.GET("/analysis/results/:file_id/history", proxyHandler.GetAnalysisHistory)
+	api.GET("/analysis/results/:file_id/passages", proxyHandler.GetPassageReport)
+	api.GET("/analysis/similarity/:file_id", proxyHandler.GetSimilarFiles)
+	api.GET("/analysis/jobs/:id", proxyHandler.GetAnalysisJob)
+	api.GET("/analysis/:file_id/events", proxyHandler.StreamAnalysisEvents)
+	api.GET("/analysis/collections/:collection_id/similarity", proxyHandler.GetCollectionSimilarity)
+	api.GET("/analysis/webhook", proxyHandler.GetWebhook)
+	api.PUT("/analysis/webhook", proxyHandler.SetWebhook)
+	api.DELETE("/analysis/webhook", proxyHandler.DeleteWebhook)
+	api.GET("/analysis/webhook/deliveries", proxyHandler.ListWebhookDeliveries)
 
 	// 3. Получение файла
-	r.GET("/files/:id", proxyHandler.GetFileByID)
+	api.GET("/files/:id", proxyHandler.GetFileByID)
+	api.GET("/files/:id/text", proxyHandler.GetFileText)
+	api.DELETE("/files/:id", proxyHandler.DeleteFile)
 
 	// 4. Получение облака слов
-	r.GET("/analysis/wordclouds", proxyHandler.GetWordCloud)
+	api.GET("/analysis/wordclouds", proxyHandler.GetWordCloud)
 
 	// Дополнительные эндпоинты
-	r.GET("/files", proxyHandler.ListFiles)
-	r.GET("/analysis/results-all", proxyHandler.ListAnalysisResults)
+	api.GET("/files", proxyHandler.ListFiles)
+	api.GET("/usage", proxyHandler.GetStorageUsage)
+	api.GET("/search", proxyHandler.Search)
+	api.GET("/analysis/results-all", proxyHandler.ListAnalysisResults)
 
 	// Swagger документация
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
@@ -0,0 +1,131 @@
+package middleware
+
+import (
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"pkg/auth"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HeaderAPIKey — заголовок с API-ключом.
+const HeaderAPIKey = "X-API-Key"
+
+// Authenticator проверяет API-ключи и JWT и передает сервисам проверенного пользователя
+// в доверенных заголовках X-User-ID и X-User-Role.
+type Authenticator struct {
+	APIKeys    map[string]auth.Identity  // Пользователь по API-ключу
+	HMACSecret []byte                    // Секрет для JWT с алгоритмом HS256
+	RSAKeys    map[string]*rsa.PublicKey // Ключи для JWT с алгоритмом RS256 по kid
+	Issuer     string                    // Ожидаемое утверждение iss (пусто — не проверяется)
+	Audience   string                    // Ожидаемое значение в утверждении aud (пусто — не проверяется)
+	Leeway     time.Duration             // Допустимое расхождение часов при проверке exp и nbf
+}
+
+// ParseAPIKeys разбирает список API-ключей вида "ключ:пользователь[:роль],...". Роль по умолчанию — user.
+func ParseAPIKeys(value string) (map[string]auth.Identity, error) {
+	keys := make(map[string]auth.Identity)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.Split(entry, ":")
+		if len(parts) < 2 || len(parts) > 3 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("некорректная запись API-ключа %q: ожидается ключ:пользователь[:роль]", entry)
+		}
+		identity := auth.Identity{UserID: parts[1], Role: auth.RoleUser}
+		if len(parts) == 3 {
+			if parts[2] != auth.RoleUser && parts[2] != auth.RoleAdmin {
+				return nil, fmt.Errorf("неизвестная роль %q у пользователя %s", parts[2], parts[1])
+			}
+			identity.Role = parts[2]
+		}
+		keys[parts[0]] = identity
+	}
+	return keys, nil
+}
+
+// Enabled сообщает, настроен ли хотя бы один способ аутентификации.
+func (a *Authenticator) Enabled() bool {
+	return len(a.APIKeys) > 0 || len(a.HMACSecret) > 0 || len(a.RSAKeys) > 0
+}
+
+// Authenticate определяет пользователя по заголовку X-API-Key или Authorization: Bearer <JWT>.
+func (a *Authenticator) Authenticate(r *http.Request) (auth.Identity, error) {
+	if key := r.Header.Get(HeaderAPIKey); key != "" {
+		if identity, ok := a.lookupAPIKey(key); ok {
+			return identity, nil
+		}
+		return auth.Identity{}, errors.New("invalid API key")
+	}
+
+	authorization := r.Header.Get("Authorization")
+	if authorization == "" {
+		return auth.Identity{}, errors.New("missing credentials: provide X-API-Key or Authorization: Bearer <token>")
+	}
+	scheme, token, found := strings.Cut(authorization, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return auth.Identity{}, errors.New("unsupported authorization scheme, expected Bearer")
+	}
+	claims, err := a.verifyJWT(strings.TrimSpace(token))
+	if err != nil {
+		return auth.Identity{}, err
+	}
+	identity := auth.Identity{UserID: claims.Subject, Role: auth.RoleUser}
+	if claims.Role == auth.RoleAdmin || containsString(claims.Roles, auth.RoleAdmin) {
+		identity.Role = auth.RoleAdmin
+	}
+	return identity, nil
+}
+
+// lookupAPIKey ищет API-ключ, сравнивая хеши ключей за постоянное время.
+func (a *Authenticator) lookupAPIKey(key string) (auth.Identity, bool) {
+	keyHash := sha256.Sum256([]byte(key))
+	var found auth.Identity
+	ok := false
+	for candidate, identity := range a.APIKeys {
+		candidateHash := sha256.Sum256([]byte(candidate))
+		if subtle.ConstantTimeCompare(keyHash[:], candidateHash[:]) == 1 {
+			found, ok = identity, true
+		}
+	}
+	return found, ok
+}
+
+// Middleware возвращает middleware Gin, пропускающий только аутентифицированные запросы.
+// Заголовки X-User-ID и X-User-Role клиента отбрасываются и заменяются проверенными,
+// а учетные данные клиента не передаются сервисам.
+func (a *Authenticator) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		auth.DeleteHeaders(c.Request.Header)
+
+		identity, err := a.Authenticate(c.Request)
+		if err != nil {
+			c.Header("WWW-Authenticate", `Bearer realm="api_gateway"`)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+			return
+		}
+
+		c.Request.Header.Del(HeaderAPIKey)
+		c.Request.Header.Del("Authorization")
+		identity.SetHeaders(c.Request.Header)
+		c.Next()
+	}
+}
+
+// Anonymous возвращает middleware для работы без аутентификации (AUTH_DISABLED=true):
+// все запросы выполняются от имени администратора anonymous.
+func Anonymous() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		auth.DeleteHeaders(c.Request.Header)
+		auth.Identity{UserID: "anonymous", Role: auth.RoleAdmin}.SetHeaders(c.Request.Header)
+		c.Next()
+	}
+}
@@ -0,0 +1,295 @@
+package middleware
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"pkg/auth"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+var testHMACSecret = []byte("test-jwt-secret")
+
+func encodeSegment(t *testing.T, value any) string {
+	t.Helper()
+	data, err := json.Marshal(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// hs256Token подписывает утверждения claims секретом с заголовком header.
+func hs256Token(t *testing.T, secret []byte, header, claims map[string]any) string {
+	t.Helper()
+	signed := encodeSegment(t, header) + "." + encodeSegment(t, claims)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(signed))
+	return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// rs256Token подписывает утверждения claims ключом key; пустой kid не передается в заголовке.
+func rs256Token(t *testing.T, key *rsa.PrivateKey, kid string, claims map[string]any) string {
+	t.Helper()
+	header := map[string]any{"alg": "RS256", "typ": "JWT"}
+	if kid != "" {
+		header["kid"] = kid
+	}
+	signed := encodeSegment(t, header) + "." + encodeSegment(t, claims)
+	digest := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// writeJWKS записывает открытые ключи keys в файл JWKS и загружает его через LoadJWKS.
+func writeJWKS(t *testing.T, keys map[string]*rsa.PrivateKey) map[string]*rsa.PublicKey {
+	t.Helper()
+	var jwks struct {
+		Keys []map[string]string `json:"keys"`
+	}
+	for kid, key := range keys {
+		jwks.Keys = append(jwks.Keys, map[string]string{
+			"kty": "RSA", "kid": kid, "use": "sig", "alg": "RS256",
+			"n": base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e": base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		})
+	}
+	// Ключ шифрования и ключ другого типа пропускаются
+	jwks.Keys = append(jwks.Keys,
+		map[string]string{"kty": "RSA", "kid": "enc", "use": "enc", "n": "AQAB", "e": "AQAB"},
+		map[string]string{"kty": "EC", "kid": "ec", "crv": "P-256"},
+	)
+	data, _ := json.Marshal(jwks)
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadJWKS(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded) != len(keys) {
+		t.Fatalf("из JWKS загружено %d ключей, ожидалось %d", len(loaded), len(keys))
+	}
+	return loaded
+}
+
+func generateKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func bearerRequest(token string) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/files", nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+	return r
+}
+
+func TestAuthenticateJWT(t *testing.T) {
+	key1, key2, unknownKey := generateKey(t), generateKey(t), generateKey(t)
+	bothKeys := writeJWKS(t, map[string]*rsa.PrivateKey{"k1": key1, "k2": key2})
+	oneKey := writeJWKS(t, map[string]*rsa.PrivateKey{"k1": key1})
+
+	now := time.Now()
+	claims := func(changes map[string]any) map[string]any {
+		c := map[string]any{"sub": "alice", "iss": "kpo", "aud": []string{"web", "api"}, "exp": now.Add(time.Hour).Unix()}
+		for k, v := range changes {
+			if v == nil {
+				delete(c, k)
+			} else {
+				c[k] = v
+			}
+		}
+		return c
+	}
+	hs256 := map[string]any{"alg": "HS256", "typ": "JWT"}
+
+	hmacAuth := &Authenticator{HMACSecret: testHMACSecret, Issuer: "kpo", Audience: "api", Leeway: 30 * time.Second}
+	jwksAuth := &Authenticator{RSAKeys: bothKeys, Issuer: "kpo", Audience: "api", Leeway: 30 * time.Second}
+	singleKeyAuth := &Authenticator{RSAKeys: oneKey}
+
+	tests := []struct {
+		name          string
+		authenticator *Authenticator
+		token         string
+		want          auth.Identity
+		wantErr       string
+	}{
+		{"HS256", hmacAuth, hs256Token(t, testHMACSecret, hs256, claims(nil)), auth.Identity{UserID: "alice", Role: auth.RoleUser}, ""},
+		{"HS256 администратор", hmacAuth, hs256Token(t, testHMACSecret, hs256, claims(map[string]any{"roles": []string{"reader", "admin"}})),
+			auth.Identity{UserID: "alice", Role: auth.RoleAdmin}, ""},
+		{"HS256 aud строкой", hmacAuth, hs256Token(t, testHMACSecret, hs256, claims(map[string]any{"aud": "api"})), auth.Identity{UserID: "alice", Role: auth.RoleUser}, ""},
+		{"HS256 чужой секрет", hmacAuth, hs256Token(t, []byte("other"), hs256, claims(nil)), auth.Identity{}, "invalid token signature"},
+		{"RS256 по kid", jwksAuth, rs256Token(t, key2, "k2", claims(map[string]any{"role": "admin"})), auth.Identity{UserID: "alice", Role: auth.RoleAdmin}, ""},
+		{"RS256 без kid, один ключ", singleKeyAuth, rs256Token(t, key1, "", claims(nil)), auth.Identity{UserID: "alice", Role: auth.RoleUser}, ""},
+		{"RS256 подпись другим ключом", jwksAuth, rs256Token(t, key1, "k2", claims(nil)), auth.Identity{}, "invalid token signature"},
+		{"RS256 неизвестный kid", jwksAuth, rs256Token(t, unknownKey, "k3", claims(nil)), auth.Identity{}, "unknown token key id"},
+		{"RS256 без kid, несколько ключей", jwksAuth, rs256Token(t, key1, "", claims(nil)), auth.Identity{}, "no key id"},
+		{"RS256 без JWKS", hmacAuth, rs256Token(t, key1, "k1", claims(nil)), auth.Identity{}, "RS256 tokens are not accepted"},
+		// Подмена алгоритма: HS256 с открытым ключом в качестве секрета, когда настроен только JWKS
+		{"HS256 при настроенном только JWKS", jwksAuth, hs256Token(t, key1.PublicKey.N.Bytes(), hs256, claims(nil)), auth.Identity{}, "HS256 tokens are not accepted"},
+		{"alg none", hmacAuth, encodeSegment(t, map[string]any{"alg": "none"}) + "." + encodeSegment(t, claims(nil)) + ".", auth.Identity{}, "unsupported token algorithm"},
+		{"неизвестный alg", hmacAuth, hs256Token(t, testHMACSecret, map[string]any{"alg": "HS512"}, claims(nil)), auth.Identity{}, "unsupported token algorithm"},
+		{"истекший токен", hmacAuth, hs256Token(t, testHMACSecret, hs256, claims(map[string]any{"exp": now.Add(-time.Minute).Unix()})), auth.Identity{}, "expired"},
+		{"истекший в пределах leeway", hmacAuth, hs256Token(t, testHMACSecret, hs256, claims(map[string]any{"exp": now.Add(-10 * time.Second).Unix()})),
+			auth.Identity{UserID: "alice", Role: auth.RoleUser}, ""},
+		{"без exp", hmacAuth, hs256Token(t, testHMACSecret, hs256, claims(map[string]any{"exp": nil})), auth.Identity{}, "no expiration"},
+		{"nbf в будущем", hmacAuth, hs256Token(t, testHMACSecret, hs256, claims(map[string]any{"nbf": now.Add(time.Minute).Unix()})), auth.Identity{}, "not valid yet"},
+		{"nbf в пределах leeway", hmacAuth, hs256Token(t, testHMACSecret, hs256, claims(map[string]any{"nbf": now.Add(10 * time.Second).Unix()})),
+			auth.Identity{UserID: "alice", Role: auth.RoleUser}, ""},
+		{"чужой iss", hmacAuth, hs256Token(t, testHMACSecret, hs256, claims(map[string]any{"iss": "other"})), auth.Identity{}, "issuer"},
+		{"чужой aud", hmacAuth, hs256Token(t, testHMACSecret, hs256, claims(map[string]any{"aud": []string{"web"}})), auth.Identity{}, "audience"},
+		{"без sub", hmacAuth, hs256Token(t, testHMACSecret, hs256, claims(map[string]any{"sub": nil})), auth.Identity{}, "no subject"},
+		{"не JWT", hmacAuth, "not-a-token", auth.Identity{}, "malformed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			identity, err := tt.authenticator.Authenticate(bearerRequest(tt.token))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ошибка %v, ожидалась содержащая %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if identity != tt.want {
+				t.Fatalf("пользователь %+v, ожидался %+v", identity, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseAPIKeys(t *testing.T) {
+	keys, err := ParseAPIKeys(" admin-key:root:admin , user-key:alice,, ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]auth.Identity{
+		"admin-key": {UserID: "root", Role: auth.RoleAdmin},
+		"user-key":  {UserID: "alice", Role: auth.RoleUser},
+	}
+	if len(keys) != len(want) {
+		t.Fatalf("ключи %+v, ожидались %+v", keys, want)
+	}
+	for key, identity := range want {
+		if keys[key] != identity {
+			t.Errorf("ключ %s: %+v, ожидался %+v", key, keys[key], identity)
+		}
+	}
+
+	for _, value := range []string{"key", "key:", ":alice", "key:alice:owner", "key:alice:admin:extra"} {
+		if _, err := ParseAPIKeys(value); err == nil {
+			t.Errorf("ParseAPIKeys(%q) принял некорректную запись", value)
+		}
+	}
+}
+
+func TestAuthenticateAPIKey(t *testing.T) {
+	authenticator := &Authenticator{APIKeys: map[string]auth.Identity{"user-key": {UserID: "alice", Role: auth.RoleUser}}}
+	tests := []struct {
+		headers map[string]string
+		want    auth.Identity
+		ok      bool
+	}{
+		{map[string]string{HeaderAPIKey: "user-key"}, auth.Identity{UserID: "alice", Role: auth.RoleUser}, true},
+		{map[string]string{HeaderAPIKey: "user-key2"}, auth.Identity{}, false},
+		{map[string]string{HeaderAPIKey: "user-ke"}, auth.Identity{}, false},
+		{map[string]string{}, auth.Identity{}, false},
+		{map[string]string{"Authorization": "Basic dXNlcjpwYXNz"}, auth.Identity{}, false},
+		// Неверный API-ключ не заменяется токеном: JWT без HMACSecret и JWKS все равно не принимается
+		{map[string]string{HeaderAPIKey: "wrong", "Authorization": "Bearer x.y.z"}, auth.Identity{}, false},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/files", nil)
+		for name, value := range tt.headers {
+			r.Header.Set(name, value)
+		}
+		identity, err := authenticator.Authenticate(r)
+		if (err == nil) != tt.ok || identity != tt.want {
+			t.Errorf("заголовки %v: %+v, %v", tt.headers, identity, err)
+		}
+	}
+}
+
+func TestMiddlewareReplacesIdentityHeaders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	authenticator := &Authenticator{
+		APIKeys:    map[string]auth.Identity{"user-key": {UserID: "alice", Role: auth.RoleUser}},
+		HMACSecret: testHMACSecret,
+	}
+	router := gin.New()
+	router.Use(authenticator.Middleware())
+	var forwarded http.Header
+	router.GET("/files", func(c *gin.Context) { forwarded = c.Request.Header.Clone() })
+
+	token := hs256Token(t, testHMACSecret, map[string]any{"alg": "HS256"}, map[string]any{"sub": "bob", "exp": time.Now().Add(time.Hour).Unix()})
+	tests := []struct {
+		name       string
+		headers    map[string]string
+		wantStatus int
+		want       auth.Identity
+	}{
+		{"API-ключ", map[string]string{HeaderAPIKey: "user-key"}, http.StatusOK, auth.Identity{UserID: "alice", Role: auth.RoleUser}},
+		{"JWT", map[string]string{"Authorization": "Bearer " + token}, http.StatusOK, auth.Identity{UserID: "bob", Role: auth.RoleUser}},
+		{"без учетных данных", map[string]string{}, http.StatusUnauthorized, auth.Identity{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			forwarded = nil
+			r := httptest.NewRequest(http.MethodGet, "/files", nil)
+			for name, value := range tt.headers {
+				r.Header.Set(name, value)
+			}
+			// Клиент пытается выдать себя за администратора
+			r.Header.Set(auth.HeaderUserID, "root")
+			r.Header.Add(auth.HeaderUserID, "root2")
+			r.Header.Set(auth.HeaderUserRole, auth.RoleAdmin)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("статус %d, ожидался %d", w.Code, tt.wantStatus)
+			}
+			if tt.wantStatus != http.StatusOK {
+				if forwarded != nil {
+					t.Fatal("запрос без учетных данных передан дальше")
+				}
+				if w.Header().Get("WWW-Authenticate") == "" {
+					t.Error("ответ 401 без заголовка WWW-Authenticate")
+				}
+				return
+			}
+			if got := forwarded.Values(auth.HeaderUserID); len(got) != 1 || got[0] != tt.want.UserID {
+				t.Errorf("X-User-ID: %v, ожидалось [%s]", got, tt.want.UserID)
+			}
+			if got := forwarded.Values(auth.HeaderUserRole); len(got) != 1 || got[0] != tt.want.Role {
+				t.Errorf("X-User-Role: %v, ожидалось [%s]", got, tt.want.Role)
+			}
+			if forwarded.Get(HeaderAPIKey) != "" || forwarded.Get("Authorization") != "" {
+				t.Error("учетные данные клиента переданы сервисам")
+			}
+		})
+	}
+}
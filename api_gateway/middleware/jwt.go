@@ -0,0 +1,212 @@
+package middleware
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+)
+
+// jwtHeader — заголовок JWT.
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// jwtClaims — утверждения JWT, используемые при аутентификации.
+type jwtClaims struct {
+	Subject   string       `json:"sub"`
+	Role      string       `json:"role"`
+	Roles     []string     `json:"roles"`
+	Issuer    string       `json:"iss"`
+	Audience  jwtAudience  `json:"aud"`
+	ExpiresAt *json.Number `json:"exp"`
+	NotBefore *json.Number `json:"nbf"`
+}
+
+// jwtAudience — утверждение aud, которое может быть строкой или массивом строк.
+type jwtAudience []string
+
+func (a *jwtAudience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = jwtAudience{single}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return errors.New("aud must be a string or an array of strings")
+	}
+	*a = many
+	return nil
+}
+
+// verifyJWT проверяет подпись и сроки действия токена и возвращает его утверждения.
+func (a *Authenticator) verifyJWT(token string) (*jwtClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("malformed token header: %w", err)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.New("malformed token signature")
+	}
+	signed := []byte(parts[0] + "." + parts[1])
+
+	switch header.Alg {
+	case "HS256":
+		if len(a.HMACSecret) == 0 {
+			return nil, errors.New("HS256 tokens are not accepted")
+		}
+		mac := hmac.New(sha256.New, a.HMACSecret)
+		mac.Write(signed)
+		if !hmac.Equal(signature, mac.Sum(nil)) {
+			return nil, errors.New("invalid token signature")
+		}
+	case "RS256":
+		key, err := a.rsaKey(header.Kid)
+		if err != nil {
+			return nil, err
+		}
+		digest := sha256.Sum256(signed)
+		if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+			return nil, errors.New("invalid token signature")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported token algorithm %q", header.Alg)
+	}
+
+	var claims jwtClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("malformed token claims: %w", err)
+	}
+
+	now := time.Now()
+	if claims.ExpiresAt == nil {
+		return nil, errors.New("token has no expiration time")
+	}
+	exp, err := claims.ExpiresAt.Int64()
+	if err != nil {
+		return nil, errors.New("malformed token expiration time")
+	}
+	if now.After(time.Unix(exp, 0).Add(a.Leeway)) {
+		return nil, errors.New("token has expired")
+	}
+	if claims.NotBefore != nil {
+		nbf, err := claims.NotBefore.Int64()
+		if err != nil {
+			return nil, errors.New("malformed token not-before time")
+		}
+		if now.Add(a.Leeway).Before(time.Unix(nbf, 0)) {
+			return nil, errors.New("token is not valid yet")
+		}
+	}
+	if a.Issuer != "" && claims.Issuer != a.Issuer {
+		return nil, errors.New("unexpected token issuer")
+	}
+	if a.Audience != "" && !containsString(claims.Audience, a.Audience) {
+		return nil, errors.New("unexpected token audience")
+	}
+	if claims.Subject == "" {
+		return nil, errors.New("token has no subject")
+	}
+	return &claims, nil
+}
+
+// rsaKey возвращает открытый ключ RS256 по kid. Токен без kid принимается, если в JWKS ровно один ключ.
+func (a *Authenticator) rsaKey(kid string) (*rsa.PublicKey, error) {
+	if len(a.RSAKeys) == 0 {
+		return nil, errors.New("RS256 tokens are not accepted")
+	}
+	if kid == "" {
+		if len(a.RSAKeys) == 1 {
+			for _, key := range a.RSAKeys {
+				return key, nil
+			}
+		}
+		return nil, errors.New("token has no key id")
+	}
+	key, ok := a.RSAKeys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown token key id %q", kid)
+	}
+	return key, nil
+}
+
+// decodeSegment декодирует часть JWT из base64url и JSON.
+func decodeSegment(segment string, out interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(strings.NewReader(string(data)))
+	decoder.UseNumber()
+	return decoder.Decode(out)
+}
+
+// LoadJWKS читает RSA-ключи для проверки подписи RS256 из локального файла JWKS (RFC 7517).
+// Ключи других типов и ключи, предназначенные не для подписи, пропускаются.
+func LoadJWKS(path string) (map[string]*rsa.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось прочитать JWKS %s: %w", path, err)
+	}
+	var jwks struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			Alg string `json:"alg"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &jwks); err != nil {
+		return nil, fmt.Errorf("некорректный JWKS %s: %w", path, err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey)
+	for _, jwk := range jwks.Keys {
+		if jwk.Kty != "RSA" || (jwk.Use != "" && jwk.Use != "sig") || (jwk.Alg != "" && jwk.Alg != "RS256") {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil {
+			return nil, fmt.Errorf("некорректный модуль ключа %q в JWKS %s: %w", jwk.Kid, path, err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil || len(e) == 0 || len(e) > 4 {
+			return nil, fmt.Errorf("некорректная экспонента ключа %q в JWKS %s", jwk.Kid, path)
+		}
+		keys[jwk.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("в JWKS %s нет RSA-ключей для RS256", path)
+	}
+	return keys, nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
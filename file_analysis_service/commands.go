@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"file_analysis_service/models"
+	"file_analysis_service/services"
+	"flag"
+	"fmt"
+	"log"
+)
+
+// runCommand выполняет административную команду, заданную аргументами запуска сервиса, вместо запуска HTTP-сервера.
+// Пример: file_analysis_service_executable rebuild-index -reanalyze
+func runCommand(args []string, analysisService *services.AnalysisService, jobQueue *services.JobQueue) error {
+	switch args[0] {
+	case "rebuild-index":
+		return rebuildIndexCommand(args[1:], analysisService, jobQueue)
+	default:
+		return fmt.Errorf("неизвестная команда %q (доступна rebuild-index)", args[0])
+	}
+}
+
+// rebuildIndexCommand перестраивает индекс сходства по сигнатурам всех проанализированных файлов.
+// С флагом -reanalyze все проанализированные файлы ставятся в очередь на повторный анализ: так в индекс
+// попадают отпечатки файлов, проанализированных до их появления. Задачи выполняют обработчики работающего сервиса.
+func rebuildIndexCommand(args []string, analysisService *services.AnalysisService, jobQueue *services.JobQueue) error {
+	flags := flag.NewFlagSet("rebuild-index", flag.ContinueOnError)
+	reanalyze := flags.Bool("reanalyze", false, "поставить все проанализированные файлы в очередь на повторный анализ")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	stats, err := analysisService.RebuildSimilarityIndex(context.Background())
+	if err != nil {
+		return err
+	}
+	log.Printf("Индекс сходства перестроен: файлов %d, записей %d, удалено устаревших записей %d",
+		stats.Signatures, stats.Bands, stats.Orphans)
+	if stats.WithoutFingerprints > 0 && !*reanalyze {
+		log.Printf("У %d файлов нет отпечатков winnowing: запустите команду с флагом -reanalyze, чтобы проанализировать их заново",
+			stats.WithoutFingerprints)
+	}
+	if !*reanalyze {
+		return nil
+	}
+
+	var fileIDs []string
+	err = analysisService.DBAdapter.DB.Model(&models.AnalysisResult{}).Distinct().Order("file_id").Pluck("file_id", &fileIDs).Error
+	if err != nil {
+		return fmt.Errorf("не удалось получить список проанализированных файлов: %w", err)
+	}
+	for _, fileID := range fileIDs {
+		if _, err := jobQueue.Enqueue(fileID, true); err != nil {
+			return fmt.Errorf("не удалось поставить файл %s в очередь на анализ: %w", fileID, err)
+		}
+	}
+	log.Printf("В очередь на повторный анализ поставлено файлов: %d", len(fileIDs))
+	return nil
+}
@@ -24,23 +24,124 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/analysis/results": {
+        "/analysis/collections/{collection_id}/similarity": {
             "get": {
-                "description": "Возвращает file_id и location облака слов для всех проанализированных файлов.",
+                "description": "Вычисляет попарное сходство всех проанализированных файлов коллекции (MinHash по шинглам из слов) и возвращает пары\nс оценкой не ниже min_score в порядке убывания сходства. Файлы, которые еще не анализировались, не сравниваются и перечислены в not_analyzed.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "analysis"
                 ],
-                "summary": "Список всех результатов анализа",
+                "summary": "Сравнение файлов коллекции",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID коллекции",
+                        "name": "collection_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "number",
+                        "description": "Наименьшая оценка сходства от 0 до 1 (по умолчанию 0.3)",
+                        "name": "min_score",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Максимальное количество пар (по умолчанию 100, не более 1000)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
                 "responses": {
                     "200": {
-                        "description": "Список результатов анализа",
+                        "description": "Подозрительные пары файлов коллекции",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/models.AnalysisResult"
+                            "$ref": "#/definitions/services.CollectionSimilarityReport"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректные параметры запроса",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Коллекция не найдена",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "502": {
+                        "description": "Не удалось получить файлы коллекции от File Storing Service",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/analysis/jobs/{id}": {
+            "get": {
+                "description": "Возвращает состояние задачи анализа (pending, running, succeeded, failed, cancelled), количество попыток и последнюю ошибку.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "analysis"
+                ],
+                "summary": "Получение состояния задачи анализа",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID задачи анализа",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Состояние задачи",
+                        "schema": {
+                            "$ref": "#/definitions/models.AnalysisJob"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный ID задачи",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Задача не найдена",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
                             }
                         }
                     },
@@ -58,21 +159,107 @@ const docTemplate = `{
         },
         "/analysis/results-all": {
             "get": {
-                "description": "Возвращает file_id и location облака слов для всех проанализированных файлов.",
+                "description": "Возвращает результаты анализа постранично: пользователю — только результаты его файлов, администратору — всех файлов\nили файлов владельца owner_id. Страницы выбираются по курсору: ссылка на следующую страницу\nвозвращается в поле next, на последней странице ее нет.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "analysis"
                 ],
-                "summary": "Список всех результатов анализа (для отладки)",
+                "summary": "Список результатов анализа",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Количество результатов на странице (по умолчанию 50, не более 500)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Курсор следующей страницы из поля next_cursor",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "created_at",
+                            "file_id",
+                            "word_count"
+                        ],
+                        "type": "string",
+                        "description": "Поле сортировки (по умолчанию created_at)",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "description": "Порядок сортировки (по умолчанию desc)",
+                        "name": "order",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID файла",
+                        "name": "file_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID владельца файла (другого пользователя — только для администратора)",
+                        "name": "owner_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Код языка текста (ISO 639-1 или und)",
+                        "name": "language",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Версия алгоритмов анализа",
+                        "name": "analyzer_version",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Результаты, полученные не раньше этого времени (RFC 3339)",
+                        "name": "created_after",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Результаты, полученные раньше этого времени (RFC 3339)",
+                        "name": "created_before",
+                        "in": "query"
+                    }
+                ],
                 "responses": {
                     "200": {
-                        "description": "Список результатов анализа",
+                        "description": "Страница списка результатов анализа",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/models.AnalysisResult"
+                            "$ref": "#/definitions/handlers.AnalysisResultListResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректные параметры запроса",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Результаты анализа файлов другого пользователя доступны только администратору",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
                             }
                         }
                     },
@@ -90,7 +277,7 @@ const docTemplate = `{
         },
         "/analysis/results/{file_id}": {
             "get": {
-                "description": "Возвращает результаты анализа файла (количество абзацев, слов, символов) по его ID.",
+                "description": "Возвращает статистику текста файла по его ID: количество абзацев, непустых строк, предложений, слов (всего и различных),\nсимволов (без пробельных и со всеми), среднюю длину слова и предложения и лексическую плотность.\nПо умолчанию возвращается последний результат; result_id позволяет получить результат из истории анализов файла.",
                 "produces": [
                     "application/json"
                 ],
@@ -105,6 +292,12 @@ const docTemplate = `{
                         "name": "file_id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID результата анализа из истории (по умолчанию последний)",
+                        "name": "result_id",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -114,6 +307,15 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.AnalysisResult"
                         }
                     },
+                    "400": {
+                        "description": "Некорректный result_id",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
                     "404": {
                         "description": "Результаты анализа не найдены",
                         "schema": {
@@ -133,39 +335,123 @@ const docTemplate = `{
                         }
                     }
                 }
+            },
+            "delete": {
+                "description": "Удаляет все результаты анализа файла, его частоты слов, сигнатуру, отпечатки, совпадения сходства, задачи анализа и облака слов.\nВызывается File Storing Service при удалении файла. Повторный вызов безопасен и возвращает нулевые счетчики.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "analysis"
+                ],
+                "summary": "Удаление результатов анализа файла",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID файла",
+                        "name": "file_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Количество удаленных результатов анализа и облаков слов (file_id, deleted_results, deleted_word_clouds)",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "404": {
+                        "description": "Файл принадлежит другому пользователю",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
             }
         },
-        "/analysis/wordclouds": {
+        "/analysis/results/{file_id}/frequencies": {
             "get": {
-                "description": "Возвращает изображение облака слов по его location (пути к файлу).",
+                "description": "Возвращает самые частые слова текста файла, сохраненные при анализе, в порядке убывания количества вхождений.\nПри lemmatize=true формы одного слова (для русского и английского языков) объединяются. Служебные слова по умолчанию исключаются.",
                 "produces": [
-                    "image/png",
-                    "image/jpeg",
-                    "image/gif",
-                    "image/svg+xml"
+                    "application/json",
+                    "text/csv"
                 ],
                 "tags": [
                     "analysis"
                 ],
-                "summary": "Получение облака слов",
+                "summary": "Получение частот слов",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Location (путь) к файлу облака слов",
-                        "name": "location",
-                        "in": "query",
+                        "description": "ID файла",
+                        "name": "file_id",
+                        "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Максимальное количество слов (по умолчанию 100, не более 1000)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Сколько слов пропустить (по умолчанию 0)",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Включать ли служебные слова (по умолчанию false)",
+                        "name": "stopwords",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Объединять ли формы одного слова (по умолчанию false)",
+                        "name": "lemmatize",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "json",
+                            "csv"
+                        ],
+                        "type": "string",
+                        "description": "Формат ответа: json (по умолчанию) или csv",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID результата анализа из истории (по умолчанию последний)",
+                        "name": "result_id",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Изображение облака слов",
+                        "description": "Частоты слов",
                         "schema": {
-                            "type": "file"
+                            "$ref": "#/definitions/services.WordFrequencyReport"
                         }
                     },
                     "400": {
-                        "description": "Параметр location не указан",
+                        "description": "Некорректные параметры запроса",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -174,7 +460,7 @@ const docTemplate = `{
                         }
                     },
                     "404": {
-                        "description": "Облако слов не найдено",
+                        "description": "Результаты анализа не найдены",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -194,37 +480,35 @@ const docTemplate = `{
                 }
             }
         },
-        "/analysis/{file_id}": {
-            "post": {
-                "description": "Инициирует процесс анализа файла по его ID.",
+        "/analysis/results/{file_id}/history": {
+            "get": {
+                "description": "Возвращает все результаты анализа файла в порядке от нового к старому вместе с версией алгоритмов, которой получен каждый из них,\nи текущей версией алгоритмов сервиса. Это позволяет сравнить результаты до и после повторного анализа.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "analysis"
                 ],
-                "summary": "Запрос на анализ файла",
+                "summary": "Получение истории анализов файла",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "ID файла для анализа",
+                        "description": "ID файла",
                         "name": "file_id",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
-                    "202": {
-                        "description": "Сообщение о принятии запроса на анализ",
+                    "200": {
+                        "description": "История анализов (file_id, analyzer_version — текущая версия, results — результаты анализа)",
                         "schema": {
                             "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "additionalProperties": true
                         }
                     },
-                    "400": {
-                        "description": "Ошибка валидации ID файла",
+                    "404": {
+                        "description": "Файл еще не анализировался",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -233,7 +517,7 @@ const docTemplate = `{
                         }
                     },
                     "500": {
-                        "description": "Внутренняя ошибка сервера при запуске анализа",
+                        "description": "Внутренняя ошибка сервера",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -243,53 +527,1225 @@ const docTemplate = `{
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "models.AnalysisResult": {
-            "description": "Результаты анализа текстового файла, включая количество абзацев, слов, символов и путь к облаку слов.",
-            "type": "object",
-            "properties": {
-                "character_count": {
-                    "type": "integer",
-                    "example": 1500
-                },
-                "created_at": {
-                    "type": "string",
-                    "format": "date-time"
-                },
-                "deleted_at": {
-                    "type": "string",
-                    "format": "date-time"
-                },
-                "file_id": {
-                    "description": "ID оригинального файла",
-                    "type": "string",
-                    "example": "unique-file-id"
-                },
-                "id": {
-                    "description": "gorm.Model заменено на явные поля для Swagger",
-                    "type": "integer",
-                    "example": 1
-                },
-                "paragraph_count": {
-                    "type": "integer",
-                    "example": 5
-                },
-                "updated_at": {
-                    "type": "string",
-                    "format": "date-time"
+        },
+        "/analysis/results/{file_id}/passages": {
+            "get": {
+                "description": "Возвращает фрагменты последнего проанализированного текста файла, совпадающие с фрагментами других проанализированных файлов:\nсмещения начала и конца фрагмента в файле и в файле-источнике (в символах Unicode извлеченного текста), ID источника и длину совпадения в словах.\nСовпадения находятся по отпечаткам winnowing, поэтому фрагменты короче 8 слов могут быть пропущены.\nС format=html возвращается страница с текстом файла, в котором совпадающие фрагменты выделены цветом источника.\nИсточники других пользователей показываются без ID.",
+                "produces": [
+                    "application/json",
+                    "text/html"
+                ],
+                "tags": [
+                    "analysis"
+                ],
+                "summary": "Отчет о совпадающих фрагментах (заимствованиях)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID файла",
+                        "name": "file_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "json",
+                            "html"
+                        ],
+                        "type": "string",
+                        "description": "Формат отчета (по умолчанию json)",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Наименьшая длина фрагмента в словах (по умолчанию 8)",
+                        "name": "min_words",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Сколько самых длинных фрагментов включить в отчет (по умолчанию 500, не более 5000)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Отчет о совпадающих фрагментах",
+                        "schema": {
+                            "$ref": "#/definitions/services.PassageReport"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректные параметры запроса",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Файл еще не анализировался",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Файл проанализирован предыдущей версией алгоритмов; нужен повторный анализ",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "502": {
+                        "description": "Не удалось получить текст файла или владельцев источников из File Storing Service",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/analysis/similarity/{file_id}": {
+            "get": {
+                "description": "Возвращает наиболее похожие на файл ранее проанализированные файлы с оценкой сходства (MinHash по шинглам из слов).",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "analysis"
+                ],
+                "summary": "Получение похожих файлов",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID файла",
+                        "name": "file_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Список похожих файлов",
+                        "schema": {
+                            "$ref": "#/definitions/services.SimilarityReport"
+                        }
+                    },
+                    "404": {
+                        "description": "Файл еще не анализировался",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/analysis/webhook": {
+            "get": {
+                "description": "Возвращает URL уведомлений о завершении анализа файлов пользователя и секрет, которым подписываются все его уведомления.\nНастройки создаются при первом PUT /analysis/webhook или первом запросе анализа с callback_url.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Получение настроек уведомлений",
+                "responses": {
+                    "200": {
+                        "description": "Настройки уведомлений",
+                        "schema": {
+                            "$ref": "#/definitions/models.WebhookEndpoint"
+                        }
+                    },
+                    "404": {
+                        "description": "Пользователь не настраивал уведомления",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Задает URL, на который отправляются уведомления о завершении анализа всех файлов пользователя, в том числе проанализированных автоматически после загрузки.\nПустой url отключает такие уведомления; уведомления на callback_url из запросов анализа продолжают отправляться. rotate_secret=true заменяет секрет подписи.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Изменение настроек уведомлений",
+                "parameters": [
+                    {
+                        "description": "URL уведомлений",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.WebhookEndpointRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Настройки уведомлений",
+                        "schema": {
+                            "$ref": "#/definitions/models.WebhookEndpoint"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный запрос или URL",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Удаляет URL и секрет уведомлений пользователя. Недоставленные уведомления и уведомления, запрошенные с callback_url, не отправляются.",
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Удаление настроек уведомлений",
+                "responses": {
+                    "204": {
+                        "description": "Настройки удалены",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Пользователь не настраивал уведомления",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/analysis/webhook/deliveries": {
+            "get": {
+                "description": "Возвращает последние уведомления пользователя (сначала новые): получатель, количество неудачных попыток, время доставки или отказа и последнюю ошибку.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "История доставки уведомлений",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Количество уведомлений (по умолчанию 20, не более 100)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Уведомления",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.WebhookDelivery"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный параметр limit",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/analysis/wordclouds": {
+            "get": {
+                "description": "Возвращает изображение облака слов по его location (ключу в хранилище, полученному из результатов анализа).",
+                "produces": [
+                    "image/png",
+                    "image/jpeg",
+                    "image/gif",
+                    "image/svg+xml"
+                ],
+                "tags": [
+                    "analysis"
+                ],
+                "summary": "Получение облака слов",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Location (ключ в хранилище) файла облака слов",
+                        "name": "location",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Изображение облака слов",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Параметр location не указан",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Облако слов не найдено",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/analysis/{file_id}": {
+            "post": {
+                "description": "Создает персистентную задачу анализа файла по его ID и возвращает ее ID. Состояние задачи можно получить по GET /analysis/jobs/{id}.\nЕсли для файла уже есть незавершенная задача, возвращается она.\nЕсли последний результат анализа файла получен текущей версией алгоритмов, задача вернет его без повторного анализа;\nforce=true заставляет проанализировать файл заново (например, если облако слов не удалось построить). Новый результат добавляется в историю анализов файла.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "analysis"
+                ],
+                "summary": "Запрос на анализ файла",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID файла для анализа",
+                        "name": "file_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Выполнить анализ заново, даже если есть результат текущей версии",
+                        "name": "force",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "URL, на который по завершении задачи будет отправлено подписанное уведомление с результатом анализа или причиной неудачи",
+                        "name": "callback_url",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Сообщение о принятии запроса, ID и состояние задачи",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Ошибка валидации ID файла, параметра force или callback_url",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Файл не найден или принадлежит другому пользователю",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера при постановке задачи в очередь",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/analysis/{file_id}/events": {
+            "get": {
+                "description": "Первым событием передается текущее состояние последней задачи анализа файла (queued, started, retrying, saved, failed или cancelled),\nзатем — этапы анализа по мере выполнения: started, fetched, counted, wordcloud, saved; при неудачной попытке — retrying, после исчерпания попыток — failed, если файл удален во время анализа — cancelled.\nИмя SSE-события совпадает с полем stage, данные — объект ProgressEvent в JSON. Поток закрывается после saved, failed или cancelled.\nКаждые 15 секунд отправляется комментарий, чтобы прокси не закрывали соединение.",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "analysis"
+                ],
+                "summary": "Поток событий анализа файла",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID файла",
+                        "name": "file_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Поток событий",
+                        "schema": {
+                            "$ref": "#/definitions/services.ProgressEvent"
+                        }
+                    },
+                    "404": {
+                        "description": "Файл не найден, принадлежит другому пользователю или не анализировался",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/internal/events": {
+            "post": {
+                "description": "Внутренний эндпоинт для File Storing Service. file.uploaded ставит файл в очередь анализа (если AUTO_ANALYZE не выключен или файл загружен с analyze=true),\nfile.deleted удаляет все результаты анализа файла. Событие с уже обработанным ID повторно не обрабатывается,\nпоэтому доставку можно безопасно повторять. Ответ 2xx означает, что событие принято.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "events"
+                ],
+                "summary": "Прием события о файле",
+                "parameters": [
+                    {
+                        "description": "Событие о файле (id, type: file.uploaded или file.deleted, file_id, occurred_at, data)",
+                        "name": "event",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Событие обработано (event_id, status: processed или duplicate)",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректное событие",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка обработки события; отправитель повторит доставку",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "handlers.AnalysisResultListResponse": {
+            "description": "Результаты анализа текущей страницы, общее количество результатов, подходящих под фильтры, и курсор следующей страницы.",
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.AnalysisResult"
+                    }
+                },
+                "next": {
+                    "description": "Ссылка на следующую страницу относительно текущего пути",
+                    "type": "string",
+                    "example": "?cursor=eyJzIjoiY3JlYXRlZF9hdCJ9\u0026limit=50\u0026order=desc\u0026sort=created_at"
+                },
+                "next_cursor": {
+                    "description": "Курсор следующей страницы; пусто на последней странице",
+                    "type": "string",
+                    "example": "eyJzIjoiY3JlYXRlZF9hdCJ9"
+                },
+                "total": {
+                    "description": "Количество результатов, подходящих под фильтры, на всех страницах",
+                    "type": "integer",
+                    "example": 120
+                }
+            }
+        },
+        "handlers.WebhookEndpointRequest": {
+            "description": "Структура запроса для изменения настроек уведомлений о завершении анализа.",
+            "type": "object",
+            "properties": {
+                "rotate_secret": {
+                    "description": "Заменить секрет подписи новым",
+                    "type": "boolean",
+                    "example": false
+                },
+                "url": {
+                    "description": "URL уведомлений обо всех файлах пользователя; пустой отключает их",
+                    "type": "string",
+                    "example": "https://example.com/hooks/analysis"
+                }
+            }
+        },
+        "models.AnalysisJob": {
+            "description": "Задача анализа файла: состояние, количество попыток и последняя ошибка.",
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "description": "Количество выполненных попыток",
+                    "type": "integer",
+                    "example": 1
+                },
+                "created_at": {
+                    "type": "string",
+                    "format": "date-time"
+                },
+                "file_id": {
+                    "description": "ID анализируемого файла",
+                    "type": "string",
+                    "example": "unique-file-id"
+                },
+                "finished_at": {
+                    "type": "string",
+                    "format": "date-time"
+                },
+                "force": {
+                    "description": "Выполнить анализ заново, даже если есть результат текущей версии",
+                    "type": "boolean",
+                    "example": false
+                },
+                "id": {
+                    "description": "ID задачи",
+                    "type": "integer",
+                    "example": 1
+                },
+                "last_error": {
+                    "description": "Ошибка последней неудачной попытки",
+                    "type": "string",
+                    "example": "FileStoringService вернул ошибку 404"
+                },
+                "max_attempts": {
+                    "description": "Максимальное количество попыток",
+                    "type": "integer",
+                    "example": 3
+                },
+                "next_run_at": {
+                    "description": "Не раньше этого времени задача будет взята в работу",
+                    "type": "string",
+                    "format": "date-time"
+                },
+                "result_id": {
+                    "description": "ID записи AnalysisResult после успешного анализа",
+                    "type": "integer",
+                    "example": 1
+                },
+                "started_at": {
+                    "type": "string",
+                    "format": "date-time"
+                },
+                "status": {
+                    "description": "pending, running, succeeded, failed или cancelled",
+                    "type": "string",
+                    "example": "pending"
+                },
+                "updated_at": {
+                    "type": "string",
+                    "format": "date-time"
+                }
+            }
+        },
+        "models.AnalysisResult": {
+            "description": "Результаты анализа текстового файла: статистика текста (абзацы, строки, предложения, слова, символы, средние длины, лексическая плотность) и путь к облаку слов. Для одного файла хранится история анализов: каждый повторный анализ создает новую запись.",
+            "type": "object",
+            "properties": {
+                "analyzer_version": {
+                    "description": "Версия алгоритмов анализа, которой получен результат; пустая у результатов, полученных до введения версий",
+                    "type": "string",
+                    "example": "2"
+                },
+                "average_sentence_length": {
+                    "description": "Средняя длина предложения в словах",
+                    "type": "number",
+                    "example": 13.89
+                },
+                "average_word_length": {
+                    "description": "Средняя длина слова в символах",
+                    "type": "number",
+                    "example": 5.2
+                },
+                "character_count": {
+                    "description": "Символы без пробельных",
+                    "type": "integer",
+                    "example": 1500
+                },
+                "character_count_with_spaces": {
+                    "description": "Все символы, включая пробельные",
+                    "type": "integer",
+                    "example": 1750
+                },
+                "created_at": {
+                    "type": "string",
+                    "format": "date-time"
+                },
+                "deleted_at": {
+                    "type": "string",
+                    "format": "date-time"
+                },
+                "file_id": {
+                    "description": "ID оригинального файла; у файла может быть несколько результатов анализа",
+                    "type": "string",
+                    "example": "unique-file-id"
+                },
+                "id": {
+                    "description": "gorm.Model заменено на явные поля для Swagger",
+                    "type": "integer",
+                    "example": 1
+                },
+                "language": {
+                    "description": "Код языка текста по ISO 639-1 или \"und\", если язык не определен",
+                    "type": "string",
+                    "example": "ru"
+                },
+                "lexical_density": {
+                    "description": "Доля знаменательных (не служебных) слов",
+                    "type": "number",
+                    "example": 0.62
+                },
+                "line_count": {
+                    "description": "Непустые строки",
+                    "type": "integer",
+                    "example": 12
+                },
+                "owner_id": {
+                    "description": "ID владельца файла; пустой у файлов без владельца",
+                    "type": "string",
+                    "example": "user1"
+                },
+                "paragraph_count": {
+                    "description": "Абзацы, разделенные пустыми строками",
+                    "type": "integer",
+                    "example": 5
+                },
+                "sentence_count": {
+                    "type": "integer",
+                    "example": 18
+                },
+                "unique_word_count": {
+                    "description": "Различные слова без учета регистра",
+                    "type": "integer",
+                    "example": 140
+                },
+                "updated_at": {
+                    "type": "string",
+                    "format": "date-time"
+                },
+                "word_cloud_location": {
+                    "description": "Ключ основного (растрового) изображения облака слов в хранилище",
+                    "type": "string",
+                    "example": "unique-file-id_wordcloud.png"
+                },
+                "word_cloud_svg_location": {
+                    "description": "Ключ того же облака слов в SVG; пустой у результатов, полученных до сохранения SVG",
+                    "type": "string",
+                    "example": "unique-file-id_wordcloud.svg"
+                },
+                "word_count": {
+                    "type": "integer",
+                    "example": 250
+                }
+            }
+        },
+        "models.WebhookDelivery": {
+            "description": "Уведомление о завершении анализа: получатель, количество попыток и результат доставки.",
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "description": "Количество неудачных попыток доставки",
+                    "type": "integer",
+                    "example": 1
+                },
+                "created_at": {
+                    "type": "string",
+                    "format": "date-time"
+                },
+                "delivered_at": {
+                    "description": "Время доставки",
+                    "type": "string",
+                    "format": "date-time"
+                },
+                "event_id": {
+                    "description": "ID уведомления, по которому получатель отбрасывает повторы",
+                    "type": "string",
+                    "example": "5f1d7c3e-8a4b-4c1f-9d2e-7b6a5c4d3e2f"
+                },
+                "failed_at": {
+                    "description": "Время, когда попытки доставки были исчерпаны",
+                    "type": "string",
+                    "format": "date-time"
+                },
+                "file_id": {
+                    "type": "string",
+                    "example": "unique-file-id"
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "job_id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "last_error": {
+                    "description": "Ошибка последней неудачной попытки",
+                    "type": "string",
+                    "example": "получатель вернул код 503"
+                },
+                "next_attempt_at": {
+                    "description": "Не раньше этого времени будет следующая попытка",
+                    "type": "string",
+                    "format": "date-time"
+                },
+                "owner_id": {
+                    "description": "Пользователь, секретом которого подписывается уведомление",
+                    "type": "string",
+                    "example": "user-1"
+                },
+                "type": {
+                    "description": "analysis.succeeded или analysis.failed",
+                    "type": "string",
+                    "example": "analysis.succeeded"
+                },
+                "url": {
+                    "type": "string",
+                    "example": "https://example.com/hooks/analysis"
+                }
+            }
+        },
+        "models.WebhookEndpoint": {
+            "description": "Настройки уведомлений пользователя о завершении анализа.",
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string",
+                    "format": "date-time"
+                },
+                "owner_id": {
+                    "description": "ID пользователя",
+                    "type": "string",
+                    "example": "user-1"
+                },
+                "secret": {
+                    "description": "Секрет подписи HMAC-SHA256",
+                    "type": "string",
+                    "example": "8d0b4c1f9e2a7b6c5d4e3f2a1b0c9d8e7f6a5b4c3d2e1f0a9b8c7d6e5f4a3b2c"
+                },
+                "updated_at": {
+                    "type": "string",
+                    "format": "date-time"
+                },
+                "url": {
+                    "description": "URL для уведомлений обо всех файлах пользователя; пустой — только для указанных в запросе анализа",
+                    "type": "string",
+                    "example": "https://example.com/hooks/analysis"
+                }
+            }
+        },
+        "services.CollectionSimilarityReport": {
+            "description": "Пары файлов коллекции с оценкой сходства не ниже min_score в порядке убывания сходства. Сравниваются только проанализированные файлы; остальные перечислены в not_analyzed.",
+            "type": "object",
+            "properties": {
+                "analyzed_count": {
+                    "description": "Количество файлов, для которых есть сигнатура",
+                    "type": "integer",
+                    "example": 24
+                },
+                "collection_id": {
+                    "type": "string",
+                    "example": "collection-id"
+                },
+                "file_count": {
+                    "description": "Количество файлов в коллекции",
+                    "type": "integer",
+                    "example": 25
+                },
+                "min_score": {
+                    "description": "Наименьшая оценка сходства подозрительной пары",
+                    "type": "number",
+                    "example": 0.3
+                },
+                "not_analyzed": {
+                    "description": "ID файлов, которые еще не анализировались",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "pairs": {
+                    "description": "Не больше limit самых похожих пар",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/services.SimilarPair"
+                    }
+                },
+                "pairs_compared": {
+                    "description": "Количество сравненных пар",
+                    "type": "integer",
+                    "example": 276
+                },
+                "suspicious_count": {
+                    "description": "Количество пар с оценкой не ниже min_score",
+                    "type": "integer",
+                    "example": 3
+                }
+            }
+        },
+        "services.Passage": {
+            "description": "Фрагмент проверяемого файла и совпадающий с ним фрагмент файла-источника. Смещения отсчитываются в символах Unicode (кодовых точках) извлеченного текста файлов: конец фрагмента — смещение символа, следующего за ним.",
+            "type": "object",
+            "properties": {
+                "description": {
+                    "description": "Описание фрагмента",
+                    "type": "string",
+                    "example": "64 слова из other-file-id"
+                },
+                "end": {
+                    "description": "Конец фрагмента в проверяемом файле",
+                    "type": "integer",
+                    "example": 1290
+                },
+                "length": {
+                    "description": "Длина совпадения в словах",
+                    "type": "integer",
+                    "example": 64
+                },
+                "source": {
+                    "description": "Номер источника в списке sources",
+                    "type": "integer",
+                    "example": 0
+                },
+                "source_end": {
+                    "description": "Конец фрагмента в файле-источнике",
+                    "type": "integer",
+                    "example": 518
+                },
+                "source_file_id": {
+                    "description": "ID файла-источника; пусто для файла другого пользователя",
+                    "type": "string",
+                    "example": "other-file-id"
+                },
+                "source_start": {
+                    "description": "Начало фрагмента в файле-источнике",
+                    "type": "integer",
+                    "example": 40
+                },
+                "start": {
+                    "description": "Начало фрагмента в проверяемом файле",
+                    "type": "integer",
+                    "example": 812
+                }
+            }
+        },
+        "services.PassageReport": {
+            "description": "Фрагменты файла, совпадающие с фрагментами других проанализированных файлов, найденные по отпечаткам winnowing.",
+            "type": "object",
+            "properties": {
+                "analysis_result_id": {
+                    "description": "Результат анализа, отпечатки которого использованы",
+                    "type": "integer",
+                    "example": 1
+                },
+                "file_id": {
+                    "type": "string",
+                    "example": "unique-file-id"
+                },
+                "matched_percent": {
+                    "type": "integer",
+                    "example": 14
+                },
+                "matched_words": {
+                    "description": "Количество слов, входящих хотя бы в один фрагмент",
+                    "type": "integer",
+                    "example": 180
+                },
+                "min_words": {
+                    "description": "Наименьшая длина фрагмента в словах",
+                    "type": "integer",
+                    "example": 8
+                },
+                "passage_count": {
+                    "description": "Количество найденных фрагментов; в passages не больше limit самых длинных",
+                    "type": "integer",
+                    "example": 4
+                },
+                "passages": {
+                    "description": "Фрагменты в порядке их положения в проверяемом файле",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/services.Passage"
+                    }
+                },
+                "sources": {
+                    "description": "Источники в порядке убывания количества совпадающих слов",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/services.PassageSource"
+                    }
+                },
+                "word_count": {
+                    "description": "Количество слов проверяемого файла",
+                    "type": "integer",
+                    "example": 1250
+                }
+            }
+        },
+        "services.PassageSource": {
+            "description": "Файл-источник, количество совпадающих фрагментов и слов проверяемого файла, покрытых ими.",
+            "type": "object",
+            "properties": {
+                "file_id": {
+                    "description": "Пусто для файла другого пользователя",
+                    "type": "string",
+                    "example": "other-file-id"
+                },
+                "matched_percent": {
+                    "description": "Доля слов проверяемого файла, совпадающих с этим источником",
+                    "type": "integer",
+                    "example": 12
+                },
+                "matched_words": {
+                    "type": "integer",
+                    "example": 150
+                },
+                "passage_count": {
+                    "type": "integer",
+                    "example": 3
+                }
+            }
+        },
+        "services.ProgressEvent": {
+            "description": "Этап анализа файла: время и данные, зависящие от этапа.",
+            "type": "object",
+            "properties": {
+                "attempt": {
+                    "description": "Номер попытки анализа",
+                    "type": "integer",
+                    "example": 1
+                },
+                "cached": {
+                    "description": "Возвращен ранее сохраненный результат текущей версии (для saved)",
+                    "type": "boolean",
+                    "example": false
+                },
+                "error": {
+                    "description": "Ошибка попытки (для retrying, failed и cancelled)",
+                    "type": "string"
+                },
+                "file_id": {
+                    "type": "string",
+                    "example": "unique-file-id"
+                },
+                "job_id": {
+                    "description": "ID задачи анализа (для queued, started, retrying, failed и cancelled)",
+                    "type": "integer",
+                    "example": 1
+                },
+                "next_run_at": {
+                    "description": "Время следующей попытки (для retrying)",
+                    "type": "string",
+                    "format": "date-time"
+                },
+                "result_id": {
+                    "description": "ID результата анализа (для saved)",
+                    "type": "integer",
+                    "example": 1
+                },
+                "stage": {
+                    "description": "queued, started, fetched, counted, wordcloud, saved, retrying, failed или cancelled",
+                    "type": "string",
+                    "example": "counted"
+                },
+                "time": {
+                    "type": "string",
+                    "format": "date-time"
                 },
                 "word_cloud_location": {
-                    "description": "Путь к сохраненному изображению облака слов",
+                    "description": "Ключ облака слов; пустой, если его не удалось построить (для wordcloud)",
+                    "type": "string",
+                    "example": "unique-file-id_wordcloud.png"
+                },
+                "word_cloud_svg_location": {
+                    "description": "Ключ облака слов в SVG (для wordcloud)",
                     "type": "string",
-                    "example": "/app/file_storage_2/unique-file-id_wordcloud.png"
+                    "example": "unique-file-id_wordcloud.svg"
                 },
                 "word_count": {
+                    "description": "Количество слов текста (для counted)",
                     "type": "integer",
                     "example": 250
                 }
             }
+        },
+        "services.SimilarFile": {
+            "description": "Похожий файл и оценка сходства с ним.",
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string",
+                    "example": "Файл unique-file-id похож на файл other-file-id на 87%"
+                },
+                "file_id": {
+                    "type": "string",
+                    "example": "other-file-id"
+                },
+                "percent": {
+                    "type": "integer",
+                    "example": 87
+                },
+                "score": {
+                    "type": "number",
+                    "example": 0.87
+                }
+            }
+        },
+        "services.SimilarPair": {
+            "description": "Пара файлов коллекции и оценка их сходства.",
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string",
+                    "example": "Файл unique-file-id похож на файл other-file-id на 87%"
+                },
+                "file_id": {
+                    "type": "string",
+                    "example": "unique-file-id"
+                },
+                "matched_file_id": {
+                    "type": "string",
+                    "example": "other-file-id"
+                },
+                "percent": {
+                    "type": "integer",
+                    "example": 87
+                },
+                "score": {
+                    "type": "number",
+                    "example": 0.87
+                }
+            }
+        },
+        "services.SimilarityReport": {
+            "description": "Результат анализа сходства файла с ранее проанализированными файлами.",
+            "type": "object",
+            "properties": {
+                "file_id": {
+                    "type": "string",
+                    "example": "unique-file-id"
+                },
+                "matches": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/services.SimilarFile"
+                    }
+                }
+            }
+        },
+        "services.WordFrequencyItem": {
+            "description": "Слово (или лемма при lemmatize=true), количество его вхождений и доля от всех слов текста.",
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer",
+                    "example": 12
+                },
+                "frequency": {
+                    "description": "Доля от общего количества слов текста",
+                    "type": "number",
+                    "example": 0.0048
+                },
+                "word": {
+                    "type": "string",
+                    "example": "книга"
+                }
+            }
+        },
+        "services.WordFrequencyReport": {
+            "description": "Частоты слов текста файла в порядке убывания.",
+            "type": "object",
+            "properties": {
+                "file_id": {
+                    "type": "string",
+                    "example": "unique-file-id"
+                },
+                "include_stop_words": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/services.WordFrequencyItem"
+                    }
+                },
+                "language": {
+                    "type": "string",
+                    "example": "ru"
+                },
+                "lemmatized": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "limit": {
+                    "type": "integer",
+                    "example": 100
+                },
+                "offset": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "result_id": {
+                    "description": "ID результата анализа",
+                    "type": "integer",
+                    "example": 1
+                },
+                "total": {
+                    "description": "Количество строк таблицы с учетом фильтров",
+                    "type": "integer",
+                    "example": 640
+                },
+                "word_count": {
+                    "description": "Всего слов в тексте",
+                    "type": "integer",
+                    "example": 2500
+                }
+            }
         }
     }
 }`
@@ -1,14 +1,37 @@
 package handlers
 
 import (
+	"encoding/csv"
+	"errors"
+	"file_analysis_service/models"
 	"file_analysis_service/services"
 	"fmt"
+	"io"
+	"io/fs"
+	"log"
 	"net/http"
 	"os"
-	"path/filepath"
+	"pkg/adapters"
+	"pkg/auth"
+	"pkg/pagination"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultFrequencyLimit = 100  // Количество слов в ответе GetWordFrequencies по умолчанию
+	maxFrequencyLimit     = 1000 // Наибольшее допустимое значение limit
+	defaultPairLimit      = 100  // Количество пар в ответе GetCollectionSimilarity по умолчанию
+	maxPairLimit          = 1000 // Наибольшее допустимое значение limit для пар
+	maxPassageLimit       = 5000 // Наибольшее допустимое значение limit для фрагментов
+
+	// progressHeartbeat — как часто в поток событий анализа отправляется комментарий, чтобы прокси не закрывали
+	// простаивающее соединение; заодно перечитывается состояние задачи.
+	progressHeartbeat = 15 * time.Second
 )
 
 // AnalysisHandler обрабатывает HTTP-запросы, связанные с анализом файлов.
@@ -19,17 +42,26 @@ import (
 // @Produce json
 // @Router /analysis/{file_id} [post]
 // @Router /analysis/results/{file_id} [get]
+// @Router /analysis/results/{file_id} [delete]
+// @Router /analysis/results/{file_id}/frequencies [get]
+// @Router /analysis/results/{file_id}/history [get]
+// @Router /analysis/results/{file_id}/passages [get]
 // @Router /analysis/wordclouds [get] // Используем query param для location
+// @Router /analysis/similarity/{file_id} [get]
+// @Router /analysis/jobs/{id} [get]
+// @Router /analysis/{file_id}/events [get]
+// @Router /analysis/collections/{collection_id}/similarity [get]
 type AnalysisHandler struct {
 	AnalysisService *services.AnalysisService
+	JobQueue        *services.JobQueue
 }
 
 // NewAnalysisHandler создает новый экземпляр AnalysisHandler.
 // @Summary Создает новый AnalysisHandler
-// @Description Инициализирует AnalysisHandler с сервисом анализа.
+// @Description Инициализирует AnalysisHandler с сервисом анализа и очередью задач анализа.
 // @Return *AnalysisHandler
-func NewAnalysisHandler(analysisService *services.AnalysisService) *AnalysisHandler {
-	return &AnalysisHandler{AnalysisService: analysisService}
+func NewAnalysisHandler(analysisService *services.AnalysisService, jobQueue *services.JobQueue) *AnalysisHandler {
+	return &AnalysisHandler{AnalysisService: analysisService, JobQueue: jobQueue}
 }
 
 // AnalyzeFileRequest определяет структуру запроса на анализ файла.
@@ -39,15 +71,21 @@ type AnalyzeFileRequest struct {
 	FileID string `json:"file_id" binding:"required" example:"unique-file-id"`
 }
 
-// RequestAnalysis запускает анализ файла.
+// RequestAnalysis ставит файл в очередь на анализ.
 // @Summary Запрос на анализ файла
-// @Description Инициирует процесс анализа файла по его ID.
+// @Description Создает персистентную задачу анализа файла по его ID и возвращает ее ID. Состояние задачи можно получить по GET /analysis/jobs/{id}.
+// @Description Если для файла уже есть незавершенная задача, возвращается она.
 // @Tags analysis
+// @Description Если последний результат анализа файла получен текущей версией алгоритмов, задача вернет его без повторного анализа;
+// @Description force=true заставляет проанализировать файл заново (например, если облако слов не удалось построить). Новый результат добавляется в историю анализов файла.
 // @Param file_id path string true "ID файла для анализа"
+// @Param force query bool false "Выполнить анализ заново, даже если есть результат текущей версии"
+// @Param callback_url query string false "URL, на который по завершении задачи будет отправлено подписанное уведомление с результатом анализа или причиной неудачи"
 // @Produce json
-// @Success 202 {object} map[string]string "Сообщение о принятии запроса на анализ"
-// @Failure 400 {object} map[string]string "Ошибка валидации ID файла"
-// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера при запуске анализа"
+// @Success 202 {object} map[string]any "Сообщение о принятии запроса, ID и состояние задачи"
+// @Failure 400 {object} map[string]string "Ошибка валидации ID файла, параметра force или callback_url"
+// @Failure 404 {object} map[string]string "Файл не найден или принадлежит другому пользователю"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера при постановке задачи в очередь"
 // @Router /analysis/{file_id} [post]
 func (h *AnalysisHandler) RequestAnalysis(c *gin.Context) {
 	fileID := c.Param("file_id")
@@ -55,34 +93,191 @@ func (h *AnalysisHandler) RequestAnalysis(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "file_id не может быть пустым"})
 		return
 	}
+	if !h.authorizeFile(c, fileID) {
+		return
+	}
 
-	// Запускаем анализ асинхронно (в реальном приложении здесь могла бы быть очередь)
-	// Для данного примера, выполняем синхронно, но возвращаем 202 Accepted
-	go func() {
-		_, err := h.AnalysisService.AnalyzeFile(fileID)
-		if err != nil {
-			// В реальном приложении здесь было бы логирование ошибки
-			// Для простоты, просто выводим в консоль сервера
-			println(err.Error()) // Используем println, чтобы не импортировать log/fmt здесь
+	force := false
+	if value := c.Query("force"); value != "" {
+		var err error
+		if force, err = strconv.ParseBool(value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Параметр 'force' должен быть true или false"})
+			return
+		}
+	}
+
+	callbackURL := c.Query("callback_url")
+	if callbackURL != "" {
+		if h.JobQueue.Webhooks == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Уведомления о завершении анализа отключены"})
+			return
+		}
+		if err := services.ValidateWebhookURL(callbackURL); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	job, err := h.JobQueue.Enqueue(fileID, force)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if callbackURL != "" {
+		identity, _ := auth.FromHeaders(c.Request.Header)
+		if err := h.JobQueue.Webhooks.RegisterCallback(job.ID, identity.UserID, callbackURL); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	response := gin.H{
+		"message": fmt.Sprintf("Запрос на анализ файла %s принят", fileID),
+		"job_id":  job.ID,
+		"status":  job.Status,
+		"force":   job.Force,
+	}
+	if callbackURL != "" {
+		response["callback_url"] = callbackURL
+	}
+	c.JSON(http.StatusAccepted, response)
+}
+
+// StreamAnalysisEvents передает ход анализа файла потоком Server-Sent Events.
+// @Summary Поток событий анализа файла
+// @Description Первым событием передается текущее состояние последней задачи анализа файла (queued, started, retrying, saved, failed или cancelled),
+// @Description затем — этапы анализа по мере выполнения: started, fetched, counted, wordcloud, saved; при неудачной попытке — retrying, после исчерпания попыток — failed, если файл удален во время анализа — cancelled.
+// @Description Имя SSE-события совпадает с полем stage, данные — объект ProgressEvent в JSON. Поток закрывается после saved, failed или cancelled.
+// @Description Каждые 15 секунд отправляется комментарий, чтобы прокси не закрывали соединение.
+// @Tags analysis
+// @Param file_id path string true "ID файла"
+// @Produce text/event-stream
+// @Success 200 {object} services.ProgressEvent "Поток событий"
+// @Failure 404 {object} map[string]string "Файл не найден, принадлежит другому пользователю или не анализировался"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /analysis/{file_id}/events [get]
+func (h *AnalysisHandler) StreamAnalysisEvents(c *gin.Context) {
+	fileID := c.Param("file_id")
+	if !h.authorizeFile(c, fileID) {
+		return
+	}
+
+	// Подписка оформляется до чтения состояния задачи, чтобы не потерять события, опубликованные между ними
+	progress, unsubscribe := h.AnalysisService.Progress.Subscribe(fileID)
+	defer unsubscribe()
+
+	var current services.ProgressEvent
+	job, err := h.JobQueue.LatestJob(fileID)
+	switch {
+	case err == nil:
+		current = services.JobProgress(job)
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		// Файл анализировался до появления очереди задач или его задачи удалены
+		var result models.AnalysisResult
+		if err := h.AnalysisService.DBAdapter.Last(&result, "file_id = ?", fileID); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Файл с ID %s не анализировался", fileID)})
+			return
 		}
-	}()
+		current = services.ProgressEvent{Stage: services.ProgressSaved, FileID: fileID, Time: result.CreatedAt, ResultID: result.ID, Cached: true}
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
 
-	c.JSON(http.StatusAccepted, gin.H{"message": fmt.Sprintf("Запрос на анализ файла %s принят", fileID)})
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no") // Запрещает буферизацию ответа nginx
+	c.SSEvent(current.Stage, current)
+	c.Writer.Flush()
+	if current.Final() {
+		return
+	}
+
+	heartbeat := time.NewTicker(progressHeartbeat)
+	defer heartbeat.Stop()
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case event, ok := <-progress:
+			if !ok {
+				return false
+			}
+			c.SSEvent(event.Stage, event)
+			return !event.Final()
+		case <-heartbeat.C:
+			// Последнее событие могло быть опубликовано до подписки, а задача — завершиться
+			// уже после чтения ее состояния: тогда оно берется из сохраненной задачи
+			if job != nil {
+				if latest, err := h.JobQueue.GetJob(job.ID); err == nil {
+					if event := services.JobProgress(latest); event.Final() {
+						c.SSEvent(event.Stage, event)
+						return false
+					}
+				}
+			}
+			_, err := io.WriteString(w, ": ping\n\n")
+			return err == nil
+		case <-c.Request.Context().Done():
+			return false
+		}
+	})
+}
+
+// GetAnalysisJob возвращает состояние задачи анализа.
+// @Summary Получение состояния задачи анализа
+// @Description Возвращает состояние задачи анализа (pending, running, succeeded, failed, cancelled), количество попыток и последнюю ошибку.
+// @Tags analysis
+// @Param id path int true "ID задачи анализа"
+// @Produce json
+// @Success 200 {object} models.AnalysisJob "Состояние задачи"
+// @Failure 400 {object} map[string]string "Некорректный ID задачи"
+// @Failure 404 {object} map[string]string "Задача не найдена"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /analysis/jobs/{id} [get]
+func (h *AnalysisHandler) GetAnalysisJob(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный ID задачи"})
+		return
+	}
+
+	job, err := h.JobQueue.GetJob(uint(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	if !h.authorizeFile(c, job.FileID) {
+		return
+	}
+	c.JSON(http.StatusOK, job)
 }
 
 // GetAnalysisResults получает результаты анализа файла.
 // @Summary Получение результатов анализа
-// @Description Возвращает результаты анализа файла (количество абзацев, слов, символов) по его ID.
+// @Description Возвращает статистику текста файла по его ID: количество абзацев, непустых строк, предложений, слов (всего и различных),
+// @Description символов (без пробельных и со всеми), среднюю длину слова и предложения и лексическую плотность.
+// @Description По умолчанию возвращается последний результат; result_id позволяет получить результат из истории анализов файла.
 // @Tags analysis
 // @Param file_id path string true "ID файла"
+// @Param result_id query int false "ID результата анализа из истории (по умолчанию последний)"
 // @Produce json
 // @Success 200 {object} models.AnalysisResult "Результаты анализа (без облака слов)"
+// @Failure 400 {object} map[string]string "Некорректный result_id"
 // @Failure 404 {object} map[string]string "Результаты анализа не найдены"
 // @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
 // @Router /analysis/results/{file_id} [get]
 func (h *AnalysisHandler) GetAnalysisResults(c *gin.Context) {
 	fileID := c.Param("file_id")
-	result, err := h.AnalysisService.GetAnalysisResult(fileID)
+	if !h.authorizeFile(c, fileID) {
+		return
+	}
+	resultID, ok := resultIDQuery(c)
+	if !ok {
+		return
+	}
+	result, err := h.AnalysisService.GetAnalysisResult(fileID, resultID)
 	if err != nil {
 		if _, ok := err.(interface{ NotFound() }); ok || os.IsNotExist(err) || strings.Contains(err.Error(), "не найдены") {
 			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
@@ -93,20 +288,198 @@ func (h *AnalysisHandler) GetAnalysisResults(c *gin.Context) {
 	}
 
 	response := gin.H{
-		"file_id":         result.FileID,
-		"paragraph_count": result.ParagraphCount,
-		"word_count":      result.WordCount,
-		"character_count": result.CharacterCount,
+		"id":                          result.ID,
+		"created_at":                  result.CreatedAt,
+		"analyzer_version":            result.AnalyzerVersion,
+		"file_id":                     result.FileID,
+		"language":                    result.Language,
+		"paragraph_count":             result.ParagraphCount,
+		"line_count":                  result.LineCount,
+		"sentence_count":              result.SentenceCount,
+		"word_count":                  result.WordCount,
+		"unique_word_count":           result.UniqueWordCount,
+		"character_count":             result.CharacterCount,
+		"character_count_with_spaces": result.CharacterCountWithSpaces,
+		"average_word_length":         result.AverageWordLength,
+		"average_sentence_length":     result.AverageSentenceLength,
+		"lexical_density":             result.LexicalDensity,
 	}
 
 	c.JSON(http.StatusOK, response)
 }
 
+// DeleteAnalysisResults удаляет результаты анализа файла.
+// @Summary Удаление результатов анализа файла
+// @Description Удаляет все результаты анализа файла, его частоты слов, сигнатуру, отпечатки, совпадения сходства, задачи анализа и облака слов.
+// @Description Вызывается File Storing Service при удалении файла. Повторный вызов безопасен и возвращает нулевые счетчики.
+// @Tags analysis
+// @Param file_id path string true "ID файла"
+// @Produce json
+// @Success 200 {object} map[string]any "Количество удаленных результатов анализа и облаков слов (file_id, deleted_results, deleted_word_clouds)"
+// @Failure 404 {object} map[string]string "Файл принадлежит другому пользователю"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /analysis/results/{file_id} [delete]
+func (h *AnalysisHandler) DeleteAnalysisResults(c *gin.Context) {
+	fileID := c.Param("file_id")
+	if !h.authorizeFile(c, fileID) {
+		return
+	}
+	deletedResults, deletedWordClouds, err := h.AnalysisService.DeleteAnalysisResults(fileID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if deletedResults > 0 {
+		// Отметка нужна только фильтру has_analysis списка файлов, поэтому ошибка ее отправки не отменяет удаление
+		if err := h.AnalysisService.FileStoringServiceAdapter.SetFileAnalyzed(fileID, false); err != nil {
+			log.Printf("Не удалось сбросить отметку об анализе файла %s: %v", fileID, err)
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"file_id":             fileID,
+		"deleted_results":     deletedResults,
+		"deleted_word_clouds": deletedWordClouds,
+	})
+}
+
+// GetAnalysisHistory возвращает историю анализов файла.
+// @Summary Получение истории анализов файла
+// @Description Возвращает все результаты анализа файла в порядке от нового к старому вместе с версией алгоритмов, которой получен каждый из них,
+// @Description и текущей версией алгоритмов сервиса. Это позволяет сравнить результаты до и после повторного анализа.
+// @Tags analysis
+// @Param file_id path string true "ID файла"
+// @Produce json
+// @Success 200 {object} map[string]any "История анализов (file_id, analyzer_version — текущая версия, results — результаты анализа)"
+// @Failure 404 {object} map[string]string "Файл еще не анализировался"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /analysis/results/{file_id}/history [get]
+func (h *AnalysisHandler) GetAnalysisHistory(c *gin.Context) {
+	fileID := c.Param("file_id")
+	if !h.authorizeFile(c, fileID) {
+		return
+	}
+	results, err := h.AnalysisService.GetAnalysisHistory(fileID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"file_id":          fileID,
+		"analyzer_version": services.AnalyzerVersion,
+		"results":          results,
+	})
+}
+
+// resultIDQuery разбирает необязательный параметр result_id. При ошибке отправляет ответ 400 и возвращает false.
+func resultIDQuery(c *gin.Context) (uint, bool) {
+	value := c.Query("result_id")
+	if value == "" {
+		return 0, true
+	}
+	id, err := strconv.ParseUint(value, 10, 64)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Параметр 'result_id' должен быть положительным числом"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// GetWordFrequencies возвращает таблицу частот слов файла.
+// @Summary Получение частот слов
+// @Description Возвращает самые частые слова текста файла, сохраненные при анализе, в порядке убывания количества вхождений.
+// @Description При lemmatize=true формы одного слова (для русского и английского языков) объединяются. Служебные слова по умолчанию исключаются.
+// @Tags analysis
+// @Param file_id path string true "ID файла"
+// @Param limit query int false "Максимальное количество слов (по умолчанию 100, не более 1000)"
+// @Param offset query int false "Сколько слов пропустить (по умолчанию 0)"
+// @Param stopwords query bool false "Включать ли служебные слова (по умолчанию false)"
+// @Param lemmatize query bool false "Объединять ли формы одного слова (по умолчанию false)"
+// @Param format query string false "Формат ответа: json (по умолчанию) или csv" Enums(json, csv)
+// @Param result_id query int false "ID результата анализа из истории (по умолчанию последний)"
+// @Produce json
+// @Produce text/csv
+// @Success 200 {object} services.WordFrequencyReport "Частоты слов"
+// @Failure 400 {object} map[string]string "Некорректные параметры запроса"
+// @Failure 404 {object} map[string]string "Результаты анализа не найдены"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /analysis/results/{file_id}/frequencies [get]
+func (h *AnalysisHandler) GetWordFrequencies(c *gin.Context) {
+	query := services.WordFrequencyQuery{Limit: defaultFrequencyLimit}
+	var err error
+	if value := c.Query("limit"); value != "" {
+		if query.Limit, err = strconv.Atoi(value); err != nil || query.Limit <= 0 || query.Limit > maxFrequencyLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Параметр 'limit' должен быть числом от 1 до %d", maxFrequencyLimit)})
+			return
+		}
+	}
+	if value := c.Query("offset"); value != "" {
+		if query.Offset, err = strconv.Atoi(value); err != nil || query.Offset < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Параметр 'offset' должен быть неотрицательным числом"})
+			return
+		}
+	}
+	if value := c.Query("stopwords"); value != "" {
+		if query.IncludeStopWords, err = strconv.ParseBool(value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Параметр 'stopwords' должен быть true или false"})
+			return
+		}
+	}
+	if value := c.Query("lemmatize"); value != "" {
+		if query.Lemmatize, err = strconv.ParseBool(value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Параметр 'lemmatize' должен быть true или false"})
+			return
+		}
+	}
+	var ok bool
+	if query.ResultID, ok = resultIDQuery(c); !ok {
+		return
+	}
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "csv" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Параметр 'format' должен быть json или csv"})
+		return
+	}
+
+	fileID := c.Param("file_id")
+	if !h.authorizeFile(c, fileID) {
+		return
+	}
+	report, err := h.AnalysisService.GetWordFrequencies(fileID, query)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	if format == "json" {
+		c.JSON(http.StatusOK, report)
+		return
+	}
+	// Параметры выборки в CSV не попадают, поэтому общее количество строк передается заголовком
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileID+"_frequencies.csv"))
+	c.Header("X-Total-Count", strconv.Itoa(report.Total))
+	c.Status(http.StatusOK)
+	writer := csv.NewWriter(c.Writer)
+	_ = writer.Write([]string{"word", "count", "frequency"})
+	for _, item := range report.Items {
+		_ = writer.Write([]string{item.Word, strconv.Itoa(item.Count), strconv.FormatFloat(item.Frequency, 'f', -1, 64)})
+	}
+	writer.Flush()
+}
+
 // GetWordCloud получает изображение облака слов.
 // @Summary Получение облака слов
-// @Description Возвращает изображение облака слов по его location (пути к файлу).
+// @Description Возвращает изображение облака слов по его location (ключу в хранилище, полученному из результатов анализа).
 // @Tags analysis
-// @Param location query string true "Location (путь) к файлу облака слов"
+// @Param location query string true "Location (ключ в хранилище) файла облака слов"
 // @Produce image/png
 // @Produce image/jpeg
 // @Produce image/gif
@@ -125,21 +498,25 @@ func (h *AnalysisHandler) GetWordCloud(c *gin.Context) {
 
 	fmt.Printf("DEBUG: Получен запрос на облако слов по location: %s\n", location)
 
-	// Проверим, указан ли относительный или абсолютный путь
-	// и обработаем соответственно
-	if !filepath.IsAbs(location) && !strings.HasPrefix(location, "/app/file_storage_2/") {
-		// Если location - относительный путь, добавим к нему путь файлового хранилища
-		storagePathEnv := os.Getenv("FILE_STORAGE_PATH")
-		if storagePathEnv == "" {
-			storagePathEnv = "/app/file_storage_2" // Значение по умолчанию
+	// Облако слов доступно тем же пользователям, что и файл, для которого оно построено
+	if identity, ok := auth.FromHeaders(c.Request.Header); ok && !identity.IsAdmin() {
+		fileID, err := h.AnalysisService.WordCloudFileID(location)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Облако слов не найдено по указанному пути: %s", location)})
+			} else {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			}
+			return
+		}
+		if !h.authorizeFile(c, fileID) {
+			return
 		}
-		location = filepath.Join(storagePathEnv, location)
-		fmt.Printf("DEBUG: Преобразован путь к облаку слов: %s\n", location)
 	}
 
 	imageData, contentType, err := h.AnalysisService.GetWordCloudImage(location)
 	if err != nil {
-		if os.IsNotExist(err) || strings.Contains(err.Error(), "не найден") || strings.Contains(err.Error(), "недопустимый путь") {
+		if errors.Is(err, fs.ErrNotExist) || strings.Contains(err.Error(), "не найден") || strings.Contains(err.Error(), "недопустимый ключ") {
 			c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Облако слов не найдено по указанному пути: %s. Ошибка: %v", location, err)})
 		} else {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Ошибка при получении облака слов: %v", err)})
@@ -159,7 +536,7 @@ func (h *AnalysisHandler) GetWordCloud(c *gin.Context) {
 	}
 
 	// Устанавливаем заголовок, чтобы браузер знал, что это изображение и показал его
-	c.Header("Cache-Control", "public, max-age=86400") // Кешировать на 24 часа
+	c.Header("Cache-Control", "private, max-age=86400") // Кешировать на 24 часа только в браузере: облако слов доступно не всем пользователям
 	c.Header("Content-Type", contentType)
 
 	// Вместо Content-Disposition: inline, который может вызывать проблемы в некоторых браузерах,
@@ -169,19 +546,299 @@ func (h *AnalysisHandler) GetWordCloud(c *gin.Context) {
 	c.Data(http.StatusOK, contentType, imageData)
 }
 
-// ListAnalysisResultsHandler возвращает список всех доступных результатов анализа.
-// @Summary Список всех результатов анализа (для отладки)
-// @Description Возвращает file_id и location облака слов для всех проанализированных файлов.
+// AnalysisResultListResponse — страница списка результатов анализа.
+// @Description Результаты анализа текущей страницы, общее количество результатов, подходящих под фильтры, и курсор следующей страницы.
+// @Name AnalysisResultListResponse
+type AnalysisResultListResponse struct {
+	Items      []models.AnalysisResult `json:"items"`
+	Total      int64                   `json:"total" example:"120"`                                                                           // Количество результатов, подходящих под фильтры, на всех страницах
+	NextCursor string                  `json:"next_cursor,omitempty" example:"eyJzIjoiY3JlYXRlZF9hdCJ9"`                                      // Курсор следующей страницы; пусто на последней странице
+	Next       string                  `json:"next,omitempty" example:"?cursor=eyJzIjoiY3JlYXRlZF9hdCJ9&limit=50&order=desc&sort=created_at"` // Ссылка на следующую страницу относительно текущего пути
+}
+
+// ListAnalysisResultsHandler возвращает страницу списка результатов анализа.
+// @Summary Список результатов анализа
+// @Description Возвращает результаты анализа постранично: пользователю — только результаты его файлов, администратору — всех файлов
+// @Description или файлов владельца owner_id. Страницы выбираются по курсору: ссылка на следующую страницу
+// @Description возвращается в поле next, на последней странице ее нет.
 // @Tags analysis
+// @Param limit query int false "Количество результатов на странице (по умолчанию 50, не более 500)"
+// @Param cursor query string false "Курсор следующей страницы из поля next_cursor"
+// @Param sort query string false "Поле сортировки (по умолчанию created_at)" Enums(created_at, file_id, word_count)
+// @Param order query string false "Порядок сортировки (по умолчанию desc)" Enums(asc, desc)
+// @Param file_id query string false "ID файла"
+// @Param owner_id query string false "ID владельца файла (другого пользователя — только для администратора)"
+// @Param language query string false "Код языка текста (ISO 639-1 или und)"
+// @Param analyzer_version query string false "Версия алгоритмов анализа"
+// @Param created_after query string false "Результаты, полученные не раньше этого времени (RFC 3339)"
+// @Param created_before query string false "Результаты, полученные раньше этого времени (RFC 3339)"
 // @Produce json
-// @Success 200 {array} models.AnalysisResult "Список результатов анализа"
+// @Success 200 {object} AnalysisResultListResponse "Страница списка результатов анализа"
+// @Failure 400 {object} map[string]string "Некорректные параметры запроса"
+// @Failure 403 {object} map[string]string "Результаты анализа файлов другого пользователя доступны только администратору"
 // @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
 // @Router /analysis/results-all [get]
 func (h *AnalysisHandler) ListAnalysisResultsHandler(c *gin.Context) {
-	results, err := h.AnalysisService.ListAnalysisResults()
+	params := c.Request.URL.Query()
+	page, err := services.ResultSorting.Parse(params)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	filter := services.ResultFilter{
+		FileID:          params.Get("file_id"),
+		OwnerID:         params.Get("owner_id"),
+		Language:        params.Get("language"),
+		AnalyzerVersion: params.Get("analyzer_version"),
+	}
+	if identity, ok := auth.FromHeaders(c.Request.Header); ok && !identity.IsAdmin() {
+		if filter.OwnerID != "" && !identity.CanAccess(filter.OwnerID) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Результаты анализа файлов другого пользователя доступны только администратору"})
+			return
+		}
+		filter.OwnerID = identity.UserID
+	}
+	if filter.CreatedAfter, err = pagination.TimeParam(params, "created_after"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if filter.CreatedBefore, err = pagination.TimeParam(params, "created_before"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	results, total, err := h.AnalysisService.ListAnalysisResults(filter, page)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить список результатов анализа: " + err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, results)
+	response := AnalysisResultListResponse{Total: total}
+	size, nextCursor, next := page.Next(params, len(results), func(i int) (any, any) {
+		result := results[i]
+		switch page.Sort {
+		case "file_id":
+			return result.FileID, result.ID
+		case "word_count":
+			return result.WordCount, result.ID
+		}
+		return result.CreatedAt, result.ID
+	})
+	response.Items, response.NextCursor, response.Next = results[:size], nextCursor, next
+	c.JSON(http.StatusOK, response)
+}
+
+// GetSimilarFiles возвращает файлы, наиболее похожие на указанный.
+// @Summary Получение похожих файлов
+// @Description Возвращает наиболее похожие на файл ранее проанализированные файлы с оценкой сходства (MinHash по шинглам из слов).
+// @Tags analysis
+// @Param file_id path string true "ID файла"
+// @Produce json
+// @Success 200 {object} services.SimilarityReport "Список похожих файлов"
+// @Failure 404 {object} map[string]string "Файл еще не анализировался"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /analysis/similarity/{file_id} [get]
+func (h *AnalysisHandler) GetSimilarFiles(c *gin.Context) {
+	fileID := c.Param("file_id")
+	if !h.authorizeFile(c, fileID) {
+		return
+	}
+	report, err := h.AnalysisService.GetSimilarFiles(fileID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	// Похожие файлы других пользователей показываются без их ID
+	if identity, ok := auth.FromHeaders(c.Request.Header); ok && !identity.IsAdmin() && len(report.Matches) > 0 {
+		ids := make([]string, len(report.Matches))
+		for i, match := range report.Matches {
+			ids[i] = match.FileID
+		}
+		owners, err := h.AnalysisService.FileStoringServiceAdapter.GetFileOwners(ids)
+		if err != nil {
+			c.JSON(http.StatusBadGateway, gin.H{"error": "Не удалось проверить владельцев похожих файлов: " + err.Error()})
+			return
+		}
+		report.HideFiles(func(matchedFileID string) bool {
+			owner, found := owners[matchedFileID]
+			return found && identity.CanAccess(owner)
+		})
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// GetPassageReport возвращает отчет о фрагментах файла, совпадающих с фрагментами других файлов.
+// @Summary Отчет о совпадающих фрагментах (заимствованиях)
+// @Description Возвращает фрагменты последнего проанализированного текста файла, совпадающие с фрагментами других проанализированных файлов:
+// @Description смещения начала и конца фрагмента в файле и в файле-источнике (в символах Unicode извлеченного текста), ID источника и длину совпадения в словах.
+// @Description Совпадения находятся по отпечаткам winnowing, поэтому фрагменты короче 8 слов могут быть пропущены.
+// @Description С format=html возвращается страница с текстом файла, в котором совпадающие фрагменты выделены цветом источника.
+// @Description Источники других пользователей показываются без ID.
+// @Tags analysis
+// @Param file_id path string true "ID файла"
+// @Param format query string false "Формат отчета (по умолчанию json)" Enums(json, html)
+// @Param min_words query int false "Наименьшая длина фрагмента в словах (по умолчанию 8)"
+// @Param limit query int false "Сколько самых длинных фрагментов включить в отчет (по умолчанию 500, не более 5000)"
+// @Produce json
+// @Produce html
+// @Success 200 {object} services.PassageReport "Отчет о совпадающих фрагментах"
+// @Failure 400 {object} map[string]string "Некорректные параметры запроса"
+// @Failure 404 {object} map[string]string "Файл еще не анализировался"
+// @Failure 409 {object} map[string]string "Файл проанализирован предыдущей версией алгоритмов; нужен повторный анализ"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Failure 502 {object} map[string]string "Не удалось получить текст файла или владельцев источников из File Storing Service"
+// @Router /analysis/results/{file_id}/passages [get]
+func (h *AnalysisHandler) GetPassageReport(c *gin.Context) {
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "html" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Параметр 'format' должен быть json или html"})
+		return
+	}
+	minWords := services.DefaultPassageMinWords
+	if value := c.Query("min_words"); value != "" {
+		var err error
+		if minWords, err = strconv.Atoi(value); err != nil || minWords <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Параметр 'min_words' должен быть положительным числом"})
+			return
+		}
+	}
+	limit := services.DefaultPassageLimit
+	if value := c.Query("limit"); value != "" {
+		var err error
+		if limit, err = strconv.Atoi(value); err != nil || limit <= 0 || limit > maxPassageLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Параметр 'limit' должен быть числом от 1 до %d", maxPassageLimit)})
+			return
+		}
+	}
+	fileID := c.Param("file_id")
+	if !h.authorizeFile(c, fileID) {
+		return
+	}
+
+	report, err := h.AnalysisService.GetPassageReport(fileID, minWords, limit)
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrOutdatedAnalysis):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	// Источники других пользователей показываются без их ID
+	if identity, ok := auth.FromHeaders(c.Request.Header); ok && !identity.IsAdmin() && len(report.Sources) > 0 {
+		ids := make([]string, len(report.Sources))
+		for i, source := range report.Sources {
+			ids[i] = source.FileID
+		}
+		owners, err := h.AnalysisService.FileStoringServiceAdapter.GetFileOwners(ids)
+		if err != nil {
+			c.JSON(http.StatusBadGateway, gin.H{"error": "Не удалось проверить владельцев файлов-источников: " + err.Error()})
+			return
+		}
+		report.HideSources(func(sourceFileID string) bool {
+			owner, found := owners[sourceFileID]
+			return found && identity.CanAccess(owner)
+		})
+	}
+	if format == "json" {
+		c.JSON(http.StatusOK, report)
+		return
+	}
+
+	text, truncated, err := h.AnalysisService.ReadFileText(fileID, maxPassageHTMLRunes)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	page, err := renderPassageReport(report, text, truncated)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось сформировать отчет: " + err.Error()})
+		return
+	}
+	c.Data(http.StatusOK, "text/html; charset=utf-8", page)
+}
+
+// GetCollectionSimilarity попарно сравнивает файлы коллекции и возвращает самые похожие пары.
+// @Summary Сравнение файлов коллекции
+// @Description Вычисляет попарное сходство всех проанализированных файлов коллекции (MinHash по шинглам из слов) и возвращает пары
+// @Description с оценкой не ниже min_score в порядке убывания сходства. Файлы, которые еще не анализировались, не сравниваются и перечислены в not_analyzed.
+// @Tags analysis
+// @Param collection_id path string true "ID коллекции"
+// @Param min_score query number false "Наименьшая оценка сходства от 0 до 1 (по умолчанию 0.3)"
+// @Param limit query int false "Максимальное количество пар (по умолчанию 100, не более 1000)"
+// @Produce json
+// @Success 200 {object} services.CollectionSimilarityReport "Подозрительные пары файлов коллекции"
+// @Failure 400 {object} map[string]string "Некорректные параметры запроса"
+// @Failure 404 {object} map[string]string "Коллекция не найдена"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Failure 502 {object} map[string]string "Не удалось получить файлы коллекции от File Storing Service"
+// @Router /analysis/collections/{collection_id}/similarity [get]
+func (h *AnalysisHandler) GetCollectionSimilarity(c *gin.Context) {
+	minScore := services.DefaultCollectionMinScore
+	if value := c.Query("min_score"); value != "" {
+		var err error
+		if minScore, err = strconv.ParseFloat(value, 64); err != nil || minScore < 0 || minScore > 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Параметр 'min_score' должен быть числом от 0 до 1"})
+			return
+		}
+	}
+	limit := defaultPairLimit
+	if value := c.Query("limit"); value != "" {
+		var err error
+		if limit, err = strconv.Atoi(value); err != nil || limit <= 0 || limit > maxPairLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Параметр 'limit' должен быть числом от 1 до %d", maxPairLimit)})
+			return
+		}
+	}
+
+	collectionID := c.Param("collection_id")
+	collection, err := h.AnalysisService.FileStoringServiceAdapter.GetCollectionFiles(collectionID)
+	if err != nil {
+		if errors.Is(err, adapters.ErrCollectionNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Коллекция с ID %s не найдена", collectionID)})
+		} else {
+			c.JSON(http.StatusBadGateway, gin.H{"error": "Не удалось получить файлы коллекции: " + err.Error()})
+		}
+		return
+	}
+	// Чужая коллекция для пользователя не отличается от несуществующей
+	if identity, ok := auth.FromHeaders(c.Request.Header); ok && !identity.CanAccess(collection.OwnerID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Коллекция с ID %s не найдена", collectionID)})
+		return
+	}
+
+	report, err := h.AnalysisService.CompareFiles(collection.ID, collection.FileIDs, minScore, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// authorizeFile проверяет, что пользователь, от имени которого пришел запрос, может работать с файлом fileID,
+// и иначе сам отправляет ответ. Чужой файл для пользователя не отличается от несуществующего.
+// Запросы без пользователя (от других сервисов) и запросы администраторов не ограничиваются.
+func (h *AnalysisHandler) authorizeFile(c *gin.Context, fileID string) bool {
+	identity, ok := auth.FromHeaders(c.Request.Header)
+	if !ok || identity.IsAdmin() {
+		return true
+	}
+	owners, err := h.AnalysisService.FileStoringServiceAdapter.GetFileOwners([]string{fileID})
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Не удалось проверить владельца файла: " + err.Error()})
+		return false
+	}
+	if owner, found := owners[fileID]; !found || !identity.CanAccess(owner) {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Файл с ID %s не найден", fileID)})
+		return false
+	}
+	return true
 }
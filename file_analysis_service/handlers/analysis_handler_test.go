@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"bufio"
+	"file_analysis_service/models"
+	"file_analysis_service/services"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"pkg/adapters"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newTestEventsServer запускает сервер с потоком событий анализа поверх отдельной базы SQLite в памяти.
+func newTestEventsServer(t *testing.T) (*httptest.Server, *AnalysisHandler, *gorm.DB) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("не удалось открыть SQLite: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	t.Cleanup(func() { sqlDB.Close() })
+	if err := db.AutoMigrate(&models.AnalysisJob{}, &models.AnalysisResult{}); err != nil {
+		t.Fatalf("не удалось выполнить миграцию: %v", err)
+	}
+	dbAdapter := &adapters.DBAdapter{DB: db}
+	analysisService := &services.AnalysisService{DBAdapter: dbAdapter, Progress: services.NewProgressBroker()}
+	handler := NewAnalysisHandler(analysisService, services.NewJobQueue(dbAdapter, analysisService))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/analysis/:file_id/events", handler.StreamAnalysisEvents)
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	return server, handler, db
+}
+
+// readEventName читает из потока SSE имя следующего события; пустое имя — поток закрыт.
+func readEventName(t *testing.T, reader *bufio.Reader) string {
+	t.Helper()
+	name := ""
+	for {
+		line, err := reader.ReadString('\n')
+		if err == io.EOF {
+			return ""
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		line = strings.TrimRight(line, "\n")
+		if strings.HasPrefix(line, "event:") {
+			name = strings.TrimPrefix(line, "event:")
+		}
+		if line == "" && name != "" {
+			return name
+		}
+	}
+}
+
+func TestStreamAnalysisEventsClosesAfterFinalEvent(t *testing.T) {
+	server, handler, db := newTestEventsServer(t)
+	db.Create(&models.AnalysisJob{FileID: "f1", Status: models.JobStatusRunning, Attempts: 1, MaxAttempts: 3})
+
+	// Поток, который не закрылся после события saved, прерывается по таймауту клиента
+	client := server.Client()
+	client.Timeout = 5 * time.Second
+	resp, err := client.Get(server.URL + "/analysis/f1/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		t.Fatalf("ответ %d, Content-Type %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	reader := bufio.NewReader(resp.Body)
+	// Поток начинается с сохраненного состояния задачи; к этому моменту обработчик уже подписан на события
+	if name := readEventName(t, reader); name != services.ProgressStarted {
+		t.Fatalf("первое событие %q, ожидалось состояние задачи started", name)
+	}
+
+	stages := []string{services.ProgressFetched, services.ProgressCounted, services.ProgressWordCloud, services.ProgressSaved}
+	for _, stage := range stages {
+		handler.AnalysisService.Progress.Publish(services.ProgressEvent{Stage: stage, FileID: "f1"})
+	}
+	// События после последнего в поток не попадают
+	handler.AnalysisService.Progress.Publish(services.ProgressEvent{Stage: services.ProgressStarted, FileID: "f1"})
+
+	var names []string
+	for name := readEventName(t, reader); name != ""; name = readEventName(t, reader) {
+		names = append(names, name)
+	}
+	if !reflect.DeepEqual(names, stages) {
+		t.Fatalf("события %v, ожидалось %v", names, stages)
+	}
+}
+
+func TestStreamAnalysisEventsOfFinishedJob(t *testing.T) {
+	server, _, db := newTestEventsServer(t)
+	now := time.Now()
+	db.Create(&models.AnalysisJob{FileID: "f1", Status: models.JobStatusFailed, Attempts: 3, MaxAttempts: 3, LastError: "ошибка", FinishedAt: &now})
+
+	resp, err := server.Client().Get(server.URL + "/analysis/f1/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	// Задача уже завершилась: поток состоит из ее последнего события и сразу закрывается
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(body), "event:failed\n") || strings.Count(string(body), "event:") != 1 {
+		t.Fatalf("поток завершенной задачи:\n%s", body)
+	}
+
+	// Файл без задач и результатов анализа не найден
+	resp, err = server.Client().Get(server.URL + "/analysis/f2/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("поток файла без анализа: %d, ожидалось 404", resp.StatusCode)
+	}
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"errors"
+	"file_analysis_service/services"
+	"net/http"
+	"pkg/events"
+
+	"github.com/gin-gonic/gin"
+)
+
+// EventHandler принимает события о файлах от File Storing Service.
+// @Summary Обработчик событий о файлах
+// @Description Принимает события file.uploaded и file.deleted, доставляемые из outbox File Storing Service.
+// @Tags events
+// @Accept json
+// @Produce json
+// @Router /internal/events [post]
+type EventHandler struct {
+	Consumer *services.EventConsumer
+}
+
+// NewEventHandler создает новый экземпляр EventHandler.
+// @Summary Создает новый EventHandler
+// @Description Инициализирует EventHandler с обработчиком событий.
+// @Return *EventHandler
+func NewEventHandler(consumer *services.EventConsumer) *EventHandler {
+	return &EventHandler{Consumer: consumer}
+}
+
+// ReceiveEvent принимает событие о файле.
+// @Summary Прием события о файле
+// @Description Внутренний эндпоинт для File Storing Service. file.uploaded ставит файл в очередь анализа (если AUTO_ANALYZE не выключен или файл загружен с analyze=true),
+// @Description file.deleted удаляет все результаты анализа файла. Событие с уже обработанным ID повторно не обрабатывается,
+// @Description поэтому доставку можно безопасно повторять. Ответ 2xx означает, что событие принято.
+// @Tags events
+// @Accept json
+// @Param event body object true "Событие о файле (id, type: file.uploaded или file.deleted, file_id, occurred_at, data)"
+// @Produce json
+// @Success 200 {object} map[string]any "Событие обработано (event_id, status: processed или duplicate)"
+// @Failure 400 {object} map[string]string "Некорректное событие"
+// @Failure 500 {object} map[string]string "Ошибка обработки события; отправитель повторит доставку"
+// @Router /internal/events [post]
+func (h *EventHandler) ReceiveEvent(c *gin.Context) {
+	var event events.Event
+	if err := c.ShouldBindJSON(&event); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректное тело события: " + err.Error()})
+		return
+	}
+
+	duplicate, err := h.Consumer.Handle(c.Request.Context(), event)
+	if err != nil {
+		if errors.Is(err, events.ErrInvalidEvent) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	status := "processed"
+	if duplicate {
+		status = "duplicate"
+	}
+	c.JSON(http.StatusOK, gin.H{"event_id": event.ID, "status": status})
+}
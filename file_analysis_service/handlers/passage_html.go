@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"bytes"
+	"file_analysis_service/services"
+	"fmt"
+	"html/template"
+	"sort"
+)
+
+// maxPassageHTMLRunes — сколько символов текста файла показывается в HTML-отчете о фрагментах.
+const maxPassageHTMLRunes = 1 << 20
+
+// passageColors — число цветов, которыми выделяются фрагменты разных источников в HTML-отчете.
+const passageColors = 8
+
+// passageSegment — часть текста файла в HTML-отчете: выделенная, если входит в совпадающий фрагмент.
+type passageSegment struct {
+	Text   string
+	Marked bool
+	Color  int    // Номер цвета источника
+	Title  string // Описание самого длинного фрагмента, в который входит часть текста
+}
+
+// passageSource — строка таблицы источников HTML-отчета.
+type passageSource struct {
+	services.PassageSource
+	Number int
+	Color  int
+}
+
+// passagePage — данные шаблона HTML-отчета.
+type passagePage struct {
+	Report    *services.PassageReport
+	Sources   []passageSource
+	Segments  []passageSegment
+	Truncated bool
+}
+
+var passageTemplate = template.Must(template.New("passages").Funcs(template.FuncMap{
+	"inc": func(i int) int { return i + 1 },
+}).Parse(`<!DOCTYPE html>
+<html lang="ru">
+<head>
+<meta charset="utf-8">
+<title>Совпадающие фрагменты файла {{.Report.FileID}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin: 1em 0; }
+th, td { border: 1px solid #ccc; padding: 0.3em 0.6em; text-align: left; }
+.text { white-space: pre-wrap; font-family: serif; line-height: 1.5; border: 1px solid #ccc; padding: 1em; }
+.swatch { display: inline-block; width: 1em; height: 1em; vertical-align: middle; }
+.c0 { background: #ffd54f; } .c1 { background: #81d4fa; } .c2 { background: #a5d6a7; } .c3 { background: #f48fb1; }
+.c4 { background: #ce93d8; } .c5 { background: #ffab91; } .c6 { background: #80cbc4; } .c7 { background: #e6ee9c; }
+mark { color: inherit; }
+</style>
+</head>
+<body>
+<h1>Совпадающие фрагменты файла {{.Report.FileID}}</h1>
+<p>С другими файлами совпадает {{.Report.MatchedWords}} из {{.Report.WordCount}} слов ({{.Report.MatchedPercent}}%).
+Найдено фрагментов не короче {{.Report.MinWords}} слов: {{.Report.PassageCount}}{{if lt (len .Report.Passages) .Report.PassageCount}}, выделены {{len .Report.Passages}} самых длинных{{end}}.</p>
+{{if .Sources}}
+<table>
+<tr><th>№</th><th>Источник</th><th>Фрагментов</th><th>Совпадающих слов</th></tr>
+{{range .Sources}}<tr><td><span class="swatch c{{.Color}}"></span> {{.Number}}</td><td>{{if .FileID}}{{.FileID}}{{else}}файл другого пользователя{{end}}</td><td>{{.PassageCount}}</td><td>{{.MatchedWords}} ({{.MatchedPercent}}%)</td></tr>
+{{end}}</table>
+{{end}}
+<div class="text">{{range .Segments}}{{if .Marked}}<mark class="c{{.Color}}" title="{{.Title}}">{{.Text}}</mark>{{else}}{{.Text}}{{end}}{{end}}</div>
+{{if .Truncated}}<p>Показано начало текста; фрагменты дальше него перечислены в таблице ниже.</p>{{end}}
+{{if .Report.Passages}}
+<h2>Фрагменты</h2>
+<table>
+<tr><th>Начало</th><th>Конец</th><th>Источник</th><th>Начало в источнике</th><th>Конец в источнике</th><th>Слов</th></tr>
+{{range .Report.Passages}}<tr><td>{{.Start}}</td><td>{{.End}}</td><td>{{inc .Source}}</td><td>{{.SourceStart}}</td><td>{{.SourceEnd}}</td><td>{{.Length}}</td></tr>
+{{end}}</table>
+{{end}}
+</body>
+</html>
+`))
+
+// renderPassageReport формирует HTML-страницу отчета: текст файла, в котором каждая часть, входящая в совпадающие
+// фрагменты, выделена цветом источника самого длинного из них. text — начало текста файла; truncated сообщает, что текст длиннее.
+func renderPassageReport(report *services.PassageReport, text string, truncated bool) ([]byte, error) {
+	page := passagePage{Report: report, Truncated: truncated}
+	for i, source := range report.Sources {
+		page.Sources = append(page.Sources, passageSource{PassageSource: source, Number: i + 1, Color: i % passageColors})
+	}
+
+	runes := []rune(text)
+	// Границы частей текста — начала и концы фрагментов
+	bounds := []int{0, len(runes)}
+	for _, passage := range report.Passages {
+		for _, bound := range []int{passage.Start, passage.End} {
+			if bound > 0 && bound < len(runes) {
+				bounds = append(bounds, bound)
+			}
+		}
+	}
+	sort.Ints(bounds)
+	for i := 0; i+1 < len(bounds); i++ {
+		start, end := bounds[i], bounds[i+1]
+		if start == end {
+			continue
+		}
+		segment := passageSegment{Text: string(runes[start:end])}
+		longest := -1
+		for j, passage := range report.Passages {
+			if passage.Start <= start && end <= passage.End && (longest < 0 || passage.Length > report.Passages[longest].Length) {
+				longest = j
+			}
+		}
+		if longest >= 0 {
+			passage := report.Passages[longest]
+			segment.Marked = true
+			segment.Color = passage.Source % passageColors
+			segment.Title = fmt.Sprintf("Источник %d: %s, символы %d–%d", passage.Source+1, passage.Description, passage.SourceStart, passage.SourceEnd)
+		}
+		// Соседние части одного фрагмента объединяются
+		if last := len(page.Segments) - 1; last >= 0 && page.Segments[last].Marked == segment.Marked && page.Segments[last].Title == segment.Title {
+			page.Segments[last].Text += segment.Text
+			continue
+		}
+		page.Segments = append(page.Segments, segment)
+	}
+
+	var buf bytes.Buffer
+	if err := passageTemplate.Execute(&buf, page); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"errors"
+	"file_analysis_service/services"
+	"fmt"
+	"net/http"
+	"pkg/auth"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultDeliveryLimit = 20  // Количество уведомлений в ответе ListWebhookDeliveries по умолчанию
+	maxDeliveryLimit     = 100 // Наибольшее допустимое значение limit для уведомлений
+)
+
+// WebhookHandler обрабатывает HTTP-запросы к настройкам уведомлений о завершении анализа.
+// @Summary Обработчик настроек уведомлений
+// @Description Позволяет пользователю задать URL уведомлений о завершении анализа его файлов, получить секрет подписи и историю доставки.
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Router /analysis/webhook [get]
+// @Router /analysis/webhook [put]
+// @Router /analysis/webhook [delete]
+// @Router /analysis/webhook/deliveries [get]
+type WebhookHandler struct {
+	Dispatcher *services.WebhookDispatcher
+}
+
+// NewWebhookHandler создает новый экземпляр WebhookHandler.
+// @Summary Создает новый WebhookHandler
+// @Description Инициализирует WebhookHandler с сервисом доставки уведомлений.
+// @Return *WebhookHandler
+func NewWebhookHandler(dispatcher *services.WebhookDispatcher) *WebhookHandler {
+	return &WebhookHandler{Dispatcher: dispatcher}
+}
+
+// WebhookEndpointRequest определяет структуру запроса на изменение настроек уведомлений.
+// @Description Структура запроса для изменения настроек уведомлений о завершении анализа.
+// @Name WebhookEndpointRequest
+type WebhookEndpointRequest struct {
+	URL          string `json:"url" example:"https://example.com/hooks/analysis"` // URL уведомлений обо всех файлах пользователя; пустой отключает их
+	RotateSecret bool   `json:"rotate_secret" example:"false"`                    // Заменить секрет подписи новым
+}
+
+// GetWebhook возвращает настройки уведомлений пользователя.
+// @Summary Получение настроек уведомлений
+// @Description Возвращает URL уведомлений о завершении анализа файлов пользователя и секрет, которым подписываются все его уведомления.
+// @Description Настройки создаются при первом PUT /analysis/webhook или первом запросе анализа с callback_url.
+// @Tags webhooks
+// @Produce json
+// @Success 200 {object} models.WebhookEndpoint "Настройки уведомлений"
+// @Failure 404 {object} map[string]string "Пользователь не настраивал уведомления"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /analysis/webhook [get]
+func (h *WebhookHandler) GetWebhook(c *gin.Context) {
+	identity, _ := auth.FromHeaders(c.Request.Header)
+	endpoint, err := h.Dispatcher.GetEndpoint(identity.UserID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Уведомления не настроены"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, endpoint)
+}
+
+// SetWebhook задает URL уведомлений пользователя.
+// @Summary Изменение настроек уведомлений
+// @Description Задает URL, на который отправляются уведомления о завершении анализа всех файлов пользователя, в том числе проанализированных автоматически после загрузки.
+// @Description Пустой url отключает такие уведомления; уведомления на callback_url из запросов анализа продолжают отправляться. rotate_secret=true заменяет секрет подписи.
+// @Tags webhooks
+// @Accept json
+// @Param request body WebhookEndpointRequest true "URL уведомлений"
+// @Produce json
+// @Success 200 {object} models.WebhookEndpoint "Настройки уведомлений"
+// @Failure 400 {object} map[string]string "Некорректный запрос или URL"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /analysis/webhook [put]
+func (h *WebhookHandler) SetWebhook(c *gin.Context) {
+	var request WebhookEndpointRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректное тело запроса: " + err.Error()})
+		return
+	}
+	identity, _ := auth.FromHeaders(c.Request.Header)
+	endpoint, err := h.Dispatcher.SetEndpoint(identity.UserID, request.URL, request.RotateSecret)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidWebhookURL) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, endpoint)
+}
+
+// DeleteWebhook удаляет настройки уведомлений пользователя.
+// @Summary Удаление настроек уведомлений
+// @Description Удаляет URL и секрет уведомлений пользователя. Недоставленные уведомления и уведомления, запрошенные с callback_url, не отправляются.
+// @Tags webhooks
+// @Success 204 {string} string "Настройки удалены"
+// @Failure 404 {object} map[string]string "Пользователь не настраивал уведомления"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /analysis/webhook [delete]
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	identity, _ := auth.FromHeaders(c.Request.Header)
+	deleted, err := h.Dispatcher.DeleteEndpoint(identity.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !deleted {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Уведомления не настроены"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ListWebhookDeliveries возвращает последние уведомления пользователя.
+// @Summary История доставки уведомлений
+// @Description Возвращает последние уведомления пользователя (сначала новые): получатель, количество неудачных попыток, время доставки или отказа и последнюю ошибку.
+// @Tags webhooks
+// @Param limit query int false "Количество уведомлений (по умолчанию 20, не более 100)"
+// @Produce json
+// @Success 200 {array} models.WebhookDelivery "Уведомления"
+// @Failure 400 {object} map[string]string "Некорректный параметр limit"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /analysis/webhook/deliveries [get]
+func (h *WebhookHandler) ListWebhookDeliveries(c *gin.Context) {
+	limit := defaultDeliveryLimit
+	if value := c.Query("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 || parsed > maxDeliveryLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Параметр 'limit' должен быть числом от 1 до %d", maxDeliveryLimit)})
+			return
+		}
+		limit = parsed
+	}
+	identity, _ := auth.FromHeaders(c.Request.Header)
+	deliveries, err := h.Dispatcher.ListDeliveries(identity.UserID, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, deliveries)
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"file_analysis_service/handlers"
 	"file_analysis_service/models"
 	"file_analysis_service/services"
@@ -8,6 +9,10 @@ import (
 	"log"
 	"os"
 	"pkg/adapters"
+	"pkg/auth"
+	"pkg/wordcloud"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -42,19 +47,37 @@ func main() {
 	postgresDB := os.Getenv("POSTGRES_DB_DB2")
 	postgresHost := os.Getenv("POSTGRES_HOST_DB2")
 	postgresPort := os.Getenv("POSTGRES_PORT_DB2")
+	wordCloudGenerator := os.Getenv("WORDCLOUD_GENERATOR") // local или remote
 	wordCloudAPIURL := os.Getenv("WORDCLOUD_API_URL")
 	fileStoragePath := os.Getenv("FILE_STORAGE_PATH") // Для сохранения облаков слов
 	fileStoringServiceAddr := os.Getenv("FILE_STORING_SERVICE_ADDR")
+	similarityTopN := os.Getenv("SIMILARITY_TOP_N")
+	wordFrequencyTopK := os.Getenv("WORD_FREQUENCY_TOP_K")
+	analysisWorkers := os.Getenv("ANALYSIS_WORKERS")
+	analysisMaxAttempts := os.Getenv("ANALYSIS_MAX_ATTEMPTS")
+	analysisRetryDelay := os.Getenv("ANALYSIS_RETRY_DELAY")
+	autoAnalyze := os.Getenv("AUTO_ANALYZE")
+	serviceAuthSecret := os.Getenv("SERVICE_AUTH_SECRET")
+	internalListenAddr := os.Getenv("INTERNAL_LISTEN_ADDR")
+	webhookMaxAttempts := os.Getenv("WEBHOOK_MAX_ATTEMPTS")
+	webhookTimeout := os.Getenv("WEBHOOK_TIMEOUT")
+	webhookAllowPrivate := os.Getenv("WEBHOOK_ALLOW_PRIVATE_NETWORKS")
 
 	if fileStoragePath == "" {
 		fileStoragePath = "./file_storage_2" // Значение по умолчанию
 	}
+	if wordCloudGenerator == "" {
+		wordCloudGenerator = "local" // По умолчанию облако слов строится без обращения к внешним сервисам
+	}
 	if wordCloudAPIURL == "" {
 		wordCloudAPIURL = "https://quickchart.io/wordcloud" // Значение по умолчанию
 	}
 	if fileStoringServiceAddr == "" {
 		fileStoringServiceAddr = "http://localhost:8081" // Значение по умолчанию для локального запуска
 	}
+	if serviceAuthSecret == "" {
+		log.Fatalf("Не задан SERVICE_AUTH_SECRET: общий секрет для подписи запросов между сервисами")
+	}
 
 	// Инициализация адаптеров
 	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=disable TimeZone=Europe/Moscow",
@@ -64,38 +87,201 @@ func main() {
 		log.Fatalf("Не удалось инициализировать DBAdapter: %v", err)
 	}
 
-	err = dbAdapter.AutoMigrate(&models.AnalysisResult{})
+	// До появления истории анализов file_id в analysis_results был уникальным: снимаем это ограничение
+	if dbAdapter.DB.Migrator().HasIndex(&models.AnalysisResult{}, "idx_analysis_results_file_id") {
+		if err := dbAdapter.DB.Migrator().DropIndex(&models.AnalysisResult{}, "idx_analysis_results_file_id"); err != nil {
+			log.Fatalf("Не удалось удалить уникальный индекс analysis_results.file_id: %v", err)
+		}
+	}
+
+	err = dbAdapter.AutoMigrate(&models.AnalysisResult{}, &models.WordFrequency{}, &models.FileSignature{}, &models.SimilarityMatch{}, &models.FileFingerprint{}, &models.SignatureBand{}, &models.AnalysisJob{}, &models.ProcessedEvent{},
+		&models.WebhookEndpoint{}, &models.AnalysisCallback{}, &models.WebhookDelivery{})
 	if err != nil {
 		log.Fatalf("Не удалось выполнить миграцию БД для AnalysisResult: %v", err)
 	}
 
-	fsAdapter, err := adapters.NewFileStorageAdapter(fileStoragePath)
+	// Хранилище облаков слов: локальная директория или S3-совместимое хранилище
+	wordCloudStorage, err := adapters.NewFileStorage(adapters.StorageConfig{
+		Backend:     os.Getenv("STORAGE_BACKEND"),
+		LocalPath:   fileStoragePath,
+		S3Endpoint:  os.Getenv("S3_ENDPOINT"),
+		S3Region:    os.Getenv("S3_REGION"),
+		S3Bucket:    os.Getenv("S3_BUCKET"),
+		S3AccessKey: os.Getenv("S3_ACCESS_KEY_ID"),
+		S3SecretKey: os.Getenv("S3_SECRET_ACCESS_KEY"),
+	})
 	if err != nil {
-		log.Fatalf("Не удалось инициализировать FileStorageAdapter для облаков слов: %v", err)
+		log.Fatalf("Не удалось инициализировать хранилище для облаков слов: %v", err)
 	}
 
-	storingServiceAdapter := adapters.NewFileStoringServiceAdapter(fileStoringServiceAddr)
-	cloudAPIAdapter := adapters.NewWordCloudAPIAdapter(wordCloudAPIURL)
+	// Внутренние эндпоинты File Storing Service принимают только подписанные запросы
+	storingServiceAdapter := adapters.NewFileStoringServiceAdapter(fileStoringServiceAddr,
+		auth.ServiceSigner{Service: "file_analysis_service", Secret: []byte(serviceAuthSecret)})
+
+	var cloudGenerator adapters.WordCloudGenerator
+	switch wordCloudGenerator {
+	case "local":
+		cloudGenerator, err = newLocalWordCloudAdapter()
+		if err != nil {
+			log.Fatalf("Не удалось инициализировать локальный генератор облака слов: %v", err)
+		}
+	case "remote":
+		cloudGenerator = adapters.NewWordCloudAPIAdapter(wordCloudAPIURL)
+	default:
+		log.Fatalf("Некорректное значение WORDCLOUD_GENERATOR: %s (допустимы local и remote)", wordCloudGenerator)
+	}
 
 	// Инициализация сервиса
-	analysisService := services.NewAnalysisService(dbAdapter, fsAdapter, storingServiceAdapter, cloudAPIAdapter)
+	analysisService := services.NewAnalysisService(dbAdapter, wordCloudStorage, storingServiceAdapter, cloudGenerator)
+	if similarityTopN != "" {
+		topN, err := strconv.Atoi(similarityTopN)
+		if err != nil || topN <= 0 {
+			log.Fatalf("Некорректное значение SIMILARITY_TOP_N: %s", similarityTopN)
+		}
+		analysisService.SimilarityTopN = topN
+	}
+	if wordFrequencyTopK != "" {
+		topK, err := strconv.Atoi(wordFrequencyTopK)
+		if err != nil || topK <= 0 {
+			log.Fatalf("Некорректное значение WORD_FREQUENCY_TOP_K: %s", wordFrequencyTopK)
+		}
+		analysisService.WordFrequencyTopK = topK
+	}
+
+	// Инициализация очереди задач анализа
+	jobQueue := services.NewJobQueue(dbAdapter, analysisService)
+	if analysisWorkers != "" {
+		workers, err := strconv.Atoi(analysisWorkers)
+		if err != nil || workers <= 0 {
+			log.Fatalf("Некорректное значение ANALYSIS_WORKERS: %s", analysisWorkers)
+		}
+		jobQueue.Workers = workers
+	}
+	if analysisMaxAttempts != "" {
+		maxAttempts, err := strconv.Atoi(analysisMaxAttempts)
+		if err != nil || maxAttempts <= 0 {
+			log.Fatalf("Некорректное значение ANALYSIS_MAX_ATTEMPTS: %s", analysisMaxAttempts)
+		}
+		jobQueue.MaxAttempts = maxAttempts
+	}
+	if analysisRetryDelay != "" {
+		retryDelay, err := time.ParseDuration(analysisRetryDelay)
+		if err != nil || retryDelay <= 0 {
+			log.Fatalf("Некорректное значение ANALYSIS_RETRY_DELAY: %s", analysisRetryDelay)
+		}
+		jobQueue.RetryDelay = retryDelay
+	}
+
+	// Уведомления о завершении задач анализа
+	allowPrivateNetworks := false
+	if webhookAllowPrivate != "" {
+		allowPrivateNetworks, err = strconv.ParseBool(webhookAllowPrivate)
+		if err != nil {
+			log.Fatalf("Некорректное значение WEBHOOK_ALLOW_PRIVATE_NETWORKS: %s", webhookAllowPrivate)
+		}
+	}
+	webhookDispatcher := services.NewWebhookDispatcher(dbAdapter, allowPrivateNetworks)
+	if webhookMaxAttempts != "" {
+		maxAttempts, err := strconv.Atoi(webhookMaxAttempts)
+		if err != nil || maxAttempts <= 0 {
+			log.Fatalf("Некорректное значение WEBHOOK_MAX_ATTEMPTS: %s", webhookMaxAttempts)
+		}
+		webhookDispatcher.MaxAttempts = maxAttempts
+	}
+	if webhookTimeout != "" {
+		timeout, err := time.ParseDuration(webhookTimeout)
+		if err != nil || timeout <= 0 {
+			log.Fatalf("Некорректное значение WEBHOOK_TIMEOUT: %s", webhookTimeout)
+		}
+		webhookDispatcher.Client.Timeout = timeout
+	}
+	jobQueue.Webhooks = webhookDispatcher
 
-	// Инициализация обработчика
-	analysisHandler := handlers.NewAnalysisHandler(analysisService)
+	// Административная команда (например, rebuild-index) выполняется вместо запуска сервера
+	if len(os.Args) > 1 {
+		if err := runCommand(os.Args[1:], analysisService, jobQueue); err != nil {
+			log.Fatalf("Не удалось выполнить команду %s: %v", os.Args[1], err)
+		}
+		return
+	}
+
+	// Файлы, проанализированные до появления индекса сходства, добавляются в него в фоне,
+	// а результатам, полученным до сохранения владельцев, назначается владелец файла
+	go func() {
+		if err := analysisService.EnsureSimilarityIndex(context.Background()); err != nil {
+			log.Printf("Предупреждение: %v", err)
+		}
+		if err := analysisService.EnsureResultOwners(context.Background()); err != nil {
+			log.Printf("Предупреждение: %v", err)
+		}
+	}()
+
+	if err := jobQueue.Start(context.Background()); err != nil {
+		log.Fatalf("Не удалось запустить очередь задач анализа: %v", err)
+	}
+	webhookDispatcher.Start(context.Background())
+
+	// Обработка событий о загрузке и удалении файлов от File Storing Service
+	eventConsumer := services.NewEventConsumer(dbAdapter, analysisService, jobQueue)
+	if autoAnalyze != "" {
+		enabled, err := strconv.ParseBool(autoAnalyze)
+		if err != nil {
+			log.Fatalf("Некорректное значение AUTO_ANALYZE: %s", autoAnalyze)
+		}
+		eventConsumer.AutoAnalyze = enabled
+	}
+
+	// Инициализация обработчиков
+	analysisHandler := handlers.NewAnalysisHandler(analysisService, jobQueue)
+	eventHandler := handlers.NewEventHandler(eventConsumer)
+	webhookHandler := handlers.NewWebhookHandler(webhookDispatcher)
 
 	r := gin.Default()
 
-	apiV1 := r.Group("/api/v1")
+	// Пользовательские эндпоинты принимают только запросы, которые API Gateway переслал с подписанным пользователем
+	apiV1 := r.Group("/api/v1", auth.IdentityAuth([]byte(serviceAuthSecret)))
 	{
 		analysisGroup := apiV1.Group("/analysis")
 		{
 			analysisGroup.POST("/:file_id", analysisHandler.RequestAnalysis)
 			analysisGroup.GET("/results/:file_id", analysisHandler.GetAnalysisResults)
+			analysisGroup.DELETE("/results/:file_id", analysisHandler.DeleteAnalysisResults)
+			analysisGroup.GET("/results/:file_id/frequencies", analysisHandler.GetWordFrequencies)
+			analysisGroup.GET("/results/:file_id/history", analysisHandler.GetAnalysisHistory)
+			analysisGroup.GET("/results/:file_id/passages", analysisHandler.GetPassageReport)
 			analysisGroup.GET("/wordclouds", analysisHandler.GetWordCloud)                // location передается как query param
 			analysisGroup.GET("/results-all", analysisHandler.ListAnalysisResultsHandler) // Для отладки
+			analysisGroup.GET("/similarity/:file_id", analysisHandler.GetSimilarFiles)
+			analysisGroup.GET("/jobs/:id", analysisHandler.GetAnalysisJob)
+			analysisGroup.GET("/:file_id/events", analysisHandler.StreamAnalysisEvents)
+			analysisGroup.GET("/collections/:collection_id/similarity", analysisHandler.GetCollectionSimilarity)
+			analysisGroup.GET("/webhook", webhookHandler.GetWebhook)
+			analysisGroup.PUT("/webhook", webhookHandler.SetWebhook)
+			analysisGroup.DELETE("/webhook", webhookHandler.DeleteWebhook)
+			analysisGroup.GET("/webhook/deliveries", webhookHandler.ListWebhookDeliveries)
 		}
 	}
 
+	// Внутренние эндпоинты, не предназначенные для прямого вызова пользователем через API Gateway.
+	// Принимают только запросы, подписанные SERVICE_AUTH_SECRET; если задан INTERNAL_LISTEN_ADDR,
+	// обслуживаются отдельным портом, который не нужно открывать наружу
+	internalRouter := r
+	if internalListenAddr != "" {
+		internalRouter = gin.Default()
+	}
+	internalGroup := internalRouter.Group("/api/v1/internal", auth.ServiceAuth([]byte(serviceAuthSecret)))
+	{
+		internalGroup.POST("/events", eventHandler.ReceiveEvent)
+	}
+	if internalListenAddr != "" {
+		go func() {
+			log.Printf("Внутренние эндпоинты File Analysis Service доступны на %s", internalListenAddr)
+			if err := internalRouter.Run(internalListenAddr); err != nil {
+				log.Fatalf("Не удалось запустить сервер внутренних эндпоинтов: %v", err)
+			}
+		}()
+	}
+
 	// Swagger документация
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
@@ -104,3 +290,40 @@ func main() {
 		log.Fatalf("Не удалось запустить сервер: %v", err)
 	}
 }
+
+// newLocalWordCloudAdapter создает локальный генератор облака слов по переменным окружения
+// WORDCLOUD_WIDTH, WORDCLOUD_HEIGHT, WORDCLOUD_MAX_WORDS, WORDCLOUD_PALETTE и WORDCLOUD_STOP_WORDS.
+func newLocalWordCloudAdapter() (*adapters.LocalWordCloudAdapter, error) {
+	options := wordcloud.DefaultOptions()
+	intSettings := map[string]*int{
+		"WORDCLOUD_WIDTH":     &options.Width,
+		"WORDCLOUD_HEIGHT":    &options.Height,
+		"WORDCLOUD_MAX_WORDS": &options.MaxWords,
+	}
+	for name, target := range intSettings {
+		if value := os.Getenv(name); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil || parsed <= 0 {
+				return nil, fmt.Errorf("некорректное значение %s: %s", name, value)
+			}
+			*target = parsed
+		}
+	}
+	if value := os.Getenv("WORDCLOUD_PALETTE"); value != "" {
+		palette, err := wordcloud.ParsePalette(value)
+		if err != nil {
+			return nil, fmt.Errorf("некорректное значение WORDCLOUD_PALETTE: %w", err)
+		}
+		options.Palette = palette
+	}
+
+	adapter := adapters.NewLocalWordCloudAdapter(options)
+	if value := os.Getenv("WORDCLOUD_STOP_WORDS"); value != "" {
+		filter, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("некорректное значение WORDCLOUD_STOP_WORDS: %s", value)
+		}
+		adapter.FilterStopWords = filter
+	}
+	return adapter, nil
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+)
+
+// Состояния задачи анализа.
+const (
+	JobStatusPending   = "pending"   // Задача ожидает выполнения
+	JobStatusRunning   = "running"   // Задача выполняется одним из воркеров
+	JobStatusSucceeded = "succeeded" // Анализ успешно завершен
+	JobStatusFailed    = "failed"    // Все попытки анализа исчерпаны
+	JobStatusCancelled = "cancelled" // Файл удален во время анализа; задача не повторяется
+)
+
+// AnalysisJob представляет задачу анализа файла в персистентной очереди.
+// У файла не больше одной незавершенной задачи без force и одной с force (индекс idx_analysis_jobs_active, см. JobQueue.Enqueue).
+// @Description Задача анализа файла: состояние, количество попыток и последняя ошибка.
+// @Name AnalysisJob
+type AnalysisJob struct {
+	ID        uint      `json:"id" swaggertype:"integer" example:"1"` // ID задачи
+	CreatedAt time.Time `json:"created_at" swaggertype:"string" format:"date-time"`
+	UpdatedAt time.Time `json:"updated_at" swaggertype:"string" format:"date-time"`
+
+	FileID string `json:"file_id" gorm:"index;uniqueIndex:idx_analysis_jobs_active,where:status = 'pending' OR status = 'running'" example:"unique-file-id"` // ID анализируемого файла
+
+	Status      string     `json:"status" gorm:"index" example:"pending"`                               // pending, running, succeeded, failed или cancelled
+	Attempts    int        `json:"attempts" example:"1"`                                                // Количество выполненных попыток
+	MaxAttempts int        `json:"max_attempts" example:"3"`                                            // Максимальное количество попыток
+	Force       bool       `json:"force" gorm:"uniqueIndex:idx_analysis_jobs_active" example:"false"`   // Выполнить анализ заново, даже если есть результат текущей версии
+	LastError   string     `json:"last_error,omitempty" example:"FileStoringService вернул ошибку 404"` // Ошибка последней неудачной попытки
+	NextRunAt   time.Time  `json:"next_run_at" gorm:"index" swaggertype:"string" format:"date-time"`    // Не раньше этого времени задача будет взята в работу
+	StartedAt   *time.Time `json:"started_at,omitempty" swaggertype:"string" format:"date-time"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty" swaggertype:"string" format:"date-time"`
+	ResultID    *uint      `json:"result_id,omitempty" swaggertype:"integer" example:"1"` // ID записи AnalysisResult после успешного анализа
+}
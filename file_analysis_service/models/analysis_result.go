@@ -7,7 +7,8 @@ import (
 )
 
 // AnalysisResult представляет результаты анализа файла.
-// @Description Результаты анализа текстового файла, включая количество абзацев, слов, символов и путь к облаку слов.
+// @Description Результаты анализа текстового файла: статистика текста (абзацы, строки, предложения, слова, символы, средние длины, лексическая плотность) и путь к облаку слов.
+// @Description Для одного файла хранится история анализов: каждый повторный анализ создает новую запись.
 // @Name AnalysisResult
 type AnalysisResult struct {
 	// gorm.Model заменено на явные поля для Swagger
@@ -16,9 +17,33 @@ type AnalysisResult struct {
 	UpdatedAt time.Time      `json:"updated_at" swaggertype:"string" format:"date-time"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty" swaggertype:"string" format:"date-time"`
 
-	FileID            string `json:"file_id" gorm:"uniqueIndex" example:"unique-file-id"` // ID оригинального файла
-	ParagraphCount    int    `json:"paragraph_count" example:"5"`
-	WordCount         int    `json:"word_count" example:"250"`
-	CharacterCount    int    `json:"character_count" example:"1500"`
-	WordCloudLocation string `json:"word_cloud_location" example:"/app/file_storage_2/unique-file-id_wordcloud.png"` // Путь к сохраненному изображению облака слов
+	FileID                   string  `json:"file_id" gorm:"index:idx_analysis_results_file_history" example:"unique-file-id"` // ID оригинального файла; у файла может быть несколько результатов анализа
+	OwnerID                  string  `json:"owner_id,omitempty" gorm:"index" example:"user1"`                                 // ID владельца файла; пустой у файлов без владельца
+	AnalyzerVersion          string  `json:"analyzer_version" example:"2"`                                                    // Версия алгоритмов анализа, которой получен результат; пустая у результатов, полученных до введения версий
+	Language                 string  `json:"language" example:"ru"`                                                           // Код языка текста по ISO 639-1 или "und", если язык не определен
+	ParagraphCount           int     `json:"paragraph_count" example:"5"`                                                     // Абзацы, разделенные пустыми строками
+	LineCount                int     `json:"line_count" example:"12"`                                                         // Непустые строки
+	SentenceCount            int     `json:"sentence_count" example:"18"`
+	WordCount                int     `json:"word_count" example:"250"`
+	UniqueWordCount          int     `json:"unique_word_count" example:"140"`                                          // Различные слова без учета регистра
+	CharacterCount           int     `json:"character_count" example:"1500"`                                           // Символы без пробельных
+	CharacterCountWithSpaces int     `json:"character_count_with_spaces" example:"1750"`                               // Все символы, включая пробельные
+	AverageWordLength        float64 `json:"average_word_length" example:"5.2"`                                        // Средняя длина слова в символах
+	AverageSentenceLength    float64 `json:"average_sentence_length" example:"13.89"`                                  // Средняя длина предложения в словах
+	LexicalDensity           float64 `json:"lexical_density" example:"0.62"`                                           // Доля знаменательных (не служебных) слов
+	WordCloudLocation        string  `json:"word_cloud_location" example:"unique-file-id_wordcloud.png"`               // Ключ основного (растрового) изображения облака слов в хранилище
+	WordCloudSVGLocation     string  `json:"word_cloud_svg_location,omitempty" example:"unique-file-id_wordcloud.svg"` // Ключ того же облака слов в SVG; пустой у результатов, полученных до сохранения SVG
+
+	WordFrequencies []WordFrequency `json:"-" gorm:"constraint:OnDelete:CASCADE"` // Самые частые слова текста
+}
+
+// WordCloudLocations возвращает ключи всех сохраненных изображений облака слов результата без повторов.
+func (r AnalysisResult) WordCloudLocations() []string {
+	var locations []string
+	for _, location := range []string{r.WordCloudLocation, r.WordCloudSVGLocation} {
+		if location != "" && (len(locations) == 0 || locations[0] != location) {
+			locations = append(locations, location)
+		}
+	}
+	return locations
 }
@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+)
+
+// ProcessedEvent — событие от File Storing Service, которое уже обработано.
+// Нужна для идемпотентной обработки: повторно доставленное событие с тем же ID пропускается.
+// @Description Обработанное событие о файле: ID, тип и время обработки.
+// @Name ProcessedEvent
+type ProcessedEvent struct {
+	EventID     string    `json:"event_id" gorm:"primaryKey;size:36" example:"5f1d7c3e-8a4b-4c1f-9d2e-7b6a5c4d3e2f"`
+	Type        string    `json:"type" example:"file.uploaded"`
+	FileID      string    `json:"file_id" gorm:"index" example:"unique-file-id"`
+	ProcessedAt time.Time `json:"processed_at" swaggertype:"string" format:"date-time"`
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+)
+
+// FileSignature хранит MinHash-сигнатуру проанализированного файла.
+// @Description MinHash-сигнатура множества шинглов (n-грамм слов) файла, используемая для поиска похожих файлов.
+// @Name FileSignature
+type FileSignature struct {
+	FileID       string    `json:"file_id" gorm:"primaryKey" example:"unique-file-id"`
+	ShingleCount int       `json:"shingle_count" example:"248"` // Количество уникальных шинглов в файле (для очень больших файлов — оценка)
+	Signature    []byte    `json:"-"`                           // Значения MinHash, упакованные в little-endian uint64
+	CreatedAt    time.Time `json:"created_at" swaggertype:"string" format:"date-time"`
+}
+
+// SimilarityMatch представляет один из наиболее похожих на файл ранее проанализированных файлов.
+// @Description Оценка сходства (коэффициент Жаккара по MinHash) между файлом и ранее проанализированным файлом.
+// @Name SimilarityMatch
+type SimilarityMatch struct {
+	ID               uint      `json:"id" swaggertype:"integer" example:"1"`
+	CreatedAt        time.Time `json:"created_at" swaggertype:"string" format:"date-time"`
+	FileID           string    `json:"file_id" gorm:"index" example:"unique-file-id"`        // ID проанализированного файла
+	AnalysisResultID uint      `json:"analysis_result_id" gorm:"index" example:"1"`          // ID результата анализа, при котором найдено совпадение; 0 у совпадений, найденных до введения истории анализов
+	MatchedFileID    string    `json:"matched_file_id" gorm:"index" example:"other-file-id"` // ID похожего файла
+	Score            float64   `json:"score" example:"0.87"`                                 // Оценка коэффициента Жаккара от 0 до 1
+}
+
+// FileFingerprint — отпечаток winnowing: хеш k-граммы слов проанализированного файла и ее положение в тексте.
+// @Description Отпечаток, выбранный алгоритмом winnowing; по совпадающим отпечаткам находятся общие фрагменты текста файлов.
+// @Name FileFingerprint
+type FileFingerprint struct {
+	ID       uint   `json:"-"`
+	FileID   string `json:"file_id" gorm:"index" example:"unique-file-id"`
+	Hash     int64  `json:"hash" gorm:"index" example:"-4203212473281123571"` // Хеш k-граммы (uint64, сохраненный как int64)
+	Position int    `json:"position" example:"120"`                           // Номер первого слова k-граммы в тексте (с 0)
+	Start    int    `json:"start" example:"812"`                              // Смещение первого символа k-граммы в тексте (в символах Unicode)
+	End      int    `json:"end" example:"846"`                                // Смещение символа, следующего за k-граммой
+}
+
+// SignatureBand — запись LSH-индекса MinHash-сигнатур: хеш одной полосы сигнатуры файла.
+// @Description Файлы с одинаковым хешем хотя бы одной полосы сигнатуры становятся кандидатами в похожие (locality-sensitive hashing).
+// @Name SignatureBand
+type SignatureBand struct {
+	Band   int    `json:"band" gorm:"primaryKey;autoIncrement:false" example:"7"`               // Номер полосы сигнатуры
+	Hash   int64  `json:"hash" gorm:"primaryKey;autoIncrement:false" example:"-42032124732811"` // Хеш значений MinHash полосы
+	FileID string `json:"file_id" gorm:"primaryKey;index" example:"unique-file-id"`
+}
@@ -0,0 +1,54 @@
+package models
+
+import (
+	"time"
+)
+
+// Типы уведомлений о завершении задачи анализа.
+const (
+	WebhookAnalysisSucceeded = "analysis.succeeded" // Анализ успешно завершен
+	WebhookAnalysisFailed    = "analysis.failed"    // Все попытки анализа исчерпаны
+)
+
+// WebhookEndpoint — настройки уведомлений пользователя: URL, на который отправляются уведомления о завершении
+// анализа всех его файлов, и секрет, которым подписываются все уведомления пользователя.
+// @Description Настройки уведомлений пользователя о завершении анализа.
+// @Name WebhookEndpoint
+type WebhookEndpoint struct {
+	OwnerID   string    `json:"owner_id" gorm:"primaryKey" example:"user-1"`                                       // ID пользователя
+	URL       string    `json:"url" example:"https://example.com/hooks/analysis"`                                  // URL для уведомлений обо всех файлах пользователя; пустой — только для указанных в запросе анализа
+	Secret    string    `json:"secret" example:"8d0b4c1f9e2a7b6c5d4e3f2a1b0c9d8e7f6a5b4c3d2e1f0a9b8c7d6e5f4a3b2c"` // Секрет подписи HMAC-SHA256
+	CreatedAt time.Time `json:"created_at" swaggertype:"string" format:"date-time"`
+	UpdatedAt time.Time `json:"updated_at" swaggertype:"string" format:"date-time"`
+}
+
+// AnalysisCallback — URL, указанный в запросе анализа, на который нужно отправить уведомление о завершении задачи.
+// Запись удаляется, когда по ней создается уведомление.
+type AnalysisCallback struct {
+	ID        uint   `gorm:"primaryKey"`
+	JobID     uint   `gorm:"index"`
+	OwnerID   string // Пользователь, запросивший уведомление; его секретом подписывается уведомление
+	URL       string
+	CreatedAt time.Time
+}
+
+// WebhookDelivery — уведомление о завершении задачи анализа в очереди доставки.
+// Неудачные попытки повторяются с экспоненциально растущей паузой, пока не будет исчерпано их количество.
+// @Description Уведомление о завершении анализа: получатель, количество попыток и результат доставки.
+// @Name WebhookDelivery
+type WebhookDelivery struct {
+	ID            uint       `json:"id" gorm:"primaryKey" swaggertype:"integer" example:"1"`
+	EventID       string     `json:"event_id" gorm:"size:36;uniqueIndex" example:"5f1d7c3e-8a4b-4c1f-9d2e-7b6a5c4d3e2f"` // ID уведомления, по которому получатель отбрасывает повторы
+	Type          string     `json:"type" example:"analysis.succeeded"`                                                  // analysis.succeeded или analysis.failed
+	OwnerID       string     `json:"owner_id" gorm:"index" example:"user-1"`                                             // Пользователь, секретом которого подписывается уведомление
+	JobID         uint       `json:"job_id" gorm:"index" swaggertype:"integer" example:"1"`
+	FileID        string     `json:"file_id" example:"unique-file-id"`
+	URL           string     `json:"url" example:"https://example.com/hooks/analysis"`
+	Payload       string     `json:"-"` // Тело уведомления в JSON
+	CreatedAt     time.Time  `json:"created_at" swaggertype:"string" format:"date-time"`
+	Attempts      int        `json:"attempts" example:"1"`                                                 // Количество неудачных попыток доставки
+	NextAttemptAt time.Time  `json:"next_attempt_at" gorm:"index" swaggertype:"string" format:"date-time"` // Не раньше этого времени будет следующая попытка
+	DeliveredAt   *time.Time `json:"delivered_at,omitempty" swaggertype:"string" format:"date-time"`       // Время доставки
+	FailedAt      *time.Time `json:"failed_at,omitempty" swaggertype:"string" format:"date-time"`          // Время, когда попытки доставки были исчерпаны
+	LastError     string     `json:"last_error,omitempty" example:"получатель вернул код 503"`             // Ошибка последней неудачной попытки
+}
@@ -0,0 +1,13 @@
+package models
+
+// WordFrequency хранит количество вхождений слова в проанализированный текст.
+// @Description Частота слова в тексте файла. Сохраняются только самые частые слова (WORD_FREQUENCY_TOP_K).
+// @Name WordFrequency
+type WordFrequency struct {
+	ID               uint   `json:"-"`
+	AnalysisResultID uint   `json:"-" gorm:"index"`            // ID результата анализа, к которому относится слово
+	Word             string `json:"word" example:"книги"`      // Слово в нижнем регистре
+	Lemma            string `json:"lemma" example:"книга"`     // Самая частая в тексте форма слова с той же основой
+	Count            int    `json:"count" example:"12"`        // Количество вхождений слова
+	StopWord         bool   `json:"stop_word" example:"false"` // Является ли слово служебным в языке текста
+}
@@ -1,26 +1,43 @@
 package services
 
 import (
-	"bufio"
+	"context"
+	"errors"
 	"file_analysis_service/models"
 	"fmt"
+	"log"
 	"path/filepath"
 	"pkg/adapters" // Исправленный путь к адаптерам
+	"pkg/pagination"
+	"pkg/textproc"
 	"strings"
-	"unicode"
+	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// AnalyzerVersion — версия алгоритмов анализа, записываемая в каждый результат.
+// Ее нужно увеличивать при любом изменении, влияющем на результаты анализа (статистика, токенизация,
+// частоты слов, сходство, отпечатки winnowing, облако слов): результаты другой версии пересчитываются при следующем запросе анализа.
+const AnalyzerVersion = "4"
+
+// ErrAnalysisCancelled возвращается, если файл удалили во время анализа: результаты удаленного файла не сохраняются.
+var ErrAnalysisCancelled = errors.New("файл удален во время анализа")
+
 // AnalysisService предоставляет методы для анализа файлов.
 // @Summary Сервис анализа файлов
 // @Description Отвечает за логику анализа текстовых файлов и взаимодействие с зависимостями.
 // @Tags services
 type AnalysisService struct {
 	DBAdapter                 *adapters.DBAdapter
-	FileStorageAdapter        *adapters.FileStorageAdapter // Для сохранения облака слов
+	FileStorage               adapters.FileStorage // Для сохранения облака слов
 	FileStoringServiceAdapter *adapters.FileStoringServiceAdapter
-	WordCloudAPIAdapter       *adapters.WordCloudAPIAdapter
+	WordCloudGenerator        adapters.WordCloudGenerator // Локальный или удаленный генератор облака слов
+	ShingleSize               int                         // Количество слов в шингле для анализа сходства
+	SimilarityTopN            int                         // Сколько наиболее похожих файлов сохранять
+	WordFrequencyTopK         int                         // Сколько самых частых слов сохранять
+	Progress                  *ProgressBroker             // События о ходе анализа файлов
 }
 
 // NewAnalysisService создает новый экземпляр AnalysisService.
@@ -29,109 +46,246 @@ type AnalysisService struct {
 // @Return *AnalysisService
 func NewAnalysisService(
 	dbAdapter *adapters.DBAdapter,
-	fileStorageAdapter *adapters.FileStorageAdapter,
+	fileStorage adapters.FileStorage,
 	fileStoringServiceAdapter *adapters.FileStoringServiceAdapter,
-	wordCloudAPIAdapter *adapters.WordCloudAPIAdapter,
+	wordCloudGenerator adapters.WordCloudGenerator,
 ) *AnalysisService {
 	return &AnalysisService{
 		DBAdapter:                 dbAdapter,
-		FileStorageAdapter:        fileStorageAdapter,
+		FileStorage:               fileStorage,
 		FileStoringServiceAdapter: fileStoringServiceAdapter,
-		WordCloudAPIAdapter:       wordCloudAPIAdapter,
+		WordCloudGenerator:        wordCloudGenerator,
+		ShingleSize:               DefaultShingleSize,
+		SimilarityTopN:            DefaultSimilarityTopN,
+		WordFrequencyTopK:         DefaultWordFrequencyTopK,
+		Progress:                  NewProgressBroker(),
 	}
 }
 
-// AnalyzeFile выполняет анализ файла: подсчитывает абзацы, слова, символы и генерирует облако слов.
+// AnalyzeFile выполняет анализ файла: вычисляет статистику и частоты слов текста, ищет похожие файлы и генерирует облако слов.
 // @Summary Анализ файла
 // @Description Основной метод для анализа файла. Возвращает результаты анализа или ошибку.
+// @Description Если последний результат анализа файла получен текущей версией алгоритмов и force не задан, возвращается он.
+// @Description Иначе файл анализируется заново, а новый результат добавляется в историю анализов файла.
 // @Param fileID path string true "ID файла для анализа"
+// @Param force query bool false "Выполнить анализ заново, даже если есть результат текущей версии"
 // @Return *models.AnalysisResult, error "Результаты анализа и ошибка, если есть"
-func (s *AnalysisService) AnalyzeFile(fileID string) (*models.AnalysisResult, error) {
+func (s *AnalysisService) AnalyzeFile(fileID string, force bool) (*models.AnalysisResult, error) {
 	// 1. Попытка получить результаты ранее проведенного анализа из БД
-	var existingResult models.AnalysisResult
-	if err := s.DBAdapter.First(&existingResult, "file_id = ?", fileID); err == nil {
-		return &existingResult, nil // Результаты найдены, возвращаем их
-	} else if err != gorm.ErrRecordNotFound {
-		return nil, fmt.Errorf("ошибка при поиске существующего анализа для fileID %s: %w", fileID, err)
+	if !force {
+		var existingResult models.AnalysisResult
+		if err := s.DBAdapter.Last(&existingResult, "file_id = ?", fileID); err == nil {
+			if existingResult.AnalyzerVersion == AnalyzerVersion {
+				// Результаты текущей версии найдены, возвращаем их
+				s.Progress.Publish(ProgressEvent{Stage: ProgressSaved, FileID: fileID, ResultID: existingResult.ID, Cached: true})
+				return &existingResult, nil
+			}
+		} else if err != gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("ошибка при поиске существующего анализа для fileID %s: %w", fileID, err)
+		}
 	}
 
-	// 2. File Analisys Service обращается к File Storing Service чтобы получить содержимое файла по id
-	// Сначала получаем location файла
-	fileLocationOriginal, err := s.FileStoringServiceAdapter.GetFileLocationByID(fileID)
+	// 2. File Analisys Service получает текст файла из File Storing Service потоком по id
+	content, err := s.FileStoringServiceAdapter.OpenFileContent(fileID)
 	if err != nil {
-		return nil, fmt.Errorf("не удалось получить местоположение файла %s из FileStoringService: %w", fileID, err)
+		return nil, fmt.Errorf("не удалось получить содержимое файла %s из FileStoringService: %w", fileID, err)
 	}
+	defer content.Close()
+	s.Progress.Publish(ProgressEvent{Stage: ProgressFetched, FileID: fileID})
 
-	// Затем получаем содержимое файла по location
-	fileContent, err := s.FileStoringServiceAdapter.GetFileContentByLocation(fileLocationOriginal)
-	if err != nil {
-		return nil, fmt.Errorf("не удалось получить содержимое файла %s (location: %s) из FileStoringService: %w", fileID, fileLocationOriginal, err)
+	// 3. Анализ файла за один проход по тексту: текст читается фрагментами и целиком в памяти не хранится
+	analyzer := newTextAnalyzer(s.ShingleSize)
+	if err := textproc.ReadChunks(content, textproc.DefaultChunkSize, analyzer.Feed); err != nil {
+		return nil, fmt.Errorf("не удалось прочитать содержимое файла %s из FileStoringService: %w", fileID, err)
+	}
+	text := analyzer.Finish()
+	stats := text.Statistics
+	s.Progress.Publish(ProgressEvent{Stage: ProgressCounted, FileID: fileID, WordCount: stats.WordCount})
+
+	// Поиск похожих среди ранее проанализированных файлов: кандидаты по индексу, оценка по MinHash-сигнатуре
+	var similarFiles []models.SimilarityMatch
+	if text.Signature != nil {
+		similarFiles, err = s.findSimilarFiles(fileID, text.Signature, text.Fingerprints)
+		if err != nil {
+			return nil, fmt.Errorf("не удалось выполнить анализ сходства для fileID %s: %w", fileID, err)
+		}
 	}
 
-	// 3. Анализ файла
-	paragraphCount := countParagraphs(string(fileContent))
-	wordCount := countWords(string(fileContent))
-	characterCount := countCharacters(string(fileContent))
+	// Владелец файла сохраняется в результате, чтобы список результатов можно было ограничить файлами пользователя.
+	// Удаленного во время анализа файла в ответе нет
+	owners, err := s.FileStoringServiceAdapter.GetFileOwners([]string{fileID})
+	if err != nil {
+		return nil, fmt.Errorf("не удалось получить владельца файла %s: %w", fileID, err)
+	}
+	ownerID, exists := owners[fileID]
+	if !exists {
+		return nil, ErrAnalysisCancelled
+	}
 
 	// 4. Генерация облака слов
-	wordCloudImage, contentType, err := s.WordCloudAPIAdapter.GenerateWordCloud(string(fileContent))
+	wordCloudImages, err := s.WordCloudGenerator.GenerateWordCloud(textproc.SortWordCounts(text.WordCounts), stats.Language)
 	if err != nil {
-		// Не фатальная ошибка, анализ продолжается без облака слов, если API недоступен
+		// Не фатальная ошибка, анализ продолжается без облака слов, если генератор недоступен
 		fmt.Printf("Предупреждение: не удалось сгенерировать облако слов для fileID %s: %v\n", fileID, err)
 		// Можно логировать эту ошибку, но не прерывать процесс
 	}
 
-	wordCloudLocation := "" // Пусто, если генерация не удалась
-	if wordCloudImage != nil {
+	// Ключи сохраненных изображений; пустые, если генерация или сохранение не удались.
+	// Все форматы одного облака слов сохраняются под общим именем и отличаются только расширением
+	wordCloudLocation, wordCloudSVGLocation := "", ""
+	var savedWordClouds []string
+	wordCloudBaseName := fmt.Sprintf("%s_wordcloud_%d", fileID, time.Now().UnixNano()) // Время анализа в имени сохраняет облака слов предыдущих анализов файла
+	for _, image := range wordCloudImages {
+		if len(image.Data) == 0 {
+			continue
+		}
 		// Выводим информацию о полученном изображении для отладки
-		fmt.Printf("DEBUG: Получено изображение (%d байт, Content-Type: %s) для сохранения\n", len(wordCloudImage), contentType)
+		fmt.Printf("DEBUG: Получено изображение (%d байт, Content-Type: %s) для сохранения\n", len(image.Data), image.ContentType)
 
 		// Определяем расширение файла на основе Content-Type
 		fileExt := ".png" // По умолчанию
-		if contentType == "image/jpeg" || contentType == "image/jpg" {
+		if image.ContentType == "image/jpeg" || image.ContentType == "image/jpg" {
 			fileExt = ".jpg"
-		} else if contentType == "image/gif" {
+		} else if image.ContentType == "image/gif" {
 			fileExt = ".gif"
-		} else if contentType == "image/svg+xml" {
+		} else if image.ContentType == "image/svg+xml" {
 			fileExt = ".svg"
 		}
 
-		// Сохранение полученной по API картинки в File Storage №2
-		// Включаем ID файла и четко указываем, что это облако слов с правильным расширением
-		wordCloudFileName := fmt.Sprintf("%s_wordcloud%s", fileID, fileExt)
-		actualWordCloudLocation, errSaveCloud := s.FileStorageAdapter.SaveFileFromBytes(wordCloudFileName, wordCloudImage)
-		if errSaveCloud != nil {
+		// Сохранение сгенерированной картинки в File Storage №2
+		wordCloudFileName := wordCloudBaseName + fileExt
+		if errSaveCloud := s.FileStorage.SaveFileFromBytes(wordCloudFileName, image.Data); errSaveCloud != nil {
 			// Ошибка сохранения облака слов, не фатально, но логируем
 			fmt.Printf("Предупреждение: не удалось сохранить облако слов для fileID %s: %v\n", fileID, errSaveCloud)
-		} else {
-			wordCloudLocation = actualWordCloudLocation
+			continue
+		}
+		savedWordClouds = append(savedWordClouds, wordCloudFileName)
+		if fileExt == ".svg" {
+			wordCloudSVGLocation = wordCloudFileName
+		} else if wordCloudLocation == "" {
+			wordCloudLocation = wordCloudFileName
 		}
 	}
+	// Основное изображение — растровое; если его нет, им становится SVG
+	if wordCloudLocation == "" {
+		wordCloudLocation = wordCloudSVGLocation
+	}
+
+	s.Progress.Publish(ProgressEvent{Stage: ProgressWordCloud, FileID: fileID, Location: wordCloudLocation, SVGLocation: wordCloudSVGLocation})
 
 	// 5. Сохранение результатов анализа в БД
 	analysisResult := models.AnalysisResult{
-		FileID:            fileID,
-		ParagraphCount:    paragraphCount,
-		WordCount:         wordCount,
-		CharacterCount:    characterCount,
-		WordCloudLocation: wordCloudLocation, // Сохраняем фактический путь или пустую строку
+		FileID:                   fileID,
+		OwnerID:                  ownerID,
+		AnalyzerVersion:          AnalyzerVersion,
+		Language:                 string(stats.Language),
+		ParagraphCount:           stats.ParagraphCount,
+		LineCount:                stats.LineCount,
+		SentenceCount:            stats.SentenceCount,
+		WordCount:                stats.WordCount,
+		UniqueWordCount:          stats.UniqueWordCount,
+		CharacterCount:           stats.CharacterCount,
+		CharacterCountWithSpaces: stats.CharacterCountWithSpaces,
+		AverageWordLength:        stats.AverageWordLength,
+		AverageSentenceLength:    stats.AverageSentenceLength,
+		LexicalDensity:           stats.LexicalDensity,
+		WordCloudLocation:        wordCloudLocation, // Сохраняем фактический путь или пустую строку
+		WordCloudSVGLocation:     wordCloudSVGLocation,
+		// Частоты слов сохраняются вместе с результатом анализа в дочерней таблице word_frequencies
+		WordFrequencies: buildWordFrequencies(text.WordCounts, stats.Language, s.WordFrequencyTopK),
 	}
 
-	if err := s.DBAdapter.Create(&analysisResult); err != nil {
+	err = s.DBAdapter.Transaction(func(tx *adapters.DBAdapter) error {
+		// Пока файл анализировался, его могли удалить вместе с задачами анализа. Выполняющаяся задача блокируется
+		// до конца транзакции, а DeleteAnalysisResults удаляет задачи раньше результатов, поэтому результаты
+		// удаленного файла либо не сохраняются, либо удаляются вместе с остальными
+		var runningJobs []uint
+		err := tx.DB.Model(&models.AnalysisJob{}).Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("file_id = ? AND status = ?", fileID, models.JobStatusRunning).
+			Limit(1).Pluck("id", &runningJobs).Error
+		if err != nil {
+			return err
+		}
+		if len(runningJobs) == 0 {
+			return ErrAnalysisCancelled
+		}
+		if err := tx.Create(&analysisResult); err != nil {
+			return err
+		}
+		// Сигнатура и ее полосы в LSH-индексе хранятся только для последнего анализа файла.
+		// Пустой файл не имеет шинглов, поэтому сигнатура для него не сохраняется
+		if err := tx.DB.Delete(&models.FileSignature{}, "file_id = ?", fileID).Error; err != nil {
+			return err
+		}
+		if err := tx.DB.Delete(&models.SignatureBand{}, "file_id = ?", fileID).Error; err != nil {
+			return err
+		}
+		if text.Signature != nil {
+			fileSignature := models.FileSignature{
+				FileID:       fileID,
+				ShingleCount: text.ShingleCount,
+				Signature:    encodeSignature(text.Signature),
+			}
+			if err := tx.Create(&fileSignature); err != nil {
+				return err
+			}
+			bands := signatureBands(fileID, text.Signature)
+			if err := tx.Create(&bands); err != nil {
+				return err
+			}
+		}
+		// Отпечатки, как и сигнатура, хранятся только для последнего анализа файла
+		if err := tx.DB.Delete(&models.FileFingerprint{}, "file_id = ?", fileID).Error; err != nil {
+			return err
+		}
+		if len(text.Fingerprints) > 0 {
+			fingerprints := make([]models.FileFingerprint, len(text.Fingerprints))
+			for i, fp := range text.Fingerprints {
+				fingerprints[i] = models.FileFingerprint{FileID: fileID, Hash: int64(fp.Hash), Position: fp.Position, Start: fp.Start, End: fp.End}
+			}
+			if err := tx.DB.CreateInBatches(&fingerprints, fingerprintInsertBatch).Error; err != nil {
+				return err
+			}
+		}
+		if len(similarFiles) > 0 {
+			for i := range similarFiles {
+				similarFiles[i].AnalysisResultID = analysisResult.ID
+			}
+			if err := tx.Create(&similarFiles); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		// Облако слов уже сохранено в хранилище, но без результата на него ничто не ссылается
+		for _, location := range savedWordClouds {
+			if errDelete := s.FileStorage.DeleteFile(location); errDelete != nil {
+				fmt.Printf("Предупреждение: не удалось удалить облако слов %s: %v\n", location, errDelete)
+			}
+		}
 		return nil, fmt.Errorf("не удалось сохранить результаты анализа для fileID %s: %w", fileID, err)
 	}
+	s.Progress.Publish(ProgressEvent{Stage: ProgressSaved, FileID: fileID, ResultID: analysisResult.ID})
 
 	return &analysisResult, nil
 }
 
 // GetAnalysisResult получает результаты анализа по ID файла.
 // @Summary Получение результатов анализа
-// @Description Ищет и возвращает сохраненные результаты анализа для указанного файла.
+// @Description Ищет и возвращает сохраненные результаты анализа для указанного файла: последний результат или, если resultID не 0, результат с этим ID из истории анализов файла.
 // @Param fileID path string true "ID файла"
+// @Param resultID query int false "ID результата анализа из истории (0 — последний)"
 // @Return *models.AnalysisResult, error "Результаты анализа и ошибка, если есть (например, если анализ не найден)"
-func (s *AnalysisService) GetAnalysisResult(fileID string) (*models.AnalysisResult, error) {
+func (s *AnalysisService) GetAnalysisResult(fileID string, resultID uint) (*models.AnalysisResult, error) {
 	var result models.AnalysisResult
-	if err := s.DBAdapter.First(&result, "file_id = ?", fileID); err != nil {
+	var err error
+	if resultID == 0 {
+		err = s.DBAdapter.Last(&result, "file_id = ?", fileID)
+	} else {
+		err = s.DBAdapter.First(&result, "file_id = ? AND id = ?", fileID, resultID)
+	}
+	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, fmt.Errorf("результаты анализа для файла с ID %s не найдены: %w", fileID, err)
 		}
@@ -140,37 +294,108 @@ func (s *AnalysisService) GetAnalysisResult(fileID string) (*models.AnalysisResu
 	return &result, nil
 }
 
-// GetWordCloudImage получает изображение облака слов по его местоположению.
-// @Summary Получение изображения облака слов
-// @Description Читает и возвращает изображение облака слов из файлового хранилища.
-// @Param location path string true "Путь к файлу изображения облака слов"
-// @Return []byte, string, error "Данные изображения, тип контента и ошибка, если есть"
-func (s *AnalysisService) GetWordCloudImage(location string) ([]byte, string, error) {
-	// Проверка, что location не пустой и безопасный (например, не выходит за пределы хранилища)
-	// filepath.Clean для нормализации пути
-	cleanedLocation := filepath.Clean(location)
-
-	// Проверка, что путь после очистки все еще указывает на ожидаемую директорию.
-	// Это базовая проверка, в реальном приложении могут потребоваться более строгие правила.
-	expectedPrefix, _ := filepath.Abs(s.FileStorageAdapter.StoragePath)
-	absLocation, err := filepath.Abs(cleanedLocation)
+// GetAnalysisHistory возвращает все результаты анализа файла, начиная с последнего.
+// @Summary Получение истории анализов файла
+// @Description Возвращает результаты всех анализов файла в порядке от нового к старому, чтобы их можно было сравнить.
+// @Param fileID path string true "ID файла"
+// @Return []models.AnalysisResult, error "История анализов и ошибка, если есть (например, если файл не анализировался)"
+func (s *AnalysisService) GetAnalysisHistory(fileID string) ([]models.AnalysisResult, error) {
+	var results []models.AnalysisResult
+	if err := s.DBAdapter.DB.Where("file_id = ?", fileID).Order("id DESC").Find(&results).Error; err != nil {
+		return nil, fmt.Errorf("не удалось получить историю анализов файла %s: %w", fileID, err)
+	}
+	if len(results) == 0 {
+		return nil, fmt.Errorf("результаты анализа для файла с ID %s не найдены: %w", fileID, gorm.ErrRecordNotFound)
+	}
+	return results, nil
+}
+
+// DeleteAnalysisResults удаляет все данные анализа файла.
+// @Summary Удаление результатов анализа файла
+// @Description Удаляет историю анализов файла вместе с частотами слов, сигнатуру, отпечатки, совпадения сходства (в том числе с другими файлами),
+// @Description задачи анализа и изображения облаков слов. Используется при удалении файла; повторный вызов безопасен.
+// @Param fileID path string true "ID файла"
+// @Return int, int, error "Количество удаленных результатов анализа, количество удаленных облаков слов и ошибка, если есть"
+func (s *AnalysisService) DeleteAnalysisResults(fileID string) (int, int, error) {
+	var results []models.AnalysisResult
+	err := s.DBAdapter.Transaction(func(tx *adapters.DBAdapter) error {
+		// Задачи удаляются первыми: удаление ждет, пока AnalyzeFile сохраняет результаты выполняющейся задачи,
+		// и следующие запросы транзакции видят эти результаты, а AnalyzeFile, начавший сохранение позже, их не сохранит
+		err := tx.DB.Where("job_id IN (?)", tx.DB.Model(&models.AnalysisJob{}).Select("id").Where("file_id = ?", fileID)).
+			Delete(&models.AnalysisCallback{}).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.DB.Delete(&models.AnalysisJob{}, "file_id = ?", fileID).Error; err != nil {
+			return err
+		}
+		if err := tx.DB.Unscoped().Where("file_id = ?", fileID).Find(&results).Error; err != nil {
+			return err
+		}
+		if len(results) > 0 {
+			resultIDs := make([]uint, len(results))
+			for i, result := range results {
+				resultIDs[i] = result.ID
+			}
+			if err := tx.DB.Where("analysis_result_id IN ?", resultIDs).Delete(&models.WordFrequency{}).Error; err != nil {
+				return err
+			}
+			if err := tx.DB.Unscoped().Where("id IN ?", resultIDs).Delete(&models.AnalysisResult{}).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.DB.Delete(&models.FileSignature{}, "file_id = ?", fileID).Error; err != nil {
+			return err
+		}
+		if err := tx.DB.Delete(&models.SignatureBand{}, "file_id = ?", fileID).Error; err != nil {
+			return err
+		}
+		if err := tx.DB.Delete(&models.FileFingerprint{}, "file_id = ?", fileID).Error; err != nil {
+			return err
+		}
+		return tx.DB.Delete(&models.SimilarityMatch{}, "file_id = ? OR matched_file_id = ?", fileID, fileID).Error
+	})
 	if err != nil {
-		return nil, "", fmt.Errorf("ошибка получения абсолютного пути для облака слов %s: %w", cleanedLocation, err)
+		return 0, 0, fmt.Errorf("не удалось удалить результаты анализа файла %s: %w", fileID, err)
 	}
 
-	if !strings.HasPrefix(absLocation, expectedPrefix) {
-		return nil, "", fmt.Errorf("недопустимый путь к файлу облака слов: %s", cleanedLocation)
+	// Облака слов удаляются после фиксации транзакции: оставшееся в хранилище изображение
+	// ни на что не ссылается, а удаленное изображение при откате транзакции сломало бы результат
+	deletedWordClouds := 0
+	for _, result := range results {
+		for _, location := range result.WordCloudLocations() {
+			if err := s.FileStorage.DeleteFile(location); err != nil {
+				fmt.Printf("Предупреждение: не удалось удалить облако слов %s: %v\n", location, err)
+				continue
+			}
+			deletedWordClouds++
+		}
 	}
+	return len(results), deletedWordClouds, nil
+}
 
-	// Извлекаем относительный путь от StoragePath, чтобы использовать с адаптером
-	relativePath, err := filepath.Rel(s.FileStorageAdapter.StoragePath, absLocation)
-	if err != nil {
-		return nil, "", fmt.Errorf("ошибка вычисления относительного пути для облака слов %s: %w", absLocation, err)
+// WordCloudFileID возвращает ID файла, для которого построено облако слов с ключом location.
+// Если облако слов не найдено, возвращаемая ошибка оборачивает gorm.ErrRecordNotFound.
+func (s *AnalysisService) WordCloudFileID(location string) (string, error) {
+	var result models.AnalysisResult
+	if err := s.DBAdapter.First(&result, "word_cloud_location = ? OR word_cloud_svg_location = ?", location, location); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", fmt.Errorf("облако слов %s не найдено: %w", location, err)
+		}
+		return "", fmt.Errorf("ошибка при поиске облака слов %s: %w", location, err)
 	}
+	return result.FileID, nil
+}
 
+// GetWordCloudImage получает изображение облака слов по его местоположению.
+// @Summary Получение изображения облака слов
+// @Description Читает и возвращает изображение облака слов из файлового хранилища.
+// @Param location path string true "Ключ файла изображения облака слов в хранилище"
+// @Return []byte, string, error "Данные изображения, тип контента и ошибка, если есть"
+func (s *AnalysisService) GetWordCloudImage(location string) ([]byte, string, error) {
 	// Определяем Content-Type на основе расширения файла
 	contentType := "image/png" // По умолчанию
-	ext := strings.ToLower(filepath.Ext(relativePath))
+	ext := strings.ToLower(filepath.Ext(location))
 	if ext == ".jpg" || ext == ".jpeg" {
 		contentType = "image/jpeg"
 	} else if ext == ".gif" {
@@ -179,76 +404,131 @@ func (s *AnalysisService) GetWordCloudImage(location string) ([]byte, string, er
 		contentType = "image/svg+xml"
 	}
 
-	fmt.Printf("DEBUG: Чтение файла облака слов %s с Content-Type %s\n", relativePath, contentType)
+	fmt.Printf("DEBUG: Чтение файла облака слов %s с Content-Type %s\n", location, contentType)
 
-	imageData, err := s.FileStorageAdapter.ReadFile(relativePath)
+	// Проверка, что ключ не выходит за пределы хранилища, выполняется самим хранилищем
+	imageData, err := s.FileStorage.ReadFile(location)
 	if err != nil {
-		return nil, "", fmt.Errorf("не удалось прочитать файл облака слов %s: %w", relativePath, err)
+		return nil, "", fmt.Errorf("не удалось прочитать файл облака слов %s: %w", location, err)
 	}
 
 	// Проверяем размер данных
 	if len(imageData) == 0 {
-		return nil, "", fmt.Errorf("файл облака слов %s пуст", relativePath)
+		return nil, "", fmt.Errorf("файл облака слов %s пуст", location)
 	}
 
-	fmt.Printf("DEBUG: Прочитано %d байт из файла облака слов %s\n", len(imageData), relativePath)
+	fmt.Printf("DEBUG: Прочитано %d байт из файла облака слов %s\n", len(imageData), location)
 
 	return imageData, contentType, nil
 }
 
-// Вспомогательные функции для анализа текста
-func countParagraphs(text string) int {
-	if text == "" {
-		return 0
+// ResultFilter — фильтры списка результатов анализа. Пустые поля не ограничивают список.
+type ResultFilter struct {
+	FileID          string
+	OwnerID         string
+	Language        string
+	AnalyzerVersion string
+	CreatedAfter    *time.Time // Результаты, полученные не раньше этого времени
+	CreatedBefore   *time.Time // Результаты, полученные раньше этого времени
+}
+
+// ResultSorting — допустимые сортировки списка результатов анализа.
+var ResultSorting = pagination.Sorting{
+	Fields: map[string]pagination.Field{
+		"created_at": pagination.TimeField("created_at"),
+		"file_id":    pagination.StringField("file_id"),
+		"word_count": pagination.IntField("word_count"),
+	},
+	ID:          pagination.IntField("id"),
+	Default:     "created_at",
+	DefaultDesc: true,
+}
+
+// ListAnalysisResults возвращает страницу результатов анализа, подходящих под фильтры, и их общее количество.
+// @Summary Список результатов анализа
+// @Description Возвращает результаты анализа, подходящие под фильтры, постранично по курсору.
+// @Description Выбирается на один результат больше page.Limit, чтобы определить, есть ли следующая страница (см. pagination.Request.Next).
+// @Param filter query ResultFilter false "Фильтры"
+// @Param page query object true "Сортировка, курсор и размер страницы (pagination.Request)"
+// @Return []models.AnalysisResult, int64, error "Результаты анализа, их общее количество и ошибка, если есть"
+func (s *AnalysisService) ListAnalysisResults(filter ResultFilter, page pagination.Request) ([]models.AnalysisResult, int64, error) {
+	query := s.DBAdapter.DB.Model(&models.AnalysisResult{})
+	if filter.FileID != "" {
+		query = query.Where("file_id = ?", filter.FileID)
 	}
-	// Разделяем по переносу строки. Пустые строки между абзацами будут считаться как отдельные абзацы, если их много подряд.
-	// Чтобы считать "реальные" абзацы, можно дополнительно отфильтровать пустые строки после split.
-	scanner := bufio.NewScanner(strings.NewReader(text))
-	count := 0
-	for scanner.Scan() {
-		if strings.TrimSpace(scanner.Text()) != "" { // Считаем только непустые строки как абзацы
-			count++
-		}
+	if filter.OwnerID != "" {
+		query = query.Where("owner_id = ?", filter.OwnerID)
 	}
-	// Если текст непустой, но не содержит переносов строк, считаем его одним абзацем
-	if count == 0 && len(strings.TrimSpace(text)) > 0 {
-		return 1
+	if filter.Language != "" {
+		query = query.Where("language = ?", filter.Language)
 	}
-	return count
-}
+	if filter.AnalyzerVersion != "" {
+		query = query.Where("analyzer_version = ?", filter.AnalyzerVersion)
+	}
+	if filter.CreatedAfter != nil {
+		query = query.Where("created_at >= ?", *filter.CreatedAfter)
+	}
+	if filter.CreatedBefore != nil {
+		query = query.Where("created_at < ?", *filter.CreatedBefore)
+	}
+	// Запрос с фильтрами используется дважды: для подсчета и для выборки страницы
+	query = query.Session(&gorm.Session{})
 
-func countWords(text string) int {
-	scanner := bufio.NewScanner(strings.NewReader(text))
-	scanner.Split(bufio.ScanWords)
-	count := 0
-	for scanner.Scan() {
-		count++
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("не удалось подсчитать результаты анализа: %w", err)
+	}
+	var results []models.AnalysisResult
+	if err := page.Apply(query).Find(&results).Error; err != nil {
+		return nil, 0, fmt.Errorf("не удалось получить список результатов анализа: %w", err)
 	}
-	return count
+	return results, total, nil
 }
 
-func countCharacters(text string) int {
-	count := 0
-	for _, r := range text {
-		if !unicode.IsSpace(r) { // Не считаем пробельные символы
-			count++
+// ownerBackfillBatch — для скольких файлов за раз запрашиваются владельцы при заполнении owner_id результатов анализа.
+const ownerBackfillBatch = 500
+
+// EnsureResultOwners заполняет владельца в результатах анализа, полученных до того, как он стал сохраняться,
+// чтобы пользователи видели эти результаты в списке результатов анализа. Результаты удаленных файлов и файлов
+// без владельца остаются без владельца и видны только администраторам.
+// @Summary Заполнение владельцев результатов анализа
+// @Return error
+func (s *AnalysisService) EnsureResultOwners(ctx context.Context) error {
+	updated := int64(0)
+	after := ""
+	for ctx.Err() == nil {
+		var fileIDs []string
+		err := s.DBAdapter.DB.Model(&models.AnalysisResult{}).
+			Where("owner_id = ? AND file_id > ?", "", after).
+			Distinct("file_id").Order("file_id").Limit(ownerBackfillBatch).
+			Pluck("file_id", &fileIDs).Error
+		if err != nil {
+			return fmt.Errorf("не удалось получить результаты анализа без владельца: %w", err)
 		}
-	}
-	return count
-}
+		if len(fileIDs) == 0 {
+			break
+		}
+		after = fileIDs[len(fileIDs)-1]
 
-// ListAnalysisResults возвращает список всех доступных результатов анализа.
-// @Summary Список всех результатов анализа
-// @Description Возвращает file_id и location облака слов для всех проанализированных файлов.
-// @Tags analysis
-// @Produce json
-// @Success 200 {array} models.AnalysisResult "Список результатов анализа"
-// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
-// @Router /analysis/results [get]
-func (s *AnalysisService) ListAnalysisResults() ([]models.AnalysisResult, error) {
-	var results []models.AnalysisResult
-	if err := s.DBAdapter.Find(&results); err != nil {
-		return nil, fmt.Errorf("не удалось получить список результатов анализа: %w", err)
+		owners, err := s.FileStoringServiceAdapter.GetFileOwners(fileIDs)
+		if err != nil {
+			return fmt.Errorf("не удалось заполнить владельцев результатов анализа: %w", err)
+		}
+		for fileID, ownerID := range owners {
+			if ownerID == "" {
+				continue
+			}
+			result := s.DBAdapter.DB.Model(&models.AnalysisResult{}).
+				Where("file_id = ? AND owner_id = ?", fileID, "").
+				Update("owner_id", ownerID)
+			if result.Error != nil {
+				return fmt.Errorf("не удалось сохранить владельца результатов анализа файла %s: %w", fileID, result.Error)
+			}
+			updated += result.RowsAffected
+		}
 	}
-	return results, nil
+	if updated > 0 {
+		log.Printf("Владелец файла заполнен в %d результатах анализа", updated)
+	}
+	return ctx.Err()
 }
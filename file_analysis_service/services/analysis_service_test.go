@@ -0,0 +1,98 @@
+package services
+
+import (
+	"file_analysis_service/models"
+	"pkg/adapters"
+	"testing"
+	"time"
+)
+
+func TestDeleteAnalysisResults(t *testing.T) {
+	consumer, db := newTestConsumer(t)
+	service := consumer.AnalysisService
+	storage, err := adapters.NewFileStorageAdapter(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	service.FileStorage = storage
+
+	// Два анализа удаляемого файла (второй — с облаком слов в PNG и SVG) и файл, который с ним совпадает
+	for _, location := range []string{"f1_wordcloud_1.png", "f1_wordcloud_2.png", "f1_wordcloud_2.svg", "f2_wordcloud_1.png"} {
+		if err := storage.SaveFileFromBytes(location, []byte("image")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	results := []models.AnalysisResult{
+		{FileID: "f1", WordCloudLocation: "f1_wordcloud_1.png", WordFrequencies: []models.WordFrequency{{Word: "старое", Count: 1}}},
+		{FileID: "f1", WordCloudLocation: "f1_wordcloud_2.png", WordCloudSVGLocation: "f1_wordcloud_2.svg", WordFrequencies: []models.WordFrequency{{Word: "новое", Count: 2}}},
+		{FileID: "f2", WordCloudLocation: "f2_wordcloud_1.png", WordFrequencies: []models.WordFrequency{{Word: "другое", Count: 3}}},
+	}
+	if err := db.Create(&results).Error; err != nil {
+		t.Fatal(err)
+	}
+	saveAnalysis(t, db, "f1", analyzeWords(testWords("a", 100)))
+	saveAnalysis(t, db, "f2", analyzeWords(testWords("a", 100)))
+	db.Create(&[]models.SimilarityMatch{
+		{AnalysisResultID: results[1].ID, FileID: "f1", MatchedFileID: "f2", Score: 1},
+		{AnalysisResultID: results[2].ID, FileID: "f2", MatchedFileID: "f1", Score: 1},
+	})
+	job := models.AnalysisJob{FileID: "f1", Status: models.JobStatusPending, NextRunAt: time.Now()}
+	db.Create(&job)
+	db.Create(&models.AnalysisCallback{JobID: job.ID, OwnerID: "user1", URL: "https://example.com/hook"})
+	// Удаленный (soft delete) результат тоже удаляется
+	db.Delete(&results[0])
+
+	deleted, deletedWordClouds, err := service.DeleteAnalysisResults("f1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted != 2 || deletedWordClouds != 3 {
+		t.Fatalf("удалено результатов %d и облаков слов %d, ожидалось 2 и 3", deleted, deletedWordClouds)
+	}
+	for _, model := range []interface{}{&models.FileSignature{}, &models.SignatureBand{}, &models.FileFingerprint{}, &models.AnalysisJob{}} {
+		if got := countRows(t, db, model, "file_id = ?", "f1"); got != 0 {
+			t.Fatalf("после удаления осталось записей %T: %d", model, got)
+		}
+	}
+	if got := countRows(t, db.Unscoped(), &models.AnalysisResult{}, "file_id = ?", "f1"); got != 0 {
+		t.Fatalf("после удаления осталось результатов: %d", got)
+	}
+	if got := countRows(t, db, &models.AnalysisCallback{}); got != 0 {
+		t.Fatalf("после удаления осталось уведомлений: %d", got)
+	}
+	// Совпадения удаляются в обе стороны, частоты слов — только у результатов удаленного файла
+	if got := countRows(t, db, &models.SimilarityMatch{}); got != 0 {
+		t.Fatalf("после удаления осталось совпадений: %d", got)
+	}
+	if got := countRows(t, db, &models.WordFrequency{}); got != 1 {
+		t.Fatalf("частот слов: %d, ожидались только частоты f2", got)
+	}
+	for _, location := range []string{"f1_wordcloud_1.png", "f1_wordcloud_2.png", "f1_wordcloud_2.svg"} {
+		if _, err := storage.ReadFile(location); err == nil {
+			t.Fatalf("облако слов %s не удалено", location)
+		}
+	}
+
+	// Данные другого файла не затронуты
+	if got := countRows(t, db, &models.AnalysisResult{}, "file_id = ?", "f2"); got != 1 {
+		t.Fatalf("результатов f2: %d", got)
+	}
+	if got := countRows(t, db, &models.SignatureBand{}, "file_id = ?", "f2"); got != lshBands {
+		t.Fatalf("полос индекса f2: %d", got)
+	}
+	if _, err := storage.ReadFile("f2_wordcloud_1.png"); err != nil {
+		t.Fatalf("облако слов f2 удалено: %v", err)
+	}
+	if fileID, err := service.WordCloudFileID("f2_wordcloud_1.png"); err != nil || fileID != "f2" {
+		t.Fatalf("WordCloudFileID: %q, %v", fileID, err)
+	}
+	if _, err := service.WordCloudFileID("f1_wordcloud_2.svg"); err == nil {
+		t.Fatal("облако слов удаленного файла найдено")
+	}
+
+	// Повторный вызов безопасен
+	deleted, deletedWordClouds, err = service.DeleteAnalysisResults("f1")
+	if err != nil || deleted != 0 || deletedWordClouds != 0 {
+		t.Fatalf("повторное удаление: %d, %d, %v", deleted, deletedWordClouds, err)
+	}
+}
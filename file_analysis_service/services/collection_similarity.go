@@ -0,0 +1,91 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// DefaultCollectionMinScore — наименьшая оценка сходства, при которой пара файлов коллекции считается подозрительной.
+const DefaultCollectionMinScore = 0.3
+
+// SimilarPair — пара похожих файлов коллекции.
+// @Description Пара файлов коллекции и оценка их сходства.
+// @Name SimilarPair
+type SimilarPair struct {
+	FileID        string  `json:"file_id" example:"unique-file-id"`
+	MatchedFileID string  `json:"matched_file_id" example:"other-file-id"`
+	Score         float64 `json:"score" example:"0.87"`
+	Percent       int     `json:"percent" example:"87"`
+	Description   string  `json:"description" example:"Файл unique-file-id похож на файл other-file-id на 87%"`
+}
+
+// CollectionSimilarityReport — результат попарного сравнения файлов коллекции.
+// @Description Пары файлов коллекции с оценкой сходства не ниже min_score в порядке убывания сходства.
+// @Description Сравниваются только проанализированные файлы; остальные перечислены в not_analyzed.
+// @Name CollectionSimilarityReport
+type CollectionSimilarityReport struct {
+	CollectionID    string        `json:"collection_id" example:"collection-id"`
+	FileCount       int           `json:"file_count" example:"25"`      // Количество файлов в коллекции
+	AnalyzedCount   int           `json:"analyzed_count" example:"24"`  // Количество файлов, для которых есть сигнатура
+	NotAnalyzed     []string      `json:"not_analyzed"`                 // ID файлов, которые еще не анализировались
+	PairsCompared   int           `json:"pairs_compared" example:"276"` // Количество сравненных пар
+	MinScore        float64       `json:"min_score" example:"0.3"`      // Наименьшая оценка сходства подозрительной пары
+	SuspiciousCount int           `json:"suspicious_count" example:"3"` // Количество пар с оценкой не ниже min_score
+	Pairs           []SimilarPair `json:"pairs"`                        // Не больше limit самых похожих пар
+}
+
+// CompareFiles попарно сравнивает MinHash-сигнатуры файлов коллекции и возвращает limit самых похожих пар
+// с оценкой сходства не ниже minScore.
+// @Summary Попарное сравнение файлов коллекции
+// @Description Вычисляет матрицу сходства всех проанализированных файлов коллекции по сохраненным сигнатурам, без повторного анализа файлов.
+// @Param collectionID path string true "ID коллекции"
+// @Param fileIDs body []string true "ID файлов коллекции"
+// @Return *CollectionSimilarityReport, error "Подозрительные пары файлов и ошибка, если есть"
+func (s *AnalysisService) CompareFiles(collectionID string, fileIDs []string, minScore float64, limit int) (*CollectionSimilarityReport, error) {
+	signatures, err := s.loadSignatures(fileIDs)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось загрузить сигнатуры файлов коллекции %s: %w", collectionID, err)
+	}
+
+	report := &CollectionSimilarityReport{
+		CollectionID: collectionID,
+		FileCount:    len(fileIDs),
+		NotAnalyzed:  []string{},
+		MinScore:     minScore,
+		Pairs:        []SimilarPair{},
+	}
+	analyzed := make([]string, 0, len(signatures))
+	for _, fileID := range fileIDs {
+		if _, ok := signatures[fileID]; ok {
+			analyzed = append(analyzed, fileID)
+		} else {
+			report.NotAnalyzed = append(report.NotAnalyzed, fileID)
+		}
+	}
+	report.AnalyzedCount = len(analyzed)
+
+	for i := range analyzed {
+		for j := i + 1; j < len(analyzed); j++ {
+			report.PairsCompared++
+			score := estimateJaccard(signatures[analyzed[i]], signatures[analyzed[j]])
+			if score == 0 || score < minScore {
+				continue
+			}
+			percent := int(math.Round(score * 100))
+			report.Pairs = append(report.Pairs, SimilarPair{
+				FileID:        analyzed[i],
+				MatchedFileID: analyzed[j],
+				Score:         score,
+				Percent:       percent,
+				Description:   fmt.Sprintf("Файл %s похож на файл %s на %d%%", analyzed[i], analyzed[j], percent),
+			})
+		}
+	}
+	sort.SliceStable(report.Pairs, func(i, j int) bool { return report.Pairs[i].Score > report.Pairs[j].Score })
+	report.SuspiciousCount = len(report.Pairs)
+	if len(report.Pairs) > limit {
+		report.Pairs = report.Pairs[:limit]
+	}
+	return report, nil
+}
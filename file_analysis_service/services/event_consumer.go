@@ -0,0 +1,107 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"file_analysis_service/models"
+	"fmt"
+	"log"
+	"pkg/adapters"
+	"pkg/events"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// EventConsumer обрабатывает события о файлах от File Storing Service.
+// @Summary Обработчик событий о файлах
+// @Description Ставит загруженные файлы в очередь анализа и удаляет результаты анализа удаленных файлов.
+// @Description Повторно доставленные события (с уже обработанным ID) пропускаются.
+// @Tags services
+type EventConsumer struct {
+	DBAdapter       *adapters.DBAdapter
+	AnalysisService *AnalysisService
+	JobQueue        *JobQueue
+	AutoAnalyze     bool // Ставить ли загруженные файлы в очередь анализа; файлы, загруженные с analyze=true, ставятся всегда
+}
+
+// NewEventConsumer создает новый экземпляр EventConsumer с автоматическим анализом загруженных файлов.
+// @Summary Создает новый EventConsumer
+// @Description Инициализирует обработчик событий с доступом к БД, сервису анализа и очереди задач.
+// @Return *EventConsumer
+func NewEventConsumer(dbAdapter *adapters.DBAdapter, analysisService *AnalysisService, jobQueue *JobQueue) *EventConsumer {
+	return &EventConsumer{
+		DBAdapter:       dbAdapter,
+		AnalysisService: analysisService,
+		JobQueue:        jobQueue,
+		AutoAnalyze:     true,
+	}
+}
+
+// Handle обрабатывает событие. Возвращает duplicate=true, если событие с таким ID уже было обработано.
+// Действия для каждого типа события идемпотентны, поэтому событие, обработанное, но не отмеченное
+// из-за сбоя, безопасно обработать повторно. События неизвестных типов отмечаются обработанными и пропускаются.
+// @Summary Обработка события
+// @Description Выполняет действие для события и запоминает его ID.
+// @Return bool, error "Было ли событие обработано ранее и ошибка, если есть"
+func (c *EventConsumer) Handle(ctx context.Context, event events.Event) (bool, error) {
+	if err := event.Validate(); err != nil {
+		return false, err
+	}
+
+	var processed models.ProcessedEvent
+	err := c.DBAdapter.First(&processed, "event_id = ?", event.ID)
+	if err == nil {
+		return true, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, fmt.Errorf("ошибка при проверке события %s: %w", event.ID, err)
+	}
+
+	switch event.Type {
+	case events.FileUploaded:
+		var data events.FileUploadedData
+		if len(event.Data) > 0 {
+			if err := json.Unmarshal(event.Data, &data); err != nil {
+				log.Printf("Не удалось разобрать данные события %s: %v", event.ID, err)
+			}
+		}
+		if c.AutoAnalyze || data.Analyze {
+			job, err := c.JobQueue.Enqueue(event.FileID, false)
+			if err != nil {
+				return false, fmt.Errorf("не удалось поставить файл %s в очередь анализа: %w", event.FileID, err)
+			}
+			log.Printf("Файл %s поставлен в очередь анализа по событию %s (задача %d)", event.FileID, event.ID, job.ID)
+		}
+	case events.FileDeleted:
+		deletedResults, deletedWordClouds, err := c.AnalysisService.DeleteAnalysisResults(event.FileID)
+		if err != nil {
+			return false, err
+		}
+		log.Printf("Удалены результаты анализа файла %s по событию %s: результатов %d, облаков слов %d", event.FileID, event.ID, deletedResults, deletedWordClouds)
+	default:
+		log.Printf("Пропущено событие %s неизвестного типа %s", event.ID, event.Type)
+	}
+
+	processed = models.ProcessedEvent{
+		EventID:     event.ID,
+		Type:        event.Type,
+		FileID:      event.FileID,
+		ProcessedAt: time.Now(),
+	}
+	// Параллельная доставка того же события могла успеть его отметить
+	if err := c.DBAdapter.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&processed).Error; err != nil {
+		return false, fmt.Errorf("не удалось отметить событие %s обработанным: %w", event.ID, err)
+	}
+	return false, nil
+}
+
+// Handler возвращает обработчик для подписки на брокер событий.
+func (c *EventConsumer) Handler() events.Handler {
+	return func(ctx context.Context, event events.Event) error {
+		_, err := c.Handle(ctx, event)
+		return err
+	}
+}
@@ -0,0 +1,133 @@
+package services
+
+import (
+	"context"
+	"file_analysis_service/models"
+	"pkg/adapters"
+	"pkg/events"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// analysisModels — таблицы, с которыми работают EventConsumer и DeleteAnalysisResults.
+var analysisModels = []interface{}{
+	&models.AnalysisResult{}, &models.WordFrequency{}, &models.FileSignature{}, &models.SimilarityMatch{},
+	&models.FileFingerprint{}, &models.SignatureBand{}, &models.AnalysisJob{}, &models.ProcessedEvent{}, &models.AnalysisCallback{},
+}
+
+// newTestConsumer создает EventConsumer поверх отдельной базы SQLite в памяти.
+func newTestConsumer(t *testing.T) (*EventConsumer, *gorm.DB) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("не удалось открыть SQLite: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	t.Cleanup(func() { sqlDB.Close() })
+	if err := db.AutoMigrate(analysisModels...); err != nil {
+		t.Fatalf("не удалось выполнить миграцию: %v", err)
+	}
+	dbAdapter := &adapters.DBAdapter{DB: db}
+	analysisService := &AnalysisService{DBAdapter: dbAdapter, Progress: NewProgressBroker()}
+	return NewEventConsumer(dbAdapter, analysisService, NewJobQueue(dbAdapter, analysisService)), db
+}
+
+func countRows(t *testing.T, db *gorm.DB, model interface{}, where ...interface{}) int64 {
+	t.Helper()
+	var count int64
+	query := db.Model(model)
+	if len(where) > 0 {
+		query = query.Where(where[0], where[1:]...)
+	}
+	if err := query.Count(&count).Error; err != nil {
+		t.Fatal(err)
+	}
+	return count
+}
+
+func TestEventConsumerSkipsDuplicateDelivery(t *testing.T) {
+	consumer, db := newTestConsumer(t)
+	event := events.Event{ID: "event-1", Type: events.FileUploaded, FileID: "f1", OccurredAt: time.Now()}
+
+	duplicate, err := consumer.Handle(context.Background(), event)
+	if err != nil || duplicate {
+		t.Fatalf("первая доставка: duplicate=%v, err=%v", duplicate, err)
+	}
+	if got := countRows(t, db, &models.AnalysisJob{}, "file_id = ?", "f1"); got != 1 {
+		t.Fatalf("задач анализа после первой доставки: %d, ожидалась 1", got)
+	}
+
+	// Анализ завершился: повторная обработка события поставила бы файл в очередь еще раз
+	db.Model(&models.AnalysisJob{}).Where("file_id = ?", "f1").Update("status", models.JobStatusSucceeded)
+
+	duplicate, err = consumer.Handle(context.Background(), event)
+	if err != nil || !duplicate {
+		t.Fatalf("повторная доставка: duplicate=%v, err=%v", duplicate, err)
+	}
+	if got := countRows(t, db, &models.AnalysisJob{}, "file_id = ?", "f1"); got != 1 {
+		t.Fatalf("повторная доставка создала задачу анализа: всего %d", got)
+	}
+}
+
+func TestEventConsumerRedeliveryAfterFailure(t *testing.T) {
+	consumer, db := newTestConsumer(t)
+	broker := events.NewInMemoryBroker()
+	broker.Subscribe(consumer.Handler())
+	event := events.Event{ID: "event-2", Type: events.FileUploaded, FileID: "f2", OccurredAt: time.Now()}
+
+	// Очередь анализа недоступна: событие не обработано и не должно считаться обработанным
+	if err := db.Migrator().DropTable(&models.AnalysisJob{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := broker.Publish(context.Background(), event); err == nil {
+		t.Fatal("ожидалась ошибка обработки события")
+	}
+	if got := countRows(t, db, &models.ProcessedEvent{}, "event_id = ?", event.ID); got != 0 {
+		t.Fatal("необработанное событие отмечено обработанным")
+	}
+
+	// Повторная доставка после восстановления обрабатывает событие один раз
+	if err := db.AutoMigrate(&models.AnalysisJob{}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := broker.Publish(context.Background(), event); err != nil {
+			t.Fatalf("доставка %d: %v", i+2, err)
+		}
+	}
+	if got := countRows(t, db, &models.AnalysisJob{}, "file_id = ?", "f2"); got != 1 {
+		t.Fatalf("задач анализа: %d, ожидалась 1", got)
+	}
+	if got := countRows(t, db, &models.ProcessedEvent{}, "event_id = ?", event.ID); got != 1 {
+		t.Fatalf("отметок об обработке: %d, ожидалась 1", got)
+	}
+}
+
+func TestEventConsumerFileDeletedIsIdempotent(t *testing.T) {
+	consumer, db := newTestConsumer(t)
+	result := models.AnalysisResult{FileID: "f3", WordFrequencies: []models.WordFrequency{{Word: "слово", Count: 2}}}
+	if err := db.Create(&result).Error; err != nil {
+		t.Fatal(err)
+	}
+	db.Create(&models.AnalysisJob{FileID: "f3", Status: models.JobStatusSucceeded, NextRunAt: time.Now()})
+
+	// Одно и то же событие удаления под разными ID (например, повтор после потерянной отметки) безопасно
+	for _, id := range []string{"event-3", "event-4"} {
+		event := events.Event{ID: id, Type: events.FileDeleted, FileID: "f3", OccurredAt: time.Now()}
+		if _, err := consumer.Handle(context.Background(), event); err != nil {
+			t.Fatalf("событие %s: %v", id, err)
+		}
+	}
+	for name, model := range map[string]interface{}{"результатов": &models.AnalysisResult{}, "задач": &models.AnalysisJob{}} {
+		if got := countRows(t, db, model, "file_id = ?", "f3"); got != 0 {
+			t.Fatalf("после удаления файла осталось %s: %d", name, got)
+		}
+	}
+	if got := countRows(t, db, &models.WordFrequency{}); got != 0 {
+		t.Fatalf("после удаления файла осталось частот слов: %d", got)
+	}
+}
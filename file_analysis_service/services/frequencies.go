@@ -0,0 +1,148 @@
+package services
+
+import (
+	"file_analysis_service/models"
+	"fmt"
+	"math"
+	"pkg/textproc"
+	"sort"
+	"unicode/utf8"
+)
+
+// DefaultWordFrequencyTopK — сколько самых частых слов текста сохраняется в результате анализа.
+const DefaultWordFrequencyTopK = 1000
+
+// WordFrequencyQuery задает параметры выборки частот слов.
+type WordFrequencyQuery struct {
+	Limit            int  // Максимальное количество слов в ответе
+	Offset           int  // Сколько слов пропустить
+	IncludeStopWords bool // Включать ли служебные слова
+	Lemmatize        bool // Объединять ли формы одного слова
+	ResultID         uint // ID результата анализа из истории (0 — последний)
+}
+
+// WordFrequencyItem — слово и его частота в тексте.
+// @Description Слово (или лемма при lemmatize=true), количество его вхождений и доля от всех слов текста.
+// @Name WordFrequencyItem
+type WordFrequencyItem struct {
+	Word      string  `json:"word" example:"книга"`
+	Count     int     `json:"count" example:"12"`
+	Frequency float64 `json:"frequency" example:"0.0048"` // Доля от общего количества слов текста
+}
+
+// WordFrequencyReport содержит страницу таблицы частот слов файла.
+// @Description Частоты слов текста файла в порядке убывания.
+// @Name WordFrequencyReport
+type WordFrequencyReport struct {
+	FileID           string              `json:"file_id" example:"unique-file-id"`
+	ResultID         uint                `json:"result_id" example:"1"` // ID результата анализа
+	Language         string              `json:"language" example:"ru"`
+	WordCount        int                 `json:"word_count" example:"2500"` // Всего слов в тексте
+	Total            int                 `json:"total" example:"640"`       // Количество строк таблицы с учетом фильтров
+	Limit            int                 `json:"limit" example:"100"`
+	Offset           int                 `json:"offset" example:"0"`
+	IncludeStopWords bool                `json:"include_stop_words" example:"false"`
+	Lemmatized       bool                `json:"lemmatized" example:"false"`
+	Items            []WordFrequencyItem `json:"items"`
+}
+
+// buildWordFrequencies по количествам вхождений слов текста (без чисел) возвращает topK самых частых слов.
+// Для языков, поддерживаемых textproc.Stem, формы с общей основой объединяются в лемму —
+// самую частую из этих форм в тексте («книги» и «книгой» получают лемму «книга», если она встречается чаще).
+func buildWordFrequencies(counts map[string]int, lang textproc.Language, topK int) []models.WordFrequency {
+	// Самая частая форма каждой основы; при равенстве — более короткая, затем по алфавиту
+	lemmas := make(map[string]string)
+	for word, count := range counts {
+		stem := textproc.Stem(lang, word)
+		best, ok := lemmas[stem]
+		if !ok || count > counts[best] ||
+			(count == counts[best] && (utf8.RuneCountInString(word) < utf8.RuneCountInString(best) ||
+				(utf8.RuneCountInString(word) == utf8.RuneCountInString(best) && word < best))) {
+			lemmas[stem] = word
+		}
+	}
+
+	frequencies := make([]models.WordFrequency, 0, len(counts))
+	for word, count := range counts {
+		frequencies = append(frequencies, models.WordFrequency{
+			Word:     word,
+			Lemma:    lemmas[textproc.Stem(lang, word)],
+			Count:    count,
+			StopWord: textproc.IsStopWordIn(lang, word) || utf8.RuneCountInString(word) < 2,
+		})
+	}
+	sort.Slice(frequencies, func(i, j int) bool {
+		if frequencies[i].Count != frequencies[j].Count {
+			return frequencies[i].Count > frequencies[j].Count
+		}
+		return frequencies[i].Word < frequencies[j].Word
+	})
+	if len(frequencies) > topK {
+		frequencies = frequencies[:topK]
+	}
+	return frequencies
+}
+
+// GetWordFrequencies возвращает страницу таблицы частот слов проанализированного файла.
+// @Summary Получение частот слов
+// @Description Возвращает сохраненные при анализе частоты слов файла с учетом фильтра служебных слов, объединения форм слова и пагинации.
+// @Param fileID path string true "ID файла"
+// @Return *WordFrequencyReport, error "Частоты слов и ошибка, если есть (например, если файл не анализировался)"
+func (s *AnalysisService) GetWordFrequencies(fileID string, query WordFrequencyQuery) (*WordFrequencyReport, error) {
+	result, err := s.GetAnalysisResult(fileID, query.ResultID)
+	if err != nil {
+		return nil, err
+	}
+
+	var stored []models.WordFrequency
+	if err := s.DBAdapter.Find(&stored, "analysis_result_id = ?", result.ID); err != nil {
+		return nil, fmt.Errorf("не удалось получить частоты слов для файла %s: %w", fileID, err)
+	}
+
+	counts := make(map[string]int)
+	for _, f := range stored {
+		if f.StopWord && !query.IncludeStopWords {
+			continue
+		}
+		word := f.Word
+		if query.Lemmatize && f.Lemma != "" {
+			word = f.Lemma
+		}
+		counts[word] += f.Count
+	}
+	items := make([]WordFrequencyItem, 0, len(counts))
+	for word, count := range counts {
+		item := WordFrequencyItem{Word: word, Count: count}
+		if result.WordCount > 0 {
+			item.Frequency = math.Round(float64(count)/float64(result.WordCount)*10000) / 10000
+		}
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Count != items[j].Count {
+			return items[i].Count > items[j].Count
+		}
+		return items[i].Word < items[j].Word
+	})
+
+	report := &WordFrequencyReport{
+		FileID:           fileID,
+		ResultID:         result.ID,
+		Language:         result.Language,
+		WordCount:        result.WordCount,
+		Total:            len(items),
+		Limit:            query.Limit,
+		Offset:           query.Offset,
+		IncludeStopWords: query.IncludeStopWords,
+		Lemmatized:       query.Lemmatize,
+		Items:            []WordFrequencyItem{},
+	}
+	if query.Offset < len(items) {
+		end := len(items)
+		if query.Limit > 0 && query.Offset+query.Limit < end {
+			end = query.Offset + query.Limit
+		}
+		report.Items = items[query.Offset:end]
+	}
+	return report, nil
+}
@@ -0,0 +1,338 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"file_analysis_service/models"
+	"fmt"
+	"log"
+	"pkg/adapters"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// DefaultJobWorkers — количество воркеров очереди анализа по умолчанию.
+	DefaultJobWorkers = 2
+	// DefaultJobMaxAttempts — количество попыток анализа файла по умолчанию.
+	DefaultJobMaxAttempts = 3
+	// DefaultJobRetryDelay — базовая задержка перед повторной попыткой; удваивается с каждой попыткой.
+	DefaultJobRetryDelay = 10 * time.Second
+	// DefaultJobPollInterval — как часто воркеры проверяют очередь, если их не разбудили явно.
+	DefaultJobPollInterval = 2 * time.Second
+)
+
+// errJobRemoved — задача удалена (вместе с файлом) или перестала выполняться, пока шел анализ.
+var errJobRemoved = errors.New("задача анализа больше не выполняется")
+
+// JobQueue — персистентная очередь задач анализа с пулом воркеров.
+// @Summary Очередь задач анализа
+// @Description Хранит задачи анализа в БД, выполняет их пулом воркеров и повторяет неудачные попытки.
+// @Tags services
+type JobQueue struct {
+	DBAdapter       *adapters.DBAdapter
+	AnalysisService *AnalysisService
+	Workers         int                // Количество одновременно выполняемых задач
+	MaxAttempts     int                // Максимальное количество попыток для новой задачи
+	RetryDelay      time.Duration      // Базовая задержка перед повторной попыткой
+	PollInterval    time.Duration      // Интервал опроса очереди
+	Webhooks        *WebhookDispatcher // Уведомления о завершении задач; nil — уведомления не отправляются
+
+	wakeup chan struct{}
+}
+
+// NewJobQueue создает новую очередь задач анализа.
+// @Summary Создает новый JobQueue
+// @Description Инициализирует очередь с настройками по умолчанию. Воркеры запускаются методом Start.
+// @Return *JobQueue
+func NewJobQueue(dbAdapter *adapters.DBAdapter, analysisService *AnalysisService) *JobQueue {
+	return &JobQueue{
+		DBAdapter:       dbAdapter,
+		AnalysisService: analysisService,
+		Workers:         DefaultJobWorkers,
+		MaxAttempts:     DefaultJobMaxAttempts,
+		RetryDelay:      DefaultJobRetryDelay,
+		PollInterval:    DefaultJobPollInterval,
+		wakeup:          make(chan struct{}, 1),
+	}
+}
+
+// enqueueAttempts — сколько раз Enqueue повторяет поиск задачи, если одновременный запрос создал ее раньше.
+const enqueueAttempts = 3
+
+// Enqueue ставит файл в очередь на анализ.
+// @Summary Постановка задачи в очередь
+// @Description Создает задачу анализа файла. Если для файла уже есть незавершенная задача, возвращает ее.
+// @Description Ожидающая задача при force становится принудительной; если выполняется задача без force, принудительная задача создается следом за ней.
+// @Description Одновременные запросы получают одну и ту же задачу: уникальный индекс не дает создать вторую.
+// @Param fileID path string true "ID файла"
+// @Param force query bool false "Выполнить анализ заново, даже если есть результат текущей версии"
+// @Return *models.AnalysisJob, error
+func (q *JobQueue) Enqueue(fileID string, force bool) (*models.AnalysisJob, error) {
+	for attempt := 0; attempt < enqueueAttempts; attempt++ {
+		job, err := q.enqueueOnce(fileID, force)
+		if err != nil || job != nil {
+			return job, err
+		}
+	}
+	return nil, fmt.Errorf("не удалось поставить файл %s в очередь анализа: задачу одновременно изменяют другие запросы", fileID)
+}
+
+// enqueueOnce возвращает подходящую незавершенную задачу файла или создает новую.
+// Возвращает nil, если задачу между поиском и созданием создал одновременный запрос.
+func (q *JobQueue) enqueueOnce(fileID string, force bool) (*models.AnalysisJob, error) {
+	var jobs []models.AnalysisJob
+	err := q.DBAdapter.Find(&jobs, "file_id = ? AND status IN ?", fileID, []string{models.JobStatusPending, models.JobStatusRunning})
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при поиске задачи анализа для файла %s: %w", fileID, err)
+	}
+	for i := range jobs {
+		job := &jobs[i]
+		if job.Status == models.JobStatusPending {
+			if force && !job.Force {
+				// Задача еще не взята в работу — достаточно сделать ее принудительной
+				result := q.DBAdapter.DB.Model(job).Where("status = ?", models.JobStatusPending).Update("force", true)
+				if result.Error != nil {
+					return nil, fmt.Errorf("не удалось обновить задачу анализа %d: %w", job.ID, result.Error)
+				}
+				if result.RowsAffected == 0 {
+					continue // Задачу успели взять в работу
+				}
+				job.Force = true
+			}
+			return job, nil
+		}
+		// Выполняющаяся задача без force может вернуть устаревший результат, поэтому для force нужна новая задача
+		if job.Force || !force {
+			return job, nil
+		}
+	}
+
+	job := models.AnalysisJob{
+		FileID:      fileID,
+		Status:      models.JobStatusPending,
+		MaxAttempts: q.MaxAttempts,
+		Force:       force,
+		NextRunAt:   time.Now(),
+	}
+	// Индекс idx_analysis_jobs_active допускает одну незавершенную задачу файла с каждым значением force,
+	// поэтому из одновременных запросов задачу создает только один, а остальные находят ее при повторном поиске
+	result := q.DBAdapter.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&job)
+	if result.Error != nil {
+		return nil, fmt.Errorf("не удалось создать задачу анализа для файла %s: %w", fileID, result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+	q.notify()
+	return &job, nil
+}
+
+// GetJob возвращает задачу анализа по ее ID.
+// @Summary Получение задачи анализа
+// @Description Возвращает состояние задачи анализа.
+// @Param id path int true "ID задачи"
+// @Return *models.AnalysisJob, error
+func (q *JobQueue) GetJob(id uint) (*models.AnalysisJob, error) {
+	var job models.AnalysisJob
+	if err := q.DBAdapter.First(&job, "id = ?", id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("задача анализа %d не найдена: %w", id, err)
+		}
+		return nil, fmt.Errorf("ошибка при поиске задачи анализа %d: %w", id, err)
+	}
+	return &job, nil
+}
+
+// LatestJob возвращает последнюю задачу анализа файла.
+// @Summary Получение последней задачи анализа файла
+// @Param fileID path string true "ID файла"
+// @Return *models.AnalysisJob, error "Задача и ошибка, если есть (gorm.ErrRecordNotFound, если задач у файла нет)"
+func (q *JobQueue) LatestJob(fileID string) (*models.AnalysisJob, error) {
+	var job models.AnalysisJob
+	if err := q.DBAdapter.Last(&job, "file_id = ?", fileID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("задачи анализа файла %s не найдены: %w", fileID, err)
+		}
+		return nil, fmt.Errorf("ошибка при поиске задачи анализа файла %s: %w", fileID, err)
+	}
+	return &job, nil
+}
+
+// Start возобновляет прерванные задачи и запускает воркеры.
+// @Summary Запуск очереди
+// @Description Переводит задачи, которые выполнялись в момент остановки сервиса, обратно в pending и запускает воркеры. Воркеры завершаются при отмене ctx.
+// @Return error
+func (q *JobQueue) Start(ctx context.Context) error {
+	// Сервис был остановлен во время выполнения этих задач — возвращаем их в очередь
+	result := q.DBAdapter.DB.Model(&models.AnalysisJob{}).
+		Where("status = ?", models.JobStatusRunning).
+		Updates(map[string]interface{}{"status": models.JobStatusPending, "next_run_at": time.Now()})
+	if result.Error != nil {
+		return fmt.Errorf("не удалось возобновить прерванные задачи анализа: %w", result.Error)
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("Возобновлено прерванных задач анализа: %d", result.RowsAffected)
+	}
+
+	for i := 0; i < q.Workers; i++ {
+		go q.worker(ctx)
+	}
+	return nil
+}
+
+func (q *JobQueue) notify() {
+	select {
+	case q.wakeup <- struct{}{}:
+	default:
+	}
+}
+
+func (q *JobQueue) worker(ctx context.Context) {
+	ticker := time.NewTicker(q.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Выбираем задачи, пока они есть, затем ждем сигнала или тика
+		for {
+			if ctx.Err() != nil {
+				return
+			}
+			job, err := q.claimNext()
+			if err != nil {
+				log.Printf("Ошибка при получении задачи анализа из очереди: %v", err)
+				break
+			}
+			if job == nil {
+				break
+			}
+			q.process(job)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-q.wakeup:
+		case <-ticker.C:
+		}
+	}
+}
+
+// claimNext атомарно переводит самую старую готовую задачу в состояние running.
+// Возвращает nil, если готовых задач нет.
+func (q *JobQueue) claimNext() (*models.AnalysisJob, error) {
+	var job models.AnalysisJob
+	err := q.DBAdapter.DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_run_at <= ?", models.JobStatusPending, now).
+			Order("next_run_at, id").
+			First(&job).Error
+		if err != nil {
+			return err
+		}
+		job.Status = models.JobStatusRunning
+		job.Attempts++
+		job.StartedAt = &now
+		return tx.Save(&job).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (q *JobQueue) process(job *models.AnalysisJob) {
+	progress := q.AnalysisService.Progress
+	progress.Publish(ProgressEvent{Stage: ProgressStarted, FileID: job.FileID, JobID: job.ID, Attempt: job.Attempts})
+	result, err := q.AnalysisService.AnalyzeFile(job.FileID, job.Force)
+	now := time.Now()
+	if err == nil {
+		job.Status = models.JobStatusSucceeded
+		job.LastError = ""
+		job.ResultID = &result.ID
+		job.FinishedAt = &now
+		// Отметка нужна только фильтру has_analysis списка файлов, поэтому ошибка ее отправки не делает анализ неуспешным
+		if err := q.AnalysisService.FileStoringServiceAdapter.SetFileAnalyzed(job.FileID, true); err != nil {
+			log.Printf("Не удалось сохранить отметку об анализе файла %s: %v", job.FileID, err)
+		}
+	} else if errors.Is(err, ErrAnalysisCancelled) {
+		// Файл удален: повторять анализ и уведомлять о неудаче незачем, задача завершается сразу
+		job.Status = models.JobStatusCancelled
+		job.LastError = err.Error()
+		job.FinishedAt = &now
+		cancelled := ProgressEvent{Stage: ProgressCancelled, FileID: job.FileID, JobID: job.ID, Attempt: job.Attempts, Error: job.LastError}
+		defer func() { progress.Publish(cancelled) }()
+		log.Printf("Задача анализа %d отменена: файл %s удален во время анализа", job.ID, job.FileID)
+	} else {
+		job.LastError = err.Error()
+		failure := ProgressEvent{Stage: ProgressFailed, FileID: job.FileID, JobID: job.ID, Attempt: job.Attempts, Error: job.LastError}
+		// Событие публикуется после сохранения состояния задачи, чтобы подписчик, запросивший задачу, увидел его
+		defer func() { progress.Publish(failure) }()
+		if job.Attempts >= job.MaxAttempts {
+			job.Status = models.JobStatusFailed
+			job.FinishedAt = &now
+			log.Printf("Задача анализа %d (файл %s) завершилась ошибкой после %d попыток: %v", job.ID, job.FileID, job.Attempts, err)
+		} else {
+			job.Status = models.JobStatusPending
+			job.NextRunAt = now.Add(q.RetryDelay << (job.Attempts - 1))
+			failure.Stage = ProgressRetrying
+			failure.NextRunAt = &job.NextRunAt
+			log.Printf("Попытка %d анализа файла %s (задача %d) не удалась, повтор в %s: %v", job.Attempts, job.FileID, job.ID, job.NextRunAt.Format(time.RFC3339), err)
+		}
+	}
+
+	// Об отмененной задаче не уведомляют: файла и его результатов больше нет
+	finished := job.Status == models.JobStatusSucceeded || job.Status == models.JobStatusFailed
+	notify := finished && q.Webhooks != nil
+	ownerID := ""
+	if notify {
+		// Владелец файла получает уведомление на URL из своих настроек; если его не удалось узнать,
+		// отправляются только уведомления на URL из запросов анализа
+		owners, err := q.AnalysisService.FileStoringServiceAdapter.GetFileOwners([]string{job.FileID})
+		if err != nil {
+			log.Printf("Не удалось получить владельца файла %s для уведомления о завершении задачи %d: %v", job.FileID, job.ID, err)
+		}
+		ownerID = owners[job.FileID]
+	}
+
+	// Задача обновляется, только если она все еще выполняется: пока шел анализ, файл могли удалить вместе с его задачами,
+	// и Save вставил бы удаленную задачу заново
+	err = q.DBAdapter.Transaction(func(tx *adapters.DBAdapter) error {
+		result := tx.DB.Model(&models.AnalysisJob{}).
+			Where("id = ? AND status = ?", job.ID, models.JobStatusRunning).
+			Updates(map[string]interface{}{
+				"status":      job.Status,
+				"last_error":  job.LastError,
+				"result_id":   job.ResultID,
+				"next_run_at": job.NextRunAt,
+				"finished_at": job.FinishedAt,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errJobRemoved
+		}
+		if notify {
+			return q.Webhooks.JobFinished(tx, job, ownerID)
+		}
+		return nil
+	})
+	if errors.Is(err, errJobRemoved) {
+		log.Printf("Задача анализа %d (файл %s) удалена во время выполнения, ее состояние не сохраняется", job.ID, job.FileID)
+		return
+	}
+	if err != nil {
+		log.Printf("Не удалось сохранить состояние задачи анализа %d: %v", job.ID, err)
+		return
+	}
+	if notify {
+		q.Webhooks.Notify()
+	}
+}
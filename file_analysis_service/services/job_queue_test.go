@@ -0,0 +1,322 @@
+package services
+
+import (
+	"context"
+	"file_analysis_service/models"
+	"net/http"
+	"net/http/httptest"
+	"pkg/adapters"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// newTestQueue создает очередь анализа поверх базы из newTestConsumer; запросы к File Storing Service обрабатывает fileStoring.
+func newTestQueue(t *testing.T, fileStoring http.Handler) (*JobQueue, *gorm.DB) {
+	t.Helper()
+	consumer, db := newTestConsumer(t)
+	server := httptest.NewServer(fileStoring)
+	t.Cleanup(server.Close)
+	consumer.AnalysisService.FileStoringServiceAdapter = &adapters.FileStoringServiceAdapter{ServiceBaseURL: server.URL, Client: server.Client()}
+	return consumer.JobQueue, db
+}
+
+// unavailableFileStoring отвечает ошибкой на любой запрос, поэтому каждая попытка анализа неудачна.
+var unavailableFileStoring = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	http.Error(w, "недоступен", http.StatusServiceUnavailable)
+})
+
+func TestEnqueueReusesActiveJob(t *testing.T) {
+	queue, db := newTestQueue(t, unavailableFileStoring)
+
+	job, err := queue.Enqueue("f1", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := queue.Enqueue("f1", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.ID != job.ID {
+		t.Fatalf("повторная постановка создала задачу %d, ожидалась %d", again.ID, job.ID)
+	}
+
+	// Ожидающая задача становится принудительной, новая не создается
+	forced, err := queue.Enqueue("f1", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if forced.ID != job.ID || !forced.Force {
+		t.Fatalf("force для ожидающей задачи: задача %d, force=%v, ожидалась задача %d с force", forced.ID, forced.Force, job.ID)
+	}
+	var stored models.AnalysisJob
+	db.First(&stored, job.ID)
+	if !stored.Force {
+		t.Fatal("force ожидающей задачи не сохранен")
+	}
+	if got := countRows(t, db, &models.AnalysisJob{}, "file_id = ?", "f1"); got != 1 {
+		t.Fatalf("задач файла: %d, ожидалась 1", got)
+	}
+}
+
+func TestEnqueueForceWhileRunning(t *testing.T) {
+	queue, db := newTestQueue(t, unavailableFileStoring)
+
+	job, err := queue.Enqueue("f1", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claimed, err := queue.claimNext(); err != nil || claimed == nil || claimed.ID != job.ID {
+		t.Fatalf("claimNext: %+v, %v", claimed, err)
+	}
+
+	// Выполняющаяся задача без force может вернуть устаревший результат — принудительная задача создается следом
+	forced, err := queue.Enqueue("f1", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if forced.ID == job.ID || !forced.Force || forced.Status != models.JobStatusPending {
+		t.Fatalf("force во время выполнения: %+v, ожидалась новая ожидающая задача с force", forced)
+	}
+	for _, force := range []bool{true, false} {
+		if _, err := queue.Enqueue("f1", force); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := countRows(t, db, &models.AnalysisJob{}, "file_id = ?", "f1"); got != 2 {
+		t.Fatalf("задач файла: %d, ожидалось 2", got)
+	}
+}
+
+func TestEnqueueConcurrentRequestsShareJob(t *testing.T) {
+	queue, db := newTestQueue(t, unavailableFileStoring)
+
+	// Одновременный запрос создает задачу между поиском и вставкой
+	var competitor models.AnalysisJob
+	raced := false
+	err := db.Callback().Create().Before("gorm:begin_transaction").Register("test:concurrent_enqueue", func(tx *gorm.DB) {
+		if _, ok := tx.Statement.Dest.(*models.AnalysisJob); !ok || raced {
+			return
+		}
+		raced = true
+		competitor = models.AnalysisJob{FileID: "f1", Status: models.JobStatusPending, MaxAttempts: 3, NextRunAt: time.Now()}
+		if err := db.Create(&competitor).Error; err != nil {
+			t.Errorf("не удалось создать задачу одновременного запроса: %v", err)
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	job, err := queue.Enqueue("f1", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.ID != competitor.ID {
+		t.Fatalf("Enqueue вернул задачу %d, ожидалась задача одновременного запроса %d", job.ID, competitor.ID)
+	}
+	if got := countRows(t, db, &models.AnalysisJob{}, "file_id = ?", "f1"); got != 1 {
+		t.Fatalf("задач файла: %d, ожидалась 1", got)
+	}
+}
+
+func TestActiveJobUniqueIndex(t *testing.T) {
+	_, db := newTestQueue(t, unavailableFileStoring)
+
+	create := func(status string, force bool) error {
+		return db.Create(&models.AnalysisJob{FileID: "f1", Status: status, Force: force, NextRunAt: time.Now()}).Error
+	}
+	if err := create(models.JobStatusRunning, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := create(models.JobStatusPending, false); err == nil {
+		t.Fatal("создана вторая незавершенная задача без force")
+	}
+	if err := create(models.JobStatusPending, true); err != nil {
+		t.Fatalf("принудительная задача следом за выполняющейся: %v", err)
+	}
+	// Завершенные задачи индекс не ограничивает
+	for i := 0; i < 2; i++ {
+		if err := create(models.JobStatusSucceeded, false); err != nil {
+			t.Fatal(err)
+		}
+		if err := create(models.JobStatusFailed, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestJobRetriesWithBackoff(t *testing.T) {
+	queue, db := newTestQueue(t, unavailableFileStoring)
+	queue.RetryDelay = time.Minute
+
+	job, err := queue.Enqueue("f1", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for attempt := 1; attempt <= queue.MaxAttempts; attempt++ {
+		claimed, err := queue.claimNext()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if claimed == nil || claimed.ID != job.ID || claimed.Attempts != attempt || claimed.Status != models.JobStatusRunning {
+			t.Fatalf("попытка %d: взята задача %+v", attempt, claimed)
+		}
+		before := time.Now()
+		queue.process(claimed)
+
+		var stored models.AnalysisJob
+		db.First(&stored, job.ID)
+		if stored.LastError == "" {
+			t.Fatalf("попытка %d: ошибка не сохранена", attempt)
+		}
+		if attempt == queue.MaxAttempts {
+			if stored.Status != models.JobStatusFailed || stored.FinishedAt == nil {
+				t.Fatalf("после последней попытки: status=%s, finished_at=%v", stored.Status, stored.FinishedAt)
+			}
+			break
+		}
+		// Задержка удваивается с каждой попыткой: 1m, 2m, ...
+		delay := queue.RetryDelay << (attempt - 1)
+		if stored.Status != models.JobStatusPending || stored.NextRunAt.Before(before.Add(delay)) || stored.NextRunAt.After(time.Now().Add(delay)) {
+			t.Fatalf("попытка %d: status=%s, next_run_at через %v, ожидалось через %v", attempt, stored.Status, time.Until(stored.NextRunAt), delay)
+		}
+		if next, err := queue.claimNext(); err != nil || next != nil {
+			t.Fatalf("попытка %d: задача взята до истечения задержки: %+v, %v", attempt, next, err)
+		}
+		db.Model(&stored).Update("next_run_at", time.Now().Add(-time.Second))
+	}
+}
+
+func TestClaimNextTakesOldestReadyJob(t *testing.T) {
+	queue, db := newTestQueue(t, unavailableFileStoring)
+	now := time.Now()
+	jobs := []models.AnalysisJob{
+		{FileID: "later", Status: models.JobStatusPending, NextRunAt: now.Add(-time.Minute)},
+		{FileID: "oldest", Status: models.JobStatusPending, NextRunAt: now.Add(-time.Hour)},
+		{FileID: "future", Status: models.JobStatusPending, NextRunAt: now.Add(time.Hour)},
+		{FileID: "finished", Status: models.JobStatusFailed, NextRunAt: now.Add(-2 * time.Hour)},
+	}
+	if err := db.Create(&jobs).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{"oldest", "later"} {
+		job, err := queue.claimNext()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if job == nil || job.FileID != want || job.StartedAt == nil {
+			t.Fatalf("взята задача %+v, ожидалась задача файла %s", job, want)
+		}
+	}
+	if job, err := queue.claimNext(); err != nil || job != nil {
+		t.Fatalf("взята задача %+v (%v), хотя готовых задач нет", job, err)
+	}
+}
+
+func TestStartResumesInterruptedJobs(t *testing.T) {
+	queue, db := newTestQueue(t, unavailableFileStoring)
+	queue.Workers = 0
+	started := time.Now().Add(-time.Hour)
+	jobs := []models.AnalysisJob{
+		{FileID: "f1", Status: models.JobStatusRunning, Attempts: 1, MaxAttempts: 3, StartedAt: &started, NextRunAt: started},
+		{FileID: "f2", Status: models.JobStatusSucceeded, Attempts: 1, MaxAttempts: 3, StartedAt: &started, NextRunAt: started},
+	}
+	if err := db.Create(&jobs).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := queue.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	var resumed, finished models.AnalysisJob
+	db.First(&resumed, jobs[0].ID)
+	db.First(&finished, jobs[1].ID)
+	if resumed.Status != models.JobStatusPending || resumed.Attempts != 1 {
+		t.Fatalf("прерванная задача: status=%s, attempts=%d", resumed.Status, resumed.Attempts)
+	}
+	if finished.Status != models.JobStatusSucceeded {
+		t.Fatalf("завершенная задача возвращена в очередь: status=%s", finished.Status)
+	}
+	job, err := queue.claimNext()
+	if err != nil || job == nil || job.ID != resumed.ID || job.Attempts != 2 {
+		t.Fatalf("возобновленная задача не взята сразу: %+v, %v", job, err)
+	}
+}
+
+// deletedFileStoring отдает текст файла, но владельца у файла уже нет: файл удалили, пока шел анализ.
+var deletedFileStoring = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/api/v1/internal/files/owners" {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"owners":{}}`))
+		return
+	}
+	w.Write([]byte("Текст файла, который удалили во время анализа."))
+})
+
+func TestCancelledJobIsNotRetried(t *testing.T) {
+	queue, db := newTestQueue(t, deletedFileStoring)
+	if err := db.AutoMigrate(&models.WebhookEndpoint{}, &models.WebhookDelivery{}); err != nil {
+		t.Fatal(err)
+	}
+	queue.AnalysisService.ShingleSize = DefaultShingleSize
+	queue.Webhooks = NewWebhookDispatcher(queue.DBAdapter, true)
+
+	job, err := queue.Enqueue("f1", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := queue.Webhooks.RegisterCallback(job.ID, "user1", "https://example.com/hook"); err != nil {
+		t.Fatal(err)
+	}
+	events, unsubscribe := queue.AnalysisService.Progress.Subscribe("f1")
+	defer unsubscribe()
+
+	claimed, err := queue.claimNext()
+	if err != nil || claimed == nil {
+		t.Fatalf("задача не взята: %+v, %v", claimed, err)
+	}
+	queue.process(claimed)
+
+	var stored models.AnalysisJob
+	db.First(&stored, job.ID)
+	if stored.Status != models.JobStatusCancelled || stored.FinishedAt == nil || stored.Attempts != 1 {
+		t.Fatalf("задача после удаления файла: status=%s, attempts=%d, finished_at=%v", stored.Status, stored.Attempts, stored.FinishedAt)
+	}
+	if next, err := queue.claimNext(); err != nil || next != nil {
+		t.Fatalf("отмененная задача взята повторно: %+v, %v", next, err)
+	}
+	if got := countRows(t, db, &models.WebhookDelivery{}); got != 0 {
+		t.Fatalf("уведомлений об отмененной задаче: %d", got)
+	}
+	if got := countRows(t, db, &models.AnalysisResult{}); got != 0 {
+		t.Fatalf("результатов удаленного файла: %d", got)
+	}
+
+	// Последнее событие задачи — cancelled, после него поток событий закрывается
+	var last ProgressEvent
+	for drained := false; !drained; {
+		select {
+		case event := <-events:
+			last = event
+		default:
+			drained = true
+		}
+	}
+	if last.Stage != ProgressCancelled || !last.Final() || last.JobID != job.ID {
+		t.Fatalf("последнее событие %+v, ожидалось cancelled", last)
+	}
+	if event := JobProgress(&stored); event.Stage != ProgressCancelled || !event.Final() {
+		t.Fatalf("состояние отмененной задачи: %+v", event)
+	}
+
+	// На отмененную задачу уведомление не регистрируется
+	if err := queue.Webhooks.RegisterCallback(job.ID, "user1", "https://example.com/hook"); err != nil {
+		t.Fatal(err)
+	}
+	if got := countRows(t, db, &models.WebhookDelivery{}); got != 0 {
+		t.Fatalf("уведомлений после регистрации на отмененную задачу: %d", got)
+	}
+}
@@ -0,0 +1,371 @@
+package services
+
+import (
+	"bufio"
+	"errors"
+	"file_analysis_service/models"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// DefaultPassageMinWords — наименьшая длина общего фрагмента в словах, включаемого в отчет по умолчанию.
+	// Более короткие фрагменты winnowing находит не всегда, поэтому меньшее значение не дает полного отчета.
+	DefaultPassageMinWords = fingerprintSize + winnowingWindow - 1
+	// DefaultPassageLimit — сколько самых длинных фрагментов включается в отчет по умолчанию.
+	DefaultPassageLimit = 500
+	// fingerprintInsertBatch — сколько отпечатков сохраняется в БД одним запросом.
+	fingerprintInsertBatch = 1000
+	// fingerprintQueryBatch — сколько хешей отпечатков ищется в БД одним запросом.
+	fingerprintQueryBatch = 1000
+	// maxHashOccurrences — отпечаток, встречающийся в других файлах чаще, считается общей фразой
+	// (шаблон задания, цитата из условия) и не используется для поиска фрагментов.
+	maxHashOccurrences = 50
+)
+
+// ErrOutdatedAnalysis возвращается, если последний анализ файла выполнен версией алгоритмов без отпечатков winnowing.
+var ErrOutdatedAnalysis = errors.New("файл проанализирован предыдущей версией алгоритмов без отпечатков, выполните повторный анализ")
+
+// Passage — фрагмент текста файла, совпадающий с фрагментом другого файла.
+// @Description Фрагмент проверяемого файла и совпадающий с ним фрагмент файла-источника. Смещения отсчитываются в символах Unicode
+// @Description (кодовых точках) извлеченного текста файлов: конец фрагмента — смещение символа, следующего за ним.
+// @Name Passage
+type Passage struct {
+	Source       int    `json:"source" example:"0"`                              // Номер источника в списке sources
+	SourceFileID string `json:"source_file_id" example:"other-file-id"`          // ID файла-источника; пусто для файла другого пользователя
+	Start        int    `json:"start" example:"812"`                             // Начало фрагмента в проверяемом файле
+	End          int    `json:"end" example:"1290"`                              // Конец фрагмента в проверяемом файле
+	SourceStart  int    `json:"source_start" example:"40"`                       // Начало фрагмента в файле-источнике
+	SourceEnd    int    `json:"source_end" example:"518"`                        // Конец фрагмента в файле-источнике
+	Length       int    `json:"length" example:"64"`                             // Длина совпадения в словах
+	Description  string `json:"description" example:"64 слова из other-file-id"` // Описание фрагмента
+}
+
+// PassageSource — файл, из которого взяты фрагменты проверяемого файла.
+// @Description Файл-источник, количество совпадающих фрагментов и слов проверяемого файла, покрытых ими.
+// @Name PassageSource
+type PassageSource struct {
+	FileID         string `json:"file_id" example:"other-file-id"` // Пусто для файла другого пользователя
+	PassageCount   int    `json:"passage_count" example:"3"`
+	MatchedWords   int    `json:"matched_words" example:"150"`
+	MatchedPercent int    `json:"matched_percent" example:"12"` // Доля слов проверяемого файла, совпадающих с этим источником
+}
+
+// PassageReport — отчет о совпадающих фрагментах файла.
+// @Description Фрагменты файла, совпадающие с фрагментами других проанализированных файлов, найденные по отпечаткам winnowing.
+// @Name PassageReport
+type PassageReport struct {
+	FileID           string          `json:"file_id" example:"unique-file-id"`
+	AnalysisResultID uint            `json:"analysis_result_id" example:"1"` // Результат анализа, отпечатки которого использованы
+	WordCount        int             `json:"word_count" example:"1250"`      // Количество слов проверяемого файла
+	MatchedWords     int             `json:"matched_words" example:"180"`    // Количество слов, входящих хотя бы в один фрагмент
+	MatchedPercent   int             `json:"matched_percent" example:"14"`
+	MinWords         int             `json:"min_words" example:"8"`     // Наименьшая длина фрагмента в словах
+	PassageCount     int             `json:"passage_count" example:"4"` // Количество найденных фрагментов; в passages не больше limit самых длинных
+	Sources          []PassageSource `json:"sources"`                   // Источники в порядке убывания количества совпадающих слов
+	Passages         []Passage       `json:"passages"`                  // Фрагменты в порядке их положения в проверяемом файле
+}
+
+// HideSources скрывает ID файлов-источников, для которых visible возвращает false:
+// фрагменты остаются в отчете, а источник описывается как файл другого пользователя.
+func (r *PassageReport) HideSources(visible func(fileID string) bool) {
+	hidden := make([]bool, len(r.Sources))
+	for i, source := range r.Sources {
+		if !visible(source.FileID) {
+			hidden[i] = true
+			r.Sources[i].FileID = ""
+		}
+	}
+	for i, passage := range r.Passages {
+		if hidden[passage.Source] {
+			r.Passages[i].SourceFileID = ""
+			r.Passages[i].Description = fmt.Sprintf("%d %s из файла другого пользователя", passage.Length, wordsNoun(passage.Length))
+		}
+	}
+}
+
+// fingerprintPair — общий отпечаток проверяемого файла и файла-источника.
+type fingerprintPair struct {
+	suspect, source models.FileFingerprint
+}
+
+// GetPassageReport находит фрагменты последнего проанализированного текста файла, совпадающие с фрагментами других файлов.
+// @Summary Отчет о совпадающих фрагментах
+// @Description Ищет отпечатки winnowing файла среди отпечатков других файлов и объединяет идущие подряд общие отпечатки
+// @Description в фрагменты. В отчет включаются фрагменты не короче minWords слов, в passages — не больше limit самых длинных.
+// @Param fileID path string true "ID файла"
+// @Param minWords query int false "Наименьшая длина фрагмента в словах"
+// @Param limit query int false "Сколько самых длинных фрагментов включить в отчет"
+// @Return *PassageReport, error "Отчет и ошибка, если есть (например, если файл не анализировался)"
+func (s *AnalysisService) GetPassageReport(fileID string, minWords, limit int) (*PassageReport, error) {
+	result, err := s.GetAnalysisResult(fileID, 0)
+	if err != nil {
+		return nil, err
+	}
+	var suspect []models.FileFingerprint
+	if err := s.DBAdapter.DB.Where("file_id = ?", fileID).Order("position").Find(&suspect).Error; err != nil {
+		return nil, fmt.Errorf("не удалось загрузить отпечатки файла %s: %w", fileID, err)
+	}
+	if len(suspect) == 0 && result.AnalyzerVersion != AnalyzerVersion && result.WordCount >= fingerprintSize {
+		return nil, ErrOutdatedAnalysis
+	}
+
+	report := &PassageReport{
+		FileID:           fileID,
+		AnalysisResultID: result.ID,
+		WordCount:        result.WordCount,
+		MinWords:         minWords,
+		Sources:          []PassageSource{},
+		Passages:         []Passage{},
+	}
+	pairs, err := s.findFingerprintPairs(fileID, suspect)
+	if err != nil {
+		return nil, err
+	}
+
+	// Фрагменты каждого источника; интервалы слов нужны для подсчета покрытия
+	type sourcePassages struct {
+		fileID   string
+		passages []Passage
+		words    [][2]int
+	}
+	var sources []sourcePassages
+	var allWords [][2]int
+	for sourceID, sourcePairs := range pairs {
+		passages, words := mergePairs(sourceID, sourcePairs, minWords)
+		if len(passages) == 0 {
+			continue
+		}
+		sources = append(sources, sourcePassages{fileID: sourceID, passages: passages, words: words})
+		allWords = append(allWords, words...)
+	}
+	report.MatchedWords = coveredWords(allWords)
+	report.MatchedPercent = percentOf(report.MatchedWords, report.WordCount)
+
+	for _, source := range sources {
+		matched := coveredWords(source.words)
+		report.Sources = append(report.Sources, PassageSource{
+			FileID:         source.fileID,
+			PassageCount:   len(source.passages),
+			MatchedWords:   matched,
+			MatchedPercent: percentOf(matched, report.WordCount),
+		})
+	}
+	sort.SliceStable(report.Sources, func(i, j int) bool {
+		if report.Sources[i].MatchedWords != report.Sources[j].MatchedWords {
+			return report.Sources[i].MatchedWords > report.Sources[j].MatchedWords
+		}
+		return report.Sources[i].FileID < report.Sources[j].FileID
+	})
+	index := make(map[string]int, len(report.Sources))
+	for i, source := range report.Sources {
+		index[source.FileID] = i
+	}
+	for _, source := range sources {
+		for _, passage := range source.passages {
+			passage.Source = index[source.fileID]
+			report.Passages = append(report.Passages, passage)
+		}
+	}
+	report.PassageCount = len(report.Passages)
+
+	// Оставляем limit самых длинных фрагментов и упорядочиваем их по положению в тексте
+	if len(report.Passages) > limit {
+		sort.SliceStable(report.Passages, func(i, j int) bool { return report.Passages[i].Length > report.Passages[j].Length })
+		report.Passages = report.Passages[:limit]
+	}
+	sort.SliceStable(report.Passages, func(i, j int) bool {
+		a, b := report.Passages[i], report.Passages[j]
+		if a.Start != b.Start {
+			return a.Start < b.Start
+		}
+		if a.Length != b.Length {
+			return a.Length > b.Length
+		}
+		return a.Source < b.Source
+	})
+	return report, nil
+}
+
+// findFingerprintPairs ищет отпечатки suspect в других файлах и возвращает общие отпечатки, сгруппированные по файлу-источнику.
+// Отпечатки, встречающиеся в других файлах чаще maxHashOccurrences раз, пропускаются.
+func (s *AnalysisService) findFingerprintPairs(fileID string, suspect []models.FileFingerprint) (map[string][]fingerprintPair, error) {
+	byHash := make(map[int64][]models.FileFingerprint)
+	hashes := make([]int64, 0, len(suspect))
+	for _, fp := range suspect {
+		if _, ok := byHash[fp.Hash]; !ok {
+			hashes = append(hashes, fp.Hash)
+		}
+		byHash[fp.Hash] = append(byHash[fp.Hash], fp)
+	}
+
+	pairs := make(map[string][]fingerprintPair)
+	for start := 0; start < len(hashes); start += fingerprintQueryBatch {
+		end := start + fingerprintQueryBatch
+		if end > len(hashes) {
+			end = len(hashes)
+		}
+		batch := hashes[start:end]
+
+		query, err := s.fingerprintMatches(fileID, batch)
+		if err != nil {
+			return nil, err
+		}
+		var matches []models.FileFingerprint
+		if err := query.Find(&matches).Error; err != nil {
+			return nil, fmt.Errorf("не удалось найти отпечатки файла %s в других файлах: %w", fileID, err)
+		}
+		for _, match := range matches {
+			for _, fp := range byHash[match.Hash] {
+				pairs[match.FileID] = append(pairs[match.FileID], fingerprintPair{suspect: fp, source: match})
+			}
+		}
+	}
+	return pairs, nil
+}
+
+// fingerprintMatches возвращает запрос отпечатков других файлов с хешами из hashes. Отпечатки, встречающиеся
+// в других файлах чаще maxHashOccurrences раз, считаются общими фразами и в запрос не попадают.
+func (s *AnalysisService) fingerprintMatches(fileID string, hashes []int64) (*gorm.DB, error) {
+	var common []int64
+	err := s.DBAdapter.DB.Model(&models.FileFingerprint{}).
+		Where("hash IN ? AND file_id <> ?", hashes, fileID).
+		Group("hash").Having("COUNT(*) > ?", maxHashOccurrences).
+		Pluck("hash", &common).Error
+	if err != nil {
+		return nil, fmt.Errorf("не удалось найти общие отпечатки файла %s: %w", fileID, err)
+	}
+	query := s.DBAdapter.DB.Model(&models.FileFingerprint{}).Where("hash IN ? AND file_id <> ?", hashes, fileID)
+	if len(common) > 0 {
+		query = query.Where("hash NOT IN ?", common)
+	}
+	return query, nil
+}
+
+// mergePairs объединяет общие отпечатки с одним источником во фрагменты. Отпечатки относятся к одному фрагменту,
+// если сдвиг между их положениями в файлах одинаков (текст скопирован подряд), а между соседними отпечатками
+// не больше winnowingWindow слов: на непрерывном совпадении winnowing выбирает отпечаток в каждом окне.
+// Возвращает фрагменты не короче minWords слов и интервалы номеров слов проверяемого файла, которые они занимают.
+func mergePairs(sourceID string, pairs []fingerprintPair, minWords int) ([]Passage, [][2]int) {
+	sort.Slice(pairs, func(i, j int) bool {
+		di := pairs[i].source.Position - pairs[i].suspect.Position
+		dj := pairs[j].source.Position - pairs[j].suspect.Position
+		if di != dj {
+			return di < dj
+		}
+		return pairs[i].suspect.Position < pairs[j].suspect.Position
+	})
+
+	var passages []Passage
+	var words [][2]int
+	flush := func(first, last fingerprintPair, end, sourceEnd int) {
+		length := last.suspect.Position + fingerprintSize - first.suspect.Position
+		if length < minWords {
+			return
+		}
+		passages = append(passages, Passage{
+			SourceFileID: sourceID,
+			Start:        first.suspect.Start,
+			End:          end,
+			SourceStart:  first.source.Start,
+			SourceEnd:    sourceEnd,
+			Length:       length,
+			Description:  fmt.Sprintf("%d %s из %s", length, wordsNoun(length), sourceID),
+		})
+		words = append(words, [2]int{first.suspect.Position, last.suspect.Position + fingerprintSize})
+	}
+
+	for i := 0; i < len(pairs); {
+		first, last := pairs[i], pairs[i]
+		end, sourceEnd := first.suspect.End, first.source.End
+		j := i + 1
+		for ; j < len(pairs); j++ {
+			next := pairs[j]
+			if next.source.Position-next.suspect.Position != last.source.Position-last.suspect.Position ||
+				next.suspect.Position-last.suspect.Position > winnowingWindow {
+				break
+			}
+			last = next
+			if next.suspect.End > end {
+				end = next.suspect.End
+			}
+			if next.source.End > sourceEnd {
+				sourceEnd = next.source.End
+			}
+		}
+		flush(first, last, end, sourceEnd)
+		i = j
+	}
+	return passages, words
+}
+
+// coveredWords возвращает количество слов, входящих хотя бы в один из интервалов [начало, конец).
+func coveredWords(intervals [][2]int) int {
+	sort.Slice(intervals, func(i, j int) bool { return intervals[i][0] < intervals[j][0] })
+	covered, end := 0, math.MinInt
+	for _, interval := range intervals {
+		start := interval[0]
+		if start < end {
+			start = end
+		}
+		if interval[1] > start {
+			covered += interval[1] - start
+			end = interval[1]
+		}
+	}
+	return covered
+}
+
+func percentOf(part, total int) int {
+	if total == 0 {
+		return 0
+	}
+	percent := int(math.Round(float64(part) * 100 / float64(total)))
+	if percent > 100 {
+		percent = 100
+	}
+	return percent
+}
+
+// wordsNoun возвращает слово «слово» в форме, согласованной с числом n.
+func wordsNoun(n int) string {
+	switch {
+	case n%100 >= 11 && n%100 <= 14:
+		return "слов"
+	case n%10 == 1:
+		return "слово"
+	case n%10 >= 2 && n%10 <= 4:
+		return "слова"
+	}
+	return "слов"
+}
+
+// ReadFileText читает из File Storing Service не больше maxRunes символов извлеченного текста файла.
+// Второе значение сообщает, что текст длиннее и прочитано только его начало.
+func (s *AnalysisService) ReadFileText(fileID string, maxRunes int) (string, bool, error) {
+	content, err := s.FileStoringServiceAdapter.OpenFileContent(fileID)
+	if err != nil {
+		return "", false, fmt.Errorf("не удалось получить содержимое файла %s из FileStoringService: %w", fileID, err)
+	}
+	defer content.Close()
+
+	reader := bufio.NewReader(content)
+	var text strings.Builder
+	for runes := 0; runes < maxRunes; runes++ {
+		r, _, err := reader.ReadRune()
+		if err == io.EOF {
+			return text.String(), false, nil
+		}
+		if err != nil {
+			return "", false, fmt.Errorf("не удалось прочитать содержимое файла %s из FileStoringService: %w", fileID, err)
+		}
+		text.WriteRune(r)
+	}
+	_, _, err = reader.ReadRune()
+	return text.String(), err != io.EOF, nil
+}
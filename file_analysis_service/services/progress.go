@@ -0,0 +1,133 @@
+package services
+
+import (
+	"file_analysis_service/models"
+	"sync"
+	"time"
+)
+
+// Этапы анализа файла, о которых сообщают события прогресса.
+const (
+	ProgressQueued    = "queued"    // Задача анализа ожидает выполнения
+	ProgressStarted   = "started"   // Воркер взял задачу анализа в работу
+	ProgressFetched   = "fetched"   // File Storing Service начал передавать текст файла
+	ProgressCounted   = "counted"   // Статистика, частоты слов, сигнатура и отпечатки вычислены
+	ProgressWordCloud = "wordcloud" // Облако слов построено и сохранено (или его не удалось построить)
+	ProgressSaved     = "saved"     // Результат анализа сохранен; последнее событие задачи
+	ProgressRetrying  = "retrying"  // Попытка анализа не удалась, задача будет повторена
+	ProgressFailed    = "failed"    // Все попытки анализа исчерпаны; последнее событие задачи
+	ProgressCancelled = "cancelled" // Файл удален во время анализа; последнее событие задачи
+)
+
+// progressBuffer — сколько событий может ждать отправки одному подписчику. События для подписчика,
+// который не успевает их получать, отбрасываются, чтобы он не задерживал анализ.
+const progressBuffer = 32
+
+// ProgressEvent — событие о ходе анализа файла.
+// @Description Этап анализа файла: время и данные, зависящие от этапа.
+// @Name ProgressEvent
+type ProgressEvent struct {
+	Stage       string     `json:"stage" example:"counted"` // queued, started, fetched, counted, wordcloud, saved, retrying, failed или cancelled
+	FileID      string     `json:"file_id" example:"unique-file-id"`
+	JobID       uint       `json:"job_id,omitempty" example:"1"` // ID задачи анализа (для queued, started, retrying, failed и cancelled)
+	Time        time.Time  `json:"time" swaggertype:"string" format:"date-time"`
+	Attempt     int        `json:"attempt,omitempty" example:"1"`                                            // Номер попытки анализа
+	WordCount   int        `json:"word_count,omitempty" example:"250"`                                       // Количество слов текста (для counted)
+	ResultID    uint       `json:"result_id,omitempty" example:"1"`                                          // ID результата анализа (для saved)
+	Cached      bool       `json:"cached,omitempty" example:"false"`                                         // Возвращен ранее сохраненный результат текущей версии (для saved)
+	Location    string     `json:"word_cloud_location,omitempty" example:"unique-file-id_wordcloud.png"`     // Ключ облака слов; пустой, если его не удалось построить (для wordcloud)
+	SVGLocation string     `json:"word_cloud_svg_location,omitempty" example:"unique-file-id_wordcloud.svg"` // Ключ облака слов в SVG (для wordcloud)
+	Error       string     `json:"error,omitempty"`                                                          // Ошибка попытки (для retrying, failed и cancelled)
+	NextRunAt   *time.Time `json:"next_run_at,omitempty" swaggertype:"string" format:"date-time"`            // Время следующей попытки (для retrying)
+}
+
+// Final сообщает, является ли событие последним событием задачи анализа.
+func (e ProgressEvent) Final() bool {
+	return e.Stage == ProgressSaved || e.Stage == ProgressFailed || e.Stage == ProgressCancelled
+}
+
+// JobProgress возвращает событие, соответствующее сохраненному состоянию задачи job: с него начинается поток событий
+// подписчика, который подключился, когда задача уже выполнялась или завершилась.
+func JobProgress(job *models.AnalysisJob) ProgressEvent {
+	event := ProgressEvent{FileID: job.FileID, JobID: job.ID, Time: job.UpdatedAt, Attempt: job.Attempts}
+	switch job.Status {
+	case models.JobStatusSucceeded:
+		event.Stage = ProgressSaved
+		if job.ResultID != nil {
+			event.ResultID = *job.ResultID
+		}
+	case models.JobStatusFailed:
+		event.Stage = ProgressFailed
+		event.Error = job.LastError
+	case models.JobStatusCancelled:
+		event.Stage = ProgressCancelled
+		event.Error = job.LastError
+	case models.JobStatusRunning:
+		event.Stage = ProgressStarted
+	default:
+		event.Stage = ProgressQueued
+		if job.Attempts > 0 {
+			// Задача ожидает повторной попытки после неудачной
+			event.Stage = ProgressRetrying
+			event.Error = job.LastError
+			nextRunAt := job.NextRunAt
+			event.NextRunAt = &nextRunAt
+		}
+	}
+	return event
+}
+
+// ProgressBroker передает события о ходе анализа подписчикам внутри процесса.
+// @Summary Рассылка событий прогресса анализа
+// @Description Подписчики получают события о файле, на который подписались. События не сохраняются:
+// @Description подписчик получает только события, опубликованные после подписки.
+// @Tags services
+type ProgressBroker struct {
+	mu          sync.Mutex
+	subscribers map[string]map[chan ProgressEvent]struct{}
+}
+
+// NewProgressBroker создает брокер без подписчиков.
+func NewProgressBroker() *ProgressBroker {
+	return &ProgressBroker{subscribers: make(map[string]map[chan ProgressEvent]struct{})}
+}
+
+// Subscribe подписывает на события о файле fileID. Возвращает канал событий и функцию отмены подписки,
+// которую нужно вызвать, когда события больше не нужны; после нее канал закрывается.
+func (b *ProgressBroker) Subscribe(fileID string) (<-chan ProgressEvent, func()) {
+	ch := make(chan ProgressEvent, progressBuffer)
+	b.mu.Lock()
+	if b.subscribers[fileID] == nil {
+		b.subscribers[fileID] = make(map[chan ProgressEvent]struct{})
+	}
+	b.subscribers[fileID][ch] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subscribers[fileID], ch)
+			if len(b.subscribers[fileID]) == 0 {
+				delete(b.subscribers, fileID)
+			}
+			b.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+// Publish передает событие подписчикам его файла, не дожидаясь их.
+func (b *ProgressBroker) Publish(event ProgressEvent) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subscribers[event.FileID] {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
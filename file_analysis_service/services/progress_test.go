@@ -0,0 +1,220 @@
+package services
+
+import (
+	"file_analysis_service/models"
+	"net/http"
+	"pkg/adapters"
+	"pkg/wordcloud"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// ownedFileStoring отдает текст файла и его владельца и принимает отметку об анализе.
+var ownedFileStoring = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.URL.Path == "/api/v1/internal/files/owners":
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"owners":{"f1":"user1"}}`))
+	case r.Method == http.MethodPut:
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.Write([]byte("Облако слов строится по словам текста. Текст анализируется один раз, слова текста считаются."))
+	}
+})
+
+// receiveEvents получает события из events, пока не придет последнее событие задачи.
+func receiveEvents(t *testing.T, events <-chan ProgressEvent) []ProgressEvent {
+	t.Helper()
+	var received []ProgressEvent
+	for {
+		select {
+		case event := <-events:
+			received = append(received, event)
+			if event.Final() {
+				return received
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("нет последнего события задачи, получены %+v", received)
+		}
+	}
+}
+
+func TestProgressEventsOfAnalysisJob(t *testing.T) {
+	queue, _ := newTestQueue(t, ownedFileStoring)
+	storage, err := adapters.NewFileStorageAdapter(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	queue.AnalysisService.FileStorage = storage
+	queue.AnalysisService.WordCloudGenerator = adapters.NewLocalWordCloudAdapter(wordcloud.DefaultOptions())
+	queue.AnalysisService.ShingleSize = DefaultShingleSize
+
+	job, err := queue.Enqueue("f1", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	events, unsubscribe := queue.AnalysisService.Progress.Subscribe("f1")
+	defer unsubscribe()
+	// Подписчик другого файла событий анализа f1 не получает
+	otherEvents, unsubscribeOther := queue.AnalysisService.Progress.Subscribe("f2")
+	defer unsubscribeOther()
+
+	claimed, err := queue.claimNext()
+	if err != nil || claimed == nil {
+		t.Fatalf("задача не взята: %+v, %v", claimed, err)
+	}
+	queue.process(claimed)
+
+	received := receiveEvents(t, events)
+	var stages []string
+	for _, event := range received {
+		stages = append(stages, event.Stage)
+		if event.FileID != "f1" || event.Time.IsZero() {
+			t.Fatalf("событие %+v без файла или времени", event)
+		}
+	}
+	want := []string{ProgressStarted, ProgressFetched, ProgressCounted, ProgressWordCloud, ProgressSaved}
+	if !reflect.DeepEqual(stages, want) {
+		t.Fatalf("этапы %v, ожидалось %v", stages, want)
+	}
+	if started := received[0]; started.JobID != job.ID || started.Attempt != 1 {
+		t.Fatalf("событие started: %+v", started)
+	}
+	if counted := received[2]; counted.WordCount == 0 {
+		t.Fatalf("событие counted без количества слов: %+v", counted)
+	}
+	if cloud := received[3]; cloud.Location == "" || cloud.SVGLocation == "" {
+		t.Fatalf("событие wordcloud без облака слов: %+v", cloud)
+	}
+	var stored models.AnalysisJob
+	queue.DBAdapter.DB.First(&stored, job.ID)
+	if saved := received[4]; saved.ResultID == 0 || stored.ResultID == nil || saved.ResultID != *stored.ResultID {
+		t.Fatalf("событие saved: %+v, результат задачи %v", saved, stored.ResultID)
+	}
+	// Событие сохраненного состояния задачи совпадает с последним событием
+	if event := JobProgress(&stored); event.Stage != ProgressSaved || !event.Final() || event.ResultID != received[4].ResultID {
+		t.Fatalf("состояние завершенной задачи: %+v", event)
+	}
+	// Только последнее событие завершает поток
+	for _, event := range received[:len(received)-1] {
+		if event.Final() {
+			t.Fatalf("промежуточное событие %s считается последним", event.Stage)
+		}
+	}
+
+	select {
+	case event := <-otherEvents:
+		t.Fatalf("подписчик другого файла получил событие %+v", event)
+	case event := <-events:
+		t.Fatalf("событие после последнего: %+v", event)
+	default:
+	}
+}
+
+func TestProgressBrokerDoesNotWaitForSlowSubscriber(t *testing.T) {
+	broker := NewProgressBroker()
+	// Медленный подписчик не читает события
+	slow, unsubscribeSlow := broker.Subscribe("f1")
+	defer unsubscribeSlow()
+	fast, unsubscribeFast := broker.Subscribe("f1")
+	defer unsubscribeFast()
+
+	const count = progressBuffer * 3
+	received := make(chan int)
+	go func() {
+		n := 0
+		for event := range fast {
+			if event.Attempt != n {
+				t.Errorf("событие %d получено под номером %d", event.Attempt, n)
+			}
+			n++
+			if n == count {
+				break
+			}
+		}
+		received <- n
+	}()
+
+	published := make(chan struct{})
+	go func() {
+		for i := 0; i < count; i++ {
+			broker.Publish(ProgressEvent{Stage: ProgressCounted, FileID: "f1", Attempt: i})
+			// Быстрый подписчик успевает получать события
+			for len(fast) > progressBuffer/2 {
+				time.Sleep(time.Millisecond)
+			}
+		}
+		close(published)
+	}()
+	select {
+	case <-published:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Publish ждет медленного подписчика")
+	}
+	if n := <-received; n != count {
+		t.Fatalf("быстрый подписчик получил %d событий, ожидалось %d", n, count)
+	}
+
+	// Медленный подписчик получает первые события, остальные для него отброшены
+	if len(slow) != progressBuffer {
+		t.Fatalf("в очереди медленного подписчика %d событий, ожидалось %d", len(slow), progressBuffer)
+	}
+	if event := <-slow; event.Attempt != 0 {
+		t.Fatalf("первое событие медленного подписчика: %+v", event)
+	}
+}
+
+func TestProgressBrokerUnsubscribe(t *testing.T) {
+	broker := NewProgressBroker()
+	events, unsubscribe := broker.Subscribe("f1")
+	broker.Publish(ProgressEvent{Stage: ProgressFetched, FileID: "f1"})
+	unsubscribe()
+	// Повторная отмена подписки и публикация после нее безопасны
+	unsubscribe()
+	broker.Publish(ProgressEvent{Stage: ProgressCounted, FileID: "f1"})
+
+	// Отмена подписки закрывает канал; событие, опубликованное до нее, еще можно получить
+	if event, ok := <-events; !ok || event.Stage != ProgressFetched {
+		t.Fatalf("событие до отмены подписки: %+v, %v", event, ok)
+	}
+	if event, ok := <-events; ok {
+		t.Fatalf("событие после отмены подписки: %+v", event)
+	}
+	if len(broker.subscribers) != 0 {
+		t.Fatalf("подписчики после отмены подписки: %v", broker.subscribers)
+	}
+}
+
+func TestJobProgress(t *testing.T) {
+	resultID := uint(7)
+	nextRunAt := time.Now().Add(time.Minute)
+	tests := []struct {
+		job   models.AnalysisJob
+		stage string
+		final bool
+	}{
+		{models.AnalysisJob{Status: models.JobStatusPending}, ProgressQueued, false},
+		{models.AnalysisJob{Status: models.JobStatusPending, Attempts: 1, LastError: "ошибка", NextRunAt: nextRunAt}, ProgressRetrying, false},
+		{models.AnalysisJob{Status: models.JobStatusRunning, Attempts: 1}, ProgressStarted, false},
+		{models.AnalysisJob{Status: models.JobStatusSucceeded, ResultID: &resultID}, ProgressSaved, true},
+		{models.AnalysisJob{Status: models.JobStatusFailed, LastError: "ошибка"}, ProgressFailed, true},
+		{models.AnalysisJob{Status: models.JobStatusCancelled, LastError: "ошибка"}, ProgressCancelled, true},
+	}
+	for _, tt := range tests {
+		tt.job.FileID = "f1"
+		event := JobProgress(&tt.job)
+		if event.Stage != tt.stage || event.Final() != tt.final || event.FileID != "f1" {
+			t.Errorf("задача %s (попыток %d): событие %+v, ожидался этап %s", tt.job.Status, tt.job.Attempts, event, tt.stage)
+		}
+		if tt.job.LastError != "" && event.Error != tt.job.LastError {
+			t.Errorf("задача %s: ошибка %q", tt.job.Status, event.Error)
+		}
+	}
+	if event := JobProgress(&tests[1].job); event.NextRunAt == nil || !event.NextRunAt.Equal(nextRunAt) {
+		t.Errorf("время повтора задачи: %v", event.NextRunAt)
+	}
+	if event := JobProgress(&tests[3].job); event.ResultID != resultID {
+		t.Errorf("результат завершенной задачи: %d", event.ResultID)
+	}
+}
@@ -0,0 +1,237 @@
+package services
+
+import (
+	"encoding/binary"
+	"errors"
+	"file_analysis_service/models"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// DefaultShingleSize — количество слов в одном шингле.
+	DefaultShingleSize = 3
+	// DefaultSimilarityTopN — сколько наиболее похожих файлов сохраняется для каждого файла.
+	DefaultSimilarityTopN = 5
+	// minHashSize — количество хеш-функций (длина MinHash-сигнатуры).
+	minHashSize = 128
+)
+
+// minHashSeeds — фиксированные зерна хеш-функций. Они не должны меняться,
+// иначе ранее сохраненные сигнатуры станут несравнимы с новыми.
+var minHashSeeds = func() []uint64 {
+	seeds := make([]uint64, minHashSize)
+	state := uint64(0x5eed5eed5eed5eed)
+	for i := range seeds {
+		state = splitMix64(state)
+		seeds[i] = state
+	}
+	return seeds
+}()
+
+// SimilarFile описывает файл, похожий на запрошенный.
+// @Description Похожий файл и оценка сходства с ним.
+// @Name SimilarFile
+type SimilarFile struct {
+	FileID      string  `json:"file_id" example:"other-file-id"`
+	Score       float64 `json:"score" example:"0.87"`
+	Percent     int     `json:"percent" example:"87"`
+	Description string  `json:"description" example:"Файл unique-file-id похож на файл other-file-id на 87%"`
+}
+
+// SimilarityReport содержит список файлов, наиболее похожих на запрошенный.
+// @Description Результат анализа сходства файла с ранее проанализированными файлами.
+// @Name SimilarityReport
+type SimilarityReport struct {
+	FileID  string        `json:"file_id" example:"unique-file-id"`
+	Matches []SimilarFile `json:"matches"`
+}
+
+// HideFiles скрывает ID похожих файлов, для которых visible возвращает false:
+// оценка сходства остается, а файл описывается как файл другого пользователя.
+func (r *SimilarityReport) HideFiles(visible func(fileID string) bool) {
+	for i, match := range r.Matches {
+		if visible(match.FileID) {
+			continue
+		}
+		r.Matches[i].FileID = ""
+		r.Matches[i].Description = fmt.Sprintf("Файл %s похож на файл другого пользователя на %d%%", r.FileID, match.Percent)
+	}
+}
+
+// shingleHash возвращает хеш шингла — последовательности подряд идущих слов.
+func shingleHash(words []string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(strings.Join(words, " ")))
+	return h.Sum64()
+}
+
+// newMinHashSignature возвращает MinHash-сигнатуру пустого множества шинглов.
+func newMinHashSignature() []uint64 {
+	signature := make([]uint64, minHashSize)
+	for i := range signature {
+		signature[i] = math.MaxUint64
+	}
+	return signature
+}
+
+// updateMinHash добавляет шингл в MinHash-сигнатуру. Повторное добавление шингла сигнатуру не меняет.
+func updateMinHash(signature []uint64, shingle uint64) {
+	for i, seed := range minHashSeeds {
+		if v := splitMix64(shingle ^ seed); v < signature[i] {
+			signature[i] = v
+		}
+	}
+}
+
+// estimateShingleCount оценивает количество уникальных шинглов по MinHash-сигнатуре:
+// минимум n равномерно распределенных значений в среднем равен 1/(n+1) от диапазона.
+func estimateShingleCount(signature []uint64) int {
+	sum := 0.0
+	for _, v := range signature {
+		sum += float64(v) / math.MaxUint64
+	}
+	if sum == 0 {
+		return 0
+	}
+	return int(math.Round(float64(len(signature))/sum - 1))
+}
+
+// estimateJaccard оценивает коэффициент Жаккара как долю совпадающих позиций двух сигнатур.
+func estimateJaccard(a, b []uint64) float64 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0
+	}
+	equal := 0
+	for i := range a {
+		if a[i] == b[i] {
+			equal++
+		}
+	}
+	return float64(equal) / float64(len(a))
+}
+
+func encodeSignature(signature []uint64) []byte {
+	buf := make([]byte, 8*len(signature))
+	for i, v := range signature {
+		binary.LittleEndian.PutUint64(buf[i*8:], v)
+	}
+	return buf
+}
+
+func decodeSignature(data []byte) []uint64 {
+	signature := make([]uint64, len(data)/8)
+	for i := range signature {
+		signature[i] = binary.LittleEndian.Uint64(data[i*8:])
+	}
+	return signature
+}
+
+// splitMix64 — быстрая перемешивающая функция, используемая как семейство хеш-функций MinHash.
+func splitMix64(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
+
+// findSimilarFiles находит кандидатов в похожие файлы по LSH-индексу сигнатур и отпечаткам winnowing,
+// сравнивает сигнатуру файла с их сигнатурами и возвращает topN наиболее похожих из них (с ненулевым сходством).
+func (s *AnalysisService) findSimilarFiles(fileID string, signature []uint64, fingerprints []fingerprint) ([]models.SimilarityMatch, error) {
+	candidates, err := s.candidateFiles(fileID, signature, fingerprints)
+	if err != nil {
+		return nil, err
+	}
+	signatures, err := s.loadSignatures(candidates)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось загрузить сигнатуры файлов: %w", err)
+	}
+
+	matches := make([]models.SimilarityMatch, 0, len(signatures))
+	for otherID, other := range signatures {
+		score := estimateJaccard(signature, other)
+		if score > 0 {
+			matches = append(matches, models.SimilarityMatch{FileID: fileID, MatchedFileID: otherID, Score: score})
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].MatchedFileID < matches[j].MatchedFileID
+	})
+	if len(matches) > s.SimilarityTopN {
+		matches = matches[:s.SimilarityTopN]
+	}
+	return matches, nil
+}
+
+// GetSimilarFiles возвращает файлы, наиболее похожие на указанный.
+// @Summary Получение похожих файлов
+// @Description Возвращает сохраненные оценки сходства файла с другими файлами в порядке убывания.
+// @Description Учитываются как совпадения, найденные при последнем анализе этого файла, так и совпадения, найденные при анализе более поздних файлов.
+// @Param fileID path string true "ID файла"
+// @Return *SimilarityReport, error "Список похожих файлов и ошибка, если есть (например, если файл не анализировался)"
+func (s *AnalysisService) GetSimilarFiles(fileID string) (*SimilarityReport, error) {
+	var signature models.FileSignature
+	if err := s.DBAdapter.First(&signature, "file_id = ?", fileID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("результаты анализа сходства для файла с ID %s не найдены: %w", fileID, err)
+		}
+		return nil, fmt.Errorf("ошибка при поиске сигнатуры файла %s: %w", fileID, err)
+	}
+
+	latest, err := s.GetAnalysisResult(fileID, 0)
+	if err != nil {
+		return nil, err
+	}
+	// Совпадения предыдущих анализов файла не учитываются. Результаты, полученные до появления
+	// истории анализов (без версии), единственные у файла, а их совпадения сохранены без ID результата
+	latestID := latest.ID
+	if latest.AnalyzerVersion == "" {
+		latestID = 0
+	}
+
+	var stored []models.SimilarityMatch
+	if err := s.DBAdapter.Find(&stored, "(file_id = ? AND analysis_result_id = ?) OR matched_file_id = ?", fileID, latestID, fileID); err != nil {
+		return nil, fmt.Errorf("не удалось получить оценки сходства для файла %s: %w", fileID, err)
+	}
+
+	// Одна и та же пара может встретиться в обоих направлениях — оставляем максимальную оценку
+	best := make(map[string]float64)
+	for _, m := range stored {
+		other := m.MatchedFileID
+		if other == fileID {
+			other = m.FileID
+		}
+		if m.Score > best[other] {
+			best[other] = m.Score
+		}
+	}
+
+	report := &SimilarityReport{FileID: fileID, Matches: make([]SimilarFile, 0, len(best))}
+	for other, score := range best {
+		percent := int(math.Round(score * 100))
+		report.Matches = append(report.Matches, SimilarFile{
+			FileID:      other,
+			Score:       score,
+			Percent:     percent,
+			Description: fmt.Sprintf("Файл %s похож на файл %s на %d%%", fileID, other, percent),
+		})
+	}
+	sort.Slice(report.Matches, func(i, j int) bool {
+		if report.Matches[i].Score != report.Matches[j].Score {
+			return report.Matches[i].Score > report.Matches[j].Score
+		}
+		return report.Matches[i].FileID < report.Matches[j].FileID
+	})
+	if len(report.Matches) > s.SimilarityTopN {
+		report.Matches = report.Matches[:s.SimilarityTopN]
+	}
+	return report, nil
+}
@@ -0,0 +1,214 @@
+package services
+
+import (
+	"context"
+	"file_analysis_service/models"
+	"fmt"
+	"log"
+	"pkg/adapters"
+	"sort"
+)
+
+const (
+	// lshBands и lshRows — разбиение MinHash-сигнатуры на полосы для LSH-индекса: lshBands полос по lshRows значений.
+	// Файлы с коэффициентом Жаккара J становятся кандидатами с вероятностью 1-(1-J^lshRows)^lshBands:
+	// около 0,3 при J=0,2, 0,7 при J=0,3 и больше 0,99 при J≥0,5. Изменение параметров требует перестроения индекса.
+	lshBands = 42
+	lshRows  = 3
+	// minSharedFingerprints — сколько общих отпечатков winnowing делает файл кандидатом в похожие
+	// даже без общей полосы сигнатуры: так находятся файлы, в которые скопирована только часть текста.
+	minSharedFingerprints = 2
+	// signatureQueryBatch — сколько сигнатур запрашивается из БД одним запросом.
+	signatureQueryBatch = 1000
+	// indexRebuildBatch — сколько сигнатур обрабатывается за одну транзакцию при перестроении индекса.
+	indexRebuildBatch = 500
+)
+
+// signatureBands возвращает записи LSH-индекса для MinHash-сигнатуры файла: хеш каждой полосы из lshRows значений.
+func signatureBands(fileID string, signature []uint64) []models.SignatureBand {
+	bands := make([]models.SignatureBand, 0, lshBands)
+	for band := 0; band < lshBands && (band+1)*lshRows <= len(signature); band++ {
+		h := uint64(band)
+		for _, v := range signature[band*lshRows : (band+1)*lshRows] {
+			h = splitMix64(h ^ v)
+		}
+		bands = append(bands, models.SignatureBand{Band: band, Hash: int64(h), FileID: fileID})
+	}
+	return bands
+}
+
+// candidateFiles возвращает файлы, которые могут быть похожи на файл fileID: файлы с общей полосой MinHash-сигнатуры
+// и файлы, у которых с ним не меньше minSharedFingerprints общих отпечатков winnowing (без общих фраз).
+// Оба поиска выполняются по индексам БД, поэтому время не зависит от количества несхожих файлов.
+func (s *AnalysisService) candidateFiles(fileID string, signature []uint64, fingerprints []fingerprint) ([]string, error) {
+	candidates := make(map[string]struct{})
+
+	bands := signatureBands(fileID, signature)
+	if len(bands) > 0 {
+		keys := make([][]interface{}, len(bands))
+		for i, band := range bands {
+			keys[i] = []interface{}{band.Band, band.Hash}
+		}
+		var fileIDs []string
+		err := s.DBAdapter.DB.Model(&models.SignatureBand{}).
+			Where("(band, hash) IN ? AND file_id <> ?", keys, fileID).
+			Distinct().Pluck("file_id", &fileIDs).Error
+		if err != nil {
+			return nil, fmt.Errorf("не удалось найти файлы с общими полосами сигнатуры: %w", err)
+		}
+		for _, id := range fileIDs {
+			candidates[id] = struct{}{}
+		}
+	}
+
+	seen := make(map[int64]struct{}, len(fingerprints))
+	hashes := make([]int64, 0, len(fingerprints))
+	for _, fp := range fingerprints {
+		if _, ok := seen[int64(fp.Hash)]; !ok {
+			seen[int64(fp.Hash)] = struct{}{}
+			hashes = append(hashes, int64(fp.Hash))
+		}
+	}
+	shared := make(map[string]int)
+	for start := 0; start < len(hashes); start += fingerprintQueryBatch {
+		end := start + fingerprintQueryBatch
+		if end > len(hashes) {
+			end = len(hashes)
+		}
+		query, err := s.fingerprintMatches(fileID, hashes[start:end])
+		if err != nil {
+			return nil, err
+		}
+		var counts []struct {
+			FileID string
+			Count  int
+		}
+		if err := query.Select("file_id, COUNT(DISTINCT hash) AS count").Group("file_id").Scan(&counts).Error; err != nil {
+			return nil, fmt.Errorf("не удалось найти файлы с общими отпечатками: %w", err)
+		}
+		for _, c := range counts {
+			shared[c.FileID] += c.Count
+		}
+	}
+	for id, count := range shared {
+		if count >= minSharedFingerprints {
+			candidates[id] = struct{}{}
+		}
+	}
+
+	result := make([]string, 0, len(candidates))
+	for id := range candidates {
+		result = append(result, id)
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// loadSignatures загружает MinHash-сигнатуры файлов fileIDs. Файлов без сигнатуры (не анализировавшихся) в результате нет.
+func (s *AnalysisService) loadSignatures(fileIDs []string) (map[string][]uint64, error) {
+	signatures := make(map[string][]uint64, len(fileIDs))
+	for start := 0; start < len(fileIDs); start += signatureQueryBatch {
+		end := start + signatureQueryBatch
+		if end > len(fileIDs) {
+			end = len(fileIDs)
+		}
+		var stored []models.FileSignature
+		if err := s.DBAdapter.Find(&stored, "file_id IN ?", fileIDs[start:end]); err != nil {
+			return nil, err
+		}
+		for _, signature := range stored {
+			signatures[signature.FileID] = decodeSignature(signature.Signature)
+		}
+	}
+	return signatures, nil
+}
+
+// IndexRebuildStats — итог перестроения индекса сходства.
+type IndexRebuildStats struct {
+	Signatures          int // Количество файлов, полосы сигнатур которых записаны в индекс
+	Bands               int // Количество записей индекса
+	Orphans             int // Количество удаленных записей файлов без сигнатуры
+	WithoutFingerprints int // Количество файлов с сигнатурой, но без отпечатков winnowing (проанализированных старой версией)
+}
+
+// RebuildSimilarityIndex перестраивает LSH-индекс по сохраненным MinHash-сигнатурам всех проанализированных файлов
+// и удаляет записи индекса файлов, у которых сигнатуры больше нет. Файлы заново не анализируются.
+// @Summary Перестроение индекса сходства
+// @Description Пересчитывает полосы сигнатур из таблицы file_signatures порциями по indexRebuildBatch файлов, каждая в своей транзакции,
+// @Description поэтому перестроение можно выполнять на работающем сервисе. Сообщает, у скольких файлов нет отпечатков winnowing.
+// @Return IndexRebuildStats, error "Итог перестроения и ошибка, если есть"
+func (s *AnalysisService) RebuildSimilarityIndex(ctx context.Context) (IndexRebuildStats, error) {
+	var stats IndexRebuildStats
+	lastID := ""
+	for ctx.Err() == nil {
+		var batch []models.FileSignature
+		err := s.DBAdapter.DB.Where("file_id > ?", lastID).Order("file_id").Limit(indexRebuildBatch).Find(&batch).Error
+		if err != nil {
+			return stats, fmt.Errorf("не удалось загрузить сигнатуры файлов: %w", err)
+		}
+		if len(batch) == 0 {
+			break
+		}
+		fileIDs := make([]string, len(batch))
+		var bands []models.SignatureBand
+		for i, signature := range batch {
+			fileIDs[i] = signature.FileID
+			bands = append(bands, signatureBands(signature.FileID, decodeSignature(signature.Signature))...)
+		}
+		err = s.DBAdapter.Transaction(func(tx *adapters.DBAdapter) error {
+			if err := tx.DB.Delete(&models.SignatureBand{}, "file_id IN ?", fileIDs).Error; err != nil {
+				return err
+			}
+			if len(bands) == 0 {
+				return nil
+			}
+			return tx.DB.CreateInBatches(&bands, fingerprintInsertBatch).Error
+		})
+		if err != nil {
+			return stats, fmt.Errorf("не удалось записать полосы сигнатур в индекс: %w", err)
+		}
+
+		var withPrints []string
+		err = s.DBAdapter.DB.Model(&models.FileFingerprint{}).Where("file_id IN ?", fileIDs).Distinct().Pluck("file_id", &withPrints).Error
+		if err != nil {
+			return stats, fmt.Errorf("не удалось проверить отпечатки файлов: %w", err)
+		}
+		stats.Signatures += len(batch)
+		stats.Bands += len(bands)
+		stats.WithoutFingerprints += len(batch) - len(withPrints)
+		lastID = batch[len(batch)-1].FileID
+	}
+	if err := ctx.Err(); err != nil {
+		return stats, err
+	}
+
+	result := s.DBAdapter.DB.Where("NOT EXISTS (SELECT 1 FROM file_signatures fs WHERE fs.file_id = signature_bands.file_id)").
+		Delete(&models.SignatureBand{})
+	if result.Error != nil {
+		return stats, fmt.Errorf("не удалось удалить записи индекса файлов без сигнатуры: %w", result.Error)
+	}
+	stats.Orphans = int(result.RowsAffected)
+	return stats, nil
+}
+
+// EnsureSimilarityIndex перестраивает LSH-индекс, если он пуст, а сигнатуры уже есть: так файлы, проанализированные
+// до появления индекса, участвуют в поиске похожих без ручного перестроения.
+// @Summary Заполнение пустого индекса сходства
+// @Return error
+func (s *AnalysisService) EnsureSimilarityIndex(ctx context.Context) error {
+	var band models.SignatureBand
+	if err := s.DBAdapter.DB.Limit(1).Find(&band).Error; err != nil {
+		return fmt.Errorf("не удалось проверить индекс сходства: %w", err)
+	}
+	if band.FileID != "" {
+		return nil
+	}
+	stats, err := s.RebuildSimilarityIndex(ctx)
+	if err != nil {
+		return err
+	}
+	if stats.Signatures > 0 {
+		log.Printf("Индекс сходства построен по сигнатурам %d файлов", stats.Signatures)
+	}
+	return nil
+}
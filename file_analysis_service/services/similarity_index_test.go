@@ -0,0 +1,199 @@
+package services
+
+import (
+	"context"
+	"file_analysis_service/models"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+// saveAnalysis сохраняет сигнатуру, полосы и отпечатки текста файла так же, как AnalyzeFile.
+func saveAnalysis(t *testing.T, db *gorm.DB, fileID string, text textAnalysis) {
+	t.Helper()
+	saveSignature(t, db, fileID, text.Signature)
+	fingerprints := make([]models.FileFingerprint, len(text.Fingerprints))
+	for i, fp := range text.Fingerprints {
+		fingerprints[i] = models.FileFingerprint{FileID: fileID, Hash: int64(fp.Hash), Position: fp.Position, Start: fp.Start, End: fp.End}
+	}
+	if len(fingerprints) > 0 {
+		if err := db.Create(&fingerprints).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestSignatureBands(t *testing.T) {
+	signature := testSignature(0, 1000)
+	bands := signatureBands("f1", signature)
+	if len(bands) != lshBands || lshBands*lshRows > minHashSize {
+		t.Fatalf("полос %d, ожидалось %d по %d значений", len(bands), lshBands, lshRows)
+	}
+	for i, band := range bands {
+		if band.Band != i || band.FileID != "f1" {
+			t.Fatalf("полоса %d: %+v", i, band)
+		}
+	}
+
+	// Одинаковые сигнатуры разных файлов дают одинаковые хеши полос
+	other := signatureBands("f2", testSignature(0, 1000))
+	for i := range bands {
+		if other[i].Hash != bands[i].Hash {
+			t.Fatalf("полоса %d одинаковых сигнатур различается", i)
+		}
+	}
+
+	// Значение сигнатуры влияет только на свою полосу
+	changed := append([]uint64(nil), signature...)
+	changed[4]++
+	for i, band := range signatureBands("f1", changed) {
+		if differs := band.Hash != bands[i].Hash; differs != (i == 4/lshRows) {
+			t.Fatalf("изменение значения 4 затронуло полосу %d", i)
+		}
+	}
+	// Одинаковые значения в разных полосах дают разные хеши: номер полосы входит в хеш
+	same := make([]uint64, minHashSize)
+	sameBands := signatureBands("f1", same)
+	if sameBands[0].Hash == sameBands[1].Hash {
+		t.Fatal("полосы с одинаковыми значениями имеют одинаковый хеш")
+	}
+
+	// Значения за пределами lshBands полос и неполная последняя полоса не учитываются
+	if got := len(signatureBands("f1", signature[:10])); got != 3 {
+		t.Fatalf("полос для сигнатуры из 10 значений: %d, ожидалось 3", got)
+	}
+	if got := signatureBands("f1", nil); len(got) != 0 {
+		t.Fatalf("полосы пустой сигнатуры: %v", got)
+	}
+}
+
+// copiedTextPairs возвращает n пар текстов с коэффициентом Жаккара шинглов около DefaultCollectionMinScore:
+// проверяемый текст начинается с 470 слов источника (469 шинглов поровну), остальные слова у текстов свои.
+func copiedTextPairs(n int) (sources, suspects []textAnalysis) {
+	for i := 0; i < n; i++ {
+		source := testWords(fmt.Sprintf("s%d_", i), 1000)
+		sources = append(sources, analyzeWords(source))
+		suspects = append(suspects, analyzeWords(concatWords(source[:470], testWords(fmt.Sprintf("x%d_", i), 530))))
+	}
+	return sources, suspects
+}
+
+func TestCandidateFilesAboveReportingThreshold(t *testing.T) {
+	consumer, db := newTestConsumer(t)
+	service := consumer.AnalysisService
+
+	const pairs = 30
+	sources, suspects := copiedTextPairs(pairs)
+	for i, source := range sources {
+		saveAnalysis(t, db, fmt.Sprintf("source%d", i), source)
+	}
+	saveAnalysis(t, db, "unrelated", analyzeWords(testWords("u", 1000)))
+
+	// Полоса сигнатуры совпадает у такой пары лишь с вероятностью около 0,7, поэтому кандидатом
+	// ее гарантированно делают общие отпечатки winnowing скопированного фрагмента
+	for i, suspect := range suspects {
+		score := estimateJaccard(suspect.Signature, sources[i].Signature)
+		candidates, err := service.candidateFiles(fmt.Sprintf("suspect%d", i), suspect.Signature, suspect.Fingerprints)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := []string{fmt.Sprintf("source%d", i)}; !reflect.DeepEqual(candidates, want) {
+			t.Fatalf("пара %d (оценка %.2f): кандидаты %v, ожидалось %v", i, score, candidates, want)
+		}
+	}
+
+	// Сам файл не является своим кандидатом, даже если его прежний анализ сохранен
+	candidates, err := service.candidateFiles("source0", sources[0].Signature, sources[0].Fingerprints)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(candidates) != 0 {
+		t.Fatalf("кандидаты файла без похожих: %v", candidates)
+	}
+}
+
+func TestRebuildSimilarityIndexMatchesIncremental(t *testing.T) {
+	consumer, db := newTestConsumer(t)
+	service := consumer.AnalysisService
+
+	base := testWords("b", 600)
+	files := map[string]textAnalysis{
+		"copy":    analyzeWords(base),
+		"most":    analyzeWords(concatWords(base[:500], testWords("m", 100))),
+		"half":    analyzeWords(concatWords(testWords("h", 300), base[300:])),
+		"other":   analyzeWords(testWords("o", 600)),
+		"partial": analyzeWords(concatWords(testWords("p", 500), base[:60], testWords("q", 500))),
+	}
+	query := analyzeWords(base)
+	candidates := func() []string {
+		t.Helper()
+		result, err := service.candidateFiles("query", query.Signature, query.Fingerprints)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+	var bands []models.SignatureBand
+	for _, id := range []string{"copy", "half", "most", "other", "partial"} {
+		saveAnalysis(t, db, id, files[id])
+	}
+	// Файл, проанализированный до появления отпечатков winnowing: только сигнатура
+	saveSignature(t, db, "legacy", analyzeWords(concatWords(base[:550], testWords("l", 50))).Signature)
+	db.Order("file_id, band").Find(&bands)
+	incremental := candidates()
+	if want := []string{"copy", "half", "legacy", "most", "partial"}; !reflect.DeepEqual(incremental, want) {
+		t.Fatalf("кандидаты при пополнении индекса: %v, ожидалось %v", incremental, want)
+	}
+
+	// Индекс потерян, а в нем остались записи удаленного файла
+	db.Where("1 = 1").Delete(&models.SignatureBand{})
+	orphans := signatureBands("deleted", query.Signature)
+	db.Create(&orphans)
+
+	stats, err := service.RebuildSimilarityIndex(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := IndexRebuildStats{Signatures: 6, Bands: 6 * lshBands, Orphans: lshBands, WithoutFingerprints: 1}
+	if stats != want {
+		t.Fatalf("итог перестроения %+v, ожидалось %+v", stats, want)
+	}
+	var rebuilt []models.SignatureBand
+	db.Order("file_id, band").Find(&rebuilt)
+	if !reflect.DeepEqual(rebuilt, bands) {
+		t.Fatalf("перестроенный индекс (%d записей) отличается от пополненного (%d записей)", len(rebuilt), len(bands))
+	}
+	if got := candidates(); !reflect.DeepEqual(got, incremental) {
+		t.Fatalf("кандидаты после перестроения: %v, при пополнении индекса: %v", got, incremental)
+	}
+
+	// Повторное перестроение ничего не меняет
+	stats, err = service.RebuildSimilarityIndex(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want.Orphans = 0; stats != want {
+		t.Fatalf("итог повторного перестроения %+v, ожидалось %+v", stats, want)
+	}
+}
+
+func TestEnsureSimilarityIndex(t *testing.T) {
+	consumer, db := newTestConsumer(t)
+	service := consumer.AnalysisService
+
+	if err := service.EnsureSimilarityIndex(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	signature := testSignature(0, 1000)
+	if err := db.Create(&models.FileSignature{FileID: "f1", Signature: encodeSignature(signature)}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := service.EnsureSimilarityIndex(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := countRows(t, db, &models.SignatureBand{}, "file_id = ?", "f1"); got != lshBands {
+		t.Fatalf("записей индекса после заполнения: %d, ожидалось %d", got, lshBands)
+	}
+}
@@ -0,0 +1,178 @@
+package services
+
+import (
+	"file_analysis_service/models"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+// testSignature возвращает MinHash-сигнатуру множества шинглов с номерами от from до to (не включая to).
+func testSignature(from, to int) []uint64 {
+	signature := newMinHashSignature()
+	for i := from; i < to; i++ {
+		updateMinHash(signature, uint64(i))
+	}
+	return signature
+}
+
+// analyzeWords анализирует текст из слов words так же, как AnalyzeFile.
+func analyzeWords(words []string) textAnalysis {
+	analyzer := newTextAnalyzer(DefaultShingleSize)
+	analyzer.Feed(strings.Join(words, " "))
+	return analyzer.Finish()
+}
+
+// saveSignature сохраняет сигнатуру файла и ее полосы в LSH-индексе так же, как AnalyzeFile.
+func saveSignature(t *testing.T, db *gorm.DB, fileID string, signature []uint64) {
+	t.Helper()
+	if err := db.Create(&models.FileSignature{FileID: fileID, Signature: encodeSignature(signature)}).Error; err != nil {
+		t.Fatal(err)
+	}
+	bands := signatureBands(fileID, signature)
+	if err := db.Create(&bands).Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEstimateJaccard(t *testing.T) {
+	tests := []struct {
+		name      string
+		a, b      []uint64
+		want      float64
+		tolerance float64
+	}{
+		{"одинаковые множества", testSignature(0, 1000), testSignature(0, 1000), 1, 0},
+		{"непересекающиеся множества", testSignature(0, 1000), testSignature(1000, 2000), 0, 0.03},
+		{"пересечение 1/3", testSignature(0, 1000), testSignature(500, 1500), 1.0 / 3, 0.12},
+		{"пересечение 1/2", testSignature(0, 1500), testSignature(500, 2000), 0.5, 0.12},
+		{"вложенное множество", testSignature(0, 1000), testSignature(0, 800), 0.8, 0.12},
+		{"пустая сигнатура", nil, nil, 0, 0},
+		{"разная длина", testSignature(0, 10), testSignature(0, 10)[:64], 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := estimateJaccard(tt.a, tt.b)
+			if math.Abs(got-tt.want) > tt.tolerance {
+				t.Fatalf("estimateJaccard = %.3f, ожидалось %.3f ± %.2f", got, tt.want, tt.tolerance)
+			}
+			if reverse := estimateJaccard(tt.b, tt.a); reverse != got {
+				t.Fatalf("оценка несимметрична: %.3f и %.3f", got, reverse)
+			}
+		})
+	}
+}
+
+func TestEstimateJaccardOfTexts(t *testing.T) {
+	base := testWords("a", 600)
+	identical := analyzeWords(base)
+	if got := estimateJaccard(analyzeWords(base).Signature, identical.Signature); got != 1 {
+		t.Fatalf("одинаковые тексты: %.3f, ожидалось 1", got)
+	}
+	if got := estimateJaccard(analyzeWords(testWords("b", 600)).Signature, identical.Signature); got > 0.03 {
+		t.Fatalf("тексты без общих слов: %.3f, ожидалось около 0", got)
+	}
+	// Половина текста скопирована: из 598 шинглов каждого текста общие 298 (шинглы на стыке различаются),
+	// J = 298 / (598 + 598 - 298) ≈ 0,33
+	half := analyzeWords(concatWords(base[:300], testWords("c", 300)))
+	want := 298.0 / (598 + 598 - 298)
+	if got := estimateJaccard(half.Signature, identical.Signature); math.Abs(got-want) > 0.12 {
+		t.Fatalf("половина текста: %.3f, ожидалось %.3f ± 0,12", got, want)
+	}
+}
+
+func TestUpdateMinHash(t *testing.T) {
+	signature := testSignature(0, 100)
+
+	// Повторное добавление шингла и порядок добавления не меняют сигнатуру
+	repeated := testSignature(0, 100)
+	for i := 99; i >= 0; i-- {
+		updateMinHash(repeated, uint64(i))
+	}
+	if !reflect.DeepEqual(repeated, signature) {
+		t.Fatal("повторное добавление шинглов изменило сигнатуру")
+	}
+
+	// Каждое значение сигнатуры — минимум по всем шинглам
+	for i, seed := range minHashSeeds {
+		min := uint64(math.MaxUint64)
+		for shingle := uint64(0); shingle < 100; shingle++ {
+			if v := splitMix64(shingle ^ seed); v < min {
+				min = v
+			}
+		}
+		if signature[i] != min {
+			t.Fatalf("позиция %d: %x, ожидалось %x", i, signature[i], min)
+		}
+	}
+
+	empty := newMinHashSignature()
+	if len(empty) != minHashSize || empty[0] != math.MaxUint64 {
+		t.Fatalf("сигнатура пустого множества: длина %d, первое значение %x", len(empty), empty[0])
+	}
+}
+
+func TestEstimateShingleCount(t *testing.T) {
+	for _, n := range []int{100, 1000, 100000} {
+		got := estimateShingleCount(testSignature(0, n))
+		if math.Abs(float64(got-n)) > 0.25*float64(n) {
+			t.Errorf("%d шинглов: оценка %d", n, got)
+		}
+	}
+}
+
+func TestSignatureEncoding(t *testing.T) {
+	signature := testSignature(0, 100)
+	signature[0], signature[1] = 0, math.MaxUint64
+	encoded := encodeSignature(signature)
+	if len(encoded) != 8*minHashSize {
+		t.Fatalf("длина закодированной сигнатуры %d, ожидалось %d", len(encoded), 8*minHashSize)
+	}
+	if decoded := decodeSignature(encoded); !reflect.DeepEqual(decoded, signature) {
+		t.Fatal("декодированная сигнатура не совпадает с исходной")
+	}
+	if decoded := decodeSignature(nil); len(decoded) != 0 {
+		t.Fatalf("пустые данные декодированы в сигнатуру длины %d", len(decoded))
+	}
+}
+
+func TestFindSimilarFiles(t *testing.T) {
+	consumer, db := newTestConsumer(t)
+	service := consumer.AnalysisService
+	service.SimilarityTopN = 2
+
+	saveSignature(t, db, "copy", testSignature(0, 1000))
+	saveSignature(t, db, "most", testSignature(100, 1100))
+	saveSignature(t, db, "half", testSignature(333, 1333)) // J = 0,5: кандидат в LSH-индексе с вероятностью больше 0,99
+	saveSignature(t, db, "other", testSignature(5000, 6000))
+	// Прежняя сигнатура самого файла не считается похожим файлом
+	saveSignature(t, db, "file", testSignature(0, 1000))
+
+	matches, err := service.findSimilarFiles("file", testSignature(0, 1000), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 2 || matches[0].MatchedFileID != "copy" || matches[0].Score != 1 || matches[1].MatchedFileID != "most" {
+		t.Fatalf("похожие файлы: %+v, ожидались copy (1,0) и most", matches)
+	}
+	if matches[1].Score < 0.7 || matches[1].Score > 0.95 {
+		t.Fatalf("оценка сходства с most: %.3f, ожидалось около 0,82", matches[1].Score)
+	}
+
+	service.SimilarityTopN = 10
+	matches, err = service.findSimilarFiles("file", testSignature(0, 1000), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, match := range matches {
+		if match.MatchedFileID == "other" || match.MatchedFileID == "file" || match.FileID != "file" {
+			t.Fatalf("лишний похожий файл: %+v", match)
+		}
+	}
+	if len(matches) != 3 || matches[2].MatchedFileID != "half" {
+		t.Fatalf("похожие файлы: %+v, ожидались copy, most и half", matches)
+	}
+}
@@ -0,0 +1,120 @@
+package services
+
+import (
+	"math"
+	"pkg/textproc"
+	"strings"
+	"unicode"
+)
+
+// TextStatistics — статистические характеристики текста, сохраняемые в результате анализа.
+type TextStatistics struct {
+	Language                 textproc.Language // Язык текста
+	ParagraphCount           int               // Абзацы, разделенные пустыми строками
+	LineCount                int               // Непустые строки
+	SentenceCount            int               // Предложения
+	WordCount                int               // Слова и числа по правилам границ слов Unicode
+	UniqueWordCount          int               // Различные слова без учета регистра
+	CharacterCount           int               // Символы без пробельных
+	CharacterCountWithSpaces int               // Все символы, включая пробельные
+	AverageWordLength        float64           // Средняя длина слова в символах
+	AverageSentenceLength    float64           // Средняя длина предложения в словах
+	LexicalDensity           float64           // Доля знаменательных (не служебных) слов, от 0 до 1
+}
+
+// sentenceClosers — закрывающие кавычки и скобки, которые могут стоять после знаков конца предложения.
+const sentenceClosers = `"'»”’)]`
+
+// textCounter подсчитывает символы, непустые строки, абзацы и предложения текста.
+// Текст передается посимвольно, поэтому может поступать фрагментами произвольной длины.
+//
+// Абзацы — блоки непустых строк, разделенные одной или несколькими пустыми строками.
+// Предложение заканчивается знаками «.», «!», «?» или «…», за которыми следует пробельный символ или конец текста
+// (так «3.14» и «example.com» не разрывают предложение), а также концом абзаца — это учитывает заголовки и пункты списков без точки.
+// Фрагменты без слов (например, «...» или «—») предложениями не считаются.
+type textCounter struct {
+	characters           int // Символы без пробельных
+	charactersWithSpaces int // Все символы
+	lines                int // Непустые строки
+	paragraphs           int
+	sentences            int
+
+	lineHasText     bool // В текущей строке есть непробельные символы
+	inParagraph     bool // Текущая строка продолжает абзац предыдущей непустой строки
+	sentenceHasWord bool // В текущем предложении есть буквы или цифры
+	afterTerminator bool // Последние символы — знаки конца предложения и закрывающие кавычки и скобки
+	afterNewline    bool // После последнего перевода строки были только пробельные символы
+}
+
+// add учитывает очередной символ текста.
+func (c *textCounter) add(r rune) {
+	c.charactersWithSpaces++
+	space := unicode.IsSpace(r)
+	if !space {
+		c.characters++
+	}
+
+	// Предложения
+	if c.afterTerminator && !isSentenceTerminator(r) && !strings.ContainsRune(sentenceClosers, r) {
+		c.afterTerminator = false
+		if space {
+			c.finishSentence()
+		}
+	}
+	if c.afterNewline {
+		if r == '\n' {
+			c.finishSentence() // Пустая строка завершает абзац, а вместе с ним и предложение
+		} else if !space {
+			c.afterNewline = false
+		}
+	}
+	switch {
+	case unicode.IsLetter(r) || unicode.IsDigit(r):
+		c.sentenceHasWord = true
+	case isSentenceTerminator(r):
+		c.afterTerminator = true
+	case r == '\n':
+		c.afterNewline = true
+	}
+
+	// Строки и абзацы
+	if r == '\n' {
+		c.finishLine()
+	} else if !space {
+		c.lineHasText = true
+	}
+}
+
+// finish учитывает конец текста.
+func (c *textCounter) finish() {
+	c.finishLine()
+	c.finishSentence()
+}
+
+func (c *textCounter) finishLine() {
+	if !c.lineHasText {
+		c.inParagraph = false
+		return
+	}
+	c.lines++
+	if !c.inParagraph {
+		c.paragraphs++
+		c.inParagraph = true
+	}
+	c.lineHasText = false
+}
+
+func (c *textCounter) finishSentence() {
+	if c.sentenceHasWord {
+		c.sentences++
+		c.sentenceHasWord = false
+	}
+}
+
+func isSentenceTerminator(r rune) bool {
+	return r == '.' || r == '!' || r == '?' || r == '…'
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
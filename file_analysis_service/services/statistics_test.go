@@ -0,0 +1,77 @@
+package services
+
+import (
+	"testing"
+	"unicode"
+	"unicode/utf8"
+)
+
+func TestTextCounter(t *testing.T) {
+	tests := []struct {
+		name                         string
+		text                         string
+		lines, paragraphs, sentences int
+	}{
+		{"пустой текст", "", 0, 0, 0},
+		{"только пробельные символы", "  \t\n\n \r\n ", 0, 0, 0},
+		{"одно предложение без точки", "Привет мир", 1, 1, 1},
+		{"точка в конце текста", "Конец.", 1, 1, 1},
+		{"несколько предложений", "Привет. Как дела? Хорошо!", 1, 1, 3},
+		{"многоточие", "Ну... Ладно… Пока", 1, 1, 3},
+		{"повторяющиеся знаки", "Что?! Да!!! Нет?..", 1, 1, 3},
+		{"кавычки после знака", "Он сказал: «Привет!» Потом ушел.", 1, 1, 2},
+		{"скобки и английские кавычки", `"Yes." He left (at once.) Then "no?"`, 1, 1, 3},
+		{"точка внутри слова и числа", "Число 3.14 и сайт example.com — не конец", 1, 1, 1},
+		{"знаки без слов", "... — ?! …", 1, 1, 0},
+		{"предложение на нескольких строках", "Первая строка\nвторая строка.", 2, 1, 1},
+		{"заголовок без точки", "Заголовок\n\nТекст абзаца. Еще", 2, 2, 3},
+		{"CRLF", "Первая строка\r\nВторая строка.\r\n\r\nНовый абзац\r\n", 3, 2, 2},
+		{"CR в конце строки без точки", "Пункт один\r\n\r\nПункт два\r\n", 2, 2, 2},
+		{"переводы строк в конце", "Текст.\n\n\n", 1, 1, 1},
+		{"несколько пустых строк", "Абзац один\n\n\n\nАбзац два\n\n\nАбзац три", 3, 3, 3},
+		{"строка из пробелов разделяет абзацы", "Абзац один\n \t \nАбзац два", 2, 2, 2},
+		{"знак перед пустой строкой", "Вопрос?»\n\nОтвет", 2, 2, 2},
+		{"пробелы в начале строк", "  Строка один\n  строка два.\n", 2, 1, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var c textCounter
+			for _, r := range tt.text {
+				c.add(r)
+			}
+			c.finish()
+			if c.lines != tt.lines || c.paragraphs != tt.paragraphs || c.sentences != tt.sentences {
+				t.Fatalf("строк %d, абзацев %d, предложений %d; ожидалось %d, %d, %d",
+					c.lines, c.paragraphs, c.sentences, tt.lines, tt.paragraphs, tt.sentences)
+			}
+			spaces := 0
+			for _, r := range tt.text {
+				if unicode.IsSpace(r) {
+					spaces++
+				}
+			}
+			if runes := utf8.RuneCountInString(tt.text); c.charactersWithSpaces != runes || c.characters != runes-spaces {
+				t.Fatalf("символов %d (с пробельными %d), ожидалось %d (%d)", c.characters, c.charactersWithSpaces, runes-spaces, runes)
+			}
+		})
+	}
+}
+
+func TestTextAnalyzerStatisticsAcrossChunks(t *testing.T) {
+	text := "Первый абзац. Второе предложение...\r\n\r\nВторой абзац «с цитатой!» и числом 3,14\n"
+	whole := newTextAnalyzer(DefaultShingleSize)
+	whole.Feed(text)
+	want := whole.Finish().Statistics
+
+	// Границы фрагментов между «\r» и «\n» и между знаком конца и кавычкой не меняют результат
+	chunked := newTextAnalyzer(DefaultShingleSize)
+	for _, chunk := range []string{"Первый абзац. Второе предложение...\r", "\n\r\nВторой абзац «с цитатой!", "» и числом 3,14\n"} {
+		chunked.Feed(chunk)
+	}
+	if got := chunked.Finish().Statistics; got != want {
+		t.Fatalf("статистика по фрагментам %+v, целиком %+v", got, want)
+	}
+	if want.ParagraphCount != 2 || want.LineCount != 2 || want.SentenceCount != 4 || want.WordCount != 11 {
+		t.Fatalf("статистика %+v", want)
+	}
+}
@@ -0,0 +1,152 @@
+package services
+
+import (
+	"pkg/textproc"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxExactShingles — сколько уникальных шинглов текста запоминается для их точного подсчета.
+// Для текстов, где их больше, количество шинглов оценивается по MinHash-сигнатуре, чтобы память не росла с размером текста.
+const maxExactShingles = 1 << 20
+
+// textAnalysis — характеристики текста, вычисленные textAnalyzer.
+type textAnalysis struct {
+	Statistics   TextStatistics
+	WordCounts   map[string]int // Количество вхождений каждого слова (без чисел)
+	Signature    []uint64       // MinHash-сигнатура шинглов; nil для текста без слов
+	ShingleCount int            // Количество уникальных шинглов (для очень больших текстов — оценка)
+	Fingerprints []fingerprint  // Отпечатки winnowing; пусто для текста короче fingerprintSize слов
+}
+
+// textAnalyzer за один проход по тексту вычисляет его статистику, частоты слов, MinHash-сигнатуру шинглов
+// и отпечатки winnowing.
+// Текст передается фрагментами через Feed (например, из textproc.ReadChunks) и целиком в памяти не хранится:
+// память зависит от словаря текста, а не от его размера. Фрагменты не должны разрезать слова.
+type textAnalyzer struct {
+	counter     textCounter
+	sample      strings.Builder // Начало текста для определения языка
+	sampleRunes int
+
+	tokens     int // Слова и числа
+	letters    int // Символы в словах и числах
+	wordCounts map[string]int
+	numbers    map[string]struct{} // Различные числа
+
+	shingleSize      int
+	window           []string // Последние shingleSize слов
+	signature        []uint64
+	shingles         map[uint64]struct{}
+	shinglesOverflow bool // Уникальных шинглов больше maxExactShingles, их количество оценивается
+
+	winnower *winnower
+	offset   int // Смещение начала текущего фрагмента в тексте (в рунах)
+}
+
+func newTextAnalyzer(shingleSize int) *textAnalyzer {
+	if shingleSize < 1 {
+		shingleSize = 1
+	}
+	return &textAnalyzer{
+		wordCounts:  make(map[string]int),
+		numbers:     make(map[string]struct{}),
+		shingleSize: shingleSize,
+		window:      make([]string, 0, shingleSize),
+		shingles:    make(map[uint64]struct{}),
+		winnower:    newWinnower(),
+	}
+}
+
+// Feed учитывает очередной фрагмент текста. Ошибку не возвращает; сигнатура совместима с textproc.ReadChunks.
+func (a *textAnalyzer) Feed(chunk string) error {
+	runes := 0
+	for _, r := range chunk {
+		a.counter.add(r)
+		if a.sampleRunes < textproc.DetectionSampleRunes {
+			a.sample.WriteRune(r)
+			a.sampleRunes++
+		}
+		runes++
+	}
+
+	for _, token := range textproc.Tokenize(chunk) {
+		a.tokens++
+		a.letters += utf8.RuneCountInString(token.Text)
+		if token.Kind == textproc.TokenWord {
+			a.wordCounts[token.Text]++
+		} else {
+			a.numbers[token.Text] = struct{}{}
+		}
+
+		// Шинглы — все последовательности из shingleSize подряд идущих слов
+		if len(a.window) == a.shingleSize {
+			a.window = append(a.window[:0], a.window[1:]...)
+		}
+		a.window = append(a.window, token.Text)
+		if len(a.window) == a.shingleSize {
+			a.addShingle(shingleHash(a.window))
+		}
+		a.winnower.add(token.Text, a.offset+token.Start, a.offset+token.End)
+	}
+	a.offset += runes
+	return nil
+}
+
+func (a *textAnalyzer) addShingle(shingle uint64) {
+	if !a.shinglesOverflow {
+		if _, ok := a.shingles[shingle]; ok {
+			return
+		}
+		if len(a.shingles) < maxExactShingles {
+			a.shingles[shingle] = struct{}{}
+		} else {
+			a.shinglesOverflow = true
+			a.shingles = nil
+		}
+	}
+	if a.signature == nil {
+		a.signature = newMinHashSignature()
+	}
+	updateMinHash(a.signature, shingle)
+}
+
+// Finish завершает анализ и возвращает его результат. После Finish анализатор использовать нельзя.
+func (a *textAnalyzer) Finish() textAnalysis {
+	a.counter.finish()
+	if a.tokens > 0 && a.tokens < a.shingleSize {
+		// Текст короче одного шингла считаем единственным шинглом
+		a.addShingle(shingleHash(a.window))
+	}
+
+	stats := TextStatistics{
+		Language:                 textproc.DetectLanguage(a.sample.String()),
+		ParagraphCount:           a.counter.paragraphs,
+		LineCount:                a.counter.lines,
+		SentenceCount:            a.counter.sentences,
+		WordCount:                a.tokens,
+		UniqueWordCount:          len(a.wordCounts) + len(a.numbers),
+		CharacterCount:           a.counter.characters,
+		CharacterCountWithSpaces: a.counter.charactersWithSpaces,
+	}
+	if stats.WordCount > 0 {
+		contentWords := stats.WordCount
+		for word, count := range a.wordCounts {
+			if textproc.IsStopWordIn(stats.Language, word) {
+				contentWords -= count
+			}
+		}
+		stats.AverageWordLength = round2(float64(a.letters) / float64(stats.WordCount))
+		stats.LexicalDensity = round2(float64(contentWords) / float64(stats.WordCount))
+		if stats.SentenceCount > 0 {
+			stats.AverageSentenceLength = round2(float64(stats.WordCount) / float64(stats.SentenceCount))
+		}
+	}
+
+	result := textAnalysis{Statistics: stats, WordCounts: a.wordCounts, Signature: a.signature, Fingerprints: a.winnower.finish()}
+	if a.shinglesOverflow {
+		result.ShingleCount = estimateShingleCount(a.signature)
+	} else {
+		result.ShingleCount = len(a.shingles)
+	}
+	return result
+}
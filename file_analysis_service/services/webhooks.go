@@ -0,0 +1,571 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"file_analysis_service/models"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"pkg/adapters"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// DefaultWebhookMaxAttempts — количество попыток доставки уведомления по умолчанию.
+	DefaultWebhookMaxAttempts = 10
+	// DefaultWebhookTimeout — таймаут одного запроса к получателю уведомления по умолчанию.
+	DefaultWebhookTimeout = 10 * time.Second
+	// DefaultWebhookPollInterval — как часто проверяется очередь уведомлений, если ее не разбудили раньше.
+	DefaultWebhookPollInterval = 5 * time.Second
+	// DefaultWebhookMaxBackoff — наибольшая пауза между попытками доставки одного уведомления.
+	DefaultWebhookMaxBackoff = time.Hour
+	// webhookInitialBackoff — пауза после первой неудачной попытки; затем она удваивается.
+	webhookInitialBackoff = 10 * time.Second
+	// webhookBatchSize — сколько уведомлений обрабатывается за один запрос к БД.
+	webhookBatchSize = 100
+	// maxWebhookURLLength — наибольшая длина URL уведомлений.
+	maxWebhookURLLength = 2048
+	// maxWebhookErrorBody — сколько байт ответа получателя сохраняется в ошибке попытки.
+	maxWebhookErrorBody = 512
+)
+
+// Заголовки уведомления о завершении анализа.
+const (
+	HeaderWebhookID        = "X-Webhook-ID"        // ID уведомления, одинаковый во всех попытках доставки
+	HeaderWebhookEvent     = "X-Webhook-Event"     // Тип уведомления
+	HeaderWebhookTimestamp = "X-Webhook-Timestamp" // Время подписи (Unix-время в секундах)
+	HeaderWebhookSignature = "X-Webhook-Signature" // sha256=<HMAC-SHA256 от "<timestamp>.<тело>" в hex>
+)
+
+// ErrInvalidWebhookURL возвращается для URL уведомлений, который не является абсолютным URL http или https.
+var ErrInvalidWebhookURL = errors.New("URL уведомлений должен быть абсолютным URL со схемой http или https")
+
+// errWebhookEndpointDeleted возвращается при доставке уведомления пользователю, удалившему настройки уведомлений.
+var errWebhookEndpointDeleted = errors.New("пользователь удалил настройки уведомлений, уведомление нечем подписать")
+
+// errPrivateAddress возвращается при попытке отправить уведомление на адрес во внутренней сети.
+var errPrivateAddress = errors.New("отправка уведомлений на адреса внутренней сети запрещена")
+
+// WebhookPayload — тело уведомления о завершении задачи анализа.
+// @Description Уведомление о завершении анализа: результат анализа или причина неудачи.
+// @Name WebhookPayload
+type WebhookPayload struct {
+	ID         string                 `json:"id" example:"5f1d7c3e-8a4b-4c1f-9d2e-7b6a5c4d3e2f"` // ID уведомления, одинаковый во всех попытках доставки
+	Type       string                 `json:"type" example:"analysis.succeeded"`                 // analysis.succeeded или analysis.failed
+	JobID      uint                   `json:"job_id" example:"1"`                                // ID задачи анализа
+	FileID     string                 `json:"file_id" example:"unique-file-id"`
+	Attempts   int                    `json:"attempts" example:"1"` // Количество выполненных попыток анализа
+	OccurredAt time.Time              `json:"occurred_at" swaggertype:"string" format:"date-time"`
+	Result     *models.AnalysisResult `json:"result,omitempty"` // Результат анализа (для analysis.succeeded)
+	Error      string                 `json:"error,omitempty"`  // Ошибка последней попытки анализа (для analysis.failed)
+}
+
+// WebhookDispatcher создает уведомления о завершении задач анализа и доставляет их получателям.
+// @Summary Доставка уведомлений о завершении анализа
+// @Description Хранит настройки уведомлений пользователей и очередь уведомлений в БД. Уведомления подписываются секретом пользователя (HMAC-SHA256);
+// @Description неудачные попытки доставки повторяются с экспоненциально растущей паузой.
+// @Tags services
+type WebhookDispatcher struct {
+	DBAdapter   *adapters.DBAdapter
+	Client      *http.Client
+	MaxAttempts int           // Количество попыток доставки уведомления
+	Interval    time.Duration // Интервал проверки очереди уведомлений
+	MaxBackoff  time.Duration // Наибольшая пауза между попытками доставки уведомления
+
+	wake chan struct{}
+}
+
+// NewWebhookDispatcher создает новый экземпляр WebhookDispatcher с настройками по умолчанию.
+// Если allowPrivateNetworks равно false, уведомления не отправляются на адреса внутренней сети (защита от SSRF).
+// @Summary Создает новый WebhookDispatcher
+// @Description Инициализирует доставку уведомлений. Фоновая доставка запускается методом Start.
+// @Return *WebhookDispatcher
+func NewWebhookDispatcher(dbAdapter *adapters.DBAdapter, allowPrivateNetworks bool) *WebhookDispatcher {
+	dialer := &net.Dialer{Timeout: DefaultWebhookTimeout}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if !allowPrivateNetworks {
+		// Адрес проверяется при подключении, уже после разрешения имени, поэтому его не обойти DNS-записью,
+		// указывающей во внутреннюю сеть. Прокси из окружения не используется по той же причине
+		dialer.Control = rejectPrivateAddress
+		transport.Proxy = nil
+	}
+	transport.DialContext = dialer.DialContext
+
+	return &WebhookDispatcher{
+		DBAdapter: dbAdapter,
+		Client: &http.Client{
+			Timeout:   DefaultWebhookTimeout,
+			Transport: transport,
+			// Перенаправления не выполняются: ответ 3xx считается неудачной попыткой
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+		MaxAttempts: DefaultWebhookMaxAttempts,
+		Interval:    DefaultWebhookPollInterval,
+		MaxBackoff:  DefaultWebhookMaxBackoff,
+		wake:        make(chan struct{}, 1),
+	}
+}
+
+// Сети, в которые запрещено отправлять уведомления, кроме определяемых методами net.IP.
+var (
+	// sharedAddressSpace — адреса провайдерского NAT (RFC 6598), во многих облаках — внутренняя сеть
+	sharedAddressSpace = mustParseCIDR("100.64.0.0/10")
+	// nat64Prefix — адреса IPv6, транслируемые в IPv4-адрес из последних 4 байт (RFC 6052)
+	nat64Prefix = mustParseCIDR("64:ff9b::/96")
+	// sixToFourPrefix — адреса 6to4, содержащие IPv4-адрес в байтах 2–5 (RFC 3056)
+	sixToFourPrefix = mustParseCIDR("2002::/16")
+)
+
+func mustParseCIDR(cidr string) *net.IPNet {
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		panic(err)
+	}
+	return network
+}
+
+// rejectPrivateAddress запрещает подключение к локальным, частным и служебным адресам.
+func rejectPrivateAddress(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if isPrivateAddress(net.ParseIP(host)) {
+		return fmt.Errorf("%w: %s", errPrivateAddress, host)
+	}
+	return nil
+}
+
+// isPrivateAddress проверяет, что адрес локальный, частный или служебный. У адресов NAT64 и 6to4
+// проверяется и встроенный IPv4-адрес: через шлюз трансляции они ведут в сеть этого адреса.
+func isPrivateAddress(ip net.IP) bool {
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || sharedAddressSpace.Contains(ip) {
+		return true
+	}
+	if ip.To4() == nil {
+		ip = ip.To16()
+		switch {
+		case nat64Prefix.Contains(ip):
+			return isPrivateAddress(ip[12:16])
+		case sixToFourPrefix.Contains(ip):
+			return isPrivateAddress(ip[2:6])
+		}
+	}
+	return false
+}
+
+// ValidateWebhookURL проверяет URL уведомлений.
+func ValidateWebhookURL(raw string) error {
+	if len(raw) > maxWebhookURLLength {
+		return fmt.Errorf("%w: URL длиннее %d символов", ErrInvalidWebhookURL, maxWebhookURLLength)
+	}
+	parsed, err := url.Parse(raw)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return ErrInvalidWebhookURL
+	}
+	return nil
+}
+
+// WebhookSignature вычисляет подпись уведомления: HMAC-SHA256 строки "<timestamp>.<тело>" секретом пользователя в hex.
+// Получатель вычисляет ее так же и сравнивает со значением заголовка X-Webhook-Signature после префикса "sha256=".
+func WebhookSignature(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func newWebhookSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("не удалось сгенерировать секрет уведомлений: %w", err)
+	}
+	return hex.EncodeToString(secret), nil
+}
+
+// GetEndpoint возвращает настройки уведомлений пользователя.
+// @Summary Получение настроек уведомлений
+// @Param ownerID path string true "ID пользователя"
+// @Return *models.WebhookEndpoint, error "Настройки и ошибка, если есть (gorm.ErrRecordNotFound, если пользователь их не задавал)"
+func (d *WebhookDispatcher) GetEndpoint(ownerID string) (*models.WebhookEndpoint, error) {
+	var endpoint models.WebhookEndpoint
+	if err := d.DBAdapter.First(&endpoint, "owner_id = ?", ownerID); err != nil {
+		return nil, err
+	}
+	return &endpoint, nil
+}
+
+// SetEndpoint задает URL уведомлений пользователя обо всех его файлах (пустой URL отключает их).
+// Секрет создается при первой настройке и заменяется новым, если задан rotateSecret.
+// @Summary Изменение настроек уведомлений
+// @Param ownerID path string true "ID пользователя"
+// @Param url body string true "URL уведомлений или пустая строка"
+// @Param rotateSecret query bool false "Заменить секрет подписи новым"
+// @Return *models.WebhookEndpoint, error
+func (d *WebhookDispatcher) SetEndpoint(ownerID, endpointURL string, rotateSecret bool) (*models.WebhookEndpoint, error) {
+	if endpointURL != "" {
+		if err := ValidateWebhookURL(endpointURL); err != nil {
+			return nil, err
+		}
+	}
+	var endpoint models.WebhookEndpoint
+	err := d.DBAdapter.Transaction(func(tx *adapters.DBAdapter) error {
+		current, err := d.ensureEndpoint(tx, ownerID)
+		if err != nil {
+			return err
+		}
+		updates := map[string]interface{}{"url": endpointURL}
+		if rotateSecret {
+			secret, err := newWebhookSecret()
+			if err != nil {
+				return err
+			}
+			updates["secret"] = secret
+		}
+		if err := tx.DB.Model(current).Updates(updates).Error; err != nil {
+			return err
+		}
+		return tx.First(&endpoint, "owner_id = ?", ownerID)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("не удалось сохранить настройки уведомлений пользователя: %w", err)
+	}
+	return &endpoint, nil
+}
+
+// DeleteEndpoint удаляет настройки уведомлений пользователя. Уведомления, которые еще не доставлены, доставлены не будут:
+// подписать их больше нечем.
+// @Summary Удаление настроек уведомлений
+// @Param ownerID path string true "ID пользователя"
+// @Return bool, error "Были ли настройки и ошибка, если есть"
+func (d *WebhookDispatcher) DeleteEndpoint(ownerID string) (bool, error) {
+	deleted := false
+	err := d.DBAdapter.Transaction(func(tx *adapters.DBAdapter) error {
+		result := tx.DB.Delete(&models.WebhookEndpoint{}, "owner_id = ?", ownerID)
+		if result.Error != nil {
+			return result.Error
+		}
+		deleted = result.RowsAffected > 0
+		if err := tx.DB.Delete(&models.AnalysisCallback{}, "owner_id = ?", ownerID).Error; err != nil {
+			return err
+		}
+		return tx.DB.Model(&models.WebhookDelivery{}).
+			Where("owner_id = ? AND delivered_at IS NULL AND failed_at IS NULL", ownerID).
+			Updates(map[string]interface{}{"failed_at": time.Now(), "last_error": errWebhookEndpointDeleted.Error()}).Error
+	})
+	if err != nil {
+		return false, fmt.Errorf("не удалось удалить настройки уведомлений пользователя: %w", err)
+	}
+	return deleted, nil
+}
+
+// ensureEndpoint возвращает настройки уведомлений пользователя, создавая их с новым секретом и без URL, если их нет.
+func (d *WebhookDispatcher) ensureEndpoint(tx *adapters.DBAdapter, ownerID string) (*models.WebhookEndpoint, error) {
+	secret, err := newWebhookSecret()
+	if err != nil {
+		return nil, err
+	}
+	endpoint := models.WebhookEndpoint{OwnerID: ownerID, Secret: secret}
+	if err := tx.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&endpoint).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.DB.Clauses(clause.Locking{Strength: "UPDATE"}).First(&endpoint, "owner_id = ?", ownerID).Error; err != nil {
+		return nil, err
+	}
+	return &endpoint, nil
+}
+
+// RegisterCallback добавляет к задаче анализа уведомление о ее завершении на URL, указанный пользователем ownerID
+// в запросе анализа. Если задача уже завершена, уведомление сразу ставится в очередь доставки.
+// @Summary Уведомление о завершении задачи
+// @Param jobID path int true "ID задачи анализа"
+// @Param ownerID path string true "ID пользователя, секретом которого подписывается уведомление"
+// @Param callbackURL query string true "URL уведомления"
+// @Return error
+func (d *WebhookDispatcher) RegisterCallback(jobID uint, ownerID, callbackURL string) error {
+	if err := ValidateWebhookURL(callbackURL); err != nil {
+		return err
+	}
+	scheduled := false
+	err := d.DBAdapter.Transaction(func(tx *adapters.DBAdapter) error {
+		if _, err := d.ensureEndpoint(tx, ownerID); err != nil {
+			return err
+		}
+		// Блокировка задачи упорядочивает регистрацию с сохранением ее завершения в JobQueue:
+		// уведомление либо увидит завершенная задача, либо оно будет создано здесь
+		var job models.AnalysisJob
+		if err := tx.DB.Clauses(clause.Locking{Strength: "UPDATE"}).First(&job, jobID).Error; err != nil {
+			return err
+		}
+		if job.Status == models.JobStatusCancelled {
+			// Файл удален, уведомлять не о чем
+			return nil
+		}
+		if job.Status == models.JobStatusSucceeded || job.Status == models.JobStatusFailed {
+			scheduled = true
+			return d.schedule(tx, &job, []models.AnalysisCallback{{JobID: jobID, OwnerID: ownerID, URL: callbackURL}})
+		}
+		return tx.Create(&models.AnalysisCallback{JobID: jobID, OwnerID: ownerID, URL: callbackURL})
+	})
+	if err != nil {
+		return fmt.Errorf("не удалось сохранить уведомление о завершении задачи %d: %w", jobID, err)
+	}
+	if scheduled {
+		d.Notify()
+	}
+	return nil
+}
+
+// JobFinished ставит в очередь доставки уведомления о завершении задачи job: на URL из запросов анализа
+// и на URL владельца файла ownerID из его настроек. Вызывается в транзакции, сохраняющей завершение задачи.
+// @Summary Уведомления о завершении задачи
+// @Param tx body object true "Адаптер транзакции"
+// @Param job body object true "Завершенная задача анализа"
+// @Param ownerID path string false "ID владельца файла; пустой, если владелец неизвестен"
+// @Return error
+func (d *WebhookDispatcher) JobFinished(tx *adapters.DBAdapter, job *models.AnalysisJob, ownerID string) error {
+	var callbacks []models.AnalysisCallback
+	if err := tx.DB.Where("job_id = ?", job.ID).Order("id").Find(&callbacks).Error; err != nil {
+		return err
+	}
+	if ownerID != "" {
+		var endpoints []models.WebhookEndpoint
+		if err := tx.DB.Where("owner_id = ? AND url <> ''", ownerID).Find(&endpoints).Error; err != nil {
+			return err
+		}
+		for _, endpoint := range endpoints {
+			callbacks = append(callbacks, models.AnalysisCallback{JobID: job.ID, OwnerID: endpoint.OwnerID, URL: endpoint.URL})
+		}
+	}
+	if len(callbacks) == 0 {
+		return nil
+	}
+	if err := d.schedule(tx, job, callbacks); err != nil {
+		return err
+	}
+	return tx.DB.Delete(&models.AnalysisCallback{}, "job_id = ?", job.ID).Error
+}
+
+// schedule создает уведомления о завершении задачи job по одному на каждую пару пользователя и URL из callbacks.
+func (d *WebhookDispatcher) schedule(tx *adapters.DBAdapter, job *models.AnalysisJob, callbacks []models.AnalysisCallback) error {
+	payload := WebhookPayload{JobID: job.ID, FileID: job.FileID, Attempts: job.Attempts, OccurredAt: time.Now()}
+	if job.FinishedAt != nil {
+		payload.OccurredAt = *job.FinishedAt
+	}
+	if job.Status == models.JobStatusSucceeded {
+		payload.Type = models.WebhookAnalysisSucceeded
+		if job.ResultID != nil {
+			var result models.AnalysisResult
+			if err := tx.First(&result, *job.ResultID); err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			} else if err == nil {
+				payload.Result = &result
+			}
+		}
+	} else {
+		payload.Type = models.WebhookAnalysisFailed
+		payload.Error = job.LastError
+	}
+
+	seen := make(map[[2]string]bool, len(callbacks))
+	for _, callback := range callbacks {
+		key := [2]string{callback.OwnerID, callback.URL}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		payload.ID = uuid.New().String()
+		body, err := json.Marshal(payload)
+		if err != nil {
+			return fmt.Errorf("ошибка при сериализации уведомления: %w", err)
+		}
+		delivery := models.WebhookDelivery{
+			EventID:       payload.ID,
+			Type:          payload.Type,
+			OwnerID:       callback.OwnerID,
+			JobID:         job.ID,
+			FileID:        job.FileID,
+			URL:           callback.URL,
+			Payload:       string(body),
+			NextAttemptAt: time.Now(),
+		}
+		if err := tx.Create(&delivery); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListDeliveries возвращает limit последних уведомлений пользователя вместе с результатами их доставки.
+// @Summary Список уведомлений пользователя
+// @Param ownerID path string true "ID пользователя"
+// @Param limit query int true "Количество уведомлений"
+// @Return []models.WebhookDelivery, error
+func (d *WebhookDispatcher) ListDeliveries(ownerID string, limit int) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+	err := d.DBAdapter.DB.Where("owner_id = ?", ownerID).Order("id DESC").Limit(limit).Find(&deliveries).Error
+	if err != nil {
+		return nil, fmt.Errorf("не удалось получить уведомления пользователя: %w", err)
+	}
+	return deliveries, nil
+}
+
+// Notify сообщает о новых уведомлениях, чтобы они были доставлены без ожидания Interval.
+func (d *WebhookDispatcher) Notify() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Start запускает фоновую доставку уведомлений: сразу, затем каждые Interval и после каждого Notify, пока не отменен ctx.
+// @Summary Запуск доставки уведомлений
+// @Description Запускает горутину, выполняющую DeliverPending.
+func (d *WebhookDispatcher) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(d.Interval)
+		defer ticker.Stop()
+		for {
+			if err := d.DeliverPending(ctx); err != nil {
+				log.Printf("Ошибка при доставке уведомлений: %v", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			case <-d.wake:
+			}
+		}
+	}()
+}
+
+// DeliverPending выполняет один проход доставки уведомлений, время попытки которых наступило.
+// @Summary Один проход доставки
+// @Description Если попытка доставки на URL не удалась, остальные уведомления на этот URL в этом проходе не отправляются,
+// @Description чтобы недоступный получатель не задерживал доставку другим.
+// @Return error
+func (d *WebhookDispatcher) DeliverPending(ctx context.Context) error {
+	unavailable := make(map[string]bool)
+	var afterID uint
+	for {
+		var pending []models.WebhookDelivery
+		err := d.DBAdapter.DB.Where("delivered_at IS NULL AND failed_at IS NULL AND next_attempt_at <= ? AND id > ?", time.Now(), afterID).
+			Order("id").Limit(webhookBatchSize).
+			Find(&pending).Error
+		if err != nil {
+			return fmt.Errorf("не удалось получить недоставленные уведомления: %w", err)
+		}
+
+		for i := range pending {
+			delivery := &pending[i]
+			afterID = delivery.ID
+			if ctx.Err() != nil {
+				return nil
+			}
+			if unavailable[delivery.URL] {
+				continue
+			}
+			if err := d.deliver(ctx, delivery); err != nil {
+				log.Printf("Не удалось доставить уведомление %s (%s) задачи %d на %s: %v", delivery.EventID, delivery.Type, delivery.JobID, delivery.URL, err)
+				unavailable[delivery.URL] = true
+			}
+		}
+		if len(pending) < webhookBatchSize {
+			return nil
+		}
+	}
+}
+
+// deliver отправляет уведомление и записывает результат попытки.
+func (d *WebhookDispatcher) deliver(ctx context.Context, delivery *models.WebhookDelivery) error {
+	sendErr := d.send(ctx, delivery)
+	now := time.Now()
+	updates := map[string]interface{}{"delivered_at": now, "last_error": ""}
+	if sendErr != nil {
+		attempts := delivery.Attempts + 1
+		updates = map[string]interface{}{
+			"attempts":        attempts,
+			"next_attempt_at": now.Add(d.backoff(attempts)),
+			"last_error":      sendErr.Error(),
+		}
+		if attempts >= d.MaxAttempts || errors.Is(sendErr, errWebhookEndpointDeleted) {
+			updates["failed_at"] = now
+			log.Printf("Уведомление %s задачи %d не доставлено на %s после %d попыток", delivery.EventID, delivery.JobID, delivery.URL, attempts)
+		}
+	}
+	if err := d.DBAdapter.DB.Model(delivery).Updates(updates).Error; err != nil {
+		if sendErr != nil {
+			return fmt.Errorf("%v; не удалось сохранить попытку доставки: %w", sendErr, err)
+		}
+		return fmt.Errorf("не удалось отметить доставку уведомления %s: %w", delivery.EventID, err)
+	}
+	return sendErr
+}
+
+// send выполняет одну попытку доставки: POST-запрос с телом уведомления, подписанным текущим секретом пользователя.
+// Уведомление считается доставленным, если получатель ответил кодом 2xx.
+func (d *WebhookDispatcher) send(ctx context.Context, delivery *models.WebhookDelivery) error {
+	endpoint, err := d.GetEndpoint(delivery.OwnerID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errWebhookEndpointDeleted
+		}
+		return fmt.Errorf("не удалось получить секрет уведомлений: %w", err)
+	}
+
+	body := []byte(delivery.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("ошибка при создании запроса: %w", err)
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "file-analysis-service-webhook")
+	req.Header.Set(HeaderWebhookID, delivery.EventID)
+	req.Header.Set(HeaderWebhookEvent, delivery.Type)
+	req.Header.Set(HeaderWebhookTimestamp, timestamp)
+	req.Header.Set(HeaderWebhookSignature, "sha256="+WebhookSignature(endpoint.Secret, timestamp, body))
+
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("ошибка при отправке уведомления: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, maxWebhookErrorBody))
+		return fmt.Errorf("получатель вернул код %d: %s", resp.StatusCode, string(respBody))
+	}
+	return nil
+}
+
+// backoff возвращает паузу перед следующей попыткой после attempts неудачных попыток.
+func (d *WebhookDispatcher) backoff(attempts int) time.Duration {
+	delay := webhookInitialBackoff
+	for i := 1; i < attempts && delay < d.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > d.MaxBackoff {
+		delay = d.MaxBackoff
+	}
+	return delay
+}
@@ -0,0 +1,55 @@
+package services
+
+import (
+	"errors"
+	"net"
+	"testing"
+)
+
+func TestRejectPrivateAddress(t *testing.T) {
+	tests := []struct {
+		host    string
+		private bool
+	}{
+		{"93.184.216.34", false},
+		{"2606:2800:220:1:248:1893:25c8:1946", false},
+		{"127.0.0.1", true},
+		{"10.1.2.3", true},
+		{"172.16.0.1", true},
+		{"192.168.1.1", true},
+		{"169.254.169.254", true},
+		{"0.0.0.0", true},
+		{"224.0.0.1", true},
+		{"::1", true},
+		{"::", true},
+		{"fd00::1", true},
+		{"fe80::1", true},
+		{"::ffff:127.0.0.1", true},
+		{"::ffff:93.184.216.34", false},
+		// Провайдерский NAT
+		{"100.64.0.1", true},
+		{"100.127.255.254", true},
+		{"100.63.255.255", false},
+		{"100.128.0.1", false},
+		// NAT64: встроенный IPv4-адрес в последних 4 байтах
+		{"64:ff9b::7f00:1", true},
+		{"64:ff9b::a9fe:a9fe", true},
+		{"64:ff9b::10.0.0.1", true},
+		{"64:ff9b::100.64.0.1", true},
+		{"64:ff9b::93.184.216.34", false},
+		// 6to4: встроенный IPv4-адрес в байтах 2–5
+		{"2002:7f00:1::", true},
+		{"2002:c0a8:101::1", true},
+		{"2002:a9fe:a9fe::", true},
+		{"2002:5db8:d822::1", false},
+	}
+	for _, tt := range tests {
+		err := rejectPrivateAddress("tcp", net.JoinHostPort(tt.host, "443"), nil)
+		if tt.private && !errors.Is(err, errPrivateAddress) {
+			t.Errorf("%s: подключение не запрещено: %v", tt.host, err)
+		}
+		if !tt.private && err != nil {
+			t.Errorf("%s: подключение запрещено: %v", tt.host, err)
+		}
+	}
+}
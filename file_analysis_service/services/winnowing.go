@@ -0,0 +1,102 @@
+package services
+
+const (
+	// fingerprintSize — количество слов в k-грамме, из хешей которых выбираются отпечатки winnowing.
+	// fingerprintSize и winnowingWindow не должны меняться без увеличения AnalyzerVersion,
+	// иначе отпечатки новых анализов станут несравнимы с ранее сохраненными.
+	fingerprintSize = 5
+	// winnowingWindow — количество подряд идущих k-грамм, среди которых выбирается отпечаток.
+	// Любой общий фрагмент длиной не меньше fingerprintSize+winnowingWindow-1 слов дает хотя бы один общий отпечаток.
+	winnowingWindow = 4
+	// maxFingerprints — сколько отпечатков сохраняется для одного файла. У очень больших текстов
+	// отпечатки есть только для начала текста, чтобы память анализа не росла с размером текста.
+	maxFingerprints = 1 << 20
+)
+
+// fingerprint — k-грамма слов текста, выбранная алгоритмом winnowing.
+type fingerprint struct {
+	Hash     uint64
+	Position int // Номер первого слова k-граммы
+	Start    int // Смещение первого символа k-граммы (в рунах)
+	End      int // Смещение символа, следующего за k-граммой (в рунах)
+}
+
+// winnower выбирает отпечатки текста алгоритмом winnowing (Schleimer, Wilkerson, Aiken, 2003):
+// в каждом окне из winnowingWindow подряд идущих k-грамм отпечатком становится k-грамма с наименьшим хешем
+// (при равенстве — самая правая), а каждая k-грамма записывается не более одного раза.
+// Слова передаются по одному через add и целиком в памяти не хранятся.
+type winnower struct {
+	words  []string // Последние fingerprintSize слов
+	starts []int    // Смещения начала последних fingerprintSize слов
+	count  int      // Количество переданных слов
+
+	window       []fingerprint // Последние winnowingWindow k-грамм
+	lastSelected int           // Номер первого слова последней записанной k-граммы
+	fingerprints []fingerprint
+	overflow     bool // Отпечатков больше maxFingerprints, остальные не записываются
+}
+
+func newWinnower() *winnower {
+	return &winnower{
+		words:        make([]string, 0, fingerprintSize),
+		starts:       make([]int, 0, fingerprintSize),
+		window:       make([]fingerprint, 0, winnowingWindow),
+		lastSelected: -1,
+	}
+}
+
+// add учитывает очередное слово текста с нормализованным текстом word, занимающее символы [start, end).
+func (w *winnower) add(word string, start, end int) {
+	if len(w.words) == fingerprintSize {
+		w.words = append(w.words[:0], w.words[1:]...)
+		w.starts = append(w.starts[:0], w.starts[1:]...)
+	}
+	w.words = append(w.words, word)
+	w.starts = append(w.starts, start)
+	w.count++
+	if len(w.words) < fingerprintSize {
+		return
+	}
+
+	if len(w.window) == winnowingWindow {
+		w.window = append(w.window[:0], w.window[1:]...)
+	}
+	w.window = append(w.window, fingerprint{
+		Hash:     shingleHash(w.words),
+		Position: w.count - fingerprintSize,
+		Start:    w.starts[0],
+		End:      end,
+	})
+	if len(w.window) == winnowingWindow {
+		w.selectMin()
+	}
+}
+
+// selectMin записывает k-грамму окна с наименьшим хешем, если она еще не записана.
+func (w *winnower) selectMin() {
+	best := 0
+	for i := 1; i < len(w.window); i++ {
+		if w.window[i].Hash <= w.window[best].Hash {
+			best = i
+		}
+	}
+	selected := w.window[best]
+	if selected.Position == w.lastSelected {
+		return
+	}
+	w.lastSelected = selected.Position
+	if len(w.fingerprints) >= maxFingerprints {
+		w.overflow = true
+		return
+	}
+	w.fingerprints = append(w.fingerprints, selected)
+}
+
+// finish возвращает выбранные отпечатки. Текст, в котором k-грамм меньше одного окна,
+// получает один отпечаток — k-грамму с наименьшим хешем.
+func (w *winnower) finish() []fingerprint {
+	if len(w.window) > 0 && len(w.window) < winnowingWindow {
+		w.selectMin()
+	}
+	return w.fingerprints
+}
@@ -0,0 +1,224 @@
+package services
+
+import (
+	"file_analysis_service/models"
+	"fmt"
+	"sort"
+	"testing"
+)
+
+// testWords возвращает n различных слов с префиксом prefix: общие k-граммы в тестах появляются только в скопированных фрагментах.
+func testWords(prefix string, n int) []string {
+	words := make([]string, n)
+	for i := range words {
+		words[i] = fmt.Sprintf("%s%d", prefix, i)
+	}
+	return words
+}
+
+func concatWords(parts ...[]string) []string {
+	var words []string
+	for _, part := range parts {
+		words = append(words, part...)
+	}
+	return words
+}
+
+// testFingerprints выбирает отпечатки текста из слов words. Смещения символов совпадают с номерами слов,
+// поэтому границы фрагментов в тестах выражаются в словах.
+func testFingerprints(fileID string, words []string) []models.FileFingerprint {
+	w := newWinnower()
+	for i, word := range words {
+		w.add(word, i, i+1)
+	}
+	var fingerprints []models.FileFingerprint
+	for _, fp := range w.finish() {
+		fingerprints = append(fingerprints, models.FileFingerprint{FileID: fileID, Hash: int64(fp.Hash), Position: fp.Position, Start: fp.Start, End: fp.End})
+	}
+	return fingerprints
+}
+
+// testPairs сопоставляет отпечатки так же, как findFingerprintPairs, но без БД.
+func testPairs(suspect, source []models.FileFingerprint) []fingerprintPair {
+	var pairs []fingerprintPair
+	for _, s := range suspect {
+		for _, m := range source {
+			if s.Hash == m.Hash {
+				pairs = append(pairs, fingerprintPair{suspect: s, source: m})
+			}
+		}
+	}
+	return pairs
+}
+
+func TestWinnowerSelectsFingerprintInEveryWindow(t *testing.T) {
+	tests := []struct {
+		words int
+		want  int // Ожидаемое количество отпечатков; -1 — не меньше одного
+	}{
+		{words: 0, want: 0},
+		{words: fingerprintSize - 1, want: 0},
+		{words: fingerprintSize, want: 1},
+		{words: fingerprintSize + winnowingWindow - 2, want: 1}, // k-грамм меньше одного окна
+		{words: fingerprintSize + winnowingWindow - 1, want: 1}, // ровно одно окно
+		{words: 200, want: -1},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d слов", tt.words), func(t *testing.T) {
+			fingerprints := testFingerprints("f", testWords("w", tt.words))
+			if tt.want >= 0 && len(fingerprints) != tt.want {
+				t.Fatalf("отпечатков: %d, ожидалось %d", len(fingerprints), tt.want)
+			}
+			if tt.want < 0 && len(fingerprints) == 0 {
+				t.Fatal("нет ни одного отпечатка")
+			}
+			for i, fp := range fingerprints {
+				if fp.End-fp.Start != fingerprintSize {
+					t.Errorf("отпечаток %d занимает [%d, %d), ожидалось %d слов", i, fp.Start, fp.End, fingerprintSize)
+				}
+				if i > 0 && fp.Position-fingerprints[i-1].Position > winnowingWindow {
+					t.Errorf("между отпечатками %d и %d больше %d k-грамм", fingerprints[i-1].Position, fp.Position, winnowingWindow)
+				}
+				if i > 0 && fp.Position <= fingerprints[i-1].Position {
+					t.Errorf("отпечатки не упорядочены или повторяются: %d после %d", fp.Position, fingerprints[i-1].Position)
+				}
+			}
+			if n := len(fingerprints); n > 0 && tt.words-fingerprintSize-fingerprints[n-1].Position >= winnowingWindow {
+				t.Errorf("после последнего отпечатка %d осталось целое окно без отпечатка", fingerprints[n-1].Position)
+			}
+		})
+	}
+}
+
+func TestMergePairs(t *testing.T) {
+	source := testWords("s", 60)
+	// region — скопированный фрагмент: слова [start, end) проверяемого файла взяты из источника начиная с sourceStart
+	type region struct{ start, end, sourceStart int }
+	tests := []struct {
+		name     string
+		suspect  []string
+		source   []string
+		minWords int
+		want     []region // Ожидаемые фрагменты в порядке положения в проверяемом файле
+	}{
+		{
+			name:     "полная копия",
+			suspect:  source[:40],
+			source:   source[:40],
+			minWords: DefaultPassageMinWords,
+			want:     []region{{0, 40, 0}},
+		},
+		{
+			name:     "копия внутри своего текста",
+			suspect:  concatWords(testWords("a", 10), source[5:35], testWords("b", 10)),
+			source:   source,
+			minWords: DefaultPassageMinWords,
+			want:     []region{{10, 40, 5}},
+		},
+		{
+			name:     "перекрывающиеся фрагменты источника",
+			suspect:  concatWords(source[0:25], testWords("a", 10), source[10:35]),
+			source:   source,
+			minWords: DefaultPassageMinWords,
+			want:     []region{{0, 25, 0}, {35, 60, 10}},
+		},
+		{
+			name:     "соседние фрагменты",
+			suspect:  concatWords(source[0:20], source[35:55]),
+			source:   source,
+			minWords: DefaultPassageMinWords,
+			want:     []region{{0, 20, 0}, {20, 40, 35}},
+		},
+		{
+			name:     "переставленные фрагменты",
+			suspect:  concatWords(source[30:50], testWords("a", 5), source[0:20]),
+			source:   source,
+			minWords: DefaultPassageMinWords,
+			want:     []region{{0, 20, 30}, {25, 45, 0}},
+		},
+		{
+			name:     "фрагмент короче minWords",
+			suspect:  concatWords(testWords("a", 10), source[0:12], testWords("b", 10)),
+			source:   source,
+			minWords: 20,
+			want:     nil,
+		},
+		{
+			name:     "тексты короче окна",
+			suspect:  source[:fingerprintSize+1],
+			source:   source[:fingerprintSize+1],
+			minWords: 1,
+			want:     []region{{0, fingerprintSize + 1, 0}},
+		},
+		{
+			name:     "тексты короче k-граммы",
+			suspect:  source[:fingerprintSize-1],
+			source:   source[:fingerprintSize-1],
+			minWords: 1,
+			want:     nil,
+		},
+		{
+			name:     "нет общих слов",
+			suspect:  testWords("a", 40),
+			source:   source,
+			minWords: 1,
+			want:     nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pairs := testPairs(testFingerprints("suspect", tt.suspect), testFingerprints("source", tt.source))
+			passages, words := mergePairs("source", pairs, tt.minWords)
+			if len(passages) != len(tt.want) {
+				t.Fatalf("фрагментов: %d, ожидалось %d: %+v", len(passages), len(tt.want), passages)
+			}
+			if len(words) != len(passages) {
+				t.Fatalf("интервалов слов: %d, фрагментов: %d", len(words), len(passages))
+			}
+			for i, passage := range passages {
+				if words[i] != [2]int{passage.Start, passage.End} {
+					t.Errorf("интервал слов %v не совпадает с фрагментом [%d, %d)", words[i], passage.Start, passage.End)
+				}
+			}
+			sort.Slice(passages, func(i, j int) bool { return passages[i].Start < passages[j].Start })
+
+			for i, want := range tt.want {
+				got := passages[i]
+				// Границы фрагмента определяются отпечатками, поэтому у краев копии может быть потеряно до winnowingWindow-1 слов
+				minLength := want.end - want.start - 2*(winnowingWindow-1)
+				if got.Start < want.start || got.End > want.end || got.Length < minLength || got.Length < tt.minWords {
+					t.Errorf("фрагмент %d: [%d, %d) длиной %d, ожидался внутри [%d, %d) длиной не меньше %d",
+						i, got.Start, got.End, got.Length, want.start, want.end, minLength)
+				}
+				if got.Length != got.End-got.Start || got.SourceEnd-got.SourceStart != got.Length {
+					t.Errorf("фрагмент %d: длина %d не совпадает с границами %+v", i, got.Length, got)
+				}
+				if got.SourceStart-got.Start != want.sourceStart-want.start {
+					t.Errorf("фрагмент %d: в источнике начинается с %d, ожидался сдвиг %d", i, got.SourceStart, want.sourceStart-want.start)
+				}
+				if got.SourceFileID != "source" {
+					t.Errorf("фрагмент %d: источник %q", i, got.SourceFileID)
+				}
+			}
+		})
+	}
+}
+
+func TestCoveredWords(t *testing.T) {
+	tests := []struct {
+		intervals [][2]int
+		want      int
+	}{
+		{nil, 0},
+		{[][2]int{{0, 10}}, 10},
+		{[][2]int{{0, 10}, {5, 15}}, 15},
+		{[][2]int{{10, 20}, {0, 10}}, 20},
+		{[][2]int{{0, 30}, {5, 10}}, 30},
+		{[][2]int{{20, 25}, {0, 5}, {3, 8}}, 13},
+	}
+	for _, tt := range tests {
+		if got := coveredWords(tt.intervals); got != tt.want {
+			t.Errorf("coveredWords(%v) = %d, ожидалось %d", tt.intervals, got, tt.want)
+		}
+	}
+}
@@ -24,23 +24,74 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/files": {
-            "get": {
-                "description": "Возвращает ID и имена всех загруженных файлов.",
+        "/batches": {
+            "post": {
+                "description": "Принимает несколько файлов в полях files формы multipart/form-data. Файлы .zip распаковываются: каждый файл архива\nподдерживаемого формата сохраняется как отдельный файл. Все сохраненные файлы относятся к одному именованному пакету.\nКаждый файл сохраняется независимо: ответ содержит результат для каждого файла. Ограничения: размер каждого файла — MAX_UPLOAD_SIZE,\nсуммарный размер файлов (и распакованного содержимого архивов) — MAX_BATCH_SIZE, количество файлов — MAX_BATCH_ENTRIES.",
+                "consumes": [
+                    "multipart/form-data"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "files"
                 ],
-                "summary": "Список всех файлов",
+                "summary": "Пакетная загрузка файлов",
+                "parameters": [
+                    {
+                        "type": "file",
+                        "description": "Файлы или ZIP-архивы (поле можно повторять)",
+                        "name": "files",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Имя пакета",
+                        "name": "name",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Поставить сохраненные файлы в очередь анализа",
+                        "name": "analyze",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID коллекции, в которую добавляются сохраненные файлы",
+                        "name": "collection_id",
+                        "in": "formData"
+                    }
+                ],
                 "responses": {
-                    "200": {
-                        "description": "Список файлов",
+                    "201": {
+                        "description": "Сохранен хотя бы один файл; результат для каждого файла",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/models.File"
+                            "$ref": "#/definitions/handlers.BatchUploadResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Не сохранен ни один файл, или запрос некорректен",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.BatchUploadResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Коллекция не найдена",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "413": {
+                        "description": "Суммарный размер запроса больше допустимого",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
                             }
                         }
                     },
@@ -56,40 +107,116 @@ const docTemplate = `{
                 }
             }
         },
-        "/files/upload": {
-            "post": {
-                "description": "Загружает текстовый файл, сохраняет его и возвращает ID.",
-                "consumes": [
-                    "multipart/form-data"
-                ],
+        "/batches/{id}": {
+            "get": {
+                "description": "Возвращает имя пакета и метаданные его неудаленных файлов.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "files"
                 ],
-                "summary": "Загрузка файла",
+                "summary": "Получение пакета по ID",
                 "parameters": [
                     {
-                        "type": "file",
-                        "description": "Файл для загрузки (только .txt)",
-                        "name": "file",
-                        "in": "formData",
+                        "type": "string",
+                        "description": "ID пакета",
+                        "name": "id",
+                        "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "ID загруженного файла",
+                    "200": {
+                        "description": "Пакет и его файлы",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.BatchResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Пакет не найден",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/collections": {
+            "get": {
+                "description": "Возвращает коллекции с количеством файлов в каждой: пользователю — только его коллекции, администратору — все.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "collections"
+                ],
+                "summary": "Список коллекций",
+                "responses": {
+                    "200": {
+                        "description": "Список коллекций",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.CollectionResponse"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
                                 "type": "string"
                             }
                         }
+                    }
+                }
+            },
+            "post": {
+                "description": "Создает именованную коллекцию (например, задание), в которую файлы добавляются при загрузке (поле collection_id)\nили позже запросом PUT /collections/{id}/files/{file_id}.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "collections"
+                ],
+                "summary": "Создание коллекции",
+                "parameters": [
+                    {
+                        "description": "Имя и описание коллекции",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CollectionRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Созданная коллекция",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CollectionResponse"
+                        }
                     },
                     "400": {
-                        "description": "Ошибка валидации или обработки файла",
+                        "description": "Некорректный запрос",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -109,20 +236,20 @@ const docTemplate = `{
                 }
             }
         },
-        "/files/{id}": {
+        "/collections/{id}": {
             "get": {
-                "description": "Возвращает содержимое файла по его ID.",
+                "description": "Возвращает коллекцию и метаданные ее неудаленных файлов.",
                 "produces": [
-                    "text/plain"
+                    "application/json"
                 ],
                 "tags": [
-                    "files"
+                    "collections"
                 ],
-                "summary": "Получение файла по ID",
+                "summary": "Получение коллекции",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "ID файла",
+                        "description": "ID коллекции",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -130,13 +257,55 @@ const docTemplate = `{
                 ],
                 "responses": {
                     "200": {
-                        "description": "Содержимое файла",
+                        "description": "Коллекция и ее файлы",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CollectionResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Коллекция не найдена",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Удаляет коллекцию; ее файлы остаются у пользователя без коллекции.",
+                "tags": [
+                    "collections"
+                ],
+                "summary": "Удаление коллекции",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID коллекции",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Коллекция удалена",
                         "schema": {
                             "type": "string"
                         }
                     },
                     "404": {
-                        "description": "Файл не найден",
+                        "description": "Коллекция не найдена",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -154,36 +323,55 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/internal/file-content": {
-            "get": {
-                "description": "Возвращает содержимое файла по его location.",
+            },
+            "patch": {
+                "description": "Изменяет имя и (или) описание коллекции; непереданные поля не меняются.",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
-                    "text/plain"
+                    "application/json"
                 ],
                 "tags": [
-                    "files"
+                    "collections"
                 ],
-                "summary": "Получение содержимого файла по location (внутренний)",
+                "summary": "Изменение коллекции",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Location файла",
-                        "name": "location",
-                        "in": "query",
+                        "description": "ID коллекции",
+                        "name": "id",
+                        "in": "path",
                         "required": true
+                    },
+                    {
+                        "description": "Новое имя и (или) описание",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UpdateCollectionRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Содержимое файла",
+                        "description": "Измененная коллекция",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/handlers.CollectionResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный запрос",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     },
                     "404": {
-                        "description": "Файл не найден по указанному location",
+                        "description": "Коллекция не найдена",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -203,28 +391,41 @@ const docTemplate = `{
                 }
             }
         },
-        "/internal/files/{id}/location": {
-            "get": {
-                "description": "Возвращает location файла по его ID для использования другими сервисами.",
+        "/collections/{id}/files/{file_id}": {
+            "put": {
+                "description": "Добавляет ранее загруженный файл в коллекцию. Файл, входивший в другую коллекцию, переносится в эту.\nФайл и коллекция должны принадлежать одному пользователю.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "files"
+                    "collections"
                 ],
-                "summary": "Получение location файла по ID (внутренний)",
+                "summary": "Добавление файла в коллекцию",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "ID файла",
+                        "description": "ID коллекции",
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID файла",
+                        "name": "file_id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Location файла",
+                        "description": "Метаданные файла",
+                        "schema": {
+                            "$ref": "#/definitions/models.File"
+                        }
+                    },
+                    "400": {
+                        "description": "Файл и коллекция принадлежат разным пользователям",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -233,7 +434,56 @@ const docTemplate = `{
                         }
                     },
                     "404": {
-                        "description": "Файл не найден",
+                        "description": "Коллекция или файл не найдены",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Исключает файл из коллекции; файл остается у пользователя без коллекции.",
+                "tags": [
+                    "collections"
+                ],
+                "summary": "Исключение файла из коллекции",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID коллекции",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID файла",
+                        "name": "file_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Файл исключен из коллекции",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Коллекция не найдена или файл не входит в нее",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -252,37 +502,1637 @@ const docTemplate = `{
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "models.File": {
-            "description": "Метаданные файла, хранящиеся в базе данных.",
-            "type": "object",
-            "properties": {
-                "created_at": {
-                    "type": "string"
-                },
-                "deleted_at": {
-                    "description": "Время удаления (если удален)",
-                    "type": "string",
-                    "example": "2023-01-01T14:00:00Z"
-                },
-                "id": {
-                    "type": "string",
-                    "example": "unique-file-id"
-                },
+        },
+        "/files": {
+            "get": {
+                "description": "Возвращает метаданные загруженных файлов постранично: пользователю — только его файлы, администратору — все.\nСтраницы выбираются по курсору: ссылка на следующую страницу возвращается в поле next, на последней странице ее нет.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "files"
+                ],
+                "summary": "Список файлов",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Количество файлов на странице (по умолчанию 50, не более 500)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Курсор следующей страницы из поля next_cursor",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "created_at",
+                            "name",
+                            "size"
+                        ],
+                        "type": "string",
+                        "description": "Поле сортировки (по умолчанию created_at)",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "description": "Порядок сортировки (по умолчанию desc)",
+                        "name": "order",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Подстрока имени файла (без учета регистра)",
+                        "name": "name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Файлы, загруженные не раньше этого времени (RFC 3339)",
+                        "name": "created_after",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Файлы, загруженные раньше этого времени (RFC 3339)",
+                        "name": "created_before",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID владельца (другого пользователя — только для администратора)",
+                        "name": "owner_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Только проанализированные (true) или непроанализированные (false) файлы",
+                        "name": "has_analysis",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID коллекции",
+                        "name": "collection_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID пакета",
+                        "name": "batch_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Страница списка файлов",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.FileListResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректные параметры запроса",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Файлы другого пользователя доступны только администратору",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/files/upload": {
+            "post": {
+                "description": "Загружает документ (.txt, .md, .html, .docx, .rtf или .pdf), сохраняет исходный файл и его текстовое представление,\nна котором затем выполняется анализ. Возвращает ID, SHA-256 хеш исходного файла, MIME-тип и использованный экстрактор.\nЕсли ранее уже был загружен файл с идентичным содержимым, ответ содержит ID и время загрузки самого раннего из них.\nТело запроса читается потоково. Файл больше MAX_UPLOAD_SIZE, не помещающийся в квоту пользователя или распаковывающийся больше чем в MAX_EXTRACTED_SIZE байт отклоняется с кодом 413.",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "files"
+                ],
+                "summary": "Загрузка файла",
+                "parameters": [
+                    {
+                        "type": "file",
+                        "description": "Файл для загрузки (.txt, .md, .html, .docx, .rtf, .pdf)",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID коллекции, в которую добавляется файл",
+                        "name": "collection_id",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "ID загруженного файла и сведения о дубликате",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UploadFileResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Ошибка валидации или извлечения текста",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Коллекция не найдена",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "413": {
+                        "description": "Файл больше допустимого размера или превышена квота хранилища",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/files/{id}": {
+            "get": {
+                "description": "Возвращает исходный файл по его ID с Content-Type, соответствующим его MIME-типу.",
+                "produces": [
+                    "text/plain",
+                    "application/octet-stream"
+                ],
+                "tags": [
+                    "files"
+                ],
+                "summary": "Получение файла по ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID файла",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Содержимое файла",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "404": {
+                        "description": "Файл не найден",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Помечает файл удаленным: он больше не выдается, не анализируется и не учитывается при поиске дубликатов.\nРезультаты анализа и облака слов файла удаляются File Analysis Service по событию file.deleted, а содержимое файла удаляется из хранилища\nи запись — из БД фоновой очисткой по истечении срока хранения (FILE_RETENTION).",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "files"
+                ],
+                "summary": "Удаление файла",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID файла",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Файл помечен удаленным, очистка запланирована",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.DeleteFileResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Файл не найден",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/files/{id}/text": {
+            "get": {
+                "description": "Возвращает нормализованный текст, извлеченный из файла при загрузке. Именно он используется при анализе.",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "files"
+                ],
+                "summary": "Получение текста файла по ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID файла",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Текст файла",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Файл не найден",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/internal/collections/{id}/files": {
+            "get": {
+                "description": "Возвращает владельца коллекции и ID ее неудаленных файлов.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "collections"
+                ],
+                "summary": "Получение файлов коллекции (внутренний)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID коллекции",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Владелец и файлы коллекции",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CollectionFilesResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Коллекция не найдена",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/internal/files/owners": {
+            "post": {
+                "description": "Возвращает ID владельца для каждого из найденных файлов. Удаленные и несуществующие файлы в ответ не попадают.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "files"
+                ],
+                "summary": "Получение владельцев файлов (внутренний)",
+                "parameters": [
+                    {
+                        "description": "ID файлов",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.FileOwnersRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Владельцы файлов (owners: ID файла -\u003e ID владельца)",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный запрос",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/internal/files/{id}/analysis": {
+            "put": {
+                "description": "Записывает время анализа файла (analyzed=true) или сбрасывает его (analyzed=false).",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "files"
+                ],
+                "summary": "Отметка об анализе файла (внутренний)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID файла",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Есть ли у файла результаты анализа",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.FileAnalysisStatusRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Отметка сохранена",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный запрос",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Файл не найден",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/internal/files/{id}/content": {
+            "get": {
+                "description": "Потоково отдает нормализованный текст файла с заголовками Content-Length и ETag.\nПоддерживаются запросы части текста (Range, If-Range) и условные запросы (If-None-Match).",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "files"
+                ],
+                "summary": "Получение текста файла по ID (внутренний)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID файла",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Текст файла",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "206": {
+                        "description": "Запрошенная часть текста",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "304": {
+                        "description": "Текст не изменился",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Файл не найден",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "416": {
+                        "description": "Запрошенный диапазон вне текста",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/search": {
+            "get": {
+                "description": "Ищет файлы, извлеченный текст которых соответствует запросу, и возвращает их в порядке убывания релевантности\nс фрагментами текста, в которых совпадения выделены тегом \u003cmark\u003e (остальной текст экранирован для HTML).\nСлова нормализуются: «революции» находит «революция». Синтаксис запроса: слова через пробел должны встречаться все;\n\"фраза в кавычках\" — слова подряд; слово* — слова с таким началом; -слово — исключить; OR — любое из условий.\nПользователь ищет только среди своих файлов, администратор — среди всех.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "search"
+                ],
+                "summary": "Полнотекстовый поиск по файлам",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "\"промышленная революция\" Англи*",
+                        "description": "Поисковый запрос",
+                        "name": "q",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID владельца (другого пользователя — только для администратора)",
+                        "name": "owner_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Количество файлов на странице (по умолчанию 20, не более 50)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Количество пропускаемых файлов (по умолчанию 0)",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Страница найденных файлов",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.SearchResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный запрос",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Файлы другого пользователя доступны только администратору",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/uploads": {
+            "post": {
+                "description": "Создает сессию, в которую файл загружается частями запросами PATCH /uploads/{id}. Размер файла и квота пользователя\nпроверяются сразу; в квоте учитываются и файлы других незавершенных сессий пользователя.\nНезавершенная сессия удаляется, если в нее дольше UPLOAD_SESSION_TTL не поступало частей файла.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "uploads"
+                ],
+                "summary": "Создание сессии возобновляемой загрузки",
+                "parameters": [
+                    {
+                        "description": "Имя, размер и SHA-256 хеш файла",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CreateUploadSessionRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Созданная сессия загрузки",
+                        "schema": {
+                            "$ref": "#/definitions/models.UploadSession"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный запрос или неподдерживаемый формат файла",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Коллекция не найдена",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "413": {
+                        "description": "Файл больше допустимого размера или превышена квота хранилища",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "429": {
+                        "description": "Слишком много незавершенных сессий загрузки",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/uploads/{id}": {
+            "get": {
+                "description": "Возвращает сессию загрузки и количество уже полученных байт (offset), с которого нужно продолжить загрузку.\nСмещение, размер файла и срок жизни сессии передаются также в заголовках Upload-Offset, Upload-Length и Upload-Expires,\nпоэтому для возобновления загрузки достаточно запроса HEAD.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "uploads"
+                ],
+                "summary": "Состояние сессии загрузки",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID сессии загрузки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Сессия загрузки",
+                        "schema": {
+                            "$ref": "#/definitions/models.UploadSession"
+                        }
+                    },
+                    "404": {
+                        "description": "Сессия загрузки не найдена",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Удаляет сессию загрузки и полученные части файла.",
+                "tags": [
+                    "uploads"
+                ],
+                "summary": "Отмена возобновляемой загрузки",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID сессии загрузки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Сессия загрузки удалена",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Сессия загрузки не найдена",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "В сессию загружается часть файла",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "head": {
+                "description": "Возвращает сессию загрузки и количество уже полученных байт (offset), с которого нужно продолжить загрузку.\nСмещение, размер файла и срок жизни сессии передаются также в заголовках Upload-Offset, Upload-Length и Upload-Expires,\nпоэтому для возобновления загрузки достаточно запроса HEAD.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "uploads"
+                ],
+                "summary": "Состояние сессии загрузки",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID сессии загрузки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Сессия загрузки",
+                        "schema": {
+                            "$ref": "#/definitions/models.UploadSession"
+                        }
+                    },
+                    "404": {
+                        "description": "Сессия загрузки не найдена",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Дописывает тело запроса к файлу сессии. Заголовок Upload-Offset должен совпадать с количеством уже полученных байт,\nиначе возвращается 409 с текущим смещением в Upload-Offset. Если соединение прервалось, полученные байты сохраняются:\nтекущее смещение можно узнать запросом HEAD /uploads/{id}. Новое смещение возвращается в заголовке Upload-Offset.",
+                "consumes": [
+                    "application/offset+octet-stream"
+                ],
+                "tags": [
+                    "uploads"
+                ],
+                "summary": "Загрузка части файла",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID сессии загрузки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Смещение части в файле",
+                        "name": "Upload-Offset",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Часть файла сохранена",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный заголовок Upload-Offset",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Сессия загрузки не найдена",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Смещение не совпадает с количеством полученных байт или в сессию уже загружается другая часть",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "413": {
+                        "description": "Часть выходит за пределы размера файла",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "415": {
+                        "description": "Тип содержимого не application/offset+octet-stream",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/uploads/{id}/complete": {
+            "post": {
+                "description": "Проверяет, что получен весь файл и его SHA-256 хеш совпадает с указанным при создании сессии, после чего сохраняет файл\nтак же, как POST /files/upload, и удаляет сессию. При несовпадении хеша сессия удаляется и загрузку нужно начать заново.\nЕсли файл не удалось сохранить (например, содержимое не соответствует формату), сессия остается до удаления или истечения срока.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "uploads"
+                ],
+                "summary": "Завершение возобновляемой загрузки",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID сессии загрузки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "ID сохраненного файла и сведения о дубликате",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UploadFileResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Хеш файла не совпадает, ошибка валидации или извлечения текста",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Сессия загрузки или коллекция не найдена",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Файл получен не полностью или сессия занята",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "413": {
+                        "description": "Превышена квота хранилища",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/usage": {
+            "get": {
+                "description": "Возвращает суммарный размер неудаленных файлов пользователя, его квоту хранилища и максимальный размер загружаемого файла.\nАдминистратор может запросить сведения о другом пользователе параметром owner_id.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "files"
+                ],
+                "summary": "Объем файлов и квота пользователя",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID пользователя (только для администратора)",
+                        "name": "owner_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Объем файлов и квота",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.StorageUsageResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Сведения о другом пользователе доступны только администратору",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "handlers.BatchEntryResult": {
+            "description": "Результат загрузки файла пакета: сведения о сохраненном файле или ошибка.",
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "Неверный формат файла. Допускаются: .docx, .html, .md, .pdf, .rtf, .txt."
+                },
+                "file": {
+                    "$ref": "#/definitions/handlers.UploadFileResponse"
+                },
+                "name": {
+                    "description": "Имя файла; для файла из архива — имя архива и путь внутри него",
+                    "type": "string",
+                    "example": "essays.zip/ivanov.docx"
+                },
+                "status": {
+                    "description": "HTTP-статус, с которым завершилась бы загрузка этого файла по отдельности",
+                    "type": "integer",
+                    "example": 201
+                }
+            }
+        },
+        "handlers.BatchResponse": {
+            "description": "Пакет файлов и метаданные его неудаленных файлов.",
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "files": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.File"
+                    }
+                },
+                "id": {
+                    "type": "string",
+                    "example": "batch-id"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "Группа 101, эссе"
+                },
+                "owner_id": {
+                    "type": "string",
+                    "example": "user-42"
+                }
+            }
+        },
+        "handlers.BatchUploadResponse": {
+            "description": "ID и имя пакета и результат загрузки каждого файла.",
+            "type": "object",
+            "properties": {
+                "analyze": {
+                    "description": "Поставлены ли сохраненные файлы в очередь анализа",
+                    "type": "boolean",
+                    "example": true
+                },
+                "failed": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "files": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.BatchEntryResult"
+                    }
+                },
+                "id": {
+                    "description": "Пусто, если ни один файл не сохранен",
+                    "type": "string",
+                    "example": "batch-id"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "Группа 101, эссе"
+                },
+                "uploaded": {
+                    "type": "integer",
+                    "example": 24
+                }
+            }
+        },
+        "handlers.CollectionFilesResponse": {
+            "description": "ID коллекции, ее владелец и ID ее неудаленных файлов.",
+            "type": "object",
+            "properties": {
+                "file_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "unique-file-id",
+                        "other-file-id"
+                    ]
+                },
+                "id": {
+                    "type": "string",
+                    "example": "collection-id"
+                },
+                "owner_id": {
+                    "type": "string",
+                    "example": "user-42"
+                }
+            }
+        },
+        "handlers.CollectionRequest": {
+            "description": "Имя и необязательное описание коллекции.",
+            "type": "object",
+            "required": [
+                "name"
+            ],
+            "properties": {
+                "description": {
+                    "type": "string",
+                    "example": "Эссе по истории, группа 101"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "Задание 3"
+                }
+            }
+        },
+        "handlers.CollectionResponse": {
+            "description": "Коллекция, количество ее неудаленных файлов и, при запросе одной коллекции, их метаданные.",
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string",
+                    "example": "Эссе по истории, группа 101"
+                },
+                "file_count": {
+                    "type": "integer",
+                    "example": 25
+                },
+                "files": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.File"
+                    }
+                },
+                "id": {
+                    "type": "string",
+                    "example": "collection-id"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "Задание 3"
+                },
+                "owner_id": {
+                    "type": "string",
+                    "example": "user-42"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.CreateUploadSessionRequest": {
+            "description": "Имя и полный размер файла и его SHA-256 хеш, который проверяется при завершении загрузки.",
+            "type": "object",
+            "required": [
+                "name",
+                "sha256"
+            ],
+            "properties": {
+                "collection_id": {
+                    "description": "ID коллекции, в которую войдет сохраненный файл",
+                    "type": "string",
+                    "example": "collection-id"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "example.pdf"
+                },
+                "sha256": {
+                    "type": "string",
+                    "example": "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+                },
+                "size": {
+                    "description": "Полный размер файла в байтах",
+                    "type": "integer",
+                    "example": 1048576
+                }
+            }
+        },
+        "handlers.DeleteFileResponse": {
+            "description": "Время удаления файла и время, не раньше которого он будет окончательно удален.",
+            "type": "object",
+            "properties": {
+                "deleted_at": {
+                    "type": "string",
+                    "example": "2023-01-01T14:00:00Z"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "unique-file-id"
+                },
+                "purge_after": {
+                    "description": "Не раньше этого времени содержимое файла будет удалено из хранилища",
+                    "type": "string",
+                    "example": "2023-01-31T14:00:00Z"
+                }
+            }
+        },
+        "handlers.DuplicateInfo": {
+            "description": "Сведения о самом раннем файле с тем же SHA-256 хешем.",
+            "type": "object",
+            "properties": {
+                "file_id": {
+                    "type": "string",
+                    "example": "original-file-id"
+                },
+                "uploaded_at": {
+                    "type": "string",
+                    "example": "2023-01-01T12:00:00Z"
+                }
+            }
+        },
+        "handlers.FileAnalysisStatusRequest": {
+            "description": "Есть ли у файла результаты анализа.",
+            "type": "object",
+            "properties": {
+                "analyzed": {
+                    "type": "boolean",
+                    "example": true
+                }
+            }
+        },
+        "handlers.FileListResponse": {
+            "description": "Файлы текущей страницы, общее количество файлов, подходящих под фильтры, и курсор следующей страницы.",
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.File"
+                    }
+                },
+                "next": {
+                    "description": "Ссылка на следующую страницу относительно текущего пути",
+                    "type": "string",
+                    "example": "?cursor=eyJzIjoiY3JlYXRlZF9hdCJ9\u0026limit=50\u0026order=desc\u0026sort=created_at"
+                },
+                "next_cursor": {
+                    "description": "Курсор следующей страницы; пусто на последней странице",
+                    "type": "string",
+                    "example": "eyJzIjoiY3JlYXRlZF9hdCJ9"
+                },
+                "total": {
+                    "description": "Количество файлов, подходящих под фильтры, на всех страницах",
+                    "type": "integer",
+                    "example": 120
+                }
+            }
+        },
+        "handlers.FileOwnersRequest": {
+            "description": "Список ID файлов, владельцев которых нужно получить.",
+            "type": "object",
+            "required": [
+                "ids"
+            ],
+            "properties": {
+                "ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "unique-file-id",
+                        "other-file-id"
+                    ]
+                }
+            }
+        },
+        "handlers.SearchResponse": {
+            "description": "Найденные файлы в порядке убывания релевантности, их общее количество и ссылка на следующую страницу.",
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/services.SearchHit"
+                    }
+                },
+                "next": {
+                    "description": "Ссылка на следующую страницу относительно текущего пути; нет на последней странице",
+                    "type": "string",
+                    "example": "?limit=20\u0026offset=20\u0026q=история"
+                },
+                "total": {
+                    "description": "Количество найденных файлов на всех страницах",
+                    "type": "integer",
+                    "example": 12
+                }
+            }
+        },
+        "handlers.StorageUsageResponse": {
+            "description": "Объем неудаленных файлов пользователя, его квота хранилища и максимальный размер загружаемого файла.",
+            "type": "object",
+            "properties": {
+                "max_file_size": {
+                    "type": "integer",
+                    "example": 104857600
+                },
+                "owner_id": {
+                    "type": "string",
+                    "example": "user-42"
+                },
+                "quota_bytes": {
+                    "description": "0 — без ограничения",
+                    "type": "integer",
+                    "example": 1073741824
+                },
+                "used_bytes": {
+                    "type": "integer",
+                    "example": 1048576
+                }
+            }
+        },
+        "handlers.UpdateCollectionRequest": {
+            "description": "Новое имя и (или) описание коллекции.",
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string",
+                    "example": "Эссе по истории, группа 101"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "Задание 3"
+                }
+            }
+        },
+        "handlers.UploadFileResponse": {
+            "description": "ID загруженного файла, хеш его содержимого и сведения о дубликате, если он найден.",
+            "type": "object",
+            "properties": {
+                "duplicate_of": {
+                    "$ref": "#/definitions/handlers.DuplicateInfo"
+                },
+                "extractor": {
+                    "type": "string",
+                    "example": "pdf"
+                },
+                "hash": {
+                    "type": "string",
+                    "example": "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "unique-file-id"
+                },
+                "is_duplicate": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "mime_type": {
+                    "type": "string",
+                    "example": "application/pdf"
+                }
+            }
+        },
+        "models.File": {
+            "description": "Метаданные файла, хранящиеся в базе данных.",
+            "type": "object",
+            "properties": {
+                "analyzed_at": {
+                    "description": "Время последнего успешного анализа; сообщается FileAnalysisService",
+                    "type": "string",
+                    "example": "2023-01-01T12:05:00Z"
+                },
+                "batch_id": {
+                    "description": "ID пакета, если файл загружен пакетной загрузкой",
+                    "type": "string",
+                    "example": "batch-id"
+                },
+                "collection_id": {
+                    "description": "ID коллекции, в которую входит файл",
+                    "type": "string",
+                    "example": "collection-id"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "deleted_at": {
+                    "description": "Время удаления (если удален)",
+                    "type": "string",
+                    "example": "2023-01-01T14:00:00Z"
+                },
+                "duplicate_of": {
+                    "description": "ID самого раннего файла того же владельца с идентичным содержимым",
+                    "type": "string",
+                    "example": "original-file-id"
+                },
+                "extractor": {
+                    "description": "Имя экстрактора, извлекшего текст",
+                    "type": "string",
+                    "example": "plain"
+                },
+                "hash": {
+                    "description": "SHA-256 содержимого в hex",
+                    "type": "string",
+                    "example": "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "unique-file-id"
+                },
                 "location": {
+                    "description": "Ключ исходного файла в хранилище (не зависит от типа хранилища)",
+                    "type": "string",
+                    "example": "unique-file-id.txt"
+                },
+                "mime_type": {
+                    "description": "MIME-тип исходного файла",
                     "type": "string",
-                    "example": "/app/file_storage_1/unique-file-id.txt"
+                    "example": "text/plain"
                 },
                 "name": {
                     "type": "string",
                     "example": "example.txt"
                 },
+                "owner_id": {
+                    "description": "ID пользователя, загрузившего файл; пустой у файлов, загруженных до появления владельцев",
+                    "type": "string",
+                    "example": "user-42"
+                },
+                "size": {
+                    "description": "Размер исходного файла в байтах; 0 у файлов, загруженных до учета квот",
+                    "type": "integer",
+                    "example": 1048576
+                },
+                "text_location": {
+                    "description": "Ключ нормализованного текста, на котором выполняется анализ",
+                    "type": "string",
+                    "example": "unique-file-id_text.txt"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.UploadSession": {
+            "description": "Сессия возобновляемой загрузки: имя и размер файла, ожидаемый SHA-256 хеш и количество уже полученных байт.",
+            "type": "object",
+            "properties": {
+                "collection_id": {
+                    "description": "Коллекция, в которую войдет сохраненный файл",
+                    "type": "string",
+                    "example": "collection-id"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "description": "После этого времени незавершенная сессия удаляется",
+                    "type": "string",
+                    "example": "2023-01-02T12:00:00Z"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "upload-session-id"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "example.pdf"
+                },
+                "offset": {
+                    "description": "Сколько байт файла уже получено",
+                    "type": "integer",
+                    "example": 524288
+                },
+                "owner_id": {
+                    "description": "ID пользователя, создавшего сессию",
+                    "type": "string",
+                    "example": "user-42"
+                },
+                "sha256": {
+                    "description": "Ожидаемый SHA-256 файла в hex",
+                    "type": "string",
+                    "example": "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+                },
+                "size": {
+                    "description": "Полный размер файла в байтах",
+                    "type": "integer",
+                    "example": 1048576
+                },
                 "updated_at": {
                     "type": "string"
                 }
             }
+        },
+        "services.SearchHit": {
+            "description": "Файл, текст которого соответствует запросу, с оценкой релевантности и фрагментами текста.",
+            "type": "object",
+            "properties": {
+                "collection_id": {
+                    "type": "string",
+                    "example": "collection-id"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "file_id": {
+                    "type": "string",
+                    "example": "unique-file-id"
+                },
+                "mime_type": {
+                    "type": "string",
+                    "example": "application/pdf"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "essay.pdf"
+                },
+                "owner_id": {
+                    "type": "string",
+                    "example": "user-42"
+                },
+                "rank": {
+                    "description": "Релевантность (ts_rank_cd); чем больше, тем выше файл в списке",
+                    "type": "number",
+                    "example": 0.42
+                },
+                "snippet": {
+                    "description": "Фрагменты текста с совпадениями в \u003cmark\u003e; остальной текст экранирован для HTML",
+                    "type": "string",
+                    "example": "… история \u003cmark\u003eпромышленной\u003c/mark\u003e \u003cmark\u003eреволюции\u003c/mark\u003e в Англии …"
+                }
+            }
+        },
+        "services.SearchRequest": {
+            "type": "object",
+            "properties": {
+                "limit": {
+                    "type": "integer"
+                },
+                "offset": {
+                    "type": "integer"
+                },
+                "ownerID": {
+                    "description": "Искать только среди файлов этого владельца; пусто — среди всех файлов",
+                    "type": "string"
+                },
+                "query": {
+                    "description": "Запрос в синтаксисе ParseSearchQuery",
+                    "type": "string"
+                }
+            }
         }
     }
 }`
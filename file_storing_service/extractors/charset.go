@@ -0,0 +1,57 @@
+package extractors
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// cp1251High — символы кодировки Windows-1251 для байтов 0x80–0xBF.
+// Байты 0xC0–0xFF соответствуют буквам А–я подряд.
+var cp1251High = [64]rune{
+	0x0402, 0x0403, 0x201A, 0x0453, 0x201E, 0x2026, 0x2020, 0x2021, 0x20AC, 0x2030, 0x0409, 0x2039, 0x040A, 0x040C, 0x040B, 0x040F,
+	0x0452, 0x2018, 0x2019, 0x201C, 0x201D, 0x2022, 0x2013, 0x2014, 0xFFFD, 0x2122, 0x0459, 0x203A, 0x045A, 0x045C, 0x045B, 0x045F,
+	0x00A0, 0x040E, 0x045E, 0x0408, 0x00A4, 0x0490, 0x00A6, 0x00A7, 0x0401, 0x00A9, 0x0404, 0x00AB, 0x00AC, 0x00AD, 0x00AE, 0x0407,
+	0x00B0, 0x00B1, 0x0406, 0x0456, 0x0491, 0x00B5, 0x00B6, 0x00B7, 0x0451, 0x2116, 0x0454, 0x00BB, 0x0458, 0x0405, 0x0455, 0x0457,
+}
+
+// cp1252High — символы кодировки Windows-1252 для байтов 0x80–0x9F.
+// Байты 0xA0–0xFF совпадают с Latin-1.
+var cp1252High = [32]rune{
+	0x20AC, 0xFFFD, 0x201A, 0x0192, 0x201E, 0x2026, 0x2020, 0x2021, 0x02C6, 0x2030, 0x0160, 0x2039, 0x0152, 0xFFFD, 0x017D, 0xFFFD,
+	0xFFFD, 0x2018, 0x2019, 0x201C, 0x201D, 0x2022, 0x2013, 0x2014, 0x02DC, 0x2122, 0x0161, 0x203A, 0x0153, 0xFFFD, 0x017E, 0x0178,
+}
+
+// decodeByte декодирует байт однобайтовой кодировки: 1251 (кириллица) или 1252 (все остальные).
+func decodeByte(b byte, codepage int) rune {
+	switch {
+	case b < 0x80:
+		return rune(b)
+	case codepage == 1251 && b >= 0xC0:
+		return 0x0410 + rune(b-0xC0)
+	case codepage == 1251:
+		return cp1251High[b-0x80]
+	case b < 0xA0:
+		return cp1252High[b-0x80]
+	default:
+		return rune(b)
+	}
+}
+
+// decodeBytes декодирует строку однобайтовой кодировки.
+func decodeBytes(data []byte, codepage int) string {
+	var sb strings.Builder
+	sb.Grow(len(data))
+	for _, b := range data {
+		sb.WriteRune(decodeByte(b, codepage))
+	}
+	return sb.String()
+}
+
+// toUTF8 возвращает текст в UTF-8. Если данные не являются корректным UTF-8,
+// они считаются текстом в кодировке Windows-1251 — самой распространенной для русскоязычных файлов.
+func toUTF8(data []byte) string {
+	if utf8.Valid(data) {
+		return string(data)
+	}
+	return decodeBytes(data, 1251)
+}
@@ -0,0 +1,101 @@
+package extractors
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// DocxExtractor обрабатывает документы Microsoft Word (.docx): читает текст абзацев из word/document.xml.
+type DocxExtractor struct{}
+
+func (DocxExtractor) Name() string { return "docx" }
+func (DocxExtractor) MimeType() string {
+	return "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+}
+func (DocxExtractor) Extensions() []string { return []string{".docx"} }
+
+// Match проверяет сигнатуру ZIP-архива, в который упакован документ.
+func (DocxExtractor) Match(head []byte) bool { return bytes.HasPrefix(head, []byte("PK\x03\x04")) }
+
+// Extract возвращает текст документа. Каждый абзац Word (в том числе абзац внутри ячейки таблицы)
+// становится отдельным абзацем текста. Удаленный в режиме рецензирования текст пропускается.
+// Из архива распаковывается не больше limit байт word/document.xml, поэтому ZIP-бомба отклоняется,
+// не успев занять память.
+func (DocxExtractor) Extract(r io.ReaderAt, size int64, limit int64) (string, error) {
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return "", fmt.Errorf("файл не является корректным документом DOCX: %w", err)
+	}
+
+	var document *zip.File
+	for _, f := range archive.File {
+		if f.Name == "word/document.xml" {
+			document = f
+			break
+		}
+	}
+	if document == nil {
+		return "", errors.New("в архиве DOCX отсутствует word/document.xml")
+	}
+
+	rc, err := document.Open()
+	if err != nil {
+		return "", fmt.Errorf("не удалось открыть word/document.xml: %w", err)
+	}
+	defer rc.Close()
+
+	var sb strings.Builder
+	decoder := xml.NewDecoder(limitReader(rc, limit))
+	inText := false
+	runDepth := 0 // Табуляции и переносы учитываются только внутри фрагментов текста w:r, а не в свойствах абзаца
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if errors.Is(err, ErrDocumentTooLarge) {
+			return "", fmt.Errorf("%w: word/document.xml больше %d байт", ErrDocumentTooLarge, limit)
+		}
+		if err != nil {
+			return "", fmt.Errorf("ошибка разбора word/document.xml: %w", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "r":
+				runDepth++
+			case "t":
+				inText = true
+			case "tab":
+				if runDepth > 0 {
+					sb.WriteByte('\t')
+				}
+			case "br", "cr":
+				if runDepth > 0 {
+					sb.WriteByte('\n')
+				}
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "r":
+				runDepth--
+			case "t":
+				inText = false
+			case "p":
+				sb.WriteString("\n\n")
+			}
+		case xml.CharData:
+			// Текст документа хранится только в элементах w:t; w:delText (удаленный текст) не учитывается
+			if inText {
+				sb.Write(t)
+			}
+		}
+	}
+	return limitText(normalizeText(sb.String()), limit)
+}
@@ -0,0 +1,178 @@
+// Package extractors извлекает обычный текст из загружаемых документов разных форматов.
+package extractors
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ErrUnsupportedFormat возвращается, если для расширения файла нет зарегистрированного экстрактора.
+var ErrUnsupportedFormat = errors.New("неподдерживаемый формат файла")
+
+// ErrContentMismatch возвращается, если содержимое файла не соответствует его расширению.
+var ErrContentMismatch = errors.New("содержимое файла не соответствует его расширению")
+
+// ErrDocumentTooLarge возвращается, если документ распаковывается в больший объем данных или дает больше текста,
+// чем разрешено (например, ZIP-бомба в DOCX или сжатые потоки PDF).
+var ErrDocumentTooLarge = errors.New("документ распаковывается в слишком большой объем данных")
+
+// DefaultExpansionRatio — во сколько раз по умолчанию объем распакованных данных документа и извлеченного
+// из него текста может превышать максимальный размер загружаемого файла.
+const DefaultExpansionRatio = 10
+
+// Extractor извлекает текст из документа одного формата.
+type Extractor interface {
+	// Name возвращает короткое имя экстрактора, которое сохраняется в метаданных файла.
+	Name() string
+	// MimeType возвращает MIME-тип формата.
+	MimeType() string
+	// Extensions возвращает расширения файлов (в нижнем регистре, с точкой), которые обрабатывает экстрактор.
+	Extensions() []string
+	// Match проверяет по первым байтам файла, что содержимое действительно имеет этот формат.
+	Match(head []byte) bool
+	// Extract извлекает из документа обычный текст. Абзацы разделяются пустой строкой.
+	// limit — сколько байт можно распаковать из сжатых частей документа и наибольший размер извлеченного текста;
+	// при превышении возвращается ErrDocumentTooLarge.
+	Extract(r io.ReaderAt, size int64, limit int64) (string, error)
+}
+
+// Registry сопоставляет расширения файлов с экстракторами.
+type Registry struct {
+	byExt map[string]Extractor
+}
+
+// NewRegistry создает реестр с указанными экстракторами.
+func NewRegistry(extractors ...Extractor) *Registry {
+	r := &Registry{byExt: make(map[string]Extractor)}
+	for _, e := range extractors {
+		r.Register(e)
+	}
+	return r
+}
+
+// DefaultRegistry возвращает реестр со всеми встроенными экстракторами:
+// .txt, .md, .html, .docx, .rtf и .pdf.
+func DefaultRegistry() *Registry {
+	return NewRegistry(
+		PlainTextExtractor{},
+		MarkdownExtractor{},
+		HTMLExtractor{},
+		DocxExtractor{},
+		RTFExtractor{},
+		PDFExtractor{},
+	)
+}
+
+// Register добавляет экстрактор в реестр. Экстрактор, зарегистрированный позже, заменяет прежний для тех же расширений.
+func (r *Registry) Register(e Extractor) {
+	for _, ext := range e.Extensions() {
+		r.byExt[strings.ToLower(ext)] = e
+	}
+}
+
+// Extensions возвращает отсортированный список поддерживаемых расширений.
+func (r *Registry) Extensions() []string {
+	exts := make([]string, 0, len(r.byExt))
+	for ext := range r.byExt {
+		exts = append(exts, ext)
+	}
+	sort.Strings(exts)
+	return exts
+}
+
+// Supports проверяет, есть ли экстрактор для расширения имени файла.
+func (r *Registry) Supports(filename string) bool {
+	_, ok := r.byExt[strings.ToLower(filepath.Ext(filename))]
+	return ok
+}
+
+// Detect выбирает экстрактор по расширению имени файла и проверяет, что первые байты содержимого соответствуют формату.
+func (r *Registry) Detect(filename string, head []byte) (Extractor, error) {
+	ext := strings.ToLower(filepath.Ext(filename))
+	e, ok := r.byExt[ext]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedFormat, ext)
+	}
+	if !e.Match(head) {
+		return nil, fmt.Errorf("%w: ожидается %s", ErrContentMismatch, e.MimeType())
+	}
+	return e, nil
+}
+
+// isText проверяет, что содержимое похоже на текст, а не на двоичные данные.
+func isText(head []byte) bool {
+	return strings.HasPrefix(http.DetectContentType(head), "text/")
+}
+
+var (
+	trailingSpaceRe = regexp.MustCompile(`[ \t]+\n`)
+	blankLinesRe    = regexp.MustCompile(`\n{3,}`)
+)
+
+// normalizeText приводит извлеченный текст к единому виду: без BOM, с переводами строк \n,
+// без пробелов в конце строк и не более чем одной пустой строкой между абзацами.
+func normalizeText(text string) string {
+	text = strings.TrimPrefix(text, "\ufeff")
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+	text = strings.ReplaceAll(text, "\u00a0", " ")
+	text = trailingSpaceRe.ReplaceAllString(text, "\n")
+	text = blankLinesRe.ReplaceAllString(text, "\n\n")
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return ""
+	}
+	return text + "\n"
+}
+
+// readAll читает документ целиком.
+func readAll(r io.ReaderAt, size int64) ([]byte, error) {
+	data, err := io.ReadAll(io.NewSectionReader(r, 0, size))
+	if err != nil {
+		return nil, fmt.Errorf("не удалось прочитать файл: %w", err)
+	}
+	return data, nil
+}
+
+// limitText возвращает text, если он не больше limit байт, и ErrDocumentTooLarge иначе.
+func limitText(text string, limit int64) (string, error) {
+	if int64(len(text)) > limit {
+		return "", fmt.Errorf("%w: извлеченный текст больше %d байт", ErrDocumentTooLarge, limit)
+	}
+	return text, nil
+}
+
+// limitReader возвращает reader, читающий из r не больше limit байт. Если в r есть данные сверх лимита,
+// чтение завершается ошибкой ErrDocumentTooLarge, а не обрезает данные молча, как io.LimitReader.
+func limitReader(r io.Reader, limit int64) io.Reader {
+	return &sizeLimitedReader{r: r, remaining: limit}
+}
+
+type sizeLimitedReader struct {
+	r         io.Reader
+	remaining int64
+}
+
+func (l *sizeLimitedReader) Read(p []byte) (int, error) {
+	if l.remaining <= 0 {
+		// Лимит исчерпан: ошибка, только если в r осталось что-то еще
+		var probe [1]byte
+		n, err := l.r.Read(probe[:])
+		if n > 0 {
+			return 0, ErrDocumentTooLarge
+		}
+		return 0, err
+	}
+	if int64(len(p)) > l.remaining {
+		p = p[:l.remaining]
+	}
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	return n, err
+}
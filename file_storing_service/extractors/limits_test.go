@@ -0,0 +1,87 @@
+package extractors
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// docxWithBody собирает минимальный DOCX, в word/document.xml которого тело документа body.
+func docxWithBody(t *testing.T, body string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	w, err := archive.Create("word/document.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Fprintf(w, `<?xml version="1.0"?><w:document xmlns:w="w"><w:body>%s</w:body></w:document>`, body)
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDocxExtractLimit(t *testing.T) {
+	small := docxWithBody(t, `<w:p><w:r><w:t>Привет, мир</w:t></w:r></w:p>`)
+	text, err := DocxExtractor{}.Extract(bytes.NewReader(small), int64(len(small)), 1<<20)
+	if err != nil || text != "Привет, мир\n" {
+		t.Fatalf("Extract = %q, %v", text, err)
+	}
+
+	// Сжатый архив в несколько килобайт распаковывается в мегабайты однообразного XML
+	bomb := docxWithBody(t, strings.Repeat(`<w:p><w:r><w:t>aaaaaaaaaaaaaaaa</w:t></w:r></w:p>`, 100000))
+	if len(bomb) > 1<<16 {
+		t.Fatalf("архив неожиданно большой: %d байт", len(bomb))
+	}
+	if _, err := (DocxExtractor{}).Extract(bytes.NewReader(bomb), int64(len(bomb)), 1<<20); !errors.Is(err, ErrDocumentTooLarge) {
+		t.Fatalf("ожидалась ErrDocumentTooLarge, получено %v", err)
+	}
+}
+
+func TestPDFExtractLimit(t *testing.T) {
+	// Один сжатый поток содержимого, на который ссылаются все страницы документа
+	var content bytes.Buffer
+	zw := zlib.NewWriter(&content)
+	fmt.Fprintf(zw, "BT /F1 12 Tf (%s) Tj ET", strings.Repeat("a", 4096))
+	zw.Close()
+
+	var kids strings.Builder
+	var pdf bytes.Buffer
+	const pages = 500
+	for i := 0; i < pages; i++ {
+		fmt.Fprintf(&kids, "%d 0 R ", 10+i)
+	}
+	fmt.Fprintf(&pdf, "%%PDF-1.4\n1 0 obj << /Type /Catalog /Pages 2 0 R >> endobj\n")
+	fmt.Fprintf(&pdf, "2 0 obj << /Type /Pages /Kids [%s] /Count %d >> endobj\n", kids.String(), pages)
+	fmt.Fprintf(&pdf, "3 0 obj << /Length %d /Filter /FlateDecode >> stream\n", content.Len())
+	pdf.Write(content.Bytes())
+	fmt.Fprintf(&pdf, "\nendstream endobj\n")
+	for i := 0; i < pages; i++ {
+		fmt.Fprintf(&pdf, "%d 0 obj << /Type /Page /Parent 2 0 R /Contents 3 0 R >> endobj\n", 10+i)
+	}
+	fmt.Fprintf(&pdf, "trailer << /Root 1 0 R >>\n%%%%EOF\n")
+	data := pdf.Bytes()
+
+	if _, err := (PDFExtractor{}).Extract(bytes.NewReader(data), int64(len(data)), 64<<10); !errors.Is(err, ErrDocumentTooLarge) {
+		t.Fatalf("ожидалась ErrDocumentTooLarge, получено %v", err)
+	}
+	text, err := PDFExtractor{}.Extract(bytes.NewReader(data), int64(len(data)), 16<<20)
+	if err != nil || !strings.Contains(text, strings.Repeat("a", 4096)) {
+		t.Fatalf("Extract: %v, текст %d байт", err, len(text))
+	}
+}
+
+func TestPlainTextExtractLimit(t *testing.T) {
+	data := []byte(strings.Repeat("слово ", 100))
+	if _, err := (PlainTextExtractor{}).Extract(bytes.NewReader(data), int64(len(data)), 100); !errors.Is(err, ErrDocumentTooLarge) {
+		t.Fatalf("ожидалась ErrDocumentTooLarge, получено %v", err)
+	}
+	if _, err := (PlainTextExtractor{}).Extract(bytes.NewReader(data), int64(len(data)), int64(len(data))); err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+}
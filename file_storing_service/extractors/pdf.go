@@ -0,0 +1,403 @@
+package extractors
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/zlib"
+	"encoding/ascii85"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PDFExtractor обрабатывает документы PDF (.pdf). Поддерживаются текстовые слои со стандартными
+// фильтрами потоков (FlateDecode, ASCIIHexDecode, ASCII85Decode), потоки объектов и шрифты с ToUnicode.
+// Зашифрованные документы и отсканированные изображения без текстового слоя не поддерживаются.
+type PDFExtractor struct{}
+
+func (PDFExtractor) Name() string         { return "pdf" }
+func (PDFExtractor) MimeType() string     { return "application/pdf" }
+func (PDFExtractor) Extensions() []string { return []string{".pdf"} }
+
+// Match проверяет наличие заголовка %PDF- (допускается мусор перед ним, как и в большинстве просмотрщиков).
+func (PDFExtractor) Match(head []byte) bool { return bytes.Contains(head, []byte("%PDF-")) }
+
+// Extract возвращает текст всех страниц документа. Страницы разделяются пустой строкой.
+// Все сжатые потоки документа вместе распаковываются не больше чем в limit байт, в том числе
+// если один поток используется многими страницами.
+func (PDFExtractor) Extract(r io.ReaderAt, size int64, limit int64) (string, error) {
+	data, err := readAll(r, size)
+	if err != nil {
+		return "", err
+	}
+	doc, err := parsePDF(data, limit)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	for _, page := range doc.pages() {
+		sb.WriteString(doc.pageText(page))
+		sb.WriteString("\n\n")
+		if doc.inflateExceeded || int64(sb.Len()) > limit {
+			break
+		}
+	}
+	if doc.inflateExceeded {
+		return "", fmt.Errorf("%w: сжатые потоки PDF больше %d байт", ErrDocumentTooLarge, limit)
+	}
+	return limitText(normalizeText(sb.String()), limit)
+}
+
+// pdfDocument — объекты документа PDF, индексированные по номеру.
+type pdfDocument struct {
+	objects map[int]any
+	trailer pdfDict
+	fonts   map[pdfRef]*pdfFont
+
+	inflateBudget   int64 // Сколько байт еще можно распаковать из потоков FlateDecode
+	inflateExceeded bool  // Потоки документа распаковываются в больший объем, чем разрешено
+}
+
+var (
+	pdfObjectRe  = regexp.MustCompile(`(\d+)\s+(\d+)\s+obj\b`)
+	pdfTrailerRe = regexp.MustCompile(`trailer\s*<<`)
+)
+
+// parsePDF находит все объекты документа. Таблица xref не используется: объекты ищутся по шаблону "N G obj",
+// поэтому поврежденные таблицы ссылок не мешают извлечению текста. При инкрементальных обновлениях
+// более поздняя версия объекта заменяет раннюю. Из потоков FlateDecode распаковывается не больше limit байт.
+func parsePDF(data []byte, limit int64) (*pdfDocument, error) {
+	doc := &pdfDocument{objects: make(map[int]any), trailer: make(pdfDict), fonts: make(map[pdfRef]*pdfFont), inflateBudget: limit}
+
+	for _, m := range pdfObjectRe.FindAllSubmatchIndex(data, -1) {
+		if m[0] > 0 && !isPDFSpace(data[m[0]-1]) && !isPDFDelimiter(data[m[0]-1]) {
+			continue // Часть другого числа
+		}
+		num, err := strconv.Atoi(string(data[m[2]:m[3]]))
+		if err != nil {
+			continue
+		}
+		lexer := &pdfLexer{data: data, pos: m[1]}
+		object, err := lexer.readObject()
+		if err != nil {
+			continue
+		}
+		if dict, ok := object.(pdfDict); ok {
+			if stream := readStreamData(data, lexer, dict); stream != nil {
+				object = stream
+			}
+		}
+		doc.objects[num] = object
+	}
+	if len(doc.objects) == 0 {
+		return nil, errors.New("файл не является корректным документом PDF: объекты не найдены")
+	}
+
+	// Словари trailer (классическая таблица xref) и словари потоков xref (PDF 1.5+)
+	for _, idx := range pdfTrailerRe.FindAllIndex(data, -1) {
+		lexer := &pdfLexer{data: data, pos: idx[0] + len("trailer")}
+		if dict, err := lexer.readObject(); err == nil {
+			if d, ok := dict.(pdfDict); ok {
+				doc.mergeTrailer(d)
+			}
+		}
+	}
+	for _, num := range doc.sortedObjectNumbers() {
+		if stream, ok := doc.objects[num].(*pdfStream); ok && stream.Dict["Type"] == pdfName("XRef") {
+			doc.mergeTrailer(stream.Dict)
+		}
+	}
+	if _, encrypted := doc.trailer["Encrypt"]; encrypted {
+		return nil, errors.New("зашифрованные документы PDF не поддерживаются")
+	}
+
+	doc.loadObjectStreams()
+	return doc, nil
+}
+
+// readStreamData читает данные потока, если за словарем объекта следует ключевое слово stream.
+func readStreamData(data []byte, lexer *pdfLexer, dict pdfDict) *pdfStream {
+	lexer.skipSpace()
+	if !bytes.HasPrefix(data[lexer.pos:], []byte("stream")) {
+		return nil
+	}
+	start := lexer.pos + len("stream")
+	if start < len(data) && data[start] == '\r' {
+		start++
+	}
+	if start < len(data) && data[start] == '\n' {
+		start++
+	}
+
+	// Длина может быть задана ссылкой на объект, поэтому при несовпадении ищем endstream
+	if length, ok := dict["Length"].(float64); ok && length >= 0 {
+		end := start + int(length)
+		if end <= len(data) {
+			rest := bytes.TrimLeft(data[end:], "\r\n \t")
+			if bytes.HasPrefix(rest, []byte("endstream")) {
+				return &pdfStream{Dict: dict, Raw: data[start:end]}
+			}
+		}
+	}
+	end := bytes.Index(data[start:], []byte("endstream"))
+	if end < 0 {
+		return &pdfStream{Dict: dict, Raw: data[start:]}
+	}
+	raw := data[start : start+end]
+	raw = bytes.TrimSuffix(raw, []byte("\n"))
+	raw = bytes.TrimSuffix(raw, []byte("\r"))
+	return &pdfStream{Dict: dict, Raw: raw}
+}
+
+func (d *pdfDocument) mergeTrailer(dict pdfDict) {
+	for k, v := range dict {
+		d.trailer[k] = v
+	}
+}
+
+func (d *pdfDocument) sortedObjectNumbers() []int {
+	nums := make([]int, 0, len(d.objects))
+	for num := range d.objects {
+		nums = append(nums, num)
+	}
+	sort.Ints(nums)
+	return nums
+}
+
+// loadObjectStreams извлекает объекты, упакованные в потоки объектов (/Type /ObjStm).
+// Объекты, найденные в файле напрямую, имеют приоритет.
+func (d *pdfDocument) loadObjectStreams() {
+	for _, num := range d.sortedObjectNumbers() {
+		stream, ok := d.objects[num].(*pdfStream)
+		if !ok || stream.Dict["Type"] != pdfName("ObjStm") {
+			continue
+		}
+		data, err := d.decodeStream(stream)
+		if err != nil {
+			continue
+		}
+		count, _ := d.resolve(stream.Dict["N"]).(float64)
+		first, _ := d.resolve(stream.Dict["First"]).(float64)
+		if int(first) > len(data) {
+			continue
+		}
+
+		header := &pdfLexer{data: data[:int(first)]}
+		for i := 0; i < int(count); i++ {
+			objNum, err1 := header.readObject()
+			offset, err2 := header.readObject()
+			n, ok1 := objNum.(float64)
+			off, ok2 := offset.(float64)
+			if err1 != nil || err2 != nil || !ok1 || !ok2 {
+				break
+			}
+			if _, exists := d.objects[int(n)]; exists {
+				continue
+			}
+			pos := int(first) + int(off)
+			if pos >= len(data) {
+				continue
+			}
+			lexer := &pdfLexer{data: data, pos: pos}
+			if object, err := lexer.readObject(); err == nil {
+				d.objects[int(n)] = object
+			}
+		}
+	}
+}
+
+// resolve разыменовывает ссылки на объекты.
+func (d *pdfDocument) resolve(v any) any {
+	for i := 0; i < 32; i++ {
+		ref, ok := v.(pdfRef)
+		if !ok {
+			return v
+		}
+		v = d.objects[ref.Num]
+	}
+	return nil
+}
+
+func (d *pdfDocument) dict(v any) pdfDict {
+	switch t := d.resolve(v).(type) {
+	case pdfDict:
+		return t
+	case *pdfStream:
+		return t.Dict
+	}
+	return nil
+}
+
+// decodeStream применяет фильтры потока.
+func (d *pdfDocument) decodeStream(stream *pdfStream) ([]byte, error) {
+	var filters []any
+	switch f := d.resolve(stream.Dict["Filter"]).(type) {
+	case pdfName:
+		filters = []any{f}
+	case []any:
+		filters = f
+	}
+
+	data := stream.Raw
+	for _, f := range filters {
+		name, _ := d.resolve(f).(pdfName)
+		var err error
+		switch name {
+		case "FlateDecode", "Fl":
+			data, err = d.inflate(data)
+		case "ASCIIHexDecode", "AHx":
+			data, err = decodeASCIIHex(data)
+		case "ASCII85Decode", "A85":
+			data, err = decodeASCII85(data)
+		default:
+			err = fmt.Errorf("фильтр %s не поддерживается", name)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// inflate распаковывает данные FlateDecode в пределах оставшегося бюджета документа. Поврежденный хвост потока
+// не считается ошибкой, если часть данных удалось распаковать.
+func (d *pdfDocument) inflate(data []byte) ([]byte, error) {
+	var reader io.ReadCloser
+	reader, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		// Некоторые генераторы пишут поток deflate без заголовка zlib
+		reader = flate.NewReader(bytes.NewReader(data))
+	}
+	defer reader.Close()
+	out, err := io.ReadAll(limitReader(reader, d.inflateBudget))
+	d.inflateBudget -= int64(len(out))
+	if errors.Is(err, ErrDocumentTooLarge) {
+		d.inflateExceeded = true
+		return nil, err
+	}
+	if err != nil && len(out) == 0 {
+		return nil, fmt.Errorf("не удалось распаковать поток PDF: %w", err)
+	}
+	return out, nil
+}
+
+func decodeASCIIHex(data []byte) ([]byte, error) {
+	if end := bytes.IndexByte(data, '>'); end >= 0 {
+		data = data[:end]
+	}
+	lexer := &pdfLexer{data: append(append([]byte{'<'}, data...), '>')}
+	return lexer.readHexString()
+}
+
+func decodeASCII85(data []byte) ([]byte, error) {
+	data = bytes.TrimSpace(data)
+	data = bytes.TrimPrefix(data, []byte("<~"))
+	if end := bytes.Index(data, []byte("~>")); end >= 0 {
+		data = data[:end]
+	}
+	out := make([]byte, len(data)*4/5+4)
+	n, _, err := ascii85.Decode(out, data, true)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось декодировать поток ASCII85: %w", err)
+	}
+	return out[:n], nil
+}
+
+// pdfPage — страница документа вместе с унаследованными ресурсами.
+type pdfPage struct {
+	dict      pdfDict
+	resources pdfDict
+}
+
+// pages возвращает страницы в порядке дерева страниц документа. Если дерево повреждено,
+// используются все объекты /Type /Page в порядке номеров.
+func (d *pdfDocument) pages() []pdfPage {
+	var pages []pdfPage
+	if root := d.dict(d.trailer["Root"]); root != nil {
+		visited := make(map[pdfRef]bool)
+		d.walkPages(root["Pages"], nil, visited, &pages, 0)
+	}
+	if len(pages) > 0 {
+		return pages
+	}
+	for _, num := range d.sortedObjectNumbers() {
+		if dict, ok := d.objects[num].(pdfDict); ok && dict["Type"] == pdfName("Page") {
+			pages = append(pages, pdfPage{dict: dict, resources: d.dict(dict["Resources"])})
+		}
+	}
+	return pages
+}
+
+func (d *pdfDocument) walkPages(node any, inherited pdfDict, visited map[pdfRef]bool, pages *[]pdfPage, depth int) {
+	if ref, ok := node.(pdfRef); ok {
+		if visited[ref] {
+			return
+		}
+		visited[ref] = true
+	}
+	dict := d.dict(node)
+	if dict == nil || depth > maxPDFNesting {
+		return
+	}
+	resources := inherited
+	if own := d.dict(dict["Resources"]); own != nil {
+		resources = own
+	}
+	if kids, ok := d.resolve(dict["Kids"]).([]any); ok {
+		for _, kid := range kids {
+			d.walkPages(kid, resources, visited, pages, depth+1)
+		}
+		return
+	}
+	*pages = append(*pages, pdfPage{dict: dict, resources: resources})
+}
+
+// pageContent возвращает объединенные потоки содержимого страницы.
+func (d *pdfDocument) pageContent(page pdfPage) []byte {
+	var streams []any
+	switch c := d.resolve(page.dict["Contents"]).(type) {
+	case *pdfStream:
+		streams = []any{c}
+	case []any:
+		streams = c
+	}
+
+	var content bytes.Buffer
+	for _, s := range streams {
+		stream, ok := d.resolve(s).(*pdfStream)
+		if !ok {
+			continue
+		}
+		data, err := d.decodeStream(stream)
+		if err != nil {
+			continue
+		}
+		content.Write(data)
+		content.WriteByte('\n')
+	}
+	return content.Bytes()
+}
+
+// pageFonts возвращает шрифты страницы по именам ресурсов.
+func (d *pdfDocument) pageFonts(page pdfPage) map[pdfName]*pdfFont {
+	fonts := make(map[pdfName]*pdfFont)
+	for name, value := range d.dict(page.resources["Font"]) {
+		if ref, ok := value.(pdfRef); ok {
+			if font, cached := d.fonts[ref]; cached {
+				fonts[name] = font
+				continue
+			}
+			font := d.loadFont(d.dict(ref))
+			d.fonts[ref] = font
+			fonts[name] = font
+			continue
+		}
+		fonts[name] = d.loadFont(d.dict(value))
+	}
+	return fonts
+}
@@ -0,0 +1,296 @@
+package extractors
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// Объекты PDF после разбора представлены значениями Go:
+// nil, bool, float64, []byte (строка), pdfName, pdfKeyword, []any (массив), pdfDict, pdfRef и *pdfStream.
+
+type pdfName string
+
+// pdfKeyword — ключевое слово PDF или оператор потока содержимого (BT, Tj и т.п.).
+type pdfKeyword string
+
+type pdfDict map[pdfName]any
+
+type pdfRef struct {
+	Num, Gen int
+}
+
+type pdfStream struct {
+	Dict pdfDict
+	Raw  []byte // Данные потока до декодирования фильтров
+}
+
+var errPDFEOF = errors.New("неожиданный конец данных PDF")
+
+// maxPDFNesting ограничивает вложенность массивов и словарей, чтобы некорректный файл не исчерпал стек.
+const maxPDFNesting = 64
+
+// pdfLexer читает объекты PDF из среза байт.
+type pdfLexer struct {
+	data []byte
+	pos  int
+}
+
+func isPDFSpace(c byte) bool {
+	return c == 0 || c == '\t' || c == '\n' || c == '\f' || c == '\r' || c == ' '
+}
+
+func isPDFDelimiter(c byte) bool {
+	switch c {
+	case '(', ')', '<', '>', '[', ']', '{', '}', '/', '%':
+		return true
+	}
+	return false
+}
+
+// skipSpace пропускает пробельные символы и комментарии.
+func (l *pdfLexer) skipSpace() {
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		if isPDFSpace(c) {
+			l.pos++
+		} else if c == '%' {
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' && l.data[l.pos] != '\r' {
+				l.pos++
+			}
+		} else {
+			return
+		}
+	}
+}
+
+// readObject читает следующий объект. Ключевые слова (в том числе закрывающие "]" и ">>") возвращаются как pdfKeyword.
+func (l *pdfLexer) readObject() (any, error) {
+	return l.readNested(0)
+}
+
+func (l *pdfLexer) readNested(depth int) (any, error) {
+	if depth > maxPDFNesting {
+		return nil, errors.New("слишком глубокая вложенность объектов PDF")
+	}
+	l.skipSpace()
+	if l.pos >= len(l.data) {
+		return nil, errPDFEOF
+	}
+
+	c := l.data[l.pos]
+	switch {
+	case c == '/':
+		return l.readName(), nil
+	case c == '(':
+		return l.readLiteralString()
+	case c == '<' && l.pos+1 < len(l.data) && l.data[l.pos+1] == '<':
+		l.pos += 2
+		return l.readDict(depth)
+	case c == '<':
+		return l.readHexString()
+	case c == '>' && l.pos+1 < len(l.data) && l.data[l.pos+1] == '>':
+		l.pos += 2
+		return pdfKeyword(">>"), nil
+	case c == '[':
+		l.pos++
+		return l.readArray(depth)
+	case c == ']' || c == '{' || c == '}' || c == ')' || c == '>':
+		l.pos++
+		return pdfKeyword(string(c)), nil
+	case c == '+' || c == '-' || c == '.' || (c >= '0' && c <= '9'):
+		return l.readNumberOrRef()
+	default:
+		start := l.pos
+		for l.pos < len(l.data) && !isPDFSpace(l.data[l.pos]) && !isPDFDelimiter(l.data[l.pos]) {
+			l.pos++
+		}
+		switch word := string(l.data[start:l.pos]); word {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null":
+			return nil, nil
+		default:
+			return pdfKeyword(word), nil
+		}
+	}
+}
+
+func (l *pdfLexer) readName() pdfName {
+	l.pos++ // '/'
+	var name []byte
+	for l.pos < len(l.data) && !isPDFSpace(l.data[l.pos]) && !isPDFDelimiter(l.data[l.pos]) {
+		c := l.data[l.pos]
+		if c == '#' && l.pos+2 < len(l.data) {
+			if b, err := strconv.ParseUint(string(l.data[l.pos+1:l.pos+3]), 16, 8); err == nil {
+				name = append(name, byte(b))
+				l.pos += 3
+				continue
+			}
+		}
+		name = append(name, c)
+		l.pos++
+	}
+	return pdfName(name)
+}
+
+func (l *pdfLexer) readLiteralString() ([]byte, error) {
+	l.pos++ // '('
+	var out []byte
+	depth := 1
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+		switch c {
+		case '(':
+			depth++
+			out = append(out, c)
+		case ')':
+			depth--
+			if depth == 0 {
+				return out, nil
+			}
+			out = append(out, c)
+		case '\\':
+			if l.pos >= len(l.data) {
+				return out, nil
+			}
+			e := l.data[l.pos]
+			l.pos++
+			switch e {
+			case 'n':
+				out = append(out, '\n')
+			case 'r':
+				out = append(out, '\r')
+			case 't':
+				out = append(out, '\t')
+			case 'b':
+				out = append(out, '\b')
+			case 'f':
+				out = append(out, '\f')
+			case '\r':
+				// Перенос строки после обратной косой черты продолжает строку
+				if l.pos < len(l.data) && l.data[l.pos] == '\n' {
+					l.pos++
+				}
+			case '\n':
+			default:
+				if e >= '0' && e <= '7' {
+					value := int(e - '0')
+					for n := 0; n < 2 && l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '7'; n++ {
+						value = value*8 + int(l.data[l.pos]-'0')
+						l.pos++
+					}
+					out = append(out, byte(value))
+				} else {
+					out = append(out, e)
+				}
+			}
+		default:
+			out = append(out, c)
+		}
+	}
+	return out, nil
+}
+
+func (l *pdfLexer) readHexString() ([]byte, error) {
+	l.pos++ // '<'
+	end := bytes.IndexByte(l.data[l.pos:], '>')
+	if end < 0 {
+		return nil, errPDFEOF
+	}
+	digits := make([]byte, 0, end)
+	for _, c := range l.data[l.pos : l.pos+end] {
+		if !isPDFSpace(c) {
+			digits = append(digits, c)
+		}
+	}
+	l.pos += end + 1
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	out := make([]byte, len(digits)/2)
+	if _, err := hex.Decode(out, digits); err != nil {
+		return nil, fmt.Errorf("некорректная шестнадцатеричная строка PDF: %w", err)
+	}
+	return out, nil
+}
+
+func (l *pdfLexer) readDict(depth int) (pdfDict, error) {
+	dict := make(pdfDict)
+	for {
+		key, err := l.readNested(depth + 1)
+		if err != nil {
+			return dict, err
+		}
+		if key == pdfKeyword(">>") {
+			return dict, nil
+		}
+		name, ok := key.(pdfName)
+		if !ok {
+			continue // Пропускаем мусор вместо ключа
+		}
+		value, err := l.readNested(depth + 1)
+		if err != nil {
+			return dict, err
+		}
+		if value == pdfKeyword(">>") {
+			return dict, nil
+		}
+		dict[name] = value
+	}
+}
+
+func (l *pdfLexer) readArray(depth int) ([]any, error) {
+	var array []any
+	for {
+		item, err := l.readNested(depth + 1)
+		if err != nil {
+			return array, err
+		}
+		if item == pdfKeyword("]") {
+			return array, nil
+		}
+		array = append(array, item)
+	}
+}
+
+// readNumberOrRef читает число, а если за целым числом следуют номер поколения и R — ссылку на объект.
+func (l *pdfLexer) readNumberOrRef() (any, error) {
+	start := l.pos
+	l.pos++
+	for l.pos < len(l.data) && (l.data[l.pos] == '.' || (l.data[l.pos] >= '0' && l.data[l.pos] <= '9')) {
+		l.pos++
+	}
+	text := string(l.data[start:l.pos])
+	number, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return float64(0), nil // Некорректные числа (например, "--5") трактуются как 0, как это делают просмотрщики PDF
+	}
+	if number < 0 || number != float64(int(number)) || bytes.ContainsRune([]byte(text), '.') {
+		return number, nil
+	}
+
+	// Проверяем шаблон "num gen R"
+	saved := l.pos
+	l.skipSpace()
+	genStart := l.pos
+	for l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '9' {
+		l.pos++
+	}
+	if l.pos > genStart {
+		gen, _ := strconv.Atoi(string(l.data[genStart:l.pos]))
+		l.skipSpace()
+		if l.pos < len(l.data) && l.data[l.pos] == 'R' &&
+			(l.pos+1 == len(l.data) || isPDFSpace(l.data[l.pos+1]) || isPDFDelimiter(l.data[l.pos+1])) {
+			l.pos++
+			return pdfRef{Num: int(number), Gen: gen}, nil
+		}
+	}
+	l.pos = saved
+	return number, nil
+}
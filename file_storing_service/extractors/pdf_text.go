@@ -0,0 +1,366 @@
+package extractors
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"unicode/utf16"
+)
+
+// pdfFont декодирует коды символов строк PDF в текст.
+type pdfFont struct {
+	cmap     *pdfCMap // Таблица ToUnicode, если есть
+	twoByte  bool     // Составной шрифт (Type0): коды символов двухбайтовые
+	codepage int      // Кодировка простого шрифта без ToUnicode
+}
+
+func (d *pdfDocument) loadFont(dict pdfDict) *pdfFont {
+	font := &pdfFont{codepage: 1252}
+	if dict == nil {
+		return font
+	}
+	font.twoByte = d.resolve(dict["Subtype"]) == pdfName("Type0")
+	if stream, ok := d.resolve(dict["ToUnicode"]).(*pdfStream); ok {
+		if data, err := d.decodeStream(stream); err == nil {
+			font.cmap = parseCMap(data)
+		}
+	}
+	return font
+}
+
+// decode преобразует строку PDF в текст. Для составных шрифтов без ToUnicode текст восстановить нельзя.
+func (f *pdfFont) decode(s []byte) string {
+	if f == nil {
+		return decodeBytes(s, 1252)
+	}
+	if f.cmap != nil {
+		return f.cmap.decode(s, f.twoByte)
+	}
+	if f.twoByte {
+		return ""
+	}
+	return decodeBytes(s, f.codepage)
+}
+
+// pdfCodespace — диапазон кодов символов заданной длины.
+type pdfCodespace struct {
+	lo, hi []byte
+}
+
+// pdfCMap — таблица соответствия кодов символов и Unicode (ToUnicode CMap).
+type pdfCMap struct {
+	codespaces []pdfCodespace
+	mapping    map[string]string
+}
+
+// parseCMap разбирает секции codespacerange, bfchar и bfrange таблицы ToUnicode.
+func parseCMap(data []byte) *pdfCMap {
+	cmap := &pdfCMap{mapping: make(map[string]string)}
+	lexer := &pdfLexer{data: data}
+	var operands []any
+	section := ""
+	for {
+		object, err := lexer.readObject()
+		if err != nil {
+			break
+		}
+		keyword, isKeyword := object.(pdfKeyword)
+		if !isKeyword {
+			operands = append(operands, object)
+			continue
+		}
+		switch keyword {
+		case "begincodespacerange", "beginbfchar", "beginbfrange":
+			section = string(keyword)
+		case "endcodespacerange":
+			for i := 0; i+1 < len(operands); i += 2 {
+				lo, ok1 := operands[i].([]byte)
+				hi, ok2 := operands[i+1].([]byte)
+				if ok1 && ok2 && len(lo) == len(hi) && len(lo) > 0 {
+					cmap.codespaces = append(cmap.codespaces, pdfCodespace{lo: lo, hi: hi})
+				}
+			}
+			section = ""
+		case "endbfchar":
+			for i := 0; i+1 < len(operands); i += 2 {
+				src, ok1 := operands[i].([]byte)
+				dst, ok2 := operands[i+1].([]byte)
+				if ok1 && ok2 {
+					cmap.mapping[string(src)] = decodeUTF16BE(dst)
+				}
+			}
+			section = ""
+		case "endbfrange":
+			for i := 0; i+2 < len(operands); i += 3 {
+				cmap.addRange(operands[i], operands[i+1], operands[i+2])
+			}
+			section = ""
+		}
+		if section == "" || keyword == pdfKeyword(section) {
+			operands = operands[:0]
+		}
+	}
+	return cmap
+}
+
+// maxCMapRange ограничивает размер одного диапазона bfrange, чтобы некорректный файл не исчерпал память.
+const maxCMapRange = 1 << 16
+
+func (c *pdfCMap) addRange(loObj, hiObj, dstObj any) {
+	lo, ok1 := loObj.([]byte)
+	hi, ok2 := hiObj.([]byte)
+	if !ok1 || !ok2 || len(lo) != len(hi) || len(lo) == 0 || len(lo) > 4 {
+		return
+	}
+	start, end := bytesToUint(lo), bytesToUint(hi)
+	if end < start || end-start >= maxCMapRange {
+		return
+	}
+	for code := start; code <= end; code++ {
+		offset := code - start
+		key := string(uintToBytes(code, len(lo)))
+		switch dst := dstObj.(type) {
+		case []byte:
+			// Последний байт значения увеличивается на смещение кода внутри диапазона
+			value := append([]byte(nil), dst...)
+			if len(value) >= 2 {
+				last := bytesToUint(value[len(value)-2:]) + offset
+				value[len(value)-2], value[len(value)-1] = byte(last>>8), byte(last)
+			}
+			c.mapping[key] = decodeUTF16BE(value)
+		case []any:
+			if int(offset) < len(dst) {
+				if value, ok := dst[offset].([]byte); ok {
+					c.mapping[key] = decodeUTF16BE(value)
+				}
+			}
+		}
+	}
+}
+
+// decode разбивает строку на коды символов согласно codespacerange и заменяет их по таблице.
+func (c *pdfCMap) decode(s []byte, twoByte bool) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); {
+		n := c.codeLength(s[i:], twoByte)
+		if i+n > len(s) {
+			n = len(s) - i
+		}
+		if text, ok := c.mapping[string(s[i:i+n])]; ok {
+			sb.WriteString(text)
+		}
+		i += n
+	}
+	return sb.String()
+}
+
+func (c *pdfCMap) codeLength(s []byte, twoByte bool) int {
+	for _, cs := range c.codespaces {
+		n := len(cs.lo)
+		if n > len(s) {
+			continue
+		}
+		inRange := true
+		for j := 0; j < n; j++ {
+			if s[j] < cs.lo[j] || s[j] > cs.hi[j] {
+				inRange = false
+				break
+			}
+		}
+		if inRange {
+			return n
+		}
+	}
+	if twoByte {
+		return 2
+	}
+	return 1
+}
+
+func decodeUTF16BE(b []byte) string {
+	if len(b) == 1 {
+		return string(rune(b[0]))
+	}
+	units := make([]uint16, 0, len(b)/2)
+	for i := 0; i+1 < len(b); i += 2 {
+		units = append(units, uint16(b[i])<<8|uint16(b[i+1]))
+	}
+	return string(utf16.Decode(units))
+}
+
+func bytesToUint(b []byte) uint32 {
+	var v uint32
+	for _, c := range b {
+		v = v<<8 | uint32(c)
+	}
+	return v
+}
+
+func uintToBytes(v uint32, n int) []byte {
+	b := make([]byte, n)
+	for i := n - 1; i >= 0; i-- {
+		b[i] = byte(v)
+		v >>= 8
+	}
+	return b
+}
+
+// pdfTextWriter собирает текст страницы, восстанавливая строки и абзацы по вертикальным смещениям.
+type pdfTextWriter struct {
+	sb       strings.Builder
+	y        float64 // Вертикальная позиция последнего выведенного текста
+	started  bool
+	lineStep float64 // Наименьший встреченный межстрочный интервал
+}
+
+// show выводит фрагмент текста, расположенный на высоте y.
+func (w *pdfTextWriter) show(text string, y float64) {
+	if text == "" {
+		return
+	}
+	if w.started {
+		gap := math.Abs(y - w.y)
+		if gap > 0.5 {
+			// Интервал заметно больше обычного межстрочного считается границей абзаца
+			if w.lineStep > 0 && gap > w.lineStep*1.6 {
+				w.sb.WriteString("\n\n")
+			} else {
+				w.sb.WriteByte('\n')
+			}
+			if w.lineStep == 0 || gap < w.lineStep {
+				w.lineStep = gap
+			}
+		}
+	}
+	w.started = true
+	w.y = y
+	w.sb.WriteString(text)
+}
+
+func (w *pdfTextWriter) space() {
+	s := w.sb.String()
+	if s != "" && !strings.HasSuffix(s, " ") && !strings.HasSuffix(s, "\n") {
+		w.sb.WriteByte(' ')
+	}
+}
+
+// pageText интерпретирует поток содержимого страницы и возвращает ее текст.
+// Учитываются операторы текста (BT/ET, Tf, Td, TD, Tm, T*, TL, Tj, TJ, ', ") и вложенные формы (Do).
+func (d *pdfDocument) pageText(page pdfPage) string {
+	w := &pdfTextWriter{}
+	d.runContent(d.pageContent(page), page.resources, w, 0)
+	return w.sb.String()
+}
+
+func (d *pdfDocument) runContent(content []byte, resources pdfDict, w *pdfTextWriter, depth int) {
+	if depth > 8 {
+		return
+	}
+	fonts := d.pageFonts(pdfPage{resources: resources})
+	var font *pdfFont
+	var y, leading float64
+	scale := 1.0 // Вертикальный масштаб матрицы текста: смещения Td и T* задаются в ее единицах
+	var operands []any
+
+	lexer := &pdfLexer{data: content}
+	for {
+		object, err := lexer.readObject()
+		if err != nil {
+			return
+		}
+		op, isOperator := object.(pdfKeyword)
+		if !isOperator {
+			operands = append(operands, object)
+			continue
+		}
+
+		switch op {
+		case "BT":
+			y, scale = 0, 1
+		case "Tf":
+			if len(operands) >= 1 {
+				if name, ok := operands[0].(pdfName); ok {
+					font = fonts[name]
+				}
+			}
+		case "TL":
+			leading = number(operands, 0)
+		case "Td":
+			y += number(operands, 1) * scale
+		case "TD":
+			leading = -number(operands, 1)
+			y += number(operands, 1) * scale
+		case "Tm":
+			y = number(operands, 5)
+			if scale = number(operands, 3); scale == 0 {
+				scale = 1
+			}
+		case "T*":
+			y -= leading * scale
+		case "Tj":
+			if s, ok := lastString(operands); ok {
+				w.show(font.decode(s), y)
+			}
+		case "'", "\"":
+			y -= leading * scale
+			if s, ok := lastString(operands); ok {
+				w.show(font.decode(s), y)
+			}
+		case "TJ":
+			if len(operands) > 0 {
+				items, _ := operands[len(operands)-1].([]any)
+				for _, item := range items {
+					switch v := item.(type) {
+					case []byte:
+						w.show(font.decode(v), y)
+					case float64:
+						// Большой отрицательный сдвиг между фрагментами обозначает пробел между словами
+						if v < -200 {
+							w.space()
+						}
+					}
+				}
+			}
+		case "Do":
+			if len(operands) > 0 {
+				if name, ok := operands[0].(pdfName); ok {
+					xobject, ok := d.resolve(d.dict(resources["XObject"])[name]).(*pdfStream)
+					if ok && xobject.Dict["Subtype"] == pdfName("Form") {
+						if data, err := d.decodeStream(xobject); err == nil {
+							formResources := d.dict(xobject.Dict["Resources"])
+							if formResources == nil {
+								formResources = resources
+							}
+							d.runContent(data, formResources, w, depth+1)
+						}
+					}
+				}
+			}
+		case "ID":
+			// Данные встроенного изображения не являются объектами PDF: пропускаем их до EI
+			if end := bytes.Index(content[lexer.pos:], []byte("EI")); end >= 0 {
+				lexer.pos += end + 2
+			} else {
+				return
+			}
+		}
+		operands = operands[:0]
+	}
+}
+
+func number(operands []any, i int) float64 {
+	if i < len(operands) {
+		if v, ok := operands[i].(float64); ok {
+			return v
+		}
+	}
+	return 0
+}
+
+func lastString(operands []any) ([]byte, bool) {
+	if len(operands) == 0 {
+		return nil, false
+	}
+	s, ok := operands[len(operands)-1].([]byte)
+	return s, ok
+}
@@ -0,0 +1,152 @@
+package extractors
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// RTFExtractor обрабатывает документы в формате RTF (.rtf).
+type RTFExtractor struct{}
+
+func (RTFExtractor) Name() string           { return "rtf" }
+func (RTFExtractor) MimeType() string       { return "application/rtf" }
+func (RTFExtractor) Extensions() []string   { return []string{".rtf"} }
+func (RTFExtractor) Match(head []byte) bool { return bytes.HasPrefix(head, []byte(`{\rtf`)) }
+
+// rtfSkipDestinations — группы, которые не содержат текста документа (таблицы шрифтов, стилей, метаданные, картинки и т.п.).
+var rtfSkipDestinations = map[string]bool{
+	"fonttbl": true, "colortbl": true, "stylesheet": true, "info": true, "pict": true, "object": true,
+	"header": true, "headerl": true, "headerr": true, "headerf": true,
+	"footer": true, "footerl": true, "footerr": true, "footerf": true,
+	"listtable": true, "listoverridetable": true, "rsidtbl": true, "generator": true, "xmlnstbl": true,
+	"themedata": true, "colorschememapping": true, "datastore": true, "latentstyles": true, "fldinst": true,
+	"filetbl": true, "revtbl": true, "pgdsctbl": true, "mmathPr": true, "bkmkstart": true, "bkmkend": true,
+}
+
+// rtfSymbols — управляющие слова, обозначающие отдельные символы.
+var rtfSymbols = map[string]string{
+	"par": "\n\n", "sect": "\n\n", "page": "\n\n", "line": "\n", "row": "\n", "tab": "\t", "cell": "\t",
+	"emdash": "—", "endash": "–", "bullet": "•", "lquote": "‘", "rquote": "’", "ldblquote": "“", "rdblquote": "”",
+	"emspace": " ", "enspace": " ", "qmspace": " ",
+}
+
+type rtfState struct {
+	skip bool // Содержимое группы не является текстом
+	uc   int  // Сколько символов-заменителей следует за \uN
+}
+
+// Extract разбирает RTF и возвращает текст документа. Символы в кодировке документа (\'hh)
+// декодируются по кодовой странице \ansicpg (поддерживаются 1251 и 1252), символы \uN — как Unicode.
+func (RTFExtractor) Extract(r io.ReaderAt, size int64, limit int64) (string, error) {
+	data, err := readAll(r, size)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	state := rtfState{uc: 1}
+	var stack []rtfState
+	codepage := 1252
+	pendingSkip := 0 // Сколько символов-заменителей после \uN осталось пропустить
+
+	write := func(s string) {
+		if pendingSkip > 0 {
+			pendingSkip--
+			return
+		}
+		if !state.skip {
+			sb.WriteString(s)
+		}
+	}
+
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		switch c {
+		case '{':
+			stack = append(stack, state)
+			pendingSkip = 0
+		case '}':
+			if len(stack) > 0 {
+				state = stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+			}
+			pendingSkip = 0
+		case '\r', '\n':
+			// Переводы строк в исходном тексте RTF не значимы
+		case '\\':
+			if i+1 >= len(data) {
+				break
+			}
+			i++
+			c = data[i]
+			switch {
+			case c == '\'':
+				if i+2 < len(data) {
+					if b, err := strconv.ParseUint(string(data[i+1:i+3]), 16, 8); err == nil {
+						write(string(decodeByte(byte(b), codepage)))
+					}
+					i += 2
+				}
+			case c == '*':
+				state.skip = true
+			case c == '\\' || c == '{' || c == '}':
+				write(string(c))
+			case c == '~':
+				write(" ")
+			case c == '_':
+				write("-")
+			case c == '\r' || c == '\n':
+				write("\n\n")
+			case isASCIILetter(c):
+				start := i
+				for i < len(data) && isASCIILetter(data[i]) {
+					i++
+				}
+				word := string(data[start:i])
+				paramStart := i
+				if i < len(data) && data[i] == '-' {
+					i++
+				}
+				for i < len(data) && data[i] >= '0' && data[i] <= '9' {
+					i++
+				}
+				param, hasParam := 0, i > paramStart
+				if hasParam {
+					param, _ = strconv.Atoi(string(data[paramStart:i]))
+				}
+				// Пробел после управляющего слова является разделителем и не входит в текст
+				if i >= len(data) || data[i] != ' ' {
+					i--
+				}
+
+				switch {
+				case rtfSkipDestinations[word]:
+					state.skip = true
+				case word == "ansicpg" && hasParam:
+					codepage = param
+				case word == "uc" && hasParam:
+					state.uc = param
+				case word == "u" && hasParam:
+					if param < 0 {
+						param += 65536
+					}
+					write(string(rune(param)))
+					pendingSkip = state.uc
+				default:
+					if symbol, ok := rtfSymbols[word]; ok {
+						write(symbol)
+					}
+				}
+			}
+		default:
+			write(string(decodeByte(c, codepage)))
+		}
+	}
+	return limitText(normalizeText(sb.String()), limit)
+}
+
+func isASCIILetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
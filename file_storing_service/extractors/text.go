@@ -0,0 +1,134 @@
+package extractors
+
+import (
+	"html"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// PlainTextExtractor обрабатывает обычные текстовые файлы (.txt).
+// Текст в кодировке, отличной от UTF-8, считается текстом в Windows-1251.
+type PlainTextExtractor struct{}
+
+func (PlainTextExtractor) Name() string           { return "plain" }
+func (PlainTextExtractor) MimeType() string       { return "text/plain" }
+func (PlainTextExtractor) Extensions() []string   { return []string{".txt"} }
+func (PlainTextExtractor) Match(head []byte) bool { return isText(head) }
+
+// Extract возвращает нормализованное содержимое файла.
+func (PlainTextExtractor) Extract(r io.ReaderAt, size int64, limit int64) (string, error) {
+	data, err := readAll(r, size)
+	if err != nil {
+		return "", err
+	}
+	return limitText(normalizeText(toUTF8(data)), limit)
+}
+
+// MarkdownExtractor обрабатывает файлы Markdown (.md): убирает разметку, оставляя текст
+// заголовков, списков, ссылок и блоков кода.
+type MarkdownExtractor struct{}
+
+func (MarkdownExtractor) Name() string           { return "markdown" }
+func (MarkdownExtractor) MimeType() string       { return "text/markdown" }
+func (MarkdownExtractor) Extensions() []string   { return []string{".md", ".markdown"} }
+func (MarkdownExtractor) Match(head []byte) bool { return isText(head) }
+
+var (
+	mdFenceRe     = regexp.MustCompile("(?m)^[ \t]*(```|~~~).*$")
+	mdRuleRe      = regexp.MustCompile(`(?m)^[ \t]*([-*_][ \t]*){3,}$`)
+	mdHeadingRe   = regexp.MustCompile(`(?m)^[ \t]*#{1,6}[ \t]+(.*?)[ \t#]*$`)
+	mdSetextRe    = regexp.MustCompile(`(?m)^[ \t]*(=+|-+)[ \t]*$`)
+	mdQuoteRe     = regexp.MustCompile(`(?m)^[ \t]*(>[ \t]?)+`)
+	mdListRe      = regexp.MustCompile(`(?m)^[ \t]*([-*+]|\d+[.)])[ \t]+(\[[ xX]\][ \t]+)?`)
+	mdRefDefRe    = regexp.MustCompile(`(?m)^[ \t]*\[[^\]]+\]:[ \t]+\S+.*$`)
+	mdImageRe     = regexp.MustCompile(`!\[([^\]]*)\]\([^)]*\)`)
+	mdLinkRe      = regexp.MustCompile(`\[([^\]]*)\](\([^)]*\)|\[[^\]]*\])`)
+	mdStrongRe    = regexp.MustCompile(`(\*\*|__)(\S(?:.*?\S)?)(\*\*|__)`)
+	mdEmphasisRe  = regexp.MustCompile(`(^|[^\w*])\*(\S(?:.*?\S)?)\*`)
+	mdStrikeRe    = regexp.MustCompile(`~~(.+?)~~`)
+	mdInlineCode  = regexp.MustCompile("`+([^`]*)`+")
+	mdTableSepRe  = regexp.MustCompile(`(?m)^[ \t]*\|?[ \t]*:?-+:?[ \t]*(\|[ \t]*:?-+:?[ \t]*)*\|?[ \t]*\n`)
+	mdTableEdgeRe = regexp.MustCompile(`(?m)^[ \t]*\|[ \t]*|[ \t]*\|[ \t]*$`)
+	mdHTMLTagRe   = regexp.MustCompile(`</?[a-zA-Z][^>]*>`)
+	mdTablePipeRe = regexp.MustCompile(`[ \t]*\|[ \t]*`)
+)
+
+// Extract убирает разметку Markdown и возвращает текст документа.
+func (MarkdownExtractor) Extract(r io.ReaderAt, size int64, limit int64) (string, error) {
+	data, err := readAll(r, size)
+	if err != nil {
+		return "", err
+	}
+	text := strings.ReplaceAll(toUTF8(data), "\r\n", "\n")
+
+	text = mdFenceRe.ReplaceAllString(text, "")
+	text = mdTableSepRe.ReplaceAllString(text, "")
+	text = mdRuleRe.ReplaceAllString(text, "")
+	text = mdHeadingRe.ReplaceAllString(text, "$1")
+	text = mdSetextRe.ReplaceAllString(text, "")
+	text = mdQuoteRe.ReplaceAllString(text, "")
+	text = mdListRe.ReplaceAllString(text, "")
+	text = mdRefDefRe.ReplaceAllString(text, "")
+	text = mdImageRe.ReplaceAllString(text, "$1")
+	text = mdLinkRe.ReplaceAllString(text, "$1")
+	text = mdInlineCode.ReplaceAllString(text, "$1")
+	text = mdStrongRe.ReplaceAllString(text, "$2")
+	text = mdEmphasisRe.ReplaceAllString(text, "$1$2")
+	text = mdStrikeRe.ReplaceAllString(text, "$1")
+	text = mdHTMLTagRe.ReplaceAllString(text, "")
+	text = mdTableEdgeRe.ReplaceAllString(text, "")
+	text = mdTablePipeRe.ReplaceAllString(text, "\t")
+	return limitText(normalizeText(html.UnescapeString(text)), limit)
+}
+
+// HTMLExtractor обрабатывает HTML-документы (.html, .htm): убирает теги, скрипты и стили,
+// а блочные элементы превращает в абзацы.
+type HTMLExtractor struct{}
+
+func (HTMLExtractor) Name() string           { return "html" }
+func (HTMLExtractor) MimeType() string       { return "text/html" }
+func (HTMLExtractor) Extensions() []string   { return []string{".html", ".htm"} }
+func (HTMLExtractor) Match(head []byte) bool { return isText(head) }
+
+var (
+	htmlCommentRe = regexp.MustCompile(`(?s)<!--.*?-->`)
+	htmlSpaceRe   = regexp.MustCompile(`\s+`)
+	htmlBreakRe   = regexp.MustCompile(`(?i)<br\s*/?>`)
+	htmlCellRe    = regexp.MustCompile(`(?i)</t[dh]\s*>`)
+	htmlBlockRe   = regexp.MustCompile(`(?i)</?(p|div|h[1-6]|li|ul|ol|dl|dt|dd|tr|table|section|article|header|footer|aside|nav|main|blockquote|pre|figure|figcaption|hr|address|form|fieldset)\b[^>]*>`)
+	htmlTagRe     = regexp.MustCompile(`<[^>]*>`)
+	htmlLineRe    = regexp.MustCompile(`[ \t]*\n[ \t]*`)
+)
+
+// htmlSkipRes находят элементы, содержимое которых не является текстом документа.
+var htmlSkipRes = func() []*regexp.Regexp {
+	var res []*regexp.Regexp
+	for _, tag := range []string{"head", "script", "style", "noscript", "template", "svg"} {
+		res = append(res, regexp.MustCompile(`(?is)<`+tag+`\b.*?</`+tag+`\s*>`))
+	}
+	return res
+}()
+
+// Extract убирает разметку HTML и возвращает текст документа.
+func (HTMLExtractor) Extract(r io.ReaderAt, size int64, limit int64) (string, error) {
+	data, err := readAll(r, size)
+	if err != nil {
+		return "", err
+	}
+	text := toUTF8(data)
+
+	text = htmlCommentRe.ReplaceAllString(text, "")
+	for _, re := range htmlSkipRes {
+		text = re.ReplaceAllString(text, "")
+	}
+	// Переводы строк в HTML не значимы: вся структура задается тегами
+	text = htmlSpaceRe.ReplaceAllString(text, " ")
+	text = htmlBreakRe.ReplaceAllString(text, "\n")
+	text = htmlCellRe.ReplaceAllString(text, "\t")
+	text = htmlBlockRe.ReplaceAllString(text, "\n\n")
+	text = htmlTagRe.ReplaceAllString(text, "")
+	text = html.UnescapeString(text)
+	text = htmlLineRe.ReplaceAllString(text, "\n")
+	return limitText(normalizeText(text), limit)
+}
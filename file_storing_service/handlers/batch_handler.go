@@ -0,0 +1,390 @@
+package handlers
+
+import (
+	"archive/zip"
+	"errors"
+	"file_storing_service/models"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"path/filepath"
+	"pkg/auth"
+	"pkg/limits"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// errTooManyBatchEntries возвращается, если в пакете больше файлов, чем MaxBatchEntries.
+var errTooManyBatchEntries = errors.New("слишком много файлов в пакете")
+
+// BatchEntryResult — результат загрузки одного файла пакета.
+// @Description Результат загрузки файла пакета: сведения о сохраненном файле или ошибка.
+// @Name BatchEntryResult
+type BatchEntryResult struct {
+	Name   string              `json:"name" example:"essays.zip/ivanov.docx"` // Имя файла; для файла из архива — имя архива и путь внутри него
+	Status int                 `json:"status" example:"201"`                  // HTTP-статус, с которым завершилась бы загрузка этого файла по отдельности
+	File   *UploadFileResponse `json:"file,omitempty"`
+	Error  string              `json:"error,omitempty" example:"Неверный формат файла. Допускаются: .docx, .html, .md, .pdf, .rtf, .txt."`
+}
+
+// BatchUploadResponse — результат пакетной загрузки.
+// @Description ID и имя пакета и результат загрузки каждого файла.
+// @Name BatchUploadResponse
+type BatchUploadResponse struct {
+	ID       string             `json:"id,omitempty" example:"batch-id"` // Пусто, если ни один файл не сохранен
+	Name     string             `json:"name" example:"Группа 101, эссе"`
+	Analyze  bool               `json:"analyze" example:"true"` // Поставлены ли сохраненные файлы в очередь анализа
+	Uploaded int                `json:"uploaded" example:"24"`
+	Failed   int                `json:"failed" example:"1"`
+	Files    []BatchEntryResult `json:"files"`
+}
+
+// BatchResponse — пакет и его файлы.
+// @Description Пакет файлов и метаданные его неудаленных файлов.
+// @Name BatchResponse
+type BatchResponse struct {
+	ID        string        `json:"id" example:"batch-id"`
+	Name      string        `json:"name" example:"Группа 101, эссе"`
+	OwnerID   string        `json:"owner_id" example:"user-42"`
+	CreatedAt time.Time     `json:"created_at"`
+	Files     []models.File `json:"files"`
+}
+
+// batchEntry — файл пакета, прочитанный из запроса или архива, или ошибка его чтения.
+type batchEntry struct {
+	Name   string
+	Upload *receivedUpload
+	Err    *uploadError
+}
+
+// UploadBatch загружает несколько файлов одним запросом.
+// @Summary Пакетная загрузка файлов
+// @Description Принимает несколько файлов в полях files формы multipart/form-data. Файлы .zip распаковываются: каждый файл архива
+// @Description поддерживаемого формата сохраняется как отдельный файл. Все сохраненные файлы относятся к одному именованному пакету.
+// @Description Каждый файл сохраняется независимо: ответ содержит результат для каждого файла. Ограничения: размер каждого файла — MAX_UPLOAD_SIZE,
+// @Description суммарный размер файлов (и распакованного содержимого архивов) — MAX_BATCH_SIZE, количество файлов — MAX_BATCH_ENTRIES.
+// @Tags files
+// @Accept multipart/form-data
+// @Param files formData file true "Файлы или ZIP-архивы (поле можно повторять)"
+// @Param name formData string false "Имя пакета"
+// @Param analyze formData bool false "Поставить сохраненные файлы в очередь анализа"
+// @Param collection_id formData string false "ID коллекции, в которую добавляются сохраненные файлы"
+// @Produce json
+// @Success 201 {object} BatchUploadResponse "Сохранен хотя бы один файл; результат для каждого файла"
+// @Failure 400 {object} BatchUploadResponse "Не сохранен ни один файл, или запрос некорректен"
+// @Failure 404 {object} map[string]string "Коллекция не найдена"
+// @Failure 413 {object} map[string]string "Суммарный размер запроса больше допустимого"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /batches [post]
+func (h *FileHandler) UploadBatch(c *gin.Context) {
+	tooLarge := gin.H{"error": fmt.Sprintf("Пакет слишком большой: максимальный суммарный размер — %d байт", h.MaxBatchSize)}
+	bodyLimit := h.MaxBatchSize + limits.MultipartOverhead
+	if c.Request.ContentLength > bodyLimit {
+		c.JSON(http.StatusRequestEntityTooLarge, tooLarge)
+		return
+	}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, bodyLimit)
+
+	entries, fields, err := h.receiveBatch(c.Request)
+	defer func() {
+		for _, entry := range entries {
+			if entry.Upload != nil {
+				entry.Upload.Remove()
+			}
+		}
+	}()
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		switch {
+		case errors.As(err, &maxBytesErr):
+			c.JSON(http.StatusRequestEntityTooLarge, tooLarge)
+		case errors.Is(err, errTooManyBatchEntries):
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Пакет может содержать не более %d файлов", h.MaxBatchEntries)})
+		case errors.Is(err, http.ErrNotMultipart):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Файлы не предоставлены"})
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Не удалось прочитать загруженные файлы: " + err.Error()})
+		}
+		return
+	}
+	if len(entries) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Файлы не предоставлены"})
+		return
+	}
+
+	analyze := false
+	if value := fields["analyze"]; value != "" {
+		analyze, err = strconv.ParseBool(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Параметр analyze должен быть true или false"})
+			return
+		}
+	}
+	identity, _ := auth.FromHeaders(c.Request.Header)
+	collectionID := strings.TrimSpace(fields["collection_id"])
+	if collectionID != "" {
+		if err := h.checkCollection(collectionID, identity.UserID); err != nil {
+			c.JSON(err.Status, gin.H{"error": err.Message})
+			return
+		}
+	}
+	batch := models.Batch{
+		ID:      uuid.New().String(),
+		Name:    strings.TrimSpace(fields["name"]),
+		OwnerID: identity.UserID,
+	}
+	if batch.Name == "" {
+		batch.Name = "Пакет от " + time.Now().Format("2006-01-02 15:04:05")
+	}
+
+	// Пакет создается вместе с первым сохраненным файлом, поэтому пакетов без файлов не бывает
+	options := uploadOptions{
+		OwnerID:      identity.UserID,
+		BatchID:      batch.ID,
+		CollectionID: collectionID,
+		Analyze:      analyze,
+		InTx: func(tx *gorm.DB) error {
+			return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&batch).Error
+		},
+	}
+	response := BatchUploadResponse{Name: batch.Name, Analyze: analyze, Files: make([]BatchEntryResult, 0, len(entries))}
+	for _, entry := range entries {
+		result := BatchEntryResult{Name: entry.Name}
+		if entry.Err == nil {
+			file, err := h.saveUpload(entry.Upload, options)
+			if err == nil {
+				result.File = &file
+			}
+			entry.Err = err
+			// Временный файл больше не нужен; удаляем сразу, чтобы большой пакет не занимал место на диске дольше необходимого.
+			// Повторное удаление при выходе из функции ничего не делает
+			entry.Upload.Remove()
+		}
+		if entry.Err != nil {
+			result.Status = entry.Err.Status
+			result.Error = entry.Err.Message
+			response.Failed++
+		} else {
+			result.Status = http.StatusCreated
+			response.Uploaded++
+		}
+		response.Files = append(response.Files, result)
+	}
+
+	if response.Uploaded == 0 {
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+	response.ID = batch.ID
+	c.JSON(http.StatusCreated, response)
+}
+
+// receiveBatch потоково читает тело multipart/form-data пакетной загрузки: файлы полей files (и file) записываются во временные файлы,
+// ZIP-архивы распаковываются, а текстовые поля возвращаются в fields. Файл, не прошедший ограничения, возвращается с ошибкой в Err.
+// При ошибке возвращаются и уже прочитанные файлы, чтобы вызывающий удалил их временные файлы.
+func (h *FileHandler) receiveBatch(r *http.Request) (entries []batchEntry, fields map[string]string, err error) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, nil, err
+	}
+	fields = map[string]string{}
+	budget := h.MaxBatchSize // Сколько еще байт файлов можно принять
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return entries, fields, nil
+		}
+		if err != nil {
+			return entries, fields, err
+		}
+		if part.FileName() == "" {
+			value, err := readFormField(part)
+			if err != nil {
+				return entries, fields, err
+			}
+			fields[part.FormName()] = value
+			continue
+		}
+		if part.FormName() != "files" && part.FormName() != "file" {
+			continue // Остаток части пропускается при переходе к следующей
+		}
+		if len(entries) >= h.MaxBatchEntries {
+			return entries, fields, errTooManyBatchEntries
+		}
+
+		name := part.FileName()
+		if strings.EqualFold(filepath.Ext(name), ".zip") {
+			archive, err := spoolUpload(name, part, h.MaxBatchSize)
+			if errors.Is(err, errUploadTooLarge) {
+				entries = append(entries, batchEntry{Name: name, Err: &uploadError{http.StatusRequestEntityTooLarge, fmt.Sprintf("Архив больше %d байт", h.MaxBatchSize)}})
+				continue
+			}
+			if err != nil {
+				return entries, fields, err
+			}
+			unpacked, err := h.unpackArchive(archive, &budget, h.MaxBatchEntries-len(entries))
+			archive.Remove()
+			entries = append(entries, unpacked...)
+			if err != nil {
+				return entries, fields, err
+			}
+			continue
+		}
+
+		limit := h.entryLimit(budget)
+		upload, err := spoolUpload(name, part, limit)
+		if errors.Is(err, errUploadTooLarge) {
+			entries = append(entries, batchEntry{Name: name, Err: h.batchTooLargeError(limit)})
+			continue
+		}
+		if err != nil {
+			return entries, fields, err
+		}
+		budget -= upload.Size
+		entries = append(entries, batchEntry{Name: name, Upload: upload})
+	}
+}
+
+// unpackArchive распаковывает файлы ZIP-архива во временные файлы. Содержимое архива не записывается по путям из архива,
+// а пути файлов проверяются, чтобы в имена файлов не попадали абсолютные пути и выход за пределы архива (zip slip).
+// Суммарный размер распакованных файлов ограничивается остатком budget, который уменьшается на размер распакованных файлов.
+// Если в архиве больше maxEntries файлов, возвращается errTooManyBatchEntries.
+func (h *FileHandler) unpackArchive(archive *receivedUpload, budget *int64, maxEntries int) ([]batchEntry, error) {
+	zr, err := zip.NewReader(archive.File, archive.Size)
+	if err != nil {
+		return []batchEntry{{Name: archive.Name, Err: &uploadError{http.StatusBadRequest, fmt.Sprintf("Не удалось прочитать ZIP-архив: %v", err)}}}, nil
+	}
+
+	var files []*zip.File
+	var declared uint64
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() || isArchiveMetadata(f.Name) {
+			continue
+		}
+		files = append(files, f)
+		declared += f.UncompressedSize64
+	}
+	if len(files) > maxEntries {
+		return nil, errTooManyBatchEntries
+	}
+	if declared > uint64(*budget) {
+		return []batchEntry{{Name: archive.Name, Err: &uploadError{http.StatusRequestEntityTooLarge, fmt.Sprintf("Суммарный размер распакованных файлов архива больше допустимого (%d байт)", h.MaxBatchSize)}}}, nil
+	}
+
+	entries := make([]batchEntry, 0, len(files))
+	for _, f := range files {
+		entry := batchEntry{Name: archive.Name + "/" + f.Name}
+		entryPath, ok := safeArchivePath(f.Name)
+		if !ok {
+			entry.Err = &uploadError{http.StatusBadRequest, "Недопустимый путь файла в архиве"}
+			entries = append(entries, entry)
+			continue
+		}
+
+		// Заявленный в архиве размер может не совпадать с действительным, поэтому распакованное содержимое тоже ограничивается
+		limit := h.entryLimit(*budget)
+		if f.UncompressedSize64 > uint64(limit) {
+			entry.Err = h.batchTooLargeError(limit)
+			entries = append(entries, entry)
+			continue
+		}
+		content, err := f.Open()
+		if err != nil {
+			entry.Err = &uploadError{http.StatusBadRequest, fmt.Sprintf("Не удалось распаковать файл: %v", err)}
+			entries = append(entries, entry)
+			continue
+		}
+		upload, err := spoolUpload(path.Base(entryPath), content, limit)
+		content.Close()
+		switch {
+		case errors.Is(err, errUploadTooLarge):
+			entry.Err = h.batchTooLargeError(limit)
+		case err != nil:
+			entry.Err = &uploadError{http.StatusBadRequest, fmt.Sprintf("Не удалось распаковать файл: %v", err)}
+		default:
+			*budget -= upload.Size
+			entry.Upload = upload
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// entryLimit возвращает наибольший допустимый размер следующего файла пакета при остатке budget суммарного размера.
+func (h *FileHandler) entryLimit(budget int64) int64 {
+	if budget < h.MaxFileSize {
+		return budget
+	}
+	return h.MaxFileSize
+}
+
+// batchTooLargeError возвращает ошибку файла пакета, не уложившегося в limit байт: либо файл больше MaxFileSize,
+// либо превышен суммарный размер пакета.
+func (h *FileHandler) batchTooLargeError(limit int64) *uploadError {
+	if limit < h.MaxFileSize {
+		return &uploadError{http.StatusRequestEntityTooLarge, fmt.Sprintf("Превышен суммарный размер файлов пакета (%d байт)", h.MaxBatchSize)}
+	}
+	return &uploadError{http.StatusRequestEntityTooLarge, fmt.Sprintf("Файл слишком большой: максимальный размер — %d байт", h.MaxFileSize)}
+}
+
+// safeArchivePath нормализует путь файла в архиве и проверяет, что он относительный и не выходит за пределы архива.
+func safeArchivePath(name string) (string, bool) {
+	name = strings.ReplaceAll(name, "\\", "/")
+	cleaned := path.Clean(name)
+	if cleaned == "." || cleaned == ".." || path.IsAbs(cleaned) || strings.HasPrefix(cleaned, "../") || strings.Contains(cleaned, ":") {
+		return "", false
+	}
+	return cleaned, true
+}
+
+// isArchiveMetadata проверяет, что файл архива — служебный файл архиватора, а не документ пользователя.
+func isArchiveMetadata(name string) bool {
+	return strings.HasPrefix(name, "__MACOSX/") || path.Base(name) == ".DS_Store"
+}
+
+// GetBatch возвращает пакет и его файлы.
+// @Summary Получение пакета по ID
+// @Description Возвращает имя пакета и метаданные его неудаленных файлов.
+// @Tags files
+// @Param id path string true "ID пакета"
+// @Produce json
+// @Success 200 {object} BatchResponse "Пакет и его файлы"
+// @Failure 404 {object} map[string]string "Пакет не найден"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /batches/{id} [get]
+func (h *FileHandler) GetBatch(c *gin.Context) {
+	var batch models.Batch
+	if err := h.DB.First(&batch, "id = ?", c.Param("id")).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Пакет не найден"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при поиске пакета"})
+		}
+		return
+	}
+	// Чужой пакет для пользователя не отличается от несуществующего
+	if identity, ok := auth.FromHeaders(c.Request.Header); ok && !identity.CanAccess(batch.OwnerID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Пакет не найден"})
+		return
+	}
+
+	var files []models.File
+	if err := h.DB.Where("batch_id = ?", batch.ID).Order("created_at asc").Find(&files).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить файлы пакета"})
+		return
+	}
+	c.JSON(http.StatusOK, BatchResponse{
+		ID:        batch.ID,
+		Name:      batch.Name,
+		OwnerID:   batch.OwnerID,
+		CreatedAt: batch.CreatedAt,
+		Files:     files,
+	})
+}
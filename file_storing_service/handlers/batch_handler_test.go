@@ -0,0 +1,246 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSafeArchivePath(t *testing.T) {
+	tests := []struct {
+		name string
+		want string // Пусто — путь отклоняется
+	}{
+		{"essay.txt", "essay.txt"},
+		{"group/ivanov.docx", "group/ivanov.docx"},
+		{"group/./drafts/../ivanov.docx", "group/ivanov.docx"},
+		{`group\ivanov.docx`, "group/ivanov.docx"},
+		{"group/../essay.txt", "essay.txt"},
+		{"../essay.txt", ""},
+		{"group/../../essay.txt", ""},
+		{`..\..\essay.txt`, ""},
+		{`group\..\..\essay.txt`, ""},
+		{"..", ""},
+		{".", ""},
+		{"", ""},
+		{"/etc/passwd", ""},
+		{`\Windows\win.ini`, ""},
+		{`C:\Windows\win.ini`, ""},
+		{"C:essay.txt", ""},
+	}
+	for _, tt := range tests {
+		got, ok := safeArchivePath(tt.name)
+		if ok != (tt.want != "") || got != tt.want {
+			t.Errorf("safeArchivePath(%q) = %q, %v; ожидалось %q", tt.name, got, ok, tt.want)
+		}
+	}
+}
+
+// archiveFile — файл тестового ZIP-архива.
+type archiveFile struct {
+	name string
+	size int
+}
+
+// testArchive записывает ZIP-архив с файлами files во временный файл, как его записала бы загрузка пакета.
+func testArchive(t *testing.T, files ...archiveFile) *receivedUpload {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, f := range files {
+		w, err := zw.Create(f.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(bytes.Repeat([]byte("a"), f.size))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	archive, err := spoolUpload("essays.zip", &buf, int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(archive.Remove)
+	return archive
+}
+
+func newBatchTestHandler() *FileHandler {
+	return &FileHandler{MaxFileSize: 100, MaxBatchSize: 250, MaxBatchEntries: 5}
+}
+
+func removeEntries(entries []batchEntry) {
+	for _, entry := range entries {
+		if entry.Upload != nil {
+			entry.Upload.Remove()
+		}
+	}
+}
+
+func TestUnpackArchive(t *testing.T) {
+	tests := []struct {
+		name       string
+		files      []archiveFile
+		budget     int64
+		maxEntries int
+		wantErr    error
+		want       map[string]int // Ожидаемый статус ошибки каждого файла; 0 — файл распакован
+		wantBudget int64
+	}{
+		{
+			name: "недопустимые пути",
+			files: []archiveFile{
+				{"group/ivanov.txt", 10}, {"../evil.txt", 1}, {"/etc/evil.txt", 1}, {`..\..\evil.txt`, 1},
+				{`C:\evil.txt`, 1}, {`group\petrov.txt`, 20},
+			},
+			budget:     250,
+			maxEntries: 10,
+			want: map[string]int{
+				"essays.zip/group/ivanov.txt": 0, "essays.zip/../evil.txt": http.StatusBadRequest, "essays.zip//etc/evil.txt": http.StatusBadRequest,
+				`essays.zip/..\..\evil.txt`: http.StatusBadRequest, `essays.zip/C:\evil.txt`: http.StatusBadRequest, `essays.zip/group\petrov.txt`: 0,
+			},
+			wantBudget: 220,
+		},
+		{
+			name:       "файл больше MaxFileSize",
+			files:      []archiveFile{{"small.txt", 100}, {"big.txt", 101}},
+			budget:     250,
+			maxEntries: 10,
+			want:       map[string]int{"essays.zip/small.txt": 0, "essays.zip/big.txt": http.StatusRequestEntityTooLarge},
+			wantBudget: 150,
+		},
+		{
+			name:       "заявленный размер больше остатка бюджета",
+			files:      []archiveFile{{"a.txt", 100}, {"b.txt", 100}, {"c.txt", 40}},
+			budget:     200,
+			maxEntries: 10,
+			want:       map[string]int{"essays.zip": http.StatusRequestEntityTooLarge},
+			wantBudget: 200,
+		},
+		{
+			name:       "файлы ровно по бюджету",
+			files:      []archiveFile{{"a.txt", 100}, {"b.txt", 60}},
+			budget:     160,
+			maxEntries: 10,
+			want:       map[string]int{"essays.zip/a.txt": 0, "essays.zip/b.txt": 0},
+			wantBudget: 0,
+		},
+		{
+			name:       "служебные файлы архиватора не учитываются",
+			files:      []archiveFile{{"a.txt", 10}, {"__MACOSX/._a.txt", 200}, {"group/.DS_Store", 200}},
+			budget:     250,
+			maxEntries: 1,
+			want:       map[string]int{"essays.zip/a.txt": 0},
+			wantBudget: 240,
+		},
+		{
+			name:       "файлов больше допустимого",
+			files:      []archiveFile{{"a.txt", 1}, {"b.txt", 1}, {"c.txt", 1}},
+			budget:     250,
+			maxEntries: 2,
+			wantErr:    errTooManyBatchEntries,
+			wantBudget: 250,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			budget := tt.budget
+			entries, err := newBatchTestHandler().unpackArchive(testArchive(t, tt.files...), &budget, tt.maxEntries)
+			defer removeEntries(entries)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ошибка %v, ожидалась %v", err, tt.wantErr)
+			}
+			if len(entries) != len(tt.want) {
+				t.Fatalf("файлов: %d, ожидалось %d", len(entries), len(tt.want))
+			}
+			for _, entry := range entries {
+				want, ok := tt.want[entry.Name]
+				switch {
+				case !ok:
+					t.Errorf("неожиданный файл %q", entry.Name)
+				case want == 0 && (entry.Err != nil || entry.Upload == nil):
+					t.Errorf("файл %q не распакован: %+v", entry.Name, entry.Err)
+				case want != 0 && (entry.Err == nil || entry.Err.Status != want || entry.Upload != nil):
+					t.Errorf("файл %q: ошибка %+v, ожидался статус %d", entry.Name, entry.Err, want)
+				}
+				if entry.Upload != nil && strings.ContainsAny(entry.Upload.Name, `/\`) {
+					t.Errorf("имя распакованного файла %q содержит путь", entry.Upload.Name)
+				}
+			}
+			if budget != tt.wantBudget {
+				t.Errorf("остаток бюджета %d, ожидалось %d", budget, tt.wantBudget)
+			}
+		})
+	}
+}
+
+// batchRequest возвращает запрос пакетной загрузки с файлами указанных размеров в поле files.
+func batchRequest(t *testing.T, sizes ...int) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	mw.WriteField("name", "Группа 101")
+	for i, size := range sizes {
+		w, err := mw.CreateFormFile("files", string(rune('a'+i))+".txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(bytes.Repeat([]byte("a"), size))
+	}
+	mw.Close()
+	r := httptest.NewRequest(http.MethodPost, "/api/v1/batches", &body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	return r
+}
+
+func TestReceiveBatchBudget(t *testing.T) {
+	h := newBatchTestHandler()
+	entries, fields, err := h.receiveBatch(batchRequest(t, 100, 101, 100, 60, 50))
+	defer removeEntries(entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fields["name"] != "Группа 101" {
+		t.Errorf("поле name: %q", fields["name"])
+	}
+	// a — в пределах MaxFileSize, b — больше MaxFileSize, c — исчерпывает остаток до 50 байт,
+	// d — больше остатка суммарного размера, e — занимает остаток целиком
+	want := []struct {
+		status  int
+		message string
+	}{
+		{0, ""},
+		{http.StatusRequestEntityTooLarge, "Файл слишком большой"},
+		{0, ""},
+		{http.StatusRequestEntityTooLarge, "Превышен суммарный размер"},
+		{0, ""},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("файлов: %d, ожидалось %d", len(entries), len(want))
+	}
+	for i, entry := range entries {
+		switch {
+		case want[i].status == 0 && (entry.Err != nil || entry.Upload == nil):
+			t.Errorf("файл %s не принят: %+v", entry.Name, entry.Err)
+		case want[i].status != 0 && (entry.Err == nil || entry.Err.Status != want[i].status || !strings.Contains(entry.Err.Message, want[i].message)):
+			t.Errorf("файл %s: ошибка %+v, ожидался статус %d и %q", entry.Name, entry.Err, want[i].status, want[i].message)
+		}
+	}
+}
+
+func TestReceiveBatchTooManyEntries(t *testing.T) {
+	h := newBatchTestHandler()
+	entries, _, err := h.receiveBatch(batchRequest(t, 1, 1, 1, 1, 1, 1))
+	defer removeEntries(entries)
+	if !errors.Is(err, errTooManyBatchEntries) {
+		t.Fatalf("ошибка %v, ожидалась errTooManyBatchEntries", err)
+	}
+	if len(entries) != h.MaxBatchEntries {
+		t.Fatalf("до ошибки прочитано %d файлов, ожидалось %d", len(entries), h.MaxBatchEntries)
+	}
+}
@@ -0,0 +1,435 @@
+package handlers
+
+import (
+	"errors"
+	"file_storing_service/models"
+	"fmt"
+	"net/http"
+	"pkg/auth"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	maxCollectionNameLength        = 200  // Наибольшая длина имени коллекции в символах
+	maxCollectionDescriptionLength = 2000 // Наибольшая длина описания коллекции в символах
+	// collectionNotFoundMessage — ответ на запрос к несуществующей или чужой коллекции.
+	collectionNotFoundMessage = "Коллекция не найдена"
+)
+
+// errCollectionNotFound возвращается, если коллекции нет или она принадлежит другому пользователю.
+var errCollectionNotFound = errors.New("коллекция не найдена")
+
+// CollectionRequest — запрос на создание коллекции.
+// @Description Имя и необязательное описание коллекции.
+// @Name CollectionRequest
+type CollectionRequest struct {
+	Name        string `json:"name" binding:"required" example:"Задание 3"`
+	Description string `json:"description" example:"Эссе по истории, группа 101"`
+}
+
+// UpdateCollectionRequest — запрос на изменение коллекции. Изменяются только переданные поля.
+// @Description Новое имя и (или) описание коллекции.
+// @Name UpdateCollectionRequest
+type UpdateCollectionRequest struct {
+	Name        *string `json:"name" example:"Задание 3"`
+	Description *string `json:"description" example:"Эссе по истории, группа 101"`
+}
+
+// CollectionResponse — коллекция и ее файлы.
+// @Description Коллекция, количество ее неудаленных файлов и, при запросе одной коллекции, их метаданные.
+// @Name CollectionResponse
+type CollectionResponse struct {
+	ID          string        `json:"id" example:"collection-id"`
+	Name        string        `json:"name" example:"Задание 3"`
+	Description string        `json:"description,omitempty" example:"Эссе по истории, группа 101"`
+	OwnerID     string        `json:"owner_id" example:"user-42"`
+	FileCount   int64         `json:"file_count" example:"25"`
+	CreatedAt   time.Time     `json:"created_at"`
+	UpdatedAt   time.Time     `json:"updated_at"`
+	Files       []models.File `json:"files,omitempty"`
+}
+
+// newCollectionResponse возвращает ответ с коллекцией без файлов.
+func newCollectionResponse(collection models.Collection, fileCount int64) CollectionResponse {
+	return CollectionResponse{
+		ID:          collection.ID,
+		Name:        collection.Name,
+		Description: collection.Description,
+		OwnerID:     collection.OwnerID,
+		FileCount:   fileCount,
+		CreatedAt:   collection.CreatedAt,
+		UpdatedAt:   collection.UpdatedAt,
+	}
+}
+
+// validateCollection проверяет имя и описание коллекции и возвращает текст ошибки или пустую строку.
+func validateCollection(name, description string) string {
+	if name == "" {
+		return "Имя коллекции не может быть пустым"
+	}
+	if utf8.RuneCountInString(name) > maxCollectionNameLength {
+		return fmt.Sprintf("Имя коллекции длиннее %d символов", maxCollectionNameLength)
+	}
+	if utf8.RuneCountInString(description) > maxCollectionDescriptionLength {
+		return fmt.Sprintf("Описание коллекции длиннее %d символов", maxCollectionDescriptionLength)
+	}
+	return ""
+}
+
+// CreateCollection создает коллекцию файлов.
+// @Summary Создание коллекции
+// @Description Создает именованную коллекцию (например, задание), в которую файлы добавляются при загрузке (поле collection_id)
+// @Description или позже запросом PUT /collections/{id}/files/{file_id}.
+// @Tags collections
+// @Accept json
+// @Param request body CollectionRequest true "Имя и описание коллекции"
+// @Produce json
+// @Success 201 {object} CollectionResponse "Созданная коллекция"
+// @Failure 400 {object} map[string]string "Некорректный запрос"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /collections [post]
+func (h *FileHandler) CreateCollection(c *gin.Context) {
+	var request CollectionRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный запрос: " + err.Error()})
+		return
+	}
+	identity, _ := auth.FromHeaders(c.Request.Header)
+	collection := models.Collection{
+		ID:          uuid.New().String(),
+		Name:        strings.TrimSpace(request.Name),
+		Description: strings.TrimSpace(request.Description),
+		OwnerID:     identity.UserID,
+	}
+	if message := validateCollection(collection.Name, collection.Description); message != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": message})
+		return
+	}
+	if err := h.DB.Create(&collection).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось создать коллекцию"})
+		return
+	}
+	c.JSON(http.StatusCreated, newCollectionResponse(collection, 0))
+}
+
+// ListCollections возвращает список коллекций.
+// @Summary Список коллекций
+// @Description Возвращает коллекции с количеством файлов в каждой: пользователю — только его коллекции, администратору — все.
+// @Tags collections
+// @Produce json
+// @Success 200 {array} CollectionResponse "Список коллекций"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /collections [get]
+func (h *FileHandler) ListCollections(c *gin.Context) {
+	query := h.DB
+	if identity, ok := auth.FromHeaders(c.Request.Header); ok && !identity.IsAdmin() {
+		query = query.Where("owner_id = ?", identity.UserID)
+	}
+	var collections []models.Collection
+	if err := query.Order("created_at asc").Find(&collections).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить список коллекций"})
+		return
+	}
+
+	counts := make(map[string]int64, len(collections))
+	if len(collections) > 0 {
+		ids := make([]string, len(collections))
+		for i, collection := range collections {
+			ids[i] = collection.ID
+		}
+		var rows []struct {
+			CollectionID string
+			Count        int64
+		}
+		err := h.DB.Model(&models.File{}).Select("collection_id, count(*) AS count").
+			Where("collection_id IN ?", ids).Group("collection_id").Scan(&rows).Error
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось подсчитать файлы коллекций"})
+			return
+		}
+		for _, row := range rows {
+			counts[row.CollectionID] = row.Count
+		}
+	}
+
+	response := make([]CollectionResponse, len(collections))
+	for i, collection := range collections {
+		response[i] = newCollectionResponse(collection, counts[collection.ID])
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// GetCollection возвращает коллекцию и ее файлы.
+// @Summary Получение коллекции
+// @Description Возвращает коллекцию и метаданные ее неудаленных файлов.
+// @Tags collections
+// @Param id path string true "ID коллекции"
+// @Produce json
+// @Success 200 {object} CollectionResponse "Коллекция и ее файлы"
+// @Failure 404 {object} map[string]string "Коллекция не найдена"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /collections/{id} [get]
+func (h *FileHandler) GetCollection(c *gin.Context) {
+	collection, ok := h.findCollection(c)
+	if !ok {
+		return
+	}
+	var files []models.File
+	if err := h.DB.Where("collection_id = ?", collection.ID).Order("created_at asc").Find(&files).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить файлы коллекции"})
+		return
+	}
+	response := newCollectionResponse(collection, int64(len(files)))
+	response.Files = files
+	c.JSON(http.StatusOK, response)
+}
+
+// UpdateCollection изменяет имя или описание коллекции.
+// @Summary Изменение коллекции
+// @Description Изменяет имя и (или) описание коллекции; непереданные поля не меняются.
+// @Tags collections
+// @Accept json
+// @Param id path string true "ID коллекции"
+// @Param request body UpdateCollectionRequest true "Новое имя и (или) описание"
+// @Produce json
+// @Success 200 {object} CollectionResponse "Измененная коллекция"
+// @Failure 400 {object} map[string]string "Некорректный запрос"
+// @Failure 404 {object} map[string]string "Коллекция не найдена"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /collections/{id} [patch]
+func (h *FileHandler) UpdateCollection(c *gin.Context) {
+	var request UpdateCollectionRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный запрос: " + err.Error()})
+		return
+	}
+	collection, ok := h.findCollection(c)
+	if !ok {
+		return
+	}
+	if request.Name != nil {
+		collection.Name = strings.TrimSpace(*request.Name)
+	}
+	if request.Description != nil {
+		collection.Description = strings.TrimSpace(*request.Description)
+	}
+	if message := validateCollection(collection.Name, collection.Description); message != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": message})
+		return
+	}
+	err := h.DB.Model(&collection).Updates(map[string]any{"name": collection.Name, "description": collection.Description}).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось изменить коллекцию"})
+		return
+	}
+
+	var fileCount int64
+	if err := h.DB.Model(&models.File{}).Where("collection_id = ?", collection.ID).Count(&fileCount).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось подсчитать файлы коллекции"})
+		return
+	}
+	c.JSON(http.StatusOK, newCollectionResponse(collection, fileCount))
+}
+
+// DeleteCollection удаляет коллекцию. Файлы коллекции не удаляются, а только перестают в нее входить.
+// @Summary Удаление коллекции
+// @Description Удаляет коллекцию; ее файлы остаются у пользователя без коллекции.
+// @Tags collections
+// @Param id path string true "ID коллекции"
+// @Success 204 {string} string "Коллекция удалена"
+// @Failure 404 {object} map[string]string "Коллекция не найдена"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /collections/{id} [delete]
+func (h *FileHandler) DeleteCollection(c *gin.Context) {
+	collection, ok := h.findCollection(c)
+	if !ok {
+		return
+	}
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		// Удаление записи блокирует ее, поэтому параллельная загрузка файла в коллекцию дождется удаления и не найдет коллекцию
+		if err := tx.Delete(&collection).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Model(&models.File{}).Where("collection_id = ?", collection.ID).Update("collection_id", "").Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось удалить коллекцию"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// AddCollectionFile добавляет файл в коллекцию.
+// @Summary Добавление файла в коллекцию
+// @Description Добавляет ранее загруженный файл в коллекцию. Файл, входивший в другую коллекцию, переносится в эту.
+// @Description Файл и коллекция должны принадлежать одному пользователю.
+// @Tags collections
+// @Param id path string true "ID коллекции"
+// @Param file_id path string true "ID файла"
+// @Produce json
+// @Success 200 {object} models.File "Метаданные файла"
+// @Failure 400 {object} map[string]string "Файл и коллекция принадлежат разным пользователям"
+// @Failure 404 {object} map[string]string "Коллекция или файл не найдены"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /collections/{id}/files/{file_id} [put]
+func (h *FileHandler) AddCollectionFile(c *gin.Context) {
+	collection, ok := h.findCollection(c)
+	if !ok {
+		return
+	}
+	fileMetadata, ok := h.findCollectionFile(c, "")
+	if !ok {
+		return
+	}
+	if fileMetadata.OwnerID != collection.OwnerID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Файл и коллекция принадлежат разным пользователям"})
+		return
+	}
+
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := lockCollection(tx, collection.ID, collection.OwnerID); err != nil {
+			return err
+		}
+		return tx.Model(&fileMetadata).Update("collection_id", collection.ID).Error
+	})
+	if errors.Is(err, errCollectionNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": collectionNotFoundMessage})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось добавить файл в коллекцию"})
+		return
+	}
+	c.JSON(http.StatusOK, fileMetadata)
+}
+
+// RemoveCollectionFile исключает файл из коллекции. Сам файл не удаляется.
+// @Summary Исключение файла из коллекции
+// @Description Исключает файл из коллекции; файл остается у пользователя без коллекции.
+// @Tags collections
+// @Param id path string true "ID коллекции"
+// @Param file_id path string true "ID файла"
+// @Success 204 {string} string "Файл исключен из коллекции"
+// @Failure 404 {object} map[string]string "Коллекция не найдена или файл не входит в нее"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /collections/{id}/files/{file_id} [delete]
+func (h *FileHandler) RemoveCollectionFile(c *gin.Context) {
+	collection, ok := h.findCollection(c)
+	if !ok {
+		return
+	}
+	fileMetadata, ok := h.findCollectionFile(c, collection.ID)
+	if !ok {
+		return
+	}
+	if err := h.DB.Model(&fileMetadata).Update("collection_id", "").Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось исключить файл из коллекции"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// CollectionFilesResponse — владелец коллекции и ID ее файлов.
+// @Description ID коллекции, ее владелец и ID ее неудаленных файлов.
+// @Name CollectionFilesResponse
+type CollectionFilesResponse struct {
+	ID      string   `json:"id" example:"collection-id"`
+	OwnerID string   `json:"owner_id" example:"user-42"`
+	FileIDs []string `json:"file_ids" example:"unique-file-id,other-file-id"`
+}
+
+// GetCollectionFiles возвращает владельца и ID файлов коллекции. Используется FileAnalysisService для сравнения файлов коллекции.
+// @Summary Получение файлов коллекции (внутренний)
+// @Description Возвращает владельца коллекции и ID ее неудаленных файлов.
+// @Tags collections
+// @Param id path string true "ID коллекции"
+// @Produce json
+// @Success 200 {object} CollectionFilesResponse "Владелец и файлы коллекции"
+// @Failure 404 {object} map[string]string "Коллекция не найдена"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /internal/collections/{id}/files [get]
+func (h *FileHandler) GetCollectionFiles(c *gin.Context) {
+	collection, ok := h.findCollection(c)
+	if !ok {
+		return
+	}
+	fileIDs := []string{}
+	if err := h.DB.Model(&models.File{}).Where("collection_id = ?", collection.ID).Order("created_at asc").Pluck("id", &fileIDs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить файлы коллекции"})
+		return
+	}
+	c.JSON(http.StatusOK, CollectionFilesResponse{ID: collection.ID, OwnerID: collection.OwnerID, FileIDs: fileIDs})
+}
+
+// findCollection ищет коллекцию по параметру пути id и при ошибке сам отправляет ответ.
+// Чужая коллекция для пользователя не отличается от несуществующей.
+func (h *FileHandler) findCollection(c *gin.Context) (models.Collection, bool) {
+	var collection models.Collection
+	if err := h.DB.First(&collection, "id = ?", c.Param("id")).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": collectionNotFoundMessage})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при поиске коллекции"})
+		}
+		return collection, false
+	}
+	if identity, ok := auth.FromHeaders(c.Request.Header); ok && !identity.CanAccess(collection.OwnerID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": collectionNotFoundMessage})
+		return collection, false
+	}
+	return collection, true
+}
+
+// findCollectionFile ищет файл по параметру пути file_id и при ошибке сам отправляет ответ.
+// Если collectionID не пуст, файл должен входить в эту коллекцию.
+func (h *FileHandler) findCollectionFile(c *gin.Context, collectionID string) (models.File, bool) {
+	query := h.DB.Where("id = ?", c.Param("file_id"))
+	if collectionID != "" {
+		query = query.Where("collection_id = ?", collectionID)
+	}
+	var fileMetadata models.File
+	if err := query.First(&fileMetadata).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Файл не найден"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при поиске файла"})
+		}
+		return fileMetadata, false
+	}
+	if identity, ok := auth.FromHeaders(c.Request.Header); ok && !identity.CanAccess(fileMetadata.OwnerID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Файл не найден"})
+		return fileMetadata, false
+	}
+	return fileMetadata, true
+}
+
+// checkCollection проверяет, что у пользователя ownerID есть коллекция id. Используется, чтобы отклонить загрузку
+// в несуществующую коллекцию до приема файлов; при сохранении файла коллекция проверяется повторно (lockCollection).
+func (h *FileHandler) checkCollection(id, ownerID string) *uploadError {
+	var count int64
+	if err := h.DB.Model(&models.Collection{}).Where("id = ? AND owner_id = ?", id, ownerID).Count(&count).Error; err != nil {
+		return &uploadError{http.StatusInternalServerError, "Ошибка при поиске коллекции"}
+	}
+	if count == 0 {
+		return &uploadError{http.StatusNotFound, collectionNotFoundMessage}
+	}
+	return nil
+}
+
+// lockCollection блокирует коллекцию id владельца ownerID до конца транзакции tx, чтобы ее не удалили,
+// пока в нее добавляется файл. Если коллекции нет, возвращается errCollectionNotFound.
+func lockCollection(tx *gorm.DB, id, ownerID string) error {
+	var collection models.Collection
+	err := tx.Clauses(clause.Locking{Strength: "SHARE"}).First(&collection, "id = ? AND owner_id = ?", id, ownerID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return errCollectionNotFound
+	}
+	return err
+}
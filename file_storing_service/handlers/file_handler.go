@@ -1,11 +1,28 @@
 package handlers
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"file_storing_service/extractors"
 	"file_storing_service/models"
+	"file_storing_service/services"
 	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"mime/multipart"
 	"net/http"
 	"os"
 	"path/filepath"
+	"pkg/adapters"
+	"pkg/auth"
+	"pkg/events"
+	"pkg/limits"
+	"pkg/pagination"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -20,202 +37,886 @@ import (
 // @Produce json
 // @Router /files [get]
 // @Router /files/{id} [get]
+// @Router /files/{id} [delete]
+// @Router /files/{id}/text [get]
 // @Router /files/upload [post]
+// @Router /uploads [post]
+// @Router /batches [post]
+// @Router /collections [post]
+// @Router /search [get]
 type FileHandler struct {
 	DB              *gorm.DB
-	FileStoragePath string
+	Storage         adapters.FileStorage     // Хранилище содержимого файлов (локальное или S3)
+	Extractors      *extractors.Registry     // Экстракторы текста для поддерживаемых форматов
+	Purger          *services.FilePurger     // Очистка данных удаленных файлов
+	Relay           *services.OutboxRelay    // Доставка событий о файлах в FileAnalysisService
+	Quota           *services.QuotaManager   // Учет объема файлов пользователей и их квоты
+	Uploads         *services.UploadSessions // Сессии возобновляемой загрузки
+	SearchIndex     *services.SearchIndex    // Полнотекстовый поиск по извлеченному тексту файлов
+	MaxFileSize     int64                    // Максимальный размер загружаемого файла в байтах
+	MaxBatchSize    int64                    // Максимальный суммарный размер файлов пакетной загрузки в байтах
+	MaxBatchEntries int                      // Максимальное количество файлов в пакетной загрузке
+	// Сколько байт можно распаковать из документа (DOCX, потоки PDF) и наибольший размер извлеченного текста;
+	// 0 — extractors.DefaultExpansionRatio размеров MaxFileSize
+	MaxExtractedSize int64
 }
 
 // NewFileHandler создает новый экземпляр FileHandler.
 // @Summary Создает новый FileHandler
-// @Description Инициализирует FileHandler с подключением к базе данных и путем к хранилищу файлов.
+// @Description Инициализирует FileHandler с подключением к базе данных, хранилищем файлов, реестром экстракторов текста,
+// @Description очисткой удаленных файлов, ретранслятором событий, учетом квот, сессиями возобновляемой загрузки
+// @Description и поисковым индексом. Ограничения размера загрузки — значения по умолчанию из pkg/limits.
 // @Return *FileHandler
-func NewFileHandler(db *gorm.DB, fileStoragePath string) *FileHandler {
-	return &FileHandler{DB: db, FileStoragePath: fileStoragePath}
+func NewFileHandler(db *gorm.DB, storage adapters.FileStorage, registry *extractors.Registry, purger *services.FilePurger, relay *services.OutboxRelay, quota *services.QuotaManager, uploads *services.UploadSessions, search *services.SearchIndex) *FileHandler {
+	return &FileHandler{
+		DB:              db,
+		Storage:         storage,
+		Extractors:      registry,
+		Purger:          purger,
+		Relay:           relay,
+		Quota:           quota,
+		Uploads:         uploads,
+		SearchIndex:     search,
+		MaxFileSize:     limits.DefaultMaxUploadSize,
+		MaxBatchSize:    limits.DefaultMaxBatchSize,
+		MaxBatchEntries: limits.DefaultMaxBatchEntries,
+	}
+}
+
+// DuplicateInfo описывает ранее загруженный файл с идентичным содержимым.
+// @Description Сведения о самом раннем файле с тем же SHA-256 хешем.
+// @Name DuplicateInfo
+type DuplicateInfo struct {
+	FileID     string    `json:"file_id" example:"original-file-id"`
+	UploadedAt time.Time `json:"uploaded_at" example:"2023-01-01T12:00:00Z"`
+}
+
+// UploadFileResponse определяет структуру ответа на загрузку файла.
+// @Description ID загруженного файла, хеш его содержимого и сведения о дубликате, если он найден.
+// @Name UploadFileResponse
+type UploadFileResponse struct {
+	ID          string         `json:"id" example:"unique-file-id"`
+	Hash        string         `json:"hash" example:"9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"`
+	MimeType    string         `json:"mime_type" example:"application/pdf"`
+	Extractor   string         `json:"extractor" example:"pdf"`
+	IsDuplicate bool           `json:"is_duplicate" example:"false"`
+	DuplicateOf *DuplicateInfo `json:"duplicate_of,omitempty"`
+}
+
+// quotaExceededMessage — ответ на загрузку файла, не помещающегося в квоту хранилища пользователя.
+const quotaExceededMessage = "Файл не помещается в квоту хранилища пользователя"
+
+// errUploadTooLarge возвращается spoolUpload, если файл больше допустимого размера.
+var errUploadTooLarge = errors.New("файл слишком большой")
+
+// receivedUpload — файл из тела запроса, сохраненный во временный файл.
+type receivedUpload struct {
+	Name string
+	File *os.File // Временный файл; удаляется методом Remove
+	Size int64
+	Hash string // SHA-256 содержимого в hex
+}
+
+// Remove закрывает и удаляет временный файл.
+func (u *receivedUpload) Remove() {
+	u.File.Close()
+	_ = os.Remove(u.File.Name())
 }
 
-// UploadFile загружает файл, сохраняет его метаданные в БД и сам файл в хранилище.
+// maxFormFieldSize — максимальный размер текстового поля формы загрузки (collection_id, name, analyze).
+const maxFormFieldSize = 1 << 10
+
+// receiveUpload потоково читает поле file тела multipart/form-data во временный файл, вычисляя его хеш,
+// и возвращает текстовые поля формы, переданные до и после файла. Файл целиком в памяти не хранится.
+// Если файл больше limit байт, возвращается errUploadTooLarge.
+func receiveUpload(r *http.Request, limit int64) (upload *receivedUpload, fields map[string]string, err error) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() {
+		if err != nil && upload != nil {
+			upload.Remove()
+			upload = nil
+		}
+	}()
+	fields = map[string]string{}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			if upload == nil {
+				return nil, fields, http.ErrMissingFile
+			}
+			return upload, fields, nil
+		}
+		if err != nil {
+			return upload, fields, err
+		}
+		if part.FileName() == "" {
+			value, err := readFormField(part)
+			if err != nil {
+				return upload, fields, err
+			}
+			fields[part.FormName()] = value
+			continue
+		}
+		if part.FormName() != "file" || upload != nil {
+			continue // Остаток части пропускается при переходе к следующей; ее размер ограничен вместе со всем телом запроса
+		}
+
+		if upload, err = spoolUpload(part.FileName(), part, limit); err != nil {
+			return nil, fields, err
+		}
+	}
+}
+
+// readFormField читает текстовое поле формы, не длиннее maxFormFieldSize байт.
+func readFormField(part *multipart.Part) (string, error) {
+	value, err := io.ReadAll(io.LimitReader(part, maxFormFieldSize+1))
+	if err != nil {
+		return "", err
+	}
+	if len(value) > maxFormFieldSize {
+		return "", fmt.Errorf("поле %s длиннее %d байт", part.FormName(), maxFormFieldSize)
+	}
+	return string(value), nil
+}
+
+// spoolUpload записывает содержимое r во временный файл, вычисляя его хеш. Если содержимое больше limit байт,
+// временный файл удаляется и возвращается errUploadTooLarge.
+func spoolUpload(name string, r io.Reader, limit int64) (*receivedUpload, error) {
+	tmp, err := os.CreateTemp("", "upload-*")
+	if err != nil {
+		return nil, fmt.Errorf("не удалось создать временный файл: %w", err)
+	}
+	upload := &receivedUpload{Name: name, File: tmp}
+	hasher := sha256.New()
+	// Читаем на байт больше лимита, чтобы отличить файл ровно допустимого размера от большего
+	upload.Size, err = io.Copy(io.MultiWriter(tmp, hasher), io.LimitReader(r, limit+1))
+	if err == nil && upload.Size > limit {
+		err = errUploadTooLarge
+	}
+	if err != nil {
+		upload.Remove()
+		return nil, err
+	}
+	upload.Hash = hex.EncodeToString(hasher.Sum(nil))
+	return upload, nil
+}
+
+// UploadFile загружает файл, сохраняет его метаданные в БД, а сам файл и извлеченный из него текст — в хранилище.
 // @Summary Загрузка файла
-// @Description Загружает текстовый файл, сохраняет его и возвращает ID.
+// @Description Загружает документ (.txt, .md, .html, .docx, .rtf или .pdf), сохраняет исходный файл и его текстовое представление,
+// @Description на котором затем выполняется анализ. Возвращает ID, SHA-256 хеш исходного файла, MIME-тип и использованный экстрактор.
+// @Description Если ранее уже был загружен файл с идентичным содержимым, ответ содержит ID и время загрузки самого раннего из них.
+// @Description Тело запроса читается потоково. Файл больше MAX_UPLOAD_SIZE, не помещающийся в квоту пользователя или распаковывающийся больше чем в MAX_EXTRACTED_SIZE байт отклоняется с кодом 413.
 // @Tags files
 // @Accept multipart/form-data
-// @Param file formData file true "Файл для загрузки (только .txt)"
+// @Param file formData file true "Файл для загрузки (.txt, .md, .html, .docx, .rtf, .pdf)"
+// @Param collection_id formData string false "ID коллекции, в которую добавляется файл"
 // @Produce json
-// @Success 201 {object} map[string]string "ID загруженного файла"
-// @Failure 400 {object} map[string]string "Ошибка валидации или обработки файла"
+// @Success 201 {object} UploadFileResponse "ID загруженного файла и сведения о дубликате"
+// @Failure 400 {object} map[string]string "Ошибка валидации или извлечения текста"
+// @Failure 404 {object} map[string]string "Коллекция не найдена"
+// @Failure 413 {object} map[string]string "Файл больше допустимого размера или превышена квота хранилища"
 // @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
 // @Router /files/upload [post]
 func (h *FileHandler) UploadFile(c *gin.Context) {
-	file, err := c.FormFile("file")
+	tooLarge := gin.H{"error": fmt.Sprintf("Файл слишком большой: максимальный размер — %d байт", h.MaxFileSize)}
+	quotaExceeded := gin.H{"error": quotaExceededMessage}
+
+	// Тело запроса не может быть больше файла максимального размера с заголовками multipart
+	bodyLimit := h.MaxFileSize + limits.MultipartOverhead
+	if c.Request.ContentLength > bodyLimit {
+		c.JSON(http.StatusRequestEntityTooLarge, tooLarge)
+		return
+	}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, bodyLimit)
+
+	// Владелец файла — пользователь, от имени которого API Gateway передал запрос
+	identity, _ := auth.FromHeaders(c.Request.Header)
+
+	// Файл, не помещающийся в остаток квоты, отклоняется, как только прочитано больше остатка
+	limit := h.MaxFileSize
+	remaining, limited, err := h.Quota.Remaining(h.DB, identity.UserID)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Файл не предоставлен"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось проверить квоту хранилища"})
 		return
 	}
+	quotaLimited := limited && remaining < limit
+	if quotaLimited {
+		limit = remaining
+	}
 
-	if filepath.Ext(file.Filename) != ".txt" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат файла. Допускаются только .txt файлы."})
+	upload, fields, err := receiveUpload(c.Request, limit)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		switch {
+		case errors.Is(err, errUploadTooLarge) && quotaLimited:
+			c.JSON(http.StatusRequestEntityTooLarge, quotaExceeded)
+		case errors.Is(err, errUploadTooLarge) || errors.As(err, &maxBytesErr):
+			c.JSON(http.StatusRequestEntityTooLarge, tooLarge)
+		case errors.Is(err, http.ErrMissingFile) || errors.Is(err, http.ErrNotMultipart):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Файл не предоставлен"})
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Не удалось прочитать загруженный файл: " + err.Error()})
+		}
 		return
 	}
+	defer upload.Remove()
+
+	h.storeUpload(c, upload, uploadOptions{OwnerID: identity.UserID, CollectionID: strings.TrimSpace(fields["collection_id"])})
+}
+
+// uploadOptions — владелец сохраняемого файла и дополнительные действия при его сохранении.
+type uploadOptions struct {
+	OwnerID      string
+	BatchID      string                  // ID пакета, если файл загружен пакетной загрузкой
+	CollectionID string                  // ID коллекции владельца, в которую добавляется файл
+	Analyze      bool                    // Поставить файл в очередь анализа, даже если в FileAnalysisService выключен AUTO_ANALYZE
+	InTx         func(tx *gorm.DB) error // Выполняется в транзакции сохранения метаданных файла
+}
+
+// uploadError — ошибка сохранения файла с HTTP-статусом, с которым на нее нужно ответить.
+type uploadError struct {
+	Status  int
+	Message string
+}
+
+func (e *uploadError) Error() string { return e.Message }
+
+// storeUpload сохраняет полученный файл и отправляет ответ. Возвращает true, если файл сохранен.
+func (h *FileHandler) storeUpload(c *gin.Context, upload *receivedUpload, options uploadOptions) bool {
+	response, err := h.saveUpload(upload, options)
+	if err != nil {
+		c.JSON(err.Status, gin.H{"error": err.Message})
+		return false
+	}
+	c.JSON(http.StatusCreated, response)
+	return true
+}
+
+// extractionLimit возвращает ограничение распакованных данных и извлеченного текста одного документа.
+func (h *FileHandler) extractionLimit() int64 {
+	if h.MaxExtractedSize > 0 {
+		return h.MaxExtractedSize
+	}
+	return extractors.DefaultExpansionRatio * h.MaxFileSize
+}
+
+// saveUpload определяет формат полученного файла, извлекает из него текст и сохраняет файл, текст, метаданные
+// и событие file.uploaded.
+func (h *FileHandler) saveUpload(upload *receivedUpload, options uploadOptions) (UploadFileResponse, *uploadError) {
+	// Формат определяется по расширению и проверяется по первым байтам содержимого
+	head := make([]byte, 512)
+	n, err := upload.File.ReadAt(head, 0)
+	if err != nil && err != io.EOF {
+		return UploadFileResponse{}, &uploadError{http.StatusInternalServerError, "Не удалось прочитать загруженный файл"}
+	}
+	extractor, err := h.Extractors.Detect(upload.Name, head[:n])
+	if err != nil {
+		if errors.Is(err, extractors.ErrUnsupportedFormat) {
+			return UploadFileResponse{}, &uploadError{http.StatusBadRequest, fmt.Sprintf("Неверный формат файла. Допускаются: %s.", strings.Join(h.Extractors.Extensions(), ", "))}
+		}
+		return UploadFileResponse{}, &uploadError{http.StatusBadRequest, fmt.Sprintf("Содержимое файла не соответствует расширению: %v", err)}
+	}
+
+	text, err := extractor.Extract(upload.File, upload.Size, h.extractionLimit())
+	if errors.Is(err, extractors.ErrDocumentTooLarge) {
+		return UploadFileResponse{}, &uploadError{http.StatusRequestEntityTooLarge, fmt.Sprintf("Не удалось извлечь текст из файла: %v", err)}
+	}
+	if err != nil {
+		return UploadFileResponse{}, &uploadError{http.StatusBadRequest, fmt.Sprintf("Не удалось извлечь текст из файла: %v", err)}
+	}
 
 	fileID := uuid.New().String()
-	filePath := filepath.Join(h.FileStoragePath, fileID+".txt")
+	location := fileID + strings.ToLower(filepath.Ext(upload.Name)) // Ключ исходного файла в хранилище
+	textLocation := fileID + "_text.txt"                            // Ключ извлеченного текста
 
-	if err := c.SaveUploadedFile(file, filePath); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось сохранить файл"})
-		return
+	if err := h.Storage.SaveFile(location, io.NewSectionReader(upload.File, 0, upload.Size)); err != nil {
+		return UploadFileResponse{}, &uploadError{http.StatusInternalServerError, "Не удалось сохранить файл"}
+	}
+	if err := h.Storage.SaveFileFromBytes(textLocation, []byte(text)); err != nil {
+		_ = h.Storage.DeleteFile(location)
+		return UploadFileResponse{}, &uploadError{http.StatusInternalServerError, "Не удалось сохранить извлеченный текст файла"}
+	}
+	cleanup := func() {
+		_ = h.Storage.DeleteFile(location)
+		_ = h.Storage.DeleteFile(textLocation)
+	}
+
+	// Ищем самый ранний файл того же владельца с таким же содержимым: файлы других пользователей не раскрываются
+	var original models.File
+	duplicate := true
+	if err := h.DB.Where("hash = ? AND owner_id = ?", upload.Hash, options.OwnerID).Order("created_at asc").First(&original).Error; err != nil {
+		if err != gorm.ErrRecordNotFound {
+			cleanup()
+			return UploadFileResponse{}, &uploadError{http.StatusInternalServerError, "Ошибка при поиске дубликатов файла"}
+		}
+		duplicate = false
 	}
 
 	fileMetadata := models.File{
-		ID:       fileID,
-		Name:     file.Filename,
-		Location: filePath,
+		ID:           fileID,
+		Name:         upload.Name,
+		Location:     location,
+		TextLocation: textLocation,
+		MimeType:     extractor.MimeType(),
+		Extractor:    extractor.Name(),
+		Size:         upload.Size,
+		Hash:         upload.Hash,
+		OwnerID:      options.OwnerID,
+		BatchID:      options.BatchID,
+		CollectionID: options.CollectionID,
+	}
+	if duplicate {
+		fileMetadata.DuplicateOf = original.ID
 	}
 
-	if err := h.DB.Create(&fileMetadata).Error; err != nil {
-		// Попытка удалить файл, если не удалось сохранить метаданные
-		_ = os.Remove(filePath)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось сохранить метаданные файла"})
-		return
+	// Событие записывается вместе с метаданными, чтобы FileAnalysisService узнал о каждом сохраненном файле.
+	// Объем файла учитывается в той же транзакции: квота окончательно проверяется здесь, так как параллельные загрузки
+	// пользователя могли занять ее остаток
+	uploadedEvent, err := services.NewOutboxEvent(events.FileUploaded, fileID, events.FileUploadedData{
+		Name:         fileMetadata.Name,
+		Hash:         fileMetadata.Hash,
+		MimeType:     fileMetadata.MimeType,
+		DuplicateOf:  fileMetadata.DuplicateOf,
+		OwnerID:      fileMetadata.OwnerID,
+		BatchID:      fileMetadata.BatchID,
+		CollectionID: fileMetadata.CollectionID,
+		Analyze:      options.Analyze,
+	})
+	if err == nil {
+		err = h.DB.Transaction(func(tx *gorm.DB) error {
+			if err := h.Quota.Reserve(tx, fileMetadata.OwnerID, fileMetadata.Size); err != nil {
+				return err
+			}
+			if fileMetadata.CollectionID != "" {
+				if err := lockCollection(tx, fileMetadata.CollectionID, fileMetadata.OwnerID); err != nil {
+					return err
+				}
+			}
+			if options.InTx != nil {
+				if err := options.InTx(tx); err != nil {
+					return err
+				}
+			}
+			if err := tx.Create(&fileMetadata).Error; err != nil {
+				return err
+			}
+			if err := h.SearchIndex.Index(tx, fileID, text); err != nil {
+				return err
+			}
+			return tx.Create(&uploadedEvent).Error
+		})
+	}
+	if err != nil {
+		// Попытка удалить файлы, если не удалось сохранить метаданные
+		cleanup()
+		if errors.Is(err, services.ErrQuotaExceeded) {
+			return UploadFileResponse{}, &uploadError{http.StatusRequestEntityTooLarge, quotaExceededMessage}
+		}
+		if errors.Is(err, errCollectionNotFound) {
+			return UploadFileResponse{}, &uploadError{http.StatusNotFound, collectionNotFoundMessage}
+		}
+		return UploadFileResponse{}, &uploadError{http.StatusInternalServerError, "Не удалось сохранить метаданные файла"}
 	}
+	h.Relay.Notify()
 
-	c.JSON(http.StatusCreated, gin.H{"id": fileID})
+	response := UploadFileResponse{ID: fileID, Hash: upload.Hash, MimeType: fileMetadata.MimeType, Extractor: fileMetadata.Extractor, IsDuplicate: duplicate}
+	if duplicate {
+		response.DuplicateOf = &DuplicateInfo{FileID: original.ID, UploadedAt: original.CreatedAt}
+	}
+	return response, nil
 }
 
-// GetFileByID получает содержимое файла по его ID.
+// GetFileByID получает исходный файл по его ID.
 // @Summary Получение файла по ID
-// @Description Возвращает содержимое файла по его ID.
+// @Description Возвращает исходный файл по его ID с Content-Type, соответствующим его MIME-типу.
 // @Tags files
 // @Param id path string true "ID файла"
 // @Produce plain
-// @Success 200 {string} string "Содержимое файла"
+// @Produce octet-stream
+// @Success 200 {file} file "Содержимое файла"
 // @Failure 404 {object} map[string]string "Файл не найден"
 // @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
 // @Router /files/{id} [get]
 func (h *FileHandler) GetFileByID(c *gin.Context) {
-	fileID := c.Param("id")
-
-	var fileMetadata models.File
-	if err := h.DB.First(&fileMetadata, "id = ?", fileID).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Файл не найден"})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при поиске файла"})
-		}
+	fileMetadata, ok := h.findFile(c)
+	if !ok {
 		return
 	}
 
-	content, err := os.ReadFile(fileMetadata.Location)
+	content, err := h.Storage.OpenFile(fileMetadata.Location)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось прочитать файл"})
 		return
 	}
+	defer content.Close()
+
+	contentType := fileMetadata.MimeType
+	if contentType == "" {
+		contentType = "text/plain; charset=utf-8" // Файлы, загруженные до появления экстракторов, всегда были .txt
+	}
+	extraHeaders := map[string]string{
+		"Content-Disposition": mime.FormatMediaType("inline", map[string]string{"filename": fileMetadata.Name}),
+	}
+	c.DataFromReader(http.StatusOK, -1, contentType, content, extraHeaders)
+}
+
+// GetFileText получает извлеченный из файла текст по ID файла.
+// @Summary Получение текста файла по ID
+// @Description Возвращает нормализованный текст, извлеченный из файла при загрузке. Именно он используется при анализе.
+// @Tags files
+// @Param id path string true "ID файла"
+// @Produce plain
+// @Success 200 {string} string "Текст файла"
+// @Failure 404 {object} map[string]string "Файл не найден"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /files/{id}/text [get]
+func (h *FileHandler) GetFileText(c *gin.Context) {
+	fileMetadata, ok := h.findFile(c)
+	if !ok {
+		return
+	}
 
-	c.String(http.StatusOK, string(content))
+	content, err := h.Storage.OpenFile(fileMetadata.TextKey())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось прочитать текст файла"})
+		return
+	}
+	defer content.Close()
+
+	c.DataFromReader(http.StatusOK, -1, "text/plain; charset=utf-8", content, nil)
 }
 
-// GetFileContentByIDInternal используется для внутреннего получения содержимого файла другим сервисом.
-// Не является публичным API эндпоинтом.
-func (h *FileHandler) GetFileContentByIDInternal(fileID string) ([]byte, error) {
+// DeleteFileResponse описывает результат удаления файла.
+// @Description Время удаления файла и время, не раньше которого он будет окончательно удален.
+// @Name DeleteFileResponse
+type DeleteFileResponse struct {
+	ID         string    `json:"id" example:"unique-file-id"`
+	DeletedAt  time.Time `json:"deleted_at" example:"2023-01-01T14:00:00Z"`
+	PurgeAfter time.Time `json:"purge_after" example:"2023-01-31T14:00:00Z"` // Не раньше этого времени содержимое файла будет удалено из хранилища
+}
+
+// DeleteFile удаляет файл по ID.
+// @Summary Удаление файла
+// @Description Помечает файл удаленным: он больше не выдается, не анализируется и не учитывается при поиске дубликатов.
+// @Description Результаты анализа и облака слов файла удаляются File Analysis Service по событию file.deleted, а содержимое файла удаляется из хранилища
+// @Description и запись — из БД фоновой очисткой по истечении срока хранения (FILE_RETENTION).
+// @Tags files
+// @Param id path string true "ID файла"
+// @Produce json
+// @Success 202 {object} DeleteFileResponse "Файл помечен удаленным, очистка запланирована"
+// @Failure 404 {object} map[string]string "Файл не найден"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /files/{id} [delete]
+func (h *FileHandler) DeleteFile(c *gin.Context) {
+	fileMetadata, ok := h.findFile(c)
+	if !ok {
+		return
+	}
+
+	// Результаты анализа удаляются FileAnalysisService при получении события file.deleted
+	deletedAt := time.Now()
+	deletedEvent, err := services.NewOutboxEvent(events.FileDeleted, fileMetadata.ID, events.FileDeletedData{DeletedAt: deletedAt})
+	if err == nil {
+		err = h.DB.Transaction(func(tx *gorm.DB) error {
+			result := tx.Model(&fileMetadata).Update("deleted_at", deletedAt)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return gorm.ErrRecordNotFound // Файл уже удален параллельным запросом
+			}
+			if err := h.Quota.Release(tx, fileMetadata.OwnerID, fileMetadata.Size); err != nil {
+				return err
+			}
+			return tx.Create(&deletedEvent).Error
+		})
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Файл не найден"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось удалить файл"})
+		return
+	}
+	h.Relay.Notify()
+
+	c.JSON(http.StatusAccepted, DeleteFileResponse{
+		ID:         fileMetadata.ID,
+		DeletedAt:  deletedAt,
+		PurgeAfter: deletedAt.Add(h.Purger.Retention),
+	})
+}
+
+// findFile ищет метаданные файла по параметру пути id и при ошибке сам отправляет ответ.
+// Чужой файл для пользователя не отличается от несуществующего.
+func (h *FileHandler) findFile(c *gin.Context) (models.File, bool) {
 	var fileMetadata models.File
-	if err := h.DB.First(&fileMetadata, "id = ?", fileID).Error; err != nil {
+	if err := h.DB.First(&fileMetadata, "id = ?", c.Param("id")).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			return nil, fmt.Errorf("файл с ID %s не найден", fileID)
+			c.JSON(http.StatusNotFound, gin.H{"error": "Файл не найден"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при поиске файла"})
 		}
-		return nil, fmt.Errorf("ошибка при поиске файла с ID %s: %w", fileID, err)
+		return fileMetadata, false
 	}
-
-	content, err := os.ReadFile(fileMetadata.Location)
-	if err != nil {
-		return nil, fmt.Errorf("не удалось прочитать файл %s: %w", fileMetadata.Location, err)
+	if identity, ok := auth.FromHeaders(c.Request.Header); ok && !identity.CanAccess(fileMetadata.OwnerID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Файл не найден"})
+		return fileMetadata, false
 	}
-	return content, nil
+	return fileMetadata, true
 }
 
-// ListFiles возвращает список всех файлов.
-// @Summary Список всех файлов
-// @Description Возвращает ID и имена всех загруженных файлов.
+// fileSorting — допустимые сортировки списка файлов.
+var fileSorting = pagination.Sorting{
+	Fields: map[string]pagination.Field{
+		"created_at": pagination.TimeField("created_at"),
+		"name":       pagination.StringField("name"),
+		"size":       pagination.IntField("size"),
+	},
+	ID:          pagination.StringField("id"),
+	Default:     "created_at",
+	DefaultDesc: true,
+}
+
+// FileListResponse — страница списка файлов.
+// @Description Файлы текущей страницы, общее количество файлов, подходящих под фильтры, и курсор следующей страницы.
+// @Name FileListResponse
+type FileListResponse struct {
+	Items      []models.File `json:"items"`
+	Total      int64         `json:"total" example:"120"`                                                                           // Количество файлов, подходящих под фильтры, на всех страницах
+	NextCursor string        `json:"next_cursor,omitempty" example:"eyJzIjoiY3JlYXRlZF9hdCJ9"`                                      // Курсор следующей страницы; пусто на последней странице
+	Next       string        `json:"next,omitempty" example:"?cursor=eyJzIjoiY3JlYXRlZF9hdCJ9&limit=50&order=desc&sort=created_at"` // Ссылка на следующую страницу относительно текущего пути
+}
+
+// ListFiles возвращает страницу списка файлов.
+// @Summary Список файлов
+// @Description Возвращает метаданные загруженных файлов постранично: пользователю — только его файлы, администратору — все.
+// @Description Страницы выбираются по курсору: ссылка на следующую страницу возвращается в поле next, на последней странице ее нет.
 // @Tags files
+// @Param limit query int false "Количество файлов на странице (по умолчанию 50, не более 500)"
+// @Param cursor query string false "Курсор следующей страницы из поля next_cursor"
+// @Param sort query string false "Поле сортировки (по умолчанию created_at)" Enums(created_at, name, size)
+// @Param order query string false "Порядок сортировки (по умолчанию desc)" Enums(asc, desc)
+// @Param name query string false "Подстрока имени файла (без учета регистра)"
+// @Param created_after query string false "Файлы, загруженные не раньше этого времени (RFC 3339)"
+// @Param created_before query string false "Файлы, загруженные раньше этого времени (RFC 3339)"
+// @Param owner_id query string false "ID владельца (другого пользователя — только для администратора)"
+// @Param has_analysis query bool false "Только проанализированные (true) или непроанализированные (false) файлы"
+// @Param collection_id query string false "ID коллекции"
+// @Param batch_id query string false "ID пакета"
 // @Produce json
-// @Success 200 {array} models.File "Список файлов"
+// @Success 200 {object} FileListResponse "Страница списка файлов"
+// @Failure 400 {object} map[string]string "Некорректные параметры запроса"
+// @Failure 403 {object} map[string]string "Файлы другого пользователя доступны только администратору"
 // @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
 // @Router /files [get]
 func (h *FileHandler) ListFiles(c *gin.Context) {
-	var files []models.File
-	if err := h.DB.Find(&files).Error; err != nil {
+	params := c.Request.URL.Query()
+	page, err := fileSorting.Parse(params)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query := h.DB.Model(&models.File{})
+	identity, authenticated := auth.FromHeaders(c.Request.Header)
+	if ownerID := params.Get("owner_id"); ownerID != "" {
+		if authenticated && !identity.CanAccess(ownerID) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Файлы другого пользователя доступны только администратору"})
+			return
+		}
+		query = query.Where("owner_id = ?", ownerID)
+	} else if authenticated && !identity.IsAdmin() {
+		query = query.Where("owner_id = ?", identity.UserID)
+	}
+	if name := params.Get("name"); name != "" {
+		query = query.Where("name ILIKE ?", pagination.ContainsPattern(name))
+	}
+	for param, condition := range map[string]string{"created_after": "created_at >= ?", "created_before": "created_at < ?"} {
+		t, err := pagination.TimeParam(params, param)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if t != nil {
+			query = query.Where(condition, *t)
+		}
+	}
+	if value := params.Get("has_analysis"); value != "" {
+		hasAnalysis, err := strconv.ParseBool(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Параметр 'has_analysis' должен быть true или false"})
+			return
+		}
+		if hasAnalysis {
+			query = query.Where("analyzed_at IS NOT NULL")
+		} else {
+			query = query.Where("analyzed_at IS NULL")
+		}
+	}
+	for _, param := range []string{"collection_id", "batch_id"} {
+		if value := params.Get(param); value != "" {
+			query = query.Where(param+" = ?", value)
+		}
+	}
+	// Запрос с фильтрами используется дважды: для подсчета и для выборки страницы
+	query = query.Session(&gorm.Session{})
+
+	var response FileListResponse
+	if err := query.Count(&response.Total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось подсчитать файлы"})
+		return
+	}
+	if err := page.Apply(query).Find(&response.Items).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить список файлов"})
 		return
 	}
-	c.JSON(http.StatusOK, files)
+	var size int
+	size, response.NextCursor, response.Next = page.Next(params, len(response.Items), func(i int) (any, any) {
+		f := response.Items[i]
+		switch page.Sort {
+		case "name":
+			return f.Name, f.ID
+		case "size":
+			return f.Size, f.ID
+		}
+		return f.CreatedAt, f.ID
+	})
+	response.Items = response.Items[:size]
+	c.JSON(http.StatusOK, response)
+}
+
+// StorageUsageResponse описывает объем файлов пользователя и его квоту.
+// @Description Объем неудаленных файлов пользователя, его квота хранилища и максимальный размер загружаемого файла.
+// @Name StorageUsageResponse
+type StorageUsageResponse struct {
+	OwnerID     string `json:"owner_id" example:"user-42"`
+	UsedBytes   int64  `json:"used_bytes" example:"1048576"`
+	QuotaBytes  int64  `json:"quota_bytes" example:"1073741824"` // 0 — без ограничения
+	MaxFileSize int64  `json:"max_file_size" example:"104857600"`
 }
 
-// GetFileLocationByID возвращает location файла по его ID. Используется FileAnalysisService.
-// @Summary Получение location файла по ID (внутренний)
-// @Description Возвращает location файла по его ID для использования другими сервисами.
+// GetStorageUsage возвращает объем файлов пользователя и его квоту.
+// @Summary Объем файлов и квота пользователя
+// @Description Возвращает суммарный размер неудаленных файлов пользователя, его квоту хранилища и максимальный размер загружаемого файла.
+// @Description Администратор может запросить сведения о другом пользователе параметром owner_id.
 // @Tags files
-// @Param id path string true "ID файла"
+// @Param owner_id query string false "ID пользователя (только для администратора)"
 // @Produce json
-// @Success 200 {object} map[string]string "Location файла"
-// @Failure 404 {object} map[string]string "Файл не найден"
+// @Success 200 {object} StorageUsageResponse "Объем файлов и квота"
+// @Failure 403 {object} map[string]string "Сведения о другом пользователе доступны только администратору"
 // @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
-// @Router /internal/files/{id}/location [get]
-func (h *FileHandler) GetFileLocationByID(c *gin.Context) {
-	fileID := c.Param("id")
-	var fileMetadata models.File
-	if err := h.DB.Where("id = ?", fileID).First(&fileMetadata).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Файл не найден"})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при поиске файла"})
+// @Router /usage [get]
+func (h *FileHandler) GetStorageUsage(c *gin.Context) {
+	identity, authenticated := auth.FromHeaders(c.Request.Header)
+	ownerID := identity.UserID
+	if requested := c.Query("owner_id"); requested != "" && requested != ownerID {
+		if authenticated && !identity.IsAdmin() {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Сведения о другом пользователе доступны только администратору"})
+			return
 		}
+		ownerID = requested
+	}
+
+	usage, err := h.Quota.Usage(h.DB, ownerID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить объем файлов пользователя"})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"location": fileMetadata.Location})
+	c.JSON(http.StatusOK, StorageUsageResponse{
+		OwnerID:     ownerID,
+		UsedBytes:   usage.UsedBytes,
+		QuotaBytes:  h.Quota.QuotaFor(ownerID),
+		MaxFileSize: h.MaxFileSize,
+	})
 }
 
-// GetFileContentByLocationInternal используется для внутреннего получения содержимого файла FileAnalysisService.
-// Не является публичным API эндпоинтом.
-// @Summary Получение содержимого файла по location (внутренний)
-// @Description Возвращает содержимое файла по его location.
+// FileOwnersRequest — запрос владельцев файлов.
+// @Description Список ID файлов, владельцев которых нужно получить.
+// @Name FileOwnersRequest
+type FileOwnersRequest struct {
+	IDs []string `json:"ids" binding:"required" example:"unique-file-id,other-file-id"`
+}
+
+// maxFileOwnersIDs — наибольшее количество файлов в одном запросе владельцев.
+const maxFileOwnersIDs = 1000
+
+// GetFileOwners возвращает владельцев файлов. Используется FileAnalysisService для проверки доступа пользователя к результатам анализа.
+// @Summary Получение владельцев файлов (внутренний)
+// @Description Возвращает ID владельца для каждого из найденных файлов. Удаленные и несуществующие файлы в ответ не попадают.
 // @Tags files
-// @Param location query string true "Location файла"
-// @Produce plain
-// @Success 200 {string} string "Содержимое файла"
-// @Failure 404 {object} map[string]string "Файл не найден по указанному location"
+// @Accept json
+// @Param request body FileOwnersRequest true "ID файлов"
+// @Produce json
+// @Success 200 {object} map[string]any "Владельцы файлов (owners: ID файла -> ID владельца)"
+// @Failure 400 {object} map[string]string "Некорректный запрос"
 // @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
-// @Router /internal/file-content [get]
-func (h *FileHandler) GetFileContentByLocationInternal(c *gin.Context) {
-	location := c.Query("location")
-	if location == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Параметр location обязателен"})
+// @Router /internal/files/owners [post]
+func (h *FileHandler) GetFileOwners(c *gin.Context) {
+	var request FileOwnersRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный запрос: " + err.Error()})
+		return
+	}
+	if len(request.IDs) > maxFileOwnersIDs {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Можно запросить не более %d файлов", maxFileOwnersIDs)})
 		return
 	}
 
-	// Проверка, что location находится внутри ожидаемой директории хранения
-	// Это простая проверка, в реальном приложении может потребоваться более строгая валидация
-	absFileStoragePath, err := filepath.Abs(h.FileStoragePath)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка определения пути к хранилищу"})
+	owners := make(map[string]string, len(request.IDs))
+	if len(request.IDs) > 0 {
+		var files []models.File
+		if err := h.DB.Select("id", "owner_id").Where("id IN ?", request.IDs).Find(&files).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при поиске файлов"})
+			return
+		}
+		for _, f := range files {
+			owners[f.ID] = f.OwnerID
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"owners": owners})
+}
+
+// FileAnalysisStatusRequest — сообщение FileAnalysisService о наличии результатов анализа файла.
+// @Description Есть ли у файла результаты анализа.
+// @Name FileAnalysisStatusRequest
+type FileAnalysisStatusRequest struct {
+	Analyzed bool `json:"analyzed" example:"true"`
+}
+
+// SetFileAnalysisStatus отмечает, есть ли у файла результаты анализа. Вызывается FileAnalysisService после успешного анализа
+// и после удаления результатов анализа; используется фильтром has_analysis списка файлов.
+// @Summary Отметка об анализе файла (внутренний)
+// @Description Записывает время анализа файла (analyzed=true) или сбрасывает его (analyzed=false).
+// @Tags files
+// @Accept json
+// @Param id path string true "ID файла"
+// @Param request body FileAnalysisStatusRequest true "Есть ли у файла результаты анализа"
+// @Success 204 {string} string "Отметка сохранена"
+// @Failure 400 {object} map[string]string "Некорректный запрос"
+// @Failure 404 {object} map[string]string "Файл не найден"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /internal/files/{id}/analysis [put]
+func (h *FileHandler) SetFileAnalysisStatus(c *gin.Context) {
+	var request FileAnalysisStatusRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный запрос: " + err.Error()})
 		return
 	}
-	absLocation, err := filepath.Abs(location)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка определения абсолютного пути файла"})
+	var analyzedAt *time.Time
+	if request.Analyzed {
+		now := time.Now()
+		analyzedAt = &now
+	}
+	result := h.DB.Model(&models.File{}).Where("id = ?", c.Param("id")).Update("analyzed_at", analyzedAt)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось сохранить отметку об анализе файла"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Файл не найден"})
 		return
 	}
+	c.Status(http.StatusNoContent)
+}
 
-	if !filepath.HasPrefix(absLocation, absFileStoragePath) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Недопустимый location файла"})
+// GetFileContent отдает извлеченный текст файла по его ID. Используется FileAnalysisService.
+// Текст файла не меняется после загрузки, поэтому ETag строится по ID файла и размеру текста.
+// @Summary Получение текста файла по ID (внутренний)
+// @Description Потоково отдает нормализованный текст файла с заголовками Content-Length и ETag.
+// @Description Поддерживаются запросы части текста (Range, If-Range) и условные запросы (If-None-Match).
+// @Tags files
+// @Param id path string true "ID файла"
+// @Produce plain
+// @Success 200 {string} string "Текст файла"
+// @Success 206 {string} string "Запрошенная часть текста"
+// @Success 304 {string} string "Текст не изменился"
+// @Failure 404 {object} map[string]string "Файл не найден"
+// @Failure 416 {string} string "Запрошенный диапазон вне текста"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /internal/files/{id}/content [get]
+func (h *FileHandler) GetFileContent(c *gin.Context) {
+	fileMetadata, ok := h.findFile(c)
+	if !ok {
 		return
 	}
 
-	content, err := os.ReadFile(location)
+	location := fileMetadata.TextKey()
+	info, err := h.Storage.Stat(location)
 	if err != nil {
-		if os.IsNotExist(err) {
-			c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Файл не найден по пути: %s", location)})
+		if errors.Is(err, fs.ErrNotExist) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Текст файла не найден в хранилище"})
 		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Не удалось прочитать файл: %s", location)})
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось прочитать текст файла"})
 		}
 		return
 	}
-	c.String(http.StatusOK, string(content))
+
+	c.Header("Content-Type", "text/plain; charset=utf-8")
+	c.Header("ETag", fmt.Sprintf(`"%s-%d"`, fileMetadata.ID, info.Size))
+	// ServeContent сам обрабатывает Range, If-Range и If-None-Match и читает из хранилища только нужную часть текста
+	content := &storageReadSeeker{storage: h.Storage, key: location, size: info.Size}
+	defer content.Close()
+	http.ServeContent(c.Writer, c.Request, "", info.ModTime, content)
+}
+
+// storageReadSeeker позволяет http.ServeContent читать файл хранилища с произвольного смещения:
+// поток открывается через OpenRange при первом чтении после перемещения.
+type storageReadSeeker struct {
+	storage adapters.FileStorage
+	key     string
+	size    int64
+	offset  int64
+	body    io.ReadCloser
+}
+
+func (r *storageReadSeeker) Read(p []byte) (int, error) {
+	if r.offset >= r.size {
+		return 0, io.EOF
+	}
+	if r.body == nil {
+		body, err := r.storage.OpenRange(r.key, r.offset, r.size-r.offset)
+		if err != nil {
+			return 0, err
+		}
+		r.body = body
+	}
+	n, err := r.body.Read(p)
+	r.offset += int64(n)
+	if err == io.EOF && r.offset < r.size {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+func (r *storageReadSeeker) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += r.offset
+	case io.SeekEnd:
+		offset += r.size
+	}
+	if offset < 0 {
+		return 0, fmt.Errorf("недопустимое смещение %d", offset)
+	}
+	if offset != r.offset {
+		r.Close()
+		r.offset = offset
+	}
+	return offset, nil
+}
+
+// Close закрывает открытый поток, если он есть.
+func (r *storageReadSeeker) Close() error {
+	if r.body == nil {
+		return nil
+	}
+	err := r.body.Close()
+	r.body = nil
+	return err
 }
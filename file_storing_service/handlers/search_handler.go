@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"errors"
+	"file_storing_service/services"
+	"net/http"
+	"net/url"
+	"pkg/auth"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// defaultSearchLimit — количество найденных файлов на странице по умолчанию.
+	defaultSearchLimit = 20
+	// maxSearchLimit — наибольшее количество найденных файлов на странице: для каждого строятся фрагменты текста.
+	maxSearchLimit = 50
+)
+
+// SearchResponse — страница результатов полнотекстового поиска.
+// @Description Найденные файлы в порядке убывания релевантности, их общее количество и ссылка на следующую страницу.
+// @Name SearchResponse
+type SearchResponse struct {
+	Items []services.SearchHit `json:"items"`
+	Total int64                `json:"total" example:"12"`                                     // Количество найденных файлов на всех страницах
+	Next  string               `json:"next,omitempty" example:"?limit=20&offset=20&q=история"` // Ссылка на следующую страницу относительно текущего пути; нет на последней странице
+}
+
+// Search ищет файлы по извлеченному тексту.
+// @Summary Полнотекстовый поиск по файлам
+// @Description Ищет файлы, извлеченный текст которых соответствует запросу, и возвращает их в порядке убывания релевантности
+// @Description с фрагментами текста, в которых совпадения выделены тегом <mark> (остальной текст экранирован для HTML).
+// @Description Слова нормализуются: «революции» находит «революция». Синтаксис запроса: слова через пробел должны встречаться все;
+// @Description "фраза в кавычках" — слова подряд; слово* — слова с таким началом; -слово — исключить; OR — любое из условий.
+// @Description Пользователь ищет только среди своих файлов, администратор — среди всех.
+// @Tags search
+// @Param q query string true "Поисковый запрос" example("промышленная революция" Англи*)
+// @Param owner_id query string false "ID владельца (другого пользователя — только для администратора)"
+// @Param limit query int false "Количество файлов на странице (по умолчанию 20, не более 50)"
+// @Param offset query int false "Количество пропускаемых файлов (по умолчанию 0)"
+// @Produce json
+// @Success 200 {object} SearchResponse "Страница найденных файлов"
+// @Failure 400 {object} map[string]string "Некорректный запрос"
+// @Failure 403 {object} map[string]string "Файлы другого пользователя доступны только администратору"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /search [get]
+func (h *FileHandler) Search(c *gin.Context) {
+	params := c.Request.URL.Query()
+	request := services.SearchRequest{Query: params.Get("q"), Limit: defaultSearchLimit}
+	if request.Query == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Параметр 'q' обязателен"})
+		return
+	}
+	if value := params.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit <= 0 || limit > maxSearchLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Параметр 'limit' должен быть числом от 1 до " + strconv.Itoa(maxSearchLimit)})
+			return
+		}
+		request.Limit = limit
+	}
+	if value := params.Get("offset"); value != "" {
+		offset, err := strconv.Atoi(value)
+		if err != nil || offset < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Параметр 'offset' должен быть неотрицательным числом"})
+			return
+		}
+		request.Offset = offset
+	}
+	identity, authenticated := auth.FromHeaders(c.Request.Header)
+	if ownerID := params.Get("owner_id"); ownerID != "" {
+		if authenticated && !identity.CanAccess(ownerID) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Файлы другого пользователя доступны только администратору"})
+			return
+		}
+		request.OwnerID = ownerID
+	} else if authenticated && !identity.IsAdmin() {
+		request.OwnerID = identity.UserID
+	}
+
+	hits, total, err := h.SearchIndex.Search(request)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidSearchQuery) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось выполнить поиск: " + err.Error()})
+		return
+	}
+	response := SearchResponse{Items: hits, Total: total}
+	if next := request.Offset + len(hits); len(hits) == request.Limit && int64(next) < total {
+		nextQuery := url.Values{}
+		for name, values := range params {
+			nextQuery[name] = values
+		}
+		nextQuery.Set("limit", strconv.Itoa(request.Limit))
+		nextQuery.Set("offset", strconv.Itoa(next))
+		response.Next = "?" + nextQuery.Encode()
+	}
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,334 @@
+package handlers
+
+import (
+	"encoding/hex"
+	"errors"
+	"file_storing_service/models"
+	"file_storing_service/services"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"pkg/auth"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// chunkContentType — тип содержимого запроса с частью файла, как в протоколе tus.
+const chunkContentType = "application/offset+octet-stream"
+
+// CreateUploadSessionRequest — запрос на создание сессии возобновляемой загрузки.
+// @Description Имя и полный размер файла и его SHA-256 хеш, который проверяется при завершении загрузки.
+// @Name CreateUploadSessionRequest
+type CreateUploadSessionRequest struct {
+	Name   string `json:"name" binding:"required" example:"example.pdf"`
+	Size   int64  `json:"size" example:"1048576"` // Полный размер файла в байтах
+	SHA256 string `json:"sha256" binding:"required" example:"9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"`
+	// ID коллекции, в которую войдет сохраненный файл
+	CollectionID string `json:"collection_id" example:"collection-id"`
+}
+
+// CreateUploadSession создает сессию возобновляемой загрузки файла.
+// @Summary Создание сессии возобновляемой загрузки
+// @Description Создает сессию, в которую файл загружается частями запросами PATCH /uploads/{id}. Размер файла и квота пользователя
+// @Description проверяются сразу; в квоте учитываются и файлы других незавершенных сессий пользователя.
+// @Description Незавершенная сессия удаляется, если в нее дольше UPLOAD_SESSION_TTL не поступало частей файла.
+// @Tags uploads
+// @Accept json
+// @Param request body CreateUploadSessionRequest true "Имя, размер и SHA-256 хеш файла"
+// @Produce json
+// @Success 201 {object} models.UploadSession "Созданная сессия загрузки"
+// @Failure 400 {object} map[string]string "Некорректный запрос или неподдерживаемый формат файла"
+// @Failure 404 {object} map[string]string "Коллекция не найдена"
+// @Failure 413 {object} map[string]string "Файл больше допустимого размера или превышена квота хранилища"
+// @Failure 429 {object} map[string]string "Слишком много незавершенных сессий загрузки"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /uploads [post]
+func (h *FileHandler) CreateUploadSession(c *gin.Context) {
+	var request CreateUploadSessionRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный запрос: " + err.Error()})
+		return
+	}
+	name := filepath.Base(request.Name)
+	if !h.Extractors.Supports(name) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Неверный формат файла. Допускаются: %s.", strings.Join(h.Extractors.Extensions(), ", "))})
+		return
+	}
+	if request.Size <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Размер файла должен быть положительным"})
+		return
+	}
+	checksum := strings.ToLower(request.SHA256)
+	if decoded, err := hex.DecodeString(checksum); err != nil || len(decoded) != 32 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "sha256 должен содержать SHA-256 хеш файла в hex"})
+		return
+	}
+	if request.Size > h.MaxFileSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("Файл слишком большой: максимальный размер — %d байт", h.MaxFileSize)})
+		return
+	}
+
+	identity, _ := auth.FromHeaders(c.Request.Header)
+	remaining, limited, err := h.Quota.Remaining(h.DB, identity.UserID)
+	if err == nil && limited {
+		// Файлы незавершенных сессий учитываются в объеме только после завершения загрузки,
+		// поэтому без их вычета несколько сессий вместе могли бы обещать больше квоты
+		var reserved int64
+		reserved, err = h.Uploads.ReservedBytes(identity.UserID)
+		remaining -= reserved
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось проверить квоту хранилища"})
+		return
+	}
+	if limited && request.Size > remaining {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": quotaExceededMessage})
+		return
+	}
+
+	collectionID := strings.TrimSpace(request.CollectionID)
+	if collectionID != "" {
+		if err := h.checkCollection(collectionID, identity.UserID); err != nil {
+			c.JSON(err.Status, gin.H{"error": err.Message})
+			return
+		}
+	}
+
+	session := models.UploadSession{
+		ID:           uuid.New().String(),
+		Name:         name,
+		OwnerID:      identity.UserID,
+		Size:         request.Size,
+		Checksum:     checksum,
+		CollectionID: collectionID,
+	}
+	if err := h.Uploads.Create(&session); err != nil {
+		if errors.Is(err, services.ErrTooManyUploadSessions) {
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": fmt.Sprintf("Нельзя иметь больше %d незавершенных сессий загрузки", h.Uploads.MaxPerOwner)})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось создать сессию загрузки"})
+		}
+		return
+	}
+	setUploadHeaders(c, session)
+	c.JSON(http.StatusCreated, session)
+}
+
+// GetUploadSession возвращает состояние сессии загрузки.
+// @Summary Состояние сессии загрузки
+// @Description Возвращает сессию загрузки и количество уже полученных байт (offset), с которого нужно продолжить загрузку.
+// @Description Смещение, размер файла и срок жизни сессии передаются также в заголовках Upload-Offset, Upload-Length и Upload-Expires,
+// @Description поэтому для возобновления загрузки достаточно запроса HEAD.
+// @Tags uploads
+// @Param id path string true "ID сессии загрузки"
+// @Produce json
+// @Success 200 {object} models.UploadSession "Сессия загрузки"
+// @Failure 404 {object} map[string]string "Сессия загрузки не найдена"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /uploads/{id} [get]
+// @Router /uploads/{id} [head]
+func (h *FileHandler) GetUploadSession(c *gin.Context) {
+	session, ok := h.findUploadSession(c)
+	if !ok {
+		return
+	}
+	setUploadHeaders(c, session)
+	c.JSON(http.StatusOK, session)
+}
+
+// UploadChunk принимает часть файла.
+// @Summary Загрузка части файла
+// @Description Дописывает тело запроса к файлу сессии. Заголовок Upload-Offset должен совпадать с количеством уже полученных байт,
+// @Description иначе возвращается 409 с текущим смещением в Upload-Offset. Если соединение прервалось, полученные байты сохраняются:
+// @Description текущее смещение можно узнать запросом HEAD /uploads/{id}. Новое смещение возвращается в заголовке Upload-Offset.
+// @Tags uploads
+// @Accept application/offset+octet-stream
+// @Param id path string true "ID сессии загрузки"
+// @Param Upload-Offset header int true "Смещение части в файле"
+// @Success 204 {string} string "Часть файла сохранена"
+// @Failure 400 {object} map[string]string "Некорректный заголовок Upload-Offset"
+// @Failure 404 {object} map[string]string "Сессия загрузки не найдена"
+// @Failure 409 {object} map[string]string "Смещение не совпадает с количеством полученных байт или в сессию уже загружается другая часть"
+// @Failure 413 {object} map[string]string "Часть выходит за пределы размера файла"
+// @Failure 415 {object} map[string]string "Тип содержимого не application/offset+octet-stream"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /uploads/{id} [patch]
+func (h *FileHandler) UploadChunk(c *gin.Context) {
+	if c.ContentType() != chunkContentType {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Часть файла передается с Content-Type " + chunkContentType})
+		return
+	}
+	offset, err := strconv.ParseInt(c.GetHeader("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Заголовок Upload-Offset должен содержать неотрицательное целое число"})
+		return
+	}
+
+	unlock, ok := h.lockUploadSession(c)
+	if !ok {
+		return
+	}
+	defer unlock()
+	session, ok := h.findUploadSession(c)
+	if !ok {
+		return
+	}
+	if offset != session.Offset {
+		setUploadHeaders(c, session)
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("Смещение части %d не совпадает с количеством полученных байт %d", offset, session.Offset)})
+		return
+	}
+	if c.Request.ContentLength > session.Size-session.Offset {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": services.ErrChunkTooLarge.Error()})
+		return
+	}
+
+	_, err = h.Uploads.Append(&session, c.Request.Body)
+	setUploadHeaders(c, session)
+	if err != nil {
+		if errors.Is(err, services.ErrChunkTooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": services.ErrChunkTooLarge.Error()})
+		} else {
+			// Полученные до ошибки байты сохранены; загрузку можно продолжить со смещения Upload-Offset
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось сохранить часть файла"})
+		}
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// CompleteUpload завершает загрузку и сохраняет файл.
+// @Summary Завершение возобновляемой загрузки
+// @Description Проверяет, что получен весь файл и его SHA-256 хеш совпадает с указанным при создании сессии, после чего сохраняет файл
+// @Description так же, как POST /files/upload, и удаляет сессию. При несовпадении хеша сессия удаляется и загрузку нужно начать заново.
+// @Description Если файл не удалось сохранить (например, содержимое не соответствует формату), сессия остается до удаления или истечения срока.
+// @Tags uploads
+// @Param id path string true "ID сессии загрузки"
+// @Produce json
+// @Success 201 {object} UploadFileResponse "ID сохраненного файла и сведения о дубликате"
+// @Failure 400 {object} map[string]string "Хеш файла не совпадает, ошибка валидации или извлечения текста"
+// @Failure 404 {object} map[string]string "Сессия загрузки не найдена"
+// @Failure 404 {object} map[string]string "Сессия загрузки или коллекция не найдена"
+// @Failure 409 {object} map[string]string "Файл получен не полностью или сессия занята"
+// @Failure 413 {object} map[string]string "Превышена квота хранилища"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /uploads/{id}/complete [post]
+func (h *FileHandler) CompleteUpload(c *gin.Context) {
+	unlock, ok := h.lockUploadSession(c)
+	if !ok {
+		return
+	}
+	defer unlock()
+	session, ok := h.findUploadSession(c)
+	if !ok {
+		return
+	}
+	if session.Offset != session.Size {
+		setUploadHeaders(c, session)
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("Файл получен не полностью: %d из %d байт", session.Offset, session.Size)})
+		return
+	}
+
+	hash, err := h.Uploads.Checksum(&session)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось прочитать загруженный файл"})
+		return
+	}
+	if hash != session.Checksum {
+		if err := h.Uploads.Remove(&session); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось удалить сессию загрузки"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("SHA-256 хеш полученного файла %s не совпадает с указанным %s; загрузку нужно начать заново", hash, session.Checksum)})
+		return
+	}
+
+	part, err := h.Uploads.Open(&session)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось прочитать загруженный файл"})
+		return
+	}
+	defer part.Close()
+
+	upload := &receivedUpload{Name: session.Name, File: part, Size: session.Size, Hash: hash}
+	// Сессия удаляется в одной транзакции с сохранением метаданных файла, чтобы повторное завершение не создало второй файл
+	saved := h.storeUpload(c, upload, uploadOptions{
+		OwnerID:      session.OwnerID,
+		CollectionID: session.CollectionID,
+		InTx: func(tx *gorm.DB) error {
+			return tx.Delete(&models.UploadSession{}, "id = ?", session.ID).Error
+		},
+	})
+	if saved {
+		h.Uploads.RemoveFile(session.ID)
+	}
+}
+
+// CancelUpload удаляет сессию загрузки вместе с полученными частями файла.
+// @Summary Отмена возобновляемой загрузки
+// @Description Удаляет сессию загрузки и полученные части файла.
+// @Tags uploads
+// @Param id path string true "ID сессии загрузки"
+// @Success 204 {string} string "Сессия загрузки удалена"
+// @Failure 404 {object} map[string]string "Сессия загрузки не найдена"
+// @Failure 409 {object} map[string]string "В сессию загружается часть файла"
+// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Router /uploads/{id} [delete]
+func (h *FileHandler) CancelUpload(c *gin.Context) {
+	unlock, ok := h.lockUploadSession(c)
+	if !ok {
+		return
+	}
+	defer unlock()
+	session, ok := h.findUploadSession(c)
+	if !ok {
+		return
+	}
+	if err := h.Uploads.Remove(&session); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось удалить сессию загрузки"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// lockUploadSession захватывает сессию из параметра пути id и при ошибке сам отправляет ответ.
+func (h *FileHandler) lockUploadSession(c *gin.Context) (unlock func(), ok bool) {
+	unlock, err := h.Uploads.Lock(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return unlock, true
+}
+
+// findUploadSession ищет незавершенную сессию загрузки по параметру пути id и при ошибке сам отправляет ответ.
+// Сессия с истекшим сроком и чужая сессия для пользователя не отличаются от несуществующей.
+func (h *FileHandler) findUploadSession(c *gin.Context) (models.UploadSession, bool) {
+	var session models.UploadSession
+	if err := h.DB.First(&session, "id = ? AND expires_at > ?", c.Param("id"), time.Now()).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Сессия загрузки не найдена"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при поиске сессии загрузки"})
+		}
+		return session, false
+	}
+	if identity, ok := auth.FromHeaders(c.Request.Header); ok && !identity.CanAccess(session.OwnerID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Сессия загрузки не найдена"})
+		return session, false
+	}
+	return session, true
+}
+
+// setUploadHeaders передает состояние сессии в заголовках, как в протоколе tus.
+func setUploadHeaders(c *gin.Context, session models.UploadSession) {
+	c.Header("Upload-Offset", strconv.FormatInt(session.Offset, 10))
+	c.Header("Upload-Length", strconv.FormatInt(session.Size, 10))
+	c.Header("Upload-Expires", session.ExpiresAt.UTC().Format(http.TimeFormat))
+	c.Header("Cache-Control", "no-store")
+}
@@ -1,11 +1,20 @@
 package main
 
 import (
+	"context"
+	"file_storing_service/extractors"
 	"file_storing_service/handlers"
 	"file_storing_service/models"
+	"file_storing_service/services"
 	"fmt"
 	"log"
 	"os"
+	"pkg/adapters"
+	"pkg/auth"
+	"pkg/events"
+	"pkg/limits"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/driver/postgres"
@@ -42,13 +51,53 @@ func main() {
 	postgresHost := os.Getenv("POSTGRES_HOST_DB1")
 	postgresPort := os.Getenv("POSTGRES_PORT_DB1")
 	fileStoragePath := os.Getenv("FILE_STORAGE_PATH")
+	fileAnalysisServiceAddr := os.Getenv("FILE_ANALYSIS_SERVICE_ADDR")
+	serviceAuthSecret := os.Getenv("SERVICE_AUTH_SECRET")
+	internalListenAddr := os.Getenv("INTERNAL_LISTEN_ADDR")
+	eventsWebhookURL := os.Getenv("EVENTS_WEBHOOK_URL")
+	outboxPollInterval := os.Getenv("OUTBOX_POLL_INTERVAL")
+	fileRetention := os.Getenv("FILE_RETENTION")
+	purgeInterval := os.Getenv("PURGE_INTERVAL")
+	maxUploadSize := os.Getenv("MAX_UPLOAD_SIZE")
+	maxBatchSize := os.Getenv("MAX_BATCH_SIZE")
+	maxBatchEntries := os.Getenv("MAX_BATCH_ENTRIES")
+	maxExtractedSize := os.Getenv("MAX_EXTRACTED_SIZE")
+	uploadSessionsPath := os.Getenv("UPLOAD_SESSIONS_PATH")
+	uploadSessionTTL := os.Getenv("UPLOAD_SESSION_TTL")
+	uploadSessionGCInterval := os.Getenv("UPLOAD_SESSION_GC_INTERVAL")
+	storageQuota := os.Getenv("STORAGE_QUOTA")
+	userStorageQuotas := os.Getenv("USER_STORAGE_QUOTAS")
+	searchMaxIndexedSize := os.Getenv("SEARCH_MAX_INDEXED_SIZE")
 
 	if fileStoragePath == "" {
 		fileStoragePath = "./file_storage_1" // Значение по умолчанию, если не указано
 	}
-	// Создаем директорию, если она не существует
-	if err := os.MkdirAll(fileStoragePath, os.ModePerm); err != nil {
-		log.Fatalf("Не удалось создать директорию для хранения файлов: %v", err)
+	if uploadSessionsPath == "" {
+		uploadSessionsPath = "./upload_sessions" // Значение по умолчанию, если не указано
+	}
+	if fileAnalysisServiceAddr == "" {
+		fileAnalysisServiceAddr = "http://localhost:8082" // Значение по умолчанию для локального запуска
+	}
+	if serviceAuthSecret == "" {
+		log.Fatalf("Не задан SERVICE_AUTH_SECRET: общий секрет для подписи запросов между сервисами")
+	}
+	serviceSigner := auth.ServiceSigner{Service: "file_storing_service", Secret: []byte(serviceAuthSecret)}
+	if eventsWebhookURL == "" {
+		eventsWebhookURL = fileAnalysisServiceAddr + "/api/v1/internal/events"
+	}
+
+	// Инициализация хранилища файлов: локальная директория или S3-совместимое хранилище
+	storage, err := adapters.NewFileStorage(adapters.StorageConfig{
+		Backend:     os.Getenv("STORAGE_BACKEND"),
+		LocalPath:   fileStoragePath,
+		S3Endpoint:  os.Getenv("S3_ENDPOINT"),
+		S3Region:    os.Getenv("S3_REGION"),
+		S3Bucket:    os.Getenv("S3_BUCKET"),
+		S3AccessKey: os.Getenv("S3_ACCESS_KEY_ID"),
+		S3SecretKey: os.Getenv("S3_SECRET_ACCESS_KEY"),
+	})
+	if err != nil {
+		log.Fatalf("Не удалось инициализировать хранилище файлов: %v", err)
 	}
 
 	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=disable TimeZone=Europe/Moscow",
@@ -60,30 +109,180 @@ func main() {
 	}
 
 	// Миграция схемы
-	err = db.AutoMigrate(&models.File{})
+	err = db.AutoMigrate(&models.File{}, &models.OutboxEvent{}, &models.StorageUsage{}, &models.UploadSession{}, &models.Batch{}, &models.Collection{})
 	if err != nil {
 		log.Fatalf("Не удалось выполнить миграцию базы данных: %v", err)
 	}
 
-	fileHandler := handlers.NewFileHandler(db, fileStoragePath)
+	// Полнотекстовый поиск по извлеченному тексту файлов
+	search := services.NewSearchIndex(db, storage)
+	if searchMaxIndexedSize != "" {
+		size, err := limits.ParseSize(searchMaxIndexedSize)
+		if err != nil || size <= 0 {
+			log.Fatalf("Некорректное значение SEARCH_MAX_INDEXED_SIZE: %s", searchMaxIndexedSize)
+		}
+		search.MaxIndexedSize = int(size)
+	}
+	if err := search.Migrate(); err != nil {
+		log.Fatalf("Не удалось выполнить миграцию поискового индекса: %v", err)
+	}
+	search.Start(context.Background())
+
+	// Доставка событий о загрузке и удалении файлов из outbox в File Analysis Service
+	publisher := events.NewWebhookPublisher(eventsWebhookURL)
+	publisher.Client = serviceSigner.Client(30 * time.Second) // Эндпоинт приема событий принимает только подписанные запросы
+	relay := services.NewOutboxRelay(db, publisher)
+	if outboxPollInterval != "" {
+		interval, err := time.ParseDuration(outboxPollInterval)
+		if err != nil || interval <= 0 {
+			log.Fatalf("Некорректное значение OUTBOX_POLL_INTERVAL: %s", outboxPollInterval)
+		}
+		relay.Interval = interval
+	}
+	relay.Start(context.Background())
+
+	// Окончательное удаление файлов: содержимое в хранилище и записи в БД
+	purger := services.NewFilePurger(db, storage)
+	if fileRetention != "" {
+		retention, err := time.ParseDuration(fileRetention)
+		if err != nil || retention < 0 {
+			log.Fatalf("Некорректное значение FILE_RETENTION: %s", fileRetention)
+		}
+		purger.Retention = retention
+	}
+	if purgeInterval != "" {
+		interval, err := time.ParseDuration(purgeInterval)
+		if err != nil || interval <= 0 {
+			log.Fatalf("Некорректное значение PURGE_INTERVAL: %s", purgeInterval)
+		}
+		purger.Interval = interval
+	}
+	purger.Start(context.Background())
+
+	// Квоты хранилища: общая для всех пользователей и отдельные для некоторых из них (0 — без ограничения)
+	var defaultQuota int64
+	if storageQuota != "" {
+		defaultQuota, err = limits.ParseSize(storageQuota)
+		if err != nil {
+			log.Fatalf("Некорректное значение STORAGE_QUOTA: %v", err)
+		}
+	}
+	userQuotas, err := services.ParseUserQuotas(userStorageQuotas)
+	if err != nil {
+		log.Fatalf("Некорректное значение USER_STORAGE_QUOTAS: %v", err)
+	}
+	quota := services.NewQuotaManager(defaultQuota, userQuotas)
+
+	// Сессии возобновляемой загрузки: полученные части файлов хранятся на локальном диске до завершения загрузки
+	uploads, err := services.NewUploadSessions(db, uploadSessionsPath)
+	if err != nil {
+		log.Fatalf("Не удалось инициализировать сессии загрузки: %v", err)
+	}
+	if uploadSessionTTL != "" {
+		ttl, err := time.ParseDuration(uploadSessionTTL)
+		if err != nil || ttl <= 0 {
+			log.Fatalf("Некорректное значение UPLOAD_SESSION_TTL: %s", uploadSessionTTL)
+		}
+		uploads.TTL = ttl
+	}
+	if uploadSessionGCInterval != "" {
+		interval, err := time.ParseDuration(uploadSessionGCInterval)
+		if err != nil || interval <= 0 {
+			log.Fatalf("Некорректное значение UPLOAD_SESSION_GC_INTERVAL: %s", uploadSessionGCInterval)
+		}
+		uploads.Interval = interval
+	}
+	uploads.Start(context.Background())
+
+	fileHandler := handlers.NewFileHandler(db, storage, extractors.DefaultRegistry(), purger, relay, quota, uploads, search)
+	if maxUploadSize != "" {
+		size, err := limits.ParseSize(maxUploadSize)
+		if err != nil || size <= 0 {
+			log.Fatalf("Некорректное значение MAX_UPLOAD_SIZE: %s", maxUploadSize)
+		}
+		fileHandler.MaxFileSize = size
+	}
+	if maxBatchSize != "" {
+		size, err := limits.ParseSize(maxBatchSize)
+		if err != nil || size <= 0 {
+			log.Fatalf("Некорректное значение MAX_BATCH_SIZE: %s", maxBatchSize)
+		}
+		fileHandler.MaxBatchSize = size
+	}
+	if maxBatchEntries != "" {
+		entries, err := strconv.Atoi(maxBatchEntries)
+		if err != nil || entries <= 0 {
+			log.Fatalf("Некорректное значение MAX_BATCH_ENTRIES: %s", maxBatchEntries)
+		}
+		fileHandler.MaxBatchEntries = entries
+	}
+	if maxExtractedSize != "" {
+		size, err := limits.ParseSize(maxExtractedSize)
+		if err != nil || size <= 0 {
+			log.Fatalf("Некорректное значение MAX_EXTRACTED_SIZE: %s", maxExtractedSize)
+		}
+		fileHandler.MaxExtractedSize = size
+	}
 
 	r := gin.Default()
 
-	apiV1 := r.Group("/api/v1")
+	// Пользовательские эндпоинты принимают только запросы, которые API Gateway переслал с подписанным пользователем
+	apiV1 := r.Group("/api/v1", auth.IdentityAuth([]byte(serviceAuthSecret)))
 	{
 		filesGroup := apiV1.Group("/files")
 		{
 			filesGroup.POST("/upload", fileHandler.UploadFile)
 			filesGroup.GET("/:id", fileHandler.GetFileByID)
+			filesGroup.GET("/:id/text", fileHandler.GetFileText)
+			filesGroup.DELETE("/:id", fileHandler.DeleteFile)
 			filesGroup.GET("", fileHandler.ListFiles) // Эндпоинт для получения списка файлов
 		}
-		// Внутренние эндпоинты, не предназначенные для прямого вызова пользователем через API Gateway
-		internalGroup := apiV1.Group("/internal")
+		apiV1.POST("/batches", fileHandler.UploadBatch)
+		apiV1.GET("/batches/:id", fileHandler.GetBatch)
+		collectionsGroup := apiV1.Group("/collections")
 		{
-			internalGroup.GET("/files/:id/location", fileHandler.GetFileLocationByID)
-			internalGroup.GET("/file-content", fileHandler.GetFileContentByLocationInternal)
-
+			collectionsGroup.POST("", fileHandler.CreateCollection)
+			collectionsGroup.GET("", fileHandler.ListCollections)
+			collectionsGroup.GET("/:id", fileHandler.GetCollection)
+			collectionsGroup.PATCH("/:id", fileHandler.UpdateCollection)
+			collectionsGroup.DELETE("/:id", fileHandler.DeleteCollection)
+			collectionsGroup.PUT("/:id/files/:file_id", fileHandler.AddCollectionFile)
+			collectionsGroup.DELETE("/:id/files/:file_id", fileHandler.RemoveCollectionFile)
+		}
+		uploadsGroup := apiV1.Group("/uploads")
+		{
+			uploadsGroup.POST("", fileHandler.CreateUploadSession)
+			uploadsGroup.GET("/:id", fileHandler.GetUploadSession)
+			uploadsGroup.HEAD("/:id", fileHandler.GetUploadSession)
+			uploadsGroup.PATCH("/:id", fileHandler.UploadChunk)
+			uploadsGroup.POST("/:id/complete", fileHandler.CompleteUpload)
+			uploadsGroup.DELETE("/:id", fileHandler.CancelUpload)
 		}
+		apiV1.GET("/usage", fileHandler.GetStorageUsage)
+		apiV1.GET("/search", fileHandler.Search)
+	}
+
+	// Внутренние эндпоинты, не предназначенные для прямого вызова пользователем через API Gateway.
+	// Принимают только запросы, подписанные SERVICE_AUTH_SECRET; если задан INTERNAL_LISTEN_ADDR,
+	// обслуживаются отдельным портом, который не нужно открывать наружу
+	internalRouter := r
+	if internalListenAddr != "" {
+		internalRouter = gin.Default()
+	}
+	internalGroup := internalRouter.Group("/api/v1/internal", auth.ServiceAuth([]byte(serviceAuthSecret)))
+	{
+		internalGroup.GET("/files/:id/content", fileHandler.GetFileContent)
+		internalGroup.POST("/files/owners", fileHandler.GetFileOwners)
+		internalGroup.PUT("/files/:id/analysis", fileHandler.SetFileAnalysisStatus)
+		internalGroup.GET("/collections/:id/files", fileHandler.GetCollectionFiles)
+	}
+	if internalListenAddr != "" {
+		go func() {
+			log.Printf("Внутренние эндпоинты File Storing Service доступны на %s", internalListenAddr)
+			if err := internalRouter.Run(internalListenAddr); err != nil {
+				log.Fatalf("Не удалось запустить сервер внутренних эндпоинтов: %v", err)
+			}
+		}()
 	}
 
 	// Swagger документация
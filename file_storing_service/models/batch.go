@@ -0,0 +1,15 @@
+package models
+
+import (
+	"time"
+)
+
+// Batch — пакет файлов, загруженных одним запросом пакетной загрузки.
+// @Description Именованный пакет файлов, загруженных одним запросом (например, работы студентов одной группы).
+// @Name Batch
+type Batch struct {
+	ID        string    `gorm:"primaryKey" json:"id" example:"batch-id"`
+	Name      string    `json:"name" example:"Группа 101, эссе"`
+	OwnerID   string    `gorm:"index" json:"owner_id" example:"user-42"` // ID пользователя, загрузившего пакет
+	CreatedAt time.Time `json:"created_at"`
+}
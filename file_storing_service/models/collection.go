@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+)
+
+// Collection — коллекция файлов, например работы по одному заданию.
+// @Description Именованная коллекция файлов пользователя (например, «Задание 3»). Файл может входить не более чем в одну коллекцию.
+// @Name Collection
+type Collection struct {
+	ID          string    `gorm:"primaryKey" json:"id" example:"collection-id"`
+	Name        string    `json:"name" example:"Задание 3"`
+	Description string    `json:"description,omitempty" example:"Эссе по истории, группа 101"`
+	OwnerID     string    `gorm:"index" json:"owner_id" example:"user-42"` // ID пользователя, создавшего коллекцию
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
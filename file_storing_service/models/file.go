@@ -13,15 +13,46 @@ import (
 // @swaggertype object
 // @property id string example="unique-file-id" Описание: ID файла.
 // @property name string example="example.txt" Описание: Имя файла.
-// @property location string example="/app/file_storage_1/unique-file-id.txt" Описание: Путь к файлу.
+// @property location string example="unique-file-id.txt" Описание: Ключ исходного файла в хранилище.
+// @property text_location string example="unique-file-id_text.txt" Описание: Ключ извлеченного текста в хранилище.
+// @property mime_type string example="application/pdf" Описание: MIME-тип исходного файла.
+// @property extractor string example="pdf" Описание: Экстрактор, извлекший текст из файла.
+// @property size integer example=1048576 Описание: Размер исходного файла в байтах.
+// @property hash string example="9f86d0...0f00a08" Описание: SHA-256 хеш содержимого файла.
+// @property duplicate_of string example="original-file-id" Описание: ID самого раннего файла того же владельца с идентичным содержимым.
+// @property owner_id string example="user-42" Описание: ID пользователя, загрузившего файл.
+// @property batch_id string example="batch-id" Описание: ID пакета, если файл загружен пакетной загрузкой.
+// @property collection_id string example="collection-id" Описание: ID коллекции, в которую входит файл.
+// @property analyzed_at string example="2023-01-01T12:05:00Z" Описание: Время последнего успешного анализа файла (если анализировался).
 // @property created_at string example="2023-01-01T12:00:00Z" Описание: Время создания.
 // @property updated_at string example="2023-01-01T13:00:00Z" Описание: Время последнего обновления.
 // @property deleted_at string example="" Описание: Время удаления (если удален).
 type File struct {
-	ID        string         `gorm:"primaryKey" json:"id" example:"unique-file-id"`
-	Name      string         `json:"name" example:"example.txt"`
-	Location  string         `json:"location" example:"/app/file_storage_1/unique-file-id.txt"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty" swaggertype:"string" example:"2023-01-01T14:00:00Z"` // Время удаления (если удален)
+	ID           string         `gorm:"primaryKey" json:"id" example:"unique-file-id"`
+	Name         string         `json:"name" example:"example.txt"`
+	Location     string         `json:"location" example:"unique-file-id.txt"`                                                                // Ключ исходного файла в хранилище (не зависит от типа хранилища)
+	TextLocation string         `json:"text_location" example:"unique-file-id_text.txt"`                                                      // Ключ нормализованного текста, на котором выполняется анализ
+	MimeType     string         `json:"mime_type" example:"text/plain"`                                                                       // MIME-тип исходного файла
+	Extractor    string         `json:"extractor" example:"plain"`                                                                            // Имя экстрактора, извлекшего текст
+	Size         int64          `json:"size" example:"1048576"`                                                                               // Размер исходного файла в байтах; 0 у файлов, загруженных до учета квот
+	Hash         string         `gorm:"size:64;index" json:"hash" example:"9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"` // SHA-256 содержимого в hex
+	DuplicateOf  string         `gorm:"index" json:"duplicate_of,omitempty" example:"original-file-id"`                                       // ID самого раннего файла того же владельца с идентичным содержимым
+	OwnerID      string         `gorm:"index" json:"owner_id" example:"user-42"`                                                              // ID пользователя, загрузившего файл; пустой у файлов, загруженных до появления владельцев
+	BatchID      string         `gorm:"index" json:"batch_id,omitempty" example:"batch-id"`                                                   // ID пакета, если файл загружен пакетной загрузкой
+	CollectionID string         `gorm:"index" json:"collection_id,omitempty" example:"collection-id"`                                         // ID коллекции, в которую входит файл
+	AnalyzedAt   *time.Time     `gorm:"index" json:"analyzed_at,omitempty" swaggertype:"string" example:"2023-01-01T12:05:00Z"`               // Время последнего успешного анализа; сообщается FileAnalysisService
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	DeletedAt    gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty" swaggertype:"string" example:"2023-01-01T14:00:00Z"` // Время удаления (если удален)
+
+	AnalysisCleanedAt *time.Time `json:"-"` // Время доставки события file.deleted, после которой результаты анализа файла удалены в FileAnalysisService
+}
+
+// TextKey возвращает ключ текстового представления файла в хранилище. У файлов, загруженных до появления
+// экстракторов, текстом является сам исходный .txt файл.
+func (f File) TextKey() string {
+	if f.TextLocation != "" {
+		return f.TextLocation
+	}
+	return f.Location
 }
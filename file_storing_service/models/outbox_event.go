@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+)
+
+// OutboxEvent — событие о файле, ожидающее доставки в FileAnalysisService (транзакционный outbox).
+// Событие записывается в той же транзакции, что и изменение файла, поэтому не теряется при сбое,
+// а доставляется фоновым ретранслятором, пока получатель его не примет.
+// @Description Событие о файле в очереди доставки: тип, данные, количество попыток и время доставки.
+// @Name OutboxEvent
+type OutboxEvent struct {
+	ID            uint       `gorm:"primaryKey" json:"id"`
+	EventID       string     `gorm:"size:36;uniqueIndex" json:"event_id"` // ID события, по которому получатель отбрасывает повторы
+	Type          string     `json:"type"`                                // file.uploaded или file.deleted
+	FileID        string     `gorm:"index" json:"file_id"`
+	Payload       string     `json:"payload"` // Данные события в JSON
+	CreatedAt     time.Time  `json:"created_at"`
+	Attempts      int        `json:"attempts"`                  // Количество неудачных попыток доставки
+	NextAttemptAt time.Time  `json:"next_attempt_at"`           // Не раньше этого времени будет следующая попытка
+	DeliveredAt   *time.Time `gorm:"index" json:"delivered_at"` // Время доставки; NULL — событие еще не доставлено
+	LastError     string     `json:"last_error,omitempty"`      // Ошибка последней неудачной попытки
+}
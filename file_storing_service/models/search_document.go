@@ -0,0 +1,14 @@
+package models
+
+import (
+	"time"
+)
+
+// SearchDocument — извлеченный текст файла, проиндексированный для полнотекстового поиска.
+// Столбец search_vector (tsvector) и GIN-индекс по нему создаются services.SearchIndex.Migrate:
+// вычисляемые столбцы не описываются тегами GORM.
+type SearchDocument struct {
+	FileID    string `gorm:"primaryKey"`
+	Content   string `gorm:"type:text"` // Начало извлеченного текста, не длиннее SearchIndex.MaxIndexedSize
+	CreatedAt time.Time
+}
@@ -0,0 +1,15 @@
+package models
+
+import (
+	"time"
+)
+
+// StorageUsage — объем файлов, занятый пользователем в хранилище. Учитывается размер исходных файлов:
+// он увеличивается при загрузке и уменьшается при удалении файла. Извлеченный текст файлов не учитывается.
+// @Description Объем хранилища, занятый файлами пользователя.
+// @Name StorageUsage
+type StorageUsage struct {
+	OwnerID   string    `gorm:"primaryKey" json:"owner_id" example:"user-42"`
+	UsedBytes int64     `json:"used_bytes" example:"1048576"` // Суммарный размер неудаленных файлов пользователя
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+)
+
+// UploadSession — сессия возобновляемой загрузки файла по частям. Полученные части хранятся
+// во временном файле сессии; после завершения загрузки сессия удаляется, а файл сохраняется как обычный File.
+// @Description Сессия возобновляемой загрузки: имя и размер файла, ожидаемый SHA-256 хеш и количество уже полученных байт.
+// @Name UploadSession
+type UploadSession struct {
+	ID           string    `gorm:"primaryKey" json:"id" example:"upload-session-id"`
+	Name         string    `json:"name" example:"example.pdf"`
+	OwnerID      string    `gorm:"index" json:"owner_id" example:"user-42"`                                                          // ID пользователя, создавшего сессию
+	Size         int64     `json:"size" example:"1048576"`                                                                           // Полный размер файла в байтах
+	Offset       int64     `gorm:"column:upload_offset" json:"offset" example:"524288"`                                              // Сколько байт файла уже получено
+	Checksum     string    `gorm:"size:64" json:"sha256" example:"9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"` // Ожидаемый SHA-256 файла в hex
+	CollectionID string    `json:"collection_id,omitempty" example:"collection-id"`                                                  // Коллекция, в которую войдет сохраненный файл
+	ExpiresAt    time.Time `gorm:"index" json:"expires_at" example:"2023-01-02T12:00:00Z"`                                           // После этого времени незавершенная сессия удаляется
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
@@ -0,0 +1,124 @@
+package services
+
+import (
+	"context"
+	"file_storing_service/models"
+	"fmt"
+	"log"
+	"pkg/adapters"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// DefaultFileRetention — сколько удаленный файл хранится до окончательной очистки.
+	DefaultFileRetention = 30 * 24 * time.Hour
+	// DefaultPurgeInterval — как часто запускается очистка удаленных файлов.
+	DefaultPurgeInterval = time.Hour
+	// purgeBatchSize — сколько файлов обрабатывается за один запрос к БД.
+	purgeBatchSize = 100
+)
+
+// FilePurger окончательно удаляет удаленные файлы.
+// @Summary Очистка удаленных файлов
+// @Description По истечении срока хранения удаляет содержимое удаленных файлов из хранилища, а их записи, события и записи поискового индекса — из БД.
+// @Tags services
+type FilePurger struct {
+	DB        *gorm.DB
+	Storage   adapters.FileStorage
+	Retention time.Duration // Срок хранения удаленного файла до окончательной очистки
+	Interval  time.Duration // Интервал запуска очистки
+}
+
+// NewFilePurger создает новый экземпляр FilePurger с настройками по умолчанию.
+// @Summary Создает новый FilePurger
+// @Description Инициализирует очистку удаленных файлов. Фоновая очистка запускается методом Start.
+// @Return *FilePurger
+func NewFilePurger(db *gorm.DB, storage adapters.FileStorage) *FilePurger {
+	return &FilePurger{
+		DB:        db,
+		Storage:   storage,
+		Retention: DefaultFileRetention,
+		Interval:  DefaultPurgeInterval,
+	}
+}
+
+// Start запускает фоновую очистку: сразу и затем каждые Interval, пока не отменен ctx.
+// @Summary Запуск очистки
+// @Description Запускает горутину, периодически выполняющую PurgeOnce.
+func (p *FilePurger) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(p.Interval)
+		defer ticker.Stop()
+		for {
+			if err := p.PurgeOnce(); err != nil {
+				log.Printf("Ошибка при очистке удаленных файлов: %v", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// PurgeOnce выполняет один проход очистки.
+// @Summary Один проход очистки
+// @Description Удаляет из хранилища содержимое файлов, удаленных раньше, чем Retention назад, результаты анализа которых
+// @Description уже удалены, и окончательно удаляет их записи и события из БД.
+// @Return error
+func (p *FilePurger) PurgeOnce() error {
+	// Окончательно удаляем файлы, срок хранения которых истек. Файл удаляется только после доставки
+	// события file.deleted, то есть после удаления его результатов анализа в FileAnalysisService
+	purged := 0
+	for {
+		var expired []models.File
+		err := p.DB.Unscoped().
+			Where("deleted_at IS NOT NULL AND deleted_at <= ? AND analysis_cleaned_at IS NOT NULL", time.Now().Add(-p.Retention)).
+			Order("deleted_at").Limit(purgeBatchSize).
+			Find(&expired).Error
+		if err != nil {
+			return fmt.Errorf("не удалось получить файлы с истекшим сроком хранения: %w", err)
+		}
+		for _, file := range expired {
+			if err := p.purgeFile(file); err != nil {
+				return err
+			}
+			purged++
+		}
+		if len(expired) < purgeBatchSize {
+			break
+		}
+	}
+	if purged > 0 {
+		log.Printf("Окончательно удалено файлов: %d", purged)
+	}
+	return nil
+}
+
+// purgeFile удаляет содержимое файла и его текст из хранилища, а затем запись, события и поисковый индекс файла из БД.
+func (p *FilePurger) purgeFile(file models.File) error {
+	for _, key := range []string{file.Location, file.TextLocation} {
+		if key == "" {
+			continue
+		}
+		if err := p.Storage.DeleteFile(key); err != nil {
+			return fmt.Errorf("не удалось удалить содержимое файла %s (%s): %w", file.ID, key, err)
+		}
+	}
+	err := p.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&models.OutboxEvent{}, "file_id = ?", file.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&models.SearchDocument{}, "file_id = ?", file.ID).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&file).Error
+	})
+	if err != nil {
+		return fmt.Errorf("не удалось удалить запись файла %s: %w", file.ID, err)
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package services
+
+import (
+	"file_storing_service/models"
+	"pkg/adapters"
+	"pkg/events"
+	"testing"
+	"time"
+)
+
+func TestFilePurgerRemovesExpiredCleanedFiles(t *testing.T) {
+	db := newTestDB(t, &models.File{}, &models.OutboxEvent{}, &models.SearchDocument{})
+	storage, err := adapters.NewFileStorageAdapter(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	purger := NewFilePurger(db, storage)
+	purger.Retention = time.Hour
+
+	now := time.Now()
+	expired, recent := now.Add(-2*time.Hour), now.Add(-time.Minute)
+	files := []struct {
+		file   models.File
+		purged bool
+	}{
+		// Срок хранения истек, результаты анализа удалены: файл удаляется окончательно
+		{models.File{ID: "expired", AnalysisCleanedAt: &expired}, true},
+		// Файл, загруженный до появления экстракторов, без отдельного текста
+		{models.File{ID: "legacy", AnalysisCleanedAt: &expired}, true},
+		// Событие file.deleted еще не доставлено: результаты анализа могут ссылаться на файл
+		{models.File{ID: "not-cleaned"}, false},
+		// Срок хранения не истек: файл еще можно восстановить
+		{models.File{ID: "recent", AnalysisCleanedAt: &recent}, false},
+		// Неудаленный файл
+		{models.File{ID: "active"}, false},
+	}
+	for _, f := range files {
+		file := f.file
+		file.Location = file.ID + ".txt"
+		if file.ID != "legacy" {
+			file.TextLocation = file.ID + "_text.txt"
+		}
+		for _, key := range []string{file.Location, file.TextLocation} {
+			if key != "" {
+				if err := storage.SaveFileFromBytes(key, []byte("текст")); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+		if err := db.Create(&file).Error; err != nil {
+			t.Fatal(err)
+		}
+		db.Create(&models.SearchDocument{FileID: file.ID, Content: "текст"})
+		addOutboxEvent(t, db, events.FileDeleted, file.ID)
+		switch file.ID {
+		case "active":
+		case "recent":
+			db.Unscoped().Model(&file).Update("deleted_at", recent)
+		default:
+			db.Unscoped().Model(&file).Update("deleted_at", expired)
+		}
+	}
+
+	for i := 0; i < 2; i++ {
+		// Повторный проход ничего не меняет
+		if err := purger.PurgeOnce(); err != nil {
+			t.Fatalf("проход %d: %v", i+1, err)
+		}
+		for _, f := range files {
+			var count int64
+			db.Unscoped().Model(&models.File{}).Where("id = ?", f.file.ID).Count(&count)
+			if exists := count == 1; exists == f.purged {
+				t.Fatalf("проход %d: файл %s существует=%v, ожидалось удаление=%v", i+1, f.file.ID, exists, f.purged)
+			}
+			for _, model := range []interface{}{&models.OutboxEvent{}, &models.SearchDocument{}} {
+				db.Model(model).Where("file_id = ?", f.file.ID).Count(&count)
+				if exists := count > 0; exists == f.purged {
+					t.Fatalf("проход %d: записи %T файла %s существуют=%v", i+1, model, f.file.ID, exists)
+				}
+			}
+			_, errContent := storage.ReadFile(f.file.ID + ".txt")
+			if exists := errContent == nil; exists == f.purged {
+				t.Fatalf("проход %d: содержимое файла %s существует=%v", i+1, f.file.ID, exists)
+			}
+			if f.file.ID != "legacy" {
+				_, errText := storage.ReadFile(f.file.ID + "_text.txt")
+				if exists := errText == nil; exists == f.purged {
+					t.Fatalf("проход %d: текст файла %s существует=%v", i+1, f.file.ID, exists)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"file_storing_service/models"
+	"fmt"
+	"log"
+	"pkg/events"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	// DefaultOutboxPollInterval — как часто ретранслятор проверяет outbox, если его не разбудили раньше.
+	DefaultOutboxPollInterval = 5 * time.Second
+	// DefaultOutboxMaxBackoff — наибольшая пауза между попытками доставки одного события.
+	DefaultOutboxMaxBackoff = 5 * time.Minute
+	// outboxBatchSize — сколько событий обрабатывается за один запрос к БД.
+	outboxBatchSize = 100
+	// outboxInitialBackoff — пауза после первой неудачной попытки; затем она удваивается.
+	outboxInitialBackoff = time.Second
+)
+
+// NewOutboxEvent создает запись outbox для события eventType о файле fileID с данными data.
+// Запись нужно сохранить в той же транзакции, что и изменение файла.
+func NewOutboxEvent(eventType, fileID string, data interface{}) (models.OutboxEvent, error) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return models.OutboxEvent{}, fmt.Errorf("ошибка при сериализации события %s для файла %s: %w", eventType, fileID, err)
+	}
+	return models.OutboxEvent{
+		EventID:       uuid.New().String(),
+		Type:          eventType,
+		FileID:        fileID,
+		Payload:       string(payload),
+		NextAttemptAt: time.Now(),
+	}, nil
+}
+
+// OutboxRelay доставляет события из outbox получателю.
+// @Summary Ретранслятор событий
+// @Description Читает недоставленные события из таблицы outbox_events и передает их Publisher. Неудачные попытки повторяются
+// @Description с экспоненциально растущей паузой. События одного файла доставляются строго в порядке записи.
+// @Tags services
+type OutboxRelay struct {
+	DB         *gorm.DB
+	Publisher  events.Publisher
+	Interval   time.Duration // Интервал проверки outbox
+	MaxBackoff time.Duration // Наибольшая пауза между попытками доставки события
+
+	wake chan struct{}
+}
+
+// NewOutboxRelay создает новый экземпляр OutboxRelay с настройками по умолчанию.
+// @Summary Создает новый OutboxRelay
+// @Description Инициализирует ретранслятор событий. Фоновая доставка запускается методом Start.
+// @Return *OutboxRelay
+func NewOutboxRelay(db *gorm.DB, publisher events.Publisher) *OutboxRelay {
+	return &OutboxRelay{
+		DB:         db,
+		Publisher:  publisher,
+		Interval:   DefaultOutboxPollInterval,
+		MaxBackoff: DefaultOutboxMaxBackoff,
+		wake:       make(chan struct{}, 1),
+	}
+}
+
+// Notify сообщает ретранслятору о новых событиях, чтобы они были доставлены без ожидания Interval.
+func (r *OutboxRelay) Notify() {
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Start запускает фоновую доставку событий: сразу, затем каждые Interval и после каждого Notify, пока не отменен ctx.
+// @Summary Запуск ретранслятора
+// @Description Запускает горутину, выполняющую DeliverPending.
+func (r *OutboxRelay) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(r.Interval)
+		defer ticker.Stop()
+		for {
+			if err := r.DeliverPending(ctx); err != nil {
+				log.Printf("Ошибка при доставке событий: %v", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			case <-r.wake:
+			}
+		}
+	}()
+}
+
+// DeliverPending выполняет один проход доставки недоставленных событий.
+// @Summary Один проход доставки
+// @Description Передает Publisher события, время попытки которых наступило. Если событие файла не доставлено,
+// @Description более поздние события того же файла откладываются до его доставки.
+// @Return error
+func (r *OutboxRelay) DeliverPending(ctx context.Context) error {
+	// Файлы, у которых есть более раннее недоставленное событие
+	blocked := make(map[string]bool)
+	now := time.Now()
+	var afterID uint
+	for {
+		var pending []models.OutboxEvent
+		err := r.DB.Where("delivered_at IS NULL AND id > ?", afterID).
+			Order("id").Limit(outboxBatchSize).
+			Find(&pending).Error
+		if err != nil {
+			return fmt.Errorf("не удалось получить недоставленные события: %w", err)
+		}
+
+		for _, outboxEvent := range pending {
+			afterID = outboxEvent.ID
+			if ctx.Err() != nil {
+				return nil
+			}
+			if blocked[outboxEvent.FileID] || outboxEvent.NextAttemptAt.After(now) {
+				blocked[outboxEvent.FileID] = true
+				continue
+			}
+			if err := r.deliver(ctx, outboxEvent); err != nil {
+				log.Printf("Не удалось доставить событие %s (%s) файла %s: %v", outboxEvent.EventID, outboxEvent.Type, outboxEvent.FileID, err)
+				blocked[outboxEvent.FileID] = true
+			}
+		}
+		if len(pending) < outboxBatchSize {
+			return nil
+		}
+	}
+}
+
+// deliver передает событие Publisher и записывает результат попытки.
+func (r *OutboxRelay) deliver(ctx context.Context, outboxEvent models.OutboxEvent) error {
+	event := events.Event{
+		ID:         outboxEvent.EventID,
+		Type:       outboxEvent.Type,
+		FileID:     outboxEvent.FileID,
+		OccurredAt: outboxEvent.CreatedAt,
+		Data:       json.RawMessage(outboxEvent.Payload),
+	}
+	if publishErr := r.Publisher.Publish(ctx, event); publishErr != nil {
+		attempts := outboxEvent.Attempts + 1
+		err := r.DB.Model(&outboxEvent).Updates(map[string]interface{}{
+			"attempts":        attempts,
+			"next_attempt_at": time.Now().Add(r.backoff(attempts)),
+			"last_error":      publishErr.Error(),
+		}).Error
+		if err != nil {
+			return fmt.Errorf("%v; не удалось сохранить попытку доставки: %w", publishErr, err)
+		}
+		return publishErr
+	}
+
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		if err := tx.Model(&outboxEvent).Updates(map[string]interface{}{"delivered_at": now, "last_error": ""}).Error; err != nil {
+			return fmt.Errorf("не удалось отметить доставку события %s: %w", outboxEvent.EventID, err)
+		}
+		// FileAnalysisService удалил результаты анализа файла: теперь файл можно окончательно удалить
+		if outboxEvent.Type == events.FileDeleted {
+			err := tx.Unscoped().Model(&models.File{}).
+				Where("id = ? AND analysis_cleaned_at IS NULL", outboxEvent.FileID).
+				Update("analysis_cleaned_at", now).Error
+			if err != nil {
+				return fmt.Errorf("не удалось отметить очистку результатов анализа файла %s: %w", outboxEvent.FileID, err)
+			}
+		}
+		return nil
+	})
+}
+
+// backoff возвращает паузу перед следующей попыткой после attempts неудачных попыток.
+func (r *OutboxRelay) backoff(attempts int) time.Duration {
+	delay := outboxInitialBackoff
+	for i := 1; i < attempts && delay < r.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > r.MaxBackoff {
+		delay = r.MaxBackoff
+	}
+	return delay
+}
@@ -0,0 +1,149 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"file_storing_service/models"
+	"pkg/events"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newTestDB открывает отдельную базу SQLite в памяти для теста.
+func newTestDB(t *testing.T, models ...interface{}) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("не удалось открыть SQLite: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	t.Cleanup(func() { sqlDB.Close() })
+	if err := db.AutoMigrate(models...); err != nil {
+		t.Fatalf("не удалось выполнить миграцию: %v", err)
+	}
+	return db
+}
+
+func addOutboxEvent(t *testing.T, db *gorm.DB, eventType, fileID string) models.OutboxEvent {
+	t.Helper()
+	event, err := NewOutboxEvent(eventType, fileID, map[string]string{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&event).Error; err != nil {
+		t.Fatal(err)
+	}
+	return event
+}
+
+func publishedIDs(broker *events.InMemoryBroker) []string {
+	var ids []string
+	for _, event := range broker.Published() {
+		ids = append(ids, event.ID)
+	}
+	return ids
+}
+
+func equalIDs(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestOutboxRelayRedeliversFailedEventsInOrder(t *testing.T) {
+	db := newTestDB(t, &models.File{}, &models.OutboxEvent{})
+	deletedAt := time.Now()
+	if err := db.Create(&models.File{ID: "f1", DeletedAt: gorm.DeletedAt{Time: deletedAt, Valid: true}}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// Получатель не принимает первое событие файла f1 с первой попытки
+	broker := events.NewInMemoryBroker()
+	failures := 1
+	broker.Subscribe(func(ctx context.Context, event events.Event) error {
+		if event.FileID == "f1" && failures > 0 {
+			failures--
+			return errors.New("получатель недоступен")
+		}
+		return nil
+	})
+	relay := NewOutboxRelay(db, broker)
+
+	uploaded := addOutboxEvent(t, db, events.FileUploaded, "f1")
+	other := addOutboxEvent(t, db, events.FileUploaded, "f2")
+	deleted := addOutboxEvent(t, db, events.FileDeleted, "f1")
+
+	// Первое событие f1 не доставлено и задерживает следующее событие f1, но не события других файлов
+	if err := relay.DeliverPending(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := publishedIDs(broker), []string{uploaded.EventID, other.EventID}; !equalIDs(got, want) {
+		t.Fatalf("опубликованы %v, ожидались %v", got, want)
+	}
+	var failed models.OutboxEvent
+	db.First(&failed, uploaded.ID)
+	if failed.DeliveredAt != nil || failed.Attempts != 1 || failed.LastError == "" || !failed.NextAttemptAt.After(time.Now()) {
+		t.Fatalf("неудачная попытка записана неверно: %+v", failed)
+	}
+
+	// До наступления времени следующей попытки событие не отправляется повторно
+	if err := relay.DeliverPending(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(broker.Published()); got != 2 {
+		t.Fatalf("опубликовано %d событий до времени повторной попытки, ожидалось 2", got)
+	}
+
+	db.Model(&failed).Update("next_attempt_at", time.Now().Add(-time.Second))
+	if err := relay.DeliverPending(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := publishedIDs(broker), []string{uploaded.EventID, other.EventID, uploaded.EventID, deleted.EventID}; !equalIDs(got, want) {
+		t.Fatalf("опубликованы %v, ожидались %v", got, want)
+	}
+	var pending int64
+	db.Model(&models.OutboxEvent{}).Where("delivered_at IS NULL").Count(&pending)
+	if pending != 0 {
+		t.Fatalf("осталось недоставленных событий: %d", pending)
+	}
+
+	// Доставка file.deleted разрешает окончательное удаление файла
+	var file models.File
+	db.Unscoped().First(&file, "id = ?", "f1")
+	if file.AnalysisCleanedAt == nil {
+		t.Fatal("после доставки file.deleted не отмечена очистка результатов анализа")
+	}
+
+	// Доставленные события больше не отправляются
+	if err := relay.DeliverPending(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(broker.Published()); got != 4 {
+		t.Fatalf("доставленные события отправлены повторно: всего %d", got)
+	}
+}
+
+func TestOutboxRelayBackoff(t *testing.T) {
+	relay := &OutboxRelay{MaxBackoff: 10 * time.Second}
+	for attempts, want := range map[int]time.Duration{
+		1:  time.Second,
+		2:  2 * time.Second,
+		4:  8 * time.Second,
+		5:  10 * time.Second,
+		40: 10 * time.Second,
+	} {
+		if got := relay.backoff(attempts); got != want {
+			t.Errorf("backoff(%d) = %s, ожидалось %s", attempts, got, want)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package services
+
+import (
+	"errors"
+	"file_storing_service/models"
+	"fmt"
+	"pkg/limits"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrQuotaExceeded возвращается, если файл не помещается в квоту пользователя.
+var ErrQuotaExceeded = errors.New("превышена квота хранилища")
+
+// QuotaManager учитывает объем файлов каждого пользователя в таблице storage_usages и проверяет его квоту.
+// @Summary Квоты хранилища
+// @Description Ограничивает суммарный размер неудаленных файлов пользователя. Квота 0 означает отсутствие ограничения.
+// @Tags services
+type QuotaManager struct {
+	DefaultQuota int64            // Квота пользователя в байтах по умолчанию
+	UserQuotas   map[string]int64 // Квоты отдельных пользователей, заменяющие DefaultQuota
+}
+
+// NewQuotaManager создает новый экземпляр QuotaManager.
+// @Summary Создает новый QuotaManager
+// @Description Инициализирует учет квот с квотой по умолчанию и квотами отдельных пользователей.
+// @Return *QuotaManager
+func NewQuotaManager(defaultQuota int64, userQuotas map[string]int64) *QuotaManager {
+	if userQuotas == nil {
+		userQuotas = map[string]int64{}
+	}
+	return &QuotaManager{DefaultQuota: defaultQuota, UserQuotas: userQuotas}
+}
+
+// QuotaFor возвращает квоту пользователя в байтах; 0 — без ограничения.
+func (q *QuotaManager) QuotaFor(ownerID string) int64 {
+	if quota, ok := q.UserQuotas[ownerID]; ok {
+		return quota
+	}
+	return q.DefaultQuota
+}
+
+// Usage возвращает объем файлов пользователя. Для пользователя без файлов возвращается нулевой объем.
+func (q *QuotaManager) Usage(db *gorm.DB, ownerID string) (models.StorageUsage, error) {
+	usage := models.StorageUsage{OwnerID: ownerID}
+	err := db.First(&usage, "owner_id = ?", ownerID).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return usage, fmt.Errorf("не удалось получить объем файлов пользователя %s: %w", ownerID, err)
+	}
+	return usage, nil
+}
+
+// Remaining возвращает, сколько байт пользователь еще может загрузить. ok равно false, если квота не ограничена.
+func (q *QuotaManager) Remaining(db *gorm.DB, ownerID string) (remaining int64, ok bool, err error) {
+	quota := q.QuotaFor(ownerID)
+	if quota <= 0 {
+		return 0, false, nil
+	}
+	usage, err := q.Usage(db, ownerID)
+	if err != nil {
+		return 0, true, err
+	}
+	if usage.UsedBytes >= quota {
+		return 0, true, nil
+	}
+	return quota - usage.UsedBytes, true, nil
+}
+
+// Reserve увеличивает объем файлов пользователя на size, если он остается в пределах квоты, иначе возвращает ErrQuotaExceeded.
+// Вызывается в транзакции сохранения файла: проверка и увеличение выполняются одним UPDATE, поэтому
+// параллельные загрузки одного пользователя не могут вместе превысить квоту.
+// size — размер исходного файла: извлеченный из него текст (<id>_text.txt) в квоте не учитывается, так как пользователь
+// не управляет его размером, а место под него ограничено MaxExtractedSize на каждый файл.
+func (q *QuotaManager) Reserve(tx *gorm.DB, ownerID string, size int64) error {
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.StorageUsage{OwnerID: ownerID}).Error; err != nil {
+		return fmt.Errorf("не удалось создать учет объема файлов пользователя %s: %w", ownerID, err)
+	}
+	query := tx.Model(&models.StorageUsage{}).Where("owner_id = ?", ownerID)
+	if quota := q.QuotaFor(ownerID); quota > 0 {
+		query = query.Where("used_bytes + ? <= ?", size, quota)
+	}
+	result := query.Update("used_bytes", gorm.Expr("used_bytes + ?", size))
+	if result.Error != nil {
+		return fmt.Errorf("не удалось учесть объем файла пользователя %s: %w", ownerID, result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrQuotaExceeded
+	}
+	return nil
+}
+
+// Release уменьшает объем файлов пользователя на size, но не ниже нуля. Вызывается в транзакции удаления файла.
+func (q *QuotaManager) Release(tx *gorm.DB, ownerID string, size int64) error {
+	if size <= 0 {
+		return nil
+	}
+	err := tx.Model(&models.StorageUsage{}).Where("owner_id = ?", ownerID).
+		Update("used_bytes", gorm.Expr("CASE WHEN used_bytes > ? THEN used_bytes - ? ELSE 0 END", size, size)).Error
+	if err != nil {
+		return fmt.Errorf("не удалось освободить объем файла пользователя %s: %w", ownerID, err)
+	}
+	return nil
+}
+
+// ParseUserQuotas разбирает квоты отдельных пользователей в формате «пользователь:размер» через запятую,
+// например «alice:1GB,bob:0» (0 — без ограничения). Размер задается как для limits.ParseSize.
+func ParseUserQuotas(value string) (map[string]int64, error) {
+	quotas := make(map[string]int64)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		separator := strings.LastIndex(entry, ":")
+		if separator <= 0 {
+			return nil, fmt.Errorf("некорректная квота %q: ожидается пользователь:размер", entry)
+		}
+		size, err := limits.ParseSize(entry[separator+1:])
+		if err != nil {
+			return nil, fmt.Errorf("некорректная квота пользователя %s: %w", entry[:separator], err)
+		}
+		quotas[entry[:separator]] = size
+	}
+	return quotas, nil
+}
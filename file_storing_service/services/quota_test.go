@@ -0,0 +1,159 @@
+package services
+
+import (
+	"errors"
+	"file_storing_service/models"
+	"reflect"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+// usedBytes возвращает учтенный объем файлов пользователя.
+func usedBytes(t *testing.T, quota *QuotaManager, db *gorm.DB, ownerID string) int64 {
+	t.Helper()
+	usage, err := quota.Usage(db, ownerID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return usage.UsedBytes
+}
+
+func TestQuotaReserve(t *testing.T) {
+	db := newTestDB(t, &models.StorageUsage{})
+	quota := NewQuotaManager(100, map[string]int64{"big": 1000, "unlimited": 0})
+
+	if err := quota.Reserve(db, "alice", 60); err != nil {
+		t.Fatal(err)
+	}
+	// Файл, не помещающийся в остаток квоты, не учитывается
+	if err := quota.Reserve(db, "alice", 41); !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("Reserve сверх квоты: %v, ожидалась ErrQuotaExceeded", err)
+	}
+	if got := usedBytes(t, quota, db, "alice"); got != 60 {
+		t.Fatalf("объем после отклоненного файла: %d, ожидалось 60", got)
+	}
+	// Квота может быть занята полностью
+	if err := quota.Reserve(db, "alice", 40); err != nil {
+		t.Fatalf("Reserve до границы квоты: %v", err)
+	}
+	if remaining, limited, err := quota.Remaining(db, "alice"); err != nil || !limited || remaining != 0 {
+		t.Fatalf("Remaining заполненной квоты: %d, %v, %v", remaining, limited, err)
+	}
+	if err := quota.Reserve(db, "alice", 1); !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("Reserve при заполненной квоте: %v", err)
+	}
+
+	// Пустой файл помещается и в заполненную квоту
+	if err := quota.Reserve(db, "alice", 0); err != nil {
+		t.Fatalf("Reserve пустого файла: %v", err)
+	}
+
+	// Квоты отдельных пользователей заменяют квоту по умолчанию; 0 — без ограничения
+	if err := quota.Reserve(db, "big", 500); err != nil {
+		t.Fatalf("Reserve с индивидуальной квотой: %v", err)
+	}
+	if remaining, limited, _ := quota.Remaining(db, "big"); !limited || remaining != 500 {
+		t.Fatalf("Remaining индивидуальной квоты: %d, %v", remaining, limited)
+	}
+	if err := quota.Reserve(db, "unlimited", 1<<40); err != nil {
+		t.Fatalf("Reserve без ограничения: %v", err)
+	}
+	if _, limited, _ := quota.Remaining(db, "unlimited"); limited {
+		t.Fatal("квота 0 считается ограниченной")
+	}
+	if got := usedBytes(t, quota, db, "unlimited"); got != 1<<40 {
+		t.Fatalf("объем без ограничения: %d", got)
+	}
+
+	// Объем учитывается и без квоты по умолчанию
+	unlimited := NewQuotaManager(0, nil)
+	if err := unlimited.Reserve(db, "bob", 1<<30); err != nil {
+		t.Fatal(err)
+	}
+	if got := usedBytes(t, unlimited, db, "bob"); got != 1<<30 {
+		t.Fatalf("объем bob: %d", got)
+	}
+}
+
+func TestQuotaReserveRollsBackWithTransaction(t *testing.T) {
+	db := newTestDB(t, &models.StorageUsage{})
+	quota := NewQuotaManager(100, nil)
+
+	// Файл не сохранился: его объем не учитывается
+	failed := errors.New("не удалось сохранить метаданные")
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := quota.Reserve(tx, "alice", 80); err != nil {
+			return err
+		}
+		return failed
+	})
+	if !errors.Is(err, failed) {
+		t.Fatal(err)
+	}
+	if got := usedBytes(t, quota, db, "alice"); got != 0 {
+		t.Fatalf("объем после отката: %d, ожидалось 0", got)
+	}
+	if err := quota.Reserve(db, "alice", 100); err != nil {
+		t.Fatalf("квота после отката: %v", err)
+	}
+}
+
+func TestQuotaRelease(t *testing.T) {
+	db := newTestDB(t, &models.StorageUsage{})
+	quota := NewQuotaManager(100, nil)
+
+	if err := quota.Reserve(db, "alice", 90); err != nil {
+		t.Fatal(err)
+	}
+	if err := quota.Release(db, "alice", 30); err != nil {
+		t.Fatal(err)
+	}
+	if got := usedBytes(t, quota, db, "alice"); got != 60 {
+		t.Fatalf("объем после удаления файла: %d, ожидалось 60", got)
+	}
+	// Освободившееся место снова можно занять
+	if err := quota.Reserve(db, "alice", 40); err != nil {
+		t.Fatalf("Reserve после удаления файла: %v", err)
+	}
+
+	// Файлы без размера (загруженные до учета квот) не меняют объем
+	if err := quota.Release(db, "alice", 0); err != nil {
+		t.Fatal(err)
+	}
+	if got := usedBytes(t, quota, db, "alice"); got != 100 {
+		t.Fatalf("объем после удаления файла без размера: %d, ожидалось 100", got)
+	}
+	// Объем не становится отрицательным, даже если учет разошелся с файлами
+	if err := quota.Release(db, "alice", 150); err != nil {
+		t.Fatal(err)
+	}
+	if got := usedBytes(t, quota, db, "alice"); got != 0 {
+		t.Fatalf("объем после удаления большего файла: %d, ожидалось 0", got)
+	}
+	// У пользователя без учета объема ничего не создается
+	if err := quota.Release(db, "nobody", 10); err != nil {
+		t.Fatal(err)
+	}
+	var count int64
+	db.Model(&models.StorageUsage{}).Where("owner_id = ?", "nobody").Count(&count)
+	if count != 0 {
+		t.Fatal("Release создал учет объема пользователя без файлов")
+	}
+}
+
+func TestParseUserQuotas(t *testing.T) {
+	quotas, err := ParseUserQuotas(" alice:10MB , bob:0,,team:lead:1KB ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]int64{"alice": 10 << 20, "bob": 0, "team:lead": 1 << 10}
+	if !reflect.DeepEqual(quotas, want) {
+		t.Fatalf("ParseUserQuotas = %v, ожидалось %v", quotas, want)
+	}
+	for _, value := range []string{"alice", ":10MB", "alice:много"} {
+		if _, err := ParseUserQuotas(value); err == nil {
+			t.Errorf("ParseUserQuotas(%q): ожидалась ошибка", value)
+		}
+	}
+}
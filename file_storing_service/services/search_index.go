@@ -0,0 +1,350 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"file_storing_service/models"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"pkg/adapters"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// DefaultMaxIndexedSize — сколько байт извлеченного текста файла индексируется по умолчанию.
+	// Ограничение нужно потому, что tsvector в PostgreSQL не может быть больше 1 МБ.
+	DefaultMaxIndexedSize = 512 << 10
+	// MaxSearchQueryLength — наибольшая длина поискового запроса в символах.
+	MaxSearchQueryLength = 500
+	// searchBackfillBatch — сколько файлов индексируется за один запрос к БД при заполнении индекса.
+	searchBackfillBatch = 100
+	// searchConfig — конфигурация текстового поиска PostgreSQL. В конфигурации russian слова кириллицей
+	// нормализуются русским стеммером, а слова латиницей — английским, поэтому она подходит для обоих языков.
+	searchConfig = "russian"
+	// Маркеры начала и конца совпадения во фрагменте ts_headline. Из индексируемого текста они удаляются,
+	// а после экранирования HTML заменяются тегами <mark>.
+	highlightStart = "\x01"
+	highlightStop  = "\x02"
+)
+
+// ErrInvalidSearchQuery возвращается для пустого поискового запроса или запроса только из исключаемых слов.
+var ErrInvalidSearchQuery = errors.New("некорректный поисковый запрос")
+
+// SearchIndex индексирует извлеченный текст файлов и ищет по нему.
+// @Summary Полнотекстовый поиск по файлам
+// @Description Хранит начало извлеченного текста каждого файла в таблице search_documents с вычисляемым столбцом tsvector
+// @Description и GIN-индексом по нему. Ищет файлы по словам, фразам и префиксам слов и ранжирует их по ts_rank_cd.
+// @Tags services
+type SearchIndex struct {
+	DB             *gorm.DB
+	Storage        adapters.FileStorage
+	MaxIndexedSize int // Сколько байт извлеченного текста файла индексируется
+}
+
+// NewSearchIndex создает новый экземпляр SearchIndex с настройками по умолчанию.
+// @Summary Создает новый SearchIndex
+// @Description Инициализирует поисковый индекс. Схему создает Migrate, индексацию ранее загруженных файлов запускает Start.
+// @Return *SearchIndex
+func NewSearchIndex(db *gorm.DB, storage adapters.FileStorage) *SearchIndex {
+	return &SearchIndex{
+		DB:             db,
+		Storage:        storage,
+		MaxIndexedSize: DefaultMaxIndexedSize,
+	}
+}
+
+// Migrate создает таблицу search_documents, вычисляемый столбец search_vector и GIN-индекс по нему.
+// @Summary Миграция поискового индекса
+// @Description Вызывается при запуске сервиса; повторный вызов ничего не меняет.
+// @Return error
+func (s *SearchIndex) Migrate() error {
+	if err := s.DB.AutoMigrate(&models.SearchDocument{}); err != nil {
+		return err
+	}
+	statements := []string{
+		fmt.Sprintf("ALTER TABLE search_documents ADD COLUMN IF NOT EXISTS search_vector tsvector "+
+			"GENERATED ALWAYS AS (to_tsvector('%s'::regconfig, content)) STORED", searchConfig),
+		"CREATE INDEX IF NOT EXISTS idx_search_documents_search_vector ON search_documents USING GIN (search_vector)",
+	}
+	for _, statement := range statements {
+		if err := s.DB.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Index добавляет в индекс извлеченный текст text файла fileID в транзакции tx, сохраняющей метаданные файла.
+// Если текст не удалось проиндексировать (например, его tsvector больше 1 МБ), файл сохраняется без записи
+// в индексе: изменения индекса откатываются до точки сохранения, а ошибка только записывается в лог.
+func (s *SearchIndex) Index(tx *gorm.DB, fileID, text string) error {
+	if err := tx.SavePoint("search_document").Error; err != nil {
+		return err
+	}
+	document := s.document(fileID, text)
+	if err := tx.Create(&document).Error; err != nil {
+		log.Printf("Не удалось добавить файл %s в поисковый индекс: %v", fileID, err)
+		return tx.RollbackTo("search_document").Error
+	}
+	return nil
+}
+
+// document возвращает запись индекса для файла fileID с извлеченным текстом text.
+func (s *SearchIndex) document(fileID, text string) models.SearchDocument {
+	if len(text) > s.MaxIndexedSize {
+		// Обрезаем по границе символа UTF-8
+		end := s.MaxIndexedSize
+		for end > 0 && !utf8.RuneStart(text[end]) {
+			end--
+		}
+		text = text[:end]
+	}
+	text = strings.NewReplacer(highlightStart, " ", highlightStop, " ", "\x00", " ").Replace(text)
+	return models.SearchDocument{FileID: fileID, Content: text}
+}
+
+// Start запускает в фоне индексацию файлов, загруженных до появления поиска.
+// @Summary Заполнение индекса
+// @Description Запускает горутину, однократно выполняющую Backfill.
+func (s *SearchIndex) Start(ctx context.Context) {
+	go func() {
+		indexed, err := s.Backfill(ctx)
+		if err != nil {
+			log.Printf("Ошибка при индексации ранее загруженных файлов: %v", err)
+		}
+		if indexed > 0 {
+			log.Printf("Проиндексировано ранее загруженных файлов: %d", indexed)
+		}
+	}()
+}
+
+// Backfill индексирует файлы, для которых еще нет записи в индексе, читая их текст из хранилища.
+// Файлы, текст которых не удалось прочитать или проиндексировать, пропускаются до следующего запуска сервиса.
+// @Summary Индексация ранее загруженных файлов
+// @Return int, error "Количество проиндексированных файлов и ошибка, если есть"
+func (s *SearchIndex) Backfill(ctx context.Context) (int, error) {
+	indexed := 0
+	lastID := ""
+	for ctx.Err() == nil {
+		var files []models.File
+		err := s.DB.Where("id > ? AND NOT EXISTS (SELECT 1 FROM search_documents d WHERE d.file_id = files.id)", lastID).
+			Order("id").Limit(searchBackfillBatch).
+			Find(&files).Error
+		if err != nil {
+			return indexed, fmt.Errorf("не удалось получить файлы без записи в поисковом индексе: %w", err)
+		}
+		for _, file := range files {
+			text, err := s.readText(file)
+			if err != nil {
+				log.Printf("Не удалось прочитать текст файла %s для поискового индекса: %v", file.ID, err)
+				continue
+			}
+			document := s.document(file.ID, text)
+			if err := s.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&document).Error; err != nil {
+				log.Printf("Не удалось добавить файл %s в поисковый индекс: %v", file.ID, err)
+				continue
+			}
+			indexed++
+		}
+		if len(files) < searchBackfillBatch {
+			break
+		}
+		lastID = files[len(files)-1].ID
+	}
+	return indexed, ctx.Err()
+}
+
+// readText читает из хранилища не больше MaxIndexedSize байт текста файла.
+func (s *SearchIndex) readText(file models.File) (string, error) {
+	// Запас в один символ UTF-8, чтобы document обрезал текст по границе символа
+	content, err := s.Storage.OpenRange(file.TextKey(), 0, int64(s.MaxIndexedSize+utf8.UTFMax))
+	if err != nil {
+		return "", err
+	}
+	defer content.Close()
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// SearchRequest — параметры поиска.
+type SearchRequest struct {
+	Query   string // Запрос в синтаксисе ParseSearchQuery
+	OwnerID string // Искать только среди файлов этого владельца; пусто — среди всех файлов
+	Limit   int
+	Offset  int
+}
+
+// SearchHit — найденный файл.
+// @Description Файл, текст которого соответствует запросу, с оценкой релевантности и фрагментами текста.
+// @Name SearchHit
+type SearchHit struct {
+	FileID       string    `json:"file_id" example:"unique-file-id"`
+	Name         string    `json:"name" example:"essay.pdf"`
+	MimeType     string    `json:"mime_type" example:"application/pdf"`
+	OwnerID      string    `json:"owner_id" example:"user-42"`
+	CollectionID string    `json:"collection_id,omitempty" example:"collection-id"`
+	CreatedAt    time.Time `json:"created_at"`
+	Rank         float64   `json:"rank" example:"0.42"`                                                                     // Релевантность (ts_rank_cd); чем больше, тем выше файл в списке
+	Snippet      string    `json:"snippet" example:"… история <mark>промышленной</mark> <mark>революции</mark> в Англии …"` // Фрагменты текста с совпадениями в <mark>; остальной текст экранирован для HTML
+}
+
+// Search возвращает страницу файлов, текст которых соответствует запросу, в порядке убывания релевантности,
+// и общее количество таких файлов.
+// @Summary Поиск файлов по тексту
+// @Param request body SearchRequest true "Запрос, владелец и страница"
+// @Return []SearchHit, int64, error "Найденные файлы, их общее количество и ошибка, если есть"
+func (s *SearchIndex) Search(request SearchRequest) ([]SearchHit, int64, error) {
+	tsquery, err := ParseSearchQuery(request.Query)
+	if err != nil {
+		return nil, 0, err
+	}
+	conditions := "d.search_vector @@ to_tsquery(@config::regconfig, @query) AND f.deleted_at IS NULL"
+	if request.OwnerID != "" {
+		conditions += " AND f.owner_id = @owner"
+	}
+	args := []any{
+		sql.Named("config", searchConfig),
+		sql.Named("query", tsquery),
+		sql.Named("owner", request.OwnerID),
+		sql.Named("limit", request.Limit),
+		sql.Named("offset", request.Offset),
+		sql.Named("headline", fmt.Sprintf(`StartSel=%s, StopSel=%s, MaxFragments=3, MaxWords=20, MinWords=8, FragmentDelimiter=" … "`,
+			highlightStart, highlightStop)),
+	}
+
+	var total int64
+	err = s.DB.Raw("SELECT COUNT(*) FROM search_documents d JOIN files f ON f.id = d.file_id WHERE "+conditions, args...).
+		Scan(&total).Error
+	if err != nil {
+		return nil, 0, fmt.Errorf("не удалось подсчитать найденные файлы: %w", err)
+	}
+	hits := []SearchHit{}
+	if total == 0 {
+		return hits, 0, nil
+	}
+	// Фрагменты текста строятся только для файлов страницы: ts_headline разбирает весь текст документа заново
+	err = s.DB.Raw(`SELECT f.id AS file_id, f.name, f.mime_type, f.owner_id, f.collection_id, f.created_at, page.rank,
+			ts_headline(@config::regconfig, d.content, to_tsquery(@config::regconfig, @query), @headline) AS snippet
+		FROM (
+			SELECT d.file_id, ts_rank_cd(d.search_vector, to_tsquery(@config::regconfig, @query), 1) AS rank
+			FROM search_documents d JOIN files f ON f.id = d.file_id
+			WHERE `+conditions+`
+			ORDER BY rank DESC, d.file_id
+			LIMIT @limit OFFSET @offset
+		) page
+		JOIN search_documents d ON d.file_id = page.file_id
+		JOIN files f ON f.id = page.file_id
+		ORDER BY page.rank DESC, page.file_id`, args...).
+		Scan(&hits).Error
+	if err != nil {
+		return nil, 0, fmt.Errorf("не удалось найти файлы: %w", err)
+	}
+	for i := range hits {
+		hits[i].Snippet = highlightSnippet(hits[i].Snippet)
+	}
+	return hits, total, nil
+}
+
+// highlightSnippet экранирует фрагмент текста для HTML и заменяет маркеры совпадений тегами <mark>.
+func highlightSnippet(snippet string) string {
+	snippet = html.EscapeString(snippet)
+	return strings.NewReplacer(highlightStart, "<mark>", highlightStop, "</mark>").Replace(snippet)
+}
+
+// ParseSearchQuery преобразует поисковый запрос в запрос tsquery PostgreSQL.
+// Поддерживаемый синтаксис:
+//   - слова через пробел должны встречаться в тексте все (И);
+//   - "фраза в кавычках" — слова должны идти подряд;
+//   - слово* — слово с таким началом;
+//   - -слово или -"фраза" — текст не должен содержать слово или фразу;
+//   - OR между словами или фразами — достаточно одного из них; OR связывает слабее, чем И.
+//
+// Слова составляются только из букв и цифр, поэтому запрос пользователя не может нарушить синтаксис tsquery.
+// Слово с другими символами (например, «e-mail») ищется как фраза из его частей.
+func ParseSearchQuery(query string) (string, error) {
+	if utf8.RuneCountInString(query) > MaxSearchQueryLength {
+		return "", fmt.Errorf("%w: запрос длиннее %d символов", ErrInvalidSearchQuery, MaxSearchQueryLength)
+	}
+	var (
+		result   strings.Builder
+		operator = ""
+		positive = false
+	)
+	rest := strings.TrimSpace(query)
+	for rest != "" {
+		negate := false
+		if strings.HasPrefix(rest, "-") {
+			negate = true
+			rest = rest[1:]
+		}
+		var term string
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.Index(rest[1:], `"`)
+			if end < 0 {
+				term, rest = rest[1:], ""
+			} else {
+				term, rest = rest[1:end+1], rest[end+2:]
+			}
+		} else {
+			end := strings.IndexFunc(rest, unicode.IsSpace)
+			if end < 0 {
+				end = len(rest)
+			}
+			term, rest = rest[:end], rest[end:]
+			if term == "OR" && !negate {
+				if result.Len() > 0 {
+					operator = " | "
+				}
+				rest = strings.TrimSpace(rest)
+				continue
+			}
+		}
+		rest = strings.TrimSpace(rest)
+
+		prefix := strings.HasSuffix(term, "*")
+		words := strings.FieldsFunc(term, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) })
+		if len(words) == 0 {
+			continue
+		}
+		for i, word := range words {
+			words[i] = "'" + strings.ToLower(word) + "'"
+		}
+		if prefix {
+			words[len(words)-1] += ":*"
+		}
+		expression := strings.Join(words, " <-> ")
+		if len(words) > 1 {
+			expression = "(" + expression + ")"
+		}
+		if negate {
+			expression = "!" + expression
+		} else {
+			positive = true
+		}
+
+		if result.Len() > 0 {
+			if operator == "" {
+				operator = " & "
+			}
+			result.WriteString(operator)
+		}
+		result.WriteString(expression)
+		operator = ""
+	}
+	if !positive {
+		return "", fmt.Errorf("%w: укажите хотя бы одно слово, которое должно встречаться в тексте", ErrInvalidSearchQuery)
+	}
+	return result.String(), nil
+}
@@ -0,0 +1,177 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"file_storing_service/models"
+	"fmt"
+	"pkg/adapters"
+	"strings"
+	"testing"
+)
+
+func TestParseSearchQuery(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{"слова через пробел", "кошка собака", `'кошка' & 'собака'`},
+		{"регистр", "КОШКА Dog", `'кошка' & 'dog'`},
+		{"фраза", `"красная шапочка"`, `('красная' <-> 'шапочка')`},
+		{"фраза вплотную к слову", `"ab"cd`, `'ab' & 'cd'`},
+		{"незакрытая кавычка", `кошка "серый волк`, `'кошка' & ('серый' <-> 'волк')`},
+		{"пустая фраза", `"" кошка`, `'кошка'`},
+		{"префикс", "револю*", `'револю':*`},
+		{"префикс в конце фразы", `"new yor*"`, `('new' <-> 'yor':*)`},
+		{"исключаемое слово", "кошка -собака", `'кошка' & !'собака'`},
+		{"исключаемая фраза", `кошка -"серый волк"`, `'кошка' & !('серый' <-> 'волк')`},
+		{"OR", "кошка OR собака", `'кошка' | 'собака'`},
+		{"OR связывает слабее И", "кошка OR собака мышь", `'кошка' | 'собака' & 'мышь'`},
+		{"OR с фразой", `"серый волк" OR лиса`, `('серый' <-> 'волк') | 'лиса'`},
+		{"повторный OR", "кошка OR OR собака", `'кошка' | 'собака'`},
+		{"OR в начале и в конце", "OR кошка OR", `'кошка'`},
+		{"or строчными — обычное слово", "кошка or собака", `'кошка' & 'or' & 'собака'`},
+		{"-OR — исключаемое слово", "кошка -OR", `'кошка' & !'or'`},
+		{"дефис внутри слова", "e-mail", `('e' <-> 'mail')`},
+		{"одиночный минус", "- кошка", `'кошка'`},
+		{"одиночная звездочка", "кошка *", `'кошка'`},
+		{"операторы tsquery", "кошка & собака | мышь ! ( ) :", `'кошка' & 'собака' & 'мышь'`},
+		{"метасимволы внутри слова", "a&b|c!d:e(f)g'h", `('a' <-> 'b' <-> 'c' <-> 'd' <-> 'e' <-> 'f' <-> 'g' <-> 'h')`},
+		{"кавычка и точка с запятой", `'; DROP TABLE files; --`, `'drop' & 'table' & 'files'`},
+		{"отрицание tsquery", "!кошка", `'кошка'`},
+		{"вес и префикс tsquery", "кошка:*A", `('кошка' <-> 'a')`},
+		{"пробелы по краям", "  \tкошка  \n", `'кошка'`},
+		{"цифры", "2024 год", `'2024' & 'год'`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSearchQuery(tt.query)
+			if err != nil {
+				t.Fatalf("ParseSearchQuery(%q): %v", tt.query, err)
+			}
+			if got != tt.want {
+				t.Fatalf("ParseSearchQuery(%q) = %s, ожидалось %s", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSearchQueryRejectsQueriesWithoutPositiveTerms(t *testing.T) {
+	for _, query := range []string{"", "   ", "-кошка", `-"серый волк"`, "OR", "& | ! ( )", `""`, strings.Repeat("а", MaxSearchQueryLength+1)} {
+		if got, err := ParseSearchQuery(query); !errors.Is(err, ErrInvalidSearchQuery) {
+			t.Errorf("ParseSearchQuery(%.20q) = %q, %v; ожидалась ErrInvalidSearchQuery", query, got, err)
+		}
+	}
+	if _, err := ParseSearchQuery(strings.Repeat("а", MaxSearchQueryLength)); err != nil {
+		t.Fatalf("запрос наибольшей длины: %v", err)
+	}
+}
+
+func TestHighlightSnippet(t *testing.T) {
+	tests := []struct {
+		snippet string
+		want    string
+	}{
+		{"обычный текст", "обычный текст"},
+		{"история " + highlightStart + "революции" + highlightStop + " в Англии", "история <mark>революции</mark> в Англии"},
+		{`<script>alert("x")</script> & 'q'`, "&lt;script&gt;alert(&#34;x&#34;)&lt;/script&gt; &amp; &#39;q&#39;"},
+		{highlightStart + "<b>" + highlightStop + " … " + highlightStart + "a&b" + highlightStop, "<mark>&lt;b&gt;</mark> … <mark>a&amp;b</mark>"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := highlightSnippet(tt.snippet); got != tt.want {
+			t.Errorf("highlightSnippet(%q) = %q, ожидалось %q", tt.snippet, got, tt.want)
+		}
+	}
+}
+
+func TestSearchIndexDocument(t *testing.T) {
+	index := &SearchIndex{MaxIndexedSize: 7}
+	// Текст обрезается по границе символа UTF-8, маркеры совпадений и NUL заменяются пробелами
+	if got := index.document("f1", "абвгд").Content; got != "абв" {
+		t.Fatalf("обрезанный текст %q, ожидалось %q", got, "абв")
+	}
+	if got := index.document("f1", "a"+highlightStart+"b\x00c"+highlightStop).Content; got != "a b c " {
+		t.Fatalf("текст с маркерами %q", got)
+	}
+}
+
+func TestSearchIndexBackfill(t *testing.T) {
+	db := newTestDB(t, &models.File{}, &models.SearchDocument{})
+	storage, err := adapters.NewFileStorageAdapter(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	index := NewSearchIndex(db, storage)
+	index.MaxIndexedSize = 9
+
+	// Файлов больше, чем обрабатывается за один запрос к БД
+	const count = searchBackfillBatch + 20
+	for i := 0; i < count; i++ {
+		file := models.File{ID: fmt.Sprintf("file%03d", i), Location: fmt.Sprintf("file%03d.pdf", i), TextLocation: fmt.Sprintf("file%03d_text.txt", i)}
+		if err := storage.SaveFileFromBytes(file.TextLocation, []byte(fmt.Sprintf("текст %d", i))); err != nil {
+			t.Fatal(err)
+		}
+		db.Create(&file)
+	}
+	// Файл, загруженный до появления экстракторов: текстом является сам исходный файл
+	storage.SaveFileFromBytes("legacy.txt", []byte("старый файл"))
+	db.Create(&models.File{ID: "legacy", Location: "legacy.txt"})
+	// Уже проиндексированный файл не индексируется заново
+	db.Create(&models.File{ID: "indexed", Location: "indexed.txt"})
+	db.Create(&models.SearchDocument{FileID: "indexed", Content: "прежний текст"})
+	// Текст файла не удалось прочитать: файл пропускается до следующего запуска
+	db.Create(&models.File{ID: "missing", Location: "missing.txt"})
+	// Удаленный файл не индексируется
+	storage.SaveFileFromBytes("deleted.txt", []byte("удален"))
+	deleted := models.File{ID: "deleted", Location: "deleted.txt"}
+	db.Create(&deleted)
+	db.Delete(&deleted)
+
+	indexed, err := index.Backfill(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if indexed != count+1 {
+		t.Fatalf("проиндексировано %d файлов, ожидалось %d", indexed, count+1)
+	}
+	var documents []models.SearchDocument
+	db.Order("file_id").Find(&documents)
+	contents := make(map[string]string, len(documents))
+	for _, document := range documents {
+		contents[document.FileID] = document.Content
+	}
+	if len(contents) != count+2 {
+		t.Fatalf("записей в индексе %d, ожидалось %d", len(contents), count+2)
+	}
+	for id, want := range map[string]string{
+		"file000": "текс", // Текст обрезан до MaxIndexedSize байт по границе символа
+		"file119": "текс",
+		"legacy":  "стар",
+		"indexed": "прежний текст",
+	} {
+		if contents[id] != want {
+			t.Errorf("текст файла %s в индексе %q, ожидалось %q", id, contents[id], want)
+		}
+	}
+	for _, id := range []string{"missing", "deleted"} {
+		if _, ok := contents[id]; ok {
+			t.Errorf("файл %s попал в индекс", id)
+		}
+	}
+
+	// Повторный запуск индексирует только то, что не удалось в прошлый раз
+	storage.SaveFileFromBytes("missing.txt", []byte("нашелся"))
+	if indexed, err := index.Backfill(context.Background()); err != nil || indexed != 1 {
+		t.Fatalf("повторная индексация: %d, %v; ожидался 1 файл", indexed, err)
+	}
+
+	// Отмененный контекст прерывает индексацию
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	db.Where("1 = 1").Delete(&models.SearchDocument{})
+	if indexed, err := index.Backfill(ctx); !errors.Is(err, context.Canceled) || indexed != 0 {
+		t.Fatalf("индексация с отмененным контекстом: %d, %v", indexed, err)
+	}
+}
@@ -0,0 +1,288 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"file_storing_service/models"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// DefaultUploadSessionTTL — сколько незавершенная сессия загрузки хранится после последней полученной части.
+	DefaultUploadSessionTTL = 24 * time.Hour
+	// DefaultUploadSessionGCInterval — как часто удаляются заброшенные сессии загрузки.
+	DefaultUploadSessionGCInterval = time.Hour
+	// DefaultMaxUploadSessionsPerOwner — сколько незавершенных сессий загрузки может быть у одного пользователя.
+	DefaultMaxUploadSessionsPerOwner = 10
+	// uploadPartSuffix — расширение временных файлов сессий в каталоге Dir.
+	uploadPartSuffix = ".part"
+)
+
+var (
+	// ErrUploadSessionBusy возвращается, если часть файла в ту же сессию уже загружается другим запросом.
+	ErrUploadSessionBusy = errors.New("в сессию уже загружается другая часть файла")
+	// ErrChunkTooLarge возвращается, если часть файла выходит за пределы его объявленного размера.
+	ErrChunkTooLarge = errors.New("часть файла выходит за пределы его размера")
+	// ErrTooManyUploadSessions возвращается, если у пользователя слишком много незавершенных сессий загрузки.
+	ErrTooManyUploadSessions = errors.New("слишком много незавершенных сессий загрузки")
+)
+
+// UploadSessions хранит сессии возобновляемой загрузки: записи в таблице upload_sessions и полученные части файлов
+// во временных файлах каталога Dir. Блокировки сессий хранятся в памяти процесса, поэтому возобновляемую загрузку
+// обслуживает один экземпляр File Storing Service.
+// @Summary Сессии возобновляемой загрузки
+// @Description Принимает части файла по смещениям и удаляет сессии, не завершенные за TTL.
+// @Tags services
+type UploadSessions struct {
+	DB          *gorm.DB
+	Dir         string        // Каталог временных файлов сессий
+	TTL         time.Duration // Срок жизни незавершенной сессии после последней полученной части
+	Interval    time.Duration // Интервал удаления заброшенных сессий
+	MaxPerOwner int64         // Наибольшее количество незавершенных сессий одного пользователя
+
+	mu     sync.Mutex
+	active map[string]bool // Сессии, в которые сейчас записывается часть файла или которые завершаются
+}
+
+// NewUploadSessions создает новый экземпляр UploadSessions с настройками по умолчанию и создает каталог dir.
+// @Summary Создает новый UploadSessions
+// @Description Инициализирует хранение сессий загрузки. Фоновое удаление заброшенных сессий запускается методом Start.
+// @Return *UploadSessions
+func NewUploadSessions(db *gorm.DB, dir string) (*UploadSessions, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("не удалось создать каталог сессий загрузки %s: %w", dir, err)
+	}
+	return &UploadSessions{
+		DB:          db,
+		Dir:         dir,
+		TTL:         DefaultUploadSessionTTL,
+		Interval:    DefaultUploadSessionGCInterval,
+		MaxPerOwner: DefaultMaxUploadSessionsPerOwner,
+		active:      map[string]bool{},
+	}, nil
+}
+
+// path возвращает путь к временному файлу сессии.
+func (s *UploadSessions) path(id string) string {
+	return filepath.Join(s.Dir, id+uploadPartSuffix)
+}
+
+// openSessions возвращает запрос незавершенных сессий пользователя ownerID, срок жизни которых не истек.
+// Истекшие сессии еще не удалены PurgeExpired, но продолжить загрузку в них уже нельзя.
+func (s *UploadSessions) openSessions(ownerID string) *gorm.DB {
+	return s.DB.Model(&models.UploadSession{}).Where("owner_id = ? AND expires_at > ?", ownerID, time.Now())
+}
+
+// ReservedBytes возвращает суммарный размер файлов незавершенных сессий пользователя ownerID.
+// Эти файлы еще не учтены в объеме файлов пользователя, но место под них уже обещано при создании сессий.
+func (s *UploadSessions) ReservedBytes(ownerID string) (int64, error) {
+	var reserved int64
+	if err := s.openSessions(ownerID).Select("COALESCE(SUM(size), 0)").Scan(&reserved).Error; err != nil {
+		return 0, fmt.Errorf("не удалось получить размер сессий загрузки пользователя %s: %w", ownerID, err)
+	}
+	return reserved, nil
+}
+
+// Create создает пустой временный файл и запись сессии. Полученных байт у новой сессии нет.
+func (s *UploadSessions) Create(session *models.UploadSession) error {
+	var count int64
+	if err := s.openSessions(session.OwnerID).Count(&count).Error; err != nil {
+		return fmt.Errorf("не удалось получить сессии загрузки пользователя %s: %w", session.OwnerID, err)
+	}
+	if s.MaxPerOwner > 0 && count >= s.MaxPerOwner {
+		return ErrTooManyUploadSessions
+	}
+
+	session.Offset = 0
+	session.ExpiresAt = time.Now().Add(s.TTL)
+	part, err := os.OpenFile(s.path(session.ID), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return fmt.Errorf("не удалось создать временный файл сессии %s: %w", session.ID, err)
+	}
+	part.Close()
+	if err := s.DB.Create(session).Error; err != nil {
+		_ = os.Remove(s.path(session.ID))
+		return fmt.Errorf("не удалось сохранить сессию загрузки %s: %w", session.ID, err)
+	}
+	return nil
+}
+
+// Lock захватывает сессию на время записи части файла или завершения загрузки.
+// Если сессия уже захвачена, возвращается ErrUploadSessionBusy.
+func (s *UploadSessions) Lock(id string) (unlock func(), err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.active[id] {
+		return nil, ErrUploadSessionBusy
+	}
+	s.active[id] = true
+	return func() {
+		s.mu.Lock()
+		delete(s.active, id)
+		s.mu.Unlock()
+	}, nil
+}
+
+// Append записывает часть файла из body начиная со смещения session.Offset и сохраняет новое смещение.
+// Вызывается под блокировкой Lock. Если соединение прервалось, полученные байты сохраняются и загрузку можно
+// продолжить с нового смещения. Часть, выходящая за пределы размера файла, отбрасывается целиком с ErrChunkTooLarge.
+func (s *UploadSessions) Append(session *models.UploadSession, body io.Reader) (int64, error) {
+	part, err := os.OpenFile(s.path(session.ID), os.O_WRONLY|os.O_CREATE, 0o600)
+	if err != nil {
+		return 0, fmt.Errorf("не удалось открыть временный файл сессии %s: %w", session.ID, err)
+	}
+	defer part.Close()
+
+	// Байты после сохраненного смещения могли остаться от части, смещение которой не удалось сохранить
+	if err := part.Truncate(session.Offset); err != nil {
+		return 0, fmt.Errorf("не удалось подготовить временный файл сессии %s: %w", session.ID, err)
+	}
+	if _, err := part.Seek(session.Offset, io.SeekStart); err != nil {
+		return 0, fmt.Errorf("не удалось подготовить временный файл сессии %s: %w", session.ID, err)
+	}
+
+	written, copyErr := io.Copy(part, io.LimitReader(body, session.Size-session.Offset))
+	if copyErr == nil {
+		var extra [1]byte
+		if n, _ := io.ReadFull(body, extra[:]); n > 0 {
+			_ = part.Truncate(session.Offset)
+			return 0, ErrChunkTooLarge
+		}
+	}
+	if written > 0 {
+		if err := part.Sync(); err != nil {
+			return 0, fmt.Errorf("не удалось записать часть файла сессии %s: %w", session.ID, err)
+		}
+		offset := session.Offset + written
+		expiresAt := time.Now().Add(s.TTL)
+		err := s.DB.Model(session).Updates(map[string]any{"upload_offset": offset, "expires_at": expiresAt}).Error
+		if err != nil {
+			return 0, fmt.Errorf("не удалось сохранить смещение сессии %s: %w", session.ID, err)
+		}
+		session.Offset = offset
+		session.ExpiresAt = expiresAt
+	}
+	return written, copyErr
+}
+
+// Open открывает временный файл сессии для чтения.
+func (s *UploadSessions) Open(session *models.UploadSession) (*os.File, error) {
+	return os.Open(s.path(session.ID))
+}
+
+// Checksum вычисляет SHA-256 полученных байт файла сессии в hex.
+func (s *UploadSessions) Checksum(session *models.UploadSession) (string, error) {
+	part, err := s.Open(session)
+	if err != nil {
+		return "", fmt.Errorf("не удалось открыть временный файл сессии %s: %w", session.ID, err)
+	}
+	defer part.Close()
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, part); err != nil {
+		return "", fmt.Errorf("не удалось прочитать временный файл сессии %s: %w", session.ID, err)
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// RemoveFile удаляет временный файл сессии. Запись сессии удаляется вызывающим, например в транзакции сохранения файла.
+func (s *UploadSessions) RemoveFile(id string) {
+	if err := os.Remove(s.path(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Printf("Не удалось удалить временный файл сессии загрузки %s: %v", id, err)
+	}
+}
+
+// Remove удаляет запись и временный файл сессии.
+func (s *UploadSessions) Remove(session *models.UploadSession) error {
+	if err := s.DB.Delete(&models.UploadSession{}, "id = ?", session.ID).Error; err != nil {
+		return fmt.Errorf("не удалось удалить сессию загрузки %s: %w", session.ID, err)
+	}
+	s.RemoveFile(session.ID)
+	return nil
+}
+
+// Start запускает фоновое удаление заброшенных сессий: сразу и затем каждые Interval, пока не отменен ctx.
+// @Summary Запуск удаления заброшенных сессий
+// @Description Запускает горутину, периодически выполняющую PurgeExpired.
+func (s *UploadSessions) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(s.Interval)
+		defer ticker.Stop()
+		for {
+			if err := s.PurgeExpired(); err != nil {
+				log.Printf("Ошибка при удалении заброшенных сессий загрузки: %v", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// PurgeExpired удаляет сессии, срок жизни которых истек, и временные файлы, для которых нет сессии.
+// @Summary Удаление заброшенных сессий
+// @Description Удаляет записи и временные файлы сессий, в которые дольше TTL не поступало частей файла,
+// @Description а также временные файлы старше TTL без записи сессии (например, оставшиеся после сбоя).
+// @Return error
+func (s *UploadSessions) PurgeExpired() error {
+	var expired []models.UploadSession
+	if err := s.DB.Where("expires_at <= ?", time.Now()).Find(&expired).Error; err != nil {
+		return fmt.Errorf("не удалось получить заброшенные сессии загрузки: %w", err)
+	}
+	purged := 0
+	for i := range expired {
+		unlock, err := s.Lock(expired[i].ID)
+		if err != nil {
+			continue // В сессию прямо сейчас загружается часть файла
+		}
+		err = s.Remove(&expired[i])
+		unlock()
+		if err != nil {
+			return err
+		}
+		purged++
+	}
+	if purged > 0 {
+		log.Printf("Удалено заброшенных сессий загрузки: %d", purged)
+	}
+	return s.purgeOrphanFiles()
+}
+
+// purgeOrphanFiles удаляет временные файлы старше TTL, для которых нет записи сессии.
+func (s *UploadSessions) purgeOrphanFiles() error {
+	entries, err := os.ReadDir(s.Dir)
+	if err != nil {
+		return fmt.Errorf("не удалось прочитать каталог сессий загрузки: %w", err)
+	}
+	threshold := time.Now().Add(-s.TTL)
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), uploadPartSuffix)
+		if !ok || entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || info.ModTime().After(threshold) {
+			continue
+		}
+		var count int64
+		if err := s.DB.Model(&models.UploadSession{}).Where("id = ?", id).Count(&count).Error; err != nil {
+			return fmt.Errorf("не удалось проверить сессию загрузки %s: %w", id, err)
+		}
+		if count == 0 {
+			s.RemoveFile(id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package services
+
+import (
+	"errors"
+	"file_storing_service/models"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func newTestUploadSessions(t *testing.T) *UploadSessions {
+	t.Helper()
+	uploads, err := NewUploadSessions(newTestDB(t, &models.UploadSession{}), t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	uploads.MaxPerOwner = 2
+	return uploads
+}
+
+func createTestSession(t *testing.T, uploads *UploadSessions, id, ownerID string, size int64) error {
+	t.Helper()
+	return uploads.Create(&models.UploadSession{ID: id, Name: id + ".txt", OwnerID: ownerID, Size: size})
+}
+
+// expireSession переносит срок жизни сессии в прошлое, как если бы в нее долго не поступали части файла.
+func expireSession(t *testing.T, uploads *UploadSessions, id string) {
+	t.Helper()
+	err := uploads.DB.Model(&models.UploadSession{}).Where("id = ?", id).Update("expires_at", time.Now().Add(-time.Minute)).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUploadSessionsLimitIgnoresExpiredSessions(t *testing.T) {
+	uploads := newTestUploadSessions(t)
+	for i := 0; i < 2; i++ {
+		if err := createTestSession(t, uploads, fmt.Sprintf("s%d", i), "alice", 10); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := createTestSession(t, uploads, "s2", "alice", 10); !errors.Is(err, ErrTooManyUploadSessions) {
+		t.Fatalf("сессия сверх MaxPerOwner: ошибка %v", err)
+	}
+	if err := createTestSession(t, uploads, "b0", "bob", 10); err != nil {
+		t.Fatalf("сессии другого пользователя не должны учитываться: %v", err)
+	}
+
+	// Истекшая сессия еще не удалена фоновой очисткой, но место в ограничении уже освобождает
+	expireSession(t, uploads, "s0")
+	if err := createTestSession(t, uploads, "s2", "alice", 10); err != nil {
+		t.Fatalf("истекшая сессия учтена в MaxPerOwner: %v", err)
+	}
+}
+
+func TestUploadSessionsReservedBytes(t *testing.T) {
+	uploads := newTestUploadSessions(t)
+	uploads.MaxPerOwner = 0
+
+	reserved, err := uploads.ReservedBytes("alice")
+	if err != nil || reserved != 0 {
+		t.Fatalf("без сессий: %d, %v", reserved, err)
+	}
+	for id, size := range map[string]int64{"s0": 100, "s1": 250, "s2": 1000} {
+		if err := createTestSession(t, uploads, id, "alice", size); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := createTestSession(t, uploads, "b0", "bob", 5000); err != nil {
+		t.Fatal(err)
+	}
+	expireSession(t, uploads, "s2")
+
+	reserved, err = uploads.ReservedBytes("alice")
+	if err != nil || reserved != 350 {
+		t.Fatalf("зарезервировано %d, %v; ожидалось 350 (без истекшей сессии и сессий другого пользователя)", reserved, err)
+	}
+}
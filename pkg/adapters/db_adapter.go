@@ -58,6 +58,17 @@ func (a *DBAdapter) First(out interface{}, where ...interface{}) error {
 	return a.DB.First(out, where...).Error
 }
 
+// Last находит последнюю (с наибольшим первичным ключом) запись, соответствующую условиям.
+// @Summary Поиск последней записи
+// @Description Находит последнюю по первичному ключу запись, удовлетворяющую заданным условиям.
+// @Param out Указатель на переменную для результата
+// @Param where Условия поиска (например, "file_id = ?")
+// @Param args Аргументы для условий поиска
+// @Return error
+func (a *DBAdapter) Last(out interface{}, where ...interface{}) error {
+	return a.DB.Last(out, where...).Error
+}
+
 // Find находит все записи, соответствующие условиям.
 // @Summary Поиск всех записей
 // @Description Находит все записи, удовлетворяющие заданным условиям.
@@ -67,3 +78,14 @@ func (a *DBAdapter) First(out interface{}, where ...interface{}) error {
 func (a *DBAdapter) Find(out interface{}, where ...interface{}) error {
 	return a.DB.Find(out, where...).Error
 }
+
+// Transaction выполняет функцию fn в рамках одной транзакции.
+// @Summary Выполнение транзакции
+// @Description Передает в fn адаптер, привязанный к транзакции. Если fn возвращает ошибку, транзакция откатывается.
+// @Param fn Функция, выполняемая в транзакции
+// @Return error
+func (a *DBAdapter) Transaction(fn func(tx *DBAdapter) error) error {
+	return a.DB.Transaction(func(tx *gorm.DB) error {
+		return fn(&DBAdapter{DB: tx})
+	})
+}
@@ -1,15 +1,46 @@
 package adapters
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 )
 
+// FileInfo — сведения о сохраненном файле.
+type FileInfo struct {
+	Size    int64     // Размер в байтах
+	ModTime time.Time // Время последнего изменения
+}
+
+// FileStorage предоставляет интерфейс объектного хранилища файлов.
+// @Summary Интерфейс файлового хранилища
+// @Description Унифицирует сохранение, чтение и удаление файлов по ключу независимо от того, где они хранятся (локальный диск или S3-совместимое хранилище).
+// @Tags adapters
+type FileStorage interface {
+	// SaveFile сохраняет содержимое data под ключом key.
+	SaveFile(key string, data io.Reader) error
+	// SaveFileFromBytes сохраняет массив байт под ключом key.
+	SaveFileFromBytes(key string, data []byte) error
+	// OpenFile открывает файл для потокового чтения. Вызывающий обязан закрыть результат.
+	OpenFile(key string) (io.ReadCloser, error)
+	// OpenRange открывает для потокового чтения length байт файла, начиная со смещения offset.
+	// При отрицательном length файл читается до конца. Вызывающий обязан закрыть результат.
+	OpenRange(key string, offset, length int64) (io.ReadCloser, error)
+	// Stat возвращает размер и время изменения файла. Для несуществующего файла ошибка оборачивает fs.ErrNotExist.
+	Stat(key string) (FileInfo, error)
+	// ReadFile читает содержимое файла целиком.
+	ReadFile(key string) ([]byte, error)
+	// DeleteFile удаляет файл. Удаление несуществующего файла не является ошибкой.
+	DeleteFile(key string) error
+}
+
 // FileStorageAdapter предоставляет интерфейс для сохранения и чтения файлов.
 // @Summary Адаптер для файлового хранилища
-// @Description Унифицирует операции сохранения и чтения файлов с диска.
+// @Description Реализация FileStorage, хранящая файлы в локальной директории. Ключ файла — путь относительно StoragePath.
 // @Tags adapters
 type FileStorageAdapter struct {
 	StoragePath string // Путь к корневой директории хранилища
@@ -27,60 +58,141 @@ func NewFileStorageAdapter(storagePath string) (*FileStorageAdapter, error) {
 	return &FileStorageAdapter{StoragePath: storagePath}, nil
 }
 
+// resolvePath преобразует ключ в путь на диске и проверяет, что он не выходит за пределы StoragePath.
+// Для совместимости с записями, созданными до перехода на ключи, принимается и абсолютный путь внутри хранилища.
+func (a *FileStorageAdapter) resolvePath(key string) (string, error) {
+	root, err := filepath.Abs(a.StoragePath)
+	if err != nil {
+		return "", fmt.Errorf("ошибка при получении абсолютного пути хранилища %s: %w", a.StoragePath, err)
+	}
+
+	var filePath string
+	if filepath.IsAbs(key) {
+		filePath = filepath.Clean(key)
+	} else {
+		filePath = filepath.Join(root, key)
+	}
+
+	rel, err := filepath.Rel(root, filePath)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("недопустимый ключ файла: %s", key)
+	}
+	return filePath, nil
+}
+
 // SaveFile сохраняет данные в файл.
 // @Summary Сохранение файла
-// @Description Записывает содержимое io.Reader в файл по указанному пути относительно StoragePath.
-// @Param relativePath Относительный путь к файлу внутри хранилища
+// @Description Записывает содержимое io.Reader в файл с указанным ключом (путь относительно StoragePath).
+// @Param key Ключ файла внутри хранилища
 // @Param data io.Reader с данными для сохранения
-// @Return string, error "Полный путь к сохраненному файлу и ошибка, если есть"
-func (a *FileStorageAdapter) SaveFile(relativePath string, data io.Reader) (string, error) {
-	filePath := filepath.Join(a.StoragePath, relativePath)
+// @Return error
+func (a *FileStorageAdapter) SaveFile(key string, data io.Reader) error {
+	filePath, err := a.resolvePath(key)
+	if err != nil {
+		return err
+	}
 
 	// Создаем все необходимые директории по пути к файлу
 	if err := os.MkdirAll(filepath.Dir(filePath), os.ModePerm); err != nil {
-		return "", fmt.Errorf("не удалось создать директории для файла %s: %w", filePath, err)
+		return fmt.Errorf("не удалось создать директории для файла %s: %w", filePath, err)
 	}
 
 	out, err := os.Create(filePath)
 	if err != nil {
-		return "", fmt.Errorf("не удалось создать файл %s: %w", filePath, err)
+		return fmt.Errorf("не удалось создать файл %s: %w", filePath, err)
 	}
 	defer out.Close()
 
-	_, err = io.Copy(out, data)
-	if err != nil {
-		return "", fmt.Errorf("не удалось записать данные в файл %s: %w", filePath, err)
+	if _, err := io.Copy(out, data); err != nil {
+		_ = os.Remove(filePath)
+		return fmt.Errorf("не удалось записать данные в файл %s: %w", filePath, err)
 	}
-	return filePath, nil
+	return nil
 }
 
 // SaveFileFromBytes сохраняет байтовый массив в файл.
 // @Summary Сохранение файла из байтов
-// @Description Записывает байтовый массив в файл по указанному пути относительно StoragePath.
-// @Param relativePath Относительный путь к файлу внутри хранилища
+// @Description Записывает байтовый массив в файл с указанным ключом.
+// @Param key Ключ файла внутри хранилища
 // @Param data Массив байт для сохранения
-// @Return string, error "Полный путь к сохраненному файлу и ошибка, если есть"
-func (a *FileStorageAdapter) SaveFileFromBytes(relativePath string, data []byte) (string, error) {
-	filePath := filepath.Join(a.StoragePath, relativePath)
+// @Return error
+func (a *FileStorageAdapter) SaveFileFromBytes(key string, data []byte) error {
+	return a.SaveFile(key, bytes.NewReader(data))
+}
 
-	if err := os.MkdirAll(filepath.Dir(filePath), os.ModePerm); err != nil {
-		return "", fmt.Errorf("не удалось создать директории для файла %s: %w", filePath, err)
+// OpenFile открывает файл для чтения.
+// @Summary Открытие файла
+// @Description Открывает файл с указанным ключом для потокового чтения.
+// @Param key Ключ файла внутри хранилища
+// @Return io.ReadCloser, error
+func (a *FileStorageAdapter) OpenFile(key string) (io.ReadCloser, error) {
+	filePath, err := a.resolvePath(key)
+	if err != nil {
+		return nil, err
 	}
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось открыть файл %s: %w", filePath, err)
+	}
+	return file, nil
+}
 
-	err := os.WriteFile(filePath, data, 0644)
+// OpenRange открывает часть файла для чтения.
+// @Summary Открытие части файла
+// @Description Открывает файл с указанным ключом и возвращает поток из length байт, начиная со смещения offset (до конца файла при отрицательном length).
+// @Param key Ключ файла внутри хранилища
+// @Param offset Смещение первого байта
+// @Param length Количество байт
+// @Return io.ReadCloser, error
+func (a *FileStorageAdapter) OpenRange(key string, offset, length int64) (io.ReadCloser, error) {
+	filePath, err := a.resolvePath(key)
 	if err != nil {
-		return "", fmt.Errorf("не удалось записать данные в файл %s: %w", filePath, err)
+		return nil, err
 	}
-	return filePath, nil
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось открыть файл %s: %w", filePath, err)
+	}
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("не удалось перейти к смещению %d файла %s: %w", offset, filePath, err)
+	}
+	if length < 0 {
+		return file, nil
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(file, length), file}, nil
+}
+
+// Stat возвращает сведения о файле.
+// @Summary Сведения о файле
+// @Description Возвращает размер и время изменения файла с указанным ключом.
+// @Param key Ключ файла внутри хранилища
+// @Return FileInfo, error
+func (a *FileStorageAdapter) Stat(key string) (FileInfo, error) {
+	filePath, err := a.resolvePath(key)
+	if err != nil {
+		return FileInfo{}, err
+	}
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return FileInfo{}, fmt.Errorf("не удалось получить сведения о файле %s: %w", filePath, err)
+	}
+	return FileInfo{Size: info.Size(), ModTime: info.ModTime()}, nil
 }
 
 // ReadFile читает содержимое файла.
 // @Summary Чтение файла
-// @Description Читает и возвращает содержимое файла по указанному пути относительно StoragePath.
-// @Param relativePath Относительный путь к файлу внутри хранилища
+// @Description Читает и возвращает содержимое файла с указанным ключом.
+// @Param key Ключ файла внутри хранилища
 // @Return []byte, error "Содержимое файла и ошибка, если есть"
-func (a *FileStorageAdapter) ReadFile(relativePath string) ([]byte, error) {
-	filePath := filepath.Join(a.StoragePath, relativePath)
+func (a *FileStorageAdapter) ReadFile(key string) ([]byte, error) {
+	filePath, err := a.resolvePath(key)
+	if err != nil {
+		return nil, err
+	}
 	data, err := os.ReadFile(filePath)
 	if err != nil {
 		return nil, fmt.Errorf("не удалось прочитать файл %s: %w", filePath, err)
@@ -88,6 +200,22 @@ func (a *FileStorageAdapter) ReadFile(relativePath string) ([]byte, error) {
 	return data, nil
 }
 
+// DeleteFile удаляет файл.
+// @Summary Удаление файла
+// @Description Удаляет файл с указанным ключом. Отсутствие файла не считается ошибкой.
+// @Param key Ключ файла внутри хранилища
+// @Return error
+func (a *FileStorageAdapter) DeleteFile(key string) error {
+	filePath, err := a.resolvePath(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(filePath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("не удалось удалить файл %s: %w", filePath, err)
+	}
+	return nil
+}
+
 // GetAbsPath возвращает абсолютный путь к файлу в хранилище.
 // @Summary Получение абсолютного пути
 // @Description Возвращает абсолютный путь к файлу, комбинируя StoragePath и relativePath.
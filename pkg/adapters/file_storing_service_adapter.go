@@ -1,88 +1,161 @@
 package adapters
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
+	"net/url"
+	"pkg/auth"
+	"time"
 )
 
 // FileStoringServiceAdapter предоставляет интерфейс для взаимодействия с FileStoringService.
 // @Summary Адаптер для FileStoringService
-// @Description Обеспечивает методы для получения данных о файлах из FileStoringService.
+// @Description Обеспечивает методы для получения данных о файлах из внутренних эндпоинтов FileStoringService.
 // @Tags adapters
 type FileStoringServiceAdapter struct {
-	ServiceBaseURL string // Базовый URL FileStoringService, например, http://file_storing_service:8081
+	ServiceBaseURL string       // Базовый URL внутренних эндпоинтов FileStoringService, например, http://file_storing_service:8091
+	Client         *http.Client // Клиент, подписывающий каждый запрос
 }
 
 // NewFileStoringServiceAdapter создает новый экземпляр FileStoringServiceAdapter.
 // @Summary Создает новый FileStoringServiceAdapter
-// @Description Инициализирует адаптер с базовым URL FileStoringService.
+// @Description Инициализирует адаптер с базовым URL FileStoringService. Каждый запрос подписывается signer,
+// @Description так как внутренние эндпоинты FileStoringService принимают только подписанные запросы.
 // @Param serviceBaseURL Базовый URL FileStoringService
+// @Param signer Подпись запросов
 // @Return *FileStoringServiceAdapter
-func NewFileStoringServiceAdapter(serviceBaseURL string) *FileStoringServiceAdapter {
-	return &FileStoringServiceAdapter{ServiceBaseURL: serviceBaseURL}
+func NewFileStoringServiceAdapter(serviceBaseURL string, signer auth.ServiceSigner) *FileStoringServiceAdapter {
+	return &FileStoringServiceAdapter{
+		ServiceBaseURL: serviceBaseURL,
+		Client:         signer.Client(5 * time.Minute),
+	}
 }
 
-// FileLocationResponse определяет структуру ответа для получения location файла.
-// @Description Структура ответа с местоположением файла.
-// @Name FileLocationResponse
-type FileLocationResponse struct {
-	Location string `json:"location"`
+// OpenFileContent открывает поток извлеченного текста файла из FileStoringService по ID файла.
+// @Summary Получение текста файла
+// @Description Обращается к внутреннему эндпоинту FileStoringService и возвращает тело ответа без чтения в память,
+// @Description поэтому анализ больших файлов не требует памяти, пропорциональной их размеру. Вызывающий обязан закрыть результат.
+// @Param fileID ID файла
+// @Return io.ReadCloser, error "Поток текста файла и ошибка, если есть"
+func (a *FileStoringServiceAdapter) OpenFileContent(fileID string) (io.ReadCloser, error) {
+	resp, err := a.Client.Get(fmt.Sprintf("%s/api/v1/internal/files/%s/content", a.ServiceBaseURL, url.PathEscape(fileID)))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при запросе текста файла %s: %w", fileID, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, fmt.Errorf("FileStoringService вернул ошибку %d для файла %s: %s", resp.StatusCode, fileID, string(body))
+	}
+	return resp.Body, nil
 }
 
-// GetFileLocationByID запрашивает у FileStoringService местоположение файла по его ID.
-// @Summary Получение местоположения файла
-// @Description Обращается к FileStoringService для получения пути к файлу.
-// @Param fileID ID файла
-// @Return string, error "Местоположение файла и ошибка, если есть"
-func (a *FileStoringServiceAdapter) GetFileLocationByID(fileID string) (string, error) {
-	resp, err := http.Get(fmt.Sprintf("%s/api/v1/internal/files/%s/location", a.ServiceBaseURL, fileID))
+// FileOwnersResponse определяет структуру ответа с владельцами файлов.
+// @Description Владельцы найденных файлов по их ID.
+// @Name FileOwnersResponse
+type FileOwnersResponse struct {
+	Owners map[string]string `json:"owners"`
+}
+
+// GetFileOwners запрашивает у FileStoringService владельцев файлов.
+// @Summary Получение владельцев файлов
+// @Description Обращается к FileStoringService для получения ID владельцев файлов. Удаленных и несуществующих файлов в ответе нет.
+// @Param fileIDs ID файлов
+// @Return map[string]string, error "Владелец каждого найденного файла и ошибка, если есть"
+func (a *FileStoringServiceAdapter) GetFileOwners(fileIDs []string) (map[string]string, error) {
+	body, err := json.Marshal(map[string][]string{"ids": fileIDs})
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при сериализации запроса владельцев файлов: %w", err)
+	}
+	resp, err := a.Client.Post(fmt.Sprintf("%s/api/v1/internal/files/owners", a.ServiceBaseURL), "application/json", bytes.NewReader(body))
 	if err != nil {
-		return "", fmt.Errorf("ошибка при запросе местоположения файла %s: %w", fileID, err)
+		return nil, fmt.Errorf("ошибка при запросе владельцев файлов: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, errRead := ioutil.ReadAll(resp.Body)
-		if errRead != nil {
-			return "", fmt.Errorf("FileStoringService вернул ошибку %d для файла %s и не удалось прочитать тело ответа: %w", resp.StatusCode, fileID, errRead)
-		}
-		return "", fmt.Errorf("FileStoringService вернул ошибку %d для файла %s: %s", resp.StatusCode, fileID, string(body))
+		respBody, _ := ioutil.ReadAll(resp.Body)
+		return nil, fmt.Errorf("FileStoringService вернул ошибку %d при запросе владельцев файлов: %s", resp.StatusCode, string(respBody))
+	}
+
+	var ownersResp FileOwnersResponse
+	if err := json.NewDecoder(resp.Body).Decode(&ownersResp); err != nil {
+		return nil, fmt.Errorf("ошибка при декодировании владельцев файлов от FileStoringService: %w", err)
+	}
+	if ownersResp.Owners == nil {
+		ownersResp.Owners = map[string]string{}
 	}
+	return ownersResp.Owners, nil
+}
 
-	var locationResp FileLocationResponse
-	if err := json.NewDecoder(resp.Body).Decode(&locationResp); err != nil {
-		return "", fmt.Errorf("ошибка при декодировании ответа от FileStoringService для файла %s: %w", fileID, err)
+// SetFileAnalyzed сообщает FileStoringService, есть ли у файла результаты анализа.
+// @Summary Отметка об анализе файла
+// @Description Обращается к внутреннему эндпоинту FileStoringService, чтобы список файлов можно было отфильтровать по наличию анализа.
+// @Param fileID ID файла
+// @Param analyzed Есть ли у файла результаты анализа
+// @Return error
+func (a *FileStoringServiceAdapter) SetFileAnalyzed(fileID string, analyzed bool) error {
+	body, err := json.Marshal(map[string]bool{"analyzed": analyzed})
+	if err != nil {
+		return fmt.Errorf("ошибка при сериализации отметки об анализе файла: %w", err)
+	}
+	req, err := http.NewRequest(http.MethodPut, fmt.Sprintf("%s/api/v1/internal/files/%s/analysis", a.ServiceBaseURL, url.PathEscape(fileID)), bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("ошибка при создании запроса отметки об анализе файла %s: %w", fileID, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := a.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("ошибка при отправке отметки об анализе файла %s: %w", fileID, err)
 	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		respBody, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("FileStoringService вернул ошибку %d при отметке об анализе файла %s: %s", resp.StatusCode, fileID, string(respBody))
+	}
+	return nil
+}
+
+// ErrCollectionNotFound возвращается GetCollectionFiles, если коллекции нет в FileStoringService.
+var ErrCollectionNotFound = errors.New("коллекция не найдена")
 
-	return locationResp.Location, nil
+// CollectionFiles — владелец коллекции и ID ее файлов.
+// @Description ID коллекции, ее владелец и ID ее неудаленных файлов.
+// @Name CollectionFiles
+type CollectionFiles struct {
+	ID      string   `json:"id"`
+	OwnerID string   `json:"owner_id"`
+	FileIDs []string `json:"file_ids"`
 }
 
-// GetFileContentByLocation запрашивает у FileStoringService содержимое файла по его местоположению.
-// @Summary Получение содержимого файла
-// @Description Обращается к FileStoringService для получения содержимого файла.
-// @Param location Местоположение файла
-// @Return []byte, error "Содержимое файла и ошибка, если есть"
-func (a *FileStoringServiceAdapter) GetFileContentByLocation(location string) ([]byte, error) {
-	resp, err := http.Get(fmt.Sprintf("%s/api/v1/internal/file-content?location=%s", a.ServiceBaseURL, location))
+// GetCollectionFiles запрашивает у FileStoringService владельца и файлы коллекции.
+// @Summary Получение файлов коллекции
+// @Description Обращается к внутреннему эндпоинту FileStoringService. Если коллекции нет, возвращается ErrCollectionNotFound.
+// @Param collectionID ID коллекции
+// @Return *CollectionFiles, error "Владелец и файлы коллекции и ошибка, если есть"
+func (a *FileStoringServiceAdapter) GetCollectionFiles(collectionID string) (*CollectionFiles, error) {
+	resp, err := a.Client.Get(fmt.Sprintf("%s/api/v1/internal/collections/%s/files", a.ServiceBaseURL, url.PathEscape(collectionID)))
 	if err != nil {
-		return nil, fmt.Errorf("ошибка при запросе содержимого файла по location %s: %w", location, err)
+		return nil, fmt.Errorf("ошибка при запросе файлов коллекции %s: %w", collectionID, err)
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("коллекция с ID %s: %w", collectionID, ErrCollectionNotFound)
+	}
 	if resp.StatusCode != http.StatusOK {
-		body, errRead := ioutil.ReadAll(resp.Body)
-		if errRead != nil {
-			return nil, fmt.Errorf("FileStoringService вернул ошибку %d для location %s и не удалось прочитать тело ответа: %w", resp.StatusCode, location, errRead)
-		}
-		return nil, fmt.Errorf("FileStoringService вернул ошибку %d для location %s: %s", resp.StatusCode, location, string(body))
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, fmt.Errorf("FileStoringService вернул ошибку %d при запросе файлов коллекции %s: %s", resp.StatusCode, collectionID, string(body))
 	}
 
-	content, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("ошибка при чтении ответа от FileStoringService для location %s: %w", location, err)
+	var collection CollectionFiles
+	if err := json.NewDecoder(resp.Body).Decode(&collection); err != nil {
+		return nil, fmt.Errorf("ошибка при декодировании файлов коллекции от FileStoringService: %w", err)
 	}
-
-	return content, nil
+	return &collection, nil
 }
@@ -0,0 +1,402 @@
+package adapters
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// emptyPayloadHash — SHA-256 пустого тела запроса.
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// S3StorageAdapter реализует FileStorage поверх S3-совместимого хранилища (AWS S3, MinIO).
+// @Summary Адаптер для S3-совместимого хранилища
+// @Description Сохраняет файлы как объекты в бакете. Запросы подписываются AWS Signature V4, используется адресация path-style.
+// @Tags adapters
+type S3StorageAdapter struct {
+	Endpoint  *url.URL // Адрес хранилища, например, http://minio:9000
+	Region    string   // Регион, участвующий в подписи запросов
+	Bucket    string   // Имя бакета
+	AccessKey string
+	SecretKey string
+	Client    *http.Client
+}
+
+const (
+	s3ConnectAttempts   = 10
+	s3ConnectRetryDelay = 3 * time.Second
+)
+
+// NewS3StorageAdapter создает новый экземпляр S3StorageAdapter и создает бакет, если его еще нет.
+// @Summary Создает новый S3StorageAdapter
+// @Description Инициализирует адаптер с адресом хранилища, регионом, бакетом и ключами доступа.
+// @Param endpoint Адрес хранилища
+// @Param region Регион
+// @Param bucket Имя бакета
+// @Param accessKey Ключ доступа
+// @Param secretKey Секретный ключ
+// @Return *S3StorageAdapter, error
+func NewS3StorageAdapter(endpoint, region, bucket, accessKey, secretKey string) (*S3StorageAdapter, error) {
+	endpointURL, err := url.Parse(endpoint)
+	if err != nil || endpointURL.Scheme == "" || endpointURL.Host == "" {
+		return nil, fmt.Errorf("некорректный адрес S3-хранилища: %s", endpoint)
+	}
+	if bucket == "" {
+		return nil, fmt.Errorf("не указано имя бакета S3-хранилища")
+	}
+	if region == "" {
+		region = "us-east-1"
+	}
+	a := &S3StorageAdapter{
+		Endpoint:  endpointURL,
+		Region:    region,
+		Bucket:    bucket,
+		AccessKey: accessKey,
+		SecretKey: secretKey,
+		Client:    &http.Client{Timeout: 5 * time.Minute},
+	}
+	// Хранилище может запускаться одновременно с сервисом, поэтому проверка бакета повторяется несколько раз
+	for attempt := 1; ; attempt++ {
+		err = a.ensureBucket()
+		if err == nil {
+			return a, nil
+		}
+		if attempt == s3ConnectAttempts {
+			return nil, err
+		}
+		time.Sleep(s3ConnectRetryDelay)
+	}
+}
+
+// ensureBucket создает бакет, если он не существует.
+func (a *S3StorageAdapter) ensureBucket() error {
+	resp, err := a.do(http.MethodHead, "", nil, 0, emptyPayloadHash, nil)
+	if err != nil {
+		return fmt.Errorf("ошибка при проверке бакета %s: %w", a.Bucket, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+	if resp.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("S3-хранилище вернуло ошибку %d при проверке бакета %s", resp.StatusCode, a.Bucket)
+	}
+
+	resp, err = a.do(http.MethodPut, "", nil, 0, emptyPayloadHash, nil)
+	if err != nil {
+		return fmt.Errorf("ошибка при создании бакета %s: %w", a.Bucket, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusConflict {
+		return a.responseError(resp, "создании бакета", a.Bucket)
+	}
+	return nil
+}
+
+// SaveFile сохраняет данные как объект.
+// @Summary Сохранение объекта
+// @Description Буферизует данные во временный файл, чтобы вычислить их размер и хеш для подписи, и загружает объект в бакет.
+// @Param key Ключ объекта
+// @Param data io.Reader с данными для сохранения
+// @Return error
+func (a *S3StorageAdapter) SaveFile(key string, data io.Reader) error {
+	tmp, err := os.CreateTemp("", "s3-upload-*")
+	if err != nil {
+		return fmt.Errorf("не удалось создать временный файл для загрузки объекта %s: %w", key, err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	hasher := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hasher), data)
+	if err != nil {
+		return fmt.Errorf("не удалось прочитать данные объекта %s: %w", key, err)
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("не удалось перемотать временный файл объекта %s: %w", key, err)
+	}
+	return a.putObject(key, tmp, size, hex.EncodeToString(hasher.Sum(nil)))
+}
+
+// SaveFileFromBytes сохраняет массив байт как объект.
+// @Summary Сохранение объекта из байтов
+// @Description Загружает массив байт в бакет под указанным ключом.
+// @Param key Ключ объекта
+// @Param data Массив байт для сохранения
+// @Return error
+func (a *S3StorageAdapter) SaveFileFromBytes(key string, data []byte) error {
+	sum := sha256.Sum256(data)
+	return a.putObject(key, bytes.NewReader(data), int64(len(data)), hex.EncodeToString(sum[:]))
+}
+
+func (a *S3StorageAdapter) putObject(key string, body io.Reader, size int64, payloadHash string) error {
+	if err := validateObjectKey(key); err != nil {
+		return err
+	}
+	resp, err := a.do(http.MethodPut, key, body, size, payloadHash, nil)
+	if err != nil {
+		return fmt.Errorf("ошибка при загрузке объекта %s: %w", key, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return a.responseError(resp, "загрузке объекта", key)
+	}
+	return nil
+}
+
+// OpenFile открывает объект для потокового чтения.
+// @Summary Открытие объекта
+// @Description Возвращает тело ответа GET-запроса к объекту. Вызывающий обязан закрыть результат.
+// @Param key Ключ объекта
+// @Return io.ReadCloser, error
+func (a *S3StorageAdapter) OpenFile(key string) (io.ReadCloser, error) {
+	if err := validateObjectKey(key); err != nil {
+		return nil, err
+	}
+	resp, err := a.do(http.MethodGet, key, nil, 0, emptyPayloadHash, nil)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при чтении объекта %s: %w", key, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, a.responseError(resp, "чтении объекта", key)
+	}
+	return resp.Body, nil
+}
+
+// OpenRange открывает часть объекта для потокового чтения.
+// @Summary Открытие части объекта
+// @Description Выполняет GET-запрос к объекту с заголовком Range и возвращает тело ответа. Вызывающий обязан закрыть результат.
+// @Param key Ключ объекта
+// @Param offset Смещение первого байта
+// @Param length Количество байт (до конца объекта при отрицательном значении)
+// @Return io.ReadCloser, error
+func (a *S3StorageAdapter) OpenRange(key string, offset, length int64) (io.ReadCloser, error) {
+	if err := validateObjectKey(key); err != nil {
+		return nil, err
+	}
+	if length == 0 {
+		return io.NopCloser(strings.NewReader("")), nil
+	}
+	byteRange := fmt.Sprintf("bytes=%d-", offset)
+	if length > 0 {
+		byteRange += strconv.FormatInt(offset+length-1, 10)
+	}
+	resp, err := a.do(http.MethodGet, key, nil, 0, emptyPayloadHash, map[string]string{"Range": byteRange})
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при чтении объекта %s: %w", key, err)
+	}
+	switch {
+	case resp.StatusCode == http.StatusPartialContent:
+		return resp.Body, nil
+	case resp.StatusCode == http.StatusOK && offset == 0:
+		// Хранилище проигнорировало Range и вернуло объект целиком
+		if length < 0 {
+			return resp.Body, nil
+		}
+		return struct {
+			io.Reader
+			io.Closer
+		}{io.LimitReader(resp.Body, length), resp.Body}, nil
+	case resp.StatusCode == http.StatusOK:
+		resp.Body.Close()
+		return nil, fmt.Errorf("S3-хранилище не поддерживает чтение части объекта %s", key)
+	default:
+		defer resp.Body.Close()
+		return nil, a.responseError(resp, "чтении объекта", key)
+	}
+}
+
+// Stat возвращает сведения об объекте.
+// @Summary Сведения об объекте
+// @Description Выполняет HEAD-запрос к объекту и возвращает его размер и время изменения.
+// @Param key Ключ объекта
+// @Return FileInfo, error
+func (a *S3StorageAdapter) Stat(key string) (FileInfo, error) {
+	if err := validateObjectKey(key); err != nil {
+		return FileInfo{}, err
+	}
+	resp, err := a.do(http.MethodHead, key, nil, 0, emptyPayloadHash, nil)
+	if err != nil {
+		return FileInfo{}, fmt.Errorf("ошибка при получении сведений об объекте %s: %w", key, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return FileInfo{}, a.responseError(resp, "получении сведений об объекте", key)
+	}
+	info := FileInfo{Size: resp.ContentLength}
+	if modTime, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		info.ModTime = modTime
+	}
+	return info, nil
+}
+
+// ReadFile читает объект целиком.
+// @Summary Чтение объекта
+// @Description Читает и возвращает содержимое объекта.
+// @Param key Ключ объекта
+// @Return []byte, error
+func (a *S3StorageAdapter) ReadFile(key string) ([]byte, error) {
+	body, err := a.OpenFile(key)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при чтении объекта %s: %w", key, err)
+	}
+	return data, nil
+}
+
+// DeleteFile удаляет объект.
+// @Summary Удаление объекта
+// @Description Удаляет объект из бакета. S3 не считает удаление несуществующего объекта ошибкой.
+// @Param key Ключ объекта
+// @Return error
+func (a *S3StorageAdapter) DeleteFile(key string) error {
+	if err := validateObjectKey(key); err != nil {
+		return err
+	}
+	resp, err := a.do(http.MethodDelete, key, nil, 0, emptyPayloadHash, nil)
+	if err != nil {
+		return fmt.Errorf("ошибка при удалении объекта %s: %w", key, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return a.responseError(resp, "удалении объекта", key)
+	}
+	return nil
+}
+
+// responseError формирует ошибку из неуспешного ответа хранилища. Ответ 404 оборачивает fs.ErrNotExist.
+func (a *S3StorageAdapter) responseError(resp *http.Response, action, key string) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("S3-хранилище вернуло ошибку 404 при %s %s: %w", action, key, fs.ErrNotExist)
+	}
+	return fmt.Errorf("S3-хранилище вернуло ошибку %d при %s %s: %s", resp.StatusCode, action, key, string(body))
+}
+
+// do выполняет подписанный запрос к бакету (key == "") или объекту.
+func (a *S3StorageAdapter) do(method, key string, body io.Reader, size int64, payloadHash string, headers map[string]string) (*http.Response, error) {
+	escapedPath := "/" + s3Escape(a.Bucket, false)
+	if key != "" {
+		escapedPath += "/" + s3Escape(key, true)
+	}
+	unescapedPath, err := url.PathUnescape(escapedPath)
+	if err != nil {
+		return nil, err
+	}
+	target := *a.Endpoint
+	target.Path = strings.TrimSuffix(a.Endpoint.Path, "/") + unescapedPath
+	target.RawPath = strings.TrimSuffix(a.Endpoint.EscapedPath(), "/") + escapedPath
+
+	req, err := http.NewRequest(method, target.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.ContentLength = size
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	a.sign(req, payloadHash, time.Now().UTC())
+	return a.Client.Do(req)
+}
+
+// sign подписывает запрос по схеме AWS Signature Version 4.
+func (a *S3StorageAdapter) sign(req *http.Request, payloadHash string, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	// Подписываются Host и все заголовки x-amz-*
+	signed := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		lower := strings.ToLower(name)
+		if strings.HasPrefix(lower, "x-amz-") {
+			signed[lower] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	names := make([]string, 0, len(signed))
+	for name := range signed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + signed[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+
+	scope := date + "/" + a.Region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	signingKey := hmacSHA256([]byte("AWS4"+a.SecretKey), date)
+	signingKey = hmacSHA256(signingKey, a.Region)
+	signingKey = hmacSHA256(signingKey, "s3")
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		a.AccessKey, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// s3Escape кодирует строку по правилам URI-encode из спецификации Signature V4.
+// Если keepSlash, символ "/" не кодируется (разделитель в ключе объекта).
+func s3Escape(s string, keepSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if ('A' <= c && c <= 'Z') || ('a' <= c && c <= 'z') || ('0' <= c && c <= '9') ||
+			c == '-' || c == '_' || c == '.' || c == '~' || (keepSlash && c == '/') {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// validateObjectKey отклоняет пустые ключи, абсолютные пути и ключи с сегментами "..".
+func validateObjectKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") {
+		return fmt.Errorf("недопустимый ключ объекта: %q", key)
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == ".." {
+			return fmt.Errorf("недопустимый ключ объекта: %q", key)
+		}
+	}
+	return nil
+}
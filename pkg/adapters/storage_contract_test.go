@@ -0,0 +1,193 @@
+package adapters
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testStorageContract проверяет поведение, которое сервисы ожидают от любой реализации FileStorage.
+// Ключи файлов уникальны для запуска, поэтому тест можно выполнять на непустом хранилище.
+func testStorageContract(t *testing.T, storage FileStorage) {
+	prefix := "contract-" + time.Now().UTC().Format("20060102T150405.000000000") + "/"
+	content := []byte("Промышленная революция в Англии, 1760–1840")
+
+	t.Run("сохранение и чтение", func(t *testing.T) {
+		key := prefix + "files/essay.txt"
+		t.Cleanup(func() { storage.DeleteFile(key) })
+		if err := storage.SaveFile(key, bytes.NewReader(content)); err != nil {
+			t.Fatal(err)
+		}
+		data, err := storage.ReadFile(key)
+		if err != nil || !bytes.Equal(data, content) {
+			t.Fatalf("ReadFile: %q, %v", data, err)
+		}
+		body, err := storage.OpenFile(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err = io.ReadAll(body)
+		body.Close()
+		if err != nil || !bytes.Equal(data, content) {
+			t.Fatalf("OpenFile: %q, %v", data, err)
+		}
+		info, err := storage.Stat(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Size != int64(len(content)) {
+			t.Errorf("Stat: размер %d, ожидалось %d", info.Size, len(content))
+		}
+		if info.ModTime.IsZero() || time.Since(info.ModTime) > time.Hour {
+			t.Errorf("Stat: время изменения %v", info.ModTime)
+		}
+	})
+
+	t.Run("перезапись", func(t *testing.T) {
+		key := prefix + "overwrite.txt"
+		t.Cleanup(func() { storage.DeleteFile(key) })
+		if err := storage.SaveFileFromBytes(key, content); err != nil {
+			t.Fatal(err)
+		}
+		if err := storage.SaveFileFromBytes(key, []byte("short")); err != nil {
+			t.Fatal(err)
+		}
+		data, err := storage.ReadFile(key)
+		if err != nil || string(data) != "short" {
+			t.Fatalf("после перезаписи прочитано %q, %v", data, err)
+		}
+	})
+
+	t.Run("пустой файл", func(t *testing.T) {
+		key := prefix + "empty.txt"
+		t.Cleanup(func() { storage.DeleteFile(key) })
+		if err := storage.SaveFileFromBytes(key, nil); err != nil {
+			t.Fatal(err)
+		}
+		info, err := storage.Stat(key)
+		if err != nil || info.Size != 0 {
+			t.Fatalf("Stat: %+v, %v", info, err)
+		}
+		data, err := storage.ReadFile(key)
+		if err != nil || len(data) != 0 {
+			t.Fatalf("ReadFile: %q, %v", data, err)
+		}
+	})
+
+	t.Run("чтение части файла", func(t *testing.T) {
+		key := prefix + "range.txt"
+		t.Cleanup(func() { storage.DeleteFile(key) })
+		data := []byte("0123456789abcdef")
+		if err := storage.SaveFileFromBytes(key, data); err != nil {
+			t.Fatal(err)
+		}
+		tests := []struct {
+			offset, length int64
+			want           string
+		}{
+			{0, 4, "0123"},
+			{10, 3, "abc"},
+			{10, -1, "abcdef"},
+			{0, -1, string(data)},
+			{12, 100, "cdef"},
+			{5, 0, ""},
+		}
+		for _, tt := range tests {
+			body, err := storage.OpenRange(key, tt.offset, tt.length)
+			if err != nil {
+				t.Errorf("OpenRange(%d, %d): %v", tt.offset, tt.length, err)
+				continue
+			}
+			got, err := io.ReadAll(body)
+			body.Close()
+			if err != nil || string(got) != tt.want {
+				t.Errorf("OpenRange(%d, %d) = %q, %v; ожидалось %q", tt.offset, tt.length, got, err, tt.want)
+			}
+		}
+	})
+
+	t.Run("отсутствующий файл", func(t *testing.T) {
+		key := prefix + "missing.txt"
+		if _, err := storage.Stat(key); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("Stat: ошибка %v не оборачивает fs.ErrNotExist", err)
+		}
+		if _, err := storage.ReadFile(key); err == nil {
+			t.Error("ReadFile: нет ошибки для отсутствующего файла")
+		}
+		if body, err := storage.OpenFile(key); err == nil {
+			body.Close()
+			t.Error("OpenFile: нет ошибки для отсутствующего файла")
+		}
+		if err := storage.DeleteFile(key); err != nil {
+			t.Errorf("DeleteFile: удаление отсутствующего файла вернуло ошибку %v", err)
+		}
+	})
+
+	t.Run("удаление", func(t *testing.T) {
+		key := prefix + "deleted.txt"
+		if err := storage.SaveFileFromBytes(key, content); err != nil {
+			t.Fatal(err)
+		}
+		if err := storage.DeleteFile(key); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := storage.Stat(key); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("файл доступен после удаления: %v", err)
+		}
+	})
+
+	t.Run("ключи за пределами хранилища", func(t *testing.T) {
+		for _, key := range []string{"../outside.txt", prefix + "../../outside.txt", "/outside.txt"} {
+			if err := storage.SaveFileFromBytes(key, content); err == nil {
+				storage.DeleteFile(key)
+				t.Errorf("сохранен файл с ключом %q", key)
+			}
+			if _, err := storage.ReadFile(key); err == nil {
+				t.Errorf("прочитан файл с ключом %q", key)
+			}
+		}
+	})
+}
+
+func TestLocalStorageContract(t *testing.T) {
+	storage, err := NewFileStorage(StorageConfig{Backend: StorageBackendLocal, LocalPath: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testStorageContract(t, storage)
+}
+
+// TestS3StorageContract выполняется, если задан адрес S3-совместимого хранилища в S3_TEST_ENDPOINT, например:
+//
+//	docker run -d -p 9000:9000 minio/minio server /data
+//	S3_TEST_ENDPOINT=http://localhost:9000 go test ./adapters
+func TestS3StorageContract(t *testing.T) {
+	endpoint := os.Getenv("S3_TEST_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("S3_TEST_ENDPOINT не задан, проверка S3-хранилища пропущена")
+	}
+	storage, err := NewFileStorage(StorageConfig{
+		Backend:     StorageBackendS3,
+		S3Endpoint:  endpoint,
+		S3Region:    os.Getenv("S3_TEST_REGION"),
+		S3Bucket:    getenvDefault("S3_TEST_BUCKET", "storage-contract-test"),
+		S3AccessKey: getenvDefault("S3_TEST_ACCESS_KEY_ID", "minioadmin"),
+		S3SecretKey: getenvDefault("S3_TEST_SECRET_ACCESS_KEY", "minioadmin"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testStorageContract(t, storage)
+}
+
+func getenvDefault(name, value string) string {
+	if v := strings.TrimSpace(os.Getenv(name)); v != "" {
+		return v
+	}
+	return value
+}
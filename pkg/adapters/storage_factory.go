@@ -0,0 +1,40 @@
+package adapters
+
+import (
+	"fmt"
+)
+
+// Типы хранилищ, поддерживаемые NewFileStorage.
+const (
+	StorageBackendLocal = "local"
+	StorageBackendS3    = "s3"
+)
+
+// StorageConfig описывает конфигурацию файлового хранилища.
+// @Description Параметры локального или S3-совместимого хранилища.
+// @Name StorageConfig
+type StorageConfig struct {
+	Backend     string // local или s3
+	LocalPath   string // Директория локального хранилища
+	S3Endpoint  string // Адрес S3-совместимого хранилища, например, http://minio:9000
+	S3Region    string
+	S3Bucket    string
+	S3AccessKey string
+	S3SecretKey string
+}
+
+// NewFileStorage создает файловое хранилище по конфигурации.
+// @Summary Создание файлового хранилища
+// @Description Возвращает FileStorageAdapter для backend "local" (или пустого) и S3StorageAdapter для backend "s3".
+// @Param cfg Конфигурация хранилища
+// @Return FileStorage, error
+func NewFileStorage(cfg StorageConfig) (FileStorage, error) {
+	switch cfg.Backend {
+	case "", StorageBackendLocal:
+		return NewFileStorageAdapter(cfg.LocalPath)
+	case StorageBackendS3:
+		return NewS3StorageAdapter(cfg.S3Endpoint, cfg.S3Region, cfg.S3Bucket, cfg.S3AccessKey, cfg.S3SecretKey)
+	default:
+		return nil, fmt.Errorf("неподдерживаемый тип хранилища: %s (допустимы %s и %s)", cfg.Backend, StorageBackendLocal, StorageBackendS3)
+	}
+}
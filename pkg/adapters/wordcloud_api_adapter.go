@@ -5,6 +5,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"pkg/textproc"
 	"strings"
 )
 
@@ -25,16 +26,50 @@ func NewWordCloudAPIAdapter(baseURL string) *WordCloudAPIAdapter {
 	return &WordCloudAPIAdapter{BaseURL: baseURL}
 }
 
-// GenerateWordCloud генерирует изображение облака слов для заданного текста.
+// Ограничения текста, передаваемого WordCloudAPI в строке запроса.
+const (
+	wordCloudAPIMaxWords   = 100 // Сколько самых частых слов передается
+	wordCloudAPIMaxRepeats = 10  // Сколько раз повторяется самое частое слово
+)
+
+// wordCloudAPIText строит текст для WordCloudAPI из самых частых слов: API само считает частоты,
+// поэтому каждое слово повторяется пропорционально своей частоте (от 1 до wordCloudAPIMaxRepeats раз).
+func wordCloudAPIText(words []textproc.WordCount) string {
+	if len(words) > wordCloudAPIMaxWords {
+		words = words[:wordCloudAPIMaxWords]
+	}
+	var text strings.Builder
+	for _, w := range words {
+		repeats := w.Count * wordCloudAPIMaxRepeats / words[0].Count
+		if repeats < 1 {
+			repeats = 1
+		}
+		for i := 0; i < repeats; i++ {
+			if text.Len() > 0 {
+				text.WriteByte(' ')
+			}
+			text.WriteString(w.Word)
+		}
+	}
+	return text.String()
+}
+
+// GenerateWordCloud генерирует изображение облака слов по частотам слов текста.
 // @Summary Генерация облака слов
-// @Description Отправляет текст в WordCloudAPI и возвращает полученное изображение в виде байтов.
-// @Param text Текст для генерации облака слов
-// @Return []byte, string, error "Изображение облака слов, его Content-Type и ошибка, если есть"
-func (a *WordCloudAPIAdapter) GenerateWordCloud(text string) ([]byte, string, error) {
+// @Description Отправляет в WordCloudAPI текст из самых частых слов и возвращает полученное изображение в виде байтов.
+// @Param words Слова текста в порядке убывания частоты
+// @Param lang Язык текста (не используется: WordCloudAPI получает слова как есть)
+// @Return []WordCloudImage, error "Единственное изображение облака слов и ошибка, если есть"
+func (a *WordCloudAPIAdapter) GenerateWordCloud(words []textproc.WordCount, lang textproc.Language) ([]WordCloudImage, error) {
+	if len(words) == 0 {
+		return nil, fmt.Errorf("в тексте нет слов для построения облака слов")
+	}
+	text := wordCloudAPIText(words)
+
 	// Формируем URL с параметром text
 	apiURL, err := url.Parse(a.BaseURL)
 	if err != nil {
-		return nil, "", fmt.Errorf("неверный базовый URL для WordCloudAPI: %w", err)
+		return nil, fmt.Errorf("неверный базовый URL для WordCloudAPI: %w", err)
 	}
 	q := apiURL.Query()
 	q.Set("text", text)
@@ -45,7 +80,7 @@ func (a *WordCloudAPIAdapter) GenerateWordCloud(text string) ([]byte, string, er
 
 	resp, err := http.Get(apiURL.String())
 	if err != nil {
-		return nil, "", fmt.Errorf("ошибка при запросе к WordCloudAPI: %w", err)
+		return nil, fmt.Errorf("ошибка при запросе к WordCloudAPI: %w", err)
 	}
 	defer resp.Body.Close()
 
@@ -54,7 +89,7 @@ func (a *WordCloudAPIAdapter) GenerateWordCloud(text string) ([]byte, string, er
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := ioutil.ReadAll(resp.Body) // Читаем тело ответа для информации об ошибке
-		return nil, "", fmt.Errorf("WordCloudAPI вернул ошибку %d: %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("WordCloudAPI вернул ошибку %d: %s", resp.StatusCode, string(body))
 	}
 
 	// Получаем Content-Type из заголовка ответа
@@ -66,20 +101,20 @@ func (a *WordCloudAPIAdapter) GenerateWordCloud(text string) ([]byte, string, er
 	} else if !strings.HasPrefix(contentType, "image/") {
 		// Если контент не является изображением, возвращаем ошибку
 		body, _ := ioutil.ReadAll(resp.Body)
-		return nil, "", fmt.Errorf("WordCloudAPI вернул неожиданный Content-Type: %s. Тело ответа: %s", contentType, string(body))
+		return nil, fmt.Errorf("WordCloudAPI вернул неожиданный Content-Type: %s. Тело ответа: %s", contentType, string(body))
 	}
 
 	imageData, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return nil, "", fmt.Errorf("ошибка при чтении ответа от WordCloudAPI: %w", err)
+		return nil, fmt.Errorf("ошибка при чтении ответа от WordCloudAPI: %w", err)
 	}
 
 	// Проверяем, что получили хоть какие-то данные
 	if len(imageData) == 0 {
-		return nil, "", fmt.Errorf("WordCloudAPI вернул пустой ответ")
+		return nil, fmt.Errorf("WordCloudAPI вернул пустой ответ")
 	}
 
 	fmt.Printf("DEBUG: Получено %d байт данных из WordCloudAPI, Content-Type: %s\n", len(imageData), contentType)
 
-	return imageData, contentType, nil
+	return []WordCloudImage{{Data: imageData, ContentType: contentType}}, nil
 }
@@ -0,0 +1,77 @@
+package adapters
+
+import (
+	"fmt"
+	"pkg/textproc"
+	"pkg/wordcloud"
+	"unicode/utf8"
+)
+
+// WordCloudGenerator генерирует изображение облака слов по частотам слов текста.
+// @Summary Генератор облака слов
+// @Description Общий интерфейс локального и удаленного (через внешний API) генераторов облака слов.
+// @Description Генератор получает частоты слов, а не сам текст, поэтому текст не нужно держать в памяти целиком.
+// @Tags adapters
+type WordCloudGenerator interface {
+	// GenerateWordCloud возвращает изображения облака слов: одно или одну раскладку в нескольких форматах.
+	// words — слова текста (без чисел) в порядке убывания частоты, lang — язык текста.
+	GenerateWordCloud(words []textproc.WordCount, lang textproc.Language) ([]WordCloudImage, error)
+}
+
+// WordCloudImage — изображение облака слов в одном формате.
+type WordCloudImage struct {
+	Data        []byte
+	ContentType string // Например, image/png или image/svg+xml
+}
+
+// LocalWordCloudAdapter генерирует облако слов локально, без обращения к внешним сервисам.
+// @Summary Локальный генератор облака слов
+// @Description Подсчитывает частоты слов, отбрасывает стоп-слова и отрисовывает облако слов в PNG и SVG.
+// @Tags adapters
+type LocalWordCloudAdapter struct {
+	Options         wordcloud.Options // Размер, палитра и максимальное количество слов
+	FilterStopWords bool              // Отбрасывать ли служебные слова языка текста
+}
+
+// NewLocalWordCloudAdapter создает новый экземпляр LocalWordCloudAdapter.
+// @Summary Создает новый LocalWordCloudAdapter
+// @Description Инициализирует локальный генератор с заданными параметрами.
+// @Param options Параметры облака слов
+// @Return *LocalWordCloudAdapter
+func NewLocalWordCloudAdapter(options wordcloud.Options) *LocalWordCloudAdapter {
+	return &LocalWordCloudAdapter{Options: options, FilterStopWords: true}
+}
+
+// GenerateWordCloud генерирует изображения облака слов по частотам слов текста.
+// @Summary Генерация облака слов
+// @Description Строит облако слов по частотам слов текста и отрисовывает одну раскладку в PNG (растровое изображение для просмотра)
+// @Description и SVG (векторное, для печати и масштабирования).
+// @Param words Слова текста в порядке убывания частоты
+// @Param lang Язык текста
+// @Return []WordCloudImage, error "Изображения облака слов в PNG и SVG и ошибка, если есть"
+func (a *LocalWordCloudAdapter) GenerateWordCloud(words []textproc.WordCount, lang textproc.Language) ([]WordCloudImage, error) {
+	cloudWords := make([]wordcloud.Word, 0, len(words))
+	for _, w := range words {
+		if a.FilterStopWords && (textproc.IsStopWordIn(lang, w.Word) || utf8.RuneCountInString(w.Word) < 2) {
+			continue
+		}
+		cloudWords = append(cloudWords, wordcloud.Word{Text: w.Word, Count: w.Count})
+	}
+	if len(cloudWords) == 0 {
+		return nil, fmt.Errorf("в тексте нет слов для построения облака слов")
+	}
+
+	placed, err := wordcloud.Layout(cloudWords, a.Options)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при размещении слов облака: %w", err)
+	}
+
+	image, err := wordcloud.RenderPNG(placed, a.Options)
+	if err != nil {
+		return nil, err
+	}
+	return []WordCloudImage{
+		{Data: image, ContentType: "image/png"},
+		{Data: wordcloud.RenderSVG(placed, a.Options), ContentType: "image/svg+xml"},
+	}, nil
+}
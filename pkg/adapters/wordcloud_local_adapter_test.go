@@ -0,0 +1,36 @@
+package adapters
+
+import (
+	"bytes"
+	"image/png"
+	"pkg/textproc"
+	"pkg/wordcloud"
+	"testing"
+)
+
+func TestLocalWordCloudAdapterReturnsPNGAndSVG(t *testing.T) {
+	adapter := NewLocalWordCloudAdapter(wordcloud.DefaultOptions())
+	words := []textproc.WordCount{{Word: "облако", Count: 5}, {Word: "и", Count: 4}, {Word: "слов", Count: 3}}
+	images, err := adapter.GenerateWordCloud(words, textproc.LanguageRussian)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(images) != 2 || images[0].ContentType != "image/png" || images[1].ContentType != "image/svg+xml" {
+		t.Fatalf("изображения %d, ожидались PNG и SVG", len(images))
+	}
+	if _, err := png.Decode(bytes.NewReader(images[0].Data)); err != nil {
+		t.Fatalf("PNG не декодируется: %v", err)
+	}
+	svg := images[1].Data
+	if !bytes.Contains(svg, []byte(">облако<")) || !bytes.Contains(svg, []byte(">слов<")) {
+		t.Fatalf("SVG не содержит слов текста:\n%s", svg)
+	}
+	// Служебные слова языка текста отбрасываются
+	if bytes.Contains(svg, []byte(">и<")) {
+		t.Fatal("служебное слово попало в облако слов")
+	}
+
+	if _, err := adapter.GenerateWordCloud([]textproc.WordCount{{Word: "и", Count: 1}}, textproc.LanguageRussian); err == nil {
+		t.Fatal("ожидалась ошибка для текста из служебных слов")
+	}
+}
@@ -0,0 +1,68 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+)
+
+// memoryBodySize — тело запроса до этого размера при подписи пользователя хранится в памяти, большее — во временном файле.
+const memoryBodySize = 1 << 20
+
+// spooledBody — прочитанное тело запроса и его SHA-256. Тело больше memoryBodySize хранится во временном файле,
+// который удаляется при закрытии.
+type spooledBody struct {
+	io.Reader
+	file   *os.File
+	Size   int64
+	SHA256 string
+}
+
+// spoolBody читает r до конца, вычисляя хеш, и возвращает копию прочитанного. При ошибке временный файл удаляется.
+func spoolBody(r io.Reader) (*spooledBody, error) {
+	hasher := sha256.New()
+	body := &spooledBody{}
+	if r == nil {
+		r = bytes.NewReader(nil)
+	}
+	r = io.TeeReader(r, hasher)
+
+	var buf bytes.Buffer
+	n, err := io.Copy(&buf, io.LimitReader(r, memoryBodySize+1))
+	if err != nil {
+		return nil, err
+	}
+	if n <= memoryBodySize {
+		body.Reader, body.Size = bytes.NewReader(buf.Bytes()), n
+		body.SHA256 = hex.EncodeToString(hasher.Sum(nil))
+		return body, nil
+	}
+
+	body.file, err = os.CreateTemp("", "signed-body-*")
+	if err != nil {
+		return nil, fmt.Errorf("не удалось создать временный файл для тела запроса: %w", err)
+	}
+	body.Size, err = io.Copy(body.file, io.MultiReader(&buf, r))
+	if err == nil {
+		_, err = body.file.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		body.Close()
+		return nil, err
+	}
+	body.Reader = body.file
+	body.SHA256 = hex.EncodeToString(hasher.Sum(nil))
+	return body, nil
+}
+
+// Close удаляет временный файл тела, если он есть.
+func (b *spooledBody) Close() error {
+	if b.file == nil {
+		return nil
+	}
+	b.file.Close()
+	return os.Remove(b.file.Name())
+}
@@ -0,0 +1,66 @@
+// Package auth описывает пользователя, от имени которого API Gateway обращается к сервисам,
+// и доверенные заголовки, в которых передается его ID и роль, а также подпись запросов
+// одного сервиса к внутренним эндпоинтам другого.
+package auth
+
+import (
+	"net/http"
+)
+
+// Заголовки с проверенным пользователем. API Gateway удаляет их из запросов клиентов
+// и выставляет заново после аутентификации вместе с подписью (см. ServiceSigner.SignIdentity),
+// а сервисы принимают их только с верной подписью (см. IdentityAuth).
+const (
+	HeaderUserID   = "X-User-ID"
+	HeaderUserRole = "X-User-Role"
+)
+
+// Роли пользователей.
+const (
+	RoleUser  = "user"  // Видит только свои файлы и результаты их анализа
+	RoleAdmin = "admin" // Видит все файлы и результаты анализа
+)
+
+// Identity — аутентифицированный пользователь.
+type Identity struct {
+	UserID string
+	Role   string
+}
+
+// IsAdmin сообщает, является ли пользователь администратором.
+func (i Identity) IsAdmin() bool {
+	return i.Role == RoleAdmin
+}
+
+// CanAccess сообщает, доступен ли пользователю ресурс владельца ownerID.
+// Ресурсы без владельца (созданные до появления владельцев) доступны только администраторам.
+func (i Identity) CanAccess(ownerID string) bool {
+	return i.IsAdmin() || (ownerID != "" && ownerID == i.UserID)
+}
+
+// SetHeaders записывает пользователя в доверенные заголовки запроса.
+func (i Identity) SetHeaders(h http.Header) {
+	h.Set(HeaderUserID, i.UserID)
+	h.Set(HeaderUserRole, i.Role)
+}
+
+// FromHeaders читает пользователя из доверенных заголовков. ok равно false, если заголовка
+// с ID пользователя нет, то есть запрос пришел не через API Gateway, а от другого сервиса
+// на внутренний эндпоинт. Подпись заголовков проверяют IdentityAuth и VerifyIdentity.
+func FromHeaders(h http.Header) (identity Identity, ok bool) {
+	userID := h.Get(HeaderUserID)
+	if userID == "" {
+		return Identity{}, false
+	}
+	role := h.Get(HeaderUserRole)
+	if role == "" {
+		role = RoleUser
+	}
+	return Identity{UserID: userID, Role: role}, true
+}
+
+// DeleteHeaders удаляет доверенные заголовки из запроса.
+func DeleteHeaders(h http.Header) {
+	h.Del(HeaderUserID)
+	h.Del(HeaderUserRole)
+}
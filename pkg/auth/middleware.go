@@ -0,0 +1,37 @@
+package auth
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ServiceAuth возвращает middleware, пропускающий только запросы других сервисов, подписанные общим секретом secret.
+// Используется для внутренних эндпоинтов, не предназначенных для пользователей. Подпись сервиса не покрывает
+// заголовки X-User-ID и X-User-Role, поэтому они удаляются: внутренние эндпоинты вызываются без пользователя.
+func ServiceAuth(secret []byte) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, err := VerifyServiceRequest(c.Request, secret, DefaultMaxClockSkew); err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Доступ к внутреннему эндпоинту запрещен: " + err.Error()})
+			return
+		}
+		DeleteHeaders(c.Request.Header)
+		c.Next()
+	}
+}
+
+// IdentityAuth возвращает middleware для пользовательских эндпоинтов: пропускаются только запросы,
+// которые API Gateway переслал с пользователем в доверенных заголовках и подписал секретом secret (см. SignIdentity).
+// Поэтому запрос без пользователя обработчики получают только с внутренних эндпоинтов.
+// Тело запроса проверяется до обработчика, поэтому обработчик получает только подписанное тело.
+func IdentityAuth(secret []byte) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, err := VerifyIdentity(c.Request, secret, DefaultMaxClockSkew); err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Запрос должен приходить через API Gateway: " + err.Error()})
+			return
+		}
+		body := c.Request.Body
+		defer body.Close()
+		c.Next()
+	}
+}
@@ -0,0 +1,230 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Заголовки подписи запроса одного сервиса к другому.
+const (
+	HeaderServiceName      = "X-Service-Name"
+	HeaderServiceTimestamp = "X-Service-Timestamp"
+	HeaderServiceSignature = "X-Service-Signature"
+	// HeaderServiceBodySHA256 — SHA-256 тела запроса, пересылаемого от имени пользователя, в hex (см. SignIdentity).
+	HeaderServiceBodySHA256 = "X-Service-Body-SHA256"
+)
+
+// DefaultMaxClockSkew — насколько время подписи может отличаться от времени получателя.
+const DefaultMaxClockSkew = 5 * time.Minute
+
+// maxSignedBodySize — наибольший размер тела подписанного запроса, который читает получатель.
+const maxSignedBodySize = 10 << 20
+
+// ServiceSigner подписывает запросы сервиса общим секретом (HMAC-SHA256).
+// Подпись покрывает метод, путь, строку запроса, тело, время подписи и имя сервиса.
+type ServiceSigner struct {
+	Service string // Имя сервиса-отправителя
+	Secret  []byte
+}
+
+// Sign добавляет к запросу заголовки подписи. Тело запроса читается и восстанавливается.
+func (s ServiceSigner) Sign(req *http.Request) error {
+	body, err := readBody(req)
+	if err != nil {
+		return fmt.Errorf("не удалось прочитать тело запроса для подписи: %w", err)
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set(HeaderServiceName, s.Service)
+	req.Header.Set(HeaderServiceTimestamp, timestamp)
+	req.Header.Set(HeaderServiceSignature, signature(s.Secret, req, timestamp, s.Service, body))
+	return nil
+}
+
+// Transport возвращает http.RoundTripper, подписывающий каждый запрос перед отправкой через base
+// (nil — http.DefaultTransport).
+func (s ServiceSigner) Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &signingTransport{signer: s, base: base}
+}
+
+// Client возвращает http.Client с заданным таймаутом, подписывающий каждый запрос.
+func (s ServiceSigner) Client(timeout time.Duration) *http.Client {
+	return &http.Client{Timeout: timeout, Transport: s.Transport(nil)}
+}
+
+type signingTransport struct {
+	signer ServiceSigner
+	base   http.RoundTripper
+}
+
+func (t *signingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// RoundTripper не должен изменять исходный запрос
+	signed := req.Clone(req.Context())
+	if err := t.signer.Sign(signed); err != nil {
+		return nil, err
+	}
+	return t.base.RoundTrip(signed)
+}
+
+// SignIdentity передает в запросе, пересылаемом от имени пользователя identity, доверенные заголовки
+// с пользователем и подпись, связывающую пользователя с методом, путем, строкой запроса, временем подписи,
+// размером и SHA-256 тела. Чтобы вычислить хеш до отправки, тело читается целиком: небольшое — в память,
+// большое — во временный файл, который удаляется при закрытии тела запроса (http.Client закрывает его после отправки).
+// При ошибке чтения тело запроса закрывается.
+func (s ServiceSigner) SignIdentity(req *http.Request, identity Identity) error {
+	var original io.Reader
+	if req.Body != nil && req.Body != http.NoBody {
+		original = req.Body
+		defer req.Body.Close()
+	}
+	body, err := spoolBody(original)
+	if err != nil {
+		return fmt.Errorf("не удалось прочитать тело запроса для подписи: %w", err)
+	}
+	if body.Size == 0 {
+		req.Body = http.NoBody
+	} else {
+		req.Body = body
+	}
+	req.GetBody = nil
+	req.ContentLength = body.Size
+	req.TransferEncoding = nil
+
+	identity.SetHeaders(req.Header)
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set(HeaderServiceName, s.Service)
+	req.Header.Set(HeaderServiceTimestamp, timestamp)
+	req.Header.Set(HeaderServiceBodySHA256, body.SHA256)
+	req.Header.Set(HeaderServiceSignature, identitySignature(s.Secret, req, timestamp, s.Service, body.Size, body.SHA256))
+	return nil
+}
+
+// ErrInvalidServiceSignature возвращается для запроса без подписи или с неверной подписью.
+var ErrInvalidServiceSignature = errors.New("запрос не подписан или подпись неверна")
+
+// VerifyServiceRequest проверяет подпись запроса другого сервиса и возвращает имя сервиса-отправителя.
+// Тело запроса читается и восстанавливается.
+func VerifyServiceRequest(r *http.Request, secret []byte, maxClockSkew time.Duration) (string, error) {
+	service, timestamp, provided, err := signatureHeaders(r, maxClockSkew)
+	if err != nil {
+		return "", err
+	}
+
+	if r.ContentLength > maxSignedBodySize {
+		return "", fmt.Errorf("%w: тело запроса слишком большое", ErrInvalidServiceSignature)
+	}
+	body, err := readBody(r)
+	if err != nil {
+		return "", fmt.Errorf("не удалось прочитать тело запроса: %w", err)
+	}
+	expected, _ := hex.DecodeString(signature(secret, r, timestamp, service, body))
+	if !hmac.Equal(provided, expected) {
+		return "", ErrInvalidServiceSignature
+	}
+	return service, nil
+}
+
+// VerifyIdentity проверяет подпись пользователя, переданного в доверенных заголовках (см. SignIdentity),
+// и возвращает его. Запрос без пользователя или без подписи отклоняется. Сначала проверяется подпись заголовков,
+// затем читается тело подписанного размера и сравнивается его хеш; тело заменяется прочитанной копией,
+// которую вызывающий должен закрыть после обработки запроса, чтобы удалить ее временный файл.
+func VerifyIdentity(r *http.Request, secret []byte, maxClockSkew time.Duration) (Identity, error) {
+	identity, ok := FromHeaders(r.Header)
+	if !ok {
+		return Identity{}, ErrInvalidServiceSignature
+	}
+	service, timestamp, provided, err := signatureHeaders(r, maxClockSkew)
+	if err != nil {
+		return Identity{}, err
+	}
+	bodyHash := r.Header.Get(HeaderServiceBodySHA256)
+	if r.ContentLength < 0 || bodyHash == "" {
+		return Identity{}, ErrInvalidServiceSignature
+	}
+	expected, _ := hex.DecodeString(identitySignature(secret, r, timestamp, service, r.ContentLength, bodyHash))
+	if !hmac.Equal(provided, expected) {
+		return Identity{}, ErrInvalidServiceSignature
+	}
+
+	// Размер тела подписан, поэтому читается не больше подписанного
+	var original io.Reader
+	if r.Body != nil && r.Body != http.NoBody {
+		original = io.LimitReader(r.Body, r.ContentLength+1)
+		defer r.Body.Close()
+	}
+	body, err := spoolBody(original)
+	if err != nil {
+		return Identity{}, fmt.Errorf("не удалось прочитать тело запроса: %w", err)
+	}
+	if body.Size != r.ContentLength || body.SHA256 != bodyHash {
+		body.Close()
+		return Identity{}, fmt.Errorf("%w: тело запроса не совпадает с подписанным", ErrInvalidServiceSignature)
+	}
+	r.Body = body
+	return identity, nil
+}
+
+// signatureHeaders читает заголовки подписи и проверяет время подписи.
+func signatureHeaders(r *http.Request, maxClockSkew time.Duration) (service, timestamp string, provided []byte, err error) {
+	service = r.Header.Get(HeaderServiceName)
+	timestamp = r.Header.Get(HeaderServiceTimestamp)
+	provided, err = hex.DecodeString(r.Header.Get(HeaderServiceSignature))
+	if service == "" || timestamp == "" || err != nil || len(provided) == 0 {
+		return "", "", nil, ErrInvalidServiceSignature
+	}
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return "", "", nil, ErrInvalidServiceSignature
+	}
+	if skew := time.Since(time.Unix(unix, 0)); skew > maxClockSkew || skew < -maxClockSkew {
+		return "", "", nil, fmt.Errorf("%w: время подписи отличается от текущего больше чем на %s", ErrInvalidServiceSignature, maxClockSkew)
+	}
+	return service, timestamp, provided, nil
+}
+
+// signature вычисляет подпись запроса в hex.
+func signature(secret []byte, r *http.Request, timestamp, service string, body []byte) string {
+	bodyHash := sha256.Sum256(body)
+	mac := hmac.New(sha256.New, secret)
+	fmt.Fprintf(mac, "%s\n%s\n%s\n%s\n%s\n%s", r.Method, r.URL.EscapedPath(), r.URL.RawQuery, timestamp, hex.EncodeToString(bodyHash[:]), service)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// identitySignature вычисляет подпись пользователя из доверенных заголовков запроса и тела размером bodySize с хешем bodyHash в hex.
+// Строка подписи начинается с "user", чтобы ее нельзя было выдать за подпись запроса сервиса.
+func identitySignature(secret []byte, r *http.Request, timestamp, service string, bodySize int64, bodyHash string) string {
+	mac := hmac.New(sha256.New, secret)
+	fmt.Fprintf(mac, "user\n%s\n%s\n%s\n%s\n%s\n%s\n%s\n%d\n%s", r.Method, r.URL.EscapedPath(), r.URL.RawQuery, timestamp, service,
+		r.Header.Get(HeaderUserID), r.Header.Get(HeaderUserRole), bodySize, bodyHash)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// readBody читает тело запроса (не больше maxSignedBodySize) и заменяет его копией, чтобы его можно было прочитать снова.
+func readBody(r *http.Request) ([]byte, error) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil, nil
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxSignedBodySize+1))
+	r.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > maxSignedBodySize {
+		return nil, errors.New("тело запроса слишком большое")
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	r.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+	return body, nil
+}
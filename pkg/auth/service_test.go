@@ -0,0 +1,386 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+var testSecret = []byte("test-secret")
+
+func newSignedRequest(t *testing.T, method, target, body string) *http.Request {
+	t.Helper()
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if err := (ServiceSigner{Service: "test_service", Secret: testSecret}).Sign(req); err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	return req
+}
+
+func TestVerifyServiceRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		tamper  func(r *http.Request)
+		secret  []byte
+		wantErr bool
+	}{
+		{name: "валидная подпись", secret: testSecret},
+		{name: "другой секрет", secret: []byte("other-secret"), wantErr: true},
+		{
+			name:   "измененное тело",
+			secret: testSecret,
+			tamper: func(r *http.Request) {
+				r.Body = io.NopCloser(strings.NewReader(`{"file_id":"other"}`))
+			},
+			wantErr: true,
+		},
+		{
+			name:    "измененная строка запроса",
+			secret:  testSecret,
+			tamper:  func(r *http.Request) { r.URL.RawQuery = "analyzed=false" },
+			wantErr: true,
+		},
+		{
+			name:    "измененный путь",
+			secret:  testSecret,
+			tamper:  func(r *http.Request) { r.URL.Path = "/api/v1/internal/files/other" },
+			wantErr: true,
+		},
+		{
+			name:    "другое имя сервиса",
+			secret:  testSecret,
+			tamper:  func(r *http.Request) { r.Header.Set(HeaderServiceName, "api_gateway") },
+			wantErr: true,
+		},
+		{
+			name:    "поврежденная подпись",
+			secret:  testSecret,
+			tamper:  func(r *http.Request) { r.Header.Set(HeaderServiceSignature, "not-hex") },
+			wantErr: true,
+		},
+		{
+			name:    "нет подписи",
+			secret:  testSecret,
+			tamper:  func(r *http.Request) { r.Header.Del(HeaderServiceSignature) },
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := newSignedRequest(t, http.MethodPut, "/api/v1/internal/files/f1?analyzed=true", `{"file_id":"f1"}`)
+			if tt.tamper != nil {
+				tt.tamper(req)
+			}
+			service, err := VerifyServiceRequest(req, tt.secret, DefaultMaxClockSkew)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidServiceSignature) {
+					t.Fatalf("ожидалась ErrInvalidServiceSignature, получено %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("неожиданная ошибка: %v", err)
+			}
+			if service != "test_service" {
+				t.Fatalf("сервис = %q, ожидался test_service", service)
+			}
+			// Тело восстанавливается и доступно обработчику
+			body, _ := io.ReadAll(req.Body)
+			if string(body) != `{"file_id":"f1"}` {
+				t.Fatalf("тело после проверки = %q", body)
+			}
+		})
+	}
+}
+
+func TestVerifyServiceRequestExpiredTimestamp(t *testing.T) {
+	for _, shift := range []time.Duration{-10 * time.Minute, 10 * time.Minute} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/internal/files/f1/content", nil)
+		timestamp := strconv.FormatInt(time.Now().Add(shift).Unix(), 10)
+		req.Header.Set(HeaderServiceName, "test_service")
+		req.Header.Set(HeaderServiceTimestamp, timestamp)
+		req.Header.Set(HeaderServiceSignature, signature(testSecret, req, timestamp, "test_service", nil))
+
+		if _, err := VerifyServiceRequest(req, testSecret, DefaultMaxClockSkew); !errors.Is(err, ErrInvalidServiceSignature) {
+			t.Fatalf("сдвиг %s: ожидалась ErrInvalidServiceSignature, получено %v", shift, err)
+		}
+	}
+}
+
+func TestServiceAuthMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/internal", ServiceAuth(testSecret), func(c *gin.Context) {
+		body, _ := io.ReadAll(c.Request.Body)
+		c.String(http.StatusOK, string(body))
+	})
+
+	signed := newSignedRequest(t, http.MethodPost, "/internal", "payload")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, signed)
+	if recorder.Code != http.StatusOK || recorder.Body.String() != "payload" {
+		t.Fatalf("подписанный запрос: код %d, тело %q", recorder.Code, recorder.Body.String())
+	}
+
+	unsigned := httptest.NewRequest(http.MethodPost, "/internal", strings.NewReader("payload"))
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, unsigned)
+	if recorder.Code != http.StatusUnauthorized {
+		t.Fatalf("неподписанный запрос: код %d, ожидался 401", recorder.Code)
+	}
+}
+
+func TestSigningTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := VerifyServiceRequest(r, testSecret, DefaultMaxClockSkew); err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	client := ServiceSigner{Service: "test_service", Secret: testSecret}.Client(5 * time.Second)
+	resp, err := client.Post(server.URL+"/path?x=1", "application/json", strings.NewReader(`{}`))
+	if err != nil {
+		t.Fatalf("Post: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("код ответа %d, ожидался 204", resp.StatusCode)
+	}
+}
+
+func TestVerifyIdentity(t *testing.T) {
+	gateway := ServiceSigner{Service: "api_gateway", Secret: testSecret}
+	user := Identity{UserID: "user1", Role: RoleUser}
+	tests := []struct {
+		name    string
+		tamper  func(r *http.Request)
+		secret  []byte
+		wantErr bool
+	}{
+		{name: "валидная подпись", secret: testSecret},
+		{name: "другой секрет", secret: []byte("other-secret"), wantErr: true},
+		{
+			name:    "подмена пользователя",
+			secret:  testSecret,
+			tamper:  func(r *http.Request) { r.Header.Set(HeaderUserID, "user2") },
+			wantErr: true,
+		},
+		{
+			name:    "повышение роли",
+			secret:  testSecret,
+			tamper:  func(r *http.Request) { r.Header.Set(HeaderUserRole, RoleAdmin) },
+			wantErr: true,
+		},
+		{
+			name:    "измененный путь",
+			secret:  testSecret,
+			tamper:  func(r *http.Request) { r.URL.Path = "/api/v1/files/other" },
+			wantErr: true,
+		},
+		{
+			name:    "измененная строка запроса",
+			secret:  testSecret,
+			tamper:  func(r *http.Request) { r.URL.RawQuery = "owner=user2" },
+			wantErr: true,
+		},
+		{
+			name:    "нет пользователя",
+			secret:  testSecret,
+			tamper:  func(r *http.Request) { DeleteHeaders(r.Header) },
+			wantErr: true,
+		},
+		{
+			name:   "подмененное тело",
+			secret: testSecret,
+			tamper: func(r *http.Request) {
+				r.Body = io.NopCloser(strings.NewReader(`{"name":"Чужая"}`))
+			},
+			wantErr: true,
+		},
+		{
+			name:   "подмененное тело с пересчитанным хешем",
+			secret: testSecret,
+			tamper: func(r *http.Request) {
+				body := `{"name":"Другая коллекция"}`
+				sum := sha256.Sum256([]byte(body))
+				r.Body = io.NopCloser(strings.NewReader(body))
+				r.ContentLength = int64(len(body))
+				r.Header.Set(HeaderServiceBodySHA256, hex.EncodeToString(sum[:]))
+			},
+			wantErr: true,
+		},
+		{
+			name:   "тело длиннее подписанного",
+			secret: testSecret,
+			tamper: func(r *http.Request) {
+				r.Body = io.NopCloser(io.MultiReader(r.Body, strings.NewReader("добавка")))
+			},
+			wantErr: true,
+		},
+		{
+			name:    "нет хеша тела",
+			secret:  testSecret,
+			tamper:  func(r *http.Request) { r.Header.Del(HeaderServiceBodySHA256) },
+			wantErr: true,
+		},
+		{
+			name:    "тело неизвестного размера",
+			secret:  testSecret,
+			tamper:  func(r *http.Request) { r.ContentLength = -1 },
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/collections?owner=user1", strings.NewReader(`{"name":"Группа"}`))
+			if err := gateway.SignIdentity(req, user); err != nil {
+				t.Fatalf("SignIdentity: %v", err)
+			}
+			if tt.tamper != nil {
+				tt.tamper(req)
+			}
+			identity, err := VerifyIdentity(req, tt.secret, DefaultMaxClockSkew)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidServiceSignature) {
+					t.Fatalf("ожидалась ErrInvalidServiceSignature, получено %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("неожиданная ошибка: %v", err)
+			}
+			if identity != user {
+				t.Fatalf("пользователь = %+v, ожидался %+v", identity, user)
+			}
+			// Тело заменяется проверенной копией и доступно обработчику
+			body, _ := io.ReadAll(req.Body)
+			if string(body) != `{"name":"Группа"}` {
+				t.Fatalf("тело после проверки = %q", body)
+			}
+		})
+	}
+}
+
+func TestVerifyIdentityLargeBody(t *testing.T) {
+	// Тело больше memoryBodySize хранится во временном файле и у отправителя, и у получателя
+	payload := bytes.Repeat([]byte("0123456789"), memoryBodySize/5)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := VerifyIdentity(r, testSecret, DefaultMaxClockSkew); err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		defer r.Body.Close()
+		spooled, ok := r.Body.(*spooledBody)
+		if !ok || spooled.file == nil {
+			http.Error(w, "тело не записано во временный файл", http.StatusInternalServerError)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		if !bytes.Equal(body, payload) {
+			http.Error(w, "тело изменено", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	// Размер тела неизвестен отправителю, как у клиента, передающего тело по частям
+	req, _ := http.NewRequest(http.MethodPost, server.URL+"/api/v1/files/upload", io.NopCloser(bytes.NewReader(payload)))
+	if err := (ServiceSigner{Service: "api_gateway", Secret: testSecret}).SignIdentity(req, Identity{UserID: "user1", Role: RoleUser}); err != nil {
+		t.Fatalf("SignIdentity: %v", err)
+	}
+	spooled := req.Body.(*spooledBody)
+	if req.ContentLength != int64(len(payload)) || spooled.file == nil {
+		t.Fatalf("ContentLength = %d, тело во временном файле: %v", req.ContentLength, spooled.file != nil)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	message, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("код %d: %s", resp.StatusCode, message)
+	}
+	// http.Client закрывает тело запроса после отправки, и временный файл удаляется
+	if _, err := os.Stat(spooled.file.Name()); !os.IsNotExist(err) {
+		t.Fatalf("временный файл тела не удален: %v", err)
+	}
+}
+
+func TestVerifyIdentityRejectsServiceSignature(t *testing.T) {
+	// Подпись запроса сервиса не покрывает пользователя, поэтому не может подтверждать его
+	req := newSignedRequest(t, http.MethodGet, "/api/v1/files/f1", "")
+	Identity{UserID: "admin", Role: RoleAdmin}.SetHeaders(req.Header)
+	if _, err := VerifyIdentity(req, testSecret, DefaultMaxClockSkew); !errors.Is(err, ErrInvalidServiceSignature) {
+		t.Fatalf("ожидалась ErrInvalidServiceSignature, получено %v", err)
+	}
+}
+
+func TestIdentityAuthMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/files", IdentityAuth(testSecret), func(c *gin.Context) {
+		identity, _ := FromHeaders(c.Request.Header)
+		c.String(http.StatusOK, identity.UserID)
+	})
+
+	signed := httptest.NewRequest(http.MethodGet, "/files", nil)
+	if err := (ServiceSigner{Service: "api_gateway", Secret: testSecret}).SignIdentity(signed, Identity{UserID: "user1", Role: RoleUser}); err != nil {
+		t.Fatalf("SignIdentity: %v", err)
+	}
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, signed)
+	if recorder.Code != http.StatusOK || recorder.Body.String() != "user1" {
+		t.Fatalf("подписанный запрос: код %d, тело %q", recorder.Code, recorder.Body.String())
+	}
+
+	for name, req := range map[string]*http.Request{
+		"без пользователя": httptest.NewRequest(http.MethodGet, "/files", nil),
+		"неподписанный пользователь": func() *http.Request {
+			r := httptest.NewRequest(http.MethodGet, "/files", nil)
+			Identity{UserID: "admin", Role: RoleAdmin}.SetHeaders(r.Header)
+			return r
+		}(),
+	} {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		if recorder.Code != http.StatusUnauthorized {
+			t.Fatalf("%s: код %d, ожидался 401", name, recorder.Code)
+		}
+	}
+}
+
+func TestServiceAuthDropsIdentity(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/internal", ServiceAuth(testSecret), func(c *gin.Context) {
+		if _, ok := FromHeaders(c.Request.Header); ok {
+			c.Status(http.StatusConflict)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+
+	req := newSignedRequest(t, http.MethodGet, "/internal", "")
+	Identity{UserID: "user1", Role: RoleUser}.SetHeaders(req.Header)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusNoContent {
+		t.Fatalf("код %d: заголовки пользователя не удалены", recorder.Code)
+	}
+}
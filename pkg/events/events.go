@@ -0,0 +1,65 @@
+// Package events описывает события о файлах, которыми обмениваются сервисы, и способы их доставки:
+// HTTP-вебхук между сервисами и брокер в памяти для работы внутри одного процесса и тестов.
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// Типы событий о файлах.
+const (
+	FileUploaded = "file.uploaded" // Файл загружен в File Storing Service
+	FileDeleted  = "file.deleted"  // Файл удален из File Storing Service
+)
+
+// Event — событие о файле.
+// Доставка выполняется по схеме «как минимум один раз», поэтому получатель должен
+// обрабатывать события идемпотентно, опираясь на ID.
+type Event struct {
+	ID         string          `json:"id" example:"5f1d7c3e-8a4b-4c1f-9d2e-7b6a5c4d3e2f"` // Уникальный ID события
+	Type       string          `json:"type" example:"file.uploaded"`                      // Тип события
+	FileID     string          `json:"file_id" example:"unique-file-id"`                  // ID файла, к которому относится событие
+	OccurredAt time.Time       `json:"occurred_at" swaggertype:"string" format:"date-time"`
+	Data       json.RawMessage `json:"data,omitempty" swaggertype:"object"` // Данные события, зависящие от типа
+}
+
+// FileUploadedData — данные события FileUploaded.
+type FileUploadedData struct {
+	Name         string `json:"name"`
+	Hash         string `json:"hash"`
+	MimeType     string `json:"mime_type"`
+	OwnerID      string `json:"owner_id,omitempty"`
+	DuplicateOf  string `json:"duplicate_of,omitempty"`
+	BatchID      string `json:"batch_id,omitempty"`      // ID пакета, если файл загружен пакетной загрузкой
+	CollectionID string `json:"collection_id,omitempty"` // ID коллекции, в которую добавлен файл при загрузке
+	Analyze      bool   `json:"analyze,omitempty"`       // Поставить файл в очередь анализа, даже если автоматический анализ выключен
+}
+
+// FileDeletedData — данные события FileDeleted.
+type FileDeletedData struct {
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+// ErrInvalidEvent возвращается для события без ID, типа или ID файла.
+var ErrInvalidEvent = errors.New("событие должно содержать id, type и file_id")
+
+// Validate проверяет, что у события заполнены обязательные поля.
+func (e Event) Validate() error {
+	if e.ID == "" || e.Type == "" || e.FileID == "" {
+		return ErrInvalidEvent
+	}
+	return nil
+}
+
+// Publisher доставляет события получателям.
+// Publish возвращает nil только после того, как событие принято получателем;
+// при ошибке отправитель должен повторить доставку.
+type Publisher interface {
+	Publish(ctx context.Context, event Event) error
+}
+
+// Handler обрабатывает полученное событие.
+type Handler func(ctx context.Context, event Event) error
@@ -0,0 +1,49 @@
+package events
+
+import (
+	"context"
+	"sync"
+)
+
+// InMemoryBroker доставляет события подписчикам внутри одного процесса.
+// Publish синхронно вызывает всех подписчиков и возвращает первую ошибку, поэтому отправитель
+// повторит доставку так же, как для вебхука. Брокер подходит для тестов и запуска сервисов в одном процессе.
+type InMemoryBroker struct {
+	mu        sync.RWMutex
+	handlers  []Handler
+	published []Event
+}
+
+// NewInMemoryBroker создает брокер без подписчиков.
+func NewInMemoryBroker() *InMemoryBroker {
+	return &InMemoryBroker{}
+}
+
+// Subscribe добавляет подписчика на все события.
+func (b *InMemoryBroker) Subscribe(handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers = append(b.handlers, handler)
+}
+
+// Publish передает событие всем подписчикам.
+func (b *InMemoryBroker) Publish(ctx context.Context, event Event) error {
+	b.mu.Lock()
+	b.published = append(b.published, event)
+	handlers := append([]Handler(nil), b.handlers...)
+	b.mu.Unlock()
+
+	for _, handler := range handlers {
+		if err := handler(ctx, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Published возвращает все события, переданные в Publish, включая неудачные попытки доставки.
+func (b *InMemoryBroker) Published() []Event {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return append([]Event(nil), b.published...)
+}
@@ -0,0 +1,53 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// WebhookPublisher доставляет события POST-запросом с JSON-телом события на заданный URL.
+// Событие считается принятым, если получатель ответил кодом 2xx.
+type WebhookPublisher struct {
+	URL    string
+	Client *http.Client
+}
+
+// NewWebhookPublisher создает WebhookPublisher с таймаутом запроса 30 секунд.
+func NewWebhookPublisher(url string) *WebhookPublisher {
+	return &WebhookPublisher{
+		URL:    url,
+		Client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Publish отправляет событие на URL вебхука.
+func (p *WebhookPublisher) Publish(ctx context.Context, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("ошибка при сериализации события %s: %w", event.ID, err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("ошибка при создании запроса для события %s: %w", event.ID, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Event-ID", event.ID)
+	req.Header.Set("X-Event-Type", event.Type)
+
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("ошибка при отправке события %s на %s: %w", event.ID, p.URL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("получатель вернул ошибку %d для события %s: %s", resp.StatusCode, event.ID, string(respBody))
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+// Package limits содержит общие для сервисов ограничения размера загружаемых файлов.
+package limits
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// DefaultMaxUploadSize — максимальный размер загружаемого файла по умолчанию.
+const DefaultMaxUploadSize = 100 << 20
+
+// DefaultMaxBatchSize — максимальный суммарный размер файлов пакетной загрузки по умолчанию.
+// Для ZIP-архива ограничивается и размер самого архива, и суммарный размер распакованных файлов.
+const DefaultMaxBatchSize = 500 << 20
+
+// DefaultMaxBatchEntries — максимальное количество файлов в пакетной загрузке по умолчанию, включая файлы внутри ZIP-архивов.
+const DefaultMaxBatchEntries = 200
+
+// MultipartOverhead — запас к максимальному размеру файла на заголовки и границы тела multipart/form-data.
+// Тело запроса загрузки ограничивается размером MaxUploadSize+MultipartOverhead, а точный размер файла проверяет File Storing Service.
+const MultipartOverhead = 64 << 10
+
+var sizeUnits = []struct {
+	suffix     string
+	multiplier int64
+}{
+	{"KB", 1 << 10},
+	{"MB", 1 << 20},
+	{"GB", 1 << 30},
+	{"TB", 1 << 40},
+	{"B", 1},
+}
+
+// ParseSize разбирает размер в байтах: число с необязательным суффиксом B, KB, MB, GB или TB
+// (двоичные единицы, регистр не важен), например «512», «10MB», «1.5GB».
+func ParseSize(value string) (int64, error) {
+	s := strings.ToUpper(strings.TrimSpace(value))
+	multiplier := int64(1)
+	for _, unit := range sizeUnits {
+		if strings.HasSuffix(s, unit.suffix) {
+			s = strings.TrimSpace(strings.TrimSuffix(s, unit.suffix))
+			multiplier = unit.multiplier
+			break
+		}
+	}
+	number, err := strconv.ParseFloat(s, 64)
+	size := number * float64(multiplier)
+	if err != nil || math.IsNaN(size) || size < 0 || size >= math.MaxInt64 {
+		return 0, fmt.Errorf("некорректный размер: %q", value)
+	}
+	return int64(size), nil
+}
@@ -0,0 +1,233 @@
+// Package pagination содержит курсорную (keyset) пагинацию списков для GORM-запросов.
+//
+// Записи упорядочиваются по полю сортировки и уникальному столбцу (ID), а курсор хранит значения этих столбцов
+// у последней записи страницы. Следующая страница выбирается условием «после курсора», поэтому ее получение
+// не замедляется с ростом номера страницы и не пропускает записи при вставке новых, как OFFSET.
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// DefaultLimit — количество записей на странице по умолчанию.
+	DefaultLimit = 50
+	// MaxLimit — наибольшее допустимое количество записей на странице.
+	MaxLimit = 500
+)
+
+// ErrInvalidCursor возвращается для курсора, который не удалось разобрать или который получен для другой сортировки.
+var ErrInvalidCursor = errors.New("некорректный курсор")
+
+// Field — столбец, по которому можно сортировать список.
+type Field struct {
+	Column string                    // Имя столбца в БД
+	Parse  func(string) (any, error) // Разбор значения столбца из курсора
+	Format func(value any) string    // Запись значения столбца в курсор
+}
+
+// TimeField возвращает поле сортировки по столбцу времени.
+func TimeField(column string) Field {
+	return Field{
+		Column: column,
+		Parse: func(value string) (any, error) {
+			return time.Parse(time.RFC3339Nano, value)
+		},
+		Format: func(value any) string {
+			return value.(time.Time).UTC().Format(time.RFC3339Nano)
+		},
+	}
+}
+
+// IntField возвращает поле сортировки по целочисленному столбцу.
+func IntField(column string) Field {
+	return Field{
+		Column: column,
+		Parse: func(value string) (any, error) {
+			return strconv.ParseInt(value, 10, 64)
+		},
+		Format: func(value any) string {
+			return fmt.Sprint(value)
+		},
+	}
+}
+
+// StringField возвращает поле сортировки по строковому столбцу.
+func StringField(column string) Field {
+	return Field{
+		Column: column,
+		Parse:  func(value string) (any, error) { return value, nil },
+		Format: func(value any) string { return value.(string) },
+	}
+}
+
+// Sorting описывает допустимые сортировки списка.
+type Sorting struct {
+	Fields      map[string]Field // Поля сортировки по значению параметра sort
+	ID          Field            // Уникальный столбец, упорядочивающий записи с равными значениями поля сортировки
+	Default     string           // Поле сортировки по умолчанию
+	DefaultDesc bool             // Сортировать по умолчанию по убыванию
+}
+
+// Names возвращает допустимые значения параметра sort по алфавиту.
+func (s Sorting) Names() []string {
+	names := make([]string, 0, len(s.Fields))
+	for name := range s.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// cursor — содержимое курсора: сортировка, для которой он получен, и ключ последней записи страницы.
+type cursor struct {
+	Sort  string `json:"s"`
+	Desc  bool   `json:"d"`
+	Value string `json:"v"`
+	ID    string `json:"id"`
+}
+
+// Request — параметры запроса страницы.
+type Request struct {
+	Limit   int
+	Sort    string
+	Desc    bool
+	field   Field
+	id      Field
+	after   *cursor
+	afterID any
+	afterV  any
+}
+
+// Parse разбирает параметры limit, sort, order (asc или desc) и cursor строки запроса.
+func (s Sorting) Parse(query url.Values) (Request, error) {
+	request := Request{Limit: DefaultLimit, Sort: s.Default, Desc: s.DefaultDesc, id: s.ID}
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit <= 0 || limit > MaxLimit {
+			return request, fmt.Errorf("параметр 'limit' должен быть числом от 1 до %d", MaxLimit)
+		}
+		request.Limit = limit
+	}
+	if value := query.Get("sort"); value != "" {
+		request.Sort = value
+	}
+	field, ok := s.Fields[request.Sort]
+	if !ok {
+		return request, fmt.Errorf("параметр 'sort' должен быть одним из: %s", strings.Join(s.Names(), ", "))
+	}
+	request.field = field
+	switch query.Get("order") {
+	case "":
+	case "asc":
+		request.Desc = false
+	case "desc":
+		request.Desc = true
+	default:
+		return request, errors.New("параметр 'order' должен быть asc или desc")
+	}
+
+	if value := query.Get("cursor"); value != "" {
+		after, err := decodeCursor(value)
+		if err != nil {
+			return request, err
+		}
+		// Курсор указывает на место в списке только при той же сортировке, при которой он получен
+		if after.Sort != request.Sort || after.Desc != request.Desc {
+			return request, fmt.Errorf("%w: курсор получен для другой сортировки", ErrInvalidCursor)
+		}
+		if request.afterV, err = field.Parse(after.Value); err != nil {
+			return request, ErrInvalidCursor
+		}
+		if request.afterID, err = s.ID.Parse(after.ID); err != nil {
+			return request, ErrInvalidCursor
+		}
+		request.after = &after
+	}
+	return request, nil
+}
+
+func decodeCursor(value string) (cursor, error) {
+	var c cursor
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+	if err := json.Unmarshal(data, &c); err != nil {
+		return c, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// Apply добавляет к запросу условие «после курсора», порядок сортировки и ограничение количества записей.
+// Выбирается на одну запись больше Limit, чтобы узнать, есть ли следующая страница (см. Next).
+func (r Request) Apply(db *gorm.DB) *gorm.DB {
+	direction, compare := "ASC", ">"
+	if r.Desc {
+		direction, compare = "DESC", "<"
+	}
+	if r.after != nil {
+		db = db.Where(fmt.Sprintf("(%[1]s %[3]s ? OR (%[1]s = ? AND %[2]s %[3]s ?))", r.field.Column, r.id.Column, compare),
+			r.afterV, r.afterV, r.afterID)
+	}
+	return db.Order(fmt.Sprintf("%s %s, %s %s", r.field.Column, direction, r.id.Column, direction)).Limit(r.Limit + 1)
+}
+
+// Next определяет, есть ли страница после полученных записей. count — количество записей, выбранных запросом Apply;
+// key возвращает значения поля сортировки и ID записи с индексом i. Если следующая страница есть, возвращаются
+// количество записей текущей страницы, курсор и относительная ссылка на следующую страницу с теми же параметрами
+// запроса query; иначе — count и пустые строки.
+func (r Request) Next(query url.Values, count int, key func(i int) (value, id any)) (pageSize int, nextCursor, next string) {
+	if count <= r.Limit {
+		return count, "", ""
+	}
+	value, id := key(r.Limit - 1)
+	data, _ := json.Marshal(cursor{Sort: r.Sort, Desc: r.Desc, Value: r.field.Format(value), ID: r.id.Format(id)})
+	nextCursor = base64.RawURLEncoding.EncodeToString(data)
+
+	nextQuery := url.Values{}
+	for name, values := range query {
+		nextQuery[name] = values
+	}
+	nextQuery.Set("cursor", nextCursor)
+	nextQuery.Set("limit", strconv.Itoa(r.Limit))
+	nextQuery.Set("sort", r.Sort)
+	if r.Desc {
+		nextQuery.Set("order", "desc")
+	} else {
+		nextQuery.Set("order", "asc")
+	}
+	// Ссылка, состоящая только из строки запроса, разрешается относительно пути текущего запроса,
+	// поэтому она верна и для пути сервиса, и для пути API Gateway
+	return r.Limit, nextCursor, "?" + nextQuery.Encode()
+}
+
+// TimeParam разбирает параметр name строки запроса в формате RFC 3339. Если параметр не задан, возвращается nil.
+func TimeParam(query url.Values, name string) (*time.Time, error) {
+	value := query.Get(name)
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, fmt.Errorf("параметр '%s' должен быть временем в формате RFC 3339, например 2024-01-31T12:00:00Z", name)
+	}
+	return &t, nil
+}
+
+// ContainsPattern возвращает шаблон LIKE, совпадающий со строками, содержащими substring.
+// Символы %, _ и \ в substring экранируются и совпадают только сами с собой.
+func ContainsPattern(substring string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(substring)
+	return "%" + escaped + "%"
+}
@@ -0,0 +1,234 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+var testSorting = Sorting{
+	Fields: map[string]Field{
+		"created_at": TimeField("created_at"),
+		"size":       IntField("size"),
+		"name":       StringField("name"),
+	},
+	ID:          StringField("id"),
+	Default:     "created_at",
+	DefaultDesc: true,
+}
+
+// nextQuery возвращает параметры ссылки на следующую страницу, полученной от Next.
+func nextQuery(t *testing.T, next string) url.Values {
+	t.Helper()
+	query, err := url.ParseQuery(next[1:])
+	if err != nil {
+		t.Fatalf("некорректная ссылка на следующую страницу %q: %v", next, err)
+	}
+	return query
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	createdAt := time.Date(2024, 1, 31, 12, 0, 0, 123456789, time.FixedZone("MSK", 3*60*60))
+	tests := []struct {
+		sort  string
+		value any
+		want  any
+	}{
+		{"created_at", createdAt, createdAt.UTC()},
+		{"size", int64(1024), int64(1024)},
+		{"name", "отчет, версия 2?.txt", "отчет, версия 2?.txt"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.sort, func(t *testing.T) {
+			request, err := testSorting.Parse(url.Values{"sort": {tt.sort}, "order": {"asc"}, "limit": {"2"}, "owner_id": {"user-1"}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			pageSize, cursor, next := request.Next(url.Values{"owner_id": {"user-1"}}, 3, func(i int) (any, any) {
+				if i != 1 {
+					t.Fatalf("курсор построен по записи %d, ожидалась последняя запись страницы", i)
+				}
+				return tt.value, "file-2"
+			})
+			if pageSize != 2 || cursor == "" {
+				t.Fatalf("pageSize=%d, cursor=%q", pageSize, cursor)
+			}
+
+			query := nextQuery(t, next)
+			if query.Get("cursor") != cursor || query.Get("owner_id") != "user-1" || query.Get("sort") != tt.sort || query.Get("order") != "asc" {
+				t.Fatalf("ссылка на следующую страницу не сохраняет параметры запроса: %q", next)
+			}
+			parsed, err := testSorting.Parse(query)
+			if err != nil {
+				t.Fatalf("курсор из ссылки не разобран: %v", err)
+			}
+			if parsed.afterV != tt.want || parsed.afterID != "file-2" {
+				t.Fatalf("из курсора получен ключ (%v, %v), ожидался (%v, file-2)", parsed.afterV, parsed.afterID, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextOnLastPage(t *testing.T) {
+	request, err := testSorting.Parse(url.Values{"limit": {"2"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pageSize, cursor, next := request.Next(url.Values{}, 2, func(int) (any, any) {
+		t.Fatal("на последней странице курсор не строится")
+		return nil, nil
+	})
+	if pageSize != 2 || cursor != "" || next != "" {
+		t.Fatalf("pageSize=%d, cursor=%q, next=%q", pageSize, cursor, next)
+	}
+}
+
+func TestParseRejectsInvalidCursor(t *testing.T) {
+	encode := func(data string) string { return base64.RawURLEncoding.EncodeToString([]byte(data)) }
+	valid := encode(`{"s":"size","d":false,"v":"10","id":"file-1"}`)
+	tests := []struct {
+		name   string
+		query  url.Values
+		reason string // Ожидаемая часть сообщения об ошибке, кроме ErrInvalidCursor
+	}{
+		{name: "не base64", query: url.Values{"sort": {"size"}, "order": {"asc"}, "cursor": {"курсор"}}},
+		{name: "стандартный base64 с дополнением", query: url.Values{"sort": {"size"}, "order": {"asc"}, "cursor": {valid + "=="}}},
+		{name: "не JSON", query: url.Values{"sort": {"size"}, "order": {"asc"}, "cursor": {encode("size:10:file-1")}}},
+		{name: "обрезанный курсор", query: url.Values{"sort": {"size"}, "order": {"asc"}, "cursor": {valid[:len(valid)-4]}}},
+		{name: "другое поле сортировки", query: url.Values{"sort": {"name"}, "order": {"asc"}, "cursor": {valid}}, reason: "другой сортировки"},
+		{name: "другой порядок", query: url.Values{"sort": {"size"}, "order": {"desc"}, "cursor": {valid}}, reason: "другой сортировки"},
+		{name: "подмененное значение", query: url.Values{"sort": {"size"}, "order": {"asc"},
+			"cursor": {encode(`{"s":"size","d":false,"v":"10 OR 1=1","id":"file-1"}`)}}},
+		{name: "подмененное время", query: url.Values{"sort": {"created_at"}, "order": {"asc"},
+			"cursor": {encode(`{"s":"created_at","d":false,"v":"вчера","id":"file-1"}`)}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := testSorting.Parse(tt.query)
+			if !errors.Is(err, ErrInvalidCursor) {
+				t.Fatalf("ожидалась ошибка ErrInvalidCursor, получено %v", err)
+			}
+			if tt.reason != "" && !strings.Contains(err.Error(), tt.reason) {
+				t.Fatalf("сообщение %q не содержит %q", err, tt.reason)
+			}
+		})
+	}
+}
+
+func TestParseValidatesParameters(t *testing.T) {
+	for _, query := range []url.Values{
+		{"limit": {"0"}},
+		{"limit": {fmt.Sprint(MaxLimit + 1)}},
+		{"limit": {"десять"}},
+		{"sort": {"owner_id"}},
+		{"order": {"up"}},
+	} {
+		if _, err := testSorting.Parse(query); err == nil {
+			t.Errorf("параметры %v приняты", query)
+		}
+	}
+
+	request, err := testSorting.Parse(url.Values{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if request.Limit != DefaultLimit || request.Sort != "created_at" || !request.Desc {
+		t.Fatalf("параметры по умолчанию: %+v", request)
+	}
+}
+
+// item — запись тестового списка; у многих записей одинаковый размер.
+type item struct {
+	ID   string
+	Size int64
+}
+
+func TestPagesBreakTiesByID(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("не удалось открыть SQLite: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	t.Cleanup(func() { sqlDB.Close() })
+	if err := db.AutoMigrate(&item{}); err != nil {
+		t.Fatal(err)
+	}
+	var items []item
+	for i := 0; i < 23; i++ {
+		items = append(items, item{ID: fmt.Sprintf("file-%02d", (i*7)%23), Size: int64(i % 3)})
+	}
+	if err := db.Create(&items).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	for _, order := range []string{"asc", "desc"} {
+		t.Run(order, func(t *testing.T) {
+			want := append([]item(nil), items...)
+			sort.Slice(want, func(i, j int) bool {
+				if want[i].Size != want[j].Size {
+					return want[i].Size < want[j].Size
+				}
+				return want[i].ID < want[j].ID
+			})
+			if order == "desc" {
+				for i, j := 0, len(want)-1; i < j; i, j = i+1, j-1 {
+					want[i], want[j] = want[j], want[i]
+				}
+			}
+
+			var got []item
+			query := url.Values{"sort": {"size"}, "order": {order}, "limit": {"4"}}
+			for pages := 0; ; pages++ {
+				if pages > len(items) {
+					t.Fatal("постраничный обход не завершился")
+				}
+				request, err := testSorting.Parse(query)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var page []item
+				if err := request.Apply(db.Model(&item{})).Find(&page).Error; err != nil {
+					t.Fatal(err)
+				}
+				pageSize, _, next := request.Next(query, len(page), func(i int) (any, any) { return page[i].Size, page[i].ID })
+				got = append(got, page[:pageSize]...)
+				if next == "" {
+					break
+				}
+				query = nextQuery(t, next)
+			}
+
+			if len(got) != len(want) {
+				t.Fatalf("получено %d записей, ожидалось %d: %v", len(got), len(want), got)
+			}
+			for i := range want {
+				if got[i] != want[i] {
+					t.Fatalf("запись %d: %v, ожидалась %v\nполучено: %v", i, got[i], want[i], got)
+				}
+			}
+		})
+	}
+}
+
+func TestContainsPattern(t *testing.T) {
+	tests := map[string]string{
+		"отчет":    "%отчет%",
+		"100%":     `%100\%%`,
+		"file_1":   `%file\_1%`,
+		`C:\files`: `%C:\\files%`,
+	}
+	for substring, want := range tests {
+		if got := ContainsPattern(substring); got != want {
+			t.Errorf("ContainsPattern(%q) = %q, ожидалось %q", substring, got, want)
+		}
+	}
+}
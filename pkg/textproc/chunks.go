@@ -0,0 +1,69 @@
+package textproc
+
+import (
+	"errors"
+	"io"
+	"unicode"
+	"unicode/utf8"
+)
+
+// DefaultChunkSize — размер фрагмента текста по умолчанию для ReadChunks.
+const DefaultChunkSize = 64 << 10
+
+// ReadChunks читает текст из r и по порядку передает fn фрагменты размером примерно size байт,
+// так что текст любого размера обрабатывается в ограниченной памяти.
+// Каждый фрагмент, кроме последнего, заканчивается пробельным символом, поэтому слова и числа (см. Tokenize)
+// не разрезаются между фрагментами. Только фрагмент без пробельных символов длиннее size режется по границе символа UTF-8.
+// Ошибка fn прерывает чтение и возвращается без изменений.
+func ReadChunks(r io.Reader, size int, fn func(chunk string) error) error {
+	if size < utf8.UTFMax {
+		size = DefaultChunkSize
+	}
+	buf := make([]byte, 0, size)
+	for {
+		n, err := io.ReadFull(r, buf[len(buf):size])
+		buf = buf[:len(buf)+n]
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			if len(buf) == 0 {
+				return nil
+			}
+			return fn(string(buf))
+		}
+		if err != nil {
+			return err
+		}
+
+		cut := lastSpaceEnd(buf)
+		if cut == 0 {
+			cut = lastRuneBoundary(buf)
+		}
+		if err := fn(string(buf[:cut])); err != nil {
+			return err
+		}
+		buf = buf[:copy(buf, buf[cut:])]
+	}
+}
+
+// lastSpaceEnd возвращает позицию сразу после последнего пробельного символа в b или 0, если его нет.
+func lastSpaceEnd(b []byte) int {
+	for end := len(b); end > 0; {
+		r, size := utf8.DecodeLastRune(b[:end])
+		if unicode.IsSpace(r) {
+			return end
+		}
+		end -= size
+	}
+	return 0
+}
+
+// lastRuneBoundary возвращает позицию начала последнего неполного символа UTF-8 в b или len(b), если такого нет.
+func lastRuneBoundary(b []byte) int {
+	start := len(b) - 1
+	for start > 0 && len(b)-start < utf8.UTFMax && !utf8.RuneStart(b[start]) {
+		start--
+	}
+	if start > 0 && !utf8.FullRune(b[start:]) {
+		return start
+	}
+	return len(b)
+}
@@ -0,0 +1,249 @@
+package textproc
+
+import (
+	"embed"
+	"sort"
+	"unicode"
+)
+
+// Language — код языка по ISO 639-1.
+type Language string
+
+const (
+	LanguageRussian   Language = "ru"
+	LanguageEnglish   Language = "en"
+	LanguageUkrainian Language = "uk"
+	LanguageGerman    Language = "de"
+	LanguageFrench    Language = "fr"
+	LanguageSpanish   Language = "es"
+	LanguageUnknown   Language = "und" // Язык не определен (ISO 639-2)
+)
+
+const (
+	profileSize         = 300  // Количество самых частых n-грамм в профиле
+	maxNGram            = 3    // Максимальная длина n-граммы
+	minDetectionLetters = 20   // Минимальное количество букв для определения языка
+	maxDistanceRatio    = 0.85 // Порог относительного расстояния, выше которого язык считается неопределенным
+	minDistanceMargin   = 0.05 // Насколько (относительно) расстояние до другого языка должно быть больше, чтобы выбор был уверенным
+	maxMixedScriptShare = 0.25 // Доля букв других алфавитов, выше которой текст считается смешанным
+)
+
+// DetectionSampleRunes — сколько первых символов текста учитывает DetectLanguage: для определения языка
+// достаточно начала текста, поэтому при потоковой обработке достаточно передать только его.
+const DetectionSampleRunes = 10000
+
+// Образцы текстов, по которым при запуске строятся n-граммные профили языков.
+//
+//go:embed langdata/*.txt
+var languageSamples embed.FS
+
+// supportedLanguages — языки, для которых есть образцы текста, в порядке сравнения.
+var supportedLanguages = []Language{LanguageRussian, LanguageEnglish, LanguageUkrainian, LanguageGerman, LanguageFrench, LanguageSpanish}
+
+// languageProfile — n-граммный профиль языка, построенный по образцу текста.
+type languageProfile struct {
+	ranks    map[string]int // Ранги n-грамм
+	script   string         // Алфавит образца
+	distinct map[rune]bool  // Буквы образца, которых нет в образцах других языков с тем же алфавитом, например «і» и «ы»
+}
+
+// languageProfiles — профили поддерживаемых языков.
+var languageProfiles = func() map[Language]*languageProfile {
+	profiles := make(map[Language]*languageProfile)
+	letters := make(map[Language]map[rune]bool)
+	for _, lang := range supportedLanguages {
+		sample, err := languageSamples.ReadFile("langdata/" + string(lang) + ".txt")
+		if err != nil {
+			panic("textproc: нет образца текста для языка " + string(lang))
+		}
+		script, _ := dominantScript(string(sample))
+		profiles[lang] = &languageProfile{ranks: ranks(buildProfile(string(sample))), script: script, distinct: make(map[rune]bool)}
+		letters[lang] = lowerLetters(string(sample))
+	}
+	for _, lang := range supportedLanguages {
+		for r := range letters[lang] {
+			distinct := true
+			for _, other := range supportedLanguages {
+				if other != lang && profiles[other].script == profiles[lang].script && letters[other][r] {
+					distinct = false
+					break
+				}
+			}
+			if distinct {
+				profiles[lang].distinct[r] = true
+			}
+		}
+	}
+	return profiles
+}()
+
+// DetectLanguage определяет язык текста методом Кавнара — Тренкла: профиль самых частых
+// n-грамм (от 1 до 3 символов) текста сравнивается с профилями языков, построенными по
+// встроенным образцам, и выбирается язык с наименьшим расстоянием между рангами n-грамм.
+// Сравниваются только языки с алфавитом, которым написана большая часть текста.
+// Если расстояния до нескольких языков почти равны (близкие языки, например русский и украинский),
+// выбирается тот из них, характерные буквы которого есть в тексте.
+// Для слишком коротких и смешанных текстов, текстов на неподдерживаемых языках и текстов,
+// язык которых нельзя выбрать уверенно, возвращается LanguageUnknown.
+func DetectLanguage(text string) Language {
+	if runes := []rune(text); len(runes) > DetectionSampleRunes {
+		text = string(runes[:DetectionSampleRunes])
+	}
+	profile := buildProfile(text)
+	if len(profile) == 0 {
+		return LanguageUnknown
+	}
+	letters := 0
+	for _, r := range text {
+		if classify(r) == wbLetter {
+			letters++
+		}
+	}
+	if letters < minDetectionLetters {
+		return LanguageUnknown
+	}
+	script, scriptLetters := dominantScript(text)
+	if float64(scriptLetters) < (1-maxMixedScriptShare)*float64(letters) {
+		return LanguageUnknown
+	}
+
+	distances := make(map[Language]int)
+	best, bestDistance := LanguageUnknown, -1
+	for _, lang := range supportedLanguages {
+		langProfile := languageProfiles[lang]
+		if langProfile.script != script {
+			continue
+		}
+		distance := 0
+		for rank, gram := range profile {
+			if langRank, ok := langProfile.ranks[gram]; ok {
+				distance += abs(rank - langRank)
+			} else {
+				distance += profileSize
+			}
+		}
+		distances[lang] = distance
+		if bestDistance < 0 || distance < bestDistance {
+			best, bestDistance = lang, distance
+		}
+	}
+	if bestDistance < 0 || float64(bestDistance) > maxDistanceRatio*float64(len(profile)*profileSize) {
+		return LanguageUnknown
+	}
+
+	// Языки, расстояние до которых почти не отличается от лучшего, различаются по характерным буквам
+	var close []Language
+	for _, lang := range supportedLanguages {
+		if distance, ok := distances[lang]; ok && float64(distance) <= (1+minDistanceMargin)*float64(bestDistance) {
+			close = append(close, lang)
+		}
+	}
+	if len(close) == 1 {
+		return best
+	}
+	textLetters := lowerLetters(text)
+	detected := LanguageUnknown
+	for _, lang := range close {
+		for r := range languageProfiles[lang].distinct {
+			if textLetters[r] {
+				if detected != LanguageUnknown {
+					return LanguageUnknown // Характерные буквы нескольких языков
+				}
+				detected = lang
+				break
+			}
+		}
+	}
+	return detected
+}
+
+// dominantScript возвращает алфавит (название письменности Unicode), к которому относится больше всего букв text,
+// и количество этих букв.
+func dominantScript(text string) (string, int) {
+	counts := make(map[string]int)
+	for _, r := range text {
+		if classify(r) == wbLetter {
+			counts[scriptOf(r)]++
+		}
+	}
+	best, bestCount := "", 0
+	for script, count := range counts {
+		if count > bestCount || (count == bestCount && script < best) {
+			best, bestCount = script, count
+		}
+	}
+	return best, bestCount
+}
+
+// detectionScripts — алфавиты поддерживаемых языков; буквы остальных письменностей относятся к алфавиту "".
+var detectionScripts = []string{"Latin", "Cyrillic"}
+
+func scriptOf(r rune) string {
+	for _, script := range detectionScripts {
+		if unicode.Is(unicode.Scripts[script], r) {
+			return script
+		}
+	}
+	return ""
+}
+
+// lowerLetters возвращает множество букв text в нижнем регистре.
+func lowerLetters(text string) map[rune]bool {
+	letters := make(map[rune]bool)
+	for _, r := range text {
+		if unicode.IsLetter(r) {
+			letters[unicode.ToLower(r)] = true
+		}
+	}
+	return letters
+}
+
+// buildProfile возвращает до profileSize самых частых n-грамм слов текста в порядке убывания частоты.
+// Слова дополняются по краям символом «_», чтобы n-граммы учитывали начала и окончания слов.
+func buildProfile(text string) []string {
+	counts := make(map[string]int)
+	for _, token := range Tokenize(text) {
+		if token.Kind != TokenWord {
+			continue
+		}
+		runes := []rune("_" + token.Text + "_")
+		for n := 1; n <= maxNGram; n++ {
+			for i := 0; i+n <= len(runes); i++ {
+				gram := string(runes[i : i+n])
+				if gram != "_" {
+					counts[gram]++
+				}
+			}
+		}
+	}
+
+	grams := make([]string, 0, len(counts))
+	for gram := range counts {
+		grams = append(grams, gram)
+	}
+	sort.Slice(grams, func(i, j int) bool {
+		if counts[grams[i]] != counts[grams[j]] {
+			return counts[grams[i]] > counts[grams[j]]
+		}
+		return grams[i] < grams[j]
+	})
+	if len(grams) > profileSize {
+		grams = grams[:profileSize]
+	}
+	return grams
+}
+
+func ranks(profile []string) map[string]int {
+	result := make(map[string]int, len(profile))
+	for rank, gram := range profile {
+		result[gram] = rank
+	}
+	return result
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,63 @@
+package textproc
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDetectLanguage(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want Language
+	}{
+		{"русский", "Мы договорились встретиться завтра утром у входа в библиотеку.", LanguageRussian},
+		{"русский короткий", "Как дела у тебя сегодня, мой дорогой друг?", LanguageRussian},
+		{"русский с английским термином", "Для сборки проекта используется Docker, а конфигурация хранится в переменных окружения сервиса.", LanguageRussian},
+		{"украинский", "Це український текст, який має бути визначений правильно моделлю мови.", LanguageUkrainian},
+		{"украинский короткий", "Ми домовилися зустрітися завтра вранці біля входу до бібліотеки.", LanguageUkrainian},
+		{"английский", "This is an English text that should be detected correctly by the language model.", LanguageEnglish},
+		{"английский короткий", "I went to the store yesterday and bought some milk and bread.", LanguageEnglish},
+		{"немецкий", "Der schnelle braune Fuchs springt über den faulen Hund.", LanguageGerman},
+		{"французский", "Le renard brun rapide saute par-dessus le chien paresseux.", LanguageFrench},
+		{"испанский", "El rápido zorro marrón salta sobre el perro perezoso.", LanguageSpanish},
+		{"длинный текст", strings.Repeat("Длинный текст определяется по первым символам. ", 1000) + strings.Repeat("English tail. ", 1000), LanguageRussian},
+		{"смешанный английский и русский", "Hello world, привет мир, how are you сегодня?", LanguageUnknown},
+		{"смешанный короткий", "Привет, как дела? Hello, how are you?", LanguageUnknown},
+		{"слишком короткий", "Привет, мир", LanguageUnknown},
+		{"без букв", "12345 67890 !!! 3,14 2024-01-31", LanguageUnknown},
+		{"пустой", "", LanguageUnknown},
+		{"неподдерживаемый алфавит", "Καλημέρα σας, τι κάνετε σήμερα το πρωί;", LanguageUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DetectLanguage(tt.text); got != tt.want {
+				t.Fatalf("DetectLanguage(%.60q) = %s, ожидалось %s", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLanguageProfilesDistinctLetters(t *testing.T) {
+	// Русский и украинский различаются по буквам, которых нет в образце другого языка
+	for _, tt := range []struct {
+		lang    Language
+		letters string
+	}{
+		{LanguageRussian, "ыэъ"},
+		{LanguageUkrainian, "іїє"},
+	} {
+		profile := languageProfiles[tt.lang]
+		if profile.script != "Cyrillic" {
+			t.Errorf("%s: алфавит %q, ожидался Cyrillic", tt.lang, profile.script)
+		}
+		for _, r := range tt.letters {
+			if !profile.distinct[r] {
+				t.Errorf("%s: буква %q не считается характерной", tt.lang, r)
+			}
+		}
+	}
+	if profile := languageProfiles[LanguageEnglish]; profile.script != "Latin" {
+		t.Errorf("en: алфавит %q, ожидался Latin", profile.script)
+	}
+}
@@ -0,0 +1,332 @@
+package textproc
+
+import "strings"
+
+// Stem возвращает основу слова (в нижнем регистре) по алгоритмам Snowball для русского и английского языков.
+// Основа используется для объединения словоформ («книга», «книги», «книгой»; «study», «studies», «studied»)
+// и сама по себе не обязана быть словом. Для остальных языков слово возвращается без изменений.
+func Stem(lang Language, word string) string {
+	switch lang {
+	case LanguageRussian:
+		return stemRussian(word)
+	case LanguageEnglish:
+		return stemEnglish(word)
+	}
+	return word
+}
+
+// longestSuffix возвращает самое длинное из окончаний suffixes, которым заканчивается слово, или "".
+func longestSuffix(word []rune, suffixes []string) string {
+	best := ""
+	for _, suffix := range suffixes {
+		if len(suffix) > len(best) && hasSuffix(word, suffix) {
+			best = suffix
+		}
+	}
+	return best
+}
+
+func hasSuffix(word []rune, suffix string) bool {
+	s := []rune(suffix)
+	if len(s) > len(word) {
+		return false
+	}
+	return string(word[len(word)-len(s):]) == suffix
+}
+
+func runeLen(s string) int {
+	return len([]rune(s))
+}
+
+// Русский стеммер Snowball: https://snowballstem.org/algorithms/russian/stemmer.html
+
+var (
+	ruPerfectiveGerund1 = []string{"в", "вши", "вшись"} // После «а» или «я»
+	ruPerfectiveGerund2 = []string{"ив", "ивши", "ившись", "ыв", "ывши", "ывшись"}
+	ruAdjective         = []string{"ее", "ие", "ые", "ое", "ими", "ыми", "ей", "ий", "ый", "ой", "ем", "им", "ым", "ом", "его", "ого", "ему", "ому", "их", "ых", "ую", "юю", "ая", "яя", "ою", "ею"}
+	ruParticiple1       = []string{"ем", "нн", "вш", "ющ", "щ"} // После «а» или «я»
+	ruParticiple2       = []string{"ивш", "ывш", "ующ"}
+	ruReflexive         = []string{"ся", "сь"}
+	ruVerb1             = []string{"ла", "на", "ете", "йте", "ли", "й", "л", "ем", "н", "ло", "но", "ет", "ют", "ны", "ть", "ешь", "нно"} // После «а» или «я»
+	ruVerb2             = []string{"ила", "ыла", "ена", "ейте", "уйте", "ите", "или", "ыли", "ей", "уй", "ил", "ыл", "им", "ым", "ен", "ило", "ыло", "ено", "ят", "ует", "уют", "ит", "ыт", "ены", "ить", "ыть", "ишь", "ую", "ю"}
+	ruNoun              = []string{"а", "ев", "ов", "ие", "ье", "е", "иями", "ями", "ами", "еи", "ии", "и", "ией", "ей", "ой", "ий", "й", "иям", "ям", "ием", "ем", "ам", "ом", "о", "у", "ах", "иях", "ях", "ы", "ь", "ию", "ью", "ю", "ия", "ья", "я"}
+	ruSuperlative       = []string{"ейше", "ейш"}
+	ruDerivational      = []string{"ость", "ост"}
+)
+
+func isRussianVowel(r rune) bool {
+	return strings.ContainsRune("аеиоуыэюя", r)
+}
+
+func stemRussian(word string) string {
+	w := []rune(word)
+
+	// RV — часть слова после первой гласной, R2 — область R2 по определению Snowball
+	rv := len(w)
+	for i, r := range w {
+		if isRussianVowel(r) {
+			rv = i + 1
+			break
+		}
+	}
+	r1 := regionAfterVowelConsonant(w, 0, isRussianVowel)
+	r2 := regionAfterVowelConsonant(w, r1, isRussianVowel)
+	if rv >= len(w) {
+		return word
+	}
+	prefix, rest := w[:rv], w[rv:]
+
+	// removeGrouped удаляет самое длинное окончание из группы: окончания первой группы должны следовать за «а» или «я»
+	removeGrouped := func(group1, group2 []string) bool {
+		s1, s2 := longestSuffix(rest, group1), longestSuffix(rest, group2)
+		if runeLen(s2) >= runeLen(s1) && s2 != "" {
+			rest = rest[:len(rest)-runeLen(s2)]
+			return true
+		}
+		if s1 != "" {
+			n := len(rest) - runeLen(s1)
+			if n > 0 && (rest[n-1] == 'а' || rest[n-1] == 'я') {
+				rest = rest[:n]
+				return true
+			}
+		}
+		return false
+	}
+	removeSuffix := func(suffixes []string) bool {
+		if s := longestSuffix(rest, suffixes); s != "" {
+			rest = rest[:len(rest)-runeLen(s)]
+			return true
+		}
+		return false
+	}
+
+	// Шаг 1
+	if !removeGrouped(ruPerfectiveGerund1, ruPerfectiveGerund2) {
+		removeSuffix(ruReflexive)
+		if removeSuffix(ruAdjective) {
+			removeGrouped(ruParticiple1, ruParticiple2)
+		} else if !removeGrouped(ruVerb1, ruVerb2) {
+			removeSuffix(ruNoun)
+		}
+	}
+
+	// Шаг 2
+	if len(rest) > 0 && rest[len(rest)-1] == 'и' {
+		rest = rest[:len(rest)-1]
+	}
+
+	// Шаг 3: словообразовательное окончание удаляется, только если оно целиком лежит в R2
+	if s := longestSuffix(rest, ruDerivational); s != "" && rv+len(rest)-runeLen(s) >= r2 {
+		rest = rest[:len(rest)-runeLen(s)]
+	}
+
+	// Шаг 4
+	switch {
+	case removeSuffix(ruSuperlative):
+		if hasSuffix(rest, "нн") {
+			rest = rest[:len(rest)-1]
+		}
+	case hasSuffix(rest, "нн"):
+		rest = rest[:len(rest)-1]
+	case hasSuffix(rest, "ь"):
+		rest = rest[:len(rest)-1]
+	}
+
+	return string(prefix) + string(rest)
+}
+
+// regionAfterVowelConsonant возвращает начало области после первой согласной, следующей за гласной,
+// начиная поиск с позиции from (определение R1 и R2 в алгоритмах Snowball).
+func regionAfterVowelConsonant(w []rune, from int, isVowel func(rune) bool) int {
+	for i := from + 1; i < len(w); i++ {
+		if !isVowel(w[i]) && isVowel(w[i-1]) {
+			return i + 1
+		}
+	}
+	return len(w)
+}
+
+// Английский стеммер Snowball (Porter2): https://snowballstem.org/algorithms/english/stemmer.html
+
+var enExceptions = map[string]string{
+	"skis": "ski", "skies": "sky", "dying": "die", "lying": "lie", "tying": "tie",
+	"idly": "idl", "gently": "gentl", "ugly": "ugli", "early": "earli", "only": "onli", "singly": "singl",
+	"sky": "sky", "news": "news", "howe": "howe", "atlas": "atlas", "cosmos": "cosmos", "bias": "bias", "andes": "andes",
+}
+
+var enExceptionsAfterStep1a = map[string]bool{
+	"inning": true, "outing": true, "canning": true, "herring": true,
+	"earring": true, "proceed": true, "exceed": true, "succeed": true,
+}
+
+var (
+	enStep2 = map[string]string{
+		"tional": "tion", "enci": "ence", "anci": "ance", "abli": "able", "entli": "ent",
+		"izer": "ize", "ization": "ize", "ational": "ate", "ation": "ate", "ator": "ate",
+		"alism": "al", "aliti": "al", "alli": "al", "fulness": "ful", "ousli": "ous", "ousness": "ous",
+		"iveness": "ive", "iviti": "ive", "biliti": "ble", "bli": "ble", "ogi": "og", "fulli": "ful",
+		"lessli": "less", "li": "",
+	}
+	enStep3 = map[string]string{
+		"tional": "tion", "ational": "ate", "alize": "al", "icate": "ic", "iciti": "ic",
+		"ical": "ic", "ful": "", "ness": "", "ative": "",
+	}
+	enStep4 = []string{"al", "ance", "ence", "er", "ic", "able", "ible", "ant", "ement", "ment", "ent", "ism", "ate", "iti", "ous", "ive", "ize", "ion"}
+)
+
+func isEnglishVowel(r rune) bool {
+	return r == 'a' || r == 'e' || r == 'i' || r == 'o' || r == 'u' || r == 'y'
+}
+
+func keys(m map[string]string) []string {
+	result := make([]string, 0, len(m))
+	for k := range m {
+		result = append(result, k)
+	}
+	return result
+}
+
+func stemEnglish(word string) string {
+	if len([]rune(word)) <= 2 {
+		return word
+	}
+	word = strings.TrimPrefix(word, "'")
+	if stem, ok := enExceptions[word]; ok {
+		return stem
+	}
+
+	w := []rune(word)
+	// «y» в начале слова и после гласной считается согласной и помечается как «Y»
+	for i, r := range w {
+		if r == 'y' && (i == 0 || isEnglishVowel(w[i-1])) {
+			w[i] = 'Y'
+		}
+	}
+
+	r1 := regionAfterVowelConsonant(w, 0, isEnglishVowel)
+	for _, prefix := range []string{"gener", "commun", "arsen"} {
+		if strings.HasPrefix(word, prefix) {
+			r1 = runeLen(prefix)
+		}
+	}
+	r2 := regionAfterVowelConsonant(w, r1, isEnglishVowel)
+
+	inR1 := func(suffix string) bool { return len(w)-runeLen(suffix) >= r1 }
+	inR2 := func(suffix string) bool { return len(w)-runeLen(suffix) >= r2 }
+	replace := func(suffix, with string) { w = append(w[:len(w)-runeLen(suffix)], []rune(with)...) }
+	containsVowel := func(s []rune) bool {
+		for _, r := range s {
+			if isEnglishVowel(r) {
+				return true
+			}
+		}
+		return false
+	}
+
+	// Шаг 0: притяжательные окончания
+	if s := longestSuffix(w, []string{"'s'", "'s", "'"}); s != "" {
+		replace(s, "")
+	}
+
+	// Шаг 1a: множественное число
+	switch s := longestSuffix(w, []string{"sses", "ied", "ies", "us", "ss", "s"}); s {
+	case "sses":
+		replace(s, "ss")
+	case "ied", "ies":
+		if len(w) > 4 {
+			replace(s, "i")
+		} else {
+			replace(s, "ie")
+		}
+	case "s":
+		if len(w) >= 2 && containsVowel(w[:len(w)-2]) {
+			replace(s, "")
+		}
+	}
+	if enExceptionsAfterStep1a[string(w)] {
+		return string(w)
+	}
+
+	// Шаг 1b: окончания прошедшего времени и причастий
+	switch s := longestSuffix(w, []string{"eed", "eedly", "ed", "edly", "ing", "ingly"}); s {
+	case "eed", "eedly":
+		if inR1(s) {
+			replace(s, "ee")
+		}
+	case "ed", "edly", "ing", "ingly":
+		if containsVowel(w[:len(w)-runeLen(s)]) {
+			replace(s, "")
+			switch {
+			case hasSuffix(w, "at") || hasSuffix(w, "bl") || hasSuffix(w, "iz"):
+				w = append(w, 'e')
+			case longestSuffix(w, []string{"bb", "dd", "ff", "gg", "mm", "nn", "pp", "rr", "tt"}) != "":
+				w = w[:len(w)-1]
+			case isShortEnglishWord(w, r1):
+				w = append(w, 'e')
+			}
+		}
+	}
+
+	// Шаг 1c: конечная «y» после согласной заменяется на «i»
+	if n := len(w); n > 2 && (w[n-1] == 'y' || w[n-1] == 'Y') && !isEnglishVowel(w[n-2]) {
+		w[n-1] = 'i'
+	}
+
+	// Шаг 2
+	if s := longestSuffix(w, keys(enStep2)); s != "" && inR1(s) {
+		switch s {
+		case "ogi":
+			if len(w) > 3 && w[len(w)-4] == 'l' {
+				replace(s, "og")
+			}
+		case "li":
+			if len(w) > 2 && strings.ContainsRune("cdeghkmnrt", w[len(w)-3]) {
+				replace(s, "")
+			}
+		default:
+			replace(s, enStep2[s])
+		}
+	}
+
+	// Шаг 3
+	if s := longestSuffix(w, keys(enStep3)); s != "" && inR1(s) {
+		if s != "ative" || inR2(s) {
+			replace(s, enStep3[s])
+		}
+	}
+
+	// Шаг 4
+	if s := longestSuffix(w, enStep4); s != "" && inR2(s) {
+		if s != "ion" || (len(w) > 3 && (w[len(w)-4] == 's' || w[len(w)-4] == 't')) {
+			replace(s, "")
+		}
+	}
+
+	// Шаг 5
+	if n := len(w); n > 0 {
+		switch {
+		case w[n-1] == 'e' && (inR2("e") || (inR1("e") && !endsWithShortSyllable(w[:n-1]))):
+			w = w[:n-1]
+		case w[n-1] == 'l' && inR2("l") && n > 1 && w[n-2] == 'l':
+			w = w[:n-1]
+		}
+	}
+
+	return strings.ReplaceAll(string(w), "Y", "y")
+}
+
+// endsWithShortSyllable проверяет, заканчивается ли слово коротким слогом: согласная, гласная и согласная,
+// отличная от «w», «x» и «Y», либо гласная в начале слова и согласная за ней.
+func endsWithShortSyllable(w []rune) bool {
+	n := len(w)
+	if n == 2 {
+		return isEnglishVowel(w[0]) && !isEnglishVowel(w[1])
+	}
+	return n >= 3 && !isEnglishVowel(w[n-3]) && isEnglishVowel(w[n-2]) &&
+		!isEnglishVowel(w[n-1]) && w[n-1] != 'w' && w[n-1] != 'x' && w[n-1] != 'Y'
+}
+
+// isShortEnglishWord проверяет, является ли слово коротким: оно заканчивается коротким слогом, а регион R1 пуст.
+func isShortEnglishWord(w []rune, r1 int) bool {
+	return r1 >= len(w) && endsWithShortSyllable(w)
+}
@@ -0,0 +1,102 @@
+package textproc
+
+import "testing"
+
+// Ожидаемые основы совпадают с результатами эталонных стеммеров Snowball (snowballstem.org) для тех же слов.
+func TestStem(t *testing.T) {
+	tests := []struct {
+		lang Language
+		word string
+		want string
+	}{
+		// Русский: существительные
+		{LanguageRussian, "книга", "книг"},
+		{LanguageRussian, "книги", "книг"},
+		{LanguageRussian, "книгой", "книг"},
+		{LanguageRussian, "книгами", "книг"},
+		{LanguageRussian, "лошадью", "лошад"},
+		{LanguageRussian, "вороны", "ворон"},
+		{LanguageRussian, "взглядом", "взгляд"},
+		{LanguageRussian, "бездействие", "бездейств"},
+		// Русский: деепричастия совершенного вида
+		{LanguageRussian, "прочитав", "прочита"},
+		{LanguageRussian, "прочитавши", "прочита"},
+		{LanguageRussian, "прочитавшись", "прочита"},
+		{LanguageRussian, "улыбаясь", "улыб"},
+		// Русский: прилагательные, причастия и превосходная степень
+		{LanguageRussian, "красивая", "красив"},
+		{LanguageRussian, "красивейший", "красив"},
+		{LanguageRussian, "добрейшая", "добр"},
+		{LanguageRussian, "открывшийся", "откр"},
+		{LanguageRussian, "бегущий", "бегущ"},
+		{LanguageRussian, "читающий", "чита"},
+		{LanguageRussian, "купленный", "куплен"},
+		// Русский: «нн» упрощается до «н»
+		{LanguageRussian, "длинный", "длин"},
+		{LanguageRussian, "длиннейший", "длин"},
+		// Русский: глаголы
+		{LanguageRussian, "смотрела", "смотрел"},
+		{LanguageRussian, "делают", "дела"},
+		{LanguageRussian, "говорить", "говор"},
+		{LanguageRussian, "сказал", "сказа"},
+		{LanguageRussian, "идти", "идт"},
+		{LanguageRussian, "ешь", "еш"},
+		// Русский: «ость» удаляется только в R2, мягкий знак — в конце основы
+		{LanguageRussian, "радость", "радост"},
+		{LanguageRussian, "радостями", "радост"},
+		{LanguageRussian, "дочь", "доч"},
+		{LanguageRussian, "мышь", "мыш"},
+		// Русский: «ё» не заменяется, короткие слова
+		{LanguageRussian, "ёлка", "ёлка"},
+		{LanguageRussian, "он", "он"},
+		{LanguageRussian, "я", "я"},
+		{LanguageRussian, "ии", "и"},
+
+		// Английский: шаг 1a и 1b
+		{LanguageEnglish, "caresses", "caress"},
+		{LanguageEnglish, "ponies", "poni"},
+		{LanguageEnglish, "ties", "tie"},
+		{LanguageEnglish, "cats", "cat"},
+		{LanguageEnglish, "running", "run"},
+		{LanguageEnglish, "hopping", "hop"},
+		{LanguageEnglish, "hoped", "hope"},
+		{LanguageEnglish, "agreed", "agre"},
+		{LanguageEnglish, "feed", "feed"},
+		{LanguageEnglish, "luxuriating", "luxuri"},
+		// Английский: шаг 1c и суффиксы шагов 2–4
+		{LanguageEnglish, "happily", "happili"},
+		{LanguageEnglish, "abilities", "abil"},
+		{LanguageEnglish, "generously", "generous"},
+		{LanguageEnglish, "generalizations", "general"},
+		{LanguageEnglish, "hopeful", "hope"},
+		{LanguageEnglish, "relational", "relat"},
+		{LanguageEnglish, "decisiveness", "decis"},
+		{LanguageEnglish, "consignment", "consign"},
+		{LanguageEnglish, "knackeries", "knackeri"},
+		{LanguageEnglish, "generate", "generat"},
+		// Английский: исключения Snowball и особые области R1
+		{LanguageEnglish, "dying", "die"},
+		{LanguageEnglish, "skies", "sky"},
+		{LanguageEnglish, "news", "news"},
+		{LanguageEnglish, "gently", "gentl"},
+		{LanguageEnglish, "early", "earli"},
+		{LanguageEnglish, "only", "onli"},
+		{LanguageEnglish, "inning", "inning"},
+		{LanguageEnglish, "proceed", "proceed"},
+		{LanguageEnglish, "succeed", "succeed"},
+		{LanguageEnglish, "communism", "communism"},
+		{LanguageEnglish, "arsenal", "arsenal"},
+		{LanguageEnglish, "yes", "yes"},
+		{LanguageEnglish, "a", "a"},
+
+		// Остальные языки не стеммируются
+		{LanguageGerman, "häuser", "häuser"},
+		{LanguageUkrainian, "книжками", "книжками"},
+		{LanguageUnknown, "running", "running"},
+	}
+	for _, tt := range tests {
+		if got := Stem(tt.lang, tt.word); got != tt.want {
+			t.Errorf("Stem(%s, %q) = %q, ожидалось %q", tt.lang, tt.word, got, tt.want)
+		}
+	}
+}
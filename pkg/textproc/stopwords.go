@@ -0,0 +1,62 @@
+package textproc
+
+import "strings"
+
+// Служебные слова, которые не несут смысловой нагрузки и не учитываются при построении облака слов
+// и подсчете лексической плотности. Слова записаны в нормализованном виде (см. Tokenize): «ё» заменена на «е».
+var stopWords = map[Language]map[string]struct{}{
+	LanguageRussian: makeSet(`
+а без более бы был была были было быть в вам вас весь во вот все всего всех вы где да даже для до его ее ей ему если есть еще же за здесь и из или им их к как ко когда кто ли либо мне может мы на над надо наш не него нее нет ни них но ну о об однако он она они оно от очень по под при с со так также такой там те тем то того тоже той только том ты у уже хотя чего чей чем что чтобы чье чья эта эти это этого этой этом я
+`),
+	LanguageEnglish: makeSet(`
+a about above after again against all am an and any are as at be because been before being below between both but by can could did do does doing down during each few for from further had has have having he her here hers herself him himself his how i if in into is it its itself just me more most my myself no nor not now of off on once only or other our ours ourselves out over own same she should so some such than that the their theirs them themselves then there these they this those through to too under until up very was we were what when where which while who whom why will with would you your yours yourself yourselves
+`),
+	LanguageUkrainian: makeSet(`
+а аби але б би був була були було бути в вам вас весь ви від він вона вони воно все всі де для до є же з за і із її їй їм їх й коли котрий ле лише мене мені ми на над не нас неї нема ні ніж них но о однак по при про саме свій та так також там те теж ти то тобто тож тому тут у хоча це цей ці ця чи що щоб як який яка які я
+`),
+	LanguageGerman: makeSet(`
+aber alle als also am an auch auf aus bei bin bis bist da damit dann das dass dem den der des die dies diese dieser dieses doch dort du durch ein eine einem einen einer eines er es für hat hatte ich ihr im in ist ja jede jeder jetzt kann kein keine mich mir mit muss nach nicht noch nun nur ob oder ohne sehr sein seine sich sie sind so über um und uns unter vom von vor war waren was weil wenn werden wie wir wird wo zu zum zur
+`),
+	LanguageFrench: makeSet(`
+à au aux avec ce ces cette c'est d'un d'une dans de des donc du elle elles en est et été être eu il ils je l'on la le les leur leurs lui ma mais me même mes moi mon ne nos notre nous on ont ou où par pas pour qu'il que qui sa se ses si son sont sur ta te tes toi ton tous tout très tu un une vos votre vous y
+`),
+	LanguageSpanish: makeSet(`
+a al algo como con cual cuando de del desde donde el ella ellas ellos en entre era es esa ese eso esta este esto fue ha han hay la las le les lo los más me mi muy no nos o otra otro para pero por porque que qué se sea ser si sí sin sobre son su sus también te tiene todo tu un una uno y ya yo
+`),
+}
+
+// allStopWords — объединение списков всех языков; используется, когда язык текста не определен.
+var allStopWords = func() map[string]struct{} {
+	set := make(map[string]struct{})
+	for _, words := range stopWords {
+		for word := range words {
+			set[word] = struct{}{}
+		}
+	}
+	return set
+}()
+
+// IsStopWord сообщает, является ли слово (в нижнем регистре) служебным хотя бы в одном из поддерживаемых языков.
+func IsStopWord(word string) bool {
+	_, ok := allStopWords[word]
+	return ok
+}
+
+// IsStopWordIn сообщает, является ли слово (в нижнем регистре) служебным в языке lang.
+// Для неопределенного языка проверяются списки всех языков.
+func IsStopWordIn(lang Language, word string) bool {
+	words, ok := stopWords[lang]
+	if !ok {
+		words = allStopWords
+	}
+	_, ok = words[word]
+	return ok
+}
+
+func makeSet(words string) map[string]struct{} {
+	set := make(map[string]struct{})
+	for _, word := range strings.Fields(words) {
+		set[word] = struct{}{}
+	}
+	return set
+}
@@ -0,0 +1,177 @@
+// Package textproc содержит общие функции обработки текста: разбиение на слова,
+// определение языка, списки стоп-слов и подсчет частот слов.
+package textproc
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// WordCount — слово и количество его вхождений в текст.
+type WordCount struct {
+	Word  string
+	Count int
+}
+
+// TokenKind — тип токена.
+type TokenKind int
+
+const (
+	TokenWord   TokenKind = iota // Слово (содержит хотя бы одну букву)
+	TokenNumber                  // Число, например «42» или «3,14»
+)
+
+// Token — слово или число текста.
+type Token struct {
+	Text  string    // Нормализованный текст: нижний регистр, «ё» заменена на «е»
+	Kind  TokenKind // Слово или число
+	Start int       // Смещение первого символа токена в тексте (в рунах)
+	End   int       // Смещение символа, следующего за токеном (в рунах)
+}
+
+// wbClass — класс символа по правилам границ слов Unicode (UAX #29), упрощенный для алфавитных языков.
+type wbClass int
+
+const (
+	wbOther        wbClass = iota
+	wbLetter               // Буквы (ALetter)
+	wbIdeographic          // Иероглифы и кана — каждый символ является отдельным словом
+	wbNumeric              // Цифры
+	wbExtend               // Комбинируемые знаки (ударения и т.п.), форматирующие символы (мягкий перенос, ZWJ), присоединяются к предыдущему символу
+	wbMidLetter            // Символы, допустимые между буквами: «·»
+	wbMidNum               // Символы, допустимые между цифрами: «,» и «;»
+	wbMidNumLet            // Символы, допустимые и между буквами, и между цифрами: «.», «'», «’»
+	wbExtendNumLet         // Соединительные знаки: «_»
+)
+
+func classify(r rune) wbClass {
+	switch {
+	case unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) || unicode.Is(unicode.Katakana, r):
+		return wbIdeographic
+	case unicode.IsLetter(r):
+		return wbLetter
+	case unicode.IsDigit(r):
+		return wbNumeric
+	case unicode.In(r, unicode.Mn, unicode.Me, unicode.Mc, unicode.Cf):
+		// WB4: форматирующие символы, как и комбинируемые знаки, не разрывают слово («пере\u00adнос» — одно слово)
+		return wbExtend
+	case r == '·' || r == '‧' || r == '״':
+		return wbMidLetter
+	case r == ',' || r == ';' || r == '٫' || r == '،':
+		return wbMidNum
+	case r == '.' || r == '\'' || r == '’' || r == '‘' || r == '․':
+		return wbMidNumLet
+	case unicode.Is(unicode.Pc, r):
+		return wbExtendNumLet
+	}
+	return wbOther
+}
+
+// Tokenize разбивает текст на слова и числа по правилам границ слов Unicode (UAX #29):
+// апостроф и точка внутри слова не разрывают его («don't», «т.е» остаются одним токеном),
+// разделители разрядов и десятичные знаки не разрывают число («3,14»), а дефис, тире и
+// прочие знаки препинания являются границами слов и в токены не попадают.
+// Текст токенов приводится к нижнему регистру, «ё» заменяется на «е», форматирующие символы удаляются.
+func Tokenize(text string) []Token {
+	runes := []rune(text)
+	classes := make([]wbClass, len(runes))
+	for i, r := range runes {
+		classes[i] = classify(r)
+	}
+
+	var tokens []Token
+	for i := 0; i < len(runes); {
+		class := classes[i]
+		if class == wbIdeographic {
+			tokens = append(tokens, newToken(runes, i, i+1, TokenWord))
+			i++
+			continue
+		}
+		if class != wbLetter && class != wbNumeric && class != wbExtendNumLet {
+			i++
+			continue
+		}
+
+		start := i
+		hasLetter := class == wbLetter
+		prev := class
+		i++
+	scan:
+		for i < len(runes) {
+			c := classes[i]
+			switch {
+			case c == wbExtend:
+				i++
+				continue
+			case c == wbLetter || c == wbNumeric || c == wbExtendNumLet:
+				// WB5, WB8–WB10, WB13a/b: буквы, цифры и соединительные знаки образуют одно слово
+			case (c == wbMidLetter || c == wbMidNumLet) && prev == wbLetter && nextClass(classes, i) == wbLetter:
+				// WB6/WB7: буква × (MidLetter | MidNumLet) × буква
+			case (c == wbMidNum || c == wbMidNumLet) && prev == wbNumeric && nextClass(classes, i) == wbNumeric:
+				// WB11/WB12: цифра × (MidNum | MidNumLet) × цифра
+			default:
+				break scan
+			}
+			if c == wbLetter {
+				hasLetter = true
+			}
+			prev = c
+			i++
+		}
+		end := i
+		// Соединительные знаки по краям токена не являются его частью
+		for start < end && classes[start] == wbExtendNumLet {
+			start++
+		}
+		for end > start && classes[end-1] == wbExtendNumLet {
+			end--
+		}
+		if start == end {
+			continue
+		}
+		kind := TokenNumber
+		if hasLetter {
+			kind = TokenWord
+		}
+		tokens = append(tokens, newToken(runes, start, end, kind))
+	}
+	return tokens
+}
+
+// nextClass возвращает класс первого символа после позиции i, пропуская комбинируемые знаки.
+func nextClass(classes []wbClass, i int) wbClass {
+	for j := i + 1; j < len(classes); j++ {
+		if classes[j] != wbExtend {
+			return classes[j]
+		}
+	}
+	return wbOther
+}
+
+func newToken(runes []rune, start, end int, kind TokenKind) Token {
+	text := strings.Map(func(r rune) rune {
+		if unicode.Is(unicode.Cf, r) {
+			return -1
+		}
+		return unicode.ToLower(r)
+	}, string(runes[start:end]))
+	text = strings.ReplaceAll(text, "ё", "е")
+	text = strings.ReplaceAll(text, "’", "'")
+	return Token{Text: text, Kind: kind, Start: start, End: end}
+}
+
+// SortWordCounts преобразует количества вхождений слов в список в порядке убывания частоты, при равенстве — по алфавиту.
+func SortWordCounts(counts map[string]int) []WordCount {
+	result := make([]WordCount, 0, len(counts))
+	for word, count := range counts {
+		result = append(result, WordCount{Word: word, Count: count})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Word < result[j].Word
+	})
+	return result
+}
@@ -0,0 +1,111 @@
+package textproc
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// tokenTexts возвращает тексты токенов; числа помечаются префиксом «#».
+func tokenTexts(tokens []Token) []string {
+	texts := make([]string, len(tokens))
+	for i, token := range tokens {
+		texts[i] = token.Text
+		if token.Kind == TokenNumber {
+			texts[i] = "#" + token.Text
+		}
+	}
+	return texts
+}
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"пустой текст", "", []string{}},
+		{"только знаки", " ... — !? ", []string{}},
+		{"регистр и ё", "Ёлка ЕЛЬ", []string{"елка", "ель"}},
+		{"дефис разделяет слова", "северо-западный e-mail", []string{"северо", "западный", "e", "mail"}},
+		{"тире и минус", "мир—труд 5-3", []string{"мир", "труд", "#5", "#3"}},
+		{"апостроф внутри слова", "don't it’s п'ять", []string{"don't", "it's", "п'ять"}},
+		{"апостроф по краям", "'quoted' rock'n'roll' ’", []string{"quoted", "rock'n'roll"}},
+		{"точка внутри слова", "т.е. U.S.A.", []string{"т.е", "u.s.a"}},
+		{"десятичная запятая", "3,14 и 2.5", []string{"#3,14", "и", "#2.5"}},
+		{"разделители разрядов", "1.000.000 1,234,567 1 000", []string{"#1.000.000", "#1,234,567", "#1", "#000"}},
+		{"число в конце предложения", "Итого 42. Конец", []string{"итого", "#42", "конец"}},
+		{"время и дробь", "10:30 1/2", []string{"#10", "#30", "#1", "#2"}},
+		{"буквы и цифры", "v2.0 COVID-19 2024г", []string{"v2.0", "covid", "#19", "2024г"}},
+		{"подчеркивание", "_snake_case_ __", []string{"snake_case"}},
+		{"комбинируемое ударение", "зáмок", []string{"зáмок"}},
+		{"эмодзи разделяют слова", "привет👋мир 👍🏽ok", []string{"привет", "мир", "ok"}},
+		{"ZWJ-последовательность", "код 👨\u200d💻 ревью", []string{"код", "ревью"}},
+		{"ZWJ и мягкий перенос внутри слова", "пере\u00adнос a\u200db", []string{"перенос", "ab"}},
+		{"иероглифы", "日本語 text", []string{"日", "本", "語", "text"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tokenTexts(Tokenize(tt.text))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Tokenize(%q) = %q, ожидалось %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTokenizeOffsets(t *testing.T) {
+	text := "«Ёж» — don't 3,14 пере\u00adнос 👨\u200d💻код"
+	runes := []rune(text)
+	want := []struct {
+		text, source string
+		start, end   int
+	}{
+		{"еж", "Ёж", 1, 3},
+		{"don't", "don't", 7, 12},
+		{"3,14", "3,14", 13, 17},
+		{"перенос", "пере\u00adнос", 18, 26},
+		{"код", "код", 30, 33},
+	}
+	tokens := Tokenize(text)
+	if len(tokens) != len(want) {
+		t.Fatalf("токены %q, ожидалось %d", tokenTexts(tokens), len(want))
+	}
+	for i, w := range want {
+		token := tokens[i]
+		if token.Text != w.text || token.Start != w.start || token.End != w.end {
+			t.Errorf("токен %d: %q [%d, %d), ожидалось %q [%d, %d)", i, token.Text, token.Start, token.End, w.text, w.start, w.end)
+			continue
+		}
+		if source := string(runes[token.Start:token.End]); source != w.source {
+			t.Errorf("токен %d: смещения указывают на %q, ожидалось %q", i, source, w.source)
+		}
+	}
+}
+
+func TestTokenizeAcrossChunks(t *testing.T) {
+	// Многобайтовые символы и числа с разделителями на границах фрагментов. Фрагменты не короче самого длинного слова
+	// («французских», 22 байта), иначе ReadChunks пришлось бы разрезать его
+	text := strings.Repeat("Съешь же ещё этих мягких французских булок, don't 3,14 1.000.000 — 👨\u200d💻 ", 50)
+	want := Tokenize(text)
+
+	for _, size := range []int{22, 23, 25, 64, 1000} {
+		var got []Token
+		offset := 0
+		err := ReadChunks(strings.NewReader(text), size, func(chunk string) error {
+			for _, token := range Tokenize(chunk) {
+				token.Start += offset
+				token.End += offset
+				got = append(got, token)
+			}
+			offset += len([]rune(chunk))
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("фрагменты по %d байт: %d токенов, ожидалось %d", size, len(got), len(want))
+		}
+	}
+}
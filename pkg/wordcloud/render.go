@@ -0,0 +1,58 @@
+package wordcloud
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"image"
+	"image/draw"
+	"image/png"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// RenderPNG отрисовывает размещенные слова в PNG-изображение.
+func RenderPNG(words []PlacedWord, opts Options) ([]byte, error) {
+	img := image.NewRGBA(image.Rect(0, 0, opts.Width, opts.Height))
+	draw.Draw(img, img.Bounds(), image.NewUniform(opts.Background), image.Point{}, draw.Src)
+
+	fontMu.Lock()
+	defer fontMu.Unlock()
+
+	for _, w := range words {
+		face, err := fontFace(w.FontSize)
+		if err != nil {
+			return nil, err
+		}
+		drawer := font.Drawer{
+			Dst:  img,
+			Src:  image.NewUniform(w.Color),
+			Face: face,
+			Dot:  fixed.P(w.X, w.Y+w.Ascent),
+		}
+		drawer.DrawString(w.Text)
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("не удалось закодировать облако слов в PNG: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// RenderSVG отрисовывает размещенные слова в SVG-документ.
+// Ширина каждого слова фиксируется атрибутом textLength, поэтому раскладка
+// сохраняется, даже если у клиента нет шрифта Go.
+func RenderSVG(words []PlacedWord, opts Options) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n",
+		opts.Width, opts.Height, opts.Width, opts.Height)
+	fmt.Fprintf(&buf, `<rect width="100%%" height="100%%" fill="%s"/>`+"\n", hexColor(opts.Background))
+	for _, w := range words {
+		fmt.Fprintf(&buf, `<text x="%d" y="%d" font-family="Go, Arial, sans-serif" font-size="%.1f" fill="%s" textLength="%d" lengthAdjust="spacingAndGlyphs">%s</text>`+"\n",
+			w.X, w.Y+w.Ascent, w.FontSize, hexColor(w.Color), w.Width, html.EscapeString(w.Text))
+	}
+	buf.WriteString("</svg>\n")
+	return buf.Bytes()
+}
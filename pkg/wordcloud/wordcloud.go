@@ -0,0 +1,225 @@
+// Package wordcloud строит облако слов по частотам слов и отрисовывает его в PNG или SVG
+// без обращения к внешним сервисам.
+package wordcloud
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+)
+
+// Word — слово и его вес (обычно количество вхождений в текст).
+type Word struct {
+	Text  string
+	Count int
+}
+
+// Options задает параметры облака слов.
+type Options struct {
+	Width       int          // Ширина изображения в пикселях
+	Height      int          // Высота изображения в пикселях
+	MaxWords    int          // Максимальное количество слов в облаке
+	MinFontSize float64      // Размер шрифта самого редкого слова
+	MaxFontSize float64      // Размер шрифта самого частого слова
+	Palette     []color.RGBA // Цвета слов, используются по кругу
+	Background  color.RGBA   // Цвет фона
+}
+
+// DefaultPalette — палитра по умолчанию.
+var DefaultPalette = []color.RGBA{
+	{0x1f, 0x77, 0xb4, 0xff},
+	{0xff, 0x7f, 0x0e, 0xff},
+	{0x2c, 0xa0, 0x2c, 0xff},
+	{0xd6, 0x27, 0x28, 0xff},
+	{0x94, 0x67, 0xbd, 0xff},
+	{0x8c, 0x56, 0x4b, 0xff},
+}
+
+// DefaultOptions возвращает параметры облака слов по умолчанию.
+func DefaultOptions() Options {
+	return Options{
+		Width:       800,
+		Height:      600,
+		MaxWords:    100,
+		MinFontSize: 12,
+		MaxFontSize: 72,
+		Palette:     DefaultPalette,
+		Background:  color.RGBA{0xff, 0xff, 0xff, 0xff},
+	}
+}
+
+// PlacedWord — слово, размещенное на изображении.
+type PlacedWord struct {
+	Text     string
+	FontSize float64
+	Color    color.RGBA
+	X, Y     int // Левый верхний угол прямоугольника слова
+	Width    int
+	Height   int
+	Ascent   int // Расстояние от верхнего края прямоугольника до базовой линии
+}
+
+// Layout размещает слова на плоскости по архимедовой спирали от центра:
+// более частые слова получают больший шрифт и ставятся первыми.
+// Слова, которые не удалось разместить даже минимальным шрифтом, пропускаются.
+func Layout(words []Word, opts Options) ([]PlacedWord, error) {
+	if opts.Width <= 0 || opts.Height <= 0 {
+		return nil, fmt.Errorf("некорректный размер облака слов %dx%d", opts.Width, opts.Height)
+	}
+	if len(opts.Palette) == 0 {
+		opts.Palette = DefaultPalette
+	}
+
+	sorted := make([]Word, 0, len(words))
+	for _, w := range words {
+		if w.Count > 0 && strings.TrimSpace(w.Text) != "" {
+			sorted = append(sorted, w)
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Count > sorted[j].Count })
+	if opts.MaxWords > 0 && len(sorted) > opts.MaxWords {
+		sorted = sorted[:opts.MaxWords]
+	}
+	if len(sorted) == 0 {
+		return nil, nil
+	}
+
+	fontMu.Lock()
+	defer fontMu.Unlock()
+
+	maxCount := float64(sorted[0].Count)
+	minCount := float64(sorted[len(sorted)-1].Count)
+
+	placed := make([]PlacedWord, 0, len(sorted))
+	for i, w := range sorted {
+		// Размер шрифта пропорционален квадратному корню частоты — так редкие слова остаются читаемыми
+		ratio := 1.0
+		if maxCount > minCount {
+			ratio = (math.Sqrt(float64(w.Count)) - math.Sqrt(minCount)) / (math.Sqrt(maxCount) - math.Sqrt(minCount))
+		}
+		size := opts.MinFontSize + ratio*(opts.MaxFontSize-opts.MinFontSize)
+
+		for ; size >= opts.MinFontSize; size *= 0.8 {
+			face, err := fontFace(size)
+			if err != nil {
+				return nil, err
+			}
+			metrics := face.Metrics()
+			candidate := PlacedWord{
+				Text:     w.Text,
+				FontSize: size,
+				Color:    opts.Palette[i%len(opts.Palette)],
+				Width:    font.MeasureString(face, w.Text).Ceil(),
+				Height:   (metrics.Ascent + metrics.Descent).Ceil(),
+				Ascent:   metrics.Ascent.Ceil(),
+			}
+			if findPosition(&candidate, placed, opts.Width, opts.Height) {
+				placed = append(placed, candidate)
+				break
+			}
+		}
+	}
+	return placed, nil
+}
+
+// findPosition ищет на спирали первое место, где слово помещается в изображение и не пересекается с уже размещенными.
+func findPosition(word *PlacedWord, placed []PlacedWord, width, height int) bool {
+	if word.Width > width || word.Height > height {
+		return false
+	}
+	cx, cy := float64(width-word.Width)/2, float64(height-word.Height)/2
+	aspect := float64(width) / float64(height)
+	maxRadius := math.Hypot(float64(width), float64(height)) / 2
+
+	for t := 0.0; ; t += 0.1 {
+		radius := 2 * t
+		if radius > maxRadius {
+			return false
+		}
+		x := int(cx + radius*math.Cos(t)*aspect)
+		y := int(cy + radius*math.Sin(t))
+		if x < 0 || y < 0 || x+word.Width > width || y+word.Height > height {
+			continue
+		}
+		word.X, word.Y = x, y
+		if !intersectsAny(*word, placed) {
+			return true
+		}
+	}
+}
+
+func intersectsAny(word PlacedWord, placed []PlacedWord) bool {
+	for _, p := range placed {
+		if word.X < p.X+p.Width && p.X < word.X+word.Width &&
+			word.Y < p.Y+p.Height && p.Y < word.Y+word.Height {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	parsedFont    *opentype.Font
+	parsedFontErr error
+	parseFontOnce sync.Once
+	faceCache     = make(map[float64]font.Face)
+	// fontMu защищает faceCache и сами начертания: font.Face не безопасен для конкурентного использования
+	fontMu sync.Mutex
+)
+
+// fontFace возвращает начертание встроенного шрифта Go (поддерживает латиницу и кириллицу) нужного размера.
+// Вызывающий должен удерживать fontMu.
+func fontFace(size float64) (font.Face, error) {
+	parseFontOnce.Do(func() {
+		parsedFont, parsedFontErr = opentype.Parse(goregular.TTF)
+	})
+	if parsedFontErr != nil {
+		return nil, fmt.Errorf("не удалось загрузить встроенный шрифт: %w", parsedFontErr)
+	}
+
+	size = math.Round(size*2) / 2 // Ограничиваем количество кешируемых начертаний
+	if face, ok := faceCache[size]; ok {
+		return face, nil
+	}
+	face, err := opentype.NewFace(parsedFont, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingFull})
+	if err != nil {
+		return nil, fmt.Errorf("не удалось создать начертание шрифта размера %.1f: %w", size, err)
+	}
+	faceCache[size] = face
+	return face, nil
+}
+
+// ParsePalette разбирает список цветов в формате "#rrggbb" через запятую.
+func ParsePalette(value string) ([]color.RGBA, error) {
+	var palette []color.RGBA
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimPrefix(strings.TrimSpace(item), "#")
+		if item == "" {
+			continue
+		}
+		if len(item) != 6 {
+			return nil, fmt.Errorf("некорректный цвет %q: ожидается формат #rrggbb", item)
+		}
+		rgb, err := strconv.ParseUint(item, 16, 32)
+		if err != nil {
+			return nil, fmt.Errorf("некорректный цвет %q: %w", item, err)
+		}
+		palette = append(palette, color.RGBA{uint8(rgb >> 16), uint8(rgb >> 8), uint8(rgb), 0xff})
+	}
+	if len(palette) == 0 {
+		return nil, fmt.Errorf("палитра не содержит ни одного цвета")
+	}
+	return palette, nil
+}
+
+func hexColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
@@ -0,0 +1,176 @@
+package wordcloud
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"image/png"
+	"io"
+	"reflect"
+	"testing"
+)
+
+// testWords возвращает n слов с убывающими весами.
+func testWords(n int) []Word {
+	words := make([]Word, n)
+	for i := range words {
+		words[i] = Word{Text: fmt.Sprintf("слово%d", i), Count: n - i}
+	}
+	return words
+}
+
+func TestLayout(t *testing.T) {
+	opts := DefaultOptions()
+	words := testWords(150)
+	placed, err := Layout(words, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(placed) == 0 || len(placed) > opts.MaxWords {
+		t.Fatalf("размещено %d слов, ожидалось от 1 до %d", len(placed), opts.MaxWords)
+	}
+
+	// Повторное размещение тех же слов дает ту же раскладку
+	again, err := Layout(words, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(again, placed) {
+		t.Fatal("раскладка одних и тех же слов различается")
+	}
+
+	for i, w := range placed {
+		if w.X < 0 || w.Y < 0 || w.X+w.Width > opts.Width || w.Y+w.Height > opts.Height {
+			t.Fatalf("слово %q (%d,%d %dx%d) выходит за пределы изображения", w.Text, w.X, w.Y, w.Width, w.Height)
+		}
+		if w.FontSize < opts.MinFontSize*0.8 || w.FontSize > opts.MaxFontSize {
+			t.Fatalf("слово %q: размер шрифта %.1f", w.Text, w.FontSize)
+		}
+		if intersectsAny(w, placed[:i]) {
+			t.Fatalf("слово %q пересекается с ранее размещенным", w.Text)
+		}
+	}
+	// Самое частое слово ставится первым и получает наибольший шрифт
+	if placed[0].Text != words[0].Text || placed[0].FontSize != opts.MaxFontSize {
+		t.Fatalf("первое слово %q размера %.1f", placed[0].Text, placed[0].FontSize)
+	}
+}
+
+func TestLayoutMaxWords(t *testing.T) {
+	opts := DefaultOptions()
+	for _, maxWords := range []int{1, 5, 20} {
+		opts.MaxWords = maxWords
+		placed, err := Layout(testWords(50), opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(placed) != maxWords {
+			t.Fatalf("MaxWords %d: размещено %d слов", maxWords, len(placed))
+		}
+		// В облако попадают самые частые слова
+		for i, w := range placed {
+			if want := fmt.Sprintf("слово%d", i); w.Text != want {
+				t.Fatalf("MaxWords %d: слово %d — %q, ожидалось %q", maxWords, i, w.Text, want)
+			}
+		}
+	}
+}
+
+func TestLayoutEdgeCases(t *testing.T) {
+	if _, err := Layout(testWords(3), Options{Width: 0, Height: 100}); err == nil {
+		t.Fatal("ожидалась ошибка для нулевой ширины")
+	}
+	placed, err := Layout([]Word{{Text: " ", Count: 5}, {Text: "ноль", Count: 0}}, DefaultOptions())
+	if err != nil || len(placed) != 0 {
+		t.Fatalf("пустые слова и слова без вхождений: %v, %v", placed, err)
+	}
+	// Слово, которое не помещается даже минимальным шрифтом, пропускается
+	opts := DefaultOptions()
+	opts.Width, opts.Height = 40, 40
+	placed, err = Layout([]Word{{Text: "оченьдлинноеслово", Count: 2}, {Text: "да", Count: 1}}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(placed) != 1 || placed[0].Text != "да" {
+		t.Fatalf("размещены %+v, ожидалось только «да»", placed)
+	}
+}
+
+func TestRenderPNG(t *testing.T) {
+	opts := DefaultOptions()
+	opts.Width, opts.Height = 320, 200
+	placed, err := Layout(testWords(20), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := RenderPNG(placed, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("PNG не декодируется: %v", err)
+	}
+	if size := img.Bounds().Size(); size.X != opts.Width || size.Y != opts.Height {
+		t.Fatalf("размер изображения %v, ожидалось %dx%d", size, opts.Width, opts.Height)
+	}
+	// Слова отрисованы: не все пиксели совпадают с фоном
+	background := opts.Background
+	for y := 0; y < opts.Height; y++ {
+		for x := 0; x < opts.Width; x++ {
+			if r, g, b, _ := img.At(x, y).RGBA(); uint8(r>>8) != background.R || uint8(g>>8) != background.G || uint8(b>>8) != background.B {
+				return
+			}
+		}
+	}
+	t.Fatal("изображение состоит только из фона")
+}
+
+func TestRenderSVG(t *testing.T) {
+	opts := DefaultOptions()
+	words := []Word{{Text: `<script>&"'`, Count: 3}, {Text: "облако", Count: 2}, {Text: "a&b", Count: 1}}
+	placed, err := Layout(words, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := RenderSVG(placed, opts)
+	if bytes.Contains(data, []byte("<script>")) {
+		t.Fatal("слово вставлено в SVG без экранирования")
+	}
+
+	// Документ корректен как XML, а текст элементов совпадает с исходными словами
+	var root string
+	var texts []string
+	inText := false
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("SVG не является корректным XML: %v", err)
+		}
+		switch token := token.(type) {
+		case xml.StartElement:
+			if root == "" {
+				root = token.Name.Local
+			}
+			if inText = token.Name.Local == "text"; inText {
+				texts = append(texts, "")
+			}
+		case xml.CharData:
+			if inText {
+				texts[len(texts)-1] += string(token)
+			}
+		case xml.EndElement:
+			inText = false
+		}
+	}
+	if root != "svg" {
+		t.Fatalf("корневой элемент %q, ожидался svg", root)
+	}
+	if want := []string{`<script>&"'`, "облако", "a&b"}; !reflect.DeepEqual(texts, want) {
+		t.Fatalf("слова SVG %q, ожидалось %q", texts, want)
+	}
+}